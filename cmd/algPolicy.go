@@ -0,0 +1,103 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	cose "github.com/veraison/go-cose"
+)
+
+// algorithmPolicy restricts the COSE algorithms that may be used for signing,
+// or that are accepted when verifying.  An empty allowed list allows any
+// algorithm that is not denied.
+type algorithmPolicy struct {
+	allowed []cose.Algorithm
+	denied  []cose.Algorithm
+}
+
+// newAlgorithmPolicy builds an algorithmPolicy from the supplied lists of
+// algorithm names (e.g., ES384)
+func newAlgorithmPolicy(allowed, denied []string) (algorithmPolicy, error) {
+	var (
+		p   algorithmPolicy
+		err error
+	)
+
+	if p.allowed, err = parseAlgorithms(allowed); err != nil {
+		return p, fmt.Errorf("invalid --allowed-algs: %w", err)
+	}
+
+	if p.denied, err = parseAlgorithms(denied); err != nil {
+		return p, fmt.Errorf("invalid --denied-algs: %w", err)
+	}
+
+	return p, nil
+}
+
+// check returns an error if alg is denied, or if it is not among the allowed
+// algorithms
+func (p algorithmPolicy) check(alg cose.Algorithm) error {
+	for _, a := range p.denied {
+		if a == alg {
+			return fmt.Errorf("algorithm policy violation: %s is denied", alg)
+		}
+	}
+
+	if len(p.allowed) == 0 {
+		return nil
+	}
+
+	for _, a := range p.allowed {
+		if a == alg {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("algorithm policy violation: %s is not allowed (allowed: %s)", alg, algorithmNames(p.allowed))
+}
+
+// parseAlgorithms resolves the supplied (case-insensitive) names of signature
+// algorithms
+func parseAlgorithms(names []string) ([]cose.Algorithm, error) {
+	var algs []cose.Algorithm
+
+	for _, name := range names {
+		alg, ok := algorithmByName(strings.TrimSpace(name))
+		if !ok {
+			return nil, fmt.Errorf("unknown signature algorithm %q, expecting one of: %s", name, supportedAlgorithmNames())
+		}
+		algs = append(algs, alg)
+	}
+
+	return algs, nil
+}
+
+func algorithmByName(name string) (cose.Algorithm, bool) {
+	for alg := range supportedAlgorithms {
+		if strings.EqualFold(alg.String(), name) {
+			return alg, true
+		}
+	}
+	return 0, false
+}
+
+func algorithmNames(algs []cose.Algorithm) string {
+	names := make([]string, 0, len(algs))
+	for _, alg := range algs {
+		names = append(names, alg.String())
+	}
+	return strings.Join(names, ", ")
+}
+
+func supportedAlgorithmNames() string {
+	var names []string
+	for alg := range supportedAlgorithms {
+		names = append(names, alg.String())
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,114 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	cose "github.com/veraison/go-cose"
+)
+
+func Test_algorithmPolicy_check(t *testing.T) {
+	tvs := []struct {
+		desc     string
+		allowed  []string
+		denied   []string
+		alg      cose.Algorithm
+		expected string
+	}{
+		{
+			desc: "no policy",
+			alg:  cose.AlgorithmES256,
+		},
+		{
+			desc:    "allowed",
+			allowed: []string{"ES384", "es512"},
+			alg:     cose.AlgorithmES512,
+		},
+		{
+			desc:     "not allowed",
+			allowed:  []string{"ES384", "ES512"},
+			alg:      cose.AlgorithmES256,
+			expected: "algorithm policy violation: ES256 is not allowed (allowed: ES384, ES512)",
+		},
+		{
+			desc:     "denied",
+			denied:   []string{"ES256"},
+			alg:      cose.AlgorithmES256,
+			expected: "algorithm policy violation: ES256 is denied",
+		},
+		{
+			desc:     "denied takes precedence",
+			allowed:  []string{"ES256"},
+			denied:   []string{"ES256"},
+			alg:      cose.AlgorithmES256,
+			expected: "algorithm policy violation: ES256 is denied",
+		},
+		{
+			desc:   "not denied",
+			denied: []string{"ES256", "PS256"},
+			alg:    cose.AlgorithmEdDSA,
+		},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.desc, func(t *testing.T) {
+			p, err := newAlgorithmPolicy(tv.allowed, tv.denied)
+			require.NoError(t, err)
+
+			err = p.check(tv.alg)
+			if tv.expected == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tv.expected)
+			}
+		})
+	}
+}
+
+func Test_newAlgorithmPolicy_unknown_algorithm(t *testing.T) {
+	_, err := newAlgorithmPolicy([]string{"ES256", "HS256"}, nil)
+	assert.EqualError(t, err,
+		`invalid --allowed-algs: unknown signature algorithm "HS256", expecting one of: ES256, ES384, ES512, EdDSA, PS256, PS384, PS512`)
+
+	_, err = newAlgorithmPolicy(nil, []string{"RS1"})
+	assert.EqualError(t, err,
+		`invalid --denied-algs: unknown signature algorithm "RS1", expecting one of: ES256, ES384, ES512, EdDSA, PS256, PS384, PS512`)
+}
+
+func Test_CorimVerifyCmd_allowed_algs(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "signed.cbor", testSignedCorimValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "key.jwk", testECKey, 0644))
+
+	cmd := NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=signed.cbor", "--key=key.jwk", "--allowed-algs=es256,ES384"})
+	assert.NoError(t, cmd.Execute())
+
+	// fails closed, even though the signature is valid
+	cmd = NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=signed.cbor", "--key=key.jwk", "--allowed-algs=ES384,ES512"})
+	assert.EqualError(t, cmd.Execute(),
+		"error verifying signed.cbor: algorithm policy violation: ES256 is not allowed (allowed: ES384, ES512)")
+}
+
+func Test_CorimVerifyCmd_allowed_algs_unknown(t *testing.T) {
+	cmd := NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=signed.cbor", "--key=key.jwk", "--allowed-algs=MD5"})
+	assert.ErrorContains(t, cmd.Execute(), `invalid --allowed-algs: unknown signature algorithm "MD5"`)
+}
+
+func Test_CorimVerifyCmd_allowed_algs_quorum(t *testing.T) {
+	setupQuorumTest(t)
+
+	// only the EdDSA signature counts
+	cmd := NewCorimVerifyCmd()
+	cmd.SetArgs([]string{
+		"--file=multi.cbor", "--quorum=2", "--quorum-key=ed.jwk", "--quorum-key=ec.jwk", "--allowed-algs=EdDSA",
+	})
+	assert.EqualError(t, cmd.Execute(), "error verifying multi.cbor: quorum not met: 1 signer(s) verified, 2 required")
+}
@@ -0,0 +1,52 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// appendToCBORSequence appends item as the next item of the CBOR sequence in
+// file, creating the file with permissions perm if it does not exist, and
+// returns the index of the appended item.  As a CBOR sequence cannot be
+// resynchronized, nothing is appended to a file that is not a well-formed
+// sequence (e.g., following an interrupted append), since every later item
+// would be lost to its readers.
+func appendToCBORSequence(file string, item []byte, perm os.FileMode) (int, error) {
+	f, err := fs.OpenFile(file, os.O_RDWR|os.O_APPEND|os.O_CREATE, perm)
+	if err != nil {
+		return 0, fmt.Errorf("error opening CBOR sequence %s: %w", file, err)
+	}
+	defer f.Close()
+
+	if osf, ok := f.(*os.File); ok {
+		if err = lockFile(osf); err != nil {
+			return 0, fmt.Errorf("error locking CBOR sequence %s: %w", file, err)
+		}
+		defer unlockFile(osf) // nolint: errcheck
+	}
+
+	// not all file systems start reading a file opened for appending from its
+	// beginning
+	if _, err = f.Seek(0, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("error reading CBOR sequence %s: %w", file, err)
+	}
+
+	n, err := decodeCBORSequence(f, file, func(int, []byte) {})
+	if err != nil {
+		return n, fmt.Errorf("refusing to append to %s: %w", file, err)
+	}
+
+	if _, err = f.Seek(0, io.SeekEnd); err != nil {
+		return n, fmt.Errorf("error appending to CBOR sequence %s: %w", file, err)
+	}
+
+	if _, err = f.Write(item); err != nil {
+		return n, fmt.Errorf("error appending to CBOR sequence %s: %w", file, err)
+	}
+
+	return n, nil
+}
@@ -0,0 +1,111 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func signToSequence(t *testing.T, extraArgs ...string) error {
+	require.NoError(t, afero.WriteFile(fs, "unsigned.cbor", testCorimValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "key.jwk", testECKey, 0644))
+	require.NoError(t, afero.WriteFile(fs, "meta.json", testMetaValid, 0644))
+
+	cmd := NewCorimSignCmd()
+	cmd.SetArgs(append([]string{
+		"--file=unsigned.cbor",
+		"--key=key.jwk",
+		"--meta=meta.json",
+		"--append-to-sequence=stream.cbor",
+	}, extraArgs...))
+
+	return cmd.Execute()
+}
+
+func Test_CorimSignCmd_append_to_sequence(t *testing.T) {
+	fs = afero.NewMemMapFs()
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, signToSequence(t))
+	}
+
+	n, err := readCBORSequence("stream.cbor", func(int, []byte) {})
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+
+	// no standalone signed CoRIM is saved
+	exists, err := afero.Exists(fs, "signed-unsigned.cbor")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	cmd := NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=stream.cbor", "--sequence", "--key=key.jwk"})
+	assert.NoError(t, cmd.Execute())
+}
+
+func Test_CorimSignCmd_append_to_sequence_malformed(t *testing.T) {
+	fs = afero.NewMemMapFs()
+
+	// e.g., an interrupted append
+	truncated := testSignedCorimValid[:10]
+	require.NoError(t, afero.WriteFile(fs, "stream.cbor", truncated, 0644))
+
+	err := signToSequence(t)
+	assert.EqualError(t, err,
+		"refusing to append to stream.cbor: error decoding CBOR sequence from stream.cbor: item 0 at offset 0: unexpected EOF")
+
+	assert.Equal(t, truncated, mustReadFile(t, "stream.cbor"))
+}
+
+func Test_CorimSignCmd_append_to_sequence_audit_log(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "stream.cbor", testSignedCorimValid, 0644))
+
+	require.NoError(t, signToSequence(t, "--audit-log=audit.log"))
+
+	assert.Contains(t, string(mustReadFile(t, "audit.log")), fmt.Sprintf(`"output":%q`, "stream.cbor[1]"))
+}
+
+func Test_CorimSignCmd_append_to_sequence_bad_args(t *testing.T) {
+	tvs := []struct {
+		desc     string
+		args     []string
+		expected string
+	}{
+		{
+			desc:     "output",
+			args:     []string{"--output=signed.cbor"},
+			expected: "--append-to-sequence cannot be used together with --output or --label-output-by-id",
+		},
+		{
+			desc:     "label by id",
+			args:     []string{"--label-output-by-id"},
+			expected: "--append-to-sequence cannot be used together with --output or --label-output-by-id",
+		},
+		{
+			desc:     "diag",
+			args:     []string{"--output-format=both"},
+			expected: "--append-to-sequence can only be used together with --output-format=cbor",
+		},
+		{
+			desc:     "index",
+			args:     []string{"--index=index.json"},
+			expected: "--append-to-sequence cannot be used together with --sign-only-if-changed or --index",
+		},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.desc, func(t *testing.T) {
+			cmd := NewCorimSignCmd()
+			cmd.SetArgs(append([]string{"--file=ok.cbor", "--key=ok.jwk", "--meta=ok.json", "--append-to-sequence=stream.cbor"}, tv.args...))
+
+			assert.EqualError(t, cmd.Execute(), tv.expected)
+		})
+	}
+}
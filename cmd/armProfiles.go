@@ -0,0 +1,157 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/eat"
+)
+
+// armProfile is an Arm endorsement profile whose CoMIDs are checked for
+// conformance by "comid create --profile"
+type armProfile struct {
+	name  string
+	id    string
+	check func(*comid.Comid) []error
+}
+
+// armProfiles maps the short names accepted by --profile to the Arm profiles
+var armProfiles = map[string]armProfile{
+	"psa": {
+		name:  "PSA",
+		id:    "http://arm.com/psa/iot/1",
+		check: func(c *comid.Comid) []error { return checkArmComid(c, false) },
+	},
+	"cca": {
+		name:  "CCA platform",
+		id:    "http://arm.com/cca/ssd/1",
+		check: func(c *comid.Comid) []error { return checkArmComid(c, true) },
+	},
+}
+
+// lookupArmProfile returns the Arm profile identified by profile, if any
+func lookupArmProfile(profile *eat.Profile) (armProfile, bool) {
+	if profile == nil {
+		return armProfile{}, false
+	}
+
+	id, err := profile.Get()
+	if err != nil {
+		return armProfile{}, false
+	}
+
+	for _, p := range armProfiles {
+		if p.id == id {
+			return p, true
+		}
+	}
+
+	return armProfile{}, false
+}
+
+// checkProfileConformance checks that c conforms to profile, if that is one of
+// the Arm profiles.  All the problems found are reported.
+func checkProfileConformance(c *comid.Comid, profile *eat.Profile) error {
+	p, ok := lookupArmProfile(profile)
+	if !ok {
+		return nil
+	}
+
+	if errs := p.check(c); len(errs) != 0 {
+		return fmt.Errorf("not conformant to the %s profile: %w", p.name, errors.Join(errs...))
+	}
+
+	return nil
+}
+
+// checkArmComid checks that the reference values and attestation verification
+// keys of c follow the layout defined by the PSA and CCA (if cca is set)
+// endorsement profiles: environments are identified by the implementation id
+// (psa.impl-id) class, software components are identified by psa.refval-id
+// keys and carry their digests and, for CCA only, the platform configuration
+// is identified by a cca.platform-config-id key and carries a raw value.
+// Attestation verification keys are bound to an instance (ueid) of the
+// implementation, and are supplied as a single pkix-base64-key.
+func checkArmComid(c *comid.Comid, cca bool) []error {
+	var errs []error
+
+	rvs := c.Triples.ReferenceValues
+	avks := c.Triples.AttestVerifKeys
+
+	if (rvs == nil || len(rvs.Values) == 0) && (avks == nil || len(*avks) == 0) {
+		return []error{errors.New("no reference-values or attester-verification-keys triples found")}
+	}
+
+	if rvs != nil {
+		for i, vt := range rvs.Values {
+			where := fmt.Sprintf("reference-values[%d]", i)
+
+			if err := checkArmImplID(vt.Environment); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", where, err))
+			}
+
+			for j, m := range vt.Measurements.Values {
+				if err := checkArmMeasurement(m, cca); err != nil {
+					errs = append(errs, fmt.Errorf("%s: measurement [%d]: %w", where, j, err))
+				}
+			}
+		}
+	}
+
+	if avks != nil {
+		for i, kt := range *avks {
+			where := fmt.Sprintf("attester-verification-keys[%d]", i)
+
+			if err := checkArmImplID(kt.Environment); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", where, err))
+			}
+
+			if kt.Environment.Instance == nil || kt.Environment.Instance.Type() != "ueid" {
+				errs = append(errs, fmt.Errorf("%s: environment instance must be a ueid", where))
+			}
+
+			if len(kt.VerifKeys) != 1 {
+				errs = append(errs, fmt.Errorf("%s: expecting exactly one verification key, got %d", where, len(kt.VerifKeys)))
+			} else if typ := kt.VerifKeys[0].Type(); typ != comid.PKIXBase64KeyType {
+				errs = append(errs, fmt.Errorf("%s: verification key must be a %s, got %s", where, comid.PKIXBase64KeyType, typ))
+			}
+		}
+	}
+
+	return errs
+}
+
+func checkArmImplID(env comid.Environment) error {
+	if env.Class == nil || env.Class.ClassID == nil || env.Class.ClassID.Type() != comid.ImplIDType {
+		return fmt.Errorf("environment class id must be a %s", comid.ImplIDType)
+	}
+
+	return nil
+}
+
+func checkArmMeasurement(m comid.Measurement, cca bool) error {
+	if m.Key == nil || !m.Key.IsSet() {
+		return errors.New("missing measurement key")
+	}
+
+	switch typ := m.Key.Type(); {
+	case typ == comid.PSARefValIDType:
+		if m.Val.Digests == nil || len(*m.Val.Digests) == 0 {
+			return fmt.Errorf("%s measurement has no digests", typ)
+		}
+	case cca && typ == comid.CCAPlatformConfigIDType:
+		if m.Val.RawValue == nil {
+			return fmt.Errorf("%s measurement has no raw-value", typ)
+		}
+	case cca:
+		return fmt.Errorf("measurement key must be a %s or a %s, got %s", comid.PSARefValIDType, comid.CCAPlatformConfigIDType, typ)
+	default:
+		return fmt.Errorf("measurement key must be a %s, got %s", comid.PSARefValIDType, typ)
+	}
+
+	return nil
+}
@@ -0,0 +1,194 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/comid"
+)
+
+// testCCARefValTemplate is a CCA platform reference value template carrying
+// both a software component and the platform configuration
+var testCCARefValTemplate = `{
+  "lang": "en-GB",
+  "tag-identity": {
+    "id": "43bbe37f-2e61-4b33-aed3-53cff1428b16"
+  },
+  "entities": [
+    {
+      "name": "ACME Ltd.",
+      "regid": "https://acme.example",
+      "roles": [ "tagCreator", "creator", "maintainer" ]
+    }
+  ],
+  "triples": {
+    "reference-values": [
+      {
+        "environment": {
+          "class": {
+            "id": {
+              "type": "psa.impl-id",
+              "value": "YWNtZS1pbXBsZW1lbnRhdGlvbi1pZC0wMDAwMDAwMDE="
+            },
+            "vendor": "ACME",
+            "model": "RoadRunner"
+          }
+        },
+        "measurements": [
+          {
+            "key": {
+              "type": "psa.refval-id",
+              "value": {
+                "label": "BL",
+                "version": "2.1.0",
+                "signer-id": "rLsRx+TaIXIFUjzkzhokWuGiOa48a/2eeHH35di66Gs="
+              }
+            },
+            "value": {
+              "digests": [
+                "sha-256;h0KPxSKAPTEGXnvOPPA/5HUJZjHl4Hu9eg/eYMTPJcc="
+              ]
+            }
+          },
+          {
+            "key": {
+              "type": "cca.platform-config-id",
+              "value": "cfg v1.0.0"
+            },
+            "value": {
+              "raw-value": {
+                "type": "bytes",
+                "value": "cmF3dmFsdWUKcmF3dmFsdWUK"
+              }
+            }
+          }
+        ]
+      }
+    ]
+  }
+}`
+
+func testComidFromTemplate(t *testing.T, tmpl string) *comid.Comid {
+	c := comid.NewComid()
+	require.NoError(t, c.FromJSON([]byte(tmpl)))
+	return c
+}
+
+// editTemplate decodes tmpl, lets edit modify it and returns the re-encoded
+// template
+func editTemplate(t *testing.T, tmpl string, edit func(map[string]interface{})) string {
+	var m map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(tmpl), &m))
+
+	edit(m)
+
+	data, err := json.Marshal(m)
+	require.NoError(t, err)
+
+	return string(data)
+}
+
+func firstRefVal(m map[string]interface{}) map[string]interface{} {
+	rvs := m["triples"].(map[string]interface{})["reference-values"].([]interface{})
+	return rvs[0].(map[string]interface{})
+}
+
+func Test_checkProfileConformance_ok(t *testing.T) {
+	tvs := []struct {
+		desc    string
+		profile string
+		tmpl    string
+	}{
+		{"PSA reference values under psa", "psa", comid.PSARefValJSONTemplate},
+		{"PSA reference values under cca", "cca", comid.PSARefValJSONTemplate},
+		{"CCA reference values under cca", "cca", testCCARefValTemplate},
+		{"CCA profile identifier", "http://arm.com/cca/ssd/1", testCCARefValTemplate},
+		{"not an Arm profile", "http://example.com/acme/1", testCCARefValTemplate},
+		{"no profile", "", testCCARefValTemplate},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.desc, func(t *testing.T) {
+			profile, err := parseProfile(tv.profile)
+			require.NoError(t, err)
+
+			assert.NoError(t, checkProfileConformance(testComidFromTemplate(t, tv.tmpl), profile))
+		})
+	}
+}
+
+func Test_checkProfileConformance_bad(t *testing.T) {
+	tvs := []struct {
+		desc     string
+		profile  string
+		tmpl     string
+		expected string
+	}{
+		{
+			desc:     "CCA platform config under psa",
+			profile:  "psa",
+			tmpl:     testCCARefValTemplate,
+			expected: "not conformant to the PSA profile: reference-values[0]: measurement [1]: measurement key must be a psa.refval-id, got cca.platform-config-id",
+		},
+		{
+			desc:    "wrong class id and missing digests",
+			profile: "cca",
+			tmpl: editTemplate(t, testCCARefValTemplate, func(m map[string]interface{}) {
+				rv := firstRefVal(m)
+				rv["environment"].(map[string]interface{})["class"].(map[string]interface{})["id"] = map[string]interface{}{
+					"type":  "uuid",
+					"value": "31fb5abf-023e-4992-aa4e-95f9c1503bfa",
+				}
+				ms := rv["measurements"].([]interface{})
+				ms[0].(map[string]interface{})["value"] = map[string]interface{}{"svn": map[string]interface{}{"type": "exact-value", "value": 1}}
+			}),
+			expected: "not conformant to the CCA platform profile: reference-values[0]: environment class id must be a psa.impl-id\n" +
+				"reference-values[0]: measurement [0]: psa.refval-id measurement has no digests",
+		},
+		{
+			desc:    "missing raw value",
+			profile: "cca",
+			tmpl: editTemplate(t, testCCARefValTemplate, func(m map[string]interface{}) {
+				ms := firstRefVal(m)["measurements"].([]interface{})
+				ms[1].(map[string]interface{})["value"] = map[string]interface{}{"svn": map[string]interface{}{"type": "exact-value", "value": 1}}
+			}),
+			expected: "not conformant to the CCA platform profile: reference-values[0]: measurement [1]: cca.platform-config-id measurement has no raw-value",
+		},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.desc, func(t *testing.T) {
+			profile, err := parseProfile(tv.profile)
+			require.NoError(t, err)
+
+			err = checkProfileConformance(testComidFromTemplate(t, tv.tmpl), profile)
+			assert.EqualError(t, err, tv.expected)
+		})
+	}
+}
+
+func Test_ComidCreateCmd_arm_profile(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "cca.json", []byte(testCCARefValTemplate), 0644))
+
+	cmd := NewComidCreateCmd()
+	cmd.SetArgs([]string{"--template=cca.json", "--profile=psa"})
+	assert.EqualError(t, cmd.Execute(), "1/1 creations(s) failed")
+
+	cmd = NewComidCreateCmd()
+	cmd.SetArgs([]string{"--template=cca.json", "--profile=cca"})
+	require.NoError(t, cmd.Execute())
+
+	data, err := afero.ReadFile(fs, "cca.cbor")
+	require.NoError(t, err)
+
+	c := comid.NewComid()
+	require.NoError(t, c.FromCBOR(data))
+	assert.Empty(t, checkArmComid(c, true))
+}
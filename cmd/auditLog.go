@@ -0,0 +1,97 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	auditResultSuccess = "success"
+	auditResultFailure = "failure"
+)
+
+// signAuditRecord is the JSON line appended to the audit log for each signing
+// operation
+type signAuditRecord struct {
+	Timestamp       string `json:"timestamp"`
+	Input           string `json:"input"`
+	Output          string `json:"output"`
+	Algorithm       string `json:"algorithm,omitempty"`
+	KeyThumbprint   string `json:"key-thumbprint,omitempty"`
+	CertFingerprint string `json:"cert-fingerprint,omitempty"`
+	Result          string `json:"result"`
+	Error           string `json:"error,omitempty"`
+}
+
+// signingKey describes the key a CoRIM was signed with, and the signing
+// certificate if any, as recorded in the audit log
+type signingKey struct {
+	alg  string
+	pub  crypto.PublicKey
+	cert *x509.Certificate
+}
+
+// newSignAuditRecord describes the signing of input into output using key.
+// The key and certificate details are those that were actually used (e.g., a
+// key from the SSH agent, or a certificate fetched from --cert-url), and are
+// left empty if the signing operation failed before they could be loaded.
+func newSignAuditRecord(input, output string, key signingKey, signErr error) signAuditRecord {
+	rec := signAuditRecord{
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Input:     input,
+		Output:    output,
+		Algorithm: key.alg,
+		Result:    auditResultSuccess,
+	}
+
+	if signErr != nil {
+		rec.Result = auditResultFailure
+		rec.Error = signErr.Error()
+	}
+
+	if key.pub != nil {
+		rec.KeyThumbprint, _ = publicKeyThumbprint(key.pub)
+	}
+
+	if key.cert != nil {
+		rec.CertFingerprint = sha256Thumbprint(key.cert.Raw)
+	}
+
+	return rec
+}
+
+// appendAuditRecord appends rec, as a single JSON line, to the audit log in
+// file, which is created if needed.  The file is exclusively locked while
+// writing, so that concurrent invocations do not interleave their records.
+func appendAuditRecord(file string, rec interface{}) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("error encoding audit record: %w", err)
+	}
+
+	f, err := fs.OpenFile(file, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("error opening audit log %s: %w", file, err)
+	}
+	defer f.Close()
+
+	if osf, ok := f.(*os.File); ok {
+		if err = lockFile(osf); err != nil {
+			return fmt.Errorf("error locking audit log %s: %w", file, err)
+		}
+		defer unlockFile(osf) // nolint: errcheck
+	}
+
+	if _, err = f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("error writing audit log %s: %w", file, err)
+	}
+
+	return nil
+}
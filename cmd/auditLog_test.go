@@ -0,0 +1,238 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readAuditLog decodes the JSON lines in the audit log file
+func readAuditLog(t *testing.T, file string) []signAuditRecord {
+	data, err := afero.ReadFile(fs, file)
+	require.NoError(t, err)
+
+	var recs []signAuditRecord
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var rec signAuditRecord
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &rec))
+		recs = append(recs, rec)
+	}
+
+	return recs
+}
+
+func Test_CorimSignCmd_audit_log(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "ok.cbor", testCorimValid, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ok.json", testMetaValid, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ok.jwk", testECKey, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "cert.der", testSigningCertificate, 0644)
+	require.NoError(t, err)
+
+	cmd := NewCorimSignCmd()
+	cmd.SetArgs([]string{
+		"--file=ok.cbor",
+		"--key=ok.jwk",
+		"--meta=ok.json",
+		"--cert=cert.der",
+		"--audit-log=audit.jsonl",
+	})
+
+	err = cmd.Execute()
+	require.NoError(t, err)
+
+	// a failed operation is logged too
+	cmd = NewCorimSignCmd()
+	cmd.SetArgs([]string{
+		"--file=ok.cbor",
+		"--key=ok.jwk",
+		"--meta=ok.json",
+		"--output=out.cbor",
+		"--allowed-algs=ES512",
+		"--audit-log=audit.jsonl",
+	})
+
+	err = cmd.Execute()
+	assert.EqualError(t, err,
+		"error signing CoRIM with key ok.jwk: algorithm policy violation: ES256 is not allowed (allowed: ES512)")
+
+	recs := readAuditLog(t, "audit.jsonl")
+	require.Len(t, recs, 2)
+
+	assert.NotEmpty(t, recs[0].Timestamp)
+	assert.Equal(t, "ok.cbor", recs[0].Input)
+	assert.Equal(t, "signed-ok.cbor", recs[0].Output)
+	assert.Equal(t, "ES256", recs[0].Algorithm)
+	assert.Regexp(t, "^sha-256;", recs[0].KeyThumbprint)
+	assert.Equal(t, sha256Thumbprint(testSigningCertificate), recs[0].CertFingerprint)
+	assert.Equal(t, auditResultSuccess, recs[0].Result)
+	assert.Empty(t, recs[0].Error)
+
+	assert.Equal(t, "out.cbor", recs[1].Output)
+	assert.Equal(t, recs[0].KeyThumbprint, recs[1].KeyThumbprint)
+	assert.Empty(t, recs[1].CertFingerprint)
+	assert.Equal(t, auditResultFailure, recs[1].Result)
+	assert.Equal(t,
+		"error signing CoRIM with key ok.jwk: algorithm policy violation: ES256 is not allowed (allowed: ES512)",
+		recs[1].Error)
+}
+
+func Test_CorimSignCmd_audit_log_bad_key(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "ok.cbor", testCorimValid, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ok.json", testMetaValid, 0644)
+	require.NoError(t, err)
+
+	cmd := NewCorimSignCmd()
+	cmd.SetArgs([]string{
+		"--file=ok.cbor",
+		"--key=missing.jwk",
+		"--meta=ok.json",
+		"--audit-log=audit.jsonl",
+	})
+
+	err = cmd.Execute()
+	assert.Error(t, err)
+
+	recs := readAuditLog(t, "audit.jsonl")
+	require.Len(t, recs, 1)
+	assert.Equal(t, auditResultFailure, recs[0].Result)
+	assert.Empty(t, recs[0].Algorithm)
+	assert.Empty(t, recs[0].KeyThumbprint)
+}
+
+func Test_CorimSignCmd_audit_log_empty_cert(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "ok.cbor", testCorimValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "ok.json", testMetaValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "ok.jwk", testECKey, 0644))
+	require.NoError(t, afero.WriteFile(fs, "empty.der", []byte{}, 0644))
+
+	cmd := NewCorimSignCmd()
+	cmd.SetArgs([]string{
+		"--file=ok.cbor",
+		"--key=ok.jwk",
+		"--meta=ok.json",
+		"--cert=empty.der",
+		"--audit-log=audit.jsonl",
+	})
+
+	assert.Error(t, cmd.Execute())
+
+	recs := readAuditLog(t, "audit.jsonl")
+	require.Len(t, recs, 1)
+	assert.Equal(t, auditResultFailure, recs[0].Result)
+	assert.Empty(t, recs[0].CertFingerprint)
+}
+
+func Test_CorimSignCmd_audit_log_ssh_agent(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+
+	useTestSSHAgent(t, map[string]crypto.Signer{"p384": key})
+
+	require.NoError(t, signWithSSHAgent(t, "--audit-log=audit.jsonl"))
+
+	recs := readAuditLog(t, "audit.jsonl")
+	require.Len(t, recs, 1)
+
+	// the details are those of the agent key, there being no key file
+	expected, err := publicKeyThumbprint(key.Public())
+	require.NoError(t, err)
+
+	assert.Equal(t, "ES384", recs[0].Algorithm)
+	assert.Equal(t, expected, recs[0].KeyThumbprint)
+	assert.Equal(t, auditResultSuccess, recs[0].Result)
+}
+
+func Test_CorimSignCmd_audit_log_container_cms(t *testing.T) {
+	certDER := newTestCMSCert(t, testECKey)
+
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "unsigned-corim.cbor", testCorimValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "key.jwk", testECKey, 0644))
+	require.NoError(t, afero.WriteFile(fs, "cert.der", certDER, 0644))
+
+	cmd := NewCorimSignCmd()
+	cmd.SetArgs([]string{"--file=unsigned-corim.cbor", "--key=key.jwk", "--cert=cert.der",
+		"--container=cms", "--audit-log=audit.jsonl"})
+	require.NoError(t, cmd.Execute())
+
+	recs := readAuditLog(t, "audit.jsonl")
+	require.Len(t, recs, 1)
+
+	assert.Equal(t, "signed-unsigned-corim.p7s", recs[0].Output)
+	assert.Equal(t, x509.ECDSAWithSHA256.String(), recs[0].Algorithm)
+	assert.Regexp(t, "^sha-256;", recs[0].KeyThumbprint)
+	assert.Equal(t, sha256Thumbprint(certDER), recs[0].CertFingerprint)
+}
+
+func Test_CorimSignCmd_audit_log_cert_url(t *testing.T) {
+	certDER := newTestCMSCert(t, testECKey)
+
+	ts := httptest.NewServer(&corimServer{data: certDER})
+	defer ts.Close()
+
+	fs = afero.NewMemMapFs()
+	_, err := signWithCertURL(t, ts.URL+"/signer.crt", "--audit-log=audit.jsonl")
+	require.NoError(t, err)
+
+	recs := readAuditLog(t, "audit.jsonl")
+	require.Len(t, recs, 1)
+
+	// the fingerprint is that of the fetched certificate
+	assert.Equal(t, sha256Thumbprint(certDER), recs[0].CertFingerprint)
+}
+
+func Test_appendAuditRecord_concurrent(t *testing.T) {
+	fs = afero.NewOsFs()
+	defer func() { fs = afero.NewMemMapFs() }()
+
+	file := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	const n = 50
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			rec := signAuditRecord{Input: fmt.Sprintf("corim-%d.cbor", i), Result: auditResultSuccess}
+			assert.NoError(t, appendAuditRecord(file, rec))
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Len(t, readAuditLog(t, file), n)
+}
+
+func Test_appendAuditRecord_bad_path(t *testing.T) {
+	fs = afero.NewReadOnlyFs(afero.NewMemMapFs())
+	defer func() { fs = afero.NewMemMapFs() }()
+
+	err := appendAuditRecord("audit.jsonl", signAuditRecord{})
+	assert.ErrorContains(t, err, "error opening audit log audit.jsonl")
+}
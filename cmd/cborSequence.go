@@ -0,0 +1,53 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// readCBORSequence decodes the CBOR sequence (RFC 8742) in file one item at a
+// time, calling fn with the index and the encoding of each item.  It returns
+// the number of items read.  As a CBOR sequence cannot be resynchronized, a
+// malformed item ends the iteration with an error.
+func readCBORSequence(file string, fn func(i int, item []byte)) (int, error) {
+	f, err := fs.Open(file)
+	if err != nil {
+		return 0, fmt.Errorf("error loading CBOR sequence from %s: %w", file, err)
+	}
+	defer f.Close()
+
+	return decodeCBORSequence(f, file, fn)
+}
+
+// decodeCBORSequence is readCBORSequence for the CBOR sequence read from r,
+// with file only used in the error messages
+func decodeCBORSequence(r io.Reader, file string, fn func(i int, item []byte)) (int, error) {
+	dec := cbor.NewDecoder(r)
+
+	for i := 0; ; i++ {
+		offset := dec.NumBytesRead()
+
+		var item cbor.RawMessage
+		if err := dec.Decode(&item); err != nil {
+			if errors.Is(err, io.EOF) {
+				return i, nil
+			}
+
+			return i, fmt.Errorf("error decoding CBOR sequence from %s: item %d at offset %d: %w", file, i, offset, err)
+		}
+
+		fn(i, item)
+	}
+}
+
+// sequenceItemName returns the name used to report on item i of the CBOR
+// sequence in file
+func sequenceItemName(file string, i int) string {
+	return fmt.Sprintf("%s[%d]", file, i)
+}
@@ -0,0 +1,102 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestSequence saves the concatenation of items to seq.cbor, together
+// with the key that verifies testSignedCorimValid
+func writeTestSequence(t *testing.T, items ...[]byte) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "seq.cbor", bytes.Join(items, nil), 0644))
+	require.NoError(t, afero.WriteFile(fs, "ok.jwk", testECKey, 0644))
+}
+
+func Test_readCBORSequence(t *testing.T) {
+	writeTestSequence(t, testSignedCorimValid, testCorimValid, []byte{0x01})
+
+	var items [][]byte
+
+	n, err := readCBORSequence("seq.cbor", func(i int, item []byte) {
+		assert.Equal(t, len(items), i)
+		items = append(items, item)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, [][]byte{testSignedCorimValid, testCorimValid, {0x01}}, items)
+}
+
+func Test_readCBORSequence_truncated(t *testing.T) {
+	writeTestSequence(t, testSignedCorimValid, testSignedCorimValid[:10])
+
+	n, err := readCBORSequence("seq.cbor", func(int, []byte) {})
+	assert.EqualError(t, err, fmt.Sprintf(
+		"error decoding CBOR sequence from seq.cbor: item 1 at offset %d: unexpected EOF", len(testSignedCorimValid)))
+	assert.Equal(t, 1, n)
+}
+
+func Test_CorimVerifyCmd_sequence_ok(t *testing.T) {
+	writeTestSequence(t, testSignedCorimValid, testSignedCorimValid)
+
+	cmd := NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=seq.cbor", "--sequence", "--key=ok.jwk"})
+	assert.NoError(t, cmd.Execute())
+}
+
+func Test_CorimVerifyCmd_sequence_some_failed(t *testing.T) {
+	writeTestSequence(t, testSignedCorimValid, testCorimValid, testSignedCorimValid)
+
+	cmd := NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=seq.cbor", "--sequence", "--key=ok.jwk"})
+	assert.EqualError(t, cmd.Execute(), "1/3 verification(s) failed")
+}
+
+func Test_CorimVerifyCmd_sequence_empty(t *testing.T) {
+	writeTestSequence(t)
+
+	cmd := NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=seq.cbor", "--sequence", "--key=ok.jwk"})
+	assert.EqualError(t, cmd.Execute(), "no signed CoRIMs found in seq.cbor")
+}
+
+func Test_CorimVerifyCmd_sequence_with_dir(t *testing.T) {
+	cmd := NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--dir=archive", "--sequence", "--key=ok.jwk"})
+	assert.EqualError(t, cmd.Execute(), "--sequence cannot be used together with --dir")
+}
+
+func Test_CorimDisplayCmd_sequence(t *testing.T) {
+	writeTestSequence(t, testSignedCorimValid, testCorimValid)
+
+	cmd := NewCorimDisplayCmd()
+	cmd.SetArgs([]string{"--file=seq.cbor", "--sequence", "--output=seq.txt"})
+	require.NoError(t, cmd.Execute())
+
+	out, err := afero.ReadFile(fs, "seq.txt")
+	require.NoError(t, err)
+	assert.Contains(t, string(out), ">> seq.cbor[0]:\nMeta:")
+	assert.Contains(t, string(out), ">> seq.cbor[1]:\nCorim:")
+}
+
+func Test_CorimDisplayCmd_sequence_some_failed(t *testing.T) {
+	writeTestSequence(t, testSignedCorimValid, []byte{0x01})
+
+	cmd := NewCorimDisplayCmd()
+	cmd.SetArgs([]string{"--file=seq.cbor", "--sequence"})
+	assert.EqualError(t, cmd.Execute(), "1/2 display(s) failed")
+}
+
+func Test_CorimDisplayCmd_sequence_with_flat(t *testing.T) {
+	cmd := NewCorimDisplayCmd()
+	cmd.SetArgs([]string{"--file=seq.cbor", "--sequence", "--measurements-flat"})
+	assert.EqualError(t, cmd.Execute(), "--measurements-flat cannot be used together with --sequence")
+}
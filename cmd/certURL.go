@@ -0,0 +1,61 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"crypto"
+	"fmt"
+
+	"github.com/veraison/apiclient/auth"
+	"github.com/veraison/corim/corim"
+)
+
+// newRemoteCert returns a fetcher for the DER or PEM certificates published at
+// rawURL, the value of "corim sign --cert-url"
+func newRemoteCert(rawURL, cacheDir string, a auth.IAuthenticator) (*remoteCorim, error) {
+	if err := checkHTTPURL("--cert-url", rawURL); err != nil {
+		return nil, err
+	}
+
+	return &remoteCorim{
+		url:      rawURL,
+		cacheDir: cacheDir,
+		auth:     a,
+		what:     "certificate",
+		accept:   "application/pkix-cert, application/pem-certificate-chain, */*;q=0.5",
+		cacheExt: ".crt",
+	}, nil
+}
+
+// addCertURL fetches the certificates at rawURL (see remoteCorim) and adds
+// them to s, like a --cert-chain file: the first one as the signing
+// certificate and the others as the intermediates.  The signing certificate
+// must certify pub, the public part of the signing key, so that a rotated
+// certificate that no longer matches the key is caught before signing.
+func addCertURL(s *corim.SignedCorim, rawURL, cacheDir string, pub crypto.PublicKey) error {
+	r, err := newRemoteCert(rawURL, cacheDir, cliConfig.Auth)
+	if err != nil {
+		return err
+	}
+
+	data, err := r.fetch()
+	if err != nil {
+		return err
+	}
+
+	certs, err := parseCertificates(data)
+	if err != nil {
+		return fmt.Errorf("error decoding certificates fetched from %s: %w", rawURL, err)
+	}
+
+	if len(certs) == 0 {
+		return fmt.Errorf("error decoding certificates fetched from %s: no certificate found", rawURL)
+	}
+
+	if k, ok := pub.(interface{ Equal(crypto.PublicKey) bool }); !ok || !k.Equal(certs[0].PublicKey) {
+		return fmt.Errorf("the signing key does not match the certificate fetched from %s", rawURL)
+	}
+
+	return addCertChain(s, certs)
+}
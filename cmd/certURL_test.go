@@ -0,0 +1,157 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/corim"
+)
+
+// signWithCertURL signs testCorimValid with testECKey and the certificate
+// served at url, and returns the resulting signed CoRIM
+func signWithCertURL(t *testing.T, url string, extraArgs ...string) (*corim.SignedCorim, error) {
+	require.NoError(t, afero.WriteFile(fs, "unsigned.cbor", testCorimValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "key.jwk", testECKey, 0644))
+	require.NoError(t, afero.WriteFile(fs, "meta.json", testMetaValid, 0644))
+
+	cmd := NewCorimSignCmd()
+	cmd.SetArgs(append([]string{
+		"--file=unsigned.cbor",
+		"--key=key.jwk",
+		"--meta=meta.json",
+		"--output=signed.cbor",
+		"--cert-url=" + url,
+	}, extraArgs...))
+
+	if err := cmd.Execute(); err != nil {
+		return nil, err
+	}
+
+	var s corim.SignedCorim
+	require.NoError(t, s.FromCOSE(mustReadFile(t, "signed.cbor")))
+
+	return &s, nil
+}
+
+func Test_CorimSignCmd_cert_url_der(t *testing.T) {
+	certDER := newTestCMSCert(t, testECKey)
+
+	srv := &corimServer{data: certDER}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	s, err := signWithCertURL(t, ts.URL+"/signer.crt")
+	require.NoError(t, err)
+
+	require.NotNil(t, s.SigningCert)
+	assert.Equal(t, certDER, s.SigningCert.Raw)
+	assert.Empty(t, s.IntermediateCerts)
+
+	require.Len(t, srv.requests, 1)
+	assert.Contains(t, srv.requests[0].Get("Accept"), "application/pkix-cert")
+}
+
+func Test_CorimSignCmd_cert_url_pem_chain(t *testing.T) {
+	certDER := newTestCMSCert(t, testECKey)
+	otherDER := newTestCMSCert(t, testEdDSAKey)
+
+	chain := append(
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: otherDER})...,
+	)
+
+	ts := httptest.NewServer(&corimServer{data: chain})
+	defer ts.Close()
+
+	s, err := signWithCertURL(t, ts.URL+"/chain.pem")
+	require.NoError(t, err)
+
+	assert.Equal(t, certDER, s.SigningCert.Raw)
+	require.Len(t, s.IntermediateCerts, 1)
+	assert.Equal(t, otherDER, s.IntermediateCerts[0].Raw)
+}
+
+func Test_CorimSignCmd_cert_url_key_mismatch(t *testing.T) {
+	ts := httptest.NewServer(&corimServer{data: newTestCMSCert(t, testEdDSAKey)})
+	defer ts.Close()
+
+	_, err := signWithCertURL(t, ts.URL+"/signer.crt")
+	assert.EqualError(t, err, "the signing key does not match the certificate fetched from "+ts.URL+"/signer.crt")
+}
+
+func Test_CorimSignCmd_cert_url_not_found(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	defer ts.Close()
+
+	fs = afero.NewMemMapFs()
+
+	_, err := signWithCertURL(t, ts.URL+"/signer.crt")
+	assert.EqualError(t, err, "error fetching "+ts.URL+`/signer.crt: unexpected HTTP status "404 Not Found"`)
+}
+
+func Test_CorimSignCmd_cert_url_cache(t *testing.T) {
+	certDER := newTestCMSCert(t, testECKey)
+
+	srv := &corimServer{data: certDER}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	for i := 0; i < 2; i++ {
+		s, err := signWithCertURL(t, ts.URL+"/signer.crt", "--cert-cache-dir=cache.d")
+		require.NoError(t, err)
+		assert.Equal(t, certDER, s.SigningCert.Raw)
+	}
+
+	assert.Equal(t, []int{http.StatusOK, http.StatusNotModified}, srv.statuses)
+}
+
+func Test_CorimSignCmd_cert_url_bad_args(t *testing.T) {
+	tvs := []struct {
+		desc     string
+		args     []string
+		expected string
+	}{
+		{
+			desc:     "cert file too",
+			args:     []string{"--cert-url=https://example.com/signer.crt", "--cert=cert.der"},
+			expected: "--cert-url cannot be used together with --cert, --cert-chain or --intermediates",
+		},
+		{
+			desc:     "intermediates",
+			args:     []string{"--cert-url=https://example.com/signer.crt", "--intermediates=int.der"},
+			expected: "--cert-url cannot be used together with --cert, --cert-chain or --intermediates",
+		},
+		{
+			desc:     "not http",
+			args:     []string{"--cert-url=file:///etc/signer.crt"},
+			expected: `invalid --cert-url "file:///etc/signer.crt": expecting an http or https URL`,
+		},
+		{
+			desc:     "cache without url",
+			args:     []string{"--cert-cache-dir=cache.d"},
+			expected: "--cert-cache-dir can only be used together with --cert-url",
+		},
+		{
+			desc:     "sign only if changed",
+			args:     []string{"--cert-url=https://example.com/signer.crt", "--sign-only-if-changed"},
+			expected: "--sign-only-if-changed cannot be used together with --cert-url",
+		},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.desc, func(t *testing.T) {
+			cmd := NewCorimSignCmd()
+			cmd.SetArgs(append([]string{"--file=ok.cbor", "--key=ok.jwk", "--meta=ok.json"}, tv.args...))
+
+			assert.EqualError(t, cmd.Execute(), tv.expected)
+		})
+	}
+}
@@ -0,0 +1,337 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/veraison/corim/corim"
+)
+
+// parseCertificates decodes one or more X.509 certificates, supplied either as
+// a sequence of PEM "CERTIFICATE" blocks or as concatenated DER
+func parseCertificates(data []byte) ([]*x509.Certificate, error) {
+	if !bytes.Contains(data, []byte("-----BEGIN")) {
+		return x509.ParseCertificates(data)
+	}
+
+	var certs []*x509.Certificate
+
+	for {
+		var block *pem.Block
+
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		return nil, errors.New("no PEM-encoded certificate found")
+	}
+
+	return certs, nil
+}
+
+// systemCertPool returns the system certificate pool, replaced in tests
+var systemCertPool = x509.SystemCertPool
+
+// loadTrustAnchors creates a certificate pool from the supplied trust anchor
+// files and, if systemRoots is set, the system certificate pool.  If the latter
+// is unavailable, a warning is printed (see warnf).
+func loadTrustAnchors(files []string, systemRoots, quiet bool) (*x509.CertPool, error) {
+	roots := x509.NewCertPool()
+
+	if systemRoots {
+		pool, err := systemCertPool()
+		switch {
+		case err == nil:
+			roots = pool
+		case len(files) == 0:
+			return nil, fmt.Errorf("system certificate pool unavailable: %w", err)
+		default:
+			warnf(quiet, ">> warning: system certificate pool unavailable, using explicit trust anchors only: %v\n", err)
+		}
+	}
+
+	for _, file := range files {
+		data, err := afero.ReadFile(fs, file)
+		if err != nil {
+			return nil, fmt.Errorf("error loading trust anchor from %s: %w", file, err)
+		}
+
+		certs, err := parseCertificates(data)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding trust anchor from %s: %w", file, err)
+		}
+
+		for _, cert := range certs {
+			roots.AddCert(cert)
+		}
+	}
+
+	return roots, nil
+}
+
+// verifyCertChain checks that the signing certificate carried in the COSE
+// header of s chains up to one of the supplied roots, possibly via the
+// intermediate certificates also found in the header
+func verifyCertChain(s *corim.SignedCorim, roots *x509.CertPool) error {
+	if s.SigningCert == nil {
+		return errors.New("no signing certificate found in COSE header")
+	}
+
+	return verifyCertificates(s.SigningCert, s.IntermediateCerts, roots)
+}
+
+// verifyCertificates checks that cert chains up to one of the supplied roots,
+// possibly via the supplied intermediate certificates
+func verifyCertificates(cert *x509.Certificate, intermediateCerts []*x509.Certificate, roots *x509.CertPool) error {
+	intermediates := x509.NewCertPool()
+	for _, c := range intermediateCerts {
+		intermediates.AddCert(c)
+	}
+
+	opts := x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}
+
+	if _, err := cert.Verify(opts); err != nil {
+		return fmt.Errorf("certificate chain validation failed: %w", err)
+	}
+
+	return nil
+}
+
+// parseX5Chain decodes the value of an x5chain COSE header (RFC 9360), either a
+// single DER-encoded certificate or an array of them, the signing certificate
+// first
+func parseX5Chain(v interface{}) ([]*x509.Certificate, error) {
+	var ders [][]byte
+
+	switch t := v.(type) {
+	case []byte:
+		ders = [][]byte{t}
+	case []interface{}:
+		for i, e := range t {
+			der, ok := e.([]byte)
+			if !ok {
+				return nil, fmt.Errorf("x5chain[%d]: expecting a byte string, got %T", i, e)
+			}
+			ders = append(ders, der)
+		}
+	default:
+		return nil, fmt.Errorf("x5chain: expecting a byte string or an array of byte strings, got %T", v)
+	}
+
+	if len(ders) == 0 {
+		return nil, errors.New("x5chain: no certificate found")
+	}
+
+	certs := make([]*x509.Certificate, 0, len(ders))
+
+	for i, der := range ders {
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, fmt.Errorf("x5chain[%d]: %w", i, err)
+		}
+		certs = append(certs, cert)
+	}
+
+	return certs, nil
+}
+
+// isSelfSigned tells whether cert is issued by itself, i.e., whether its issuer
+// is its subject and its signature verifies with its own public key
+func isSelfSigned(cert *x509.Certificate) bool {
+	if !bytes.Equal(cert.RawIssuer, cert.RawSubject) {
+		return false
+	}
+
+	return cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature) == nil
+}
+
+// addIntermediateCertFiles loads the DER-encoded intermediate certificates
+// found in files and adds them to s, in the order given, following the signing
+// certificate
+func addIntermediateCertFiles(s *corim.SignedCorim, files []string) error {
+	var chain []byte
+
+	for _, file := range files {
+		der, err := afero.ReadFile(fs, file)
+		if err != nil {
+			return fmt.Errorf("error loading intermediate certificates from %s: %w", file, err)
+		}
+
+		if _, err = x509.ParseCertificates(der); err != nil {
+			return fmt.Errorf("error adding intermediate certificates from %s: %w", file, err)
+		}
+
+		chain = append(chain, der...)
+	}
+
+	if err := s.AddIntermediateCerts(chain); err != nil {
+		return fmt.Errorf("error adding intermediate certificates: %w", err)
+	}
+
+	return nil
+}
+
+// addCertChainFile loads the certificate chain in file, a sequence of PEM
+// "CERTIFICATE" blocks (or concatenated DER), and adds it to s.  The first
+// certificate is used as the signing certificate and the others as the
+// intermediates, in the order in which they appear.
+func addCertChainFile(s *corim.SignedCorim, file string) error {
+	data, err := afero.ReadFile(fs, file)
+	if err != nil {
+		return fmt.Errorf("error loading certificate chain from %s: %w", file, err)
+	}
+
+	certs, err := parseCertificates(data)
+	if err != nil {
+		return fmt.Errorf("error decoding certificate chain from %s: %w", file, err)
+	}
+
+	if len(certs) == 0 {
+		return fmt.Errorf("error decoding certificate chain from %s: no certificate found", file)
+	}
+
+	return addCertChain(s, certs)
+}
+
+// addCertChain adds certs, which must not be empty, to s: the first
+// certificate as the signing certificate and the others as the intermediates
+func addCertChain(s *corim.SignedCorim, certs []*x509.Certificate) error {
+	if err := s.AddSigningCert(certs[0].Raw); err != nil {
+		return fmt.Errorf("error adding signing certificate: %w", err)
+	}
+
+	if len(certs) == 1 {
+		return nil
+	}
+
+	var chain []byte
+	for _, cert := range certs[1:] {
+		chain = append(chain, cert.Raw...)
+	}
+
+	if err := s.AddIntermediateCerts(chain); err != nil {
+		return fmt.Errorf("error adding intermediate certificates: %w", err)
+	}
+
+	return nil
+}
+
+// printCertChain prints the certificates carried in the COSE x5chain header
+// of s to w, signing certificate first and then the intermediates in the order
+// in which they appear.  Validity timestamps are rendered in loc (UTC if nil).
+func printCertChain(w io.Writer, s *corim.SignedCorim, loc *time.Location) {
+	if s.SigningCert == nil {
+		fmt.Fprintln(w, ">> no certificate chain found in COSE header")
+		return
+	}
+
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	chain := append([]*x509.Certificate{s.SigningCert}, s.IntermediateCerts...)
+
+	fmt.Fprintf(w, ">> certificate chain (%d certificate(s)):\n", len(chain))
+
+	for i, cert := range chain {
+		role := "intermediate"
+		if i == 0 {
+			role = "signing certificate"
+		}
+
+		fp := sha256.Sum256(cert.Raw)
+
+		fmt.Fprintf(w, "[%d] %s\n", i, role)
+		fmt.Fprintf(w, "    subject:     %s\n", cert.Subject)
+		fmt.Fprintf(w, "    issuer:      %s\n", cert.Issuer)
+		fmt.Fprintf(w, "    serial:      %s\n", colonHex(cert.SerialNumber.Bytes()))
+		fmt.Fprintf(w, "    not-before:  %s\n", cert.NotBefore.In(loc).Format(time.RFC3339))
+		fmt.Fprintf(w, "    not-after:   %s\n", cert.NotAfter.In(loc).Format(time.RFC3339))
+		fmt.Fprintf(w, "    sha-256:     %s\n", colonHex(fp[:]))
+	}
+}
+
+// colonHex renders b as colon-separated, upper-case hex bytes, the way openssl
+// prints serial numbers and fingerprints
+func colonHex(b []byte) string {
+	if len(b) == 0 {
+		return "00"
+	}
+
+	parts := make([]string, len(b))
+	for i, v := range b {
+		parts[i] = fmt.Sprintf("%02X", v)
+	}
+
+	return strings.Join(parts, ":")
+}
+
+// checkSigningKeyUsage checks that the key usage and extended key usage
+// extensions of the signing certificate cert, if present, permit signing
+// CoRIMs, i.e., that the key usage includes digitalSignature and that the
+// extended key usage includes either codeSigning or any.  The offending usages
+// are reported.
+func checkSigningKeyUsage(cert *x509.Certificate) error {
+	var problems []string
+
+	// a zero KeyUsage means that the extension is absent, i.e., unrestricted
+	if cert.KeyUsage != 0 && cert.KeyUsage&x509.KeyUsageDigitalSignature == 0 {
+		problems = append(problems, fmt.Sprintf(
+			"key usage (%s) does not include digitalSignature",
+			strings.Join(keyUsageBitNames(cert.KeyUsage), ", "),
+		))
+	}
+
+	if len(cert.ExtKeyUsage) != 0 && !permitsCodeSigning(cert.ExtKeyUsage) {
+		problems = append(problems, fmt.Sprintf(
+			"extended key usage (%s) includes neither codeSigning nor any",
+			strings.Join(extKeyUsageList(cert.ExtKeyUsage), ", "),
+		))
+	}
+
+	if len(problems) != 0 {
+		return fmt.Errorf("signing certificate %q is not suitable for signing CoRIMs: %s",
+			cert.Subject.String(), strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+func permitsCodeSigning(ekus []x509.ExtKeyUsage) bool {
+	for _, eku := range ekus {
+		if eku == x509.ExtKeyUsageAny || eku == x509.ExtKeyUsageCodeSigning {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,222 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/corim"
+	cose "github.com/veraison/go-cose"
+)
+
+// testPKI is a throw-away root -> intermediate -> leaf hierarchy
+type testPKI struct {
+	rootDER         []byte
+	intermediateDER []byte
+	leafDER         []byte
+	leafKey         *ecdsa.PrivateKey
+}
+
+func newTestCert(
+	t *testing.T, cn string, isCA bool, keyUsage x509.KeyUsage,
+	parent *x509.Certificate, parentKey *ecdsa.PrivateKey, extKeyUsage ...x509.ExtKeyUsage,
+) ([]byte, *x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              keyUsage,
+		ExtKeyUsage:           extKeyUsage,
+	}
+
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return der, cert, key
+}
+
+func newTestPKI(t *testing.T) testPKI {
+	return newTestPKIWithLeafUsage(t, x509.KeyUsageDigitalSignature)
+}
+
+// newTestPKIWithLeafUsage is like newTestPKI, with the key usage and extended
+// key usage of the leaf certificate set as supplied
+func newTestPKIWithLeafUsage(t *testing.T, keyUsage x509.KeyUsage, extKeyUsage ...x509.ExtKeyUsage) testPKI {
+	var pki testPKI
+
+	caUsage := x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+
+	rootDER, root, rootKey := newTestCert(t, "Test Root", true, caUsage, nil, nil)
+	interDER, inter, interKey := newTestCert(t, "Test Intermediate", true, caUsage, root, rootKey)
+	leafDER, _, leafKey := newTestCert(t, "Test Signer", false, keyUsage, inter, interKey, extKeyUsage...)
+
+	pki.rootDER = rootDER
+	pki.intermediateDER = interDER
+	pki.leafDER = leafDER
+	pki.leafKey = leafKey
+
+	return pki
+}
+
+func (o testPKI) rootPEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: o.rootDER})
+}
+
+// signedCorim signs testCorimValid with the leaf key of the test PKI and embeds
+// the leaf and intermediate certificates in the COSE header
+func (o testPKI) signedCorim(t *testing.T) []byte {
+	var s corim.SignedCorim
+
+	require.NoError(t, s.UnsignedCorim.FromCBOR(testCorimValid))
+	require.NoError(t, s.Meta.FromJSON(testMetaValid))
+	require.NoError(t, s.AddSigningCert(o.leafDER))
+	require.NoError(t, s.AddIntermediateCerts(o.intermediateDER))
+
+	signer, err := cose.NewSigner(cose.AlgorithmES256, o.leafKey)
+	require.NoError(t, err)
+
+	data, err := s.Sign(signer)
+	require.NoError(t, err)
+
+	return data
+}
+
+func Test_parseCertificates(t *testing.T) {
+	pki := newTestPKI(t)
+
+	certs, err := parseCertificates(pki.rootDER)
+	require.NoError(t, err)
+	assert.Len(t, certs, 1)
+
+	certs, err = parseCertificates(append(pki.rootPEM(), pki.rootPEM()...))
+	require.NoError(t, err)
+	assert.Len(t, certs, 2)
+
+	_, err = parseCertificates([]byte("-----BEGIN PUBLIC KEY-----\n-----END PUBLIC KEY-----\n"))
+	assert.EqualError(t, err, "no PEM-encoded certificate found")
+}
+
+func Test_colonHex(t *testing.T) {
+	assert.Equal(t, "00", colonHex(nil))
+	assert.Equal(t, "0A", colonHex([]byte{0x0a}))
+	assert.Equal(t, "DE:AD:BE:EF", colonHex([]byte{0xde, 0xad, 0xbe, 0xef}))
+}
+
+func Test_checkSigningKeyUsage(t *testing.T) {
+	tvs := []struct {
+		desc        string
+		keyUsage    x509.KeyUsage
+		extKeyUsage []x509.ExtKeyUsage
+		expected    string
+	}{
+		{
+			desc: "unrestricted",
+		},
+		{
+			desc:     "digitalSignature",
+			keyUsage: x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		},
+		{
+			desc:        "codeSigning",
+			keyUsage:    x509.KeyUsageDigitalSignature,
+			extKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageCodeSigning},
+		},
+		{
+			desc:        "any",
+			extKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		},
+		{
+			desc:     "no digitalSignature",
+			keyUsage: x509.KeyUsageKeyEncipherment | x509.KeyUsageCertSign,
+			expected: `signing certificate "CN=Test Signer" is not suitable for signing CoRIMs: key usage (keyEncipherment, keyCertSign) does not include digitalSignature`,
+		},
+		{
+			desc:        "no codeSigning",
+			keyUsage:    x509.KeyUsageDigitalSignature,
+			extKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+			expected:    `signing certificate "CN=Test Signer" is not suitable for signing CoRIMs: extended key usage (serverAuth, clientAuth) includes neither codeSigning nor any`,
+		},
+		{
+			desc:        "neither",
+			keyUsage:    x509.KeyUsageKeyAgreement,
+			extKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageEmailProtection},
+			expected: `signing certificate "CN=Test Signer" is not suitable for signing CoRIMs: key usage (keyAgreement) does not include digitalSignature; ` +
+				`extended key usage (emailProtection) includes neither codeSigning nor any`,
+		},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.desc, func(t *testing.T) {
+			_, cert, _ := newTestCert(t, "Test Signer", false, tv.keyUsage, nil, nil, tv.extKeyUsage...)
+
+			err := checkSigningKeyUsage(cert)
+			if tv.expected == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tv.expected)
+			}
+		})
+	}
+}
+
+func Test_isSelfSigned(t *testing.T) {
+	pki := newTestPKI(t)
+
+	root, err := x509.ParseCertificate(pki.rootDER)
+	require.NoError(t, err)
+	assert.True(t, isSelfSigned(root))
+
+	leaf, err := x509.ParseCertificate(pki.leafDER)
+	require.NoError(t, err)
+	assert.False(t, isSelfSigned(leaf))
+
+	// a self-signed end-entity certificate, as typically used in development
+	_, dev, _ := newTestCert(t, "Dev Signer", false, x509.KeyUsageDigitalSignature, nil, nil)
+	assert.True(t, isSelfSigned(dev))
+}
+
+func Test_parseX5Chain(t *testing.T) {
+	pki := newTestPKI(t)
+
+	certs, err := parseX5Chain(pki.leafDER)
+	require.NoError(t, err)
+	assert.Len(t, certs, 1)
+
+	certs, err = parseX5Chain([]interface{}{pki.leafDER, pki.intermediateDER})
+	require.NoError(t, err)
+	require.Len(t, certs, 2)
+	assert.Equal(t, "Test Intermediate", certs[1].Subject.CommonName)
+
+	_, err = parseX5Chain([]interface{}{pki.leafDER, "nope"})
+	assert.EqualError(t, err, "x5chain[1]: expecting a byte string, got string")
+
+	_, err = parseX5Chain(42)
+	assert.EqualError(t, err, "x5chain: expecting a byte string or an array of byte strings, got int")
+
+	_, err = parseX5Chain([]interface{}{})
+	assert.EqualError(t, err, "x5chain: no certificate found")
+}
@@ -0,0 +1,396 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+	"path/filepath"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/spf13/afero"
+	"github.com/veraison/corim/corim"
+)
+
+// the values accepted by corim sign --container
+const (
+	signContainerCOSE = "cose"
+	signContainerCMS  = "cms"
+)
+
+// CMS (RFC 5652) object identifiers
+var (
+	oidSignedData        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidData              = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidAttrContentType   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidAttrMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidSHA256            = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSHA384            = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
+	oidSHA512            = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+	oidECDSAWithSHA256   = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+	oidECDSAWithSHA384   = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 3}
+	oidECDSAWithSHA512   = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 4}
+	oidSHA256WithRSA     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}
+	oidEd25519           = asn1.ObjectIdentifier{1, 3, 101, 112}
+)
+
+const (
+	// the SignedData and SignerInfo versions, for id-data content and an
+	// issuerAndSerialNumber signer identifier
+	cmsSignedDataVersion = 1
+	cmsSignerInfoVersion = 1
+	// the tag of the (IMPLICIT [0]) certificates of a SignedData
+	cmsCertificatesTag = 0
+	// the encoding of the IMPLICIT [0] tag of the signed attributes
+	cmsSignedAttrsTag = 0xa0
+)
+
+// cmsContentInfo is a CMS ContentInfo (RFC 5652, Section 3)
+type cmsContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	// [0] EXPLICIT
+	Content asn1.RawValue
+}
+
+// cmsSignedData is a CMS SignedData (RFC 5652, Section 5.1)
+type cmsSignedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	EncapContentInfo cmsEncapContentInfo
+	Certificates     asn1.RawValue   `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue   `asn1:"optional,tag:1"`
+	SignerInfos      []cmsSignerInfo `asn1:"set"`
+}
+
+// cmsEncapContentInfo is a CMS EncapsulatedContentInfo (RFC 5652, Section 5.2)
+type cmsEncapContentInfo struct {
+	EContentType asn1.ObjectIdentifier
+	EContent     []byte `asn1:"explicit,optional,tag:0"`
+}
+
+// cmsSignerInfo is a CMS SignerInfo (RFC 5652, Section 5.3).  The signer
+// identifier (SID) is either an issuerAndSerialNumber or an IMPLICIT [0]
+// subjectKeyIdentifier.
+type cmsSignerInfo struct {
+	Version            int
+	SID                asn1.RawValue
+	DigestAlgorithm    pkix.AlgorithmIdentifier
+	SignedAttrs        asn1.RawValue `asn1:"optional,tag:0"`
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          []byte
+	UnsignedAttrs      asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+type cmsIssuerAndSerialNumber struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+type cmsAttribute struct {
+	Type   asn1.ObjectIdentifier
+	Values []asn1.RawValue `asn1:"set"`
+}
+
+// cmsAlgorithms are the digest and signature algorithms used with a key
+type cmsAlgorithms struct {
+	hash      crypto.Hash
+	digestOID asn1.ObjectIdentifier
+	sigOID    asn1.ObjectIdentifier
+}
+
+// cmsAlgorithmsFor returns the CMS algorithms used with the public key pub:
+// ECDSA with the SHA-2 hash matching the curve, RSA PKCS #1 v1.5 with SHA-256
+// (which legacy verifiers understand best), or Ed25519 (RFC 8419)
+func cmsAlgorithmsFor(pub crypto.PublicKey) (cmsAlgorithms, error) {
+	switch k := pub.(type) {
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return cmsAlgorithms{crypto.SHA256, oidSHA256, oidECDSAWithSHA256}, nil
+		case elliptic.P384():
+			return cmsAlgorithms{crypto.SHA384, oidSHA384, oidECDSAWithSHA384}, nil
+		case elliptic.P521():
+			return cmsAlgorithms{crypto.SHA512, oidSHA512, oidECDSAWithSHA512}, nil
+		}
+
+		return cmsAlgorithms{}, fmt.Errorf("unsupported elliptic curve %s", k.Curve.Params().Name)
+	case *rsa.PublicKey:
+		return cmsAlgorithms{crypto.SHA256, oidSHA256, oidSHA256WithRSA}, nil
+	case ed25519.PublicKey:
+		return cmsAlgorithms{crypto.SHA512, oidSHA512, oidEd25519}, nil
+	}
+
+	return cmsAlgorithms{}, fmt.Errorf("unsupported key type %T", pub)
+}
+
+// loadCMSSigner loads the private key in JWK format from keyFile
+func loadCMSSigner(keyFile string) (crypto.Signer, error) {
+	keyJWK, err := afero.ReadFile(fs, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("error loading signing key from %s: %w", keyFile, err)
+	}
+
+	k, err := jwk.ParseKey(keyJWK)
+	if err != nil {
+		return nil, fmt.Errorf("error loading signing key from %s: %w", keyFile, err)
+	}
+
+	var raw interface{}
+	if err = k.Raw(&raw); err != nil {
+		return nil, fmt.Errorf("error loading signing key from %s: %w", keyFile, err)
+	}
+
+	signer, ok := raw.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("error loading signing key from %s: not a private key", keyFile)
+	}
+
+	return signer, nil
+}
+
+// loadCMSCertificates returns the signing certificate, followed by the
+// intermediates, from certFile and intermediatesFiles (DER) or from certChain
+// (PEM or DER)
+func loadCMSCertificates(certFile string, intermediatesFiles []string, certChain string) ([]*x509.Certificate, error) {
+	if certChain != "" {
+		data, err := afero.ReadFile(fs, certChain)
+		if err != nil {
+			return nil, fmt.Errorf("error loading certificate chain from %s: %w", certChain, err)
+		}
+
+		certs, err := parseCertificates(data)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding certificate chain from %s: %w", certChain, err)
+		}
+
+		if len(certs) == 0 {
+			return nil, fmt.Errorf("error decoding certificate chain from %s: no certificate found", certChain)
+		}
+
+		return certs, nil
+	}
+
+	der, err := afero.ReadFile(fs, certFile)
+	if err != nil {
+		return nil, fmt.Errorf("error loading signing certificate from %s: %w", certFile, err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding signing certificate from %s: %w", certFile, err)
+	}
+
+	certs := []*x509.Certificate{cert}
+
+	for _, file := range intermediatesFiles {
+		der, err := afero.ReadFile(fs, file)
+		if err != nil {
+			return nil, fmt.Errorf("error loading intermediate certificates from %s: %w", file, err)
+		}
+
+		intermediates, err := x509.ParseCertificates(der)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding intermediate certificates from %s: %w", file, err)
+		}
+
+		certs = append(certs, intermediates...)
+	}
+
+	return certs, nil
+}
+
+// cmsSignedCorim returns the DER-encoded CMS SignedData (wrapped in a
+// ContentInfo) carrying content, an unsigned CoRIM, as its id-data
+// encapsulated content.  The signature, by signer, covers the content-type and
+// message-digest signed attributes.  The certificates, signing certificate
+// first, are included in the SignedData.
+func cmsSignedCorim(content []byte, signer crypto.Signer, certs []*x509.Certificate) ([]byte, error) {
+	if len(certs) == 0 {
+		return nil, errors.New("no signing certificate")
+	}
+
+	pub := signer.Public()
+
+	if k, ok := pub.(interface{ Equal(crypto.PublicKey) bool }); !ok || !k.Equal(certs[0].PublicKey) {
+		return nil, errors.New("the signing key does not match the signing certificate")
+	}
+
+	algs, err := cmsAlgorithmsFor(pub)
+	if err != nil {
+		return nil, err
+	}
+
+	h := algs.hash.New()
+	h.Write(content)
+
+	contentType, err := asn1.Marshal(oidData)
+	if err != nil {
+		return nil, err
+	}
+
+	messageDigest, err := asn1.Marshal(h.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+
+	// encoding/asn1 sorts the elements of a SET OF, as DER requires
+	attrs, err := asn1.MarshalWithParams([]cmsAttribute{
+		{Type: oidAttrContentType, Values: []asn1.RawValue{{FullBytes: contentType}}},
+		{Type: oidAttrMessageDigest, Values: []asn1.RawValue{{FullBytes: messageDigest}}},
+	}, "set")
+	if err != nil {
+		return nil, fmt.Errorf("encoding signed attributes: %w", err)
+	}
+
+	// the signature is computed over the SET OF encoding of the signed
+	// attributes (RFC 5652, Section 5.4), which are then carried with an
+	// IMPLICIT [0] tag instead
+	var sig []byte
+
+	if algs.sigOID.Equal(oidEd25519) {
+		sig, err = signer.Sign(rand.Reader, attrs, crypto.Hash(0))
+	} else {
+		ah := algs.hash.New()
+		ah.Write(attrs)
+		sig, err = signer.Sign(rand.Reader, ah.Sum(nil), algs.hash)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("signing: %w", err)
+	}
+
+	signedAttrs := append([]byte{cmsSignedAttrsTag}, attrs[1:]...)
+
+	var raw []byte
+	for _, cert := range certs {
+		raw = append(raw, cert.Raw...)
+	}
+
+	digestAlg := pkix.AlgorithmIdentifier{Algorithm: algs.digestOID}
+
+	sigAlg := pkix.AlgorithmIdentifier{Algorithm: algs.sigOID}
+	if algs.sigOID.Equal(oidSHA256WithRSA) {
+		// RFC 4055, Section 5
+		sigAlg.Parameters = asn1.NullRawValue
+	}
+
+	sid, err := asn1.Marshal(cmsIssuerAndSerialNumber{
+		Issuer:       asn1.RawValue{FullBytes: certs[0].RawIssuer},
+		SerialNumber: certs[0].SerialNumber,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("encoding signer identifier: %w", err)
+	}
+
+	sd := cmsSignedData{
+		Version:          cmsSignedDataVersion,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{digestAlg},
+		EncapContentInfo: cmsEncapContentInfo{EContentType: oidData, EContent: content},
+		Certificates:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: cmsCertificatesTag, IsCompound: true, Bytes: raw},
+		SignerInfos: []cmsSignerInfo{
+			{
+				Version:            cmsSignerInfoVersion,
+				SID:                asn1.RawValue{FullBytes: sid},
+				DigestAlgorithm:    digestAlg,
+				SignedAttrs:        asn1.RawValue{FullBytes: signedAttrs},
+				SignatureAlgorithm: sigAlg,
+				Signature:          sig,
+			},
+		},
+	}
+
+	sdDER, err := asn1.Marshal(sd)
+	if err != nil {
+		return nil, fmt.Errorf("encoding SignedData: %w", err)
+	}
+
+	return asn1.Marshal(cmsContentInfo{
+		ContentType: oidSignedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: sdDER},
+	})
+}
+
+// cmsFileName returns the name of the file the CMS container is saved to:
+// outputFile, if set, or else signed-<unsigned CoRIM file>.p7s
+func cmsFileName(unsignedCorimFile string, outputFile *string) string {
+	if outputFile != nil && *outputFile != "" {
+		return *outputFile
+	}
+
+	return makeFileName(filepath.Dir(unsignedCorimFile), "signed-"+filepath.Base(unsignedCorimFile), ".p7s")
+}
+
+// signCMS signs unsignedCorimFile into a CMS SignedData, as for corim sign
+// --container=cms, and saves it.  It returns the name of the saved file.  If
+// key is not nil, it is set to the key that signed and to its certificate.
+func signCMS(unsignedCorimFile, keyFile string, outputFile *string, certFile string, intermediatesFiles []string, certChain, outputMode string, key *signingKey) (string, error) {
+	data, err := afero.ReadFile(fs, unsignedCorimFile)
+	if err != nil {
+		return "", fmt.Errorf("error loading unsigned CoRIM from %s: %w", unsignedCorimFile, err)
+	}
+
+	var c corim.UnsignedCorim
+	if err = c.FromCBOR(data); err != nil {
+		return "", fmt.Errorf("error decoding unsigned CoRIM from %s: %w", unsignedCorimFile, err)
+	}
+
+	if err = c.Valid(); err != nil {
+		return "", fmt.Errorf("error validating CoRIM: %w", err)
+	}
+
+	signer, err := loadCMSSigner(keyFile)
+	if err != nil {
+		return "", err
+	}
+
+	if key != nil {
+		*key = signingKey{pub: signer.Public()}
+
+		if algs, err := cmsAlgorithmsFor(signer.Public()); err == nil {
+			if alg, ok := cmsSignatureAlgorithm(algs.sigOID, algs.hash); ok {
+				key.alg = alg.String()
+			}
+		}
+	}
+
+	certs, err := loadCMSCertificates(certFile, intermediatesFiles, certChain)
+	if err != nil {
+		return "", err
+	}
+
+	if key != nil {
+		key.cert = certs[0]
+	}
+
+	if err = checkSigningKeyUsage(certs[0]); err != nil {
+		fmt.Printf(">> warning: %v\n", err)
+	}
+
+	der, err := cmsSignedCorim(data, signer, certs)
+	if err != nil {
+		return "", fmt.Errorf("error signing CoRIM with key %s into a CMS container: %w", keyFile, err)
+	}
+
+	perm, err := parseFileMode(outputMode)
+	if err != nil {
+		return "", err
+	}
+
+	file := cmsFileName(unsignedCorimFile, outputFile)
+
+	if err = writeFileMode(file, der, perm); err != nil {
+		return "", fmt.Errorf("error saving CMS container to file %s: %w", file, err)
+	}
+
+	return file, nil
+}
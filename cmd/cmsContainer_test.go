@@ -0,0 +1,199 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestCMSCert returns a self-signed certificate for the private key in JWK
+// format j
+func newTestCMSCert(t *testing.T, j []byte) []byte {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "key.jwk", j, 0644))
+
+	signer, err := loadCMSSigner("key.jwk")
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: "ACME CoRIM signer"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, signer.Public(), signer)
+	require.NoError(t, err)
+
+	return der
+}
+
+// decodeTestCMS decodes the CMS SignedData in der and checks its signature
+// with cert
+func decodeTestCMS(t *testing.T, der []byte, cert *x509.Certificate, sigAlg x509.SignatureAlgorithm) cmsSignedData {
+	var ci cmsContentInfo
+	rest, err := asn1.Unmarshal(der, &ci)
+	require.NoError(t, err)
+	require.Empty(t, rest)
+	assert.True(t, ci.ContentType.Equal(oidSignedData))
+
+	var sd cmsSignedData
+	_, err = asn1.Unmarshal(ci.Content.Bytes, &sd)
+	require.NoError(t, err)
+	require.Len(t, sd.SignerInfos, 1)
+
+	si := sd.SignerInfos[0]
+
+	var sid cmsIssuerAndSerialNumber
+	_, err = asn1.Unmarshal(si.SID.FullBytes, &sid)
+	require.NoError(t, err)
+	assert.Equal(t, cert.SerialNumber, sid.SerialNumber)
+	assert.Equal(t, cert.RawIssuer, sid.Issuer.FullBytes)
+
+	// the signature covers the SET OF encoding of the signed attributes
+	attrs := append([]byte{0x31}, si.SignedAttrs.FullBytes[1:]...)
+	require.NoError(t, cert.CheckSignature(sigAlg, attrs, si.Signature))
+
+	return sd
+}
+
+func Test_CorimSignCmd_container_cms(t *testing.T) {
+	certDER := newTestCMSCert(t, testECKey)
+	cert, err := x509.ParseCertificate(certDER)
+	require.NoError(t, err)
+
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "unsigned-corim.cbor", testCorimValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "key.jwk", testECKey, 0644))
+	require.NoError(t, afero.WriteFile(fs, "cert.der", certDER, 0644))
+
+	cmd := NewCorimSignCmd()
+	cmd.SetArgs([]string{"--file=unsigned-corim.cbor", "--key=key.jwk", "--cert=cert.der", "--container=cms"})
+	require.NoError(t, cmd.Execute())
+
+	der, err := afero.ReadFile(fs, "signed-unsigned-corim.p7s")
+	require.NoError(t, err)
+
+	sd := decodeTestCMS(t, der, cert, x509.ECDSAWithSHA256)
+	assert.True(t, sd.EncapContentInfo.EContentType.Equal(oidData))
+	assert.Equal(t, testCorimValid, sd.EncapContentInfo.EContent)
+	assert.Equal(t, certDER, sd.Certificates.Bytes)
+
+	var attrs []cmsAttribute
+	_, err = asn1.UnmarshalWithParams(append([]byte{0x31}, sd.SignerInfos[0].SignedAttrs.FullBytes[1:]...), &attrs, "set")
+	require.NoError(t, err)
+	require.Len(t, attrs, 2)
+
+	digest := sha256.Sum256(testCorimValid)
+
+	for _, attr := range attrs {
+		if attr.Type.Equal(oidAttrMessageDigest) {
+			var md []byte
+			_, err = asn1.Unmarshal(attr.Values[0].FullBytes, &md)
+			require.NoError(t, err)
+			assert.Equal(t, digest[:], md)
+		}
+	}
+}
+
+func Test_cmsSignedCorim_ed25519(t *testing.T) {
+	certDER := newTestCMSCert(t, testEdDSAKey)
+	cert, err := x509.ParseCertificate(certDER)
+	require.NoError(t, err)
+
+	signer, err := loadCMSSigner("key.jwk")
+	require.NoError(t, err)
+
+	der, err := cmsSignedCorim(testCorimValid, signer, []*x509.Certificate{cert})
+	require.NoError(t, err)
+
+	decodeTestCMS(t, der, cert, x509.PureEd25519)
+}
+
+func Test_cmsSignedCorim_key_mismatch(t *testing.T) {
+	cert, err := x509.ParseCertificate(newTestCMSCert(t, testEdDSAKey))
+	require.NoError(t, err)
+
+	require.NoError(t, afero.WriteFile(fs, "ec.jwk", testECKey, 0644))
+
+	var signer crypto.Signer
+	signer, err = loadCMSSigner("ec.jwk")
+	require.NoError(t, err)
+
+	_, err = cmsSignedCorim(testCorimValid, signer, []*x509.Certificate{cert})
+	assert.EqualError(t, err, "the signing key does not match the signing certificate")
+}
+
+func Test_CorimSignCmd_container_cms_empty_cert_chain(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "unsigned-corim.cbor", testCorimValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "key.jwk", testECKey, 0644))
+	require.NoError(t, afero.WriteFile(fs, "chain.der", []byte{}, 0644))
+
+	cmd := NewCorimSignCmd()
+	cmd.SetArgs([]string{"--file=unsigned-corim.cbor", "--key=key.jwk", "--cert-chain=chain.der", "--container=cms"})
+	assert.EqualError(t, cmd.Execute(), "error decoding certificate chain from chain.der: no certificate found")
+
+	signer, err := loadCMSSigner("key.jwk")
+	require.NoError(t, err)
+
+	_, err = cmsSignedCorim(testCorimValid, signer, nil)
+	assert.EqualError(t, err, "no signing certificate")
+}
+
+func Test_CorimSignCmd_container_cms_bad_args(t *testing.T) {
+	tvs := []struct {
+		desc     string
+		args     []string
+		expected string
+	}{
+		{
+			desc:     "unknown container",
+			args:     []string{"--file=ok.cbor", "--key=ok.jwk", "--meta=ok.json", "--container=pkcs12"},
+			expected: `invalid --container "pkcs12": expecting cose or cms`,
+		},
+		{
+			desc:     "no certificate",
+			args:     []string{"--file=ok.cbor", "--key=ok.jwk", "--container=cms"},
+			expected: "--container=cms requires --cert or --cert-chain, as CMS identifies the signer by its certificate",
+		},
+		{
+			desc:     "ssh agent",
+			args:     []string{"--file=ok.cbor", "--ssh-agent", "--cert=cert.der", "--container=cms"},
+			expected: "--container=cms requires --key",
+		},
+		{
+			desc:     "meta",
+			args:     []string{"--file=ok.cbor", "--key=ok.jwk", "--cert=cert.der", "--meta=ok.json", "--container=cms"},
+			expected: "--container=cms cannot carry a CoRIM Meta: --meta, --meta-from-corim and --additional-meta cannot be used",
+		},
+		{
+			desc:     "cose option",
+			args:     []string{"--file=ok.cbor", "--key=ok.jwk", "--cert=cert.der", "--kid=thumbprint", "--container=cms"},
+			expected: "--container=cms can only be combined with --key, --cert, --intermediates, --cert-chain, --no-meta, --output, --output-mode and --audit-log",
+		},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.desc, func(t *testing.T) {
+			cmd := NewCorimSignCmd()
+			cmd.SetArgs(tv.args)
+
+			assert.EqualError(t, cmd.Execute(), tv.expected)
+		})
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/spf13/afero"
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/swid"
+)
+
+// bulkCSVHeader is the (optional) header line of a bulk measurements CSV
+var bulkCSVHeader = []string{"component", "algorithm", "digest"}
+
+// bulkComponent collects the digests found in the CSV rows of a component
+type bulkComponent struct {
+	name    string
+	digests comid.Digests
+}
+
+// parseClassID interprets s as a UUID, an OID or a base64-encoded PSA
+// implementation id, in that order
+func parseClassID(s string) (*comid.ClassID, error) {
+	for _, factory := range []comid.IClassIDFactory{
+		comid.NewUUIDClassID,
+		comid.NewOIDClassID,
+		comid.NewImplIDClassID,
+	} {
+		if classID, err := factory(s); err == nil {
+			return classID, nil
+		}
+	}
+
+	return nil, fmt.Errorf(
+		"invalid environment class id %q: expecting a UUID, an OID or a base64-encoded implementation id", s,
+	)
+}
+
+// parseBulkCSV reads the (component, algorithm, digest) rows of a bulk
+// measurements CSV and groups the digests by component, preserving the order
+// in which components first appear.  Algorithms use the names of the IANA
+// Named Information Hash Algorithm registry (e.g., sha-256) and digests are
+// hex-encoded.
+func parseBulkCSV(data []byte) ([]*bulkComponent, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = len(bulkCSVHeader)
+	r.TrimLeadingSpace = true
+	r.Comment = '#'
+
+	var (
+		components []*bulkComponent
+		index      = map[string]*bulkComponent{}
+	)
+
+	for first := true; ; first = false {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := r.FieldPos(0)
+
+		if first && isBulkCSVHeader(row) {
+			continue
+		}
+
+		name, alg, digest := strings.TrimSpace(row[0]), strings.TrimSpace(row[1]), strings.TrimSpace(row[2])
+
+		if name == "" {
+			return nil, fmt.Errorf("line %d: empty component name", line)
+		}
+
+		algID := swid.AlgIDFromString(strings.ToLower(alg))
+		if algID == 0 {
+			return nil, fmt.Errorf("line %d: unknown hash algorithm %q", line, alg)
+		}
+
+		value, err := hex.DecodeString(digest)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid digest: expecting a hex-encoded value", line)
+		}
+
+		if err = swid.ValidHashEntry(algID, value); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		c, ok := index[name]
+		if !ok {
+			c = &bulkComponent{name: name}
+			index[name] = c
+			components = append(components, c)
+		}
+
+		c.digests.AddDigest(algID, value)
+	}
+
+	if len(components) == 0 {
+		return nil, errors.New("no measurements found")
+	}
+
+	return components, nil
+}
+
+func isBulkCSVHeader(row []string) bool {
+	for i, h := range bulkCSVHeader {
+		if !strings.EqualFold(strings.TrimSpace(row[i]), h) {
+			return false
+		}
+	}
+	return true
+}
+
+// bulkComid builds a CoMID with one reference-value triple per component.
+// The environment of each triple is identified by the supplied class, with
+// the component name as its model.  If tagID is empty, a random
+// UUID is used as the tag identifier.
+func bulkComid(components []*bulkComponent, class *comid.Class, tagID string) (*comid.Comid, error) {
+	var c comid.Comid
+
+	var id interface{} = tagID
+	if tagID == "" {
+		id = uuid.New()
+	}
+
+	if c.SetTagIdentity(id, 0) == nil {
+		return nil, fmt.Errorf("invalid tag id %q", tagID)
+	}
+
+	for _, component := range components {
+		m := comid.Measurement{
+			Val: comid.Mval{Digests: &component.digests},
+		}
+
+		measurements := comid.NewMeasurements().Add(&m)
+
+		componentClass := *class
+
+		env := comid.Environment{
+			Class: componentClass.SetModel(component.name),
+		}
+
+		if c.AddReferenceValue(comid.ValueTriple{Environment: env, Measurements: *measurements}) == nil {
+			return nil, fmt.Errorf("error adding reference value for component %q", component.name)
+		}
+	}
+
+	if err := c.Valid(); err != nil {
+		return nil, fmt.Errorf("error validating CoMID: %w", err)
+	}
+
+	return &c, nil
+}
+
+// csvToCBOR creates a CoMID from the bulk measurements CSV in csvFile and saves
+// it, CBOR-encoded, to cborFile
+func csvToCBOR(csvFile, cborFile string, ec envClass, tagID string) error {
+	data, err := afero.ReadFile(fs, csvFile)
+	if err != nil {
+		return fmt.Errorf("error loading CSV from %s: %w", csvFile, err)
+	}
+
+	components, err := parseBulkCSV(data)
+	if err != nil {
+		return fmt.Errorf("error parsing CSV from %s: %w", csvFile, err)
+	}
+
+	class, err := ec.class()
+	if err != nil {
+		return err
+	}
+
+	c, err := bulkComid(components, class, tagID)
+	if err != nil {
+		return err
+	}
+
+	cborData, err := c.ToCBOR()
+	if err != nil {
+		return fmt.Errorf("error encoding CoMID to CBOR: %w", err)
+	}
+
+	if err = afero.WriteFile(fs, cborFile, cborData, 0644); err != nil {
+		return fmt.Errorf("error saving CBOR file %s: %w", cborFile, err)
+	}
+
+	fmt.Printf(">> created %q from %q (%d component(s))\n", cborFile, csvFile, len(components))
+
+	return nil
+}
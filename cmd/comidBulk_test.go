@@ -0,0 +1,225 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/swid"
+)
+
+var (
+	testBulkSHA256 = strings.Repeat("aa", 32)
+	testBulkSHA384 = strings.Repeat("bb", 48)
+	testBulkCSV    = "component,algorithm,digest\n" +
+		"bl1,sha-256," + testBulkSHA256 + "\n" +
+		"fw,SHA-256," + testBulkSHA256 + "\n" +
+		"bl1,sha-384," + testBulkSHA384 + "\n"
+)
+
+func Test_parseBulkCSV(t *testing.T) {
+	components, err := parseBulkCSV([]byte(testBulkCSV))
+	require.NoError(t, err)
+	require.Len(t, components, 2)
+
+	assert.Equal(t, "bl1", components[0].name)
+	require.Len(t, components[0].digests, 2)
+	assert.Equal(t, swid.Sha256, components[0].digests[0].HashAlgID)
+	assert.Equal(t, swid.Sha384, components[0].digests[1].HashAlgID)
+
+	assert.Equal(t, "fw", components[1].name)
+	require.Len(t, components[1].digests, 1)
+}
+
+func Test_parseBulkCSV_no_header(t *testing.T) {
+	components, err := parseBulkCSV([]byte("fw,sha-256," + testBulkSHA256 + "\n"))
+	require.NoError(t, err)
+	require.Len(t, components, 1)
+	assert.Equal(t, "fw", components[0].name)
+}
+
+func Test_parseBulkCSV_bad(t *testing.T) {
+	tvs := []struct {
+		desc     string
+		csv      string
+		expected string
+	}{
+		{
+			desc:     "digest length",
+			csv:      "component,algorithm,digest\nfw,sha-384," + testBulkSHA256 + "\n",
+			expected: "line 2: length mismatch for hash algorithm sha-384: want 48 bytes, got 32",
+		},
+		{
+			desc:     "unknown algorithm",
+			csv:      "fw,md5,00112233445566778899aabbccddeeff\n",
+			expected: `line 1: unknown hash algorithm "md5"`,
+		},
+		{
+			desc:     "bad digest",
+			csv:      "fw,sha-256,not-hex\n",
+			expected: "line 1: invalid digest: expecting a hex-encoded value",
+		},
+		{
+			desc:     "empty component",
+			csv:      " ,sha-256," + testBulkSHA256 + "\n",
+			expected: "line 1: empty component name",
+		},
+		{
+			desc:     "wrong number of fields",
+			csv:      "fw,sha-256\n",
+			expected: "record on line 1: wrong number of fields",
+		},
+		{
+			desc:     "only header",
+			csv:      "component,algorithm,digest\n",
+			expected: "no measurements found",
+		},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.desc, func(t *testing.T) {
+			_, err := parseBulkCSV([]byte(tv.csv))
+			assert.EqualError(t, err, tv.expected)
+		})
+	}
+}
+
+func Test_parseClassID(t *testing.T) {
+	classID, err := parseClassID("31fb5abf-023e-4992-aa4e-95f9c1503bfa")
+	require.NoError(t, err)
+	assert.Equal(t, comid.UUIDType, classID.Type())
+
+	classID, err = parseClassID("1.2.3.4")
+	require.NoError(t, err)
+	assert.Equal(t, comid.OIDType, classID.Type())
+
+	classID, err = parseClassID("YWNtZS1pbXBsZW1lbnRhdGlvbi1pZC0wMDAwMDAwMDE=")
+	require.NoError(t, err)
+	assert.Equal(t, comid.ImplIDType, classID.Type())
+
+	_, err = parseClassID("acme")
+	assert.EqualError(t, err,
+		`invalid environment class id "acme": expecting a UUID, an OID or a base64-encoded implementation id`)
+}
+
+func Test_ComidCreateCmd_bulk_ok(t *testing.T) {
+	cmd := NewComidCreateCmd()
+
+	args := []string{
+		"--bulk",
+		"--csv=measurements.csv",
+		"--env-class-id=1.2.3.4",
+		"--tag-id=acme-fw-1",
+		"--output=out/comid.cbor",
+	}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "measurements.csv", []byte(testBulkCSV), 0644)
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	require.NoError(t, err)
+
+	data, err := afero.ReadFile(fs, "out/comid.cbor")
+	require.NoError(t, err)
+
+	var c comid.Comid
+	require.NoError(t, c.FromCBOR(data))
+	require.NoError(t, c.Valid())
+
+	assert.Equal(t, "acme-fw-1", c.TagIdentity.TagID.String())
+	require.NotNil(t, c.Triples.ReferenceValues)
+
+	rvs := c.Triples.ReferenceValues.Values
+	require.Len(t, rvs, 2)
+	assert.Equal(t, "bl1", *rvs[0].Environment.Class.Model)
+	assert.Equal(t, "1.2.3.4", rvs[0].Environment.Class.ClassID.String())
+	assert.Equal(t, "fw", *rvs[1].Environment.Class.Model)
+}
+
+func Test_ComidCreateCmd_bulk_default_output(t *testing.T) {
+	cmd := NewComidCreateCmd()
+
+	args := []string{
+		"--bulk",
+		"--csv=data/measurements.csv",
+		"--env-class-id=31fb5abf-023e-4992-aa4e-95f9c1503bfa",
+		"--output-dir=comids",
+	}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "data/measurements.csv", []byte(testBulkCSV), 0644)
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	require.NoError(t, err)
+
+	_, err = fs.Stat("comids/measurements.cbor")
+	assert.NoError(t, err)
+}
+
+func Test_ComidCreateCmd_bulk_bad_csv(t *testing.T) {
+	cmd := NewComidCreateCmd()
+
+	args := []string{
+		"--bulk",
+		"--csv=measurements.csv",
+		"--env-class-id=1.2.3.4",
+	}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "measurements.csv", []byte("fw,sha-512,"+testBulkSHA256+"\n"), 0644)
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	assert.EqualError(t, err,
+		"error parsing CSV from measurements.csv: line 1: length mismatch for hash algorithm sha-512: want 64 bytes, got 32")
+}
+
+func Test_ComidCreateCmd_bulk_args(t *testing.T) {
+	tvs := []struct {
+		desc     string
+		args     []string
+		expected string
+	}{
+		{
+			desc:     "no CSV",
+			args:     []string{"--bulk", "--env-class-id=1.2.3.4"},
+			expected: "no CSV supplied",
+		},
+		{
+			desc:     "no class id",
+			args:     []string{"--bulk", "--csv=m.csv"},
+			expected: "no environment class id supplied",
+		},
+		{
+			desc:     "bulk and templates",
+			args:     []string{"--bulk", "--csv=m.csv", "--env-class-id=1.2.3.4", "--template=t.json"},
+			expected: "--bulk cannot be used together with --template or --template-dir",
+		},
+		{
+			desc:     "CSV without bulk",
+			args:     []string{"--csv=m.csv", "--template=t.json"},
+			expected: "--csv can only be used together with --bulk",
+		},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.desc, func(t *testing.T) {
+			cmd := NewComidCreateCmd()
+			cmd.SetArgs(tv.args)
+
+			err := cmd.Execute()
+			assert.EqualError(t, err, tv.expected)
+		})
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/veraison/corim/comid"
+)
+
+var (
+	comidBumpVersionFile   *string
+	comidBumpVersionOutput *string
+	comidBumpVersionSet    *uint
+)
+
+var comidBumpVersionCmd = NewComidBumpVersionCmd()
+
+func NewComidBumpVersionCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bump-version",
+		Short: "increment the tag version of a CBOR-encoded CoMID",
+		Long: `increment the tag version of a CBOR-encoded CoMID
+
+	Increment the tag version of the CoMID in file comid.cbor, and save the
+	updated CoMID to new-comid.cbor.  The updated CoMID is validated before
+	being saved
+
+	  cocli comid bump-version --file=comid.cbor --output=new-comid.cbor
+
+	Set the tag version of the CoMID in file comid.cbor to 5, and update the
+	file in place.  The new version must be greater than the current one
+
+	  cocli comid bump-version --file=comid.cbor --output=comid.cbor --set=5
+	`,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkComidBumpVersionArgs(); err != nil {
+				return err
+			}
+
+			var set *uint
+			if cmd.Flags().Changed("set") {
+				set = comidBumpVersionSet
+			}
+
+			return bumpComidVersion(*comidBumpVersionFile, *comidBumpVersionOutput, set)
+		},
+	}
+
+	comidBumpVersionFile = cmd.Flags().StringP("file", "f", "", "a CoMID file (in CBOR format)")
+	comidBumpVersionOutput = cmd.Flags().StringP("output", "o", "", "name of the file the updated CoMID is saved to (can be the same as --file)")
+	comidBumpVersionSet = cmd.Flags().Uint("set", 0, "set the tag version to this value, which must be greater than the current one, instead of incrementing it")
+
+	return cmd
+}
+
+func checkComidBumpVersionArgs() error {
+	if comidBumpVersionFile == nil || *comidBumpVersionFile == "" {
+		return errors.New("no CoMID supplied")
+	}
+
+	if comidBumpVersionOutput == nil || *comidBumpVersionOutput == "" {
+		return errors.New("no output file supplied")
+	}
+
+	return nil
+}
+
+// bumpComidVersion saves the CoMID in file to outputFile, with its tag version
+// set to *set if set is not nil, or else incremented.  CoMIDs carrying fields
+// that are not understood are refused, as these would be lost when the CoMID
+// is re-encoded.
+func bumpComidVersion(file, outputFile string, set *uint) error {
+	var c comid.Comid
+
+	data, err := afero.ReadFile(fs, file)
+	if err != nil {
+		return fmt.Errorf("error loading CoMID from %s: %w", file, err)
+	}
+
+	if err = decodeCBOR(&c, data, false); err != nil {
+		return fmt.Errorf("error decoding CoMID from %s: %w", file, err)
+	}
+
+	unknown, err := unknownCBORFields(&c, data)
+	if err != nil {
+		return fmt.Errorf("error decoding CoMID from %s: %w", file, err)
+	}
+
+	if len(unknown) != 0 {
+		return fmt.Errorf("%s carries fields that are not understood, which would be dropped: %s",
+			file, strings.Join(unknown, ", "))
+	}
+
+	current := c.TagIdentity.TagVersion
+
+	switch {
+	case set != nil && *set <= current:
+		return fmt.Errorf("--set %d does not increase the tag version %d of %s", *set, current, file)
+	case set != nil:
+		c.TagIdentity.TagVersion = *set
+	case current == math.MaxUint:
+		return fmt.Errorf("the tag version of %s cannot be incremented", file)
+	default:
+		c.TagIdentity.TagVersion = current + 1
+	}
+
+	if err = c.Valid(); err != nil {
+		return fmt.Errorf("error validating updated CoMID: %w", err)
+	}
+
+	cborData, err := c.ToCBOR()
+	if err != nil {
+		return fmt.Errorf("error encoding updated CoMID to CBOR: %w", err)
+	}
+
+	if err = afero.WriteFile(fs, outputFile, cborData, 0644); err != nil {
+		return fmt.Errorf("error saving CBOR file %s: %w", outputFile, err)
+	}
+
+	fmt.Printf(">> tag version of %q bumped from %d to %d, saved to %q\n",
+		file, current, c.TagIdentity.TagVersion, outputFile)
+
+	return nil
+}
+
+func init() {
+	comidCmd.AddCommand(comidBumpVersionCmd)
+}
@@ -0,0 +1,81 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/comid"
+)
+
+func readTestComidVersion(t *testing.T, file string) uint {
+	var c comid.Comid
+	require.NoError(t, c.FromCBOR(mustReadFile(t, file)))
+	return c.TagIdentity.TagVersion
+}
+
+func Test_ComidBumpVersionCmd_bad_args(t *testing.T) {
+	cmd := NewComidBumpVersionCmd()
+	cmd.SetArgs([]string{"--output=out.cbor"})
+	assert.EqualError(t, cmd.Execute(), "no CoMID supplied")
+
+	cmd = NewComidBumpVersionCmd()
+	cmd.SetArgs([]string{"--file=c.cbor"})
+	assert.EqualError(t, cmd.Execute(), "no output file supplied")
+}
+
+func Test_ComidBumpVersionCmd_increment(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	writeTestComidVersion(t, "c.cbor", 0)
+
+	cmd := NewComidBumpVersionCmd()
+	cmd.SetArgs([]string{"--file=c.cbor", "--output=out.cbor"})
+	require.NoError(t, cmd.Execute())
+
+	assert.Equal(t, uint(1), readTestComidVersion(t, "out.cbor"))
+	assert.Equal(t, uint(0), readTestComidVersion(t, "c.cbor"))
+}
+
+func Test_ComidBumpVersionCmd_set_in_place(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	writeTestComidVersion(t, "c.cbor", 2)
+
+	cmd := NewComidBumpVersionCmd()
+	cmd.SetArgs([]string{"--file=c.cbor", "--output=c.cbor", "--set=7"})
+	require.NoError(t, cmd.Execute())
+
+	assert.Equal(t, uint(7), readTestComidVersion(t, "c.cbor"))
+}
+
+func Test_ComidBumpVersionCmd_set_not_increasing(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	writeTestComidVersion(t, "c.cbor", 2)
+
+	for _, set := range []string{"2", "0"} {
+		cmd := NewComidBumpVersionCmd()
+		cmd.SetArgs([]string{"--file=c.cbor", "--output=out.cbor", "--set=" + set})
+		assert.EqualError(t, cmd.Execute(), "--set "+set+" does not increase the tag version 2 of c.cbor")
+	}
+
+	_, err := fs.Stat("out.cbor")
+	assert.Error(t, err)
+}
+
+func Test_ComidBumpVersionCmd_unknown_fields(t *testing.T) {
+	fs = afero.NewMemMapFs()
+
+	c := newTestComid(t)
+	data, err := c.ToCBOR()
+	require.NoError(t, err)
+
+	data = addUnknownCBORField(t, data)
+	require.NoError(t, afero.WriteFile(fs, "c.cbor", data, 0644))
+
+	cmd := NewComidBumpVersionCmd()
+	cmd.SetArgs([]string{"--file=c.cbor", "--output=out.cbor"})
+	assert.EqualError(t, cmd.Execute(), "c.cbor carries fields that are not understood, which would be dropped: /99")
+}
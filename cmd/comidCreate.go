@@ -6,23 +6,39 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"path/filepath"
 
 	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
-	"github.com/veraison/corim/comid"
+	"github.com/veraison/eat"
 )
 
 var (
-	comidCreateFiles     []string
-	comidCreateDirs      []string
-	comidCreateOutputDir string
+	comidCreateFiles        []string
+	comidCreateDirs         []string
+	comidCreateOutputDir    string
+	comidCreateStrictDecode bool
+	comidCreateDirMode      string
+	comidCreateBulk         bool
+	comidCreateMerge        bool
+	comidCreateOnConflict   string
+	comidCreateCSV          string
+	comidCreateSVN          string
+	comidCreateMinSVN       string
+	comidCreateEnvClass     envClass
+	comidCreateOutput       string
+	comidCreateTagID        string
+	comidCreateProfile      string
+	comidCreateJSONLimits   jsonLimits
+	comidCreateEnvExpansion envExpansion
+	comidCreatePSAIDs       psaIDs
 )
 
 var comidCreateCmd = NewComidCreateCmd()
 
 func NewComidCreateCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "create",
+		Use:   "create [flags] [measurements-file ...]",
 		Short: "create one or more CBOR-encoded CoMID(s) from the supplied JSON template(s)",
 		Long: `create one or more CBOR-encoded CoMID(s) from the supplied JSON template(s)
 
@@ -34,27 +50,137 @@ func NewComidCreateCmd() *cobra.Command {
 	    			--template-dir=templates
 	  
 	Create one CoMID from template t3.json and save it to the comids/ directory.
-	Note that the output directory must exist.
+	The output directory is created (with mode 0755, unless --dir-mode is
+	given) if it does not exist.
 	
 		cocli comid create --template=t3.json --output-dir=comids
 
 	Note: since the output file is deterministically generated from the template
 	file name, all the template file names (when from different directories)
 	MUST be different.
+
+	Reject templates larger than 64 KiB, or with arrays and objects nested
+	more than 16 levels deep (by default, the limits are 10 MiB and 64 levels)
+
+		cocli comid create --template=t1.json \
+	    			--max-json-size=65536 \
+	    			--max-json-depth=16
+
+	Create one CoMID from the CCA platform reference values template cca.json,
+	checking that it follows the layout required by the CCA profile (use
+	--profile=psa for the PSA profile)
+
+		cocli comid create --template=cca.json --profile=cca
+
+	Create one CoMID from the PSA template psa.json, setting the
+	implementation id (the class id of every reference-values and
+	attester-verification-keys environment) and the instance id (the ueid of
+	the attester-verification-keys environment), which the template can then
+	leave out.  Both are base64 or hex encoded, and must be 32 and 33 bytes
+	long respectively.  Ids that are present in the template must match
+
+		cocli comid create --template=psa.json --profile=psa \
+	    			--psa-impl-id=YWNtZS1pbXBsZW1lbnRhdGlvbi1pZC0wMDAwMDAwMDE= \
+	    			--psa-inst-id=Ac7rrnuJJ6MiflMDz14PH3s0u1Qq1yUKwD+83jbsLxUI
+
+	Create one CoMID from template rv.json, whose measurements carry a
+	raw-value together with a raw-value-mask.  Each mask must be as long as
+	its raw-value
+
+		cocli comid create --template=rv.json
+
+	Create one CoMID from template t4.json, substituting ${VAR} references
+	(e.g., ${BUILD_ID}) with the values of the corresponding environment
+	variables.  It is an error if any of them is unset, unless
+	--allow-missing-env is also given
+
+		cocli comid create --template=t4.json --expand-env
+
+	Create one CoMID from the (component, algorithm, digest) rows of
+	measurements.csv, with one reference-value triple per component, all
+	sharing the environment class id 1.2.3.4, and save it to comid.cbor
+
+		cocli comid create --bulk --csv=measurements.csv \
+	    			--env-class-id=1.2.3.4 \
+	    			--output=comid.cbor
+
+	Create one CoMID with a single reference-value triple, for the environment
+	with class id 1.2.3.4, carrying the measurements found in bl1.json and
+	fw.json (each holding one measurement, or an array of measurements, in the
+	JSON format used in templates), and save it to comid.cbor.  Measurements
+	with the same key are rejected
+
+		cocli comid create --merge-measurements \
+	    			--env-class-id=1.2.3.4 \
+	    			--output=comid.cbor \
+	    			bl1.json fw.json
+
+	As above, but when the files carry measurements with the same key and
+	different values, keep the one from the file listed last, reporting each
+	conflict
+
+		cocli comid create --merge-measurements \
+	    			--on-conflict=keep-last \
+	    			--env-class-id=1.2.3.4 \
+	    			--output=comid.cbor \
+	    			team-a.json team-b.json
+
+	Create one CoMID with a single reference-value triple, for the environment
+	with class id 1.2.3.4, carrying one measurement of its security version
+	number, and save it to comid.cbor.  With --svn, the reference value only
+	matches SVN 3; use --min-svn instead to match SVN 3 or greater
+
+		cocli comid create --svn=3 \
+	    			--env-class-id=1.2.3.4 \
+	    			--output=comid.cbor
+
+	Same as above, but for the environment whose class id is the UUID
+	31fb5abf-023e-4992-aa4e-95f9c1503bfa, made by ACME Inc. (use --class-id-oid
+	or --class-id-int for class ids that are OIDs or integers).  Unlike
+	--env-class-id, whose type is guessed from its format, these make the type
+	of the class id explicit
+
+		cocli comid create --svn=3 \
+	    			--class-id-uuid=31fb5abf-023e-4992-aa4e-95f9c1503bfa \
+	    			--vendor="ACME Inc." \
+	    			--model="RoadRunner" \
+	    			--output=comid.cbor
 	`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if err := checkComidCreateArgs(); err != nil {
+			if err := checkComidCreateArgs(args); err != nil {
 				return err
 			}
 
+			if comidCreateSVN != "" || comidCreateMinSVN != "" {
+				return svnCreate()
+			}
+
+			if comidCreateBulk {
+				return bulkCreate()
+			}
+
+			if comidCreateMerge {
+				return mergeCreate(args)
+			}
+
 			filesList := filesList(comidCreateFiles, comidCreateDirs, ".json")
 			if len(filesList) == 0 {
 				return errors.New("no files found")
 			}
 
+			if err := prepareOutputDir(comidCreateOutputDir, comidCreateDirMode); err != nil {
+				return err
+			}
+
+			profile, err := parseProfile(comidCreateProfile)
+			if err != nil {
+				return err
+			}
+
 			errs := 0
 			for _, tmplFile := range filesList {
-				cborFile, err := templateToCBOR(tmplFile, comidCreateOutputDir)
+				cborFile, err := templateToCBOR(tmplFile, comidCreateOutputDir, comidCreateStrictDecode,
+					comidCreateJSONLimits, comidCreateEnvExpansion, profile, comidCreatePSAIDs)
 				if err != nil {
 					fmt.Printf(">> creation failed for %q: %v\n", cborFile, err)
 					errs++
@@ -82,36 +208,280 @@ func NewComidCreateCmd() *cobra.Command {
 		&comidCreateOutputDir, "output-dir", "o", ".", "directory where the created files are stored",
 	)
 
+	cmd.Flags().StringVar(
+		&comidCreateDirMode, "dir-mode", defaultDirMode, "permissions of the output directory, if it needs to be created",
+	)
+
+	cmd.Flags().BoolVar(
+		&comidCreateBulk, "bulk", false, "create a CoMID from a CSV of measurements, instead of from templates",
+	)
+
+	cmd.Flags().BoolVar(
+		&comidCreateMerge, "merge-measurements", false, "create a CoMID from the measurements in the supplied JSON files, instead of from templates",
+	)
+
+	cmd.Flags().StringVar(
+		&comidCreateOnConflict, "on-conflict", "", "what to do with measurements of the same key and different values (with --merge-measurements): error (the default), keep-first, keep-last or keep-both",
+	)
+
+	cmd.Flags().StringVar(
+		&comidCreateCSV, "csv", "", "a CSV file of (component, algorithm, digest) measurements (with --bulk)",
+	)
+
+	cmd.Flags().StringVar(
+		&comidCreateSVN, "svn", "", "create a CoMID with a reference value matching this exact security version number, instead of from templates",
+	)
+
+	cmd.Flags().StringVar(
+		&comidCreateMinSVN, "min-svn", "", "create a CoMID with a reference value matching this security version number or greater, instead of from templates",
+	)
+
+	cmd.Flags().StringVar(
+		&comidCreateOutput, "output", "", "name of the created CoMID file (with --merge-measurements, --svn or --min-svn, or with --bulk, where it defaults to the CSV base name)",
+	)
+
+	cmd.Flags().StringVar(
+		&comidCreateTagID, "tag-id", "", "tag identifier of the created CoMID (with --bulk, --merge-measurements, --svn or --min-svn, defaults to a random UUID)",
+	)
+
+	cmd.Flags().StringVar(
+		&comidCreateProfile, "profile", "", "profile of the templates: psa, cca, or the id of a profile registered with --profile-def",
+	)
+
+	cmd.Flags().BoolVar(
+		&comidCreateStrictDecode, "strict-decode", false, "reject templates carrying fields that are not understood",
+	)
+
+	addEnvClassFlags(cmd, &comidCreateEnvClass, "with --bulk, --merge-measurements, --svn or --min-svn")
+	addPSAIDFlags(cmd, &comidCreatePSAIDs)
+	addJSONLimitsFlags(cmd, &comidCreateJSONLimits)
+	addEnvExpansionFlags(cmd, &comidCreateEnvExpansion)
+
 	return cmd
 }
 
-func checkComidCreateArgs() error {
-	if len(comidCreateFiles) == 0 && len(comidCreateDirs) == 0 {
-		return errors.New("no templates supplied")
+func checkComidCreateArgs(args []string) error {
+	useTemplates := len(comidCreateFiles) != 0 || len(comidCreateDirs) != 0
+
+	if comidCreateOnConflict != "" && !comidCreateMerge {
+		return errors.New("--on-conflict can only be used together with --merge-measurements")
+	}
+
+	if comidCreatePSAIDs.isSet() && !useTemplates {
+		return errors.New("--psa-impl-id and --psa-inst-id can only be used together with --template or --template-dir")
+	}
+
+	if comidCreateSVN != "" || comidCreateMinSVN != "" {
+		return checkComidCreateSVNArgs(args, useTemplates)
+	}
+
+	if comidCreateMerge {
+		return checkComidCreateMergeArgs(args, useTemplates)
+	}
+
+	if len(args) != 0 {
+		return errors.New("measurement files can only be supplied together with --merge-measurements")
+	}
+
+	if !comidCreateBulk {
+		if comidCreateCSV != "" {
+			return errors.New("--csv can only be used together with --bulk")
+		}
+
+		if comidCreateEnvClass.classID != "" || comidCreateOutput != "" || comidCreateTagID != "" {
+			return errors.New("--env-class-id, --output and --tag-id can only be used together with --bulk, --merge-measurements, --svn or --min-svn")
+		}
+
+		if comidCreateEnvClass.hasTyped() {
+			return errors.New("--class-id-oid, --class-id-uuid, --class-id-int, --vendor and --model can only be used together with --bulk, --merge-measurements, --svn or --min-svn")
+		}
+
+		if !useTemplates {
+			return errors.New("no templates supplied")
+		}
+
+		if err := comidCreateJSONLimits.valid(); err != nil {
+			return err
+		}
+
+		if err := comidCreatePSAIDs.valid(comidCreateProfile); err != nil {
+			return err
+		}
+
+		return comidCreateEnvExpansion.valid()
+	}
+
+	if useTemplates {
+		return errors.New("--bulk cannot be used together with --template or --template-dir")
+	}
+
+	if comidCreateProfile != "" {
+		return errors.New("--profile cannot be used together with --bulk")
+	}
+
+	if comidCreateCSV == "" {
+		return errors.New("no CSV supplied")
+	}
+
+	if comidCreateEnvClass.model != "" {
+		return errors.New("--model cannot be used together with --bulk, where the component names are used as models")
+	}
+
+	return comidCreateEnvClass.valid()
+}
+
+func checkComidCreateMergeArgs(args []string, useTemplates bool) error {
+	if comidCreateBulk {
+		return errors.New("--merge-measurements cannot be used together with --bulk")
+	}
+
+	if useTemplates {
+		return errors.New("--merge-measurements cannot be used together with --template or --template-dir")
+	}
+
+	if comidCreateCSV != "" {
+		return errors.New("--csv can only be used together with --bulk")
+	}
+
+	if comidCreateProfile != "" {
+		return errors.New("--profile cannot be used together with --merge-measurements")
+	}
+
+	if len(args) == 0 {
+		return errors.New("no measurement files supplied")
+	}
+
+	if err := comidCreateEnvClass.valid(); err != nil {
+		return err
+	}
+
+	if comidCreateOutput == "" {
+		return errors.New("no output file supplied")
+	}
+
+	if err := comidCreateJSONLimits.valid(); err != nil {
+		return err
+	}
+
+	if _, err := parseMergeConflictPolicy(comidCreateOnConflict); err != nil {
+		return err
+	}
+
+	return comidCreateEnvExpansion.valid()
+}
+
+func checkComidCreateSVNArgs(args []string, useTemplates bool) error {
+	if comidCreateSVN != "" && comidCreateMinSVN != "" {
+		return errors.New("--svn and --min-svn cannot be used together")
+	}
+
+	if comidCreateBulk || comidCreateMerge {
+		return errors.New("--svn and --min-svn cannot be used together with --bulk or --merge-measurements")
+	}
+
+	if useTemplates {
+		return errors.New("--svn and --min-svn cannot be used together with --template or --template-dir")
+	}
+
+	if len(args) != 0 {
+		return errors.New("measurement files can only be supplied together with --merge-measurements")
 	}
+
+	if comidCreateCSV != "" {
+		return errors.New("--csv can only be used together with --bulk")
+	}
+
+	if comidCreateProfile != "" {
+		return errors.New("--profile cannot be used together with --svn or --min-svn")
+	}
+
+	if comidCreateSVN != "" {
+		if _, err := parseSVN("--svn", comidCreateSVN); err != nil {
+			return err
+		}
+	} else if _, err := parseSVN("--min-svn", comidCreateMinSVN); err != nil {
+		return err
+	}
+
+	if err := comidCreateEnvClass.valid(); err != nil {
+		return err
+	}
+
+	if comidCreateOutput == "" {
+		return errors.New("no output file supplied")
+	}
+
 	return nil
 }
 
-func templateToCBOR(tmplFile, outputDir string) (string, error) {
+func mergeCreate(files []string) error {
+	if err := prepareOutputDir(filepath.Dir(comidCreateOutput), comidCreateDirMode); err != nil {
+		return err
+	}
+
+	policy, err := parseMergeConflictPolicy(comidCreateOnConflict)
+	if err != nil {
+		return err
+	}
+
+	return mergeMeasurementsToCBOR(files, comidCreateOutput, comidCreateEnvClass, comidCreateTagID,
+		comidCreateJSONLimits, comidCreateEnvExpansion, policy)
+}
+
+func svnCreate() error {
+	if err := prepareOutputDir(filepath.Dir(comidCreateOutput), comidCreateDirMode); err != nil {
+		return err
+	}
+
+	return svnToCBOR(comidCreateSVN, comidCreateMinSVN, comidCreateOutput, comidCreateEnvClass, comidCreateTagID)
+}
+
+func bulkCreate() error {
+	cborFile := comidCreateOutput
+	if cborFile == "" {
+		cborFile = makeFileName(comidCreateOutputDir, comidCreateCSV, ".cbor")
+	}
+
+	if err := prepareOutputDir(filepath.Dir(cborFile), comidCreateDirMode); err != nil {
+		return err
+	}
+
+	return csvToCBOR(comidCreateCSV, cborFile, comidCreateEnvClass, comidCreateTagID)
+}
+
+func templateToCBOR(tmplFile, outputDir string, strict bool, limits jsonLimits, env envExpansion, profile *eat.Profile, ids psaIDs) (string, error) {
 	var (
 		tmplData, cborData []byte
 		cborFile           string
-		c                  comid.Comid
 		err                error
 	)
 
-	if tmplData, err = afero.ReadFile(fs, tmplFile); err != nil {
+	if tmplData, err = readJSONTemplate(tmplFile, limits, env); err != nil {
 		return "", fmt.Errorf("error loading template from %s: %w", tmplFile, err)
 	}
 
-	if err = c.FromJSON(tmplData); err != nil {
+	c := newComid(profile)
+
+	if err = decodeJSON(c, tmplData, strict); err != nil {
 		return "", fmt.Errorf("error decoding template from %s: %w", tmplFile, err)
 	}
 
+	if err = ids.apply(c); err != nil {
+		return "", fmt.Errorf("error setting PSA ids in template %s: %w", tmplFile, err)
+	}
+
 	if err = c.Valid(); err != nil {
 		return "", fmt.Errorf("error validating template %s: %w", tmplFile, err)
 	}
 
+	if err = checkRawValueMasks(c); err != nil {
+		return "", fmt.Errorf("error validating template %s: %w", tmplFile, err)
+	}
+
+	if err = checkProfileConformance(c, profile); err != nil {
+		return "", fmt.Errorf("error validating template %s: %w", tmplFile, err)
+	}
+
 	cborData, err = c.ToCBOR()
 	if err != nil {
 		return "", fmt.Errorf("error encoding template %s to CBOR: %w", tmplFile, err)
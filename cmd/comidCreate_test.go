@@ -4,6 +4,8 @@
 package cmd
 
 import (
+	"os"
+	"strings"
 	"testing"
 
 	"github.com/spf13/afero"
@@ -126,3 +128,98 @@ func Test_ComidCreateCmd_template_from_dir_to_custom_dir(t *testing.T) {
 	_, err = fs.Stat(expectedFileName)
 	assert.NoError(t, err)
 }
+
+func Test_ComidCreateCmd_template_with_unknown_field_strict(t *testing.T) {
+	var err error
+
+	cmd := NewComidCreateCmd()
+
+	tmpl := strings.Replace(comid.PSARefValJSONTemplate, "{", `{ "unknown-field": "x",`, 1)
+
+	fs = afero.NewMemMapFs()
+	err = afero.WriteFile(fs, "unknown.json", []byte(tmpl), 0644)
+	require.NoError(t, err)
+
+	args := []string{
+		"--template=unknown.json",
+		"--strict-decode",
+	}
+	cmd.SetArgs(args)
+
+	err = cmd.Execute()
+	assert.EqualError(t, err, "1/1 creations(s) failed")
+
+	_, err = templateToCBOR("unknown.json", ".", true, jsonLimits{}, envExpansion{}, nil, psaIDs{})
+	assert.EqualError(t, err, `error decoding template from unknown.json: unknown field "/unknown-field"`)
+
+	// tolerant decoding is the default
+	_, err = templateToCBOR("unknown.json", ".", false, jsonLimits{}, envExpansion{}, nil, psaIDs{})
+	assert.NoError(t, err)
+}
+
+func Test_ComidCreateCmd_output_dir_is_a_file(t *testing.T) {
+	var err error
+
+	cmd := NewComidCreateCmd()
+
+	fs = afero.NewMemMapFs()
+	err = afero.WriteFile(fs, "ok.json", []byte(comid.PSARefValJSONTemplate), 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "out", []byte("hello!"), 0644)
+	require.NoError(t, err)
+
+	args := []string{
+		"--template=ok.json",
+		"--output-dir=out",
+	}
+	cmd.SetArgs(args)
+
+	err = cmd.Execute()
+	assert.EqualError(t, err, "output directory out exists but is not a directory")
+}
+
+func Test_ComidCreateCmd_output_dir_created(t *testing.T) {
+	var err error
+
+	cmd := NewComidCreateCmd()
+
+	fs = afero.NewMemMapFs()
+	err = afero.WriteFile(fs, "ok.json", []byte(comid.PSARefValJSONTemplate), 0644)
+	require.NoError(t, err)
+
+	args := []string{
+		"--template=ok.json",
+		"--output-dir=new/out",
+		"--dir-mode=0750",
+	}
+	cmd.SetArgs(args)
+
+	err = cmd.Execute()
+	assert.NoError(t, err)
+
+	fi, err := fs.Stat("new/out")
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0750), fi.Mode().Perm())
+
+	_, err = fs.Stat("new/out/ok.cbor")
+	assert.NoError(t, err)
+}
+
+func Test_ComidCreateCmd_bad_dir_mode(t *testing.T) {
+	var err error
+
+	cmd := NewComidCreateCmd()
+
+	fs = afero.NewMemMapFs()
+	err = afero.WriteFile(fs, "ok.json", []byte(comid.PSARefValJSONTemplate), 0644)
+	require.NoError(t, err)
+
+	args := []string{
+		"--template=ok.json",
+		"--dir-mode=rwx",
+	}
+	cmd.SetArgs(args)
+
+	err = cmd.Execute()
+	assert.EqualError(t, err, `invalid directory mode "rwx": expecting octal permission bits, e.g., 0755`)
+}
@@ -0,0 +1,340 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/swid"
+)
+
+var (
+	comidDiffOldFile *string
+	comidDiffNewFile *string
+	comidDiffJSON    *bool
+	comidDiffFormat  jsonFormat
+)
+
+var comidDiffCmd = NewComidDiffCmd()
+
+func NewComidDiffCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff",
+		Short: "compare the measurements of two CBOR-encoded CoMIDs",
+		Long: `compare the measurements of two CBOR-encoded CoMIDs
+
+	Report the reference and endorsed value measurements that have been added,
+	removed or changed between old.cbor and new.cbor.  Measurements are matched
+	by environment and measurement key.
+
+	  cocli comid diff --old=old.cbor --new=new.cbor
+
+	Same as above, but print the report in JSON format
+
+	  cocli comid diff --old=old.cbor --new=new.cbor --json
+
+	Same as above, but indent the JSON report with 4 spaces (or use
+	--json-compact to print it on a single line)
+
+	  cocli comid diff --old=old.cbor --new=new.cbor --json --json-indent=4
+	`,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkComidDiffArgs(); err != nil {
+				return err
+			}
+
+			var jf *jsonFormat
+			if *comidDiffJSON {
+				jf = &comidDiffFormat
+			}
+
+			return comidDiff(*comidDiffOldFile, *comidDiffNewFile, jf)
+		},
+	}
+
+	comidDiffOldFile = cmd.Flags().String("old", "", "the old CoMID file (in CBOR format)")
+	comidDiffNewFile = cmd.Flags().String("new", "", "the new CoMID file (in CBOR format)")
+	comidDiffJSON = cmd.Flags().Bool("json", false, "print the differences in JSON format")
+	addJSONFormatFlags(cmd, &comidDiffFormat)
+
+	return cmd
+}
+
+func checkComidDiffArgs() error {
+	if comidDiffOldFile == nil || *comidDiffOldFile == "" {
+		return errors.New("no old CoMID supplied")
+	}
+
+	if comidDiffNewFile == nil || *comidDiffNewFile == "" {
+		return errors.New("no new CoMID supplied")
+	}
+
+	if (comidDiffJSON == nil || !*comidDiffJSON) && !comidDiffFormat.isDefault() {
+		return errors.New("--json-indent and --json-compact can only be used together with --json")
+	}
+
+	return comidDiffFormat.valid()
+}
+
+// measurementDiff describes a single measurement that differs between two
+// CoMIDs
+type measurementDiff struct {
+	Change      string          `json:"change"`
+	Triple      string          `json:"triple"`
+	Environment json.RawMessage `json:"environment"`
+	Key         json.RawMessage `json:"key,omitempty"`
+	OldDigests  []string        `json:"old-digests,omitempty"`
+	NewDigests  []string        `json:"new-digests,omitempty"`
+}
+
+// indexedMeasurement is a measurement together with the identifying
+// information used to match it across CoMIDs
+type indexedMeasurement struct {
+	id          string
+	triple      string
+	environment json.RawMessage
+	key         json.RawMessage
+	value       []byte
+	digests     []string
+	hashes      []swid.HashEntry
+}
+
+// comidDiff prints the differences between the CoMIDs in oldFile and newFile,
+// in JSON format if jf is not nil
+func comidDiff(oldFile, newFile string, jf *jsonFormat) error {
+	oldComid, err := loadComid(oldFile)
+	if err != nil {
+		return err
+	}
+
+	newComid, err := loadComid(newFile)
+	if err != nil {
+		return err
+	}
+
+	diffs, err := diffComids(oldComid, newComid)
+	if err != nil {
+		return err
+	}
+
+	if jf != nil {
+		if diffs == nil {
+			diffs = []measurementDiff{}
+		}
+
+		j, err := jf.marshal(diffs)
+		if err != nil {
+			return fmt.Errorf("error encoding differences: %w", err)
+		}
+
+		fmt.Println(string(j))
+
+		return nil
+	}
+
+	printMeasurementDiffs(diffs)
+
+	return nil
+}
+
+func loadComid(file string) (*comid.Comid, error) {
+	var (
+		data []byte
+		c    comid.Comid
+		err  error
+	)
+
+	if data, err = afero.ReadFile(fs, file); err != nil {
+		return nil, fmt.Errorf("error loading CoMID from %s: %w", file, err)
+	}
+
+	if err = decodeCBOR(&c, data, false); err != nil {
+		return nil, fmt.Errorf("error decoding CoMID from %s: %w", file, err)
+	}
+
+	return &c, nil
+}
+
+// diffComids matches the reference and endorsed value measurements of o
+// and n by triple type, environment and measurement key, and returns the
+// measurements that were removed, changed or added (in that order)
+func diffComids(o, n *comid.Comid) ([]measurementDiff, error) {
+	oldMs, err := indexMeasurements(o)
+	if err != nil {
+		return nil, err
+	}
+
+	newMs, err := indexMeasurements(n)
+	if err != nil {
+		return nil, err
+	}
+
+	newByID := make(map[string]indexedMeasurement, len(newMs))
+	for _, m := range newMs {
+		newByID[m.id] = m
+	}
+
+	oldByID := make(map[string]indexedMeasurement, len(oldMs))
+	for _, m := range oldMs {
+		oldByID[m.id] = m
+	}
+
+	var removed, changed, added []measurementDiff
+
+	for _, om := range oldMs {
+		nm, ok := newByID[om.id]
+		switch {
+		case !ok:
+			removed = append(removed, newMeasurementDiff("removed", &om, nil))
+		case !bytes.Equal(om.value, nm.value):
+			changed = append(changed, newMeasurementDiff("changed", &om, &nm))
+		}
+	}
+
+	for _, nm := range newMs {
+		if _, ok := oldByID[nm.id]; !ok {
+			added = append(added, newMeasurementDiff("added", nil, &nm))
+		}
+	}
+
+	return append(append(removed, changed...), added...), nil
+}
+
+func newMeasurementDiff(change string, o, n *indexedMeasurement) measurementDiff {
+	ref := o
+	if ref == nil {
+		ref = n
+	}
+
+	d := measurementDiff{
+		Change:      change,
+		Triple:      ref.triple,
+		Environment: ref.environment,
+		Key:         ref.key,
+	}
+
+	if o != nil {
+		d.OldDigests = o.digests
+	}
+
+	if n != nil {
+		d.NewDigests = n.digests
+	}
+
+	return d
+}
+
+func indexMeasurements(c *comid.Comid) ([]indexedMeasurement, error) {
+	var ret []indexedMeasurement
+
+	seen := make(map[string]int)
+
+	triples := []struct {
+		name string
+		vts  *comid.ValueTriples
+	}{
+		{"reference-values", c.Triples.ReferenceValues},
+		{"endorsed-values", c.Triples.EndorsedValues},
+	}
+
+	for _, t := range triples {
+		if t.vts == nil {
+			continue
+		}
+
+		for _, vt := range t.vts.Values {
+			env, err := json.Marshal(vt.Environment)
+			if err != nil {
+				return nil, fmt.Errorf("error encoding environment: %w", err)
+			}
+
+			for _, m := range vt.Measurements.Values {
+				im, err := newIndexedMeasurement(t.name, env, m)
+				if err != nil {
+					return nil, err
+				}
+
+				// disambiguate measurements sharing environment and key
+				seen[im.id]++
+				im.id = fmt.Sprintf("%s#%d", im.id, seen[im.id])
+
+				ret = append(ret, im)
+			}
+		}
+	}
+
+	return ret, nil
+}
+
+func newIndexedMeasurement(triple string, env json.RawMessage, m comid.Measurement) (indexedMeasurement, error) {
+	im := indexedMeasurement{
+		triple:      triple,
+		environment: env,
+	}
+
+	if m.Key != nil && m.Key.IsSet() {
+		key, err := json.Marshal(m.Key)
+		if err != nil {
+			return im, fmt.Errorf("error encoding measurement key: %w", err)
+		}
+		im.key = key
+	}
+
+	value, err := json.Marshal(m.Val)
+	if err != nil {
+		return im, fmt.Errorf("error encoding measurement value: %w", err)
+	}
+	im.value = value
+
+	if m.Val.Digests != nil {
+		for _, d := range *m.Val.Digests {
+			im.digests = append(im.digests, d.String())
+			im.hashes = append(im.hashes, d)
+		}
+	}
+
+	im.id = fmt.Sprintf("%s|%s|%s", triple, env, im.key)
+
+	return im, nil
+}
+
+func printMeasurementDiffs(diffs []measurementDiff) {
+	var added, removed, changed int
+
+	for _, d := range diffs {
+		fmt.Printf(">> %s %s measurement\n", d.Change, d.Triple)
+		fmt.Printf("   environment: %s\n", d.Environment)
+		if d.Key != nil {
+			fmt.Printf("   key: %s\n", d.Key)
+		}
+		for _, v := range d.OldDigests {
+			fmt.Printf("   - %s\n", v)
+		}
+		for _, v := range d.NewDigests {
+			fmt.Printf("   + %s\n", v)
+		}
+
+		switch d.Change {
+		case "added":
+			added++
+		case "removed":
+			removed++
+		case "changed":
+			changed++
+		}
+	}
+
+	fmt.Printf(">> %d added, %d removed, %d changed\n", added, removed, changed)
+}
+
+func init() {
+	comidCmd.AddCommand(comidDiffCmd)
+}
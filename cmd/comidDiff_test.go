@@ -0,0 +1,157 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/comid"
+)
+
+func newTestComid(t *testing.T) *comid.Comid {
+	var c comid.Comid
+	require.NoError(t, c.FromJSON([]byte(comid.PSARefValJSONTemplate)))
+	return &c
+}
+
+func Test_ComidDiffCmd_unknown_argument(t *testing.T) {
+	cmd := NewComidDiffCmd()
+
+	args := []string{"--unknown-argument=val"}
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "unknown flag: --unknown-argument")
+}
+
+func Test_ComidDiffCmd_no_old(t *testing.T) {
+	cmd := NewComidDiffCmd()
+
+	args := []string{"--new=new.cbor"}
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "no old CoMID supplied")
+}
+
+func Test_ComidDiffCmd_no_new(t *testing.T) {
+	cmd := NewComidDiffCmd()
+
+	args := []string{"--old=old.cbor", "--new="}
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "no new CoMID supplied")
+}
+
+func Test_ComidDiffCmd_old_not_found(t *testing.T) {
+	cmd := NewComidDiffCmd()
+
+	fs = afero.NewMemMapFs()
+
+	args := []string{"--old=old.cbor", "--new=new.cbor"}
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "error loading CoMID from old.cbor: open old.cbor: file does not exist")
+}
+
+func Test_ComidDiffCmd_bad_new(t *testing.T) {
+	cmd := NewComidDiffCmd()
+
+	fs = afero.NewMemMapFs()
+
+	data, err := newTestComid(t).ToCBOR()
+	require.NoError(t, err)
+	require.NoError(t, afero.WriteFile(fs, "old.cbor", data, 0644))
+	require.NoError(t, afero.WriteFile(fs, "new.cbor", []byte{0xff, 0xff}, 0644))
+
+	args := []string{"--old=old.cbor", "--new=new.cbor"}
+	cmd.SetArgs(args)
+
+	err = cmd.Execute()
+	assert.ErrorContains(t, err, "error decoding CoMID from new.cbor")
+}
+
+func Test_ComidDiffCmd_ok(t *testing.T) {
+	fs = afero.NewMemMapFs()
+
+	o := newTestComid(t)
+	n := newTestComid(t)
+	(*n.Triples.ReferenceValues).Values[0].Measurements.Values[0].Val.Digests =
+		comid.NewDigests().AddDigest(1, make([]byte, 32))
+
+	data, err := o.ToCBOR()
+	require.NoError(t, err)
+	require.NoError(t, afero.WriteFile(fs, "old.cbor", data, 0644))
+
+	data, err = n.ToCBOR()
+	require.NoError(t, err)
+	require.NoError(t, afero.WriteFile(fs, "new.cbor", data, 0644))
+
+	for _, format := range []string{"--json=false", "--json"} {
+		cmd := NewComidDiffCmd()
+
+		args := []string{"--old=old.cbor", "--new=new.cbor", format}
+		cmd.SetArgs(args)
+
+		assert.NoError(t, cmd.Execute())
+	}
+}
+
+func Test_diffComids_identical(t *testing.T) {
+	diffs, err := diffComids(newTestComid(t), newTestComid(t))
+	require.NoError(t, err)
+	assert.Empty(t, diffs)
+}
+
+func Test_diffComids_changed(t *testing.T) {
+	o := newTestComid(t)
+	n := newTestComid(t)
+
+	(*n.Triples.ReferenceValues).Values[0].Measurements.Values[0].Val.Digests =
+		comid.NewDigests().AddDigest(1, make([]byte, 32))
+
+	diffs, err := diffComids(o, n)
+	require.NoError(t, err)
+	require.Len(t, diffs, 1)
+
+	assert.Equal(t, "changed", diffs[0].Change)
+	assert.Equal(t, "reference-values", diffs[0].Triple)
+	assert.Contains(t, string(diffs[0].Key), `"label":"BL"`)
+	assert.Equal(t, []string{"sha-256;h0KPxSKAPTEGXnvOPPA/5HUJZjHl4Hu9eg/eYMTPJcc="}, diffs[0].OldDigests)
+	assert.Equal(t, []string{"sha-256;AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="}, diffs[0].NewDigests)
+}
+
+func Test_diffComids_added_removed(t *testing.T) {
+	o := newTestComid(t)
+	n := newTestComid(t)
+
+	ms := &(*n.Triples.ReferenceValues).Values[0].Measurements
+	removedKey, err := ms.Values[0].Key.MarshalJSON()
+	require.NoError(t, err)
+	ms.Values = ms.Values[1:]
+
+	m, err := comid.NewUintMeasurement(uint64(7))
+	require.NoError(t, err)
+	m.AddDigest(1, make([]byte, 32))
+	ms.Add(m)
+
+	diffs, err := diffComids(o, n)
+	require.NoError(t, err)
+	require.Len(t, diffs, 2)
+
+	assert.Equal(t, "removed", diffs[0].Change)
+	assert.JSONEq(t, string(removedKey), string(diffs[0].Key))
+	assert.NotEmpty(t, diffs[0].OldDigests)
+	assert.Empty(t, diffs[0].NewDigests)
+
+	assert.Equal(t, "added", diffs[1].Change)
+	assert.JSONEq(t, `{"type":"uint","value":7}`, string(diffs[1].Key))
+	assert.Empty(t, diffs[1].OldDigests)
+	assert.Equal(t, []string{"sha-256;AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="}, diffs[1].NewDigests)
+}
@@ -6,14 +6,25 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"os"
 
 	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
+	"github.com/veraison/eat"
 )
 
 var (
-	comidDisplayFiles []string
-	comidDisplayDirs  []string
+	comidDisplayFiles        []string
+	comidDisplayDirs         []string
+	comidDisplayStrictDecode *bool
+	comidDisplayVerifKeys    *bool
+	comidDisplayRawValues    *bool
+	comidDisplayLinks        *bool
+	comidDisplayEnvSummary   *bool
+	comidDisplayJSON         *bool
+	comidDisplayJSONFormat   jsonFormat
+	comidDisplayOutputFile   *string
+	comidDisplayProfile      *string
 )
 
 var comidDisplayCmd = NewComidDisplayCmd()
@@ -33,6 +44,44 @@ func NewComidDisplayCmd() *cobra.Command {
 	directory.
 	
 	  cocli comid display --file=c1.cbor --file=c2.cbor --dir=comids
+
+	Only display the keys found in the attester verification key triples of the
+	CoMID in file c.cbor, together with their environment, thumbprint and usage.
+	Use --json to print them in JSON format instead.
+
+	  cocli comid display --file=c.cbor --verification-keys [--json]
+
+	Only display the measurement values of the reference and endorsed values
+	triples of the CoMID in file c.cbor, each with its CBOR type and a
+	type-specific rendering (e.g., MAC addresses with colons, SVNs as integers,
+	flags expanded).  Use --json to print them in JSON format instead.
+
+	  cocli comid display --file=c.cbor --raw-values [--json]
+
+	Only display the linked tags of the CoMIDs in the comids/ directory, i.e.,
+	the supersedes (replaces) and supplements relationships from each CoMID
+	(the source tag) to other tags (the target tags).  Use --json to print them
+	in JSON format instead.
+
+	  cocli comid display --dir=comids --links [--json]
+
+	Only display a summary of the CoMID in file c.cbor, with one row per
+	distinct environment and the number of reference values, endorsed values,
+	attester verification keys and device identity keys it carries.  Use --json
+	to print it in JSON format instead.
+
+	  cocli comid display --file=c.cbor --environment-summary [--json]
+
+	Print the linked tags of the CoMIDs in the comids/ directory in JSON
+	format, on a single line per CoMID (use --json-indent to choose the
+	indentation instead)
+
+	  cocli comid display --dir=comids --links --json --json-compact
+
+	Save the rendering of the CoMID in file c.cbor to c.json instead of
+	printing it.
+
+	  cocli comid display --file=c.cbor --output=c.json
 	`,
 
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -45,19 +94,44 @@ func NewComidDisplayCmd() *cobra.Command {
 				return errors.New("no files found")
 			}
 
-			errs := 0
-			for _, file := range filesList {
-				if err := displayComidFile(file); err != nil {
-					fmt.Printf(">> failed displaying %q: %v\n", file, err)
-					errs++
-					continue
-				}
+			profile, err := parseProfile(*comidDisplayProfile)
+			if err != nil {
+				return err
 			}
 
-			if errs != 0 {
-				return fmt.Errorf("%d/%d display(s) failed", errs, len(filesList))
+			var jf *jsonFormat
+			if *comidDisplayJSON {
+				jf = &comidDisplayJSONFormat
 			}
-			return nil
+
+			return withDisplayOutput(*comidDisplayOutputFile, func() error {
+				errs := 0
+				for _, file := range filesList {
+					var err error
+					switch {
+					case *comidDisplayVerifKeys:
+						err = displayComidVerificationKeys(file, profile, *comidDisplayStrictDecode, jf)
+					case *comidDisplayRawValues:
+						err = displayComidRawValues(file, profile, *comidDisplayStrictDecode, jf)
+					case *comidDisplayLinks:
+						err = displayComidLinks(file, profile, *comidDisplayStrictDecode, jf)
+					case *comidDisplayEnvSummary:
+						err = displayComidEnvironmentSummary(file, profile, *comidDisplayStrictDecode, jf)
+					default:
+						err = displayComidFile(file, profile, *comidDisplayStrictDecode)
+					}
+					if err != nil {
+						fmt.Printf(">> failed displaying %q: %v\n", file, err)
+						errs++
+						continue
+					}
+				}
+
+				if errs != 0 {
+					return fmt.Errorf("%d/%d display(s) failed", errs, len(filesList))
+				}
+				return nil
+			})
 		},
 	}
 
@@ -69,10 +143,44 @@ func NewComidDisplayCmd() *cobra.Command {
 		&comidDisplayDirs, "dir", "d", []string{}, "a directory containing CoMID files (in CBOR format)",
 	)
 
+	comidDisplayStrictDecode = cmd.Flags().Bool(
+		"strict-decode", false, "reject CoMIDs carrying fields that are not understood",
+	)
+
+	comidDisplayVerifKeys = cmd.Flags().Bool(
+		"verification-keys", false, "only display the attester verification keys",
+	)
+
+	comidDisplayRawValues = cmd.Flags().Bool(
+		"raw-values", false, "only display the measurement values, with their CBOR type",
+	)
+
+	comidDisplayLinks = cmd.Flags().Bool(
+		"links", false, "only display the linked tags (source tag, relationship and target tag)",
+	)
+
+	comidDisplayEnvSummary = cmd.Flags().Bool(
+		"environment-summary", false, "only display the number of reference values, endorsed values and keys of each environment",
+	)
+
+	comidDisplayJSON = cmd.Flags().Bool(
+		"json", false, "print the attester verification keys, measurement values, linked tags or environment summary in JSON format (with --verification-keys, --raw-values, --links or --environment-summary)",
+	)
+
+	addJSONFormatFlags(cmd, &comidDisplayJSONFormat)
+
+	comidDisplayOutputFile = cmd.Flags().StringP(
+		"output", "o", "", "save the rendered output to this file instead of printing it",
+	)
+
+	comidDisplayProfile = cmd.Flags().String(
+		"profile", "", "decode the CoMIDs with the extensions registered for this profile (see --profile-def)",
+	)
+
 	return cmd
 }
 
-func displayComidFile(file string) error {
+func displayComidFile(file string, profile *eat.Profile, strict bool) error {
 	var (
 		data []byte
 		err  error
@@ -83,14 +191,212 @@ func displayComidFile(file string) error {
 	}
 
 	// use file name as heading
-	return printComid(data, ">> ["+file+"]")
+	return printComid(data, ">> ["+file+"]", profile, strict)
+}
+
+func displayComidVerificationKeys(file string, profile *eat.Profile, strict bool, jf *jsonFormat) error {
+	var (
+		data []byte
+		err  error
+	)
+
+	if data, err = afero.ReadFile(fs, file); err != nil {
+		return fmt.Errorf("error loading CoMID from %s: %w", file, err)
+	}
+
+	c := newComid(profile)
+
+	if err = decodeCBOR(c, data, strict); err != nil {
+		return fmt.Errorf("error decoding CoMID from %s: %w", file, err)
+	}
+
+	views, err := verificationKeys(c)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(">> [" + file + "]")
+
+	if jf != nil {
+		j, err := jf.marshal(views)
+		if err != nil {
+			return fmt.Errorf("error encoding verification keys: %w", err)
+		}
+		fmt.Println(string(j))
+		return nil
+	}
+
+	for _, v := range views {
+		fmt.Printf("environment: %s\n", v.Environment)
+		fmt.Printf("  type: %s\n", v.Type)
+		fmt.Printf("  thumbprint: %s\n", v.Thumbprint)
+		fmt.Printf("  usage: %s\n", v.Usage)
+		if len(v.CertKeyUsage) != 0 {
+			fmt.Printf("  certificate key usage: %v\n", v.CertKeyUsage)
+		}
+	}
+
+	return nil
+}
+
+func displayComidRawValues(file string, profile *eat.Profile, strict bool, jf *jsonFormat) error {
+	var (
+		data []byte
+		err  error
+	)
+
+	if data, err = afero.ReadFile(fs, file); err != nil {
+		return fmt.Errorf("error loading CoMID from %s: %w", file, err)
+	}
+
+	c := newComid(profile)
+
+	if err = decodeCBOR(c, data, strict); err != nil {
+		return fmt.Errorf("error decoding CoMID from %s: %w", file, err)
+	}
+
+	views, err := rawValues(c)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(">> [" + file + "]")
+
+	if jf != nil {
+		if views == nil {
+			views = []measurementValuesView{}
+		}
+
+		j, err := jf.marshal(views)
+		if err != nil {
+			return fmt.Errorf("error encoding measurement values: %w", err)
+		}
+		fmt.Println(string(j))
+		return nil
+	}
+
+	for _, v := range views {
+		fmt.Printf("%s: %s\n", v.Triple, v.Environment)
+		fmt.Printf("  key: %s\n", orDash(string(v.Key)))
+		for _, rv := range v.Values {
+			fmt.Printf("  %s (%s): %s\n", rv.Name, rv.CBORType, rv.Value)
+		}
+	}
+
+	return nil
+}
+
+func displayComidLinks(file string, profile *eat.Profile, strict bool, jf *jsonFormat) error {
+	var (
+		data []byte
+		err  error
+	)
+
+	if data, err = afero.ReadFile(fs, file); err != nil {
+		return fmt.Errorf("error loading CoMID from %s: %w", file, err)
+	}
+
+	c := newComid(profile)
+
+	if err = decodeCBOR(c, data, strict); err != nil {
+		return fmt.Errorf("error decoding CoMID from %s: %w", file, err)
+	}
+
+	views := linkedTags(c)
+
+	fmt.Println(">> [" + file + "]")
+
+	if jf != nil {
+		j, err := jf.marshal(views)
+		if err != nil {
+			return fmt.Errorf("error encoding linked tags: %w", err)
+		}
+		fmt.Println(string(j))
+		return nil
+	}
+
+	if len(views) == 0 {
+		fmt.Println("no linked tags")
+		return nil
+	}
+
+	for _, v := range views {
+		fmt.Println(v)
+	}
+
+	return nil
+}
+
+func displayComidEnvironmentSummary(file string, profile *eat.Profile, strict bool, jf *jsonFormat) error {
+	var (
+		data []byte
+		err  error
+	)
+
+	if data, err = afero.ReadFile(fs, file); err != nil {
+		return fmt.Errorf("error loading CoMID from %s: %w", file, err)
+	}
+
+	c := newComid(profile)
+
+	if err = decodeCBOR(c, data, strict); err != nil {
+		return fmt.Errorf("error decoding CoMID from %s: %w", file, err)
+	}
+
+	views, err := environmentSummary(c)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(">> [" + file + "]")
+
+	if jf != nil {
+		if views == nil {
+			views = []environmentSummaryView{}
+		}
+
+		j, err := jf.marshal(views)
+		if err != nil {
+			return fmt.Errorf("error encoding environment summary: %w", err)
+		}
+		fmt.Println(string(j))
+		return nil
+	}
+
+	if len(views) == 0 {
+		fmt.Println("no environments")
+		return nil
+	}
+
+	return printEnvironmentSummary(os.Stdout, views)
 }
 
 func checkComidDisplayArgs() error {
 	if len(comidDisplayFiles) == 0 && len(comidDisplayDirs) == 0 {
 		return errors.New("no files supplied")
 	}
-	return nil
+
+	if *comidDisplayVerifKeys && *comidDisplayRawValues {
+		return errors.New("--verification-keys and --raw-values cannot be used together")
+	}
+
+	if *comidDisplayLinks && (*comidDisplayVerifKeys || *comidDisplayRawValues) {
+		return errors.New("--links cannot be used together with --verification-keys or --raw-values")
+	}
+
+	if *comidDisplayEnvSummary && (*comidDisplayVerifKeys || *comidDisplayRawValues || *comidDisplayLinks) {
+		return errors.New("--environment-summary cannot be used together with --verification-keys, --raw-values or --links")
+	}
+
+	if *comidDisplayJSON && !*comidDisplayVerifKeys && !*comidDisplayRawValues && !*comidDisplayLinks && !*comidDisplayEnvSummary {
+		return errors.New("--json can only be used together with --verification-keys, --raw-values, --links or --environment-summary")
+	}
+
+	if !*comidDisplayJSON && !comidDisplayJSONFormat.isDefault() {
+		return errors.New("--json-indent and --json-compact can only be used together with --json")
+	}
+
+	return comidDisplayJSONFormat.valid()
 }
 
 func init() {
@@ -10,6 +10,7 @@ import (
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/comid"
 )
 
 func Test_ComidDisplayCmd_unknown_argument(t *testing.T) {
@@ -99,3 +100,58 @@ func Test_ComidDisplayCmd_file_with_valid_comid_from_dir(t *testing.T) {
 	err = cmd.Execute()
 	assert.NoError(t, err)
 }
+
+func Test_ComidDisplayCmd_json_without_verification_keys(t *testing.T) {
+	cmd := NewComidDisplayCmd()
+
+	args := []string{
+		"--file=ok.cbor",
+		"--json",
+	}
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "--json can only be used together with --verification-keys, --raw-values, --links or --environment-summary")
+}
+
+func Test_ComidDisplayCmd_verification_keys_ok(t *testing.T) {
+	var c comid.Comid
+	require.NoError(t, c.FromJSON([]byte(comid.PSAKeysJSONTemplate)))
+	data, err := c.ToCBOR()
+	require.NoError(t, err)
+
+	fs = afero.NewMemMapFs()
+	err = afero.WriteFile(fs, "keys.cbor", data, 0644)
+	require.NoError(t, err)
+
+	for _, format := range []string{"--json=false", "--json"} {
+		cmd := NewComidDisplayCmd()
+
+		args := []string{
+			"--file=keys.cbor",
+			"--verification-keys",
+			format,
+		}
+		cmd.SetArgs(args)
+
+		err = cmd.Execute()
+		assert.NoError(t, err)
+	}
+}
+
+func Test_ComidDisplayCmd_verification_keys_bad_cbor(t *testing.T) {
+	cmd := NewComidDisplayCmd()
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "invalid.cbor", []byte{0xff, 0xff}, 0400)
+	require.NoError(t, err)
+
+	args := []string{
+		"--file=invalid.cbor",
+		"--verification-keys",
+	}
+	cmd.SetArgs(args)
+
+	err = cmd.Execute()
+	assert.EqualError(t, err, "1/1 display(s) failed")
+}
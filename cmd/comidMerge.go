@@ -0,0 +1,275 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/spf13/afero"
+	"github.com/veraison/corim/comid"
+)
+
+// measurementFragment holds the measurements read from one of the files
+// merged by comid create --merge-measurements
+type measurementFragment struct {
+	file         string
+	measurements []comid.Measurement
+}
+
+// parseMeasurementFragment decodes data, which is either a single measurement
+// (in the JSON format used by the measurements of CoMID templates), or an array
+// of measurements
+func parseMeasurementFragment(data []byte) ([]comid.Measurement, error) {
+	var ms []comid.Measurement
+
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		if err := json.Unmarshal(data, &ms); err != nil {
+			return nil, err
+		}
+	} else {
+		var m comid.Measurement
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, err
+		}
+		ms = append(ms, m)
+	}
+
+	if len(ms) == 0 {
+		return nil, errors.New("no measurements found")
+	}
+
+	for i, m := range ms {
+		if err := m.Valid(); err != nil {
+			return nil, fmt.Errorf("measurement [%d]: %w", i, err)
+		}
+	}
+
+	return ms, nil
+}
+
+// mergeConflictPolicy tells comid create --merge-measurements what to do when
+// two measurements with the same key carry different values
+type mergeConflictPolicy string
+
+const (
+	// mergeConflictError rejects measurements with the same key, the default
+	mergeConflictError mergeConflictPolicy = "error"
+	// mergeConflictKeepFirst keeps the measurement found first
+	mergeConflictKeepFirst mergeConflictPolicy = "keep-first"
+	// mergeConflictKeepLast replaces the measurement found first with the one
+	// found last, in place
+	mergeConflictKeepLast mergeConflictPolicy = "keep-last"
+	// mergeConflictKeepBoth keeps all the measurements
+	mergeConflictKeepBoth mergeConflictPolicy = "keep-both"
+)
+
+// parseMergeConflictPolicy parses the --on-conflict switch, where the empty
+// string stands for the default policy
+func parseMergeConflictPolicy(s string) (mergeConflictPolicy, error) {
+	switch p := mergeConflictPolicy(s); p {
+	case "":
+		return mergeConflictError, nil
+	case mergeConflictError, mergeConflictKeepFirst, mergeConflictKeepLast, mergeConflictKeepBoth:
+		return p, nil
+	}
+
+	return "", fmt.Errorf("invalid --on-conflict %q: expecting one of error, keep-first, keep-last or keep-both", s)
+}
+
+// mergedMeasurement is a measurement selected by mergeMeasurements, together
+// with the file it comes from and its JSON encoding
+type mergedMeasurement struct {
+	file        string
+	measurement *comid.Measurement
+	encoded     []byte
+}
+
+// mergeMeasurements collects the measurements of all the fragments, in order.
+// With the error policy, measurements with the same key are rejected.
+// Otherwise, a measurement identical to one already collected is dropped, and
+// one with the same key but a different value is resolved according to policy
+// and reported.  The number of conflicts found is returned along with the
+// measurements.
+func mergeMeasurements(fragments []measurementFragment, policy mergeConflictPolicy) ([]mergedMeasurement, int, error) {
+	var (
+		merged    []mergedMeasurement
+		conflicts int
+	)
+
+	// the positions in merged of the measurements collected for each key
+	byKey := make(map[string][]int)
+
+	for _, f := range fragments {
+		for i := range f.measurements {
+			m := &f.measurements[i]
+
+			if m.Key == nil || !m.Key.IsSet() {
+				merged = append(merged, mergedMeasurement{file: f.file, measurement: m})
+				continue
+			}
+
+			key, err := json.Marshal(m.Key)
+			if err != nil {
+				return nil, 0, fmt.Errorf("%s: error encoding measurement key: %w", f.file, err)
+			}
+
+			encoded, err := json.Marshal(m)
+			if err != nil {
+				return nil, 0, fmt.Errorf("%s: error encoding measurement %s: %w", f.file, key, err)
+			}
+
+			cur := mergedMeasurement{file: f.file, measurement: m, encoded: encoded}
+
+			positions, ok := byKey[string(key)]
+			if !ok {
+				byKey[string(key)] = []int{len(merged)}
+				merged = append(merged, cur)
+				continue
+			}
+
+			first := merged[positions[0]]
+
+			if policy == mergeConflictError {
+				return nil, 0, fmt.Errorf("%s: duplicate measurement key %s (already in %s)", f.file, key, first.file)
+			}
+
+			if same := findMergedMeasurement(merged, positions, encoded); same != nil {
+				fmt.Printf(">> warning: %s: measurement key %s repeats the one in %s, dropping it\n", f.file, key, same.file)
+				continue
+			}
+
+			conflicts++
+
+			switch policy {
+			case mergeConflictKeepFirst:
+				fmt.Printf(">> conflict: %s: measurement key %s differs from the one in %s, keeping the one in %s\n",
+					f.file, key, first.file, first.file)
+			case mergeConflictKeepLast:
+				fmt.Printf(">> conflict: %s: measurement key %s differs from the one in %s, keeping the one in %s\n",
+					f.file, key, first.file, f.file)
+				merged[positions[0]] = cur
+			case mergeConflictKeepBoth:
+				fmt.Printf(">> conflict: %s: measurement key %s differs from the one in %s, keeping both\n",
+					f.file, key, first.file)
+				byKey[string(key)] = append(positions, len(merged))
+				merged = append(merged, cur)
+			}
+		}
+	}
+
+	return merged, conflicts, nil
+}
+
+// findMergedMeasurement returns the measurement, among those of merged at
+// positions, whose JSON encoding is encoded, or nil if there is none
+func findMergedMeasurement(merged []mergedMeasurement, positions []int, encoded []byte) *mergedMeasurement {
+	for _, p := range positions {
+		if bytes.Equal(merged[p].encoded, encoded) {
+			return &merged[p]
+		}
+	}
+
+	return nil
+}
+
+// mergedComid builds a CoMID with a single reference-value triple, for the
+// environment of the supplied class, carrying the measurements of all the
+// fragments, in order, with the measurements sharing a key handled according
+// to policy (see mergeMeasurements).  If tagID is empty, a random UUID is used
+// as the tag identifier.  The number of conflicts found is returned along with
+// the CoMID.
+func mergedComid(fragments []measurementFragment, class *comid.Class, tagID string, policy mergeConflictPolicy) (*comid.Comid, int, error) {
+	var c comid.Comid
+
+	var id interface{} = tagID
+	if tagID == "" {
+		id = uuid.New()
+	}
+
+	if c.SetTagIdentity(id, 0) == nil {
+		return nil, 0, fmt.Errorf("invalid tag id %q", tagID)
+	}
+
+	merged, conflicts, err := mergeMeasurements(fragments, policy)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	measurements := comid.NewMeasurements()
+	for _, m := range merged {
+		measurements.Add(m.measurement)
+	}
+
+	env := comid.Environment{
+		Class: class,
+	}
+
+	if c.AddReferenceValue(comid.ValueTriple{Environment: env, Measurements: *measurements}) == nil {
+		return nil, 0, errors.New("error adding reference values")
+	}
+
+	if err := c.Valid(); err != nil {
+		return nil, 0, fmt.Errorf("error validating CoMID: %w", err)
+	}
+
+	if err := checkRawValueMasks(&c); err != nil {
+		return nil, 0, fmt.Errorf("error validating CoMID: %w", err)
+	}
+
+	return &c, conflicts, nil
+}
+
+// mergeMeasurementsToCBOR creates a CoMID from the measurement fragments in
+// files and saves it, CBOR-encoded, to cborFile
+func mergeMeasurementsToCBOR(files []string, cborFile string, ec envClass, tagID string, limits jsonLimits, env envExpansion, policy mergeConflictPolicy) error {
+	var fragments []measurementFragment
+
+	for _, file := range files {
+		data, err := readJSONTemplate(file, limits, env)
+		if err != nil {
+			return fmt.Errorf("error loading measurements from %s: %w", file, err)
+		}
+
+		ms, err := parseMeasurementFragment(data)
+		if err != nil {
+			return fmt.Errorf("error decoding measurements from %s: %w", file, err)
+		}
+
+		fragments = append(fragments, measurementFragment{file: file, measurements: ms})
+	}
+
+	class, err := ec.class()
+	if err != nil {
+		return err
+	}
+
+	c, conflicts, err := mergedComid(fragments, class, tagID, policy)
+	if err != nil {
+		return err
+	}
+
+	cborData, err := c.ToCBOR()
+	if err != nil {
+		return fmt.Errorf("error encoding CoMID to CBOR: %w", err)
+	}
+
+	if err = afero.WriteFile(fs, cborFile, cborData, 0644); err != nil {
+		return fmt.Errorf("error saving CBOR file %s: %w", cborFile, err)
+	}
+
+	count := len(c.Triples.ReferenceValues.Values[0].Measurements.Values)
+
+	if conflicts != 0 {
+		fmt.Printf(">> created %q from %d file(s) (%d measurement(s), %d conflict(s) resolved with %s)\n",
+			cborFile, len(files), count, conflicts, policy)
+	} else {
+		fmt.Printf(">> created %q from %d file(s) (%d measurement(s))\n", cborFile, len(files), count)
+	}
+
+	return nil
+}
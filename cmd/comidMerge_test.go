@@ -0,0 +1,268 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/comid"
+)
+
+var (
+	testMergeBL1 = []byte(`{
+  "key": { "type": "uint", "value": 1 },
+  "value": { "digests": [ "sha-256:RKozavTLFKh5Qy5T3WVxx/qbzK+3X0iCWSYtbqOk2Rs=" ] }
+}`)
+	testMergeFW = []byte(`[
+  {
+    "key": { "type": "uint", "value": 2 },
+    "value": { "digests": [ "sha-256:h0KPxSKAPTEGXnvOPPA/5HUJZjHl4Hu9eg/eYMTPJcc=" ] }
+  },
+  {
+    "value": { "svn": { "type": "exact-value", "value": 3 } }
+  }
+]`)
+	testMergeDupBL1 = []byte(`{
+  "key": { "type": "uint", "value": 1 },
+  "value": { "svn": { "type": "exact-value", "value": 1 } }
+}`)
+)
+
+func Test_parseMeasurementFragment(t *testing.T) {
+	ms, err := parseMeasurementFragment(testMergeBL1)
+	require.NoError(t, err)
+	assert.Len(t, ms, 1)
+
+	ms, err = parseMeasurementFragment(testMergeFW)
+	require.NoError(t, err)
+	assert.Len(t, ms, 2)
+}
+
+func Test_parseMeasurementFragment_bad(t *testing.T) {
+	_, err := parseMeasurementFragment([]byte(`[]`))
+	assert.EqualError(t, err, "no measurements found")
+
+	_, err = parseMeasurementFragment([]byte(`[ { "key": { "type": "uint", "value": 1 }, "value": {} } ]`))
+	assert.ErrorContains(t, err, "measurement [0]: ")
+}
+
+func Test_ComidCreateCmd_merge_measurements(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "frags/bl1.json", testMergeBL1, 0644))
+	require.NoError(t, afero.WriteFile(fs, "frags/fw.json", testMergeFW, 0644))
+
+	cmd := NewComidCreateCmd()
+	cmd.SetArgs([]string{
+		"--merge-measurements",
+		"--env-class-id=1.2.3.4",
+		"--tag-id=merged",
+		"--output=out/comid.cbor",
+		"frags/bl1.json",
+		"frags/fw.json",
+	})
+	require.NoError(t, cmd.Execute())
+
+	var c comid.Comid
+	require.NoError(t, c.FromCBOR(mustReadFile(t, "out/comid.cbor")))
+
+	assert.Equal(t, "merged", c.TagIdentity.TagID.String())
+
+	require.NotNil(t, c.Triples.ReferenceValues)
+	require.Len(t, c.Triples.ReferenceValues.Values, 1)
+
+	rv := c.Triples.ReferenceValues.Values[0]
+	assert.Equal(t, "1.2.3.4", rv.Environment.Class.ClassID.String())
+	assert.Len(t, rv.Measurements.Values, 3)
+}
+
+func Test_ComidCreateCmd_merge_measurements_duplicate_key(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "bl1.json", testMergeBL1, 0644))
+	require.NoError(t, afero.WriteFile(fs, "bl1-again.json", testMergeDupBL1, 0644))
+
+	cmd := NewComidCreateCmd()
+	cmd.SetArgs([]string{
+		"--merge-measurements", "--env-class-id=1.2.3.4", "--output=comid.cbor", "bl1.json", "bl1-again.json",
+	})
+	assert.EqualError(t, cmd.Execute(),
+		`bl1-again.json: duplicate measurement key {"type":"uint","value":1} (already in bl1.json)`)
+
+	_, err := fs.Stat("comid.cbor")
+	assert.Error(t, err)
+}
+
+func Test_ComidCreateCmd_merge_measurements_bad_args(t *testing.T) {
+	tvs := []struct {
+		desc     string
+		args     []string
+		expected string
+	}{
+		{
+			desc:     "no files",
+			args:     []string{"--merge-measurements", "--env-class-id=1.2.3.4", "--output=comid.cbor"},
+			expected: "no measurement files supplied",
+		},
+		{
+			desc:     "no class id",
+			args:     []string{"--merge-measurements", "--output=comid.cbor", "m.json"},
+			expected: "no environment class id supplied",
+		},
+		{
+			desc:     "no output",
+			args:     []string{"--merge-measurements", "--env-class-id=1.2.3.4", "m.json"},
+			expected: "no output file supplied",
+		},
+		{
+			desc:     "merge and bulk",
+			args:     []string{"--merge-measurements", "--bulk", "--csv=m.csv", "--env-class-id=1.2.3.4"},
+			expected: "--merge-measurements cannot be used together with --bulk",
+		},
+		{
+			desc:     "merge and templates",
+			args:     []string{"--merge-measurements", "--template=t.json", "m.json"},
+			expected: "--merge-measurements cannot be used together with --template or --template-dir",
+		},
+		{
+			desc:     "files without merge",
+			args:     []string{"--template=t.json", "m.json"},
+			expected: "measurement files can only be supplied together with --merge-measurements",
+		},
+		{
+			desc:     "bad conflict policy",
+			args:     []string{"--merge-measurements", "--on-conflict=keep-all", "--env-class-id=1.2.3.4", "--output=comid.cbor", "m.json"},
+			expected: `invalid --on-conflict "keep-all": expecting one of error, keep-first, keep-last or keep-both`,
+		},
+		{
+			desc:     "conflict policy without merge",
+			args:     []string{"--template=t.json", "--on-conflict=keep-last"},
+			expected: "--on-conflict can only be used together with --merge-measurements",
+		},
+		{
+			desc:     "class id without bulk or merge",
+			args:     []string{"--template=t.json", "--env-class-id=1.2.3.4"},
+			expected: "--env-class-id, --output and --tag-id can only be used together with --bulk, --merge-measurements, --svn or --min-svn",
+		},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.desc, func(t *testing.T) {
+			cmd := NewComidCreateCmd()
+			cmd.SetArgs(tv.args)
+
+			assert.EqualError(t, cmd.Execute(), tv.expected)
+		})
+	}
+}
+
+// testMergeTeamB carries a measurement of key 1 that conflicts with the one in
+// testMergeBL1
+var testMergeTeamB = []byte(`[
+  {
+    "key": { "type": "uint", "value": 1 },
+    "value": { "digests": [ "sha-256:h0KPxSKAPTEGXnvOPPA/5HUJZjHl4Hu9eg/eYMTPJcc=" ] }
+  },
+  {
+    "key": { "type": "uint", "value": 2 },
+    "value": { "svn": { "type": "exact-value", "value": 2 } }
+  }
+]`)
+
+func mergeWithPolicy(t *testing.T, policy string) []comid.Measurement {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "team-a.json", testMergeBL1, 0644))
+	require.NoError(t, afero.WriteFile(fs, "team-b.json", testMergeTeamB, 0644))
+
+	cmd := NewComidCreateCmd()
+	cmd.SetArgs([]string{
+		"--merge-measurements",
+		"--on-conflict=" + policy,
+		"--env-class-id=1.2.3.4",
+		"--output=comid.cbor",
+		"team-a.json",
+		"team-b.json",
+	})
+	require.NoError(t, cmd.Execute())
+
+	var c comid.Comid
+	require.NoError(t, c.FromCBOR(mustReadFile(t, "comid.cbor")))
+	require.Len(t, c.Triples.ReferenceValues.Values, 1)
+
+	return c.Triples.ReferenceValues.Values[0].Measurements.Values
+}
+
+func Test_ComidCreateCmd_merge_measurements_on_conflict(t *testing.T) {
+	bl1, err := parseMeasurementFragment(testMergeBL1)
+	require.NoError(t, err)
+
+	teamB, err := parseMeasurementFragment(testMergeTeamB)
+	require.NoError(t, err)
+
+	tvs := []struct {
+		policy   string
+		expected []comid.Measurement
+	}{
+		{"keep-first", []comid.Measurement{bl1[0], teamB[1]}},
+		{"keep-last", []comid.Measurement{teamB[0], teamB[1]}},
+		{"keep-both", []comid.Measurement{bl1[0], teamB[0], teamB[1]}},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.policy, func(t *testing.T) {
+			ms := mergeWithPolicy(t, tv.policy)
+			require.Len(t, ms, len(tv.expected))
+
+			for i := range tv.expected {
+				expected, err := json.Marshal(tv.expected[i])
+				require.NoError(t, err)
+
+				actual, err := json.Marshal(ms[i])
+				require.NoError(t, err)
+
+				assert.JSONEq(t, string(expected), string(actual), "measurement [%d]", i)
+			}
+		})
+	}
+}
+
+func Test_mergeMeasurements_conflicts(t *testing.T) {
+	bl1, err := parseMeasurementFragment(testMergeBL1)
+	require.NoError(t, err)
+
+	teamB, err := parseMeasurementFragment(testMergeTeamB)
+	require.NoError(t, err)
+
+	// team-c.json repeats the measurement of team-a.json
+	fragments := []measurementFragment{
+		{file: "team-a.json", measurements: bl1},
+		{file: "team-b.json", measurements: teamB},
+		{file: "team-c.json", measurements: bl1},
+	}
+
+	merged, conflicts, err := mergeMeasurements(fragments, mergeConflictKeepFirst)
+	require.NoError(t, err)
+	assert.Equal(t, 1, conflicts)
+	require.Len(t, merged, 2)
+	assert.Equal(t, "team-a.json", merged[0].file)
+	assert.Equal(t, "team-b.json", merged[1].file)
+
+	merged, conflicts, err = mergeMeasurements(fragments, mergeConflictKeepBoth)
+	require.NoError(t, err)
+	assert.Equal(t, 1, conflicts)
+	assert.Len(t, merged, 3)
+
+	// team-c.json conflicts again with team-b.json, and replaces it
+	merged, conflicts, err = mergeMeasurements(fragments, mergeConflictKeepLast)
+	require.NoError(t, err)
+	assert.Equal(t, 2, conflicts)
+	require.Len(t, merged, 2)
+	assert.Equal(t, "team-c.json", merged[0].file)
+	assert.Equal(t, "team-b.json", merged[1].file)
+
+	_, _, err = mergeMeasurements(fragments, mergeConflictError)
+	assert.EqualError(t, err, `team-b.json: duplicate measurement key {"type":"uint","value":1} (already in team-a.json)`)
+}
@@ -0,0 +1,107 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/spf13/afero"
+	"github.com/veraison/corim/comid"
+)
+
+// parseSVN interprets the value s of the flag name as a security version
+// number, i.e., a non-negative integer
+func parseSVN(name, s string) (uint64, error) {
+	svn, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: expecting a non-negative integer", name, s)
+	}
+
+	return svn, nil
+}
+
+// svnComid builds a CoMID with a single reference-value triple, for the
+// environment of the supplied class, carrying one measurement of the security
+// version number svn.  If minimum, the measurement matches any SVN greater
+// than or equal to svn (min-svn), otherwise only svn itself.  If tagID is
+// empty, a random UUID is used as the tag identifier.
+func svnComid(class *comid.Class, svn uint64, minimum bool, tagID string) (*comid.Comid, error) {
+	var c comid.Comid
+
+	var id interface{} = tagID
+	if tagID == "" {
+		id = uuid.New()
+	}
+
+	if c.SetTagIdentity(id, 0) == nil {
+		return nil, fmt.Errorf("invalid tag id %q", tagID)
+	}
+
+	var m comid.Measurement
+	if minimum {
+		m.SetMinSVN(svn)
+	} else {
+		m.SetSVN(svn)
+	}
+
+	env := comid.Environment{
+		Class: class,
+	}
+
+	if c.AddReferenceValue(comid.ValueTriple{Environment: env, Measurements: *comid.NewMeasurements().Add(&m)}) == nil {
+		return nil, errors.New("error adding reference value")
+	}
+
+	if err := c.Valid(); err != nil {
+		return nil, fmt.Errorf("error validating CoMID: %w", err)
+	}
+
+	return &c, nil
+}
+
+// svnToCBOR creates a CoMID with an SVN reference value from exactly one of
+// svn and minSVN (the values of --svn and --min-svn) and saves it,
+// CBOR-encoded, to cborFile
+func svnToCBOR(svn, minSVN, cborFile string, ec envClass, tagID string) error {
+	name, value, minimum := "--svn", svn, false
+	if minSVN != "" {
+		name, value, minimum = "--min-svn", minSVN, true
+	}
+
+	n, err := parseSVN(name, value)
+	if err != nil {
+		return err
+	}
+
+	class, err := ec.class()
+	if err != nil {
+		return err
+	}
+
+	c, err := svnComid(class, n, minimum, tagID)
+	if err != nil {
+		return err
+	}
+
+	cborData, err := c.ToCBOR()
+	if err != nil {
+		return fmt.Errorf("error encoding CoMID to CBOR: %w", err)
+	}
+
+	if err = afero.WriteFile(fs, cborFile, cborData, 0644); err != nil {
+		return fmt.Errorf("error saving CBOR file %s: %w", cborFile, err)
+	}
+
+	form := "exact"
+	if minimum {
+		form = "minimum"
+	}
+
+	fmt.Printf(">> created %q (%s SVN %d)\n", cborFile, form, n)
+
+	return nil
+}
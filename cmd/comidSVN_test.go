@@ -0,0 +1,127 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/comid"
+)
+
+// readTestSVNComid returns the CoMID in file, together with the SVN of the
+// single measurement of its single reference-value triple
+func readTestSVNComid(t *testing.T, file string) (*comid.Comid, *comid.SVN) {
+	data, err := afero.ReadFile(fs, file)
+	require.NoError(t, err)
+
+	var c comid.Comid
+	require.NoError(t, c.FromCBOR(data))
+	require.NoError(t, c.Valid())
+
+	require.NotNil(t, c.Triples.ReferenceValues)
+	rvs := c.Triples.ReferenceValues.Values
+	require.Len(t, rvs, 1)
+	assert.Equal(t, "1.2.3.4", rvs[0].Environment.Class.ClassID.String())
+
+	ms := rvs[0].Measurements.Values
+	require.Len(t, ms, 1)
+	require.NotNil(t, ms[0].Val.SVN)
+
+	return &c, ms[0].Val.SVN
+}
+
+func Test_parseSVN(t *testing.T) {
+	svn, err := parseSVN("--svn", "42")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(42), svn)
+
+	for _, s := range []string{"-1", "1.5", "three", "0x10"} {
+		_, err = parseSVN("--svn", s)
+		assert.EqualError(t, err, `invalid --svn "`+s+`": expecting a non-negative integer`)
+	}
+}
+
+func Test_ComidCreateCmd_svn(t *testing.T) {
+	fs = afero.NewMemMapFs()
+
+	cmd := NewComidCreateCmd()
+	cmd.SetArgs([]string{"--svn=3", "--env-class-id=1.2.3.4", "--tag-id=acme-fw-1", "--output=out/comid.cbor"})
+	require.NoError(t, cmd.Execute())
+
+	c, svn := readTestSVNComid(t, "out/comid.cbor")
+	assert.Equal(t, "acme-fw-1", c.TagIdentity.TagID.String())
+	assert.Equal(t, comid.TaggedSVN(3), *svn.Value.(*comid.TaggedSVN))
+}
+
+func Test_ComidCreateCmd_min_svn(t *testing.T) {
+	fs = afero.NewMemMapFs()
+
+	cmd := NewComidCreateCmd()
+	cmd.SetArgs([]string{"--min-svn=0", "--env-class-id=1.2.3.4", "--output=comid.cbor"})
+	require.NoError(t, cmd.Execute())
+
+	_, svn := readTestSVNComid(t, "comid.cbor")
+	assert.Equal(t, comid.TaggedMinSVN(0), *svn.Value.(*comid.TaggedMinSVN))
+}
+
+func Test_ComidCreateCmd_svn_bad_args(t *testing.T) {
+	tvs := []struct {
+		desc     string
+		args     []string
+		expected string
+	}{
+		{
+			desc:     "svn and min-svn",
+			args:     []string{"--svn=1", "--min-svn=1", "--env-class-id=1.2.3.4", "--output=comid.cbor"},
+			expected: "--svn and --min-svn cannot be used together",
+		},
+		{
+			desc:     "svn and bulk",
+			args:     []string{"--svn=1", "--bulk", "--csv=m.csv", "--env-class-id=1.2.3.4"},
+			expected: "--svn and --min-svn cannot be used together with --bulk or --merge-measurements",
+		},
+		{
+			desc:     "min-svn and templates",
+			args:     []string{"--min-svn=1", "--template=t.json"},
+			expected: "--svn and --min-svn cannot be used together with --template or --template-dir",
+		},
+		{
+			desc:     "svn and profile",
+			args:     []string{"--svn=1", "--profile=psa", "--env-class-id=1.2.3.4", "--output=comid.cbor"},
+			expected: "--profile cannot be used together with --svn or --min-svn",
+		},
+		{
+			desc:     "negative svn",
+			args:     []string{"--svn=-1", "--env-class-id=1.2.3.4", "--output=comid.cbor"},
+			expected: `invalid --svn "-1": expecting a non-negative integer`,
+		},
+		{
+			desc:     "non-integer min-svn",
+			args:     []string{"--min-svn=v2", "--env-class-id=1.2.3.4", "--output=comid.cbor"},
+			expected: `invalid --min-svn "v2": expecting a non-negative integer`,
+		},
+		{
+			desc:     "no class id",
+			args:     []string{"--svn=1", "--output=comid.cbor"},
+			expected: "no environment class id supplied",
+		},
+		{
+			desc:     "no output",
+			args:     []string{"--svn=1", "--env-class-id=1.2.3.4"},
+			expected: "no output file supplied",
+		},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.desc, func(t *testing.T) {
+			cmd := NewComidCreateCmd()
+			cmd.SetArgs(tv.args)
+
+			assert.EqualError(t, cmd.Execute(), tv.expected)
+		})
+	}
+}
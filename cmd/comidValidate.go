@@ -15,6 +15,7 @@ import (
 var (
 	comidValidateFiles []string
 	comidValidateDirs  []string
+	comidValidateJUnit *string
 )
 
 var comidValidateCmd = NewComidValidateCmd()
@@ -33,6 +34,14 @@ func NewComidValidateCmd() *cobra.Command {
 	directory.
 	
 	  cocli comid validate --file=c1.cbor --file=c2.cbor --dir=comids
+
+	Besides the structural checks, the length of each measurement digest is
+	checked against the output size of its hash algorithm.
+
+	Validate any cbor file in the comids/ directory, and also save the results
+	as a JUnit XML report in report.xml
+
+	  cocli comid validate --dir=comids --junit=report.xml
 	`,
 
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -45,21 +54,25 @@ func NewComidValidateCmd() *cobra.Command {
 				return errors.New("no files found")
 			}
 
-			errs := 0
-			for _, file := range filesList {
-				err := validateComid(file)
-				if err != nil {
-					fmt.Printf("[invalid] %q: %v\n", file, err)
-					errs++
-					continue
+			return withJUnitReport(*comidValidateJUnit, "comid validate", func(report *junitReport) error {
+				errs := 0
+				for _, file := range filesList {
+					err := validateComid(file)
+					if err != nil {
+						fmt.Printf("[invalid] %q: %v\n", file, err)
+						report.fail(file, err)
+						errs++
+						continue
+					}
+					fmt.Printf("[valid] %q\n", file)
+					report.pass(file)
 				}
-				fmt.Printf("[valid] %q\n", file)
-			}
 
-			if errs != 0 {
-				return fmt.Errorf("%d/%d validation(s) failed", errs, len(filesList))
-			}
-			return nil
+				if errs != 0 {
+					return fmt.Errorf("%d/%d validation(s) failed", errs, len(filesList))
+				}
+				return nil
+			})
 		},
 	}
 
@@ -71,6 +84,8 @@ func NewComidValidateCmd() *cobra.Command {
 		&comidValidateDirs, "dir", "d", []string{}, "a directory containing CoMID files (in CBOR format)",
 	)
 
+	comidValidateJUnit = cmd.Flags().String("junit", "", "also save the per-file results to this file as a JUnit XML report")
+
 	return cmd
 }
 
@@ -89,6 +104,14 @@ func validateComid(file string) error {
 		return fmt.Errorf("error decoding CoMID from %s: %w", file, err)
 	}
 
+	if err = checkDigestLengths(&c); err != nil {
+		return fmt.Errorf("error validating CoMID %s: %w", file, err)
+	}
+
+	if err = checkRawValueMasks(&c); err != nil {
+		return fmt.Errorf("error validating CoMID %s: %w", file, err)
+	}
+
 	if err = c.Valid(); err != nil {
 		return fmt.Errorf("error validating CoMID %s: %w", file, err)
 	}
@@ -0,0 +1,98 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	comidVersionFile      *string
+	comidVersionNewerThan *string
+)
+
+var comidVersionCmd = NewComidVersionCmd()
+
+func NewComidVersionCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "version",
+		Short: "print the tag version of a CBOR-encoded CoMID",
+		Long: `print the tag version of a CBOR-encoded CoMID
+
+	Print the tag version of the CoMID in file comid.cbor (0 if it has none)
+
+	  cocli comid version --file=comid.cbor
+
+	Same as above, but fail unless new.cbor is a later version of the CoMID in
+	old.cbor, i.e., unless both have the same tag id and the tag version of
+	new.cbor is greater
+
+	  cocli comid version --file=new.cbor --newer-than=old.cbor
+	`,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkComidVersionArgs(); err != nil {
+				return err
+			}
+
+			return comidVersion(*comidVersionFile, *comidVersionNewerThan)
+		},
+	}
+
+	comidVersionFile = cmd.Flags().StringP("file", "f", "", "a CoMID file (in CBOR format)")
+	comidVersionNewerThan = cmd.Flags().String(
+		"newer-than", "", "fail unless the CoMID is a later version of the CoMID in this file (in CBOR format)",
+	)
+
+	return cmd
+}
+
+func checkComidVersionArgs() error {
+	if comidVersionFile == nil || *comidVersionFile == "" {
+		return errors.New("no CoMID supplied")
+	}
+
+	return nil
+}
+
+// comidVersion prints the tag version of the CoMID in file.  If olderFile is
+// not empty, it also checks that file is a later version of the CoMID in
+// olderFile.
+func comidVersion(file, olderFile string) error {
+	c, err := loadComid(file)
+	if err != nil {
+		return err
+	}
+
+	if olderFile != "" {
+		o, err := loadComid(olderFile)
+		if err != nil {
+			return err
+		}
+
+		newID, oldID := c.TagIdentity.TagID.String(), o.TagIdentity.TagID.String()
+
+		if newID != oldID {
+			return fmt.Errorf("%s and %s are not versions of the same CoMID: tag id %q differs from %q",
+				file, olderFile, newID, oldID)
+		}
+
+		if c.TagIdentity.TagVersion <= o.TagIdentity.TagVersion {
+			return fmt.Errorf("tag version %d of %s is not greater than tag version %d of %s",
+				c.TagIdentity.TagVersion, file, o.TagIdentity.TagVersion, olderFile)
+		}
+	}
+
+	// only the version, so that it can be used by scripts as is
+	fmt.Println(c.TagIdentity.TagVersion)
+
+	return nil
+}
+
+func init() {
+	comidCmd.AddCommand(comidVersionCmd)
+}
@@ -0,0 +1,75 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestComidVersion saves the test CoMID, with the supplied tag version,
+// to file
+func writeTestComidVersion(t *testing.T, file string, version uint) {
+	c := newTestComid(t)
+	c.TagIdentity.TagVersion = version
+
+	data, err := c.ToCBOR()
+	require.NoError(t, err)
+	require.NoError(t, afero.WriteFile(fs, file, data, 0644))
+}
+
+func Test_ComidVersionCmd_no_file(t *testing.T) {
+	cmd := NewComidVersionCmd()
+	cmd.SetArgs([]string{})
+	assert.EqualError(t, cmd.Execute(), "no CoMID supplied")
+}
+
+func Test_ComidVersionCmd_ok(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	writeTestComidVersion(t, "c.cbor", 3)
+
+	cmd := NewComidVersionCmd()
+	cmd.SetArgs([]string{"--file=c.cbor"})
+	require.NoError(t, withDisplayOutput("out.txt", cmd.Execute))
+
+	assert.Equal(t, "3\n", string(mustReadFile(t, "out.txt")))
+}
+
+func Test_ComidVersionCmd_newer_than(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	writeTestComidVersion(t, "old.cbor", 3)
+	writeTestComidVersion(t, "new.cbor", 4)
+
+	cmd := NewComidVersionCmd()
+	cmd.SetArgs([]string{"--file=new.cbor", "--newer-than=old.cbor"})
+	assert.NoError(t, cmd.Execute())
+
+	cmd = NewComidVersionCmd()
+	cmd.SetArgs([]string{"--file=old.cbor", "--newer-than=new.cbor"})
+	assert.EqualError(t, cmd.Execute(), "tag version 3 of old.cbor is not greater than tag version 4 of new.cbor")
+
+	cmd = NewComidVersionCmd()
+	cmd.SetArgs([]string{"--file=old.cbor", "--newer-than=old.cbor"})
+	assert.EqualError(t, cmd.Execute(), "tag version 3 of old.cbor is not greater than tag version 3 of old.cbor")
+}
+
+func Test_ComidVersionCmd_newer_than_other_comid(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	writeTestComidVersion(t, "old.cbor", 3)
+
+	c := newTestComid(t)
+	require.NotNil(t, c.SetTagIdentity("another-comid", 4))
+
+	data, err := c.ToCBOR()
+	require.NoError(t, err)
+	require.NoError(t, afero.WriteFile(fs, "new.cbor", data, 0644))
+
+	cmd := NewComidVersionCmd()
+	cmd.SetArgs([]string{"--file=new.cbor", "--newer-than=old.cbor"})
+	assert.ErrorContains(t, cmd.Execute(),
+		`new.cbor and old.cbor are not versions of the same CoMID: tag id "another-comid" differs from `)
+}
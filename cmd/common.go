@@ -4,14 +4,19 @@
 package cmd
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/spf13/afero"
-	"github.com/veraison/corim/comid"
 	"github.com/veraison/corim/cots"
+	"github.com/veraison/eat"
+	cose "github.com/veraison/go-cose"
 	"github.com/veraison/swid"
 )
 
@@ -46,13 +51,13 @@ type FromCBORLoader interface {
 	FromCBOR([]byte) error
 }
 
-func printJSONFromCBOR(fcl FromCBORLoader, cbor []byte, heading string) error {
+func printJSONFromCBOR(fcl CBORCodec, cbor []byte, heading string, strict bool) error {
 	var (
 		err error
 		j   []byte
 	)
 
-	if err = fcl.FromCBOR(cbor); err != nil {
+	if err = decodeCBOR(fcl, cbor, strict); err != nil {
 		return fmt.Errorf("CBOR decoding failed: %w", err)
 	}
 
@@ -67,16 +72,16 @@ func printJSONFromCBOR(fcl FromCBORLoader, cbor []byte, heading string) error {
 	return nil
 }
 
-func printComid(cbor []byte, heading string) error {
-	return printJSONFromCBOR(&comid.Comid{}, cbor, heading)
+func printComid(cbor []byte, heading string, profile *eat.Profile, strict bool) error {
+	return printJSONFromCBOR(newComid(profile), cbor, heading, strict)
 }
 
-func printCoswid(cbor []byte, heading string) error {
-	return printJSONFromCBOR(&swid.SoftwareIdentity{}, cbor, heading)
+func printCoswid(cbor []byte, heading string, strict bool) error {
+	return printJSONFromCBOR(&swid.SoftwareIdentity{}, cbor, heading, strict)
 }
 
-func printCots(cbor []byte, heading string) error {
-	return printJSONFromCBOR(&cots.ConciseTaStore{}, cbor, heading)
+func printCots(cbor []byte, heading string, strict bool) error {
+	return printJSONFromCBOR(&cots.ConciseTaStore{}, cbor, heading, strict)
 }
 
 func makeFileName(dirName, baseName, ext string) string {
@@ -90,3 +95,117 @@ func makeFileName(dirName, baseName, ext string) string {
 		)+ext,
 	)
 }
+
+// decodeSign1 decodes the COSE Sign1 message wrapping a signed CoRIM.  This
+// gives access to the COSE headers, which SignedCorim does not expose.
+func decodeSign1(buf []byte) (*cose.Sign1Message, error) {
+	// strip the legacy tagged-corim-type-choice prefix, if present (see
+	// SignedCorim.FromCOSE)
+	buf, _ = bytes.CutPrefix(buf, []byte("\xd9\x01\xf4\xd9\x01\xf6"))
+
+	msg := cose.NewSign1Message()
+	if err := msg.UnmarshalCBOR(buf); err != nil {
+		return nil, fmt.Errorf("failed CBOR decoding for COSE-Sign1 signed CoRIM: %w", err)
+	}
+
+	return msg, nil
+}
+
+// cborTagSign1 is the encoding of the COSE_Sign1 CBOR tag (18)
+const cborTagSign1 = 0xd2
+
+// untagSign1 strips the COSE_Sign1 CBOR tag from a tagged COSE Sign1 message,
+// leaving the bare array
+func untagSign1(buf []byte) []byte {
+	if len(buf) != 0 && buf[0] == cborTagSign1 {
+		return buf[1:]
+	}
+
+	return buf
+}
+
+// tagSign1 adds the COSE_Sign1 CBOR tag to an untagged COSE Sign1 message, so
+// that it can be decoded by SignedCorim.FromCOSE.  Anything other than a bare
+// array of four elements is returned unchanged.
+func tagSign1(buf []byte) []byte {
+	if len(buf) != 0 && buf[0] == 0x84 {
+		return append([]byte{cborTagSign1}, buf...)
+	}
+
+	return buf
+}
+
+// defaultDirMode is the permission used when creating an output directory
+const defaultDirMode = "0755"
+
+// parseDirMode parses an octal permission string, e.g., "0750"
+func parseDirMode(s string) (os.FileMode, error) {
+	m, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || m&^uint64(os.ModePerm) != 0 {
+		return 0, fmt.Errorf("invalid directory mode %q: expecting octal permission bits, e.g., 0755", s)
+	}
+
+	return os.FileMode(m), nil
+}
+
+// defaultFileMode is the permission used when saving an output file
+const defaultFileMode = "0644"
+
+// parseFileMode parses an octal permission string, e.g., "0600"
+func parseFileMode(s string) (os.FileMode, error) {
+	m, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || m&^uint64(os.ModePerm) != 0 {
+		return 0, fmt.Errorf("invalid file mode %q: expecting octal permission bits, e.g., 0600", s)
+	}
+
+	return os.FileMode(m), nil
+}
+
+// writeFileMode saves data to file with permissions perm.  Unlike
+// afero.WriteFile, the permissions are also set when file already exists, and
+// are not subject to the umask.
+func writeFileMode(file string, data []byte, perm os.FileMode) error {
+	if err := afero.WriteFile(fs, file, data, perm); err != nil {
+		return err
+	}
+
+	return fs.Chmod(file, perm)
+}
+
+// ensureOutputDir makes sure that dir exists, creating it (together with any
+// missing parent) with the supplied permissions if needed
+func ensureOutputDir(dir string, mode os.FileMode) error {
+	dir = filepath.Clean(dir)
+
+	fi, err := fs.Stat(dir)
+	switch {
+	case err == nil:
+		if !fi.IsDir() {
+			return fmt.Errorf("output directory %s exists but is not a directory", dir)
+		}
+		return nil
+	case !errors.Is(err, os.ErrNotExist):
+		return fmt.Errorf("error accessing output directory %s: %w", dir, err)
+	}
+
+	if err = fs.MkdirAll(dir, mode); err != nil {
+		return fmt.Errorf("error creating output directory %s: %w", dir, err)
+	}
+
+	// MkdirAll is subject to umask, make sure the leaf gets the requested mode
+	if err = fs.Chmod(dir, mode); err != nil {
+		return fmt.Errorf("error setting mode of output directory %s: %w", dir, err)
+	}
+
+	return nil
+}
+
+// prepareOutputDir combines parseDirMode and ensureOutputDir
+func prepareOutputDir(dir, dirMode string) error {
+	mode, err := parseDirMode(dirMode)
+	if err != nil {
+		return err
+	}
+
+	return ensureOutputDir(dir, mode)
+}
@@ -0,0 +1,71 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"os"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseDirMode(t *testing.T) {
+	mode, err := parseDirMode("0750")
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0750), mode)
+
+	mode, err = parseDirMode("700")
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0700), mode)
+
+	for _, bad := range []string{"", "rwx", "0789", "1777", "-1"} {
+		_, err = parseDirMode(bad)
+		assert.EqualError(t, err,
+			`invalid directory mode "`+bad+`": expecting octal permission bits, e.g., 0755`)
+	}
+}
+
+func Test_ensureOutputDir_create(t *testing.T) {
+	fs = afero.NewMemMapFs()
+
+	err := ensureOutputDir("a/b/c/", 0700)
+	require.NoError(t, err)
+
+	fi, err := fs.Stat("a/b/c")
+	require.NoError(t, err)
+	assert.True(t, fi.IsDir())
+	assert.Equal(t, os.FileMode(0700), fi.Mode().Perm())
+}
+
+func Test_ensureOutputDir_existing_dir(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, fs.MkdirAll("out", 0755))
+
+	assert.NoError(t, ensureOutputDir("out", 0700))
+
+	fi, err := fs.Stat("out")
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), fi.Mode().Perm())
+}
+
+func Test_ensureOutputDir_existing_file(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "out", []byte("hello!"), 0644))
+
+	err := ensureOutputDir("out", 0755)
+	assert.EqualError(t, err, "output directory out exists but is not a directory")
+}
+
+func Test_tagSign1_untagSign1(t *testing.T) {
+	untagged := untagSign1(testSignedCorimValid)
+	assert.Equal(t, testSignedCorimValid[1:], untagged)
+	assert.Equal(t, testSignedCorimValid, tagSign1(untagged))
+
+	// tagged input and non-COSE input are left untouched
+	assert.Equal(t, testSignedCorimValid, tagSign1(testSignedCorimValid))
+	assert.Equal(t, testCorimValid, tagSign1(testCorimValid))
+	assert.Equal(t, untagged, untagSign1(untagged))
+}
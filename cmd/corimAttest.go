@@ -0,0 +1,214 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/veraison/corim/corim"
+)
+
+const (
+	// inTotoStatementType is the _type of an in-toto Statement, version 1
+	inTotoStatementType = "https://in-toto.io/Statement/v1"
+
+	// defaultCorimPredicateType identifies the predicate of the statements
+	// created by "corim attest".  There is no registered predicate type for
+	// CoRIMs, hence this one is specific to cocli, and --predicate-type can
+	// be used to pick another.
+	defaultCorimPredicateType = "https://github.com/veraison/cocli/attestation/corim/v1"
+
+	// signedCorimMediaType is the media type of a signed CoRIM
+	signedCorimMediaType = "application/rim+cose"
+)
+
+var (
+	corimAttestCorimFile     *string
+	corimAttestOutputFile    *string
+	corimAttestEmbed         *bool
+	corimAttestURI           *string
+	corimAttestPredicateType *string
+)
+
+var corimAttestCmd = NewCorimAttestCmd()
+
+func NewCorimAttestCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "attest",
+		Short: "wrap a signed CoRIM in an in-toto statement",
+		Long: `wrap a signed CoRIM in an in-toto statement
+
+	Save to attestation.json an in-toto statement (version 1) whose subject is
+	the signed CoRIM signed-corim.cbor, identified by its SHA-256 digest, and
+	whose predicate refers to the CoRIM and summarizes it (id, profile, number
+	of tags of each type, signer).  The statement is not signed: it can be
+	signed, e.g., as a DSSE envelope, by the supply-chain tooling
+
+	  cocli corim attest --file=signed-corim.cbor --output=attestation.json
+
+	Same as above, but also record where the CoRIM is published
+
+	  cocli corim attest --file=signed-corim.cbor --output=attestation.json \
+	    	--uri=https://example.com/corims/signed-corim.cbor
+
+	Embed the signed CoRIM (base64-encoded) in the predicate, rather than only
+	referring to it
+
+	  cocli corim attest --file=signed-corim.cbor --output=attestation.json --embed
+	`,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkCorimAttestArgs(); err != nil {
+				return err
+			}
+
+			statement, err := corimAttestation(*corimAttestCorimFile, attestOptions{
+				embed:         *corimAttestEmbed,
+				uri:           *corimAttestURI,
+				predicateType: *corimAttestPredicateType,
+			})
+			if err != nil {
+				return err
+			}
+
+			data, err := json.MarshalIndent(statement, "", "  ")
+			if err != nil {
+				return fmt.Errorf("error encoding in-toto statement: %w", err)
+			}
+
+			if err = afero.WriteFile(fs, *corimAttestOutputFile, append(data, '\n'), 0644); err != nil {
+				return fmt.Errorf("error saving in-toto statement to %s: %w", *corimAttestOutputFile, err)
+			}
+
+			fmt.Printf(">> in-toto statement for %q saved to %q\n", *corimAttestCorimFile, *corimAttestOutputFile)
+
+			return nil
+		},
+	}
+
+	corimAttestCorimFile = cmd.Flags().StringP("file", "f", "", "a signed CoRIM file (in CBOR format)")
+	corimAttestOutputFile = cmd.Flags().StringP("output", "o", "", "name of the in-toto statement file (in JSON format)")
+	corimAttestEmbed = cmd.Flags().Bool("embed", false, "embed the signed CoRIM (base64-encoded) in the predicate, instead of only referring to it")
+	corimAttestURI = cmd.Flags().String("uri", "", "the location where the signed CoRIM is published, recorded in the predicate")
+	corimAttestPredicateType = cmd.Flags().String("predicate-type", defaultCorimPredicateType, "the predicate type of the in-toto statement")
+
+	return cmd
+}
+
+func checkCorimAttestArgs() error {
+	if corimAttestCorimFile == nil || *corimAttestCorimFile == "" {
+		return errors.New("no CoRIM supplied")
+	}
+
+	if corimAttestOutputFile == nil || *corimAttestOutputFile == "" {
+		return errors.New("no output file supplied")
+	}
+
+	if corimAttestPredicateType != nil && *corimAttestPredicateType == "" {
+		return errors.New("empty --predicate-type")
+	}
+
+	return nil
+}
+
+// attestOptions are the options of "corim attest"
+type attestOptions struct {
+	embed         bool
+	uri           string
+	predicateType string
+}
+
+// inTotoResource is an in-toto ResourceDescriptor (version 1), restricted to
+// the fields used by cocli
+type inTotoResource struct {
+	Name      string            `json:"name,omitempty"`
+	URI       string            `json:"uri,omitempty"`
+	Digest    map[string]string `json:"digest,omitempty"`
+	Content   []byte            `json:"content,omitempty"`
+	MediaType string            `json:"mediaType,omitempty"`
+}
+
+// inTotoStatement is an in-toto Statement (version 1)
+type inTotoStatement struct {
+	Type          string           `json:"_type"`
+	Subject       []inTotoResource `json:"subject"`
+	PredicateType string           `json:"predicateType"`
+	Predicate     corimPredicate   `json:"predicate"`
+}
+
+// corimPredicate is the predicate of the in-toto statements created by "corim
+// attest".  CoRIM refers to (or, with --embed, carries) the signed CoRIM.
+// PayloadDigest is the SHA-256 fingerprint of the unsigned CoRIM (see "corim
+// display --hash"), which does not change if the CoRIM is re-signed.
+type corimPredicate struct {
+	CoRIM         inTotoResource    `json:"corim"`
+	ID            string            `json:"id"`
+	Profile       string            `json:"profile,omitempty"`
+	Tags          tagTypeCounts     `json:"tags"`
+	Signer        string            `json:"signer,omitempty"`
+	PayloadDigest map[string]string `json:"payloadDigest"`
+}
+
+// corimAttestation returns the in-toto statement for the signed CoRIM in file
+func corimAttestation(file string, opts attestOptions) (*inTotoStatement, error) {
+	data, err := afero.ReadFile(fs, file)
+	if err != nil {
+		return nil, fmt.Errorf("error loading signed CoRIM from %s: %w", file, err)
+	}
+
+	s, err := corim.UnmarshalSignedCorimFromCBOR(tagSign1(data))
+	if err != nil {
+		return nil, fmt.Errorf("error decoding signed CoRIM from %s: %w", file, err)
+	}
+
+	fingerprint, err := corimFingerprint(data, crypto.SHA256, false)
+	if err != nil {
+		return nil, fmt.Errorf("error hashing signed CoRIM from %s: %w", file, err)
+	}
+
+	sum := sha256.Sum256(data)
+	digest := map[string]string{"sha256": hex.EncodeToString(sum[:])}
+
+	predicate := corimPredicate{
+		CoRIM: inTotoResource{
+			Name:      file,
+			URI:       opts.uri,
+			Digest:    digest,
+			MediaType: signedCorimMediaType,
+		},
+		ID:            s.UnsignedCorim.ID.String(),
+		Tags:          countTags(s.UnsignedCorim.Tags),
+		Signer:        s.Meta.Signer.Name,
+		PayloadDigest: map[string]string{"sha256": hex.EncodeToString(fingerprint)},
+	}
+
+	if s.UnsignedCorim.Profile != nil {
+		// an unreadable profile would have failed decoding already
+		predicate.Profile, _ = s.UnsignedCorim.Profile.Get()
+	}
+
+	if opts.embed {
+		predicate.CoRIM.Content = data
+	}
+
+	return &inTotoStatement{
+		Type: inTotoStatementType,
+		Subject: []inTotoResource{
+			{Name: file, Digest: digest},
+		},
+		PredicateType: opts.predicateType,
+		Predicate:     predicate,
+	}, nil
+}
+
+func init() {
+	corimCmd.AddCommand(corimAttestCmd)
+}
@@ -0,0 +1,114 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readTestStatement(t *testing.T, file string) inTotoStatement {
+	data, err := afero.ReadFile(fs, file)
+	require.NoError(t, err)
+
+	var statement inTotoStatement
+	require.NoError(t, json.Unmarshal(data, &statement))
+
+	return statement
+}
+
+func Test_CorimAttestCmd_ok(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "signed.cbor", testSignedCorimValid, 0644))
+
+	cmd := NewCorimAttestCmd()
+	cmd.SetArgs([]string{"--file=signed.cbor", "--output=attestation.json", "--uri=https://example.com/signed.cbor"})
+	require.NoError(t, cmd.Execute())
+
+	sum := sha256.Sum256(testSignedCorimValid)
+	digest := map[string]string{"sha256": hex.EncodeToString(sum[:])}
+
+	statement := readTestStatement(t, "attestation.json")
+	assert.Equal(t, inTotoStatementType, statement.Type)
+	assert.Equal(t, defaultCorimPredicateType, statement.PredicateType)
+	assert.Equal(t, []inTotoResource{{Name: "signed.cbor", Digest: digest}}, statement.Subject)
+
+	p := statement.Predicate
+	assert.Equal(t, inTotoResource{
+		Name:      "signed.cbor",
+		URI:       "https://example.com/signed.cbor",
+		Digest:    digest,
+		MediaType: signedCorimMediaType,
+	}, p.CoRIM)
+	assert.Equal(t, "5c57e8f4-46cd-421b-91c9-08cf93e13cfc", p.ID)
+	assert.Equal(t, "http://arm.com/iot/profile/1", p.Profile)
+	assert.Equal(t, tagTypeCounts{Comids: 1}, p.Tags)
+	assert.Equal(t, "ACME Ltd signing key", p.Signer)
+	assert.Len(t, p.PayloadDigest["sha256"], 64)
+}
+
+func Test_CorimAttestCmd_embed(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "signed.cbor", testSignedCorimValid, 0644))
+
+	cmd := NewCorimAttestCmd()
+	cmd.SetArgs([]string{"--file=signed.cbor", "--output=attestation.json", "--embed", "--predicate-type=https://example.com/corim/v2"})
+	require.NoError(t, cmd.Execute())
+
+	statement := readTestStatement(t, "attestation.json")
+	assert.Equal(t, "https://example.com/corim/v2", statement.PredicateType)
+	assert.Equal(t, testSignedCorimValid, statement.Predicate.CoRIM.Content)
+	assert.Empty(t, statement.Predicate.CoRIM.URI)
+}
+
+func Test_CorimAttestCmd_unsigned(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "unsigned.cbor", testCorimValid, 0644))
+
+	cmd := NewCorimAttestCmd()
+	cmd.SetArgs([]string{"--file=unsigned.cbor", "--output=attestation.json"})
+	assert.ErrorContains(t, cmd.Execute(), "error decoding signed CoRIM from unsigned.cbor: ")
+
+	_, err := fs.Stat("attestation.json")
+	assert.Error(t, err)
+}
+
+func Test_CorimAttestCmd_bad_args(t *testing.T) {
+	tvs := []struct {
+		desc     string
+		args     []string
+		expected string
+	}{
+		{
+			desc:     "no file",
+			args:     []string{"--output=attestation.json"},
+			expected: "no CoRIM supplied",
+		},
+		{
+			desc:     "no output",
+			args:     []string{"--file=signed.cbor"},
+			expected: "no output file supplied",
+		},
+		{
+			desc:     "empty predicate type",
+			args:     []string{"--file=signed.cbor", "--output=attestation.json", "--predicate-type="},
+			expected: "empty --predicate-type",
+		},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.desc, func(t *testing.T) {
+			cmd := NewCorimAttestCmd()
+			cmd.SetArgs(tv.args)
+
+			assert.EqualError(t, cmd.Execute(), tv.expected)
+		})
+	}
+}
@@ -0,0 +1,229 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/veraison/corim/corim"
+)
+
+// the signature statuses recorded in a CoRIM catalog
+const (
+	catalogUnsigned   = "unsigned"
+	catalogUnverified = "unverified"
+	catalogVerified   = "verified"
+	catalogInvalid    = "invalid"
+)
+
+var (
+	corimCatalogDir     *string
+	corimCatalogOutput  *string
+	corimCatalogKeyFile *string
+)
+
+var corimCatalogCmd = NewCorimCatalogCmd()
+
+func NewCorimCatalogCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "catalog",
+		Short: "build a JSON index of the CoRIMs found in a directory tree",
+		Long: `build a JSON index of the CoRIMs found in a directory tree
+
+	Decode each CoRIM (signed or unsigned, with a .cbor extension) found in the
+	corims/ directory and its subdirectories, and save to catalog.json a JSON
+	array with, for each file, the CoRIM id and profile, the number of tags of
+	each type, the validity periods and whether the CoRIM is signed.  Files
+	that cannot be read or decoded are recorded with their error
+
+	  cocli corim catalog --dir=corims --output=catalog.json
+
+	Same as above, but also verify the signed CoRIMs using the key in JWK
+	format from file key.jwk, recording whether each signature is valid
+
+	  cocli corim catalog --dir=corims --output=catalog.json --key=key.jwk
+	`,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkCorimCatalogArgs(); err != nil {
+				return err
+			}
+
+			return corimCatalog(*corimCatalogDir, *corimCatalogOutput, *corimCatalogKeyFile)
+		},
+	}
+
+	corimCatalogDir = cmd.Flags().StringP("dir", "d", "", "directory in which CoRIMs (*.cbor) are looked for, recursively")
+	corimCatalogOutput = cmd.Flags().StringP("output", "o", "", "name of the JSON catalog file")
+	corimCatalogKeyFile = cmd.Flags().StringP("key", "k", "", "verify the signed CoRIMs using this key (in the formats accepted by corim verify)")
+
+	return cmd
+}
+
+func checkCorimCatalogArgs() error {
+	if corimCatalogDir == nil || *corimCatalogDir == "" {
+		return errors.New("no directory supplied")
+	}
+
+	if corimCatalogOutput == nil || *corimCatalogOutput == "" {
+		return errors.New("no output file supplied")
+	}
+
+	return nil
+}
+
+// catalogValidity is the rendering of a validity period in a CoRIM catalog
+type catalogValidity struct {
+	NotBefore string `json:"not-before,omitempty"`
+	NotAfter  string `json:"not-after"`
+}
+
+func newCatalogValidity(v *corim.Validity) *catalogValidity {
+	if v == nil {
+		return nil
+	}
+
+	cv := catalogValidity{NotAfter: v.NotAfter.UTC().Format(time.RFC3339)}
+
+	if v.NotBefore != nil {
+		cv.NotBefore = v.NotBefore.UTC().Format(time.RFC3339)
+	}
+
+	return &cv
+}
+
+// corimCatalogEntry describes a CoRIM file in the catalog built by "corim
+// catalog".  Validity is that of the CoRIM itself, SignatureValidity that of
+// the CoRIM Meta of a signed CoRIM.  If the file could not be decoded, only
+// Path and Error are set.
+type corimCatalogEntry struct {
+	Path              string           `json:"path"`
+	ID                string           `json:"id,omitempty"`
+	Profile           string           `json:"profile,omitempty"`
+	Tags              *tagTypeCounts   `json:"tags,omitempty"`
+	Validity          *catalogValidity `json:"validity,omitempty"`
+	Signature         string           `json:"signature,omitempty"`
+	Algorithm         string           `json:"algorithm,omitempty"`
+	SignatureValidity *catalogValidity `json:"signature-validity,omitempty"`
+	Error             string           `json:"error,omitempty"`
+}
+
+// newCorimCatalogEntry describes the CoRIM data, read from file.  If keyFile
+// is not empty, signed CoRIMs are verified with it.
+func newCorimCatalogEntry(file string, data []byte, keyFile string) corimCatalogEntry {
+	entry := corimCatalogEntry{Path: file}
+
+	signedCBOR := tagSign1(data)
+
+	var u *corim.UnsignedCorim
+
+	if s, err := corim.UnmarshalSignedCorimFromCBOR(signedCBOR); err == nil {
+		u = &s.UnsignedCorim
+		entry.Signature = catalogUnverified
+		entry.SignatureValidity = newCatalogValidity(s.Meta.Validity)
+
+		if msg, err := decodeSign1(signedCBOR); err == nil {
+			if alg, err := msg.Headers.Protected.Algorithm(); err == nil {
+				entry.Algorithm = alg.String()
+			}
+		}
+
+		if keyFile != "" {
+			if err = verifyCorimData(file, data, keyFile, verifyOptions{}); err != nil {
+				entry.Signature = catalogInvalid
+				entry.Error = err.Error()
+			} else {
+				entry.Signature = catalogVerified
+			}
+		}
+	} else {
+		u = corim.GetUnsignedCorim(cborProfile(data))
+		if err = u.FromCBOR(data); err != nil {
+			return corimCatalogEntry{
+				Path:  file,
+				Error: fmt.Sprintf("error decoding CoRIM (signed or unsigned): %v", err),
+			}
+		}
+
+		entry.Signature = catalogUnsigned
+	}
+
+	entry.ID = u.ID.String()
+
+	if u.Profile != nil {
+		// an unreadable profile would have failed decoding already
+		entry.Profile, _ = u.Profile.Get()
+	}
+
+	counts := countTags(u.Tags)
+	entry.Tags = &counts
+	entry.Validity = newCatalogValidity(u.RimValidity)
+
+	return entry
+}
+
+// corimCatalog saves to outputFile the catalog of the CoRIMs found in dir and
+// its subdirectories, sorted by path
+func corimCatalog(dir, outputFile, keyFile string) error {
+	var files []string
+
+	err := afero.Walk(fs, dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.IsDir() && filepath.Ext(path) == ".cbor" {
+			files = append(files, path)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error reading directory %s: %w", dir, err)
+	}
+
+	entries := []corimCatalogEntry{}
+	errs := 0
+
+	for _, file := range files {
+		var entry corimCatalogEntry
+
+		if data, err := afero.ReadFile(fs, file); err != nil {
+			entry = corimCatalogEntry{Path: file, Error: fmt.Sprintf("error loading CoRIM: %v", err)}
+		} else {
+			entry = newCorimCatalogEntry(file, data, keyFile)
+		}
+
+		if entry.Error != "" {
+			fmt.Printf(">> warning: %q: %s\n", file, entry.Error)
+			errs++
+		}
+
+		entries = append(entries, entry)
+	}
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding catalog: %w", err)
+	}
+
+	if err = afero.WriteFile(fs, outputFile, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("error saving catalog to %s: %w", outputFile, err)
+	}
+
+	fmt.Printf(">> %d CoRIM file(s) catalogued in %q (%d with errors)\n", len(entries), outputFile, errs)
+
+	return nil
+}
+
+func init() {
+	corimCmd.AddCommand(corimCatalogCmd)
+}
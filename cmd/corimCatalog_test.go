@@ -0,0 +1,110 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestCatalogTree saves a signed CoRIM in a subdirectory of corims/, an
+// unsigned CoRIM, a file that is not a CoRIM and one that is ignored
+func writeTestCatalogTree(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "corims/acme/signed.cbor", testSignedCorimValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "corims/unsigned.cbor", testCorimValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "corims/garbage.cbor", []byte{0x01, 0x02}, 0644))
+	require.NoError(t, afero.WriteFile(fs, "corims/README.txt", []byte("ignored"), 0644))
+}
+
+func readTestCatalog(t *testing.T, file string) []corimCatalogEntry {
+	data, err := afero.ReadFile(fs, file)
+	require.NoError(t, err)
+
+	var entries []corimCatalogEntry
+	require.NoError(t, json.Unmarshal(data, &entries))
+
+	return entries
+}
+
+func Test_CorimCatalogCmd_no_dir(t *testing.T) {
+	cmd := NewCorimCatalogCmd()
+	cmd.SetArgs([]string{"--output=catalog.json"})
+	assert.EqualError(t, cmd.Execute(), "no directory supplied")
+}
+
+func Test_CorimCatalogCmd_no_output(t *testing.T) {
+	cmd := NewCorimCatalogCmd()
+	cmd.SetArgs([]string{"--dir=corims"})
+	assert.EqualError(t, cmd.Execute(), "no output file supplied")
+}
+
+func Test_CorimCatalogCmd_missing_dir(t *testing.T) {
+	fs = afero.NewMemMapFs()
+
+	cmd := NewCorimCatalogCmd()
+	cmd.SetArgs([]string{"--dir=corims", "--output=catalog.json"})
+	assert.ErrorContains(t, cmd.Execute(), "error reading directory corims: ")
+}
+
+func Test_CorimCatalogCmd_ok(t *testing.T) {
+	writeTestCatalogTree(t)
+
+	cmd := NewCorimCatalogCmd()
+	cmd.SetArgs([]string{"--dir=corims", "--output=catalog.json"})
+	require.NoError(t, cmd.Execute())
+
+	entries := readTestCatalog(t, "catalog.json")
+	require.Len(t, entries, 3)
+
+	assert.Equal(t, "corims/acme/signed.cbor", entries[0].Path)
+	assert.Equal(t, catalogUnverified, entries[0].Signature)
+	assert.Equal(t, "ES256", entries[0].Algorithm)
+	assert.NotEmpty(t, entries[0].ID)
+	require.NotNil(t, entries[0].Tags)
+	assert.Equal(t, 1, entries[0].Tags.Comids)
+	assert.Empty(t, entries[0].Error)
+
+	assert.Equal(t, "corims/garbage.cbor", entries[1].Path)
+	assert.Contains(t, entries[1].Error, "error decoding CoRIM (signed or unsigned): ")
+	assert.Empty(t, entries[1].Signature)
+	assert.Nil(t, entries[1].Tags)
+
+	assert.Equal(t, "corims/unsigned.cbor", entries[2].Path)
+	assert.Equal(t, catalogUnsigned, entries[2].Signature)
+	assert.Empty(t, entries[2].Algorithm)
+	assert.Nil(t, entries[2].SignatureValidity)
+	require.NotNil(t, entries[2].Tags)
+	// the CoSWID tag of testCorimValid does not survive decoding
+	assert.Equal(t, tagTypeCounts{Other: 1}, *entries[2].Tags)
+}
+
+func Test_CorimCatalogCmd_key(t *testing.T) {
+	writeTestCatalogTree(t)
+	require.NoError(t, afero.WriteFile(fs, "ok.jwk", testECKey, 0644))
+	require.NoError(t, afero.WriteFile(fs, "bad.jwk", testEdDSAKey, 0644))
+
+	cmd := NewCorimCatalogCmd()
+	cmd.SetArgs([]string{"--dir=corims", "--output=catalog.json", "--key=ok.jwk"})
+	require.NoError(t, cmd.Execute())
+
+	entries := readTestCatalog(t, "catalog.json")
+	require.Len(t, entries, 3)
+	assert.Equal(t, catalogVerified, entries[0].Signature)
+	assert.Empty(t, entries[0].Error)
+	assert.Equal(t, catalogUnsigned, entries[2].Signature)
+
+	cmd = NewCorimCatalogCmd()
+	cmd.SetArgs([]string{"--dir=corims", "--output=catalog.json", "--key=bad.jwk"})
+	require.NoError(t, cmd.Execute())
+
+	entries = readTestCatalog(t, "catalog.json")
+	require.Len(t, entries, 3)
+	assert.Equal(t, catalogInvalid, entries[0].Signature)
+	assert.NotEmpty(t, entries[0].Error)
+}
@@ -0,0 +1,220 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/corim/corim"
+	"github.com/veraison/corim/cots"
+	"github.com/veraison/swid"
+)
+
+var (
+	corimCborDiagFile     *string
+	corimCborDiagOutput   *string
+	corimCborDiagEmbedded *bool
+)
+
+var corimCborDiagCmd = NewCorimCborDiagCmd()
+
+func NewCorimCborDiagCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cbor-diag",
+		Short: "convert a CBOR-encoded CoRIM, CoMID, CoSWID or CoTS to CBOR diagnostic notation",
+		Long: `convert a CBOR-encoded CoRIM, CoMID, CoSWID or CoTS to CBOR diagnostic notation
+
+	The type of artifact (signed CoRIM, unsigned CoRIM, CoMID, CoSWID or CoTS)
+	is detected automatically.  The extended diagnostic notation (RFC 8949,
+	Section 8) is saved to the output file.
+
+	Convert the signed CoRIM in signed-corim.cbor and save the result to
+	signed-corim.diag
+
+	  cocli corim cbor-diag --file=signed-corim.cbor
+
+	Convert the CoMID in comid.cbor, also expanding byte strings that wrap
+	CBOR data items (e.g., the payload of a signed CoRIM, or the tags of an
+	unsigned CoRIM), and save the result to comid.txt
+
+	  cocli corim cbor-diag --file=comid.cbor --embedded-cbor --output=comid.txt
+	`,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkCorimCborDiagArgs(); err != nil {
+				return err
+			}
+
+			output := *corimCborDiagOutput
+			if output == "" {
+				output = makeFileName(filepath.Dir(*corimCborDiagFile), *corimCborDiagFile, ".diag")
+			}
+
+			kind, err := cborDiag(*corimCborDiagFile, output, *corimCborDiagEmbedded)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf(">> %s %q converted to diagnostic notation and saved to %q\n",
+				kind, *corimCborDiagFile, output)
+
+			return nil
+		},
+	}
+
+	corimCborDiagFile = cmd.Flags().StringP("file", "f", "", "a CBOR-encoded CoRIM, CoMID, CoSWID or CoTS")
+	corimCborDiagOutput = cmd.Flags().StringP("output", "o", "", "name of the generated (diagnostic notation) file")
+	corimCborDiagEmbedded = cmd.Flags().Bool("embedded-cbor", false, "expand byte strings that contain CBOR data items")
+
+	return cmd
+}
+
+func checkCorimCborDiagArgs() error {
+	if corimCborDiagFile == nil || *corimCborDiagFile == "" {
+		return errors.New("no CBOR file supplied")
+	}
+
+	return nil
+}
+
+// cborDiag converts the CBOR data in file to diagnostic notation, which is saved
+// to output.  It returns the detected artifact type.
+func cborDiag(file, output string, embedded bool) (string, error) {
+	data, err := afero.ReadFile(fs, file)
+	if err != nil {
+		return "", fmt.Errorf("error loading CBOR data from %s: %w", file, err)
+	}
+
+	kind := detectArtifact(data)
+	if kind == "" {
+		// any well-formed CBOR can still be converted
+		kind = "CBOR data item"
+	}
+
+	diag, err := toDiag(data, embedded)
+	if err != nil {
+		return "", fmt.Errorf("error converting %s to diagnostic notation: %w", file, err)
+	}
+
+	if err = afero.WriteFile(fs, output, []byte(diag+"\n"), 0644); err != nil {
+		return "", fmt.Errorf("error saving diagnostic notation to %s: %w", output, err)
+	}
+
+	return kind, nil
+}
+
+// toDiag returns the extended diagnostic notation of the single CBOR data item
+// in data.  If embedded is set, byte strings wrapping a single CBOR array, map
+// or tag are notated as embedded CBOR (<<...>>).
+func toDiag(data []byte, embedded bool) (string, error) {
+	diag, err := cbor.Diagnose(data)
+	if err != nil || !embedded {
+		return diag, err
+	}
+
+	return expandEmbeddedCBOR(diag)
+}
+
+// expandEmbeddedCBOR replaces the hex-encoded byte strings in diag that wrap
+// a single CBOR array, map or tag with their (recursively expanded) embedded
+// CBOR notation.  Byte strings that merely happen to be well-formed CBOR
+// (e.g., short identifiers) are left as they are.
+func expandEmbeddedCBOR(diag string) (string, error) {
+	var out strings.Builder
+
+	for i := 0; i < len(diag); i++ {
+		switch {
+		case diag[i] == '"':
+			// copy text strings verbatim, taking escapes into account
+			j := i + 1
+			for ; j < len(diag) && diag[j] != '"'; j++ {
+				if diag[j] == '\\' {
+					j++
+				}
+			}
+			out.WriteString(diag[i:min(j+1, len(diag))])
+			i = j
+		case strings.HasPrefix(diag[i:], "h'"):
+			end := strings.IndexByte(diag[i+2:], '\'')
+			if end < 0 {
+				return "", errors.New("unterminated byte string in diagnostic notation")
+			}
+			lit := diag[i : i+2+end+1]
+			i += 2 + end
+
+			val, err := hex.DecodeString(lit[2 : len(lit)-1])
+			if err != nil || len(val) == 0 || val[0]>>5 < 4 || val[0]>>5 > 6 {
+				out.WriteString(lit)
+				continue
+			}
+
+			inner, err := toDiag(val, true)
+			if err != nil {
+				out.WriteString(lit)
+				continue
+			}
+			out.WriteString("<<" + inner + ">>")
+		default:
+			out.WriteByte(diag[i])
+		}
+	}
+
+	return out.String(), nil
+}
+
+// detectArtifact returns the type of the artifact encoded in data, or an empty
+// string if it is not recognized.  Tagged artifacts are identified by their
+// tag; untagged ones by attempting to decode (and validate) them.
+func detectArtifact(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte("\xd9\x01\xf4\xd9\x01\xf6")), bytes.HasPrefix(data, []byte("\xd2")):
+		if _, err := decodeSign1(data); err == nil {
+			return "signed CoRIM"
+		}
+		return ""
+	case bytes.HasPrefix(data, corim.UnsignedCorimTag):
+		return "unsigned CoRIM"
+	case bytes.HasPrefix(data, corim.ComidTag):
+		return "CoMID"
+	case bytes.HasPrefix(data, corim.CoswidTag):
+		return "CoSWID"
+	case bytes.HasPrefix(data, cots.CotsTag):
+		return "CoTS"
+	}
+
+	var u corim.UnsignedCorim
+	if u.FromCBOR(data) == nil && u.Valid() == nil {
+		return "unsigned CoRIM"
+	}
+
+	var c comid.Comid
+	if c.FromCBOR(data) == nil && c.Valid() == nil {
+		return "CoMID"
+	}
+
+	var t cots.ConciseTaStore
+	if t.FromCBOR(data) == nil && t.Valid() == nil {
+		return "CoTS"
+	}
+
+	var s swid.SoftwareIdentity
+	if s.FromCBOR(data) == nil && s.TagID.String() != "" && s.SoftwareName != "" {
+		return "CoSWID"
+	}
+
+	return ""
+}
+
+func init() {
+	corimCmd.AddCommand(corimCborDiagCmd)
+}
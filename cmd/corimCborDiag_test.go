@@ -0,0 +1,134 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/corim"
+)
+
+func Test_CorimCborDiagCmd_no_file(t *testing.T) {
+	cmd := NewCorimCborDiagCmd()
+
+	args := []string{}
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "no CBOR file supplied")
+}
+
+func Test_CorimCborDiagCmd_file_not_found(t *testing.T) {
+	cmd := NewCorimCborDiagCmd()
+
+	args := []string{
+		"--file=nonexistent.cbor",
+	}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "error loading CBOR data from nonexistent.cbor: open nonexistent.cbor: file does not exist")
+}
+
+func Test_CorimCborDiagCmd_bad_cbor(t *testing.T) {
+	cmd := NewCorimCborDiagCmd()
+
+	args := []string{
+		"--file=bad.cbor",
+	}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "bad.cbor", []byte{0xa1, 0x01}, 0644)
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	assert.EqualError(t, err, "error converting bad.cbor to diagnostic notation: unexpected EOF")
+}
+
+func Test_CorimCborDiagCmd_ok(t *testing.T) {
+	cmd := NewCorimCborDiagCmd()
+
+	args := []string{
+		"--file=dir/comid.cbor",
+	}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "dir/comid.cbor", []byte{0xa1, 0x01, 0x42, 0xa0, 0xa0}, 0644)
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	assert.NoError(t, err)
+
+	actual, err := afero.ReadFile(fs, "dir/comid.diag")
+	assert.NoError(t, err)
+	assert.Equal(t, "{1: h'a0a0'}\n", string(actual))
+}
+
+func Test_CorimCborDiagCmd_embedded_cbor(t *testing.T) {
+	cmd := NewCorimCborDiagCmd()
+
+	args := []string{
+		"--file=x.cbor",
+		"--output=x.txt",
+		"--embedded-cbor",
+	}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+	// neither a truncated map nor a sequence of integers is expanded
+	err := afero.WriteFile(fs, "x.cbor", []byte{0xa2, 0x01, 0x42, 0xa1, 0x02, 0x02, 0x42, 0x01, 0x02}, 0644)
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	assert.NoError(t, err)
+
+	actual, err := afero.ReadFile(fs, "x.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "{1: h'a102', 2: h'0102'}\n", string(actual))
+
+	err = afero.WriteFile(fs, "x.cbor", []byte{0xa1, 0x01, 0x45, 0x81, 0x43, 0xa1, 0x02, 0x03}, 0644)
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	assert.NoError(t, err)
+
+	actual, err = afero.ReadFile(fs, "x.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "{1: <<[<<{2: 3}>>]>>}\n", string(actual))
+}
+
+func Test_detectArtifact(t *testing.T) {
+	var u corim.UnsignedCorim
+	require.NoError(t, u.FromCBOR(testCorimValid))
+	taggedCorim, err := u.ToCBOR()
+	require.NoError(t, err)
+
+	tvs := []struct {
+		desc     string
+		data     []byte
+		expected string
+	}{
+		{"signed CoRIM", testSignedCorimValid, "signed CoRIM"},
+		{"unsigned CoRIM", testCorimValid, "unsigned CoRIM"},
+		{"tagged unsigned CoRIM", taggedCorim, "unsigned CoRIM"},
+		{"CoMID", testComid, "CoMID"},
+		{"PSA CoMID", PSARefValCBOR, "CoMID"},
+		{"CoSWID", testCoswid, "CoSWID"},
+		{"CoTS", testCots, "CoTS"},
+		{"unknown", []byte{0xa1, 0x01, 0x02}, ""},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.desc, func(t *testing.T) {
+			assert.Equal(t, tv.expected, detectArtifact(tv.data))
+		})
+	}
+}
@@ -4,26 +4,33 @@
 package cmd
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 
 	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
-	"github.com/veraison/corim/comid"
 	"github.com/veraison/corim/corim"
 	"github.com/veraison/corim/cots"
+	"github.com/veraison/eat"
 	"github.com/veraison/swid"
 )
 
 var (
-	corimCreateCorimFile   *string
-	corimCreateCoswidFiles []string
-	corimCreateCoswidDirs  []string
-	corimCreateComidFiles  []string
-	corimCreateComidDirs   []string
-	corimCreateCotsFiles   []string
-	corimCreateCotsDirs    []string
-	corimCreateOutputFile  *string
+	corimCreateCorimFile    *string
+	corimCreateCoswidFiles  []string
+	corimCreateCoswidDirs   []string
+	corimCreateComidFiles   []string
+	corimCreateComidDirs    []string
+	corimCreateCotsFiles    []string
+	corimCreateCotsDirs     []string
+	corimCreateOutputFile   *string
+	corimCreateStrictDecode *bool
+	corimCreateJSONLimits   jsonLimits
+	corimCreateEnvExpansion envExpansion
+	corimCreateEntitiesFile *string
+	corimCreateFullFile     *string
+	corimCreateMetaOutput   *string
 )
 
 var corimCreateCmd = NewCorimCreateCmd()
@@ -52,6 +59,41 @@ func NewCorimCreateCmd() *cobra.Command {
 	                   --coswid=dir/coswid2.cbor \
 					   --cots=cots1.cbor
 	                   --output=corim.cbor
+
+	Create a CoRIM from template corim-template.json, substituting ${VAR}
+	references (e.g., ${BUILD_ID}) with the values of the corresponding
+	environment variables, and the empty string for those that are unset
+
+	  cocli corim create --template=corim-template.json \
+	                   --comid=comid1.cbor \
+	                   --expand-env \
+	                   --allow-missing-env
+
+	Create a CoRIM from template corim-template.json, adding the entities
+	(organization names, URIs, roles) shared across CoRIMs, which are stored
+	as a JSON array in entities.json, after those in the template (if any)
+
+	  cocli corim create --template=corim-template.json \
+	                   --entities=entities.json \
+	                   --comid=comid1.cbor
+
+	Reject a template larger than 64 KiB, or with arrays and objects nested
+	more than 16 levels deep (by default, the limits are 10 MiB and 64 levels)
+
+	  cocli corim create --template=corim-template.json \
+	                   --comid=comid1.cbor \
+	                   --max-json-size=65536 \
+	                   --max-json-depth=16
+
+	Create a CoRIM from the single JSON document corim-full.json, which
+	describes the whole CoRIM: the members of a CoRIM template, plus the
+	CoMIDs, CoSWIDs and CoTS inlined (in JSON format) in its "comids",
+	"coswids" and "cots" arrays.  The CoRIM Meta in its "meta" member, if any,
+	is saved to meta.json, to be used with "corim sign --meta"
+
+	  cocli corim create --full=corim-full.json \
+	                   --meta-output=meta.json \
+	                   --output=unsigned.cbor
 	`,
 
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -59,6 +101,17 @@ func NewCorimCreateCmd() *cobra.Command {
 				return err
 			}
 
+			if *corimCreateFullFile != "" {
+				cborFile, err := corimFullToCBOR(*corimCreateFullFile, corimCreateOutputFile,
+					*corimCreateMetaOutput, *corimCreateStrictDecode, corimCreateJSONLimits, corimCreateEnvExpansion)
+				if err != nil {
+					return err
+				}
+				fmt.Printf(">> created %q from %q\n", cborFile, *corimCreateFullFile)
+
+				return nil
+			}
+
 			comidFilesList := filesList(corimCreateComidFiles, corimCreateComidDirs, ".cbor")
 			coswidFilesList := filesList(corimCreateCoswidFiles, corimCreateCoswidDirs, ".cbor")
 			cotsFilesList := filesList(corimCreateCotsFiles, corimCreateCotsDirs, ".cbor")
@@ -68,8 +121,9 @@ func NewCorimCreateCmd() *cobra.Command {
 			}
 
 			// checkCorimCreateArgs makes sure corimCreateCorimFile is not nil
-			cborFile, err := corimTemplateToCBOR(*corimCreateCorimFile,
-				comidFilesList, coswidFilesList, cotsFilesList, corimCreateOutputFile)
+			cborFile, err := corimTemplateToCBOR(*corimCreateCorimFile, *corimCreateEntitiesFile,
+				comidFilesList, coswidFilesList, cotsFilesList, corimCreateOutputFile, *corimCreateStrictDecode,
+				corimCreateJSONLimits, corimCreateEnvExpansion)
 			if err != nil {
 				return err
 			}
@@ -80,6 +134,9 @@ func NewCorimCreateCmd() *cobra.Command {
 	}
 
 	corimCreateCorimFile = cmd.Flags().StringP("template", "t", "", "a CoRIM template file (in JSON format)")
+	corimCreateEntitiesFile = cmd.Flags().String("entities", "", "a file with a JSON array of entities to add to those of the template")
+	corimCreateFullFile = cmd.Flags().String("full", "", "a JSON file describing the whole CoRIM, with its tags inlined, instead of --template and tag files")
+	corimCreateMetaOutput = cmd.Flags().String("meta-output", "", "save the CoRIM Meta of the --full document to this file (in JSON format)")
 
 	cmd.Flags().StringArrayVarP(
 		&corimCreateComidDirs, "comid-dir", "M", []string{}, "a directory containing CBOR-encoded CoMID files",
@@ -106,58 +163,102 @@ func NewCorimCreateCmd() *cobra.Command {
 	)
 
 	corimCreateOutputFile = cmd.Flags().StringP("output", "o", "", "name of the generated (unsigned) CoRIM file")
+	corimCreateStrictDecode = cmd.Flags().Bool("strict-decode", false, "reject template and tags carrying fields that are not understood")
+
+	addJSONLimitsFlags(cmd, &corimCreateJSONLimits)
+	addEnvExpansionFlags(cmd, &corimCreateEnvExpansion)
 
 	return cmd
 }
 
 func checkCorimCreateArgs() error {
+	hasFull := corimCreateFullFile != nil && *corimCreateFullFile != ""
+	hasTags := len(corimCreateComidDirs)+len(corimCreateComidFiles)+
+		len(corimCreateCoswidDirs)+len(corimCreateCoswidFiles)+
+		len(corimCreateCotsDirs)+len(corimCreateCotsFiles) != 0
+
+	if !hasFull && corimCreateMetaOutput != nil && *corimCreateMetaOutput != "" {
+		return errors.New("--meta-output can only be used together with --full")
+	}
+
+	if hasFull {
+		if (corimCreateCorimFile != nil && *corimCreateCorimFile != "") ||
+			(corimCreateEntitiesFile != nil && *corimCreateEntitiesFile != "") || hasTags {
+			return errors.New("--full cannot be used together with --template, --entities or CoMID, CoSWID or CoTS files or folders")
+		}
+
+		if err := corimCreateJSONLimits.valid(); err != nil {
+			return err
+		}
+
+		return corimCreateEnvExpansion.valid()
+	}
+
 	if corimCreateCorimFile == nil || *corimCreateCorimFile == "" {
 		return errors.New("no CoRIM template supplied")
 	}
 
-	if len(corimCreateComidDirs)+len(corimCreateComidFiles)+
-		len(corimCreateCoswidDirs)+len(corimCreateCoswidFiles)+
-		len(corimCreateCotsDirs)+len(corimCreateCotsFiles) == 0 {
+	if !hasTags {
 		return errors.New("no CoMID, CoSWID or CoTS files or folders supplied")
 	}
 
-	return nil
+	if err := corimCreateJSONLimits.valid(); err != nil {
+		return err
+	}
+
+	return corimCreateEnvExpansion.valid()
 }
 
-func corimTemplateToCBOR(tmplFile string, comidFiles, coswidFiles, cotsFiles []string, outputFile *string) (string, error) {
+func corimTemplateToCBOR(tmplFile, entitiesFile string, comidFiles, coswidFiles, cotsFiles []string, outputFile *string, strict bool, limits jsonLimits, env envExpansion) (string, error) {
 	var (
-		tmplData, corimCBOR []byte
-		c                   corim.UnsignedCorim
-		corimFile           string
-		err                 error
+		tmplData []byte
+		err      error
 	)
 
-	if tmplData, err = afero.ReadFile(fs, tmplFile); err != nil {
+	if tmplData, err = readJSONTemplate(tmplFile, limits, env); err != nil {
 		return "", fmt.Errorf("error loading template from %s: %w", tmplFile, err)
 	}
 
-	if err = c.FromJSON(tmplData); err != nil {
+	// register the extensions of the profile declared by the template, if any
+	c := corim.GetUnsignedCorim(jsonProfile(tmplData))
+
+	if err = decodeJSON(c, tmplData, strict); err != nil {
 		return "", fmt.Errorf("error decoding template from %s: %w", tmplFile, err)
 	}
 
+	// append the shared entities
+	if entitiesFile != "" {
+		entities, err := loadEntities(entitiesFile, c.Profile, strict, limits, env)
+		if err != nil {
+			return "", err
+		}
+
+		if c.Entities == nil {
+			c.Entities = corim.NewEntities()
+		}
+
+		for i := range entities.Values {
+			c.Entities.Add(&entities.Values[i])
+		}
+	}
+
 	// append CoMID(s)
 	for _, comidFile := range comidFiles {
-		var (
-			comidCBOR []byte
-			m         comid.Comid
-		)
+		var comidCBOR []byte
 
 		comidCBOR, err = afero.ReadFile(fs, comidFile)
 		if err != nil {
 			return "", fmt.Errorf("error loading CoMID from %s: %w", comidFile, err)
 		}
 
-		err = m.FromCBOR(comidCBOR)
+		m := newComid(c.Profile)
+
+		err = decodeCBOR(m, comidCBOR, strict)
 		if err != nil {
 			return "", fmt.Errorf("error loading CoMID from %s: %w", comidFile, err)
 		}
 
-		if c.AddComid(&m) == nil {
+		if c.AddComid(m) == nil {
 			return "", fmt.Errorf(
 				"error adding CoMID from %s (check its validity using the %q sub-command)",
 				comidFile, "comid validate",
@@ -177,7 +278,7 @@ func corimTemplateToCBOR(tmplFile string, comidFiles, coswidFiles, cotsFiles []s
 			return "", fmt.Errorf("error loading CoSWID from %s: %w", coswidFile, err)
 		}
 
-		err = s.FromCBOR(coswidCBOR)
+		err = decodeCBOR(&s, coswidCBOR, strict)
 		if err != nil {
 			return "", fmt.Errorf("error loading CoSWID from %s: %w", coswidFile, err)
 		}
@@ -199,7 +300,7 @@ func corimTemplateToCBOR(tmplFile string, comidFiles, coswidFiles, cotsFiles []s
 			return "", fmt.Errorf("error loading CoTS from %s: %w", cotsFile, err)
 		}
 
-		err = t.FromCBOR(cotsCBOR)
+		err = decodeCBOR(&t, cotsCBOR, strict)
 		if err != nil {
 			return "", fmt.Errorf("error loading CoTS from %s: %w", cotsFile, err)
 		}
@@ -209,18 +310,27 @@ func corimTemplateToCBOR(tmplFile string, comidFiles, coswidFiles, cotsFiles []s
 		}
 	}
 
+	return saveUnsignedCorim(c, tmplFile, outputFile)
+}
+
+// saveUnsignedCorim validates c and saves its CBOR encoding to outputFile or,
+// if that is not set, to the current directory with the basename of srcFile
+// and a .cbor extension.  It returns the name of the saved file.
+func saveUnsignedCorim(c *corim.UnsignedCorim, srcFile string, outputFile *string) (string, error) {
+	var corimFile string
+
 	// check the result
-	if err = c.Valid(); err != nil {
+	if err := c.Valid(); err != nil {
 		return "", fmt.Errorf("error validating CoRIM: %w", err)
 	}
 
-	corimCBOR, err = c.ToCBOR()
+	corimCBOR, err := c.ToCBOR()
 	if err != nil {
 		return "", fmt.Errorf("error encoding CoRIM to CBOR: %w", err)
 	}
 
 	if outputFile == nil || *outputFile == "" {
-		corimFile = makeFileName("", tmplFile, ".cbor")
+		corimFile = makeFileName("", srcFile, ".cbor")
 	} else {
 		corimFile = *outputFile
 	}
@@ -233,6 +343,50 @@ func corimTemplateToCBOR(tmplFile string, comidFiles, coswidFiles, cotsFiles []s
 	return corimFile, nil
 }
 
+// loadEntities loads the JSON array of entities in file, decoding them with
+// the extensions registered for profile, if any, and checks that each of them
+// is valid
+func loadEntities(file string, profile *eat.Profile, strict bool, limits jsonLimits, env envExpansion) (*corim.Entities, error) {
+	data, err := readJSONTemplate(file, limits, env)
+	if err != nil {
+		return nil, fmt.Errorf("error loading entities from %s: %w", file, err)
+	}
+
+	var values []json.RawMessage
+	if err = json.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("error decoding entities from %s: expecting a JSON array of entities: %w", file, err)
+	}
+
+	if len(values) == 0 {
+		return nil, fmt.Errorf("error decoding entities from %s: no entities found", file)
+	}
+
+	// the entities are decoded as part of an otherwise empty CoRIM (with a
+	// placeholder for its mandatory id), so that any Entity extension of the
+	// profile applies
+	u := corim.GetUnsignedCorim(profile)
+
+	wrapped, err := json.Marshal(map[string]interface{}{
+		"corim-id": "entities",
+		"entities": json.RawMessage(data),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error decoding entities from %s: %w", file, err)
+	}
+
+	if err = decodeJSON(u, wrapped, strict); err != nil {
+		return nil, fmt.Errorf("error decoding entities from %s: %w", file, err)
+	}
+
+	for i, e := range u.Entities.Values {
+		if err = e.Valid(); err != nil {
+			return nil, fmt.Errorf("error validating entities from %s: entity at index %d: %w", file, i, err)
+		}
+	}
+
+	return u.Entities, nil
+}
+
 func init() {
 	corimCmd.AddCommand(corimCreateCmd)
 }
@@ -9,6 +9,7 @@ import (
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/corim"
 )
 
 func Test_CorimCreateCmd_unknown_argument(t *testing.T) {
@@ -264,3 +265,92 @@ func Test_CorimCreateCmd_successful_comid_coswid_and_cots_from_dir(t *testing.T)
 	_, err = fs.Stat("min-tmpl.cbor")
 	assert.NoError(t, err)
 }
+
+var testEntities = []byte(`[
+  { "name": "ACME Ltd.", "regid": "https://acme.example", "roles": [ "manifestCreator" ] },
+  { "name": "EMCA Ltd.", "roles": [ "manifestCreator" ] }
+]`)
+
+// createCorimWithEntities runs corim create on tmpl, with the shared entities
+// in entities
+func createCorimWithEntities(t *testing.T, tmpl, entities []byte, extraArgs ...string) error {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "tmpl.json", tmpl, 0644))
+	require.NoError(t, afero.WriteFile(fs, "entities.json", entities, 0644))
+	require.NoError(t, afero.WriteFile(fs, "comid.cbor", testComid, 0644))
+
+	cmd := NewCorimCreateCmd()
+	cmd.SetArgs(append([]string{
+		"--template=tmpl.json",
+		"--entities=entities.json",
+		"--comid=comid.cbor",
+		"--output=corim.cbor",
+	}, extraArgs...))
+
+	return cmd.Execute()
+}
+
+func Test_CorimCreateCmd_entities(t *testing.T) {
+	tmpl := []byte(`{
+  "corim-id": "5c57e8f4-46cd-421b-91c9-08cf93e13cfc",
+  "entities": [ { "name": "Product Team", "roles": [ "manifestCreator" ] } ]
+}`)
+
+	require.NoError(t, createCorimWithEntities(t, tmpl, testEntities, "--strict-decode"))
+
+	data, err := afero.ReadFile(fs, "corim.cbor")
+	require.NoError(t, err)
+
+	var c corim.UnsignedCorim
+	require.NoError(t, c.FromCBOR(data))
+	require.NotNil(t, c.Entities)
+	require.Len(t, c.Entities.Values, 3)
+
+	// the shared entities follow those of the template
+	assert.Equal(t, "Product Team", c.Entities.Values[0].Name.String())
+	assert.Equal(t, "ACME Ltd.", c.Entities.Values[1].Name.String())
+	assert.Equal(t, "EMCA Ltd.", c.Entities.Values[2].Name.String())
+}
+
+func Test_CorimCreateCmd_entities_without_template_entities(t *testing.T) {
+	require.NoError(t, createCorimWithEntities(t, minimalCorimTemplate, testEntities))
+
+	data, err := afero.ReadFile(fs, "corim.cbor")
+	require.NoError(t, err)
+
+	var c corim.UnsignedCorim
+	require.NoError(t, c.FromCBOR(data))
+	require.NotNil(t, c.Entities)
+	assert.Len(t, c.Entities.Values, 2)
+}
+
+func Test_CorimCreateCmd_bad_entities(t *testing.T) {
+	tvs := []struct {
+		desc     string
+		entities string
+		expected string
+	}{
+		{
+			desc:     "not an array",
+			entities: `{"name": "ACME Ltd."}`,
+			expected: "error decoding entities from entities.json: expecting a JSON array of entities",
+		},
+		{
+			desc:     "empty",
+			entities: `[]`,
+			expected: "error decoding entities from entities.json: no entities found",
+		},
+		{
+			desc:     "no roles",
+			entities: `[{"name": "ACME Ltd.", "roles": []}]`,
+			expected: "error validating entities from entities.json: entity at index 0: invalid entity: empty roles",
+		},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.desc, func(t *testing.T) {
+			err := createCorimWithEntities(t, minimalCorimTemplate, []byte(tv.entities))
+			assert.ErrorContains(t, err, tv.expected)
+		})
+	}
+}
@@ -8,16 +8,31 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 	"github.com/veraison/corim/corim"
 	"github.com/veraison/corim/cots"
+	"github.com/veraison/eat"
 )
 
 var (
-	corimDisplayCorimFile *string
-	corimDisplayShowTags  *bool
+	corimDisplayCorimFile    *string
+	corimDisplayShowTags     *bool
+	corimDisplayStrictDecode *bool
+	corimDisplayTimezone     *string
+	corimDisplayFlat         *bool
+	corimDisplayJSON         *bool
+	corimDisplayJSONFormat   jsonFormat
+	corimDisplayOutputFile   *string
+	corimDisplaySequence     *bool
+	corimDisplayHash         *bool
+	corimDisplayHashAlg      *string
+	corimDisplayOffset       *int
+	corimDisplayLimit        *int
+	corimDisplayInputGlobs   []string
+	corimDisplayTagType      *string
 )
 
 var corimDisplayCmd = NewCorimDisplayCmd()
@@ -36,6 +51,51 @@ func NewCorimDisplayCmd() *cobra.Command {
 	also unpack any embedded CoMID, CoSWID and CoTS
 	
 	  cocli corim display --file yet-another-signed-corim.cbor --show-tags
+
+	Only unpack the CoTSs embedded in signed-corim.cbor, after reporting the
+	number of tags of each type
+
+	  cocli corim display --file signed-corim.cbor --show-tags --tag-type=cots
+
+	Display the contents of the signed CoRIM signed-corim.cbor, rendering the
+	validity timestamps in the Europe/Rome time zone
+
+	  cocli corim display --file signed-corim.cbor --timezone=Europe/Rome
+
+	List every measurement digest of every CoMID in signed-corim.cbor, one per
+	line, as tab-separated "tag-index environment key alg digest" rows.  Use
+	--json to print them in JSON format instead.
+
+	  cocli corim display --file signed-corim.cbor --measurements-flat [--json]
+
+	Display each of the CoRIMs concatenated, as a CBOR sequence, in
+	corims.cborseq
+
+	  cocli corim display --file corims.cborseq --sequence
+
+	Display each of the CoRIMs found anywhere below the releases directory
+
+	  cocli corim display --input-glob='releases/**/*.cbor'
+
+	Save the list of measurement digests to digests.json instead of printing it
+
+	  cocli corim display --file signed-corim.cbor --measurements-flat --json \
+	                      --output=digests.json
+
+	Same as above, but without any whitespace in the JSON output
+
+	  cocli corim display --file signed-corim.cbor --measurements-flat --json \
+	                      --json-compact --output=digests.json
+
+	Print the SHA-384 fingerprint of the unsigned CoRIM carried in
+	signed-corim.cbor, which does not change if the CoRIM is signed again
+
+	  cocli corim display --file signed-corim.cbor --hash --hash-alg=sha-384
+
+	COSE Mac0-protected CoRIMs are displayed as well, together with their MAC
+	algorithm.  Their MAC is not verified (see "corim verify --mac-key")
+
+	  cocli corim display --file mac-corim.cbor
 	`,
 
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -43,25 +103,160 @@ func NewCorimDisplayCmd() *cobra.Command {
 				return err
 			}
 
-			return display(*corimDisplayCorimFile, *corimDisplayShowTags)
+			return withDisplayOutput(*corimDisplayOutputFile, func() error {
+				if *corimDisplayHash {
+					return printCorimHash(*corimDisplayCorimFile, *corimDisplayHashAlg, *corimDisplayStrictDecode)
+				}
+
+				if *corimDisplayFlat {
+					var jf *jsonFormat
+					if *corimDisplayJSON {
+						jf = &corimDisplayJSONFormat
+					}
+
+					return displayFlatMeasurements(*corimDisplayCorimFile, *corimDisplayStrictDecode, jf)
+				}
+
+				loc, err := loadTimezone(*corimDisplayTimezone)
+				if err != nil {
+					return err
+				}
+
+				// checkCorimDisplayArgs makes sure the page and tag type are
+				// valid
+				page, _ := newTagPage(corimDisplayOffset, corimDisplayLimit)
+				page.tagType, _ = newTagType(corimDisplayTagType)
+
+				if len(corimDisplayInputGlobs) != 0 {
+					return displayGlob(corimDisplayInputGlobs, *corimDisplayShowTags, *corimDisplayStrictDecode, loc, page)
+				}
+
+				if *corimDisplaySequence {
+					return displaySequence(*corimDisplayCorimFile, *corimDisplayShowTags, *corimDisplayStrictDecode, loc, page)
+				}
+
+				return display(*corimDisplayCorimFile, *corimDisplayShowTags, *corimDisplayStrictDecode, loc, page)
+			})
 		},
 	}
 
 	corimDisplayCorimFile = cmd.Flags().StringP("file", "f", "", "a CoRIM file (in CBOR format)")
 	corimDisplayShowTags = cmd.Flags().BoolP("show-tags", "v", false, "display embedded tags")
+	corimDisplayStrictDecode = cmd.Flags().Bool("strict-decode", false, "reject CoRIMs (and tags) carrying fields that are not understood")
+	corimDisplayFlat = cmd.Flags().Bool("measurements-flat", false, "list the measurement digests of all CoMIDs, one per line")
+	corimDisplayJSON = cmd.Flags().Bool("json", false, "print the measurement digests in JSON format (with --measurements-flat)")
+	addJSONFormatFlags(cmd, &corimDisplayJSONFormat)
+	corimDisplayTimezone = cmd.Flags().String("timezone", defaultTimezone, "IANA time zone in which timestamps are rendered")
+	corimDisplaySequence = cmd.Flags().Bool("sequence", false, "the --file is a CBOR sequence of CoRIMs, each of which is displayed in turn")
+	corimDisplayHash = cmd.Flags().Bool("hash", false, "print the fingerprint (hash of the deterministic CBOR encoding) of the unsigned CoRIM instead of its content")
+	corimDisplayHashAlg = cmd.Flags().String("hash-alg", defaultCorimHashAlg, "hash algorithm used by --hash: sha-256, sha-384 or sha-512")
+	corimDisplayOffset, corimDisplayLimit = addTagPageFlags(cmd)
+	corimDisplayTagType = addTagTypeFlag(cmd)
+	corimDisplayOutputFile = cmd.Flags().StringP("output", "o", "", "save the rendered output to this file instead of printing it")
+	addInputGlobFlag(cmd, &corimDisplayInputGlobs, "CoRIM files to display, instead of --file")
 
 	return cmd
 }
 
 func checkCorimDisplayArgs() error {
-	if corimDisplayCorimFile == nil || *corimDisplayCorimFile == "" {
+	hasFile := corimDisplayCorimFile != nil && *corimDisplayCorimFile != ""
+	hasGlobs := len(corimDisplayInputGlobs) != 0
+
+	if !hasFile && !hasGlobs {
 		return errors.New("no CoRIM supplied")
 	}
 
+	if hasFile && hasGlobs {
+		return errors.New("--file cannot be used together with --input-glob")
+	}
+
+	flat := corimDisplayFlat != nil && *corimDisplayFlat
+
+	if hasGlobs && (flat || (corimDisplaySequence != nil && *corimDisplaySequence) ||
+		(corimDisplayHash != nil && *corimDisplayHash)) {
+		return errors.New("--input-glob cannot be used together with --measurements-flat, --sequence or --hash")
+	}
+
+	if flat && corimDisplayShowTags != nil && *corimDisplayShowTags {
+		return errors.New("--measurements-flat cannot be used together with --show-tags")
+	}
+
+	if flat && corimDisplaySequence != nil && *corimDisplaySequence {
+		return errors.New("--measurements-flat cannot be used together with --sequence")
+	}
+
+	if !flat && corimDisplayJSON != nil && *corimDisplayJSON {
+		return errors.New("--json can only be used together with --measurements-flat")
+	}
+
+	if (corimDisplayJSON == nil || !*corimDisplayJSON) && !corimDisplayJSONFormat.isDefault() {
+		return errors.New("--json-indent and --json-compact can only be used together with --json")
+	}
+
+	if err := corimDisplayJSONFormat.valid(); err != nil {
+		return err
+	}
+
+	page, err := newTagPage(corimDisplayOffset, corimDisplayLimit)
+	if err != nil {
+		return err
+	}
+
+	if page.isSet() && (corimDisplayShowTags == nil || !*corimDisplayShowTags) {
+		return errors.New("--offset and --limit can only be used together with --show-tags")
+	}
+
+	tt, err := newTagType(corimDisplayTagType)
+	if err != nil {
+		return err
+	}
+
+	if tt != "" && (corimDisplayShowTags == nil || !*corimDisplayShowTags) {
+		return errors.New("--tag-type can only be used together with --show-tags")
+	}
+
+	hash := corimDisplayHash != nil && *corimDisplayHash
+
+	if hash && (flat || (corimDisplayShowTags != nil && *corimDisplayShowTags) ||
+		(corimDisplaySequence != nil && *corimDisplaySequence)) {
+		return errors.New("--hash cannot be used together with --show-tags, --measurements-flat or --sequence")
+	}
+
+	if corimDisplayHashAlg != nil && *corimDisplayHashAlg != defaultCorimHashAlg {
+		if !hash {
+			return errors.New("--hash-alg can only be used together with --hash")
+		}
+
+		if _, err := parseCorimHashAlg(*corimDisplayHashAlg); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
-func displaySignedCorim(s corim.SignedCorim, corimFile string, showTags bool) error {
+func displaySignedCorim(s corim.SignedCorim, kid []byte, embeddedKey string, tst *timestampToken, corimFile string, showTags, strict bool, loc *time.Location, page tagPage) error {
+	if kid != nil {
+		fmt.Printf("Key ID: %s\n", formatKeyID(kid))
+	}
+
+	if embeddedKey != "" {
+		fmt.Printf("Embedded public key (JWK thumbprint): %s\n", embeddedKey)
+	}
+
+	if tst != nil {
+		genTime := tst.info.GenTime.UTC()
+		if loc != nil {
+			genTime = genTime.In(loc)
+		}
+
+		fmt.Printf("Timestamp: %s by %q (serial number %s), TSA certificate not verified (see \"corim verify --tsa-trust-anchor\")\n",
+			genTime.Format(time.RFC3339), tst.tsaName(), tst.info.SerialNumber)
+	}
+
+	s.Meta.Validity = validityIn(s.Meta.Validity, loc)
+	s.UnsignedCorim.RimValidity = validityIn(s.UnsignedCorim.RimValidity, loc)
+
 	metaJSON, err := json.MarshalIndent(&s.Meta, "", "  ")
 	if err != nil {
 		return fmt.Errorf("error encoding CoRIM Meta from %s: %w", corimFile, err)
@@ -80,60 +275,210 @@ func displaySignedCorim(s corim.SignedCorim, corimFile string, showTags bool) er
 
 	if showTags {
 		fmt.Println("Tags:")
-		displayTags(s.UnsignedCorim.Tags)
+		displayTags(s.UnsignedCorim.Tags, s.UnsignedCorim.Profile, strict, page)
 	}
 
 	return nil
 }
 
-func displayUnsignedCorim(u corim.UnsignedCorim, corimFile string, showTags bool) error {
+// displayMac0Corim displays the Mac0-protected CoRIM c, whose MAC is not
+// verified
+func displayMac0Corim(c *mac0Corim, corimFile string, showTags, strict bool, loc *time.Location, page tagPage) error {
+	fmt.Printf("Protection: MAC (COSE Mac0, %s), not a signature; not verified (see \"corim verify --mac-key\")\n", c.alg.name)
+
+	if c.kid != nil {
+		fmt.Printf("Key ID: %s\n", formatKeyID(c.kid))
+	}
+
+	if c.meta != nil {
+		c.meta.Validity = validityIn(c.meta.Validity, loc)
+
+		metaJSON, err := json.MarshalIndent(c.meta, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error encoding CoRIM Meta from %s: %w", corimFile, err)
+		}
+
+		fmt.Println("Meta:")
+		fmt.Println(string(metaJSON))
+	}
+
+	u := *c.unsigned
+	u.RimValidity = validityIn(u.RimValidity, loc)
+
 	corimJSON, err := json.MarshalIndent(&u, "", "  ")
 	if err != nil {
 		return fmt.Errorf("error encoding unsigned CoRIM from %s: %w", corimFile, err)
 	}
 
-	fmt.Println("Corim:")
+	fmt.Println("CoRIM:")
 	fmt.Println(string(corimJSON))
 
 	if showTags {
 		fmt.Println("Tags:")
-		displayTags(u.Tags)
+		displayTags(u.Tags, u.Profile, strict, page)
 	}
 
 	return nil
 }
 
-func display(corimFile string, showTags bool) error {
-	var (
-		corimCBOR []byte
-		err       error
-	)
+func displayUnsignedCorim(u corim.UnsignedCorim, corimFile string, showTags, strict bool, loc *time.Location, page tagPage) error {
+	u.RimValidity = validityIn(u.RimValidity, loc)
+
+	corimJSON, err := json.MarshalIndent(&u, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding unsigned CoRIM from %s: %w", corimFile, err)
+	}
+
+	fmt.Println("Corim:")
+	fmt.Println(string(corimJSON))
+
+	if showTags {
+		fmt.Println("Tags:")
+		displayTags(u.Tags, u.Profile, strict, page)
+	}
+
+	return nil
+}
 
+func display(corimFile string, showTags, strict bool, loc *time.Location, page tagPage) error {
 	// read the CoRIM file
-	if corimCBOR, err = afero.ReadFile(fs, corimFile); err != nil {
+	corimCBOR, err := afero.ReadFile(fs, corimFile)
+	if err != nil {
 		return fmt.Errorf("error loading CoRIM from %s: %w", corimFile, err)
 	}
 
-	// try to decode as a signed CoRIM
-	var s corim.SignedCorim
-	if err = s.FromCOSE(corimCBOR); err == nil {
+	return displayCorimData(corimFile, corimCBOR, showTags, strict, loc, page)
+}
+
+// displaySequence displays each of the CoRIMs in the CBOR sequence in
+// corimFile, reporting those that cannot be decoded
+func displaySequence(corimFile string, showTags, strict bool, loc *time.Location, page tagPage) error {
+	var errs int
+
+	n, err := readCBORSequence(corimFile, func(i int, item []byte) {
+		name := sequenceItemName(corimFile, i)
+
+		fmt.Printf(">> %s:\n", name)
+
+		if err := displayCorimData(name, item, showTags, strict, loc, page); err != nil {
+			fmt.Printf(">> display failed for %q: %v\n", name, err)
+			errs++
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return fmt.Errorf("no CoRIMs found in %s", corimFile)
+	}
+
+	if errs != 0 {
+		return fmt.Errorf("%d/%d display(s) failed", errs, n)
+	}
+
+	return nil
+}
+
+// displayGlob displays each of the CoRIMs matching globs, reporting those that
+// cannot be decoded
+func displayGlob(globs []string, showTags, strict bool, loc *time.Location, page tagPage) error {
+	files, err := globFiles(globs)
+	if err != nil {
+		return err
+	}
+
+	if len(files) == 0 {
+		return errors.New("no files found")
+	}
+
+	var errs int
+
+	for _, file := range files {
+		fmt.Printf(">> %s:\n", file)
+
+		if err := display(file, showTags, strict, loc, page); err != nil {
+			fmt.Printf(">> display failed for %q: %v\n", file, err)
+			errs++
+		}
+	}
+
+	if errs != 0 {
+		return fmt.Errorf("%d/%d display(s) failed", errs, len(files))
+	}
+
+	return nil
+}
+
+// displayCorimData displays the signed or unsigned CoRIM corimCBOR, which is
+// referred to as corimFile in messages
+func displayCorimData(corimFile string, corimCBOR []byte, showTags, strict bool, loc *time.Location, page tagPage) error {
+	var err error
+
+	if isMac0(corimCBOR) {
+		c, err := decodeMac0Corim(corimCBOR, strict)
+		if err != nil {
+			return fmt.Errorf("error decoding MAC-protected CoRIM from %s: %w", corimFile, err)
+		}
+
+		return displayMac0Corim(c, corimFile, showTags, strict, loc, page)
+	}
+
+	// try to decode as a signed CoRIM, either tagged or untagged
+	corimCBOR = tagSign1(corimCBOR)
+
+	// the extensions of the profile declared by the CoRIM, if any, are
+	// registered before decoding
+	if s, err := corim.UnmarshalSignedCorimFromCBOR(corimCBOR); err == nil {
+		if strict {
+			if err = checkUnknownSignedCorimFields(s, corimCBOR); err != nil {
+				return fmt.Errorf("error decoding signed CoRIM from %s: %w", corimFile, err)
+			}
+		}
+
+		var (
+			kid         []byte
+			embeddedKey string
+			tst         *timestampToken
+		)
+
+		if msg, err := decodeSign1(corimCBOR); err == nil {
+			kid = coseKeyID(msg)
+
+			if embeddedKey, err = embeddedKeyThumbprint(msg); err != nil {
+				fmt.Printf(">> warning: %v\n", err)
+			}
+
+			if tst, err = coseTimestampToken(msg); err != nil {
+				fmt.Printf(">> warning: %v\n", err)
+			}
+		}
+
 		// successfully decoded as signed CoRIM
-		return displaySignedCorim(s, corimFile, showTags)
+		return displaySignedCorim(*s, kid, embeddedKey, tst, corimFile, showTags, strict, loc, page)
 	}
 
 	// if decoding as signed CoRIM failed, attempt to decode as unsigned CoRIM
-	var u corim.UnsignedCorim
-	if err = u.FromCBOR(corimCBOR); err != nil {
+	u := corim.GetUnsignedCorim(cborProfile(corimCBOR))
+	if err = decodeCBOR(u, corimCBOR, strict); err != nil {
 		return fmt.Errorf("error decoding CoRIM (signed or unsigned) from %s: %w", corimFile, err)
 	}
 
 	// successfully decoded as unsigned CoRIM
-	return displayUnsignedCorim(u, corimFile, showTags)
+	return displayUnsignedCorim(*u, corimFile, showTags, strict, loc, page)
 }
 
-// displayTags processes and displays embedded tags within a CoRIM.
-func displayTags(tags []corim.Tag) {
+// displayTags processes and displays the embedded tags within a CoRIM that are
+// selected by page.
+func displayTags(tags []corim.Tag, profile *eat.Profile, strict bool, page tagPage) {
+	page.report(len(tags))
+	page.tagType.report(tags)
+
 	for i, t := range tags {
+		if !page.contains(i, len(tags)) {
+			continue
+		}
+
 		if len(t) < 4 {
 			fmt.Printf(">> skipping malformed tag at index %d\n", i)
 			continue
@@ -142,19 +487,23 @@ func displayTags(tags []corim.Tag) {
 		// Split tag identifier from data
 		cborTag, cborData := t[:3], t[3:]
 
+		if !page.tagType.contains(cborTag) {
+			continue
+		}
+
 		hdr := fmt.Sprintf(">> [ %d ]", i)
 
 		switch {
 		case bytes.Equal(cborTag, corim.ComidTag):
-			if err := printComid(cborData, hdr); err != nil {
+			if err := printComid(cborData, hdr, profile, strict); err != nil {
 				fmt.Printf(">> skipping malformed CoMID tag at index %d: %v\n", i, err)
 			}
 		case bytes.Equal(cborTag, corim.CoswidTag):
-			if err := printCoswid(cborData, hdr); err != nil {
+			if err := printCoswid(cborData, hdr, strict); err != nil {
 				fmt.Printf(">> skipping malformed CoSWID tag at index %d: %v\n", i, err)
 			}
 		case bytes.Equal(cborTag, cots.CotsTag):
-			if err := printCots(cborData, hdr); err != nil {
+			if err := printCots(cborData, hdr, strict); err != nil {
 				fmt.Printf(">> skipping malformed CoTS tag at index %d: %v\n", i, err)
 			}
 		default:
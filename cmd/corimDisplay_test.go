@@ -157,3 +157,104 @@ func Test_CorimDisplayCmd_ok_nested_view_with_cots(t *testing.T) {
 	err = cmd.Execute()
 	assert.NoError(t, err)
 }
+
+func Test_CorimDisplayCmd_unknown_field_tolerant(t *testing.T) {
+	cmd := NewCorimDisplayCmd()
+
+	args := []string{
+		"--file=unknown.cbor",
+	}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "unknown.cbor", testCorimUnknownField, 0644)
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	assert.NoError(t, err)
+}
+
+func Test_CorimDisplayCmd_unknown_field_strict(t *testing.T) {
+	cmd := NewCorimDisplayCmd()
+
+	args := []string{
+		"--file=unknown.cbor",
+		"--strict-decode",
+	}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "unknown.cbor", testCorimUnknownField, 0644)
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	assert.EqualError(t, err, `error decoding CoRIM (signed or unsigned) from unknown.cbor: unknown field "/99"`)
+}
+
+func Test_CorimDisplayCmd_ok_strict(t *testing.T) {
+	cmd := NewCorimDisplayCmd()
+
+	args := []string{
+		"--file=ok.cbor",
+		"--show-tags",
+		"--strict-decode",
+	}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "ok.cbor", testSignedCorimValidWithCots, 0644)
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	assert.NoError(t, err)
+}
+
+func Test_CorimDisplayCmd_ok_timezone(t *testing.T) {
+	cmd := NewCorimDisplayCmd()
+
+	args := []string{
+		"--file=ok.cbor",
+		"--timezone=Asia/Tokyo",
+	}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "ok.cbor", testSignedCorimValid, 0644)
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	assert.NoError(t, err)
+}
+
+func Test_CorimDisplayCmd_bad_timezone(t *testing.T) {
+	cmd := NewCorimDisplayCmd()
+
+	args := []string{
+		"--file=ok.cbor",
+		"--timezone=Mars/Olympus_Mons",
+	}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "ok.cbor", testSignedCorimValid, 0644)
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	assert.EqualError(t, err, `invalid time zone "Mars/Olympus_Mons": expecting an IANA time zone name, e.g., Europe/London`)
+}
+
+func Test_CorimDisplayCmd_ok_untagged(t *testing.T) {
+	cmd := NewCorimDisplayCmd()
+
+	args := []string{
+		"--file=ok.cbor",
+	}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "ok.cbor", untagSign1(testSignedCorimValid), 0644)
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	assert.NoError(t, err)
+}
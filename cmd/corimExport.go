@@ -0,0 +1,382 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/corim/corim"
+)
+
+// the values accepted by corim export --format
+const (
+	exportFormatCycloneDX = "cyclonedx"
+)
+
+const (
+	// cycloneDXSpecVersion is the version of the CycloneDX specification the
+	// exported BOMs follow
+	cycloneDXSpecVersion = "1.5"
+
+	// cycloneDXPropertyPrefix namespaces the CycloneDX properties carrying the
+	// CoRIM and CoMID details that have no CycloneDX counterpart
+	cycloneDXPropertyPrefix = "veraison:"
+)
+
+// cycloneDXHashAlgs maps the IANA Named Information Hash Algorithm names of
+// the CoMID digests to the CycloneDX hash algorithms.  The truncated SHA-256
+// variants (e.g., sha-256-128) have no CycloneDX counterpart.
+var cycloneDXHashAlgs = map[string]string{
+	"sha-256":  "SHA-256",
+	"sha-384":  "SHA-384",
+	"sha-512":  "SHA-512",
+	"sha3-256": "SHA3-256",
+	"sha3-384": "SHA3-384",
+	"sha3-512": "SHA3-512",
+}
+
+var (
+	corimExportCorimFile  *string
+	corimExportOutputFile *string
+	corimExportFormat     *string
+)
+
+var corimExportCmd = NewCorimExportCmd()
+
+func NewCorimExportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "export the reference values of a signed CoRIM as a software inventory",
+		Long: `export the reference values of a signed CoRIM as a software inventory
+
+	Save to bom.json a CycloneDX (version 1.5) BOM listing, as firmware
+	components with their hashes, the measurements that carry digests in the
+	reference-values triples of the CoMIDs of signed-corim.cbor.  A component
+	is named after the label of its psa.refval-id measurement key (or after the
+	key itself), and its version and supplier are taken from the measurement
+	and its environment.  Measurements without a digest are skipped
+
+	  cocli corim export --format=cyclonedx --file=signed-corim.cbor --output=bom.json
+	`,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkCorimExportArgs(); err != nil {
+				return err
+			}
+
+			bom, skipped, err := corimToCycloneDX(*corimExportCorimFile)
+			if err != nil {
+				return err
+			}
+
+			data, err := json.MarshalIndent(bom, "", "  ")
+			if err != nil {
+				return fmt.Errorf("error encoding CycloneDX BOM: %w", err)
+			}
+
+			if err = afero.WriteFile(fs, *corimExportOutputFile, append(data, '\n'), 0644); err != nil {
+				return fmt.Errorf("error saving CycloneDX BOM to %s: %w", *corimExportOutputFile, err)
+			}
+
+			if skipped != 0 {
+				fmt.Printf(">> %d measurement(s) without a supported digest skipped\n", skipped)
+			}
+
+			fmt.Printf(">> %d component(s) from %q saved to %q\n", len(bom.Components), *corimExportCorimFile, *corimExportOutputFile)
+
+			return nil
+		},
+	}
+
+	corimExportCorimFile = cmd.Flags().StringP("file", "f", "", "a signed CoRIM file (in CBOR format)")
+	corimExportOutputFile = cmd.Flags().StringP("output", "o", "", "name of the exported file")
+	corimExportFormat = cmd.Flags().String("format", exportFormatCycloneDX, "format of the exported file: cyclonedx (JSON)")
+
+	return cmd
+}
+
+func checkCorimExportArgs() error {
+	if corimExportCorimFile == nil || *corimExportCorimFile == "" {
+		return errors.New("no CoRIM supplied")
+	}
+
+	if corimExportOutputFile == nil || *corimExportOutputFile == "" {
+		return errors.New("no output file supplied")
+	}
+
+	if corimExportFormat != nil && *corimExportFormat != exportFormatCycloneDX {
+		return fmt.Errorf("invalid --format %q: expecting cyclonedx", *corimExportFormat)
+	}
+
+	return nil
+}
+
+// cycloneDXBOM is a CycloneDX BOM, restricted to the fields used by cocli
+type cycloneDXBOM struct {
+	BOMFormat    string               `json:"bomFormat"`
+	SpecVersion  string               `json:"specVersion"`
+	SerialNumber string               `json:"serialNumber"`
+	Version      int                  `json:"version"`
+	Metadata     cycloneDXMetadata    `json:"metadata"`
+	Components   []cycloneDXComponent `json:"components"`
+}
+
+type cycloneDXMetadata struct {
+	Tools      cycloneDXTools      `json:"tools"`
+	Properties []cycloneDXProperty `json:"properties,omitempty"`
+}
+
+type cycloneDXTools struct {
+	Components []cycloneDXComponent `json:"components"`
+}
+
+type cycloneDXComponent struct {
+	Type       string              `json:"type"`
+	BOMRef     string              `json:"bom-ref,omitempty"`
+	Supplier   *cycloneDXEntity    `json:"supplier,omitempty"`
+	Name       string              `json:"name"`
+	Version    string              `json:"version,omitempty"`
+	Hashes     []cycloneDXHash     `json:"hashes,omitempty"`
+	Properties []cycloneDXProperty `json:"properties,omitempty"`
+}
+
+type cycloneDXEntity struct {
+	Name string `json:"name"`
+}
+
+type cycloneDXHash struct {
+	Alg     string `json:"alg"`
+	Content string `json:"content"`
+}
+
+type cycloneDXProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// cycloneDXProperties returns the CycloneDX properties for the (name, value)
+// pairs in kvs whose value is not empty
+func cycloneDXProperties(kvs ...string) []cycloneDXProperty {
+	var props []cycloneDXProperty
+
+	for i := 0; i+1 < len(kvs); i += 2 {
+		if kvs[i+1] != "" {
+			props = append(props, cycloneDXProperty{Name: cycloneDXPropertyPrefix + kvs[i], Value: kvs[i+1]})
+		}
+	}
+
+	return props
+}
+
+// corimToCycloneDX returns the CycloneDX BOM of the reference values of the
+// signed CoRIM in file, together with the number of measurements skipped
+// because they carry no digest that CycloneDX can represent.  The serial
+// number of the BOM is derived from the fingerprint of the CoRIM (see "corim
+// display --hash"), so that exporting the same CoRIM again, even re-signed,
+// yields the same BOM.
+func corimToCycloneDX(file string) (*cycloneDXBOM, int, error) {
+	data, err := afero.ReadFile(fs, file)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error loading signed CoRIM from %s: %w", file, err)
+	}
+
+	s, err := corim.UnmarshalSignedCorimFromCBOR(tagSign1(data))
+	if err != nil {
+		return nil, 0, fmt.Errorf("error decoding signed CoRIM from %s: %w", file, err)
+	}
+
+	fingerprint, err := corimFingerprint(data, crypto.SHA256, false)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error hashing signed CoRIM from %s: %w", file, err)
+	}
+
+	var profile string
+	if s.UnsignedCorim.Profile != nil {
+		// an unreadable profile would have failed decoding already
+		profile, _ = s.UnsignedCorim.Profile.Get()
+	}
+
+	ni := "ni:///sha-256;" + base64.RawURLEncoding.EncodeToString(fingerprint)
+
+	bom := &cycloneDXBOM{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  cycloneDXSpecVersion,
+		SerialNumber: "urn:uuid:" + uuid.NewSHA1(uuid.NameSpaceURL, []byte(ni)).String(),
+		Version:      1,
+		Metadata: cycloneDXMetadata{
+			Tools: cycloneDXTools{
+				Components: []cycloneDXComponent{{Type: "application", Name: "cocli"}},
+			},
+			Properties: cycloneDXProperties(
+				"corim:id", s.UnsignedCorim.ID.String(),
+				"corim:profile", profile,
+				"corim:fingerprint", hex.EncodeToString(fingerprint),
+			),
+		},
+		Components: []cycloneDXComponent{},
+	}
+
+	skipped := 0
+
+	for i, e := range s.UnsignedCorim.Tags {
+		// need at least 3 bytes for the tag and 1 for the smallest bstr
+		if len(e) < 3+1 || !bytes.Equal(e[:3], corim.ComidTag) {
+			continue
+		}
+
+		var c comid.Comid
+
+		if err = c.FromCBOR(e[3:]); err != nil {
+			fmt.Printf(">> skipping malformed CoMID tag at index %d: %v\n", i, err)
+			continue
+		}
+
+		components, n, err := comidToCycloneDX(&c, i)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error exporting CoMID tag at index %d: %w", i, err)
+		}
+
+		bom.Components = append(bom.Components, components...)
+		skipped += n
+	}
+
+	return bom, skipped, nil
+}
+
+// comidToCycloneDX returns a firmware component for each measurement carrying
+// a digest in the reference-values triples of c, the CoMID at index tagIndex
+// of its CoRIM, together with the number of measurements skipped
+func comidToCycloneDX(c *comid.Comid, tagIndex int) ([]cycloneDXComponent, int, error) {
+	var (
+		components []cycloneDXComponent
+		skipped    int
+	)
+
+	rvs := c.Triples.ReferenceValues
+	if rvs == nil {
+		return nil, 0, nil
+	}
+
+	for i, vt := range rvs.Values {
+		env := newMeasurementRow(tagIndex, c.TagIdentity.TagID.String(), "reference-values", vt.Environment)
+
+		for j := range vt.Measurements.Values {
+			m := &vt.Measurements.Values[j]
+			ref := fmt.Sprintf("tag-%d/reference-values-%d/measurement-%d", tagIndex, i, j)
+
+			component, err := measurementToCycloneDX(env, m, ref)
+			if err != nil {
+				return nil, 0, fmt.Errorf("reference-values[%d] measurement %d: %w", i, j, err)
+			}
+
+			if component == nil {
+				skipped++
+				continue
+			}
+
+			components = append(components, *component)
+		}
+	}
+
+	return components, skipped, nil
+}
+
+// measurementToCycloneDX returns the component, identified by ref, for the
+// measurement m of the environment described by env, or nil if m carries no
+// digest that CycloneDX can represent
+func measurementToCycloneDX(env measurementRow, m *comid.Measurement, ref string) (*cycloneDXComponent, error) {
+	var hashes []cycloneDXHash
+
+	if m.Val.Digests != nil {
+		for _, d := range *m.Val.Digests {
+			alg, ok := cycloneDXHashAlgs[d.AlgIDToString()]
+			if !ok {
+				fmt.Printf(">> warning: %s: skipping %s digest, which CycloneDX cannot represent\n", ref, d.AlgIDToString())
+				continue
+			}
+
+			hashes = append(hashes, cycloneDXHash{Alg: alg, Content: hex.EncodeToString(d.HashValue)})
+		}
+	}
+
+	if len(hashes) == 0 {
+		return nil, nil
+	}
+
+	keyType, key, err := measurementKey(m)
+	if err != nil {
+		return nil, err
+	}
+
+	component := &cycloneDXComponent{
+		Type:   "firmware",
+		BOMRef: ref,
+		Name:   key,
+		Hashes: hashes,
+	}
+
+	var signerID string
+
+	if keyType == comid.PSARefValIDType {
+		refValID, err := m.Key.GetPSARefValID()
+		if err != nil {
+			return nil, fmt.Errorf("error decoding %s measurement key: %w", keyType, err)
+		}
+
+		// the key is a map: only its label makes a name
+		component.Name = ""
+		if refValID.Label != nil {
+			component.Name = *refValID.Label
+		}
+
+		if refValID.Version != nil {
+			component.Version = *refValID.Version
+		}
+
+		signerID = hex.EncodeToString(refValID.SignerID)
+	}
+
+	if component.Version == "" && m.Val.Ver != nil {
+		component.Version = m.Val.Ver.Version
+	}
+
+	if component.Name == "" {
+		component.Name = env.Model
+	}
+
+	if component.Name == "" {
+		component.Name = ref
+	}
+
+	if env.Vendor != "" {
+		component.Supplier = &cycloneDXEntity{Name: env.Vendor}
+	}
+
+	component.Properties = cycloneDXProperties(
+		"comid:tag-id", env.TagID,
+		"comid:class-id-type", env.ClassIDType,
+		"comid:class-id", env.ClassID,
+		"comid:model", env.Model,
+		"comid:instance", env.Instance,
+		"comid:measurement-key-type", keyType,
+		"comid:measurement-key", key,
+		"psa:signer-id", signerID,
+	)
+
+	return component, nil
+}
+
+func init() {
+	corimCmd.AddCommand(corimExportCmd)
+}
@@ -0,0 +1,126 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/comid"
+)
+
+func readTestBOM(t *testing.T, file string) cycloneDXBOM {
+	var bom cycloneDXBOM
+	require.NoError(t, json.Unmarshal(mustReadFile(t, file), &bom))
+
+	return bom
+}
+
+func Test_CorimExportCmd_cyclonedx(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "signed.cbor", newTestSignedCorimWithComids(t, 2), 0644))
+
+	cmd := NewCorimExportCmd()
+	cmd.SetArgs([]string{"--format=cyclonedx", "--file=signed.cbor", "--output=bom.json"})
+	require.NoError(t, cmd.Execute())
+
+	bom := readTestBOM(t, "bom.json")
+	assert.Equal(t, "CycloneDX", bom.BOMFormat)
+	assert.Equal(t, cycloneDXSpecVersion, bom.SpecVersion)
+	assert.Regexp(t, `^urn:uuid:[0-9a-f-]{36}$`, bom.SerialNumber)
+
+	// one component for each of the 3 measurements of each CoMID
+	require.Len(t, bom.Components, 2*3)
+
+	c := bom.Components[0]
+	assert.Equal(t, "firmware", c.Type)
+	assert.Equal(t, "tag-0/reference-values-0/measurement-0", c.BOMRef)
+	assert.Equal(t, "BL", c.Name)
+	assert.Equal(t, "2.1.0", c.Version)
+	assert.Equal(t, &cycloneDXEntity{Name: "ACME"}, c.Supplier)
+	assert.Equal(t, []cycloneDXHash{
+		{Alg: "SHA-256", Content: "87428fc522803d31065e7bce3cf03fe475096631e5e07bbd7a0fde60c4cf25c7"},
+	}, c.Hashes)
+	assert.Contains(t, c.Properties, cycloneDXProperty{Name: "veraison:comid:model", Value: "RoadRunner"})
+	assert.Contains(t, c.Properties, cycloneDXProperty{Name: "veraison:comid:class-id-type", Value: comid.ImplIDType})
+	assert.Contains(t, c.Properties, cycloneDXProperty{
+		Name: "veraison:psa:signer-id", Value: "acbb11c7e4da217205523ce4ce1a245ae1a239ae3c6bfd9e7871f7e5d8bae86b",
+	})
+
+	assert.Equal(t, "tag-1/reference-values-0/measurement-2", bom.Components[5].BOMRef)
+
+	// the BOM only depends on the CoRIM
+	first := mustReadFile(t, "bom.json")
+	require.NoError(t, cmd.Execute())
+	assert.Equal(t, first, mustReadFile(t, "bom.json"))
+}
+
+func Test_measurementToCycloneDX(t *testing.T) {
+	var m comid.Measurement
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"key": { "type": "uint", "value": 3 },
+		"value": {
+			"version": { "value": "1.0.2", "scheme": "semver" },
+			"digests": [
+				"sha-256-32:AAAAAA==",
+				"sha-384:AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"
+			]
+		}
+	}`), &m))
+
+	env := newMeasurementRow(0, "acme-fw", "reference-values", comid.Environment{})
+
+	c, err := measurementToCycloneDX(env, &m, "ref")
+	require.NoError(t, err)
+	require.NotNil(t, c)
+
+	assert.Equal(t, "3", c.Name)
+	assert.Equal(t, "1.0.2", c.Version)
+	assert.Nil(t, c.Supplier)
+	// truncated digests cannot be represented
+	require.Len(t, c.Hashes, 1)
+	assert.Equal(t, "SHA-384", c.Hashes[0].Alg)
+
+	m.Val.Digests = nil
+
+	c, err = measurementToCycloneDX(env, &m, "ref")
+	require.NoError(t, err)
+	assert.Nil(t, c)
+}
+
+func Test_CorimExportCmd_bad_args(t *testing.T) {
+	tvs := []struct {
+		desc     string
+		args     []string
+		expected string
+	}{
+		{
+			desc:     "no file",
+			args:     []string{"--output=bom.json"},
+			expected: "no CoRIM supplied",
+		},
+		{
+			desc:     "no output",
+			args:     []string{"--file=signed.cbor"},
+			expected: "no output file supplied",
+		},
+		{
+			desc:     "unknown format",
+			args:     []string{"--file=signed.cbor", "--output=bom.json", "--format=spdx"},
+			expected: `invalid --format "spdx": expecting cyclonedx`,
+		},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.desc, func(t *testing.T) {
+			cmd := NewCorimExportCmd()
+			cmd.SetArgs(tv.args)
+
+			assert.EqualError(t, cmd.Execute(), tv.expected)
+		})
+	}
+}
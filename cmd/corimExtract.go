@@ -5,21 +5,40 @@ package cmd
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
+	"github.com/veraison/corim/comid"
 	"github.com/veraison/corim/corim"
 	"github.com/veraison/corim/cots"
 )
 
 var (
-	corimExtractCorimFile *string
-	corimExtractOutputDir *string
+	corimExtractCorimFile  *string
+	corimExtractOutputDir  *string
+	corimExtractJSONArray  *bool
+	corimExtractOutputFile *string
+	corimExtractDirMode    *string
+	corimExtractComidCount *int
+	corimExtractCotsCount  *int
+	corimExtractOffset     *int
+	corimExtractLimit      *int
+	corimExtractTagType    *string
+	corimExtractFlatten    *bool
+	corimExtractFormat     *string
 )
 
+// tagCounts holds the number of CoMIDs and CoTSs found in a CoRIM
+type tagCounts struct {
+	comids int
+	cots   int
+}
+
 var corimExtractCmd = NewCorimExtractCmd()
 
 func NewCorimExtractCmd() *cobra.Command {
@@ -34,10 +53,44 @@ func NewCorimExtractCmd() *cobra.Command {
 	  cocli corim extract --file=signed-corim.cbor
 
 	Extract the contents of the signed CoRIM yet-another-signed-corim.cbor and
-	store them to directory my-dir.  Note that my-dir must exist.
+	store them to directory my-dir, which is created if it does not exist.
 	
 	  cocli corim extract --file=yet-another-signed-corim.cbor \
 	    				--output-dir=my-dir
+
+	Decode the CoMIDs found in the signed CoRIM signed-corim.cbor and save them
+	as a single JSON array to comids.json.  Any other tag is skipped.
+
+	  cocli corim extract --file=signed-corim.cbor \
+	    				--json-array \
+	    				--output=comids.json
+
+	Extract the contents of the signed CoRIM signed-corim.cbor, and fail unless
+	it contains exactly 3 CoMIDs and no CoTS
+
+	  cocli corim extract --file=signed-corim.cbor \
+	    				--expect-comid-count=3 \
+	    				--expect-cots-count=0
+
+	Extract the 20 tags starting from the one at index 100 (i.e., tags 100 to
+	119) of the signed CoRIM signed-corim.cbor
+
+	  cocli corim extract --file=signed-corim.cbor --offset=100 --limit=20
+
+	Only extract the CoTSs of the signed CoRIM signed-corim.cbor, after
+	reporting the number of tags of each type
+
+	  cocli corim extract --file=signed-corim.cbor --tag-type=cots
+
+	Save every measurement of the CoMIDs of the signed CoRIM signed-corim.cbor
+	as a flat CSV dataset, one row per digest or other measured value, with the
+	tag index, environment, measurement key, value type, algorithm and value
+	(use --format=jsonl for JSON Lines)
+
+	  cocli corim extract --file=signed-corim.cbor \
+	    				--flatten-measurements \
+	    				--format=csv \
+	    				--output=measurements.csv
 	`,
 
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -45,12 +98,61 @@ func NewCorimExtractCmd() *cobra.Command {
 				return err
 			}
 
-			return extract(*corimExtractCorimFile, corimExtractOutputDir)
+			var (
+				counts tagCounts
+				err    error
+			)
+
+			// checkCorimExtractArgs makes sure the page and tag type are valid
+			page, _ := newTagPage(corimExtractOffset, corimExtractLimit)
+			page.tagType, _ = newTagType(corimExtractTagType)
+
+			// the expected counts are about the whole CoRIM, not a page of its
+			// tags
+			expectCounts := cmd.Flags().Changed("expect-comid-count") || cmd.Flags().Changed("expect-cots-count")
+			if page.isSet() && expectCounts {
+				return errors.New("--expect-comid-count and --expect-cots-count cannot be used together with --offset or --limit")
+			}
+
+			if *corimExtractFlatten {
+				counts, err = extractFlattenedMeasurements(*corimExtractCorimFile, *corimExtractOutputFile, *corimExtractFormat, page)
+			} else if *corimExtractJSONArray {
+				counts, err = extractJSONArray(*corimExtractCorimFile, *corimExtractOutputFile, page)
+			} else {
+				if err = prepareOutputDir(*corimExtractOutputDir, *corimExtractDirMode); err != nil {
+					return err
+				}
+
+				counts, err = extract(*corimExtractCorimFile, corimExtractOutputDir, page)
+			}
+
+			if err != nil {
+				return err
+			}
+
+			var expectedComids, expectedCots *int
+			if cmd.Flags().Changed("expect-comid-count") {
+				expectedComids = corimExtractComidCount
+			}
+			if cmd.Flags().Changed("expect-cots-count") {
+				expectedCots = corimExtractCotsCount
+			}
+
+			return checkTagCounts(counts, expectedComids, expectedCots)
 		},
 	}
 
 	corimExtractCorimFile = cmd.Flags().StringP("file", "f", "", "a signed CoRIM file (in CBOR format)")
 	corimExtractOutputDir = cmd.Flags().StringP("output-dir", "o", ".", "folder to which CoSWIDs, CoMIDs, CoTSs are saved")
+	corimExtractDirMode = cmd.Flags().String("dir-mode", defaultDirMode, "permissions of the output directory, if it needs to be created")
+	corimExtractJSONArray = cmd.Flags().Bool("json-array", false, "save the decoded CoMIDs as a single JSON array")
+	corimExtractOutputFile = cmd.Flags().String("output", "", "name of the JSON file (with --json-array) or of the dataset (with --flatten-measurements)")
+	corimExtractFlatten = cmd.Flags().Bool("flatten-measurements", false, "save the measurements of the CoMIDs as a flat dataset, one row per measured value")
+	corimExtractFormat = cmd.Flags().String("format", flattenFormatCSV, "format of the --flatten-measurements dataset: csv or jsonl")
+	corimExtractComidCount = cmd.Flags().Int("expect-comid-count", 0, "fail unless the CoRIM contains exactly this many CoMIDs")
+	corimExtractCotsCount = cmd.Flags().Int("expect-cots-count", 0, "fail unless the CoRIM contains exactly this many CoTSs")
+	corimExtractOffset, corimExtractLimit = addTagPageFlags(cmd)
+	corimExtractTagType = addTagTypeFlag(cmd)
 
 	return cmd
 }
@@ -60,23 +162,93 @@ func checkCorimExtractArgs() error {
 		return errors.New("no CoRIM supplied")
 	}
 
+	if *corimExtractJSONArray && *corimExtractFlatten {
+		return errors.New("--json-array cannot be used together with --flatten-measurements")
+	}
+
+	if (*corimExtractJSONArray || *corimExtractFlatten) && *corimExtractOutputFile == "" {
+		return errors.New("no output file supplied")
+	}
+
+	if !*corimExtractJSONArray && !*corimExtractFlatten && *corimExtractOutputFile != "" {
+		return errors.New("--output can only be used together with --json-array or --flatten-measurements")
+	}
+
+	switch *corimExtractFormat {
+	case flattenFormatCSV, flattenFormatJSONL:
+	default:
+		return fmt.Errorf("invalid --format %q: expecting csv or jsonl", *corimExtractFormat)
+	}
+
+	if !*corimExtractFlatten && *corimExtractFormat != flattenFormatCSV {
+		return errors.New("--format can only be used together with --flatten-measurements")
+	}
+
+	if *corimExtractComidCount < 0 || *corimExtractCotsCount < 0 {
+		return errors.New("expected tag counts cannot be negative")
+	}
+
+	if _, err := newTagPage(corimExtractOffset, corimExtractLimit); err != nil {
+		return err
+	}
+
+	tt, err := newTagType(corimExtractTagType)
+	if err != nil {
+		return err
+	}
+
+	if *corimExtractJSONArray && tt != "" && tt != tagTypeComid {
+		return fmt.Errorf("--json-array only saves CoMIDs, and cannot be used together with --tag-type=%s", tt)
+	}
+
+	if *corimExtractFlatten && tt != "" && tt != tagTypeComid {
+		return fmt.Errorf("--flatten-measurements only reads CoMIDs, and cannot be used together with --tag-type=%s", tt)
+	}
+
+	return nil
+}
+
+// checkTagCounts compares the counts of the extracted tags against the
+// expected ones, if supplied, and reports all the mismatches found
+func checkTagCounts(counts tagCounts, expectedComids, expectedCots *int) error {
+	var mismatches []string
+
+	if expectedComids != nil {
+		fmt.Printf(">> CoMID count: %d (expected %d)\n", counts.comids, *expectedComids)
+		if counts.comids != *expectedComids {
+			mismatches = append(mismatches, fmt.Sprintf("expected %d CoMID(s), got %d", *expectedComids, counts.comids))
+		}
+	}
+
+	if expectedCots != nil {
+		fmt.Printf(">> CoTS count: %d (expected %d)\n", counts.cots, *expectedCots)
+		if counts.cots != *expectedCots {
+			mismatches = append(mismatches, fmt.Sprintf("expected %d CoTS(s), got %d", *expectedCots, counts.cots))
+		}
+	}
+
+	if len(mismatches) != 0 {
+		return fmt.Errorf("tag count mismatch: %s", strings.Join(mismatches, "; "))
+	}
+
 	return nil
 }
 
-func extract(signedCorimFile string, outputDir *string) error {
+func extract(signedCorimFile string, outputDir *string, page tagPage) (tagCounts, error) {
 	var (
 		signedCorimCBOR []byte
 		err             error
 		s               corim.SignedCorim
 		baseDir         string
+		counts          tagCounts
 	)
 
 	if signedCorimCBOR, err = afero.ReadFile(fs, signedCorimFile); err != nil {
-		return fmt.Errorf("error loading signed CoRIM from %s: %w", signedCorimFile, err)
+		return counts, fmt.Errorf("error loading signed CoRIM from %s: %w", signedCorimFile, err)
 	}
 
 	if err = s.FromCOSE(signedCorimCBOR); err != nil {
-		return fmt.Errorf("error decoding signed CoRIM from %s: %w", signedCorimFile, err)
+		return counts, fmt.Errorf("error decoding signed CoRIM from %s: %w", signedCorimFile, err)
 	}
 
 	baseDir = "."
@@ -84,11 +256,19 @@ func extract(signedCorimFile string, outputDir *string) error {
 		baseDir = *outputDir
 	}
 
-	for i, e := range s.UnsignedCorim.Tags {
+	tags := s.UnsignedCorim.Tags
+	page.report(len(tags))
+	page.tagType.report(tags)
+
+	for i, e := range tags {
 		var (
 			outputFile string
 		)
 
+		if !page.contains(i, len(tags)) {
+			continue
+		}
+
 		// need at least 3 bytes for the tag and 1 for the smallest bstr
 		if len(e) < 3+1 {
 			fmt.Printf(">> skipping malformed tag at index %d\n", i)
@@ -98,6 +278,18 @@ func extract(signedCorimFile string, outputDir *string) error {
 		// split tag from data
 		cborTag, cborData := e[:3], e[3:]
 
+		// the counts are about all the tags, whatever --tag-type
+		switch {
+		case bytes.Equal(cborTag, corim.ComidTag):
+			counts.comids++
+		case bytes.Equal(cborTag, cots.CotsTag):
+			counts.cots++
+		}
+
+		if !page.tagType.contains(cborTag) {
+			continue
+		}
+
 		switch {
 		case bytes.Equal(cborTag, corim.ComidTag):
 			outputFile = filepath.Join(baseDir, fmt.Sprintf("%06d-comid.cbor", i))
@@ -122,7 +314,79 @@ func extract(signedCorimFile string, outputDir *string) error {
 		}
 	}
 
-	return nil
+	return counts, nil
+}
+
+func extractJSONArray(signedCorimFile, outputFile string, page tagPage) (tagCounts, error) {
+	var (
+		signedCorimCBOR []byte
+		err             error
+		s               corim.SignedCorim
+		comids          []comid.Comid
+		data            []byte
+		counts          tagCounts
+	)
+
+	if signedCorimCBOR, err = afero.ReadFile(fs, signedCorimFile); err != nil {
+		return counts, fmt.Errorf("error loading signed CoRIM from %s: %w", signedCorimFile, err)
+	}
+
+	if err = s.FromCOSE(signedCorimCBOR); err != nil {
+		return counts, fmt.Errorf("error decoding signed CoRIM from %s: %w", signedCorimFile, err)
+	}
+
+	comids = []comid.Comid{}
+
+	tags := s.UnsignedCorim.Tags
+	page.report(len(tags))
+	page.tagType.report(tags)
+
+	for i, e := range tags {
+		if !page.contains(i, len(tags)) {
+			continue
+		}
+
+		// need at least 3 bytes for the tag and 1 for the smallest bstr
+		if len(e) < 3+1 {
+			fmt.Printf(">> skipping malformed tag at index %d\n", i)
+			continue
+		}
+
+		// split tag from data
+		cborTag, cborData := e[:3], e[3:]
+
+		if bytes.Equal(cborTag, cots.CotsTag) {
+			counts.cots++
+		}
+
+		if !bytes.Equal(cborTag, corim.ComidTag) {
+			fmt.Printf(">> skipping non-CoMID tag at index %d\n", i)
+			continue
+		}
+
+		var c comid.Comid
+
+		if err = c.FromCBOR(cborData); err != nil {
+			fmt.Printf(">> skipping malformed CoMID tag at index %d: %v\n", i, err)
+			continue
+		}
+
+		comids = append(comids, c)
+	}
+
+	if data, err = json.MarshalIndent(comids, "", "  "); err != nil {
+		return counts, fmt.Errorf("error encoding CoMIDs to JSON: %w", err)
+	}
+
+	if err = afero.WriteFile(fs, outputFile, data, 0644); err != nil {
+		return counts, fmt.Errorf("error saving CoMIDs to file %s: %w", outputFile, err)
+	}
+
+	fmt.Printf(">> %d CoMID(s) saved to %q\n", len(comids), outputFile)
+
+	counts.comids = len(comids)
+
+	return counts, nil
 }
 
 func init() {
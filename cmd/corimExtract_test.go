@@ -4,11 +4,14 @@
 package cmd
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/corim/corim"
 )
 
 func Test_CorimExtractCmd_unknown_argument(t *testing.T) {
@@ -138,3 +141,160 @@ func Test_CorimExtractCmd_with_cots_ok_save_to_default_dir(t *testing.T) {
 	assert.NoError(t, err)
 
 }
+
+func Test_CorimExtractCmd_json_array_missing_output_file(t *testing.T) {
+	cmd := NewCorimExtractCmd()
+
+	args := []string{
+		"--file=ok.cbor",
+		"--json-array",
+	}
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "no output file supplied")
+}
+
+func Test_CorimExtractCmd_output_file_without_json_array(t *testing.T) {
+	cmd := NewCorimExtractCmd()
+
+	args := []string{
+		"--file=ok.cbor",
+		"--output=comids.json",
+	}
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "--output can only be used together with --json-array or --flatten-measurements")
+}
+
+func newTestSignedCorimWithComids(t *testing.T, n int) []byte {
+	var c comid.Comid
+	require.NoError(t, c.FromJSON([]byte(comid.PSARefValJSONTemplate)))
+
+	u := corim.NewUnsignedCorim().SetID("test")
+	for i := 0; i < n; i++ {
+		require.NotNil(t, u.AddComid(&c))
+	}
+
+	signer, err := corim.NewSignerFromJWK(testECKey)
+	require.NoError(t, err)
+
+	s := corim.SignedCorim{UnsignedCorim: *u}
+	s.Meta.SetSigner("ACME Ltd signing key", nil)
+
+	data, err := s.Sign(signer)
+	require.NoError(t, err)
+
+	return data
+}
+
+func Test_CorimExtractCmd_json_array_ok(t *testing.T) {
+	cmd := NewCorimExtractCmd()
+
+	args := []string{
+		"--file=ok.cbor",
+		"--json-array",
+		"--output=comids.json",
+	}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "ok.cbor", newTestSignedCorimWithComids(t, 2), 0644)
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	assert.NoError(t, err)
+
+	data, err := afero.ReadFile(fs, "comids.json")
+	require.NoError(t, err)
+
+	var comids []comid.Comid
+	err = json.Unmarshal(data, &comids)
+	require.NoError(t, err)
+	assert.Len(t, comids, 2)
+}
+
+func Test_CorimExtractCmd_json_array_with_cots_ok(t *testing.T) {
+	cmd := NewCorimExtractCmd()
+
+	args := []string{
+		"--file=ok.cbor",
+		"--json-array",
+		"--output=comids.json",
+	}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "ok.cbor", testSignedCorimValidWithCots, 0644)
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	assert.NoError(t, err)
+
+	data, err := afero.ReadFile(fs, "comids.json")
+	require.NoError(t, err)
+	assert.JSONEq(t, "[]", string(data))
+}
+
+func Test_CorimExtractCmd_expected_counts(t *testing.T) {
+	tvs := []struct {
+		desc     string
+		args     []string
+		expected string
+	}{
+		{
+			desc: "match",
+			args: []string{"--expect-comid-count=2", "--expect-cots-count=0"},
+		},
+		{
+			desc: "match with json array",
+			args: []string{"--expect-comid-count=2", "--json-array", "--output=comids.json"},
+		},
+		{
+			desc:     "comid mismatch",
+			args:     []string{"--expect-comid-count=3"},
+			expected: "tag count mismatch: expected 3 CoMID(s), got 2",
+		},
+		{
+			desc:     "comid and cots mismatch",
+			args:     []string{"--expect-comid-count=1", "--expect-cots-count=1", "--json-array", "--output=comids.json"},
+			expected: "tag count mismatch: expected 1 CoMID(s), got 2; expected 1 CoTS(s), got 0",
+		},
+		{
+			desc:     "negative",
+			args:     []string{"--expect-cots-count=-1"},
+			expected: "expected tag counts cannot be negative",
+		},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.desc, func(t *testing.T) {
+			fs = afero.NewMemMapFs()
+			require.NoError(t, afero.WriteFile(fs, "ok.cbor", newTestSignedCorimWithComids(t, 2), 0644))
+
+			cmd := NewCorimExtractCmd()
+			cmd.SetArgs(append([]string{"--file=ok.cbor"}, tv.args...))
+
+			err := cmd.Execute()
+			if tv.expected == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tv.expected)
+			}
+		})
+	}
+}
+
+func Test_CorimExtractCmd_expected_cots_count(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "ok.cbor", testSignedCorimValidWithCots, 0644))
+
+	cmd := NewCorimExtractCmd()
+	cmd.SetArgs([]string{
+		"--file=ok.cbor",
+		"--expect-comid-count=0",
+		"--expect-cots-count=1",
+	})
+	assert.NoError(t, cmd.Execute())
+}
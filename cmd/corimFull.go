@@ -0,0 +1,226 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/afero"
+	"github.com/veraison/corim/corim"
+	"github.com/veraison/corim/cots"
+	"github.com/veraison/swid"
+)
+
+// the members of a full CoRIM document (see corimFullToCBOR) that are not part
+// of the CoRIM template
+const (
+	fullComidsMember  = "comids"
+	fullCoswidsMember = "coswids"
+	fullCotsMember    = "cots"
+	fullMetaMember    = "meta"
+)
+
+// corimFullToCBOR creates the unsigned CoRIM described by the single JSON
+// document in fullFile: a CoRIM template (corim-id, profile, entities, etc.)
+// with the tags inlined in its "comids", "coswids" and "cots" arrays, and an
+// optional CoRIM Meta in its "meta" member.  As the CoRIM Meta is only used
+// when signing, it is saved to metaOutputFile, if set, for "corim sign --meta".
+// It returns the name of the file the unsigned CoRIM is saved to.
+func corimFullToCBOR(fullFile string, outputFile *string, metaOutputFile string, strict bool, limits jsonLimits, env envExpansion) (string, error) {
+	data, err := readJSONTemplate(fullFile, limits, env)
+	if err != nil {
+		return "", fmt.Errorf("error loading CoRIM from %s: %w", fullFile, err)
+	}
+
+	var doc map[string]json.RawMessage
+	if err = json.Unmarshal(data, &doc); err != nil {
+		return "", fmt.Errorf("error decoding CoRIM from %s: expecting a JSON object: %w", fullFile, err)
+	}
+
+	var comids, coswids, cotss []json.RawMessage
+
+	for member, values := range map[string]*[]json.RawMessage{
+		fullComidsMember:  &comids,
+		fullCoswidsMember: &coswids,
+		fullCotsMember:    &cotss,
+	} {
+		raw, ok := doc[member]
+		if !ok {
+			continue
+		}
+
+		if err = json.Unmarshal(raw, values); err != nil {
+			return "", fmt.Errorf("error decoding CoRIM from %s: %q: expecting a JSON array: %w", fullFile, member, err)
+		}
+
+		delete(doc, member)
+	}
+
+	if len(comids)+len(coswids)+len(cotss) == 0 {
+		return "", fmt.Errorf("error decoding CoRIM from %s: no CoMID, CoSWID or CoTS found", fullFile)
+	}
+
+	meta, hasMeta := doc[fullMetaMember]
+	delete(doc, fullMetaMember)
+
+	tmplData, err := json.Marshal(doc)
+	if err != nil {
+		return "", fmt.Errorf("error decoding CoRIM from %s: %w", fullFile, err)
+	}
+
+	// register the extensions of the profile declared by the document, if any
+	c := corim.GetUnsignedCorim(jsonProfile(tmplData))
+
+	if err = decodeJSON(c, tmplData, strict); err != nil {
+		return "", fmt.Errorf("error decoding CoRIM from %s: %w", fullFile, err)
+	}
+
+	for i, raw := range comids {
+		m := newComid(c.Profile)
+
+		if err = decodeJSON(m, raw, strict); err != nil {
+			return "", fmt.Errorf("error decoding CoMID at index %d in %s: %w", i, fullFile, err)
+		}
+
+		if c.AddComid(m) == nil {
+			return "", fmt.Errorf(
+				"error adding CoMID at index %d in %s (check its validity using the %q sub-command)",
+				i, fullFile, "comid validate",
+			)
+		}
+	}
+
+	for i, raw := range coswids {
+		var s swid.SoftwareIdentity
+
+		if err = decodeJSON(&s, raw, strict); err != nil {
+			return "", fmt.Errorf("error decoding CoSWID at index %d in %s: %w", i, fullFile, err)
+		}
+
+		if c.AddCoswid(&s) == nil {
+			return "", fmt.Errorf("error adding CoSWID at index %d in %s", i, fullFile)
+		}
+	}
+
+	for i, raw := range cotss {
+		var t cots.ConciseTaStore
+
+		if err = decodeJSON(&t, raw, strict); err != nil {
+			return "", fmt.Errorf("error decoding CoTS at index %d in %s: %w", i, fullFile, err)
+		}
+
+		if c.AddCots(&t) == nil {
+			return "", fmt.Errorf("error adding CoTS at index %d in %s", i, fullFile)
+		}
+	}
+
+	if hasMeta {
+		if err = saveFullMeta(meta, fullFile, metaOutputFile, strict); err != nil {
+			return "", err
+		}
+	} else if metaOutputFile != "" {
+		return "", fmt.Errorf("error decoding CoRIM from %s: no %q found for --meta-output", fullFile, fullMetaMember)
+	}
+
+	return saveUnsignedCorim(c, fullFile, outputFile)
+}
+
+// saveFullMeta checks the CoRIM Meta of the full CoRIM document in fullFile
+// and saves it to metaOutputFile or, if that is not set, warns that it is not
+// used
+func saveFullMeta(meta json.RawMessage, fullFile, metaOutputFile string, strict bool) error {
+	var m corim.Meta
+
+	if err := decodeJSON(&m, meta, strict); err != nil {
+		return fmt.Errorf("error decoding CoRIM Meta in %s: %w", fullFile, err)
+	}
+
+	if err := m.Valid(); err != nil {
+		return fmt.Errorf("error validating CoRIM Meta in %s: %w", fullFile, err)
+	}
+
+	if metaOutputFile == "" {
+		fmt.Printf(">> warning: ignoring the CoRIM Meta in %s, use --meta-output to save it for signing\n", fullFile)
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, meta, "", "  "); err != nil {
+		return fmt.Errorf("error encoding CoRIM Meta in %s: %w", fullFile, err)
+	}
+	buf.WriteByte('\n')
+
+	if err := afero.WriteFile(fs, metaOutputFile, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("error saving CoRIM Meta to file %s: %w", metaOutputFile, err)
+	}
+
+	fmt.Printf(">> CoRIM Meta saved to %q\n", metaOutputFile)
+
+	return nil
+}
+
+// fullCorimJSON is the reverse of corimFullToCBOR: it renders the unsigned
+// CoRIM u as a full CoRIM document, with its CoMID, CoSWID and CoTS tags
+// decoded and inlined, and with meta, if not nil, in the "meta" member.  Tags
+// of unknown type are left out.
+func fullCorimJSON(u *corim.UnsignedCorim, meta *corim.Meta) ([]byte, error) {
+	tmplData, err := json.Marshal(u)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding CoRIM: %w", err)
+	}
+
+	var doc map[string]json.RawMessage
+	if err = json.Unmarshal(tmplData, &doc); err != nil {
+		return nil, fmt.Errorf("error encoding CoRIM: %w", err)
+	}
+
+	delete(doc, "tags")
+
+	members := map[string][]json.RawMessage{}
+
+	for i, t := range u.Tags {
+		var (
+			v      CBORCodec
+			member string
+		)
+
+		switch {
+		case bytes.HasPrefix(t, corim.ComidTag):
+			v, member = newComid(u.Profile), fullComidsMember
+		case bytes.HasPrefix(t, corim.CoswidTag):
+			v, member = &swid.SoftwareIdentity{}, fullCoswidsMember
+		case bytes.HasPrefix(t, cots.CotsTag):
+			v, member = &cots.ConciseTaStore{}, fullCotsMember
+		default:
+			continue
+		}
+
+		if err = v.FromCBOR(t[3:]); err != nil {
+			return nil, fmt.Errorf("error decoding tag at index %d: %w", i, err)
+		}
+
+		j, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding tag at index %d: %w", i, err)
+		}
+
+		members[member] = append(members[member], j)
+	}
+
+	for member, values := range members {
+		if doc[member], err = json.Marshal(values); err != nil {
+			return nil, fmt.Errorf("error encoding CoRIM: %w", err)
+		}
+	}
+
+	if meta != nil {
+		if doc[fullMetaMember], err = json.Marshal(meta); err != nil {
+			return nil, fmt.Errorf("error encoding CoRIM Meta: %w", err)
+		}
+	}
+
+	return json.Marshal(doc)
+}
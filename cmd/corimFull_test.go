@@ -0,0 +1,106 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/corim/corim"
+)
+
+// testCorimFull is a full CoRIM document with two inlined CoMIDs and a CoRIM
+// Meta
+var testCorimFull = []byte(fmt.Sprintf(`{
+	"corim-id": "5c57e8f4-46cd-421b-91c9-08cf93e13cfc",
+	"entities": [
+		{
+			"name": "ACME Ltd.",
+			"regid": "acme.example",
+			"roles": [ "manifestCreator" ]
+		}
+	],
+	"comids": [ %s, %s ],
+	"meta": %s
+}`, comid.PSARefValJSONTemplate, comid.PSAKeysJSONTemplate, testMetaValid))
+
+func createCorimFull(t *testing.T, doc []byte, args ...string) error {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "corim-full.json", doc, 0644))
+
+	cmd := NewCorimCreateCmd()
+	cmd.SetArgs(append([]string{"--full=corim-full.json"}, args...))
+
+	return cmd.Execute()
+}
+
+func Test_CorimCreateCmd_full_ok(t *testing.T) {
+	require.NoError(t, createCorimFull(t, testCorimFull, "--output=unsigned.cbor", "--meta-output=meta.json"))
+
+	data, err := afero.ReadFile(fs, "unsigned.cbor")
+	require.NoError(t, err)
+
+	var c corim.UnsignedCorim
+	require.NoError(t, c.FromCBOR(data))
+	assert.Equal(t, "5c57e8f4-46cd-421b-91c9-08cf93e13cfc", c.GetID())
+	assert.Len(t, c.Tags, 2)
+	require.NotNil(t, c.Entities)
+	assert.Len(t, c.Entities.Values, 1)
+
+	meta, err := afero.ReadFile(fs, "meta.json")
+	require.NoError(t, err)
+
+	var m corim.Meta
+	require.NoError(t, m.FromJSON(meta))
+	assert.Equal(t, "ACME Ltd signing key", m.Signer.Name)
+}
+
+func Test_CorimCreateCmd_full_default_output(t *testing.T) {
+	require.NoError(t, createCorimFull(t, testCorimFull))
+
+	_, err := fs.Stat("corim-full.cbor")
+	assert.NoError(t, err)
+}
+
+func Test_CorimCreateCmd_full_no_tags(t *testing.T) {
+	err := createCorimFull(t, []byte(`{"corim-id": "test", "comids": []}`))
+	assert.EqualError(t, err, "error decoding CoRIM from corim-full.json: no CoMID, CoSWID or CoTS found")
+}
+
+func Test_CorimCreateCmd_full_bad_comid(t *testing.T) {
+	doc := fmt.Sprintf(`{"corim-id": "test", "comids": [ %s, {"lang": "en"} ]}`, comid.PSARefValJSONTemplate)
+
+	err := createCorimFull(t, []byte(doc))
+	assert.ErrorContains(t, err, "error decoding CoMID at index 1 in corim-full.json")
+}
+
+func Test_CorimCreateCmd_full_bad_meta(t *testing.T) {
+	doc := fmt.Sprintf(`{"corim-id": "test", "comids": [ %s ], "meta": {}}`, comid.PSARefValJSONTemplate)
+
+	err := createCorimFull(t, []byte(doc), "--meta-output=meta.json")
+	assert.ErrorContains(t, err, "error validating CoRIM Meta in corim-full.json")
+}
+
+func Test_CorimCreateCmd_full_meta_output_without_meta(t *testing.T) {
+	doc := fmt.Sprintf(`{"corim-id": "test", "comids": [ %s ]}`, comid.PSARefValJSONTemplate)
+
+	err := createCorimFull(t, []byte(doc), "--meta-output=meta.json")
+	assert.EqualError(t, err, `error decoding CoRIM from corim-full.json: no "meta" found for --meta-output`)
+}
+
+func Test_CorimCreateCmd_full_with_template(t *testing.T) {
+	err := createCorimFull(t, testCorimFull, "--template=t.json")
+	assert.EqualError(t, err, "--full cannot be used together with --template, --entities or CoMID, CoSWID or CoTS files or folders")
+}
+
+func Test_CorimCreateCmd_meta_output_without_full(t *testing.T) {
+	cmd := NewCorimCreateCmd()
+	cmd.SetArgs([]string{"--template=t.json", "--comid=c.cbor", "--meta-output=meta.json"})
+
+	assert.EqualError(t, cmd.Execute(), "--meta-output can only be used together with --full")
+}
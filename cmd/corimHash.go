@@ -0,0 +1,108 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"crypto"
+	_ "crypto/sha256" // register SHA-256
+	_ "crypto/sha512" // register SHA-384 and SHA-512
+	"fmt"
+	"strings"
+
+	"github.com/spf13/afero"
+	"github.com/veraison/corim/corim"
+)
+
+// defaultCorimHashAlg is the algorithm used by "corim display --hash" unless
+// --hash-alg says otherwise
+const defaultCorimHashAlg = "sha-256"
+
+// corimHashAlgs maps the (IANA Named Information) names accepted by --hash-alg
+// to the corresponding hash functions
+var corimHashAlgs = map[string]crypto.Hash{
+	"sha-256": crypto.SHA256,
+	"sha-384": crypto.SHA384,
+	"sha-512": crypto.SHA512,
+}
+
+func parseCorimHashAlg(s string) (crypto.Hash, error) {
+	h, ok := corimHashAlgs[strings.ToLower(s)]
+	if !ok {
+		return 0, fmt.Errorf("unsupported hash algorithm %q: expecting sha-256, sha-384 or sha-512", s)
+	}
+
+	return h, nil
+}
+
+// corimFingerprint returns the hash, computed with alg, of the deterministic
+// CBOR encoding (see deterministicCBOR) of the unsigned CoRIM in data, which
+// may also be the payload of a signed CoRIM.  As the COSE envelope is not hashed, re-signing a CoRIM
+// does not change its fingerprint.
+func corimFingerprint(data []byte, alg crypto.Hash, strict bool) ([]byte, error) {
+	payload, err := unsignedCorimPayload(data, strict)
+	if err != nil {
+		return nil, err
+	}
+
+	canonical, err := deterministicCBOR(payload)
+	if err != nil {
+		return nil, fmt.Errorf("error canonicalizing unsigned CoRIM: %w", err)
+	}
+
+	h := alg.New()
+	h.Write(canonical)
+
+	return h.Sum(nil), nil
+}
+
+// unsignedCorimPayload returns the unsigned CoRIM in data, extracting it from
+// the COSE Sign1 envelope if data is a signed CoRIM
+func unsignedCorimPayload(data []byte, strict bool) ([]byte, error) {
+	data = tagSign1(data)
+
+	if s, err := corim.UnmarshalSignedCorimFromCBOR(data); err == nil {
+		if strict {
+			if err = checkUnknownSignedCorimFields(s, data); err != nil {
+				return nil, fmt.Errorf("error decoding signed CoRIM: %w", err)
+			}
+		}
+
+		msg, err := decodeSign1(data)
+		if err != nil {
+			return nil, err
+		}
+
+		return msg.Payload, nil
+	}
+
+	u := corim.GetUnsignedCorim(cborProfile(data))
+	if err := decodeCBOR(u, data, strict); err != nil {
+		return nil, fmt.Errorf("error decoding CoRIM (signed or unsigned): %w", err)
+	}
+
+	return data, nil
+}
+
+// printCorimHash prints the fingerprint of the CoRIM in corimFile, prefixed by
+// the name of the hash algorithm, e.g., "sha-256:3f2a..."
+func printCorimHash(corimFile, algName string, strict bool) error {
+	alg, err := parseCorimHashAlg(algName)
+	if err != nil {
+		return err
+	}
+
+	data, err := afero.ReadFile(fs, corimFile)
+	if err != nil {
+		return fmt.Errorf("error loading CoRIM from %s: %w", corimFile, err)
+	}
+
+	sum, err := corimFingerprint(data, alg, strict)
+	if err != nil {
+		return fmt.Errorf("error hashing CoRIM from %s: %w", corimFile, err)
+	}
+
+	fmt.Printf("%s:%x\n", strings.ToLower(algName), sum)
+
+	return nil
+}
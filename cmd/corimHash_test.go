@@ -0,0 +1,141 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"crypto"
+	"crypto/sha512"
+	"fmt"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/corim"
+)
+
+func Test_corimFingerprint_map_order(t *testing.T) {
+	var u corim.UnsignedCorim
+	require.NoError(t, u.FromCBOR(testCorimValid))
+
+	data, err := u.ToCBOR()
+	require.NoError(t, err)
+	require.Equal(t, byte(0xa2), data[0])
+
+	fromDet, err := corimFingerprint(data, crypto.SHA256, false)
+	require.NoError(t, err)
+
+	// the same CoRIM, with the entries of its top-level map in reverse order
+	reordered := append([]byte{0xa2}, reverseCBORMap(t, data[1:])...)
+	require.NotEqual(t, data, reordered)
+
+	fromReordered, err := corimFingerprint(reordered, crypto.SHA256, false)
+	require.NoError(t, err)
+
+	assert.Equal(t, fromDet, fromReordered)
+}
+
+// reverseCBORMap returns the entries of the encoded map body data in reverse
+// order
+func reverseCBORMap(t *testing.T, data []byte) []byte {
+	var entries [][]byte
+
+	for off := 0; off < len(data); {
+		_, n, err := deterministicItem(data[off:])
+		require.NoError(t, err)
+		_, m, err := deterministicItem(data[off+n:])
+		require.NoError(t, err)
+		entries = append([][]byte{data[off : off+n+m]}, entries...)
+		off += n + m
+	}
+
+	var out []byte
+	for _, e := range entries {
+		out = append(out, e...)
+	}
+
+	return out
+}
+
+func Test_corimFingerprint_resigned(t *testing.T) {
+	s, err := corim.UnmarshalSignedCorimFromCBOR(testSignedCorimValid)
+	require.NoError(t, err)
+
+	signer, err := corim.NewSignerFromJWK(testEdDSAKey)
+	require.NoError(t, err)
+
+	resigned, err := s.Sign(signer)
+	require.NoError(t, err)
+
+	unsigned, err := s.UnsignedCorim.ToCBOR()
+	require.NoError(t, err)
+
+	expected, err := corimFingerprint(testSignedCorimValid, crypto.SHA256, false)
+	require.NoError(t, err)
+
+	actual, err := corimFingerprint(resigned, crypto.SHA256, false)
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+
+	actual, err = corimFingerprint(unsigned, crypto.SHA256, false)
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+}
+
+func Test_corimFingerprint_not_a_corim(t *testing.T) {
+	_, err := corimFingerprint([]byte{0xa0}, crypto.SHA256, false)
+	assert.ErrorContains(t, err, "error decoding CoRIM (signed or unsigned)")
+}
+
+func Test_CorimDisplayCmd_hash_ok(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "signed.cbor", testSignedCorimValid, 0644))
+
+	cmd := NewCorimDisplayCmd()
+	cmd.SetArgs([]string{
+		"--file=signed.cbor",
+		"--hash",
+		"--hash-alg=sha-512",
+		"--output=hash.txt",
+	})
+	require.NoError(t, cmd.Execute())
+
+	msg, err := decodeSign1(testSignedCorimValid)
+	require.NoError(t, err)
+
+	canonical, err := deterministicCBOR(msg.Payload)
+	require.NoError(t, err)
+
+	actual, err := afero.ReadFile(fs, "hash.txt")
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("sha-512:%x\n", sha512.Sum512(canonical)), string(actual))
+}
+
+func Test_CorimDisplayCmd_hash_bad_args(t *testing.T) {
+	tvs := []struct {
+		args     []string
+		expected string
+	}{
+		{
+			args:     []string{"--hash", "--hash-alg=md5"},
+			expected: `unsupported hash algorithm "md5": expecting sha-256, sha-384 or sha-512`,
+		},
+		{
+			args:     []string{"--hash-alg=sha-384"},
+			expected: "--hash-alg can only be used together with --hash",
+		},
+		{
+			args:     []string{"--hash", "--show-tags"},
+			expected: "--hash cannot be used together with --show-tags, --measurements-flat or --sequence",
+		},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.expected, func(t *testing.T) {
+			cmd := NewCorimDisplayCmd()
+			cmd.SetArgs(append([]string{"--file=signed.cbor"}, tv.args...))
+			assert.EqualError(t, cmd.Execute(), tv.expected)
+		})
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/veraison/corim/corim"
+)
+
+// corimIndexEntry describes a signed CoRIM in the index maintained by "corim
+// sign --index"
+type corimIndexEntry struct {
+	ID            string `json:"id"`
+	Path          string `json:"path"`
+	PayloadSHA256 string `json:"payload-sha256"`
+	SigningTime   string `json:"signing-time"`
+	Algorithm     string `json:"algorithm"`
+}
+
+// newCorimIndexEntry describes the signed CoRIM signedCorimCBOR, saved to
+// path.  The signing time is taken from the CWT claims header, if any, and is
+// the current time otherwise.
+func newCorimIndexEntry(path string, signedCorimCBOR []byte) (corimIndexEntry, error) {
+	msg, err := decodeSign1(signedCorimCBOR)
+	if err != nil {
+		return corimIndexEntry{}, err
+	}
+
+	var u corim.UnsignedCorim
+	if err = u.FromCBOR(msg.Payload); err != nil {
+		return corimIndexEntry{}, fmt.Errorf("error decoding unsigned CoRIM: %w", err)
+	}
+
+	alg, err := msg.Headers.Protected.Algorithm()
+	if err != nil {
+		return corimIndexEntry{}, fmt.Errorf("error reading COSE algorithm: %w", err)
+	}
+
+	st, err := signingTime(msg)
+	if err != nil {
+		return corimIndexEntry{}, err
+	}
+
+	if st == nil {
+		now := time.Now().UTC()
+		st = &now
+	}
+
+	sum := sha256.Sum256(msg.Payload)
+
+	return corimIndexEntry{
+		ID:            u.ID.String(),
+		Path:          path,
+		PayloadSHA256: hex.EncodeToString(sum[:]),
+		SigningTime:   st.UTC().Format(time.RFC3339),
+		Algorithm:     alg.String(),
+	}, nil
+}
+
+// upsertCorimIndexEntry adds entry to the JSON index in file, which is created
+// if needed, replacing any entry with the same CoRIM id.  The entries are kept
+// sorted by id.  The file is exclusively locked while it is updated, so that
+// concurrent invocations do not lose each other's entries.
+func upsertCorimIndexEntry(file string, entry corimIndexEntry) error {
+	f, err := fs.OpenFile(file, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening CoRIM index %s: %w", file, err)
+	}
+	defer f.Close()
+
+	if osf, ok := f.(*os.File); ok {
+		if err = lockFile(osf); err != nil {
+			return fmt.Errorf("error locking CoRIM index %s: %w", file, err)
+		}
+		defer unlockFile(osf) // nolint: errcheck
+	}
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return fmt.Errorf("error reading CoRIM index %s: %w", file, err)
+	}
+
+	entries := []corimIndexEntry{}
+
+	if len(bytes.TrimSpace(data)) != 0 {
+		if err = json.Unmarshal(data, &entries); err != nil {
+			return fmt.Errorf("error decoding CoRIM index %s: %w", file, err)
+		}
+	}
+
+	replaced := false
+	for i := range entries {
+		if entries[i].ID == entry.ID {
+			entries[i] = entry
+			replaced = true
+		}
+	}
+
+	if !replaced {
+		entries = append(entries, entry)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+
+	if data, err = json.MarshalIndent(entries, "", "  "); err != nil {
+		return fmt.Errorf("error encoding CoRIM index: %w", err)
+	}
+
+	if err = f.Truncate(0); err != nil {
+		return fmt.Errorf("error writing CoRIM index %s: %w", file, err)
+	}
+
+	if _, err = f.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("error writing CoRIM index %s: %w", file, err)
+	}
+
+	if _, err = f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("error writing CoRIM index %s: %w", file, err)
+	}
+
+	return nil
+}
+
+// recordInCorimIndex upserts the entry of the signed CoRIM signedCorimCBOR,
+// saved to path, into the index in file
+func recordInCorimIndex(file, path string, signedCorimCBOR []byte) error {
+	entry, err := newCorimIndexEntry(path, signedCorimCBOR)
+	if err != nil {
+		return fmt.Errorf("error indexing signed CoRIM %s: %w", path, err)
+	}
+
+	if err = upsertCorimIndexEntry(file, entry); err != nil {
+		return err
+	}
+
+	fmt.Printf(">> %q (id %s) recorded in index %q\n", path, entry.ID, file)
+
+	return nil
+}
@@ -0,0 +1,108 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/corim/corim"
+)
+
+func signWithIndex(t *testing.T, unsignedFile, outputFile string) error {
+	cmd := NewCorimSignCmd()
+	cmd.SetArgs([]string{
+		"--file=" + unsignedFile,
+		"--meta=meta.json",
+		"--key=key.jwk",
+		"--output=" + outputFile,
+		"--index=index.json",
+	})
+
+	return cmd.Execute()
+}
+
+func loadCorimIndex(t *testing.T) []corimIndexEntry {
+	data, err := afero.ReadFile(fs, "index.json")
+	require.NoError(t, err)
+
+	var entries []corimIndexEntry
+	require.NoError(t, json.Unmarshal(data, &entries))
+
+	return entries
+}
+
+func Test_CorimSignCmd_index(t *testing.T) {
+	var u corim.UnsignedCorim
+	require.NoError(t, u.FromCBOR(testCorimValid))
+	id := u.ID.String()
+
+	// a second CoRIM, whose id sorts before that of testCorimValid
+	other, err := corim.NewUnsignedCorim().SetID("000-other").AddComid(testComidForIndex(t)).ToCBOR()
+	require.NoError(t, err)
+	require.Less(t, "000-other", id)
+
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "unsigned.cbor", testCorimValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "other.cbor", other, 0644))
+	require.NoError(t, afero.WriteFile(fs, "meta.json", testMetaValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "key.jwk", testECKey, 0644))
+
+	require.NoError(t, signWithIndex(t, "unsigned.cbor", "v1/signed.cbor"))
+
+	entries := loadCorimIndex(t)
+	require.Len(t, entries, 1)
+	assert.Equal(t, id, entries[0].ID)
+	assert.Equal(t, "v1/signed.cbor", entries[0].Path)
+	assert.Equal(t, "ES256", entries[0].Algorithm)
+
+	msg, err := decodeSign1(mustReadFile(t, "v1/signed.cbor"))
+	require.NoError(t, err)
+	sum := sha256.Sum256(msg.Payload)
+	assert.Equal(t, hex.EncodeToString(sum[:]), entries[0].PayloadSHA256)
+
+	_, err = time.Parse(time.RFC3339, entries[0].SigningTime)
+	assert.NoError(t, err)
+
+	// re-signing the same CoRIM replaces its entry, other CoRIMs are added
+	require.NoError(t, signWithIndex(t, "unsigned.cbor", "v2/signed.cbor"))
+	require.NoError(t, signWithIndex(t, "other.cbor", "v2/other.cbor"))
+
+	entries = loadCorimIndex(t)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "000-other", entries[0].ID)
+	assert.Equal(t, "v2/other.cbor", entries[0].Path)
+	assert.Equal(t, id, entries[1].ID)
+	assert.Equal(t, "v2/signed.cbor", entries[1].Path)
+}
+
+func Test_CorimSignCmd_bad_index(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "unsigned.cbor", testCorimValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "meta.json", testMetaValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "key.jwk", testECKey, 0644))
+	require.NoError(t, afero.WriteFile(fs, "index.json", []byte(`{"not": "an array"}`), 0644))
+
+	err := signWithIndex(t, "unsigned.cbor", "signed.cbor")
+	assert.ErrorContains(t, err, "error decoding CoRIM index index.json")
+}
+
+func testComidForIndex(t *testing.T) *comid.Comid {
+	var c comid.Comid
+	require.NoError(t, c.FromJSON([]byte(comid.PSARefValJSONTemplate)))
+	return &c
+}
+
+func mustReadFile(t *testing.T, file string) []byte {
+	data, err := afero.ReadFile(fs, file)
+	require.NoError(t, err)
+	return data
+}
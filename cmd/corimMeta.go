@@ -0,0 +1,106 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/veraison/corim/corim"
+)
+
+var (
+	corimMetaCorimFile  *string
+	corimMetaOutputFile *string
+)
+
+var corimMetaCmd = NewCorimMetaCmd()
+
+func NewCorimMetaCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "meta",
+		Short: "extract the CorimMeta of a signed CoRIM as JSON",
+		Long: `extract the CorimMeta of a signed CoRIM as JSON
+
+	Print the CorimMeta found in the COSE header of the signed CoRIM
+	signed-corim.cbor
+
+	  cocli corim meta --file=signed-corim.cbor
+
+	Save it to meta.json instead, e.g., to re-sign the CoRIM later on using
+	"corim sign --meta=meta.json"
+
+	  cocli corim meta --file=signed-corim.cbor --output=meta.json
+	`,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkCorimMetaArgs(); err != nil {
+				return err
+			}
+
+			metaJSON, err := extractMeta(*corimMetaCorimFile)
+			if err != nil {
+				return err
+			}
+
+			if *corimMetaOutputFile == "" {
+				fmt.Println(string(metaJSON))
+				return nil
+			}
+
+			if err = afero.WriteFile(fs, *corimMetaOutputFile, append(metaJSON, '\n'), 0644); err != nil {
+				return fmt.Errorf("error saving CoRIM Meta to file %s: %w", *corimMetaOutputFile, err)
+			}
+
+			fmt.Printf(">> CoRIM Meta of %q saved to %q\n", *corimMetaCorimFile, *corimMetaOutputFile)
+
+			return nil
+		},
+	}
+
+	corimMetaCorimFile = cmd.Flags().StringP("file", "f", "", "a signed CoRIM file (in CBOR format)")
+	corimMetaOutputFile = cmd.Flags().StringP("output", "o", "", "name of the CoRIM Meta file (in JSON format) to save, instead of printing it")
+
+	return cmd
+}
+
+func checkCorimMetaArgs() error {
+	if corimMetaCorimFile == nil || *corimMetaCorimFile == "" {
+		return errors.New("no CoRIM supplied")
+	}
+
+	return nil
+}
+
+// extractMeta returns the JSON encoding of the CoRIM Meta of the signed CoRIM
+// in file, in the format expected by "corim sign --meta"
+func extractMeta(file string) ([]byte, error) {
+	var (
+		data []byte
+		err  error
+		s    corim.SignedCorim
+	)
+
+	if data, err = afero.ReadFile(fs, file); err != nil {
+		return nil, fmt.Errorf("error loading signed CoRIM from %s: %w", file, err)
+	}
+
+	if err = s.FromCOSE(tagSign1(data)); err != nil {
+		return nil, fmt.Errorf("error decoding signed CoRIM from %s: %w", file, err)
+	}
+
+	metaJSON, err := json.MarshalIndent(&s.Meta, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error encoding CoRIM Meta from %s: %w", file, err)
+	}
+
+	return metaJSON, nil
+}
+
+func init() {
+	corimCmd.AddCommand(corimMetaCmd)
+}
@@ -0,0 +1,86 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/corim"
+)
+
+func Test_CorimMetaCmd_unknown_argument(t *testing.T) {
+	cmd := NewCorimMetaCmd()
+
+	args := []string{"--unknown-argument=val"}
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "unknown flag: --unknown-argument")
+}
+
+func Test_CorimMetaCmd_no_file(t *testing.T) {
+	cmd := NewCorimMetaCmd()
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "no CoRIM supplied")
+}
+
+func Test_CorimMetaCmd_unsigned_corim(t *testing.T) {
+	cmd := NewCorimMetaCmd()
+
+	args := []string{"--file=unsigned.cbor"}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "unsigned.cbor", testCorimValid, 0644)
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	assert.ErrorContains(t, err, "error decoding signed CoRIM from unsigned.cbor")
+}
+
+func Test_CorimMetaCmd_ok(t *testing.T) {
+	cmd := NewCorimMetaCmd()
+
+	args := []string{
+		"--file=signed.cbor",
+		"--output=meta.json",
+	}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "signed.cbor", testSignedCorimValid, 0644)
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	require.NoError(t, err)
+
+	metaJSON, err := afero.ReadFile(fs, "meta.json")
+	require.NoError(t, err)
+
+	// the recovered Meta can be fed back to corim sign
+	var expected, actual corim.SignedCorim
+	require.NoError(t, expected.FromCOSE(testSignedCorimValid))
+	require.NoError(t, actual.Meta.FromJSON(metaJSON))
+	assert.Equal(t, expected.Meta.Signer, actual.Meta.Signer)
+	require.NotNil(t, actual.Meta.Validity)
+	assert.True(t, expected.Meta.Validity.NotAfter.Equal(actual.Meta.Validity.NotAfter))
+}
+
+func Test_CorimMetaCmd_stdout(t *testing.T) {
+	cmd := NewCorimMetaCmd()
+
+	args := []string{"--file=signed.cbor"}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "signed.cbor", untagSign1(testSignedCorimValid), 0644)
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	assert.NoError(t, err)
+}
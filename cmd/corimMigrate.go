@@ -0,0 +1,175 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/veraison/corim/corim"
+)
+
+var (
+	corimMigrateCorimFile    *string
+	corimMigrateOutputFile   *string
+	corimMigrateFromProfile  *string
+	corimMigrateToProfile    *string
+	corimMigrateAllowPartial *bool
+)
+
+var corimMigrateCmd = NewCorimMigrateCmd()
+
+func NewCorimMigrateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "migrate a CoRIM from one profile version to another",
+		Long: `migrate a CoRIM from one profile version to another
+
+    Decode the CoRIM in old.cbor, which must declare the PSA profile version 1
+    ("http://arm.com/psa/iot/1"), convert its CoMIDs to version 2
+    ("https://arm.com/psa/iot/2.0.0") and save the result to new.cbor:
+
+      cocli corim migrate --from-profile=psa-v1 \
+                    --to-profile=psa-v2 \
+                    --file=old.cbor \
+                    --output=new.cbor
+
+    Each change applied is reported.  Fields that cannot be migrated
+    automatically are reported too and, unless --allow-partial is set, nothing
+    is saved.  CoSWID and CoTS tags are copied unchanged.
+
+    Profiles can be named by their identifier or by their short name.  The
+    supported migrations are:
+
+      psa-v1 -> psa-v2   the version of a software component moves from the
+                         version measurement value to the psa.refval-id
+                         measurement key (the version scheme is dropped)
+
+    If old.cbor is a signed CoRIM, its unsigned CoRIM is migrated, and the
+    result needs to be signed again using the "corim sign" sub-command.
+    `,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkCorimMigrateArgs(); err != nil {
+				return err
+			}
+
+			m, err := lookupProfileMigration(*corimMigrateFromProfile, *corimMigrateToProfile)
+			if err != nil {
+				return err
+			}
+
+			outputFile, err := migrate(*corimMigrateCorimFile, corimMigrateOutputFile, m, *corimMigrateAllowPartial)
+			if err != nil {
+				return err
+			}
+			fmt.Printf(">> %q migrated from %s to %s and saved to %q\n",
+				*corimMigrateCorimFile, m.fromName, m.toName, outputFile)
+
+			return nil
+		},
+	}
+
+	corimMigrateCorimFile = cmd.Flags().StringP("file", "f", "", "a CoRIM file (in CBOR format)")
+	corimMigrateOutputFile = cmd.Flags().StringP("output", "o", "", "name of the generated (unsigned) CoRIM file")
+	corimMigrateFromProfile = cmd.Flags().String("from-profile", "", "profile (identifier or short name) of the CoRIM")
+	corimMigrateToProfile = cmd.Flags().String("to-profile", "", "profile (identifier or short name) to migrate the CoRIM to")
+	corimMigrateAllowPartial = cmd.Flags().Bool(
+		"allow-partial", false, "save the migrated CoRIM even if some fields could not be migrated",
+	)
+
+	return cmd
+}
+
+func checkCorimMigrateArgs() error {
+	if corimMigrateCorimFile == nil || *corimMigrateCorimFile == "" {
+		return errors.New("no CoRIM supplied")
+	}
+
+	if corimMigrateFromProfile == nil || *corimMigrateFromProfile == "" {
+		return errors.New("no source profile supplied")
+	}
+
+	if corimMigrateToProfile == nil || *corimMigrateToProfile == "" {
+		return errors.New("no target profile supplied")
+	}
+
+	return nil
+}
+
+// migratedCorimFileName returns the name of the file the migrated CoRIM is
+// saved to: outputFile, if set, or else a name derived from that of the
+// original CoRIM
+func migratedCorimFileName(corimFile string, outputFile *string) string {
+	if outputFile == nil || *outputFile == "" {
+		dir, base := filepath.Split(corimFile)
+		return filepath.Join(dir, "migrated-"+base)
+	}
+
+	return *outputFile
+}
+
+func migrate(corimFile string, outputFile *string, m profileMigration, allowPartial bool) (string, error) {
+	data, err := afero.ReadFile(fs, corimFile)
+	if err != nil {
+		return "", fmt.Errorf("error loading CoRIM from %s: %w", corimFile, err)
+	}
+
+	payload, err := unsignedCorimPayload(data, false)
+	if err != nil {
+		return "", fmt.Errorf("error decoding CoRIM from %s: %w", corimFile, err)
+	}
+
+	c := corim.GetUnsignedCorim(cborProfile(payload))
+	if err = decodeCBOR(c, payload, false); err != nil {
+		return "", fmt.Errorf("error decoding CoRIM from %s: %w", corimFile, err)
+	}
+
+	r, err := migrateCorim(c, m)
+	if err != nil {
+		return "", fmt.Errorf("error migrating %s: %w", corimFile, err)
+	}
+
+	for _, change := range r.changes {
+		fmt.Printf(">> migrated: %s\n", change)
+	}
+
+	for _, field := range r.unmigrated {
+		fmt.Printf(">> not migrated: %s\n", field)
+	}
+
+	fmt.Printf(">> %d change(s) applied, %d field(s) not migrated\n", len(r.changes), len(r.unmigrated))
+
+	if len(r.unmigrated) != 0 && !allowPartial {
+		return "", fmt.Errorf(
+			"error migrating %s: %d field(s) could not be migrated, fix them manually or use --allow-partial",
+			corimFile, len(r.unmigrated),
+		)
+	}
+
+	if !bytes.Equal(payload, data) {
+		fmt.Printf(">> warning: %q is signed, the migrated CoRIM needs to be signed again\n", corimFile)
+	}
+
+	migrated, err := c.ToCBOR()
+	if err != nil {
+		return "", fmt.Errorf("error encoding migrated CoRIM: %w", err)
+	}
+
+	migratedFile := migratedCorimFileName(corimFile, outputFile)
+
+	if err = afero.WriteFile(fs, migratedFile, migrated, 0644); err != nil {
+		return "", fmt.Errorf("error saving CoRIM to file %s: %w", migratedFile, err)
+	}
+
+	return migratedFile, nil
+}
+
+func init() {
+	corimCmd.AddCommand(corimMigrateCmd)
+}
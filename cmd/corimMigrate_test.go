@@ -0,0 +1,235 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/corim/corim"
+)
+
+// a PSA v1 CoMID: the version of BL is only in the measurement value, that of
+// PRoT is in both the key and the value, and that of ARoT is only in the key
+var testPSAv1ComidJSON = `{
+	"tag-identity": { "id": "43BBE37F-2E61-4B33-AED3-53CFF1428B16" },
+	"entities": [
+		{ "name": "ACME Ltd.", "regid": "https://acme.example", "roles": [ "tagCreator" ] }
+	],
+	"triples": {
+		"reference-values": [
+			{
+				"environment": {
+					"class": {
+						"id": { "type": "psa.impl-id", "value": "YWNtZS1pbXBsZW1lbnRhdGlvbi1pZC0wMDAwMDAwMDE=" },
+						"vendor": "ACME",
+						"model": "RoadRunner"
+					}
+				},
+				"measurements": [
+					{
+						"key": {
+							"type": "psa.refval-id",
+							"value": { "label": "BL", "signer-id": "rLsRx+TaIXIFUjzkzhokWuGiOa48a/2eeHH35di66Gs=" }
+						},
+						"value": {
+							"version": { "scheme": "semaver", "value": "2.1.0" },
+							"digests": [ "sha-256:h0KPxSKAPTEGXnvOPPA/5HUJZjHl4Hu9eg/eYMTPJcc=" ]
+						}
+					},
+					{
+						"key": {
+							"type": "psa.refval-id",
+							"value": { "label": "PRoT", "version": "1.3.5", "signer-id": "rLsRx+TaIXIFUjzkzhokWuGiOa48a/2eeHH35di66Gs=" }
+						},
+						"value": {
+							"version": { "scheme": "semaver", "value": "%s" },
+							"digests": [ "sha-256:AmOCmYm2/ZVPcrqvL8ZLwuLwHWktTecphuqAj26ZgT8=" ]
+						}
+					},
+					{
+						"key": {
+							"type": "psa.refval-id",
+							"value": { "label": "ARoT", "version": "0.1.4", "signer-id": "rLsRx+TaIXIFUjzkzhokWuGiOa48a/2eeHH35di66Gs=" }
+						},
+						"value": {
+							"digests": [ "sha-256:o6XnFfDMV0pzw/m+u2vCTzL/1bZ7OHJEwskJ2neaFHg=" ]
+						}
+					}
+				]
+			}
+		]
+	}
+}`
+
+// testPSAv1Corim returns a PSA v1 CoRIM with the test CoMID, where the PRoT
+// measurement value carries protVersion
+func testPSAv1Corim(t *testing.T, profile, protVersion string) []byte {
+	var c comid.Comid
+	require.NoError(t, c.FromJSON([]byte(fmt.Sprintf(testPSAv1ComidJSON, protVersion))))
+
+	data, err := corim.NewUnsignedCorim().SetID("psa-v1").SetProfile(profile).AddComid(&c).ToCBOR()
+	require.NoError(t, err)
+
+	return data
+}
+
+func migrateCorimFile(t *testing.T, args ...string) error {
+	cmd := NewCorimMigrateCmd()
+	cmd.SetArgs(append([]string{"--file=old.cbor", "--from-profile=psa-v1", "--to-profile=psa-v2"}, args...))
+	return cmd.Execute()
+}
+
+// loadMigratedRefVals returns the reference value measurements of the first
+// CoMID of the CoRIM in file, and the profile of the CoRIM
+func loadMigratedRefVals(t *testing.T, file string) ([]comid.Measurement, string) {
+	var u corim.UnsignedCorim
+	require.NoError(t, u.FromCBOR(mustReadFile(t, file)))
+
+	profile, err := u.Profile.Get()
+	require.NoError(t, err)
+
+	c, err := corim.UnmarshalComidFromCBOR(u.Tags[0][3:], u.Profile)
+	require.NoError(t, err)
+
+	return c.Triples.ReferenceValues.Values[0].Measurements.Values, profile
+}
+
+func Test_CorimMigrateCmd_unknown_argument(t *testing.T) {
+	cmd := NewCorimMigrateCmd()
+	cmd.SetArgs([]string{"--unknown-argument=val"})
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "unknown flag: --unknown-argument")
+}
+
+func Test_CorimMigrateCmd_missing_args(t *testing.T) {
+	tvs := []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"--from-profile=psa-v1", "--to-profile=psa-v2"}, "no CoRIM supplied"},
+		{[]string{"--file=old.cbor", "--to-profile=psa-v2"}, "no source profile supplied"},
+		{[]string{"--file=old.cbor", "--from-profile=psa-v1"}, "no target profile supplied"},
+	}
+
+	for _, tv := range tvs {
+		cmd := NewCorimMigrateCmd()
+		cmd.SetArgs(tv.args)
+		assert.EqualError(t, cmd.Execute(), tv.err)
+	}
+}
+
+func Test_CorimMigrateCmd_unknown_migration(t *testing.T) {
+	cmd := NewCorimMigrateCmd()
+	cmd.SetArgs([]string{"--file=old.cbor", "--from-profile=psa-v2", "--to-profile=psa-v1"})
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, `no migration known from "psa-v2" to "psa-v1" (known migrations: psa-v1 -> psa-v2)`)
+}
+
+func Test_CorimMigrateCmd_ok(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "old.cbor", testPSAv1Corim(t, "http://arm.com/psa/iot/1", "1.3.5"), 0644))
+
+	require.NoError(t, migrateCorimFile(t, "--output=new.cbor"))
+
+	ms, profile := loadMigratedRefVals(t, "new.cbor")
+	assert.Equal(t, "https://arm.com/psa/iot/2.0.0", profile)
+	require.Len(t, ms, 3)
+
+	for i, version := range []string{"2.1.0", "1.3.5", "0.1.4"} {
+		id, err := ms[i].Key.GetPSARefValID()
+		require.NoError(t, err)
+		require.NotNil(t, id.Version)
+		assert.Equal(t, version, *id.Version)
+		assert.Nil(t, ms[i].Val.Ver)
+		assert.NotNil(t, ms[i].Val.Digests)
+	}
+}
+
+func Test_CorimMigrateCmd_identifiers_and_default_output(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "v1/old.cbor", testPSAv1Corim(t, "http://arm.com/psa/iot/1", "1.3.5"), 0644))
+
+	cmd := NewCorimMigrateCmd()
+	cmd.SetArgs([]string{
+		"--file=v1/old.cbor",
+		"--from-profile=http://arm.com/psa/iot/1",
+		"--to-profile=https://arm.com/psa/iot/2.0.0",
+	})
+	require.NoError(t, cmd.Execute())
+
+	_, profile := loadMigratedRefVals(t, "v1/migrated-old.cbor")
+	assert.Equal(t, "https://arm.com/psa/iot/2.0.0", profile)
+}
+
+func Test_CorimMigrateCmd_profile_mismatch(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "old.cbor", testPSAv1Corim(t, "http://arm.com/cca/ssd/1", "1.3.5"), 0644))
+
+	err := migrateCorimFile(t)
+	assert.EqualError(t, err,
+		`error migrating old.cbor: CoRIM profile mismatch: expected "http://arm.com/psa/iot/1", got "http://arm.com/cca/ssd/1"`)
+}
+
+func Test_CorimMigrateCmd_version_conflict(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "old.cbor", testPSAv1Corim(t, "http://arm.com/psa/iot/1", "1.3.6"), 0644))
+
+	err := migrateCorimFile(t, "--output=new.cbor")
+	assert.EqualError(t, err,
+		"error migrating old.cbor: 1 field(s) could not be migrated, fix them manually or use --allow-partial")
+
+	exists, _ := afero.Exists(fs, "new.cbor")
+	assert.False(t, exists)
+
+	// the conflicting measurement is left as it is
+	require.NoError(t, migrateCorimFile(t, "--output=new.cbor", "--allow-partial"))
+
+	ms, _ := loadMigratedRefVals(t, "new.cbor")
+	require.NotNil(t, ms[1].Val.Ver)
+	assert.Equal(t, "1.3.6", ms[1].Val.Ver.Version)
+	assert.Nil(t, ms[0].Val.Ver)
+}
+
+func Test_migratePSAComidV1ToV2_non_refval_key(t *testing.T) {
+	var c comid.Comid
+	require.NoError(t, c.FromJSON([]byte(fmt.Sprintf(testPSAv1ComidJSON, "1.3.5"))))
+
+	key, err := comid.NewMkeyUUID(comid.TestUUIDString)
+	require.NoError(t, err)
+	c.Triples.ReferenceValues.Values[0].Measurements.Values[2].Key = key
+
+	var r migrationReport
+	migratePSAComidV1ToV2("tag [0] (CoMID)", &c, &r)
+
+	assert.Equal(t, []string{
+		`tag [0] (CoMID): reference-values[0]: measurement [0]: version "2.1.0" moved into the psa.refval-id key`,
+		`tag [0] (CoMID): reference-values[0]: measurement [1]: version "1.3.5" dropped, as already in the psa.refval-id key`,
+	}, r.changes)
+	assert.Equal(t, []string{
+		"tag [0] (CoMID): reference-values[0]: measurement [2]: measurement key of type uuid cannot be mapped to a psa.refval-id",
+	}, r.unmigrated)
+}
+
+func Test_CorimMigrateCmd_signed(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "unsigned.cbor", testPSAv1Corim(t, "http://arm.com/psa/iot/1", "1.3.5"), 0644))
+	require.NoError(t, afero.WriteFile(fs, "meta.json", testMetaValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "key.jwk", testECKey, 0644))
+
+	sign := NewCorimSignCmd()
+	sign.SetArgs([]string{"--file=unsigned.cbor", "--meta=meta.json", "--key=key.jwk", "--output=old.cbor"})
+	require.NoError(t, sign.Execute())
+
+	require.NoError(t, migrateCorimFile(t, "--output=new.cbor"))
+
+	_, profile := loadMigratedRefVals(t, "new.cbor")
+	assert.Equal(t, "https://arm.com/psa/iot/2.0.0", profile)
+}
@@ -0,0 +1,176 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/veraison/corim/corim"
+)
+
+var (
+	corimResignCorimFile         *string
+	corimResignKeyFile           *string
+	corimResignOutputFile        *string
+	corimResignCertFile          *string
+	corimResignIntermediateCerts []string
+)
+
+var corimResignCmd = NewCorimResignCmd()
+
+func NewCorimResignCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "resign",
+		Short: "replace the signature of a signed CoRIM with one made using the supplied key",
+		Long: `replace the signature of a signed CoRIM with one made using the supplied key
+
+    Strip the signature from signed-corim.cbor (e.g., made with a test key) and
+    sign its unsigned CoRIM and CorimMeta again using the key in JWK format from
+    file prod.jwk, saving the resulting COSE Sign1 to resigned-corim.cbor:
+
+      cocli corim resign --file=signed-corim.cbor \
+                    --key=prod.jwk \
+                    --output=resigned-corim.cbor
+
+    The certificates of the original signer are not carried over.  Optionally
+    include the new signing certificate and certificate chain in the COSE header:
+
+      cocli corim resign --file=signed-corim.cbor \
+                    --key=prod.jwk \
+                    --cert=signing-cert.der \
+                    --intermediates=intermediate-certs.der \
+                    --output=resigned-corim.cbor
+    `,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkCorimResignArgs(); err != nil {
+				return err
+			}
+
+			coseFile, err := resign(*corimResignCorimFile, *corimResignKeyFile,
+				corimResignOutputFile, corimResignCertFile, corimResignIntermediateCerts)
+			if err != nil {
+				return err
+			}
+			fmt.Printf(">> %q re-signed and saved to %q\n", *corimResignCorimFile, coseFile)
+
+			return nil
+		},
+	}
+
+	corimResignCorimFile = cmd.Flags().StringP("file", "f", "", "a signed CoRIM file (in CBOR format)")
+	corimResignKeyFile = cmd.Flags().StringP("key", "k", "", "signing key in JWK format")
+	corimResignOutputFile = cmd.Flags().StringP("output", "o", "", "name of the generated COSE Sign1 file")
+	corimResignCertFile = cmd.Flags().StringP("cert", "c", "", "signing certificate in DER format")
+	cmd.Flags().StringArrayVar(
+		&corimResignIntermediateCerts, "intermediates", []string{}, "intermediate certificates in DER format (can be repeated)",
+	)
+
+	return cmd
+}
+
+func checkCorimResignArgs() error {
+	if corimResignCorimFile == nil || *corimResignCorimFile == "" {
+		return errors.New("no CoRIM supplied")
+	}
+
+	if corimResignKeyFile == nil || *corimResignKeyFile == "" {
+		return errors.New("no key supplied")
+	}
+
+	if len(corimResignIntermediateCerts) != 0 && (corimResignCertFile == nil || *corimResignCertFile == "") {
+		return errors.New("cannot add intermediate certificates without a signing certificate")
+	}
+
+	return nil
+}
+
+// resignedCorimFileName returns the name of the file the re-signed CoRIM is
+// saved to: outputFile, if set, or else a name derived from that of the signed
+// CoRIM
+func resignedCorimFileName(signedCorimFile string, outputFile *string) string {
+	if outputFile == nil || *outputFile == "" {
+		dir, base := filepath.Split(signedCorimFile)
+		return filepath.Join(dir, "resigned-"+base)
+	}
+
+	return *outputFile
+}
+
+func resign(signedCorimFile, keyFile string, outputFile, certFile *string, intermediatesFiles []string) (string, error) {
+	var (
+		signedCorimCBOR []byte
+		keyJWK          []byte
+		err             error
+		orig            corim.SignedCorim
+	)
+
+	if signedCorimCBOR, err = afero.ReadFile(fs, signedCorimFile); err != nil {
+		return "", fmt.Errorf("error loading signed CoRIM from %s: %w", signedCorimFile, err)
+	}
+
+	if err = orig.FromCOSE(signedCorimCBOR); err != nil {
+		return "", fmt.Errorf("error decoding signed CoRIM from %s: %w", signedCorimFile, err)
+	}
+
+	if keyJWK, err = afero.ReadFile(fs, keyFile); err != nil {
+		return "", fmt.Errorf("error loading signing key from %s: %w", keyFile, err)
+	}
+
+	signer, err := corim.NewSignerFromJWK(keyJWK)
+	if err != nil {
+		return "", fmt.Errorf("error loading signing key from %s: %w", keyFile, err)
+	}
+
+	// only the unsigned CoRIM and the CoRIM Meta survive: the signature and
+	// the certificates of the original signer are dropped
+	s := corim.SignedCorim{
+		UnsignedCorim: orig.UnsignedCorim,
+		Meta:          orig.Meta,
+	}
+
+	if certFile != nil && *certFile != "" {
+		certDER, err := afero.ReadFile(fs, *certFile)
+		if err != nil {
+			return "", fmt.Errorf("error loading signing certificate from %s: %w", *certFile, err)
+		}
+
+		if err = s.AddSigningCert(certDER); err != nil {
+			return "", fmt.Errorf("error adding signing certificate: %w", err)
+		}
+	}
+
+	if len(intermediatesFiles) != 0 {
+		if err = addIntermediateCertFiles(&s, intermediatesFiles); err != nil {
+			return "", err
+		}
+	}
+
+	fmt.Printf(">> warning: removing the original signature of %q\n", signedCorimFile)
+	if orig.SigningCert != nil {
+		fmt.Printf(">> warning: the certificates of the original signer (%s) are not carried over\n",
+			orig.SigningCert.Subject)
+	}
+
+	resignedCorimCBOR, err := s.Sign(signer)
+	if err != nil {
+		return "", fmt.Errorf("error signing CoRIM: %w", err)
+	}
+
+	resignedCorimFile := resignedCorimFileName(signedCorimFile, outputFile)
+
+	if err = afero.WriteFile(fs, resignedCorimFile, resignedCorimCBOR, 0644); err != nil {
+		return "", fmt.Errorf("error saving signed CoRIM to file %s: %w", resignedCorimFile, err)
+	}
+
+	return resignedCorimFile, nil
+}
+
+func init() {
+	corimCmd.AddCommand(corimResignCmd)
+}
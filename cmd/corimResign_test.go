@@ -0,0 +1,186 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/corim"
+)
+
+func Test_CorimResignCmd_unknown_argument(t *testing.T) {
+	cmd := NewCorimResignCmd()
+
+	args := []string{"--unknown-argument=val"}
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "unknown flag: --unknown-argument")
+}
+
+func Test_CorimResignCmd_no_file(t *testing.T) {
+	cmd := NewCorimResignCmd()
+
+	args := []string{"--key=key.jwk"}
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "no CoRIM supplied")
+}
+
+func Test_CorimResignCmd_no_key(t *testing.T) {
+	cmd := NewCorimResignCmd()
+
+	args := []string{"--file=signed.cbor"}
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "no key supplied")
+}
+
+func Test_CorimResignCmd_intermediates_without_cert(t *testing.T) {
+	cmd := NewCorimResignCmd()
+
+	args := []string{
+		"--file=signed.cbor",
+		"--key=key.jwk",
+		"--intermediates=intermediates.der",
+	}
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "cannot add intermediate certificates without a signing certificate")
+}
+
+func Test_CorimResignCmd_file_not_found(t *testing.T) {
+	cmd := NewCorimResignCmd()
+
+	fs = afero.NewMemMapFs()
+
+	args := []string{
+		"--file=nonexistent.cbor",
+		"--key=key.jwk",
+	}
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "error loading signed CoRIM from nonexistent.cbor: open nonexistent.cbor: file does not exist")
+}
+
+func Test_CorimResignCmd_unsigned_corim(t *testing.T) {
+	var err error
+
+	cmd := NewCorimResignCmd()
+
+	fs = afero.NewMemMapFs()
+	err = afero.WriteFile(fs, "unsigned.cbor", testCorimValid, 0644)
+	require.NoError(t, err)
+
+	args := []string{
+		"--file=unsigned.cbor",
+		"--key=key.jwk",
+	}
+	cmd.SetArgs(args)
+
+	err = cmd.Execute()
+	assert.ErrorContains(t, err, "error decoding signed CoRIM from unsigned.cbor")
+}
+
+func Test_CorimResignCmd_bad_key(t *testing.T) {
+	var err error
+
+	cmd := NewCorimResignCmd()
+
+	fs = afero.NewMemMapFs()
+	err = afero.WriteFile(fs, "signed.cbor", testSignedCorimValid, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "key.jwk", []byte("{}"), 0644)
+	require.NoError(t, err)
+
+	args := []string{
+		"--file=signed.cbor",
+		"--key=key.jwk",
+	}
+	cmd.SetArgs(args)
+
+	err = cmd.Execute()
+	assert.ErrorContains(t, err, "error loading signing key from key.jwk")
+}
+
+func Test_CorimResignCmd_ok(t *testing.T) {
+	var err error
+
+	cmd := NewCorimResignCmd()
+
+	fs = afero.NewMemMapFs()
+	err = afero.WriteFile(fs, "signed.cbor", testSignedCorimValid, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "prod.jwk", testEdDSAKey, 0644)
+	require.NoError(t, err)
+
+	args := []string{
+		"--file=signed.cbor",
+		"--key=prod.jwk",
+		"--output=resigned.cbor",
+	}
+	cmd.SetArgs(args)
+
+	err = cmd.Execute()
+	require.NoError(t, err)
+
+	data, err := afero.ReadFile(fs, "resigned.cbor")
+	require.NoError(t, err)
+
+	var orig, resigned corim.SignedCorim
+	require.NoError(t, orig.FromCOSE(testSignedCorimValid))
+	require.NoError(t, resigned.FromCOSE(data))
+
+	// the new signature verifies with the new key only
+	pk, err := corim.NewPublicKeyFromJWK(testEdDSAKey)
+	require.NoError(t, err)
+	assert.NoError(t, resigned.Verify(pk))
+
+	oldPK, err := corim.NewPublicKeyFromJWK(testECKey)
+	require.NoError(t, err)
+	assert.Error(t, resigned.Verify(oldPK))
+
+	// payload and meta are preserved
+	assert.Equal(t, orig.UnsignedCorim, resigned.UnsignedCorim)
+	assert.Equal(t, orig.Meta, resigned.Meta)
+}
+
+func Test_CorimResignCmd_with_cert_default_output(t *testing.T) {
+	var err error
+
+	cmd := NewCorimResignCmd()
+
+	fs = afero.NewMemMapFs()
+	err = afero.WriteFile(fs, "corims/signed.cbor", testSignedCorimValid, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "key.jwk", testECKey, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "cert.der", testSigningCertificate, 0644)
+	require.NoError(t, err)
+
+	args := []string{
+		"--file=corims/signed.cbor",
+		"--key=key.jwk",
+		"--cert=cert.der",
+	}
+	cmd.SetArgs(args)
+
+	err = cmd.Execute()
+	require.NoError(t, err)
+
+	data, err := afero.ReadFile(fs, "corims/resigned-signed.cbor")
+	require.NoError(t, err)
+
+	var resigned corim.SignedCorim
+	require.NoError(t, resigned.FromCOSE(data))
+	require.NotNil(t, resigned.SigningCert)
+	assert.Equal(t, testSigningCertificate, resigned.SigningCert.Raw)
+}
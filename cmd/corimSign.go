@@ -4,8 +4,13 @@
 package cmd
 
 import (
+	"crypto"
+	"crypto/rand"
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
@@ -14,14 +19,93 @@ import (
 )
 
 var (
-	corimSignCorimFile         *string
-	corimSignKeyFile           *string
-	corimSignOutputFile        *string
-	corimSignMetaFile          *string
-	corimSignCertFile          *string
-	corimSignIntermediateCerts *string
+	corimSignCorimFile          *string
+	corimSignKeyFile            *string
+	corimSignOutputFile         *string
+	corimSignMetaFile           *string
+	corimSignCertFile           *string
+	corimSignIntermediateCerts  []string
+	corimSignCertChain          *string
+	corimSignNoMeta             *bool
+	corimSignReproducible       *bool
+	corimSignMetaFromCorim      *string
+	corimSignBumpValidity       *time.Duration
+	corimSignAllowedAlgs        []string
+	corimSignDeniedAlgs         []string
+	corimSignAuditLog           *string
+	corimSignWrapTagged         *bool
+	corimSignNoWrapTagged       *bool
+	corimSignKeyID              *string
+	corimSignEmbedPublicKey     *bool
+	corimSignOutputFormat       *string
+	corimSignAdditionalMeta     *string
+	corimSignIndex              *string
+	corimSignCheckDups          *bool
+	corimSignFailOnDups         *bool
+	corimSignVerifyAfterSign    *bool
+	corimSignSSHAgent           *bool
+	corimSignSSHKey             *string
+	corimSignSummary            *bool
+	corimSignSummaryFormat      *string
+	corimSignOnlyIfChanged      *bool
+	corimSignStripUnknown       *bool
+	corimSignValidityFromCert   *bool
+	corimSignDeterministicECDSA *bool
+	corimSignOutputMode         *string
+	corimSignLabelByID          *bool
+	corimSignOutputDir          *string
+	corimSignContainer          *string
+	corimSignVerifyAgainst      []string
+	corimSignCertURL            *string
+	corimSignCertCacheDir       *string
+	corimSignAppendToSequence   *string
+	corimSignTSAURL             *string
 )
 
+// the values accepted by corim sign --output-format
+const (
+	signOutputCBOR = "cbor"
+	signOutputDiag = "diag"
+	signOutputBoth = "both"
+)
+
+// signOptions collects the optional settings that affect how a CoRIM is signed
+type signOptions struct {
+	reproducible       bool
+	metaFromCorim      string
+	bumpValidity       time.Duration
+	algPolicy          algorithmPolicy
+	untagged           bool
+	certChain          string
+	kid                string
+	embedKey           bool
+	outputFormat       string
+	additionalMeta     string
+	index              string
+	checkDups          bool
+	failOnDups         bool
+	verifyAfter        bool
+	sshAgent           bool
+	sshKey             string
+	stripUnknown       bool
+	validityFromCert   bool
+	deterministicECDSA bool
+	// fetch the signing certificate (chain) from this URL, optionally caching
+	// it in certCacheDir
+	certURL      string
+	certCacheDir string
+	// permissions of the saved files, defaultFileMode if empty
+	outputMode string
+	// append the signed CoRIM to this CBOR sequence, instead of saving it to
+	// its own file
+	appendToSequence string
+	// obtain a timestamp token over the signature from the TSA at this URL
+	tsaURL string
+	// if not nil, set to the key that signed and to its certificate, for
+	// the audit log
+	signingKey *signingKey
+}
+
 var corimSignCmd = NewCorimSignCmd()
 
 func NewCorimSignCmd() *cobra.Command {
@@ -47,6 +131,284 @@ func NewCorimSignCmd() *cobra.Command {
                     --cert=signing-cert.der \
                     --intermediates=intermediate-certs.der \
                     --output=signed-corim.cbor
+
+    Alternatively, supply the signing certificate followed by the intermediate
+    certificates in a single PEM file, as emitted by most PKI tools:
+
+      cocli corim sign  --file=unsigned-corim.cbor \
+                    --key=key.jwk \
+                    --meta=meta.json \
+                    --cert-chain=chain.pem \
+                    --output=signed-corim.cbor
+
+    The --intermediates switch can be repeated, e.g., if each intermediate
+    certificate is in its own file.  The certificates are added to the chain
+    in the order given, after the signing certificate:
+
+      cocli corim sign  --file=unsigned-corim.cbor \
+                    --key=key.jwk \
+                    --meta=meta.json \
+                    --cert=signing-cert.der \
+                    --intermediates=issuing-ca.der \
+                    --intermediates=policy-ca.der \
+                    --output=signed-corim.cbor
+
+    Fetch the signing certificate (or a PEM chain) from the URL at which it is
+    published, rather than from a file, keeping a copy in cert-cache.d that is
+    only downloaded again if its ETag changed.  The certificate must match the
+    signing key:
+
+      cocli corim sign  --file=unsigned-corim.cbor \
+                    --key=key.jwk \
+                    --meta=meta.json \
+                    --cert-url=https://pki.example.com/corim-signer.pem \
+                    --cert-cache-dir=cert-cache.d \
+                    --output=signed-corim.cbor
+
+    Sign without a CorimMeta block, for experimental profiles that do not
+    require one (note that such CoRIMs cannot be verified or displayed by
+    cocli):
+
+      cocli corim sign  --file=unsigned-corim.cbor \
+                    --key=key.jwk \
+                    --no-meta \
+                    --output=signed-corim.cbor
+
+    Produce byte-identical output when signing the same inputs again, using
+    deterministic CBOR encoding.  This requires a deterministic signature
+    algorithm such as Ed25519:
+
+      cocli corim sign  --file=unsigned-corim.cbor \
+                    --key=ed25519.jwk \
+                    --meta=meta.json \
+                    --reproducible \
+                    --output=signed-corim.cbor
+
+    The same, with an ECDSA key: the signature nonce is derived from the key
+    and the CoRIM as per RFC 6979, rather than drawn at random:
+
+      cocli corim sign  --file=unsigned-corim.cbor \
+                    --key=ec-p256.jwk \
+                    --meta=meta.json \
+                    --reproducible \
+                    --deterministic-ecdsa \
+                    --output=signed-corim.cbor
+
+    Re-sign an updated CoRIM, carrying forward the CorimMeta of the previously
+    signed CoRIM previous-signed-corim.cbor, with its validity period moved 90
+    days ahead:
+
+      cocli corim sign  --file=unsigned-corim.cbor \
+                    --key=key.jwk \
+                    --meta-from-corim=previous-signed-corim.cbor \
+                    --bump-validity=2160h \
+                    --output=signed-corim.cbor
+
+    Refuse to sign unless the algorithm of the signing key is ES384 or ES512:
+
+      cocli corim sign  --file=unsigned-corim.cbor \
+                    --key=key.jwk \
+                    --meta=meta.json \
+                    --allowed-algs=ES384,ES512
+
+    Append a JSON record of the signing operation (whether successful or not)
+    to the audit log in signing-audit.jsonl:
+
+      cocli corim sign  --file=unsigned-corim.cbor \
+                    --key=key.jwk \
+                    --meta=meta.json \
+                    --audit-log=signing-audit.jsonl
+
+    Save the COSE Sign1 as a bare array, without the COSE_Sign1 CBOR tag (18),
+    for relying parties that only accept the untagged form:
+
+      cocli corim sign  --file=unsigned-corim.cbor \
+                    --key=key.jwk \
+                    --meta=meta.json \
+                    --no-wrap-tagged
+
+    Set the COSE kid header to the RFC 7638 (SHA-256) JWK thumbprint of the
+    signing key, or to any other value supplied verbatim:
+
+      cocli corim sign  --file=unsigned-corim.cbor \
+                    --key=key.jwk \
+                    --meta=meta.json \
+                    --kid=thumbprint
+
+    Embed the public part of the signing key, as a COSE_Key, in the COSE
+    unprotected header (label -65537), so that the CoRIM can be verified
+    without having the key at hand, e.g., in lab environments (see "corim
+    verify --use-embedded-key"):
+
+      cocli corim sign  --file=unsigned-corim.cbor \
+                    --key=key.jwk \
+                    --meta=meta.json \
+                    --embed-public-key
+
+    Obtain an RFC 3161 timestamp token over the signature from the
+    time-stamping authority at https://tsa.example.com/, and embed it in the
+    COSE unprotected header (label -65538), as proof that the CoRIM was signed
+    before that time, e.g., for it to be trusted after the signing key or its
+    certificate expire (see "corim verify --tsa-trust-anchor"):
+
+      cocli corim sign  --file=unsigned-corim.cbor \
+                    --key=key.jwk \
+                    --meta=meta.json \
+                    --tsa-url=https://tsa.example.com/
+
+    Save the CBOR diagnostic notation of the signed CoRIM to signed-corim.diag,
+    alongside the signed CoRIM itself, e.g., to commit a reviewable text
+    artifact together with a test vector (use --output-format=diag to only
+    save the diagnostic notation):
+
+      cocli corim sign  --file=unsigned-corim.cbor \
+                    --key=key.jwk \
+                    --meta=meta.json \
+                    --output=signed-corim.cbor \
+                    --output-format=both
+
+    Only let the owner read the signed CoRIM (by default, it is saved with
+    mode 0644):
+
+      cocli corim sign  --file=unsigned-corim.cbor \
+                    --key=key.jwk \
+                    --meta=meta.json \
+                    --output=signed-corim.cbor \
+                    --output-mode=0600
+
+    Save the signed CoRIM to the store/ directory (created if needed), naming
+    it after its CoRIM id, e.g., store/5c57e8f4-46cd-421b-91c9-08cf93e13cfc.cbor.
+    Ids that are not safe file names are sanitized and suffixed with part of
+    their SHA-256.  A warning is printed if the file already holds another
+    CoRIM
+
+      cocli corim sign  --file=unsigned-corim.cbor \
+                    --key=key.jwk \
+                    --meta=meta.json \
+                    --label-output-by-id \
+                    --output-dir=store
+
+    Append the signed CoRIM, as the next item, to the CBOR sequence (RFC 8742)
+    in stream.cbor (created if needed), e.g., to maintain an append-only log
+    of endorsements.  Use corim verify --sequence to read them back.  Nothing
+    is appended to a stream.cbor that is not a well-formed CBOR sequence
+
+      cocli corim sign  --file=unsigned-corim.cbor \
+                    --key=key.jwk \
+                    --meta=meta.json \
+                    --append-to-sequence=stream.cbor
+
+    Merge the per-build fields in build-meta.json over the shared meta.json
+    before signing (following the JSON Merge Patch rules of RFC 7396, i.e.,
+    the values of build-meta.json take precedence):
+
+      cocli corim sign  --file=unsigned-corim.cbor \
+                    --key=key.jwk \
+                    --meta=meta.json \
+                    --additional-meta=build-meta.json
+
+    Record the signed CoRIM (its id, output path, payload SHA-256, signing
+    time and algorithm) in the JSON index in index.json, replacing any
+    previous entry with the same CoRIM id:
+
+      cocli corim sign  --file=unsigned-corim.cbor \
+                    --key=key.jwk \
+                    --meta=meta.json \
+                    --output=signed-corim.cbor \
+                    --index=index.json
+
+    Before signing, warn about measurements that are repeated (with identical
+    key and value) within the same environment of a CoMID, which is usually a
+    template bug.  Use --fail-on-duplicate-measurements to refuse to sign
+    instead:
+
+      cocli corim sign  --file=unsigned-corim.cbor \
+                    --key=key.jwk \
+                    --meta=meta.json \
+                    --check-duplicate-measurements
+
+    Make sure the CoRIM is never valid when the signing certificate is not, by
+    restricting the CoRIM Meta validity to the intersection of the requested
+    one (if any) and the validity of the signing certificate, with a warning if
+    the requested validity exceeds the certificate's:
+
+      cocli corim sign  --file=unsigned-corim.cbor \
+                    --key=key.jwk \
+                    --meta=meta.json \
+                    --cert=signing-cert.der \
+                    --meta-validity-from-cert
+
+    Re-encode the CoMID, CoSWID and CoTS tags (CoMIDs with the extensions of
+    the CoRIM profile, if any) before signing, so that fields that are not
+    understood are dropped rather than vouched for as opaque data.  A warning
+    lists each dropped field:
+
+      cocli corim sign  --file=unsigned-corim.cbor \
+                    --key=key.jwk \
+                    --meta=meta.json \
+                    --strip-unknown
+
+    Verify the signature just produced with the public part of the signing key
+    before saving the signed CoRIM, failing if the self-check does not pass:
+
+      cocli corim sign  --file=unsigned-corim.cbor \
+                    --key=key.jwk \
+                    --meta=meta.json \
+                    --verify-after-sign
+
+    During a key rotation, check that the signed CoRIM verifies with the new
+    public key and not with the old one.  Each key is reported as pass or fail,
+    and a failure does not undo the signing:
+
+      cocli corim sign  --file=unsigned-corim.cbor \
+                    --key=new-key.jwk \
+                    --meta=meta.json \
+                    --verify-against=new-key-pub.jwk \
+                    --verify-against=old-key-pub.jwk
+
+    Sign using the Ed25519 or ECDSA key with comment "release-signing" held by
+    the SSH agent listening on SSH_AUTH_SOCK, instead of a key file (the key
+    can also be selected by its SHA-256 fingerprint, e.g., "SHA256:uNiV..."):
+
+      cocli corim sign  --file=unsigned-corim.cbor \
+                    --ssh-agent \
+                    --ssh-key=release-signing \
+                    --meta=meta.json
+
+    After signing, print a summary of the signed CoRIM (input and output
+    files, id, profile, tag counts, algorithm, SHA-256 fingerprint of the
+    signing certificate and validity window), as JSON, e.g., for a release
+    pipeline to pick up:
+
+      cocli corim sign  --file=unsigned-corim.cbor \
+                    --key=key.jwk \
+                    --meta=meta.json \
+                    --cert=signing-cert.der \
+                    --summary \
+                    --format=json
+
+    In incremental builds, only sign if the unsigned CoRIM, the signing key,
+    the CoRIM Meta, the certificates or the options changed since
+    signed-corim.cbor was produced, as recorded in signed-corim.cbor.sign-state
+    (otherwise, report "unchanged, skipped"):
+
+      cocli corim sign  --file=unsigned-corim.cbor \
+                    --key=key.jwk \
+                    --meta=meta.json \
+                    --output=signed-corim.cbor \
+                    --sign-only-if-changed
+
+    Experimental: for legacy consumers that only understand CMS (PKCS #7),
+    sign unsigned-corim.cbor into a CMS SignedData, with the CoRIM CBOR as its
+    (id-data) encapsulated content, and save it (DER-encoded) to
+    signed-unsigned-corim.p7s.  This is not a standard CoRIM format, and is only
+    meant for interoperability: no CoRIM Meta is carried, and cocli cannot
+    display or verify the result
+
+      cocli corim sign  --file=unsigned-corim.cbor \
+                    --key=key.jwk \
+                    --cert=signing-cert.der \
+                    --container=cms
     `,
 
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -54,13 +416,167 @@ func NewCorimSignCmd() *cobra.Command {
 				return err
 			}
 
-			// checkCorimSignArgs makes sure corimSignCorimFile is not nil
-			coseFile, err := sign(*corimSignCorimFile, *corimSignKeyFile,
-				*corimSignMetaFile, corimSignOutputFile, corimSignCertFile, corimSignIntermediateCerts)
+			policy, err := newAlgorithmPolicy(corimSignAllowedAlgs, corimSignDeniedAlgs)
+			if err != nil {
+				return err
+			}
+
+			wrapTagged, err := wrapTaggedOption(cmd)
+			if err != nil {
+				return err
+			}
+
+			opts := signOptions{
+				reproducible:       *corimSignReproducible,
+				metaFromCorim:      *corimSignMetaFromCorim,
+				bumpValidity:       *corimSignBumpValidity,
+				algPolicy:          policy,
+				untagged:           !wrapTagged,
+				certChain:          *corimSignCertChain,
+				kid:                *corimSignKeyID,
+				embedKey:           *corimSignEmbedPublicKey,
+				outputFormat:       *corimSignOutputFormat,
+				additionalMeta:     *corimSignAdditionalMeta,
+				index:              *corimSignIndex,
+				checkDups:          *corimSignCheckDups,
+				failOnDups:         *corimSignFailOnDups,
+				verifyAfter:        *corimSignVerifyAfterSign,
+				sshAgent:           *corimSignSSHAgent,
+				sshKey:             *corimSignSSHKey,
+				stripUnknown:       *corimSignStripUnknown,
+				validityFromCert:   *corimSignValidityFromCert,
+				deterministicECDSA: *corimSignDeterministicECDSA,
+				certURL:            *corimSignCertURL,
+				certCacheDir:       *corimSignCertCacheDir,
+				outputMode:         *corimSignOutputMode,
+				appendToSequence:   *corimSignAppendToSequence,
+				tsaURL:             *corimSignTSAURL,
+			}
+
+			outputFile := corimSignOutputFile
+
+			if *corimSignLabelByID {
+				id, name, err := idOutputFileName(*corimSignCorimFile, *corimSignOutputDir)
+				if err != nil {
+					return err
+				}
+
+				if err = prepareOutputDir(*corimSignOutputDir, defaultDirMode); err != nil {
+					return err
+				}
+
+				if *corimSignOutputFormat != signOutputDiag {
+					if collision := idLabelCollision(name, id); collision != "" {
+						fmt.Printf(">> warning: %s\n", collision)
+					}
+				}
+
+				outputFile = &name
+			}
+
+			var state signState
+
+			if *corimSignOnlyIfChanged {
+				state, err = newSignState(*corimSignCorimFile, *corimSignKeyFile,
+					*corimSignMetaFile, corimSignCertFile, corimSignIntermediateCerts, opts)
+				if err != nil {
+					return err
+				}
+
+				if savedFile := savedCorimFileName(*corimSignCorimFile, outputFile, opts); signStateUnchanged(savedFile, state) {
+					fmt.Printf(">> %q unchanged, skipped\n", *corimSignCorimFile)
+					return nil
+				}
+			}
+
+			var (
+				coseFile        string
+				signedCorimCBOR []byte
+			)
+
+			cms := *corimSignContainer == signContainerCMS
+
+			var key signingKey
+			if *corimSignAuditLog != "" {
+				opts.signingKey = &key
+			}
+
+			if cms {
+				coseFile, err = signCMS(*corimSignCorimFile, *corimSignKeyFile, outputFile,
+					*corimSignCertFile, corimSignIntermediateCerts, *corimSignCertChain, *corimSignOutputMode, opts.signingKey)
+			} else {
+				// checkCorimSignArgs makes sure corimSignCorimFile is not nil and
+				// that corimSignMetaFile is only empty if --no-meta is set
+				coseFile, signedCorimCBOR, err = sign(*corimSignCorimFile, *corimSignKeyFile,
+					*corimSignMetaFile, outputFile, corimSignCertFile, corimSignIntermediateCerts, opts)
+			}
+
+			if err == nil && *corimSignOnlyIfChanged {
+				err = saveSignState(coseFile, state)
+			}
+
+			if *corimSignAuditLog != "" {
+				savedFile := coseFile
+				if savedFile == "" && *corimSignAppendToSequence != "" {
+					savedFile = *corimSignAppendToSequence
+				} else if savedFile == "" && cms {
+					savedFile = cmsFileName(*corimSignCorimFile, outputFile)
+				} else if savedFile == "" {
+					savedFile = signedCorimFileName(*corimSignCorimFile, outputFile)
+				}
+
+				rec := newSignAuditRecord(*corimSignCorimFile, savedFile, key, err)
+
+				if auditErr := appendAuditRecord(*corimSignAuditLog, rec); auditErr != nil {
+					return errors.Join(err, auditErr)
+				}
+			}
+
 			if err != nil {
 				return err
 			}
-			fmt.Printf(">> %q signed and saved to %q\n", *corimSignCorimFile, coseFile)
+			var summary *signSummary
+
+			if *corimSignSummary {
+				sum, err := newSignSummary(*corimSignCorimFile, coseFile, signedCorimCBOR)
+				if err != nil {
+					return fmt.Errorf("error summarizing signed CoRIM: %w", err)
+				}
+
+				// the JSON summary replaces the progress lines, so that it
+				// can be piped to other tools
+				if *corimSignSummaryFormat == summaryFormatJSON {
+					return sum.print(summaryFormatJSON)
+				}
+
+				summary = &sum
+			}
+
+			if cms {
+				fmt.Printf(">> %q signed into a CMS SignedData (experimental, not a standard CoRIM format) and saved to %q\n",
+					*corimSignCorimFile, coseFile)
+
+				return nil
+			}
+
+			if *corimSignAppendToSequence != "" {
+				fmt.Printf(">> %q signed and appended to the CBOR sequence as %q\n", *corimSignCorimFile, coseFile)
+			} else {
+				fmt.Printf(">> %q signed and saved to %q\n", *corimSignCorimFile, coseFile)
+			}
+
+			if *corimSignOutputFormat == signOutputBoth {
+				fmt.Printf(">> diagnostic notation saved to %q\n", diagFileName(coseFile))
+			}
+
+			// a failed check is reported but does not undo the signing
+			if len(corimSignVerifyAgainst) != 0 {
+				printVerifyAgainst(verifyAgainstKeys(signedCorimCBOR, corimSignVerifyAgainst))
+			}
+
+			if summary != nil {
+				return summary.print(summaryFormatText)
+			}
 
 			return nil
 		},
@@ -68,10 +584,97 @@ func NewCorimSignCmd() *cobra.Command {
 
 	corimSignCorimFile = cmd.Flags().StringP("file", "f", "", "an unsigned CoRIM file (in CBOR format)")
 	corimSignMetaFile = cmd.Flags().StringP("meta", "m", "", "CoRIM Meta file (in JSON format)")
+	corimSignAdditionalMeta = cmd.Flags().String(
+		"additional-meta", "", "a CoRIM Meta fragment (in JSON format) deep-merged over --meta, taking precedence over it",
+	)
 	corimSignKeyFile = cmd.Flags().StringP("key", "k", "", "signing key in JWK format")
+	corimSignSSHAgent = cmd.Flags().Bool("ssh-agent", false, "sign using a key held by the SSH agent (SSH_AUTH_SOCK), instead of --key")
+	corimSignSSHKey = cmd.Flags().String(
+		"ssh-key", "", "comment or SHA-256 fingerprint of the SSH agent key to use (with --ssh-agent, required if the agent holds more than one key)",
+	)
 	corimSignOutputFile = cmd.Flags().StringP("output", "o", "", "name of the generated COSE Sign1 file")
+	corimSignAppendToSequence = cmd.Flags().String(
+		"append-to-sequence", "", "append the signed CoRIM to this CBOR sequence file (created if needed), instead of saving it to its own file",
+	)
+	corimSignLabelByID = cmd.Flags().Bool(
+		"label-output-by-id", false, "name the signed CoRIM <corim-id>.cbor, in --output-dir, instead of signed-<file>",
+	)
+	corimSignOutputDir = cmd.Flags().String(
+		"output-dir", ".", "directory the signed CoRIM is saved to (with --label-output-by-id), created if needed",
+	)
+	corimSignContainer = cmd.Flags().String(
+		"container", signContainerCOSE, "sign into a COSE Sign1 (cose), or into a CMS SignedData for legacy consumers (cms, experimental and non-standard)",
+	)
+	corimSignOutputFormat = cmd.Flags().String("output-format", signOutputCBOR, "save the signed CoRIM as cbor, as CBOR diagnostic notation (diag), or both")
+	corimSignOutputMode = cmd.Flags().String(
+		"output-mode", defaultFileMode, "permissions (octal) of the saved signed CoRIM, also applied to an existing file that is overwritten",
+	)
 	corimSignCertFile = cmd.Flags().StringP("cert", "c", "", "signing certificate in DER format")
-	corimSignIntermediateCerts = cmd.Flags().String("intermediates", "", "intermediate certificates in DER format")
+	cmd.Flags().StringArrayVar(
+		&corimSignIntermediateCerts, "intermediates", []string{}, "intermediate certificates in DER format (can be repeated)",
+	)
+	corimSignCertChain = cmd.Flags().String("cert-chain", "", "signing certificate followed by the intermediate certificates, in a single PEM file")
+	corimSignCertURL = cmd.Flags().String(
+		"cert-url", "", "an http(s) URL from which the signing certificate (and any intermediates), in DER or PEM format, is fetched",
+	)
+	corimSignCertCacheDir = cmd.Flags().String(
+		"cert-cache-dir", "", "with --cert-url, keep a copy of the fetched certificate in this directory, and only download it again if its ETag changed",
+	)
+	corimSignTSAURL = cmd.Flags().String(
+		"tsa-url", "", "an http(s) URL of an RFC 3161 time-stamping authority, from which a timestamp token over the signature is obtained and embedded in the COSE unprotected header",
+	)
+	corimSignNoMeta = cmd.Flags().Bool("no-meta", false, "sign without a CoRIM Meta block in the COSE header")
+	corimSignMetaFromCorim = cmd.Flags().String("meta-from-corim", "", "reuse the CoRIM Meta of an existing signed CoRIM (in CBOR format)")
+	corimSignBumpValidity = cmd.Flags().Duration("bump-validity", 0, "move the validity period of the reused CoRIM Meta ahead by this amount (with --meta-from-corim)")
+	corimSignReproducible = cmd.Flags().Bool("reproducible", false, "use deterministic encoding so that signing the same inputs yields identical output")
+	corimSignDeterministicECDSA = cmd.Flags().Bool(
+		"deterministic-ecdsa", false, "derive the ECDSA signature nonce from the key and the CoRIM (RFC 6979), so that ECDSA signatures are reproducible",
+	)
+	corimSignVerifyAfterSign = cmd.Flags().Bool(
+		"verify-after-sign", false, "verify the signed CoRIM with the public part of the signing key before saving it",
+	)
+	cmd.Flags().StringArrayVar(
+		&corimSignVerifyAgainst, "verify-against", []string{}, "after signing, report whether the signed CoRIM verifies with this public key (may be repeated)",
+	)
+
+	corimSignWrapTagged = cmd.Flags().Bool("wrap-tagged", true, "wrap the COSE Sign1 in the COSE_Sign1 CBOR tag (18)")
+	corimSignNoWrapTagged = cmd.Flags().Bool("no-wrap-tagged", false, "save the COSE Sign1 without the COSE_Sign1 CBOR tag (18)")
+
+	corimSignKeyID = cmd.Flags().String("kid", "", `COSE kid header value, or "thumbprint" to use the JWK thumbprint of the signing key`)
+
+	corimSignEmbedPublicKey = cmd.Flags().Bool("embed-public-key", false, "embed the public part of the signing key, as a COSE_Key, in the COSE unprotected header")
+
+	corimSignOnlyIfChanged = cmd.Flags().Bool(
+		"sign-only-if-changed", false, "skip signing if the output was already signed from the same inputs and key (recorded in an <output>.sign-state file)",
+	)
+	corimSignSummary = cmd.Flags().Bool("summary", false, "print a summary of the signed CoRIM after signing")
+	corimSignSummaryFormat = cmd.Flags().String("format", summaryFormatText, "format of the --summary: text or json")
+
+	corimSignAuditLog = cmd.Flags().String("audit-log", "", "append a JSON record of the signing operation to this file")
+	corimSignIndex = cmd.Flags().String("index", "", "add or update the entry of the signed CoRIM in this JSON index, keyed by CoRIM id")
+
+	corimSignCheckDups = cmd.Flags().Bool(
+		"check-duplicate-measurements", false, "warn about measurements repeated within the same environment of a CoMID",
+	)
+	corimSignFailOnDups = cmd.Flags().Bool(
+		"fail-on-duplicate-measurements", false, "refuse to sign if measurements are repeated within the same environment of a CoMID",
+	)
+
+	corimSignValidityFromCert = cmd.Flags().Bool(
+		"meta-validity-from-cert", false, "restrict the CoRIM Meta validity to that of the signing certificate (with a warning if it exceeds it)",
+	)
+
+	corimSignStripUnknown = cmd.Flags().Bool(
+		"strip-unknown", false, "drop the fields of the CoRIM and its tags that are not understood (with a warning) before signing",
+	)
+
+	cmd.Flags().StringSliceVar(
+		&corimSignAllowedAlgs, "allowed-algs", []string{}, "refuse to sign unless the algorithm of the key is one of these (e.g., ES384,ES512)",
+	)
+
+	cmd.Flags().StringSliceVar(
+		&corimSignDeniedAlgs, "denied-algs", []string{}, "refuse to sign if the algorithm of the key is one of these (e.g., ES256)",
+	)
 
 	return cmd
 }
@@ -81,113 +684,770 @@ func checkCorimSignArgs() error {
 		return errors.New("no CoRIM supplied")
 	}
 
-	if corimSignKeyFile == nil || *corimSignKeyFile == "" {
-		return errors.New("no key supplied")
+	if corimSignContainer != nil {
+		switch *corimSignContainer {
+		case signContainerCOSE:
+		case signContainerCMS:
+			return checkCorimSignCMSArgs()
+		default:
+			return fmt.Errorf("invalid --container %q: expecting cose or cms", *corimSignContainer)
+		}
 	}
 
-	if corimSignMetaFile == nil || *corimSignMetaFile == "" {
-		return errors.New("no CoRIM Meta supplied")
+	hasKey := corimSignKeyFile != nil && *corimSignKeyFile != ""
+	sshAgent := corimSignSSHAgent != nil && *corimSignSSHAgent
+
+	if hasKey && sshAgent {
+		return errors.New("--key cannot be used together with --ssh-agent")
 	}
 
-	return nil
-}
+	if !hasKey && !sshAgent {
+		return errors.New("no key supplied")
+	}
 
-func sign(unsignedCorimFile, keyFile, metaFile string, outputFile, certFile, intermediatesFile *string) (string, error) {
-	var (
-		unsignedCorimCBOR []byte
-		signedCorimCBOR   []byte
-		metaJSON          []byte
-		keyJWK            []byte
-		certDER           []byte
-		intermediatesDER  []byte
-		err               error
-		signedCorimFile   string
-		c                 corim.UnsignedCorim
-		m                 corim.Meta
-		signer            cose.Signer
-	)
+	if !sshAgent && corimSignSSHKey != nil && *corimSignSSHKey != "" {
+		return errors.New("--ssh-key can only be used together with --ssh-agent")
+	}
 
-	if unsignedCorimCBOR, err = afero.ReadFile(fs, unsignedCorimFile); err != nil {
-		return "", fmt.Errorf("error loading unsigned CoRIM from %s: %w", unsignedCorimFile, err)
+	// the SSH agent computes the signature itself
+	if sshAgent && corimSignDeterministicECDSA != nil && *corimSignDeterministicECDSA {
+		return errors.New("--deterministic-ecdsa cannot be used together with --ssh-agent")
 	}
 
-	if err = c.FromCBOR(unsignedCorimCBOR); err != nil {
-		return "", fmt.Errorf("error decoding unsigned CoRIM from %s: %w", unsignedCorimFile, err)
+	if corimSignCertChain != nil && *corimSignCertChain != "" &&
+		((corimSignCertFile != nil && *corimSignCertFile != "") || len(corimSignIntermediateCerts) != 0) {
+		return errors.New("--cert-chain cannot be used together with --cert or --intermediates")
 	}
 
-	if err = c.Valid(); err != nil {
-		return "", fmt.Errorf("error validating CoRIM: %w", err)
+	if err := checkCorimSignCertURLArgs(); err != nil {
+		return err
 	}
 
-	if metaJSON, err = afero.ReadFile(fs, metaFile); err != nil {
-		return "", fmt.Errorf("error loading CoRIM Meta from %s: %w", metaFile, err)
+	if err := checkCorimSignTSAArgs(); err != nil {
+		return err
 	}
 
-	if err = m.FromJSON(metaJSON); err != nil {
-		return "", fmt.Errorf("error decoding CoRIM Meta from %s: %w", metaFile, err)
+	noMeta := corimSignNoMeta != nil && *corimSignNoMeta
+	hasMeta := corimSignMetaFile != nil && *corimSignMetaFile != ""
+	metaFromCorim := corimSignMetaFromCorim != nil && *corimSignMetaFromCorim != ""
+
+	if noMeta && hasMeta {
+		return errors.New("--meta cannot be used together with --no-meta")
 	}
 
-	if err = m.Valid(); err != nil {
-		return "", fmt.Errorf("error validating CoRIM Meta: %w", err)
+	if metaFromCorim && (noMeta || hasMeta) {
+		return errors.New("--meta-from-corim cannot be used together with --meta or --no-meta")
 	}
 
-	if keyJWK, err = afero.ReadFile(fs, keyFile); err != nil {
-		return "", fmt.Errorf("error loading signing key from %s: %w", keyFile, err)
+	if !noMeta && !hasMeta && !metaFromCorim {
+		return errors.New("no CoRIM Meta supplied")
 	}
 
-	if signer, err = corim.NewSignerFromJWK(keyJWK); err != nil {
-		return "", fmt.Errorf("error loading signing key from %s: %w", keyFile, err)
+	if !hasMeta && corimSignAdditionalMeta != nil && *corimSignAdditionalMeta != "" {
+		return errors.New("--additional-meta can only be used together with --meta")
 	}
 
-	s := corim.SignedCorim{
-		UnsignedCorim: c,
-		Meta:          m,
+	if !metaFromCorim && corimSignBumpValidity != nil && *corimSignBumpValidity != 0 {
+		return errors.New("--bump-validity can only be used together with --meta-from-corim")
 	}
 
-	// Add signing certificate if provided
+	if corimSignValidityFromCert != nil && *corimSignValidityFromCert {
+		if noMeta {
+			return errors.New("--meta-validity-from-cert cannot be used together with --no-meta")
+		}
+
+		if (corimSignCertFile == nil || *corimSignCertFile == "") &&
+			(corimSignCertChain == nil || *corimSignCertChain == "") &&
+			(corimSignCertURL == nil || *corimSignCertURL == "") {
+			return errors.New("--meta-validity-from-cert requires --cert, --cert-chain or --cert-url")
+		}
+	}
+
+	if err := checkCorimSignAppendToSequenceArgs(); err != nil {
+		return err
+	}
+
+	if corimSignLabelByID != nil && *corimSignLabelByID {
+		if corimSignOutputFile != nil && *corimSignOutputFile != "" {
+			return errors.New("--label-output-by-id cannot be used together with --output")
+		}
+	} else if corimSignOutputDir != nil && *corimSignOutputDir != "." {
+		return errors.New("--output-dir can only be used together with --label-output-by-id")
+	}
+
+	if corimSignOutputMode != nil {
+		if _, err := parseFileMode(*corimSignOutputMode); err != nil {
+			return err
+		}
+	}
+
+	if corimSignOutputFormat != nil {
+		switch *corimSignOutputFormat {
+		case signOutputCBOR, signOutputDiag, signOutputBoth:
+		default:
+			return fmt.Errorf("invalid --output-format %q: expecting cbor, diag or both", *corimSignOutputFormat)
+		}
+	}
+
+	if corimSignSummaryFormat != nil {
+		switch *corimSignSummaryFormat {
+		case summaryFormatText, summaryFormatJSON:
+		default:
+			return fmt.Errorf("invalid --format %q: expecting text or json", *corimSignSummaryFormat)
+		}
+
+		if *corimSignSummaryFormat != summaryFormatText && (corimSignSummary == nil || !*corimSignSummary) {
+			return errors.New("--format can only be used together with --summary")
+		}
+
+		if *corimSignSummaryFormat == summaryFormatJSON && len(corimSignVerifyAgainst) != 0 {
+			return errors.New("--verify-against cannot be used together with --format=json")
+		}
+	}
+
+	return nil
+}
+
+// checkCorimSignCertURLArgs checks the consistency of --cert-url and
+// --cert-cache-dir with the other switches
+func checkCorimSignCertURLArgs() error {
+	if corimSignCertURL == nil || *corimSignCertURL == "" {
+		if corimSignCertCacheDir != nil && *corimSignCertCacheDir != "" {
+			return errors.New("--cert-cache-dir can only be used together with --cert-url")
+		}
+
+		return nil
+	}
+
+	if (corimSignCertFile != nil && *corimSignCertFile != "") ||
+		(corimSignCertChain != nil && *corimSignCertChain != "") ||
+		len(corimSignIntermediateCerts) != 0 {
+		return errors.New("--cert-url cannot be used together with --cert, --cert-chain or --intermediates")
+	}
+
+	// the recorded sign state does not cover the fetched certificate
+	if corimSignOnlyIfChanged != nil && *corimSignOnlyIfChanged {
+		return errors.New("--sign-only-if-changed cannot be used together with --cert-url")
+	}
+
+	if err := checkOnline("--cert-url"); err != nil {
+		return err
+	}
+
+	return checkHTTPURL("--cert-url", *corimSignCertURL)
+}
+
+// checkCorimSignTSAArgs checks the consistency of --tsa-url with the other
+// switches
+func checkCorimSignTSAArgs() error {
+	if corimSignTSAURL == nil || *corimSignTSAURL == "" {
+		return nil
+	}
+
+	// the timestamp token differs each time
+	if corimSignReproducible != nil && *corimSignReproducible {
+		return errors.New("--tsa-url cannot be used together with --reproducible")
+	}
+
+	if err := checkOnline("--tsa-url"); err != nil {
+		return err
+	}
+
+	return checkHTTPURL("--tsa-url", *corimSignTSAURL)
+}
+
+// checkCorimSignAppendToSequenceArgs checks that --append-to-sequence is not
+// combined with the switches that assume the signed CoRIM has a file of its own
+func checkCorimSignAppendToSequenceArgs() error {
+	if corimSignAppendToSequence == nil || *corimSignAppendToSequence == "" {
+		return nil
+	}
+
+	if (corimSignOutputFile != nil && *corimSignOutputFile != "") ||
+		(corimSignLabelByID != nil && *corimSignLabelByID) {
+		return errors.New("--append-to-sequence cannot be used together with --output or --label-output-by-id")
+	}
+
+	if corimSignOutputFormat != nil && *corimSignOutputFormat != signOutputCBOR {
+		return errors.New("--append-to-sequence can only be used together with --output-format=cbor")
+	}
+
+	// both refer to the signed CoRIM by the name of its file
+	if (corimSignOnlyIfChanged != nil && *corimSignOnlyIfChanged) ||
+		(corimSignIndex != nil && *corimSignIndex != "") {
+		return errors.New("--append-to-sequence cannot be used together with --sign-only-if-changed or --index")
+	}
+
+	return nil
+}
+
+// checkCorimSignCMSArgs checks the arguments of corim sign --container=cms,
+// which only supports the options that make sense for a CMS SignedData
+func checkCorimSignCMSArgs() error {
+	if corimSignKeyFile == nil || *corimSignKeyFile == "" {
+		return errors.New("--container=cms requires --key")
+	}
+
+	hasCert := corimSignCertFile != nil && *corimSignCertFile != ""
+	hasCertChain := corimSignCertChain != nil && *corimSignCertChain != ""
+
+	if hasCertChain && (hasCert || len(corimSignIntermediateCerts) != 0) {
+		return errors.New("--cert-chain cannot be used together with --cert or --intermediates")
+	}
+
+	if !hasCert && !hasCertChain {
+		return errors.New("--container=cms requires --cert or --cert-chain, as CMS identifies the signer by its certificate")
+	}
+
+	if (corimSignMetaFile != nil && *corimSignMetaFile != "") ||
+		(corimSignMetaFromCorim != nil && *corimSignMetaFromCorim != "") ||
+		(corimSignAdditionalMeta != nil && *corimSignAdditionalMeta != "") {
+		return errors.New("--container=cms cannot carry a CoRIM Meta: --meta, --meta-from-corim and --additional-meta cannot be used")
+	}
+
+	if (corimSignSSHAgent != nil && *corimSignSSHAgent) ||
+		(corimSignSSHKey != nil && *corimSignSSHKey != "") ||
+		(corimSignBumpValidity != nil && *corimSignBumpValidity != 0) ||
+		(corimSignValidityFromCert != nil && *corimSignValidityFromCert) ||
+		(corimSignKeyID != nil && *corimSignKeyID != "") ||
+		(corimSignEmbedPublicKey != nil && *corimSignEmbedPublicKey) ||
+		(corimSignWrapTagged != nil && !*corimSignWrapTagged) ||
+		(corimSignNoWrapTagged != nil && *corimSignNoWrapTagged) ||
+		(corimSignOutputFormat != nil && *corimSignOutputFormat != signOutputCBOR) ||
+		(corimSignReproducible != nil && *corimSignReproducible) ||
+		(corimSignDeterministicECDSA != nil && *corimSignDeterministicECDSA) ||
+		(corimSignVerifyAfterSign != nil && *corimSignVerifyAfterSign) ||
+		len(corimSignVerifyAgainst) != 0 ||
+		(corimSignCertURL != nil && *corimSignCertURL != "") ||
+		(corimSignCertCacheDir != nil && *corimSignCertCacheDir != "") ||
+		(corimSignTSAURL != nil && *corimSignTSAURL != "") ||
+		(corimSignAppendToSequence != nil && *corimSignAppendToSequence != "") ||
+		(corimSignSummary != nil && *corimSignSummary) ||
+		(corimSignOnlyIfChanged != nil && *corimSignOnlyIfChanged) ||
+		(corimSignIndex != nil && *corimSignIndex != "") ||
+		(corimSignCheckDups != nil && *corimSignCheckDups) ||
+		(corimSignFailOnDups != nil && *corimSignFailOnDups) ||
+		(corimSignStripUnknown != nil && *corimSignStripUnknown) ||
+		(corimSignLabelByID != nil && *corimSignLabelByID) ||
+		(corimSignOutputDir != nil && *corimSignOutputDir != ".") ||
+		len(corimSignAllowedAlgs) != 0 || len(corimSignDeniedAlgs) != 0 {
+		return errors.New("--container=cms can only be combined with --key, --cert, --intermediates, --cert-chain, --no-meta, --output, --output-mode and --audit-log")
+	}
+
+	if corimSignOutputMode != nil {
+		if _, err := parseFileMode(*corimSignOutputMode); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// wrapTaggedOption reconciles the --wrap-tagged and --no-wrap-tagged switches
+// of cmd, returning whether the COSE Sign1 should be tagged
+func wrapTaggedOption(cmd *cobra.Command) (bool, error) {
+	if cmd.Flags().Changed("wrap-tagged") && cmd.Flags().Changed("no-wrap-tagged") {
+		return false, errors.New("--wrap-tagged cannot be used together with --no-wrap-tagged")
+	}
+
+	return *corimSignWrapTagged && !*corimSignNoWrapTagged, nil
+}
+
+// sign signs unsignedCorimFile and saves the result, according to opts.  It
+// returns the name of the saved file together with the signed CoRIM.
+func sign(unsignedCorimFile, keyFile, metaFile string, outputFile, certFile *string, intermediatesFiles []string, opts signOptions) (string, []byte, error) {
+	var (
+		signedCorimCBOR []byte
+		err             error
+		signedCorimFile string
+	)
+
+	signedCorimCBOR, err = signCorim(unsignedCorimFile, keyFile, metaFile, certFile, intermediatesFiles, opts)
+	if err != nil {
+		return "", nil, err
+	}
+
+	signedCorimFile = signedCorimFileName(unsignedCorimFile, outputFile)
+
+	mode := defaultFileMode
+	if opts.outputMode != "" {
+		mode = opts.outputMode
+	}
+
+	perm, err := parseFileMode(mode)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if opts.appendToSequence != "" {
+		n, err := appendToCBORSequence(opts.appendToSequence, signedCorimCBOR, perm)
+		if err != nil {
+			return "", nil, err
+		}
+
+		return sequenceItemName(opts.appendToSequence, n), signedCorimCBOR, nil
+	}
+
+	if opts.outputFormat == signOutputDiag || opts.outputFormat == signOutputBoth {
+		if err = saveDiag(diagFileName(signedCorimFile), signedCorimCBOR, perm); err != nil {
+			return "", nil, err
+		}
+	}
+
+	savedFile := diagFileName(signedCorimFile)
+
+	if opts.outputFormat != signOutputDiag {
+		if err = writeFileMode(signedCorimFile, signedCorimCBOR, perm); err != nil {
+			return "", nil, fmt.Errorf("error saving signed CoRIM to file %s: %w", signedCorimFile, err)
+		}
+
+		savedFile = signedCorimFile
+	}
+
+	if opts.index != "" {
+		if err = recordInCorimIndex(opts.index, savedFile, signedCorimCBOR); err != nil {
+			return "", nil, err
+		}
+	}
+
+	return savedFile, signedCorimCBOR, nil
+}
+
+// savedCorimFileName returns the name of the file sign saves the signed CoRIM
+// to, given opts.outputFormat: the diagnostic notation file with
+// --output-format=diag, or else the signed CoRIM file
+func savedCorimFileName(unsignedCorimFile string, outputFile *string, opts signOptions) string {
+	signedCorimFile := signedCorimFileName(unsignedCorimFile, outputFile)
+
+	if opts.outputFormat == signOutputDiag {
+		return diagFileName(signedCorimFile)
+	}
+
+	return signedCorimFile
+}
+
+// diagFileName returns the name of the file the diagnostic notation of the
+// signed CoRIM saved to signedCorimFile goes to
+func diagFileName(signedCorimFile string) string {
+	return makeFileName(filepath.Dir(signedCorimFile), signedCorimFile, ".diag")
+}
+
+// saveDiag saves the diagnostic notation of the signed CoRIM signedCorimCBOR,
+// with the embedded CBOR (e.g., the protected header and payload) expanded,
+// to file, with permissions perm
+func saveDiag(file string, signedCorimCBOR []byte, perm os.FileMode) error {
+	diag, err := toDiag(signedCorimCBOR, true)
+	if err != nil {
+		return fmt.Errorf("error converting signed CoRIM to diagnostic notation: %w", err)
+	}
+
+	if err = writeFileMode(file, []byte(diag+"\n"), perm); err != nil {
+		return fmt.Errorf("error saving diagnostic notation to %s: %w", file, err)
+	}
+
+	return nil
+}
+
+// signedCorimFileName returns the name of the file the signed CoRIM is saved
+// to: outputFile, if set, or else a name derived from that of the unsigned CoRIM
+func signedCorimFileName(unsignedCorimFile string, outputFile *string) string {
+	if outputFile == nil || *outputFile == "" {
+		return "signed-" + unsignedCorimFile
+	}
+
+	return *outputFile
+}
+
+// signCorim loads the unsigned CoRIM, the CoRIM Meta and the signing key (plus
+// the optional certificate chain) and returns the resulting COSE Sign1.  If
+// metaFile is empty, the CoRIM Meta is taken from opts.metaFromCorim or, if
+// that is also empty, the COSE Sign1 is produced without a CoRIM Meta header.
+func signCorim(unsignedCorimFile, keyFile, metaFile string, certFile *string, intermediatesFiles []string, opts signOptions) ([]byte, error) {
+	var (
+		unsignedCorimCBOR []byte
+		signedCorimCBOR   []byte
+		metaJSON          []byte
+		keyJWK            []byte
+		certDER           []byte
+		kid               []byte
+		embeddedKey       *cose.Key
+		err               error
+		c                 corim.UnsignedCorim
+		m                 corim.Meta
+		signer            cose.Signer
+		pub               crypto.PublicKey
+	)
+
+	if unsignedCorimCBOR, err = afero.ReadFile(fs, unsignedCorimFile); err != nil {
+		return nil, fmt.Errorf("error loading unsigned CoRIM from %s: %w", unsignedCorimFile, err)
+	}
+
+	if err = c.FromCBOR(unsignedCorimCBOR); err != nil {
+		return nil, fmt.Errorf("error decoding unsigned CoRIM from %s: %w", unsignedCorimFile, err)
+	}
+
+	if opts.stripUnknown {
+		dropped, err := stripUnknownFields(&c, unsignedCorimCBOR)
+		if err != nil {
+			return nil, fmt.Errorf("error stripping unknown fields from %s: %w", unsignedCorimFile, err)
+		}
+
+		for _, p := range dropped {
+			fmt.Printf(">> warning: dropped unknown field %q from %s\n", p, unsignedCorimFile)
+		}
+	}
+
+	if err = c.Valid(); err != nil {
+		return nil, fmt.Errorf("error validating CoRIM: %w", err)
+	}
+
+	if opts.checkDups || opts.failOnDups {
+		if err = checkDuplicateMeasurements(c, unsignedCorimFile, opts.failOnDups); err != nil {
+			return nil, err
+		}
+	}
+
+	withMeta := metaFile != "" || opts.metaFromCorim != ""
+
+	if opts.metaFromCorim != "" {
+		if err = loadMetaFromCorim(&m, opts.metaFromCorim, opts.bumpValidity); err != nil {
+			return nil, err
+		}
+	} else if metaFile != "" {
+		if metaJSON, err = afero.ReadFile(fs, metaFile); err != nil {
+			return nil, fmt.Errorf("error loading CoRIM Meta from %s: %w", metaFile, err)
+		}
+
+		if opts.additionalMeta != "" {
+			if metaJSON, err = mergeMetaJSON(metaJSON, opts.additionalMeta); err != nil {
+				return nil, err
+			}
+		}
+
+		if err = m.FromJSON(metaJSON); err != nil {
+			return nil, fmt.Errorf("error decoding CoRIM Meta from %s: %w", metaFile, err)
+		}
+
+		if err = m.Valid(); err != nil {
+			return nil, fmt.Errorf("error validating CoRIM Meta: %w", err)
+		}
+	}
+
+	// keyFile is empty when signing with the SSH agent
+	keyName := keyFile
+
+	if opts.sshAgent {
+		ag, conn, err := dialSSHAgent()
+		if err != nil {
+			return nil, fmt.Errorf("error connecting to the SSH agent: %w", err)
+		}
+		defer conn.Close()
+
+		agentSigner, err := newSSHAgentSigner(ag, opts.sshKey)
+		if err != nil {
+			return nil, fmt.Errorf("error loading signing key from the SSH agent: %w", err)
+		}
+
+		signer, pub, keyName = agentSigner, agentSigner.pub, agentSigner.String()
+	} else {
+		if keyJWK, err = afero.ReadFile(fs, keyFile); err != nil {
+			return nil, fmt.Errorf("error loading signing key from %s: %w", keyFile, err)
+		}
+
+		if opts.deterministicECDSA {
+			signer, err = newDeterministicECDSASigner(keyJWK)
+		} else {
+			signer, err = corim.NewSignerFromJWK(keyJWK)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error loading signing key from %s: %w", keyFile, err)
+		}
+
+		if pub, err = corim.NewPublicKeyFromJWK(keyJWK); err != nil {
+			return nil, fmt.Errorf("error loading signing key from %s: %w", keyFile, err)
+		}
+	}
+
+	if opts.signingKey != nil {
+		*opts.signingKey = signingKey{alg: signer.Algorithm().String(), pub: pub}
+	}
+
+	if err = opts.algPolicy.check(signer.Algorithm()); err != nil {
+		return nil, fmt.Errorf("error signing CoRIM with key %s: %w", keyName, err)
+	}
+
+	if opts.kid != "" {
+		if kid, err = signingKeyID(opts.kid, pub); err != nil {
+			return nil, fmt.Errorf("error deriving kid from signing key %s: %w", keyName, err)
+		}
+	}
+
+	if opts.embedKey {
+		if embeddedKey, err = embeddedPublicKey(pub); err != nil {
+			return nil, fmt.Errorf("error embedding public key of signing key %s: %w", keyName, err)
+		}
+	}
+
+	s := corim.SignedCorim{
+		UnsignedCorim: c,
+		Meta:          m,
+	}
+
+	// Add signing certificate if provided
 	if certFile != nil && *certFile != "" {
 		if certDER, err = afero.ReadFile(fs, *certFile); err != nil {
-			return "", fmt.Errorf("error loading signing certificate from %s: %w", *certFile, err)
+			return nil, fmt.Errorf("error loading signing certificate from %s: %w", *certFile, err)
 		}
 
 		if err = s.AddSigningCert(certDER); err != nil {
-			return "", fmt.Errorf("error adding signing certificate: %w", err)
+			return nil, fmt.Errorf("error adding signing certificate: %w", err)
 		}
 	}
 
 	// Add intermediate certificates if provided
-	if intermediatesFile != nil && *intermediatesFile != "" {
+	if len(intermediatesFiles) != 0 {
 		// Ensure signing certificate was provided
 		if certFile == nil || *certFile == "" {
-			return "", fmt.Errorf("cannot add intermediate certificates without a signing certificate")
+			return nil, fmt.Errorf("cannot add intermediate certificates without a signing certificate")
 		}
 
-		if intermediatesDER, err = afero.ReadFile(fs, *intermediatesFile); err != nil {
-			return "", fmt.Errorf("error loading intermediate certificates from %s: %w", *intermediatesFile, err)
+		if err = addIntermediateCertFiles(&s, intermediatesFiles); err != nil {
+			return nil, err
 		}
+	}
 
-		if err = s.AddIntermediateCerts(intermediatesDER); err != nil {
-			return "", fmt.Errorf("error adding intermediate certificates: %w", err)
+	if opts.certChain != "" {
+		if err = addCertChainFile(&s, opts.certChain); err != nil {
+			return nil, err
 		}
 	}
 
-	signedCorimCBOR, err = s.Sign(signer)
-	if err != nil {
-		return "", fmt.Errorf("error signing CoRIM: %w", err)
+	if opts.certURL != "" {
+		if err = addCertURL(&s, opts.certURL, opts.certCacheDir, pub); err != nil {
+			return nil, err
+		}
 	}
 
-	if outputFile == nil || *outputFile == "" {
-		signedCorimFile = "signed-" + unsignedCorimFile
+	if opts.signingKey != nil {
+		opts.signingKey.cert = s.SigningCert
+	}
+
+	if s.SigningCert != nil {
+		if err = checkSigningKeyUsage(s.SigningCert); err != nil {
+			fmt.Printf(">> warning: %v\n", err)
+		}
+	}
+
+	if opts.validityFromCert {
+		// checkCorimSignArgs makes sure that a signing certificate and a
+		// CoRIM Meta are supplied
+		if s.SigningCert == nil {
+			return nil, errors.New("no signing certificate to take the CoRIM Meta validity from")
+		}
+
+		if err = clampMetaValidity(&s.Meta, s.SigningCert); err != nil {
+			return nil, fmt.Errorf("error setting CoRIM Meta validity: %w", err)
+		}
+	}
+
+	if opts.reproducible && signer.Algorithm() != cose.AlgorithmEdDSA && !opts.deterministicECDSA {
+		fmt.Printf(">> warning: %s signatures are not deterministic, use an Ed25519 key (or --deterministic-ecdsa with an ECDSA key) for reproducible output\n",
+			signer.Algorithm())
+	}
+
+	if !withMeta || opts.reproducible || kid != nil || embeddedKey != nil {
+		signedCorimCBOR, err = signCOSE(&s, signer, withMeta, opts.reproducible, kid, embeddedKey)
 	} else {
-		signedCorimFile = *outputFile
+		signedCorimCBOR, err = s.Sign(signer)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error signing CoRIM: %w", err)
+	}
+
+	if opts.verifyAfter {
+		if err = verifyAfterSign(signedCorimCBOR, pub, signer.Algorithm()); err != nil {
+			return nil, fmt.Errorf("error self-verifying signed CoRIM with key %s: %w", keyName, err)
+		}
+	}
+
+	if opts.tsaURL != "" {
+		var tst *timestampToken
+
+		if signedCorimCBOR, tst, err = addTimestamp(signedCorimCBOR, opts.tsaURL, cliConfig.Auth); err != nil {
+			return nil, err
+		}
+
+		fmt.Printf(">> signature timestamped %s by %q\n", tst.info.GenTime.UTC().Format(time.RFC3339), tst.tsaName())
+	}
+
+	if opts.untagged {
+		signedCorimCBOR = untagSign1(signedCorimCBOR)
+	}
+
+	return signedCorimCBOR, nil
+}
+
+// verifyAfterSign re-parses the freshly signed CoRIM signedCorimCBOR and
+// verifies its signature with pkey, the public part of the signing key
+func verifyAfterSign(signedCorimCBOR []byte, pkey crypto.PublicKey, alg cose.Algorithm) error {
+	msg, err := decodeSign1(signedCorimCBOR)
+	if err != nil {
+		return err
+	}
+
+	verifier, err := cose.NewVerifier(alg, pkey)
+	if err != nil {
+		return err
+	}
+
+	return msg.Verify(corim.NoExternalData, verifier)
+}
+
+// checkDuplicateMeasurements warns about the duplicate measurements in c or,
+// if fail is set, returns an error listing them
+func checkDuplicateMeasurements(c corim.UnsignedCorim, unsignedCorimFile string, fail bool) error {
+	dups, err := duplicateMeasurements(c)
+	if err != nil {
+		return fmt.Errorf("error checking duplicate measurements in %s: %w", unsignedCorimFile, err)
+	}
+
+	if len(dups) == 0 {
+		return nil
+	}
+
+	if fail {
+		return fmt.Errorf("duplicate measurements in %s: %w", unsignedCorimFile, errors.Join(dups...))
+	}
+
+	for _, d := range dups {
+		fmt.Printf(">> warning: duplicate measurement: %v\n", d)
+	}
+
+	return nil
+}
+
+// mergeMetaJSON returns the CoRIM Meta in metaJSON with the fragment in
+// additionalMetaFile merged over it
+func mergeMetaJSON(metaJSON []byte, additionalMetaFile string) ([]byte, error) {
+	fragment, err := afero.ReadFile(fs, additionalMetaFile)
+	if err != nil {
+		return nil, fmt.Errorf("error loading additional CoRIM Meta from %s: %w", additionalMetaFile, err)
+	}
+
+	merged, err := mergeJSON(metaJSON, fragment)
+	if err != nil {
+		return nil, fmt.Errorf("error merging additional CoRIM Meta from %s: %w", additionalMetaFile, err)
+	}
+
+	return merged, nil
+}
+
+// loadMetaFromCorim sets m to the CoRIM Meta found in the signed CoRIM file,
+// with its validity period moved ahead by bump
+func loadMetaFromCorim(m *corim.Meta, file string, bump time.Duration) error {
+	var (
+		data []byte
+		s    corim.SignedCorim
+		err  error
+	)
+
+	if data, err = afero.ReadFile(fs, file); err != nil {
+		return fmt.Errorf("error loading signed CoRIM from %s: %w", file, err)
+	}
+
+	if err = s.FromCOSE(data); err != nil {
+		return fmt.Errorf("error loading CoRIM Meta from signed CoRIM %s: %w", file, err)
+	}
+
+	*m = s.Meta
+
+	if bump != 0 {
+		if m.Validity == nil {
+			return fmt.Errorf("cannot bump validity: CoRIM Meta in %s has no validity", file)
+		}
+
+		m.Validity.NotAfter = m.Validity.NotAfter.Add(bump)
+		if m.Validity.NotBefore != nil {
+			nb := m.Validity.NotBefore.Add(bump)
+			m.Validity.NotBefore = &nb
+		}
+	}
+
+	if err = m.Valid(); err != nil {
+		return fmt.Errorf("error validating CoRIM Meta: %w", err)
+	}
+
+	return nil
+}
+
+// signCOSE is like corim.SignedCorim.Sign, except that the CoRIM Meta header
+// is only included if withMeta is set, the kid header is set to kid unless it
+// is nil, the COSE_Key embeddedKey (if not nil) is added to the unprotected
+// header and, if deterministic is set, both the payload and the CoRIM Meta are
+// re-encoded using deterministic CBOR
+func signCOSE(s *corim.SignedCorim, signer cose.Signer, withMeta, deterministic bool, kid []byte, embeddedKey *cose.Key) ([]byte, error) {
+	var err error
+
+	msg := cose.NewSign1Message()
+
+	if msg.Payload, err = s.UnsignedCorim.ToCBOR(); err != nil {
+		return nil, fmt.Errorf("failed CBOR encoding of unsigned CoRIM: %w", err)
+	}
+
+	if deterministic {
+		if msg.Payload, err = deterministicCBOR(msg.Payload); err != nil {
+			return nil, fmt.Errorf("failed deterministic encoding of unsigned CoRIM: %w", err)
+		}
+	}
+
+	msg.Headers.Protected.SetAlgorithm(signer.Algorithm())
+	msg.Headers.Protected[cose.HeaderLabelContentType] = corim.ContentType
+
+	if kid != nil {
+		msg.Headers.Protected[cose.HeaderLabelKeyID] = kid
+	}
+
+	if embeddedKey != nil {
+		msg.Headers.Unprotected[headerLabelCOSEKey] = embeddedKey
+	}
+
+	if withMeta {
+		metaCBOR, err := s.Meta.ToCBOR()
+		if err != nil {
+			return nil, fmt.Errorf("failed CBOR encoding of CoRIM Meta: %w", err)
+		}
+
+		if deterministic {
+			if metaCBOR, err = deterministicCBOR(metaCBOR); err != nil {
+				return nil, fmt.Errorf("failed deterministic encoding of CoRIM Meta: %w", err)
+			}
+		}
+
+		msg.Headers.Protected[corim.HeaderLabelCorimMeta] = metaCBOR
+	}
+
+	if s.SigningCert != nil {
+		if len(s.IntermediateCerts) == 0 {
+			msg.Headers.Protected[cose.HeaderLabelX5Chain] = s.SigningCert.Raw
+		} else {
+			certChain := [][]byte{s.SigningCert.Raw}
+			for _, cert := range s.IntermediateCerts {
+				certChain = append(certChain, cert.Raw)
+			}
+			msg.Headers.Protected[cose.HeaderLabelX5Chain] = certChain
+		}
+	}
+
+	if err = msg.Sign(rand.Reader, corim.NoExternalData, signer); err != nil {
+		return nil, fmt.Errorf("COSE Sign1 signature failed: %w", err)
 	}
 
-	err = afero.WriteFile(fs, signedCorimFile, signedCorimCBOR, 0644)
+	wrap, err := msg.MarshalCBOR()
 	if err != nil {
-		return "", fmt.Errorf("error saving signed CoRIM to file %s: %w", signedCorimFile, err)
+		return nil, fmt.Errorf("signed-corim marshaling failed: %w", err)
 	}
 
-	return signedCorimFile, nil
+	return wrap, nil
 }
 
 func init() {
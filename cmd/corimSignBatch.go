@@ -0,0 +1,139 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+var (
+	corimSignBatchKeyFile           *string
+	corimSignBatchMetaFile          *string
+	corimSignBatchOutputFile        *string
+	corimSignBatchCertFile          *string
+	corimSignBatchIntermediateCerts []string
+	corimSignBatchInputGlobs        []string
+)
+
+var corimSignBatchCmd = NewCorimSignBatchCmd()
+
+func NewCorimSignBatchCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sign-batch [flags] [corim-file ...]",
+		Short: "sign a number of unsigned CoRIMs and pack them into a single bundle",
+		Long: `sign a number of unsigned CoRIMs and pack them into a single bundle
+
+    Sign the unsigned CoRIMs c1.cbor and c2.cbor using the key in JWK format
+    from file key.jwk and the CorimMeta information from file meta.json.  The
+    resulting COSE Sign1 messages are packed into a CBOR array and saved to
+    bundle.cbor.  Each CoRIM in the bundle retains its own signature.
+
+      cocli corim sign-batch --key=key.jwk \
+                    --meta=meta.json \
+                    --output=bundle.cbor \
+                    c1.cbor c2.cbor
+
+    Sign every unsigned CoRIM found anywhere below the releases directory,
+    whatever the shell in use:
+
+      cocli corim sign-batch --key=key.jwk \
+                    --meta=meta.json \
+                    --output=bundle.cbor \
+                    --input-glob='releases/**/*.cbor'
+
+    Use "cocli corim unpack" to split the bundle back into individual files.
+    `,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkCorimSignBatchArgs(args); err != nil {
+				return err
+			}
+
+			files := args
+			if len(corimSignBatchInputGlobs) != 0 {
+				matched, err := globFiles(corimSignBatchInputGlobs)
+				if err != nil {
+					return err
+				}
+				files = append(files, matched...)
+			}
+
+			if len(files) == 0 {
+				return errors.New("no files found")
+			}
+
+			if err := signBatch(files, *corimSignBatchKeyFile, *corimSignBatchMetaFile,
+				*corimSignBatchOutputFile, corimSignBatchCertFile, corimSignBatchIntermediateCerts); err != nil {
+				return err
+			}
+			fmt.Printf(">> %d CoRIM(s) signed and saved to %q\n", len(files), *corimSignBatchOutputFile)
+
+			return nil
+		},
+	}
+
+	corimSignBatchMetaFile = cmd.Flags().StringP("meta", "m", "", "CoRIM Meta file (in JSON format)")
+	corimSignBatchKeyFile = cmd.Flags().StringP("key", "k", "", "signing key in JWK format")
+	corimSignBatchOutputFile = cmd.Flags().StringP("output", "o", "", "name of the generated bundle file")
+	corimSignBatchCertFile = cmd.Flags().StringP("cert", "c", "", "signing certificate in DER format")
+	cmd.Flags().StringArrayVar(
+		&corimSignBatchIntermediateCerts, "intermediates", []string{}, "intermediate certificates in DER format (can be repeated)",
+	)
+	addInputGlobFlag(cmd, &corimSignBatchInputGlobs, "unsigned CoRIM files to sign, in addition to the arguments")
+
+	return cmd
+}
+
+func checkCorimSignBatchArgs(args []string) error {
+	if len(args) == 0 && len(corimSignBatchInputGlobs) == 0 {
+		return errors.New("no CoRIM supplied")
+	}
+
+	if corimSignBatchKeyFile == nil || *corimSignBatchKeyFile == "" {
+		return errors.New("no key supplied")
+	}
+
+	if corimSignBatchMetaFile == nil || *corimSignBatchMetaFile == "" {
+		return errors.New("no CoRIM Meta supplied")
+	}
+
+	if corimSignBatchOutputFile == nil || *corimSignBatchOutputFile == "" {
+		return errors.New("no output file supplied")
+	}
+
+	return nil
+}
+
+func signBatch(unsignedCorimFiles []string, keyFile, metaFile, outputFile string, certFile *string, intermediatesFiles []string) error {
+	var bundle []cbor.RawMessage
+
+	for _, unsignedCorimFile := range unsignedCorimFiles {
+		signedCorimCBOR, err := signCorim(unsignedCorimFile, keyFile, metaFile, certFile, intermediatesFiles, signOptions{})
+		if err != nil {
+			return err
+		}
+
+		bundle = append(bundle, signedCorimCBOR)
+	}
+
+	bundleCBOR, err := cbor.Marshal(bundle)
+	if err != nil {
+		return fmt.Errorf("error encoding bundle: %w", err)
+	}
+
+	if err = afero.WriteFile(fs, outputFile, bundleCBOR, 0644); err != nil {
+		return fmt.Errorf("error saving bundle to file %s: %w", outputFile, err)
+	}
+
+	return nil
+}
+
+func init() {
+	corimCmd.AddCommand(corimSignBatchCmd)
+}
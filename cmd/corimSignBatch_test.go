@@ -0,0 +1,122 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/corim"
+)
+
+func Test_CorimSignBatchCmd_unknown_argument(t *testing.T) {
+	cmd := NewCorimSignBatchCmd()
+
+	args := []string{"--unknown-argument=val"}
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "unknown flag: --unknown-argument")
+}
+
+func Test_CorimSignBatchCmd_mandatory_args_missing_corim_files(t *testing.T) {
+	cmd := NewCorimSignBatchCmd()
+
+	args := []string{
+		"--key=ignored.jwk",
+		"--meta=ignored.json",
+		"--output=ignored.cbor",
+	}
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "no CoRIM supplied")
+}
+
+func Test_CorimSignBatchCmd_mandatory_args_missing_output_file(t *testing.T) {
+	cmd := NewCorimSignBatchCmd()
+
+	args := []string{
+		"--key=ignored.jwk",
+		"--meta=ignored.json",
+		"ignored.cbor",
+	}
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "no output file supplied")
+}
+
+func Test_CorimSignBatchCmd_non_existent_unsigned_corim_file(t *testing.T) {
+	cmd := NewCorimSignBatchCmd()
+
+	args := []string{
+		"--key=ok.jwk",
+		"--meta=ok.json",
+		"--output=bundle.cbor",
+		"ok.cbor",
+		"nonexistent.cbor",
+	}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "ok.cbor", testCorimValid, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ok.json", testMetaValid, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ok.jwk", testECKey, 0644)
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	assert.EqualError(t, err, "error loading unsigned CoRIM from nonexistent.cbor: open nonexistent.cbor: file does not exist")
+
+	_, err = fs.Stat("bundle.cbor")
+	assert.Error(t, err)
+}
+
+func Test_CorimSignBatchCmd_ok(t *testing.T) {
+	cmd := NewCorimSignBatchCmd()
+
+	args := []string{
+		"--key=ok.jwk",
+		"--meta=ok.json",
+		"--output=bundle.cbor",
+		"ok1.cbor",
+		"ok2.cbor",
+	}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "ok1.cbor", testCorimValid, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ok2.cbor", testCorimValid, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ok.json", testMetaValid, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ok.jwk", testECKey, 0644)
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	assert.NoError(t, err)
+
+	data, err := afero.ReadFile(fs, "bundle.cbor")
+	require.NoError(t, err)
+
+	var bundle []cbor.RawMessage
+	err = cbor.Unmarshal(data, &bundle)
+	require.NoError(t, err)
+	require.Len(t, bundle, 2)
+
+	pkey, err := corim.NewPublicKeyFromJWK(testECKey)
+	require.NoError(t, err)
+
+	for _, e := range bundle {
+		var s corim.SignedCorim
+		require.NoError(t, s.FromCOSE(e))
+		assert.NoError(t, s.Verify(pkey))
+	}
+}
@@ -4,11 +4,18 @@
 package cmd
 
 import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
 	"testing"
+	"time"
 
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/corim"
+	cose "github.com/veraison/go-cose"
 )
 
 func Test_CorimSignCmd_unknown_argument(t *testing.T) {
@@ -450,3 +457,735 @@ func Test_CorimSignCmd_nonexistent_intermediates_file(t *testing.T) {
 	err = cmd.Execute()
 	assert.EqualError(t, err, "error loading intermediate certificates from nonexistent.der: open nonexistent.der: file does not exist")
 }
+
+func Test_CorimSignCmd_meta_and_no_meta(t *testing.T) {
+	cmd := NewCorimSignCmd()
+
+	args := []string{
+		"--file=ok.cbor",
+		"--key=ok.jwk",
+		"--meta=ok.json",
+		"--no-meta",
+	}
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "--meta cannot be used together with --no-meta")
+}
+
+func Test_CorimSignCmd_ok_no_meta(t *testing.T) {
+	cmd := NewCorimSignCmd()
+
+	args := []string{
+		"--file=ok.cbor",
+		"--key=ok.jwk",
+		"--no-meta",
+		"--cert=cert.der",
+	}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "ok.cbor", testCorimValid, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ok.jwk", testECKey, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "cert.der", testSigningCertificate, 0644)
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	assert.NoError(t, err)
+
+	data, err := afero.ReadFile(fs, "signed-ok.cbor")
+	require.NoError(t, err)
+
+	msg, err := decodeSign1(data)
+	require.NoError(t, err)
+
+	assert.NotContains(t, msg.Headers.Protected, corim.HeaderLabelCorimMeta)
+	assert.Equal(t, corim.ContentType, msg.Headers.Protected[cose.HeaderLabelContentType])
+	assert.Contains(t, msg.Headers.Protected, cose.HeaderLabelX5Chain)
+
+	pk, err := corim.NewPublicKeyFromJWK(testECKey)
+	require.NoError(t, err)
+
+	alg, err := msg.Headers.Protected.Algorithm()
+	require.NoError(t, err)
+
+	verifier, err := cose.NewVerifier(alg, pk)
+	require.NoError(t, err)
+
+	assert.NoError(t, msg.Verify(corim.NoExternalData, verifier))
+}
+
+func Test_CorimSignCmd_reproducible(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "ok.cbor", testCorimValid, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ok.json", testMetaValid, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ok.jwk", testEdDSAKey, 0644)
+	require.NoError(t, err)
+
+	var outputs [][]byte
+
+	for _, out := range []string{"one.cbor", "two.cbor"} {
+		cmd := NewCorimSignCmd()
+
+		args := []string{
+			"--file=ok.cbor",
+			"--key=ok.jwk",
+			"--meta=ok.json",
+			"--reproducible",
+			"--output=" + out,
+		}
+		cmd.SetArgs(args)
+
+		err = cmd.Execute()
+		require.NoError(t, err)
+
+		data, err := afero.ReadFile(fs, out)
+		require.NoError(t, err)
+
+		outputs = append(outputs, data)
+	}
+
+	assert.Equal(t, outputs[0], outputs[1])
+
+	var s corim.SignedCorim
+	require.NoError(t, s.FromCOSE(outputs[0]))
+
+	pk, err := corim.NewPublicKeyFromJWK(testEdDSAKey)
+	require.NoError(t, err)
+	assert.NoError(t, s.Verify(pk))
+}
+
+func Test_CorimSignCmd_meta_from_corim_conflicts(t *testing.T) {
+	tvs := []struct {
+		args []string
+		err  string
+	}{
+		{
+			args: []string{"--meta=ok.json", "--meta-from-corim=prev.cbor"},
+			err:  "--meta-from-corim cannot be used together with --meta or --no-meta",
+		},
+		{
+			args: []string{"--no-meta", "--meta-from-corim=prev.cbor"},
+			err:  "--meta-from-corim cannot be used together with --meta or --no-meta",
+		},
+		{
+			args: []string{"--meta=ok.json", "--bump-validity=24h"},
+			err:  "--bump-validity can only be used together with --meta-from-corim",
+		},
+	}
+
+	for _, tv := range tvs {
+		cmd := NewCorimSignCmd()
+
+		cmd.SetArgs(append([]string{"--file=ok.cbor", "--key=ok.jwk"}, tv.args...))
+
+		err := cmd.Execute()
+		assert.EqualError(t, err, tv.err)
+	}
+}
+
+func Test_CorimSignCmd_meta_from_corim_ok(t *testing.T) {
+	cmd := NewCorimSignCmd()
+
+	args := []string{
+		"--file=ok.cbor",
+		"--key=ok.jwk",
+		"--meta-from-corim=prev.cbor",
+		"--bump-validity=24h",
+		"--output=new.cbor",
+	}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "ok.cbor", testCorimValid, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ok.jwk", testECKey, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "prev.cbor", testSignedCorimValid, 0644)
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	require.NoError(t, err)
+
+	var prev, cur corim.SignedCorim
+	require.NoError(t, prev.FromCOSE(testSignedCorimValid))
+
+	data, err := afero.ReadFile(fs, "new.cbor")
+	require.NoError(t, err)
+	require.NoError(t, cur.FromCOSE(data))
+
+	assert.Equal(t, prev.Meta.Signer, cur.Meta.Signer)
+	require.NotNil(t, cur.Meta.Validity)
+	assert.Equal(t, prev.Meta.Validity.NotAfter.Add(24*time.Hour), cur.Meta.Validity.NotAfter)
+	if prev.Meta.Validity.NotBefore != nil {
+		assert.Equal(t, prev.Meta.Validity.NotBefore.Add(24*time.Hour), *cur.Meta.Validity.NotBefore)
+	}
+}
+
+func Test_CorimSignCmd_meta_from_unsigned_corim(t *testing.T) {
+	cmd := NewCorimSignCmd()
+
+	args := []string{
+		"--file=ok.cbor",
+		"--key=ok.jwk",
+		"--meta-from-corim=ok.cbor",
+	}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "ok.cbor", testCorimValid, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ok.jwk", testECKey, 0644)
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	assert.ErrorContains(t, err, "error loading CoRIM Meta from signed CoRIM ok.cbor: ")
+}
+
+func Test_CorimSignCmd_denied_algorithm(t *testing.T) {
+	cmd := NewCorimSignCmd()
+
+	args := []string{
+		"--file=ok.cbor",
+		"--key=ok.jwk",
+		"--meta=ok.json",
+		"--allowed-algs=ES384,ES512",
+	}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "ok.cbor", testCorimValid, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ok.json", testMetaValid, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ok.jwk", testECKey, 0644)
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	assert.EqualError(t, err,
+		"error signing CoRIM with key ok.jwk: algorithm policy violation: ES256 is not allowed (allowed: ES384, ES512)")
+
+	_, err = fs.Stat("signed-ok.cbor")
+	assert.Error(t, err)
+}
+
+func Test_CorimSignCmd_algorithm_policy_ok(t *testing.T) {
+	cmd := NewCorimSignCmd()
+
+	args := []string{
+		"--file=ok.cbor",
+		"--key=ok.jwk",
+		"--meta=ok.json",
+		"--denied-algs=PS256",
+		"--denied-algs=EdDSA",
+	}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "ok.cbor", testCorimValid, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ok.json", testMetaValid, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ok.jwk", testECKey, 0644)
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	assert.NoError(t, err)
+}
+
+func Test_CorimSignCmd_bad_algorithm_policy(t *testing.T) {
+	cmd := NewCorimSignCmd()
+
+	args := []string{
+		"--file=ok.cbor",
+		"--key=ok.jwk",
+		"--meta=ok.json",
+		"--denied-algs=MD5",
+	}
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+	assert.EqualError(t, err,
+		`invalid --denied-algs: unknown signature algorithm "MD5", expecting one of: ES256, ES384, ES512, EdDSA, PS256, PS384, PS512`)
+}
+
+func Test_CorimSignCmd_with_multiple_intermediates_files(t *testing.T) {
+	pki := newTestPKI(t)
+
+	cmd := NewCorimSignCmd()
+
+	args := []string{
+		"--file=ok.cbor",
+		"--key=ok.jwk",
+		"--meta=ok.json",
+		"--cert=leaf.der",
+		"--intermediates=intermediate.der",
+		"--intermediates=root.der",
+		"--output=signed.cbor",
+	}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "ok.cbor", testCorimValid, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ok.json", testMetaValid, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ok.jwk", testECKey, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "leaf.der", pki.leafDER, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "intermediate.der", pki.intermediateDER, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "root.der", pki.rootDER, 0644)
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	require.NoError(t, err)
+
+	data, err := afero.ReadFile(fs, "signed.cbor")
+	require.NoError(t, err)
+
+	var s corim.SignedCorim
+	require.NoError(t, s.FromCOSE(data))
+
+	// the x5chain follows the order of the --intermediates switches
+	require.NotNil(t, s.SigningCert)
+	assert.Equal(t, pki.leafDER, s.SigningCert.Raw)
+	require.Len(t, s.IntermediateCerts, 2)
+	assert.Equal(t, pki.intermediateDER, s.IntermediateCerts[0].Raw)
+	assert.Equal(t, pki.rootDER, s.IntermediateCerts[1].Raw)
+}
+
+func Test_CorimSignCmd_invalid_second_intermediates_file(t *testing.T) {
+	cmd := NewCorimSignCmd()
+
+	args := []string{
+		"--file=ok.cbor",
+		"--key=ok.jwk",
+		"--meta=ok.json",
+		"--cert=cert.der",
+		"--intermediates=intermediates.der",
+		"--intermediates=invalid.der",
+	}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "ok.cbor", testCorimValid, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ok.json", testMetaValid, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ok.jwk", testECKey, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "cert.der", testSigningCertificate, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "intermediates.der", testIntermediateCerts, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "invalid.der", []byte{0x30, 0x03, 0x02, 0x01, 0x02}, 0644)
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	assert.ErrorContains(t, err, "error adding intermediate certificates from invalid.der")
+}
+
+func Test_CorimSignCmd_no_wrap_tagged(t *testing.T) {
+	var err error
+
+	fs = afero.NewMemMapFs()
+	err = afero.WriteFile(fs, "ok.cbor", testCorimValid, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ok.json", testMetaValid, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ok.jwk", testECKey, 0644)
+	require.NoError(t, err)
+
+	for _, tv := range []struct {
+		flag     string
+		expected byte
+	}{
+		{"--wrap-tagged", 0xd2},
+		{"--no-wrap-tagged", 0x84},
+		{"--wrap-tagged=false", 0x84},
+	} {
+		cmd := NewCorimSignCmd()
+
+		args := []string{
+			"--file=ok.cbor",
+			"--key=ok.jwk",
+			"--meta=ok.json",
+			tv.flag,
+		}
+		cmd.SetArgs(args)
+
+		err = cmd.Execute()
+		require.NoError(t, err, tv.flag)
+
+		data, err := afero.ReadFile(fs, "signed-ok.cbor")
+		require.NoError(t, err)
+		assert.Equal(t, tv.expected, data[0], tv.flag)
+	}
+}
+
+func Test_CorimSignCmd_wrap_tagged_and_no_wrap_tagged(t *testing.T) {
+	cmd := NewCorimSignCmd()
+
+	args := []string{
+		"--file=ok.cbor",
+		"--key=ok.jwk",
+		"--meta=ok.json",
+		"--wrap-tagged",
+		"--no-wrap-tagged",
+	}
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "--wrap-tagged cannot be used together with --no-wrap-tagged")
+}
+
+func Test_CorimSignCmd_with_cert_chain(t *testing.T) {
+	pki := newTestPKI(t)
+
+	cmd := NewCorimSignCmd()
+
+	args := []string{
+		"--file=ok.cbor",
+		"--key=ok.jwk",
+		"--meta=ok.json",
+		"--cert-chain=chain.pem",
+		"--output=signed.cbor",
+	}
+	cmd.SetArgs(args)
+
+	var chain []byte
+	for _, der := range [][]byte{pki.leafDER, pki.intermediateDER} {
+		chain = append(chain, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	}
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "ok.cbor", testCorimValid, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ok.json", testMetaValid, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ok.jwk", testECKey, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "chain.pem", chain, 0644)
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	require.NoError(t, err)
+
+	data, err := afero.ReadFile(fs, "signed.cbor")
+	require.NoError(t, err)
+
+	var s corim.SignedCorim
+	require.NoError(t, s.FromCOSE(data))
+
+	require.NotNil(t, s.SigningCert)
+	assert.Equal(t, pki.leafDER, s.SigningCert.Raw)
+	require.Len(t, s.IntermediateCerts, 1)
+	assert.Equal(t, pki.intermediateDER, s.IntermediateCerts[0].Raw)
+}
+
+func Test_CorimSignCmd_with_cert_chain_leaf_only(t *testing.T) {
+	pki := newTestPKI(t)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "ok.cbor", testCorimValid, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ok.jwk", testECKey, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ok.json", testMetaValid, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "leaf.pem", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: pki.leafDER}), 0644)
+	require.NoError(t, err)
+
+	data, err := signCorim("ok.cbor", "ok.jwk", "ok.json", nil, nil, signOptions{certChain: "leaf.pem"})
+	require.NoError(t, err)
+
+	var s corim.SignedCorim
+	require.NoError(t, s.FromCOSE(data))
+
+	require.NotNil(t, s.SigningCert)
+	assert.Equal(t, pki.leafDER, s.SigningCert.Raw)
+	assert.Empty(t, s.IntermediateCerts)
+}
+
+func Test_CorimSignCmd_with_bad_cert_chain(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "ok.cbor", testCorimValid, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ok.jwk", testECKey, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ok.json", testMetaValid, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "key.pem", []byte("-----BEGIN PUBLIC KEY-----\nAAAA\n-----END PUBLIC KEY-----\n"), 0644)
+	require.NoError(t, err)
+
+	_, err = signCorim("ok.cbor", "ok.jwk", "ok.json", nil, nil, signOptions{certChain: "key.pem"})
+	assert.EqualError(t, err, "error decoding certificate chain from key.pem: no PEM-encoded certificate found")
+}
+
+func Test_CorimSignCmd_cert_chain_and_cert(t *testing.T) {
+	for _, extra := range []string{"--cert=cert.der", "--intermediates=intermediates.der"} {
+		cmd := NewCorimSignCmd()
+
+		args := []string{
+			"--file=ok.cbor",
+			"--key=ok.jwk",
+			"--meta=ok.json",
+			"--cert-chain=chain.pem",
+			extra,
+		}
+		cmd.SetArgs(args)
+
+		err := cmd.Execute()
+		assert.EqualError(t, err, "--cert-chain cannot be used together with --cert or --intermediates", extra)
+	}
+}
+
+func Test_CorimSignCmd_bad_key_usage_only_warns(t *testing.T) {
+	pki := newTestPKIWithLeafUsage(t, x509.KeyUsageKeyEncipherment)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "ok.cbor", testCorimValid, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ok.jwk", testECKey, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ok.json", testMetaValid, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "leaf.der", pki.leafDER, 0644)
+	require.NoError(t, err)
+
+	certFile := "leaf.der"
+	data, err := signCorim("ok.cbor", "ok.jwk", "ok.json", &certFile, nil, signOptions{})
+	require.NoError(t, err)
+
+	var s corim.SignedCorim
+	require.NoError(t, s.FromCOSE(data))
+	assert.Equal(t, pki.leafDER, s.SigningCert.Raw)
+}
+
+func Test_CorimSignCmd_output_format(t *testing.T) {
+	tvs := []struct {
+		format  string
+		hasCBOR bool
+		hasDiag bool
+	}{
+		{"cbor", true, false},
+		{"diag", false, true},
+		{"both", true, true},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.format, func(t *testing.T) {
+			fs = afero.NewMemMapFs()
+			require.NoError(t, afero.WriteFile(fs, "unsigned.cbor", testCorimValid, 0644))
+			require.NoError(t, afero.WriteFile(fs, "meta.json", testMetaValid, 0644))
+			require.NoError(t, afero.WriteFile(fs, "key.jwk", testECKey, 0644))
+
+			cmd := NewCorimSignCmd()
+			cmd.SetArgs([]string{
+				"--file=unsigned.cbor",
+				"--meta=meta.json",
+				"--key=key.jwk",
+				"--output=out/signed.cbor",
+				"--output-format=" + tv.format,
+			})
+			require.NoError(t, cmd.Execute())
+
+			signed, err := afero.ReadFile(fs, "out/signed.cbor")
+			if !tv.hasCBOR {
+				assert.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			diag, err := afero.ReadFile(fs, "out/signed.diag")
+			if !tv.hasDiag {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			// the signed CoRIM (tag 18), with the protected header expanded
+			assert.Regexp(t, `^18\(\[<<\{1: -7, 3: "application/rim\+cbor"`, string(diag))
+
+			if tv.hasCBOR {
+				expected, err := toDiag(signed, true)
+				require.NoError(t, err)
+				assert.Equal(t, expected+"\n", string(diag))
+			}
+		})
+	}
+}
+
+func Test_CorimSignCmd_bad_output_format(t *testing.T) {
+	cmd := NewCorimSignCmd()
+	cmd.SetArgs([]string{
+		"--file=unsigned.cbor",
+		"--meta=meta.json",
+		"--key=key.jwk",
+		"--output-format=json",
+	})
+	assert.EqualError(t, cmd.Execute(), `invalid --output-format "json": expecting cbor, diag or both`)
+}
+
+func signWithAdditionalMeta(t *testing.T, fragment string) error {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "unsigned.cbor", testCorimValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "meta.json", testMetaValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "extra.json", []byte(fragment), 0644))
+	require.NoError(t, afero.WriteFile(fs, "key.jwk", testECKey, 0644))
+
+	cmd := NewCorimSignCmd()
+	cmd.SetArgs([]string{
+		"--file=unsigned.cbor",
+		"--meta=meta.json",
+		"--additional-meta=extra.json",
+		"--key=key.jwk",
+		"--output=signed.cbor",
+	})
+
+	return cmd.Execute()
+}
+
+func Test_CorimSignCmd_additional_meta(t *testing.T) {
+	fragment := `{
+  "signer": { "name": "ACME Ltd build 42 signing key" },
+  "validity": { "not-after": "2030-12-31T00:00:00Z" }
+}`
+	require.NoError(t, signWithAdditionalMeta(t, fragment))
+
+	data, err := afero.ReadFile(fs, "signed.cbor")
+	require.NoError(t, err)
+
+	s, err := corim.UnmarshalSignedCorimFromCBOR(data)
+	require.NoError(t, err)
+
+	var base corim.Meta
+	require.NoError(t, base.FromJSON(testMetaValid))
+
+	// the fragment takes precedence, the other fields are those of the base
+	assert.Equal(t, "ACME Ltd build 42 signing key", s.Meta.Signer.Name)
+	assert.Equal(t, base.Signer.URI, s.Meta.Signer.URI)
+	require.NotNil(t, s.Meta.Validity)
+	assert.True(t, time.Date(2030, 12, 31, 0, 0, 0, 0, time.UTC).Equal(s.Meta.Validity.NotAfter))
+	assert.True(t, base.Validity.NotBefore.Equal(*s.Meta.Validity.NotBefore))
+}
+
+func Test_CorimSignCmd_additional_meta_invalid(t *testing.T) {
+	err := signWithAdditionalMeta(t, `["not", "an", "object"]`)
+	assert.EqualError(t, err, "error merging additional CoRIM Meta from extra.json: patch is not a JSON object")
+
+	// the merged CoRIM Meta is validated as usual
+	err = signWithAdditionalMeta(t, `{"validity": {"not-after": "2001-01-01T00:00:00Z"}}`)
+	assert.ErrorContains(t, err, "error validating CoRIM Meta")
+}
+
+func Test_CorimSignCmd_additional_meta_without_meta(t *testing.T) {
+	cmd := NewCorimSignCmd()
+	cmd.SetArgs([]string{
+		"--file=unsigned.cbor",
+		"--no-meta",
+		"--additional-meta=extra.json",
+		"--key=key.jwk",
+	})
+	assert.EqualError(t, cmd.Execute(), "--additional-meta can only be used together with --meta")
+}
+
+func Test_CorimSignCmd_verify_after_sign_ok(t *testing.T) {
+	tvs := []struct {
+		key  []byte
+		args []string
+	}{
+		{testECKey, []string{"--meta=meta.json"}},
+		{testECKey, []string{"--no-meta", "--no-wrap-tagged"}},
+		{testEdDSAKey, []string{"--meta=meta.json", "--reproducible", "--kid=thumbprint"}},
+	}
+
+	for _, tv := range tvs {
+		fs = afero.NewMemMapFs()
+		require.NoError(t, afero.WriteFile(fs, "unsigned.cbor", testCorimValid, 0644))
+		require.NoError(t, afero.WriteFile(fs, "meta.json", testMetaValid, 0644))
+		require.NoError(t, afero.WriteFile(fs, "key.jwk", tv.key, 0644))
+
+		cmd := NewCorimSignCmd()
+		cmd.SetArgs(append([]string{
+			"--file=unsigned.cbor",
+			"--key=key.jwk",
+			"--output=signed.cbor",
+			"--verify-after-sign",
+		}, tv.args...))
+
+		assert.NoError(t, cmd.Execute(), tv.args)
+	}
+}
+
+func Test_verifyAfterSign_bad_signature(t *testing.T) {
+	tampered := append([]byte{}, testSignedCorimValid...)
+	// the signature is the last item of the COSE Sign1
+	tampered[len(tampered)-1] ^= 0xff
+
+	pk, err := corim.NewPublicKeyFromJWK(testECKey)
+	require.NoError(t, err)
+
+	err = verifyAfterSign(tampered, pk, cose.AlgorithmES256)
+	assert.EqualError(t, err, "verification error")
+}
+
+func Test_verifyAfterSign_wrong_key(t *testing.T) {
+	pk, err := corim.NewPublicKeyFromJWK(testEdDSAKey)
+	require.NoError(t, err)
+
+	err = verifyAfterSign(testSignedCorimValid, pk, cose.AlgorithmES256)
+	assert.Error(t, err)
+}
+
+func Test_CorimSignCmd_output_mode(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "ok.cbor", testCorimValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "ok.json", testMetaValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "ok.jwk", testECKey, 0644))
+	// an existing output file gets the requested permissions too
+	require.NoError(t, afero.WriteFile(fs, "signed.cbor", []byte("old"), 0644))
+
+	cmd := NewCorimSignCmd()
+	cmd.SetArgs([]string{
+		"--file=ok.cbor", "--key=ok.jwk", "--meta=ok.json", "--output=signed.cbor",
+		"--output-format=both", "--output-mode=0600",
+	})
+	require.NoError(t, cmd.Execute())
+
+	for _, file := range []string{"signed.cbor", "signed.diag"} {
+		fi, err := fs.Stat(file)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), fi.Mode().Perm(), file)
+	}
+}
+
+func Test_CorimSignCmd_output_mode_default(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "ok.cbor", testCorimValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "ok.json", testMetaValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "ok.jwk", testECKey, 0644))
+
+	cmd := NewCorimSignCmd()
+	cmd.SetArgs([]string{"--file=ok.cbor", "--key=ok.jwk", "--meta=ok.json", "--output=signed.cbor"})
+	require.NoError(t, cmd.Execute())
+
+	fi, err := fs.Stat("signed.cbor")
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0644), fi.Mode().Perm())
+}
+
+func Test_CorimSignCmd_bad_output_mode(t *testing.T) {
+	for _, mode := range []string{"rw-------", "0999", "10600", ""} {
+		cmd := NewCorimSignCmd()
+		cmd.SetArgs([]string{"--file=ok.cbor", "--key=ok.jwk", "--meta=ok.json", "--output-mode=" + mode})
+		assert.EqualError(t, cmd.Execute(),
+			fmt.Sprintf("invalid file mode %q: expecting octal permission bits, e.g., 0600", mode))
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/corim/corim"
+	"github.com/veraison/corim/cots"
+)
+
+// corimStats is the census of the content of a CoRIM printed by corim verify
+// --stats: the number of measurements in the reference-values and
+// endorsed-values triples, of keys in the attester-verification-keys and
+// dev-identity-keys triples of its CoMIDs, and of trust anchors in its CoTSs
+type corimStats struct {
+	ReferenceValues  int `json:"reference-values"`
+	EndorsedValues   int `json:"endorsed-values"`
+	VerificationKeys int `json:"verification-keys"`
+	TrustAnchors     int `json:"trust-anchors"`
+}
+
+// newCorimStats counts the content of the tags of c.  CoSWIDs, and tags of
+// unknown types, do not contribute to any of the counts.
+func newCorimStats(c corim.UnsignedCorim) (corimStats, error) {
+	var stats corimStats
+
+	for i, t := range c.Tags {
+		if len(t) < 4 {
+			continue
+		}
+
+		cborTag, cborData := t[:3], t[3:]
+
+		switch {
+		case bytes.Equal(cborTag, corim.ComidTag):
+			cm, err := corim.UnmarshalComidFromCBOR(cborData, c.Profile)
+			if err != nil {
+				return corimStats{}, fmt.Errorf("tag [%d] (CoMID): decoding failed: %w", i, err)
+			}
+
+			stats.ReferenceValues += countMeasurements(cm.Triples.ReferenceValues)
+			stats.EndorsedValues += countMeasurements(cm.Triples.EndorsedValues)
+			stats.VerificationKeys += countKeys(cm.Triples.AttestVerifKeys) + countKeys(cm.Triples.DevIdentityKeys)
+		case bytes.Equal(cborTag, cots.CotsTag):
+			var cts cots.ConciseTaStore
+			if err := cts.FromCBOR(cborData); err != nil {
+				return corimStats{}, fmt.Errorf("tag [%d] (CoTS): decoding failed: %w", i, err)
+			}
+
+			if cts.Keys != nil {
+				stats.TrustAnchors += len(cts.Keys.Tas)
+			}
+		}
+	}
+
+	return stats, nil
+}
+
+// countMeasurements returns the number of measurements in the triples vts
+func countMeasurements(vts *comid.ValueTriples) int {
+	if vts == nil {
+		return 0
+	}
+
+	n := 0
+	for _, vt := range vts.Values {
+		n += len(vt.Measurements.Values)
+	}
+
+	return n
+}
+
+// countKeys returns the number of keys in the triples kts
+func countKeys(kts *comid.KeyTriples) int {
+	if kts == nil {
+		return 0
+	}
+
+	n := 0
+	for _, kt := range *kts {
+		n += len(kt.VerifKeys)
+	}
+
+	return n
+}
+
+// print prints the statistics in format (text or json)
+func (o corimStats) print(format string) error {
+	if format == summaryFormatJSON {
+		j, err := json.MarshalIndent(o, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error encoding statistics: %w", err)
+		}
+
+		fmt.Println(string(j))
+
+		return nil
+	}
+
+	fmt.Printf(">> stats: %d reference value(s), %d endorsed value(s), %d verification key(s), %d trust anchor(s)\n",
+		o.ReferenceValues, o.EndorsedValues, o.VerificationKeys, o.TrustAnchors)
+
+	return nil
+}
+
+// reportCorimStats prints the statistics of the verified CoRIM c, which is
+// referred to as file in messages, in format (text or json)
+func reportCorimStats(c corim.UnsignedCorim, file, format string) error {
+	stats, err := newCorimStats(c)
+	if err != nil {
+		return fmt.Errorf("error computing statistics of %s: %w", file, err)
+	}
+
+	return stats.print(format)
+}
@@ -0,0 +1,115 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/corim"
+)
+
+func Test_newCorimStats(t *testing.T) {
+	tvs := []struct {
+		signedCorim []byte
+		expected    corimStats
+	}{
+		{newTestSignedCorimWithComids(t, 2), corimStats{ReferenceValues: 6}},
+		{testSignedCorimValidWithCots, corimStats{TrustAnchors: 4}},
+	}
+
+	for _, tv := range tvs {
+		var s corim.SignedCorim
+		require.NoError(t, s.FromCOSE(tv.signedCorim))
+
+		stats, err := newCorimStats(s.UnsignedCorim)
+		require.NoError(t, err)
+		assert.Equal(t, tv.expected, stats)
+	}
+}
+
+func Test_CorimVerifyCmd_stats(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "ok.cbor", newTestSignedCorimWithComids(t, 1), 0644))
+	require.NoError(t, afero.WriteFile(fs, "ok.jwk", testECKey, 0644))
+
+	for _, format := range []string{"text", "json"} {
+		cmd := NewCorimVerifyCmd()
+		cmd.SetArgs([]string{"--file=ok.cbor", "--key=ok.jwk", "--stats", "--format=" + format})
+		assert.NoError(t, cmd.Execute())
+	}
+}
+
+func Test_CorimVerifyCmd_stats_json_quiet(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "ok.cbor", newTestSignedCorimWithClaims(t, map[int64]interface{}{
+		cwtClaimIAT: time.Now().Add(-2 * time.Hour).Unix(),
+	}), 0644))
+	require.NoError(t, afero.WriteFile(fs, "ok.jwk", testECKey, 0644))
+	require.NoError(t, afero.WriteFile(fs, "mac-corim.cbor", newTestMac0Corim(t, 5), 0644))
+	require.NoError(t, afero.WriteFile(fs, "mac-key.jwk", testMacKey, 0644))
+
+	tvs := [][]string{
+		// with a signing time, which is reported, and the CoRIM age
+		{"--file=ok.cbor", "--key=ok.jwk", "--max-age=24h"},
+		{"--file=mac-corim.cbor", "--mac-key=mac-key.jwk"},
+	}
+
+	for _, args := range tvs {
+		cmd := NewCorimVerifyCmd()
+		cmd.SetArgs(append(args, "--stats", "--format=json"))
+		require.NoError(t, withDisplayOutput("out.json", cmd.Execute), args)
+
+		// stdout is nothing but the JSON statistics
+		var stats corimStats
+		require.NoError(t, json.Unmarshal(mustReadFile(t, "out.json"), &stats), args)
+	}
+}
+
+func Test_CorimVerifyCmd_stats_bad_args(t *testing.T) {
+	tvs := []struct {
+		desc     string
+		args     []string
+		expected string
+	}{
+		{
+			desc:     "format without stats",
+			args:     []string{"--file=ok.cbor", "--key=ok.jwk", "--format=json"},
+			expected: "--format can only be used together with --stats",
+		},
+		{
+			desc:     "unknown format",
+			args:     []string{"--file=ok.cbor", "--key=ok.jwk", "--stats", "--format=yaml"},
+			expected: `invalid --format "yaml": expecting text or json`,
+		},
+		{
+			desc:     "json with dir",
+			args:     []string{"--dir=corims", "--key=ok.jwk", "--stats", "--format=json"},
+			expected: "--format=json can only be used to verify a single CoRIM, with --file (without --sequence), --url or --signature",
+		},
+		{
+			desc:     "json with sequence",
+			args:     []string{"--file=corims.cborseq", "--sequence", "--key=ok.jwk", "--stats", "--format=json"},
+			expected: "--format=json can only be used to verify a single CoRIM, with --file (without --sequence), --url or --signature",
+		},
+		{
+			desc:     "stats with extract",
+			args:     []string{"--file=ok.cbor", "--key=ok.jwk", "--stats", "--extract=$.corim-id"},
+			expected: "--stats cannot be used together with --extract",
+		},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.desc, func(t *testing.T) {
+			cmd := NewCorimVerifyCmd()
+			cmd.SetArgs(tv.args)
+
+			assert.EqualError(t, cmd.Execute(), tv.expected)
+		})
+	}
+}
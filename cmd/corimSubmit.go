@@ -47,6 +47,9 @@ func NewCorimSubmitCmd(submitter ISubmitter) *cobra.Command {
 	`,
 
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkOnline("corim submit"); err != nil {
+				return err
+			}
 
 			if err := checkSubmitArgs(); err != nil {
 				return err
@@ -0,0 +1,113 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/veraison/corim/corim"
+)
+
+var (
+	corimUnpackBundleFile *string
+	corimUnpackOutputDir  *string
+	corimUnpackDirMode    *string
+)
+
+var corimUnpackCmd = NewCorimUnpackCmd()
+
+func NewCorimUnpackCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "unpack",
+		Short: "split a bundle created with sign-batch into individual signed CoRIMs",
+		Long: `split a bundle created with sign-batch into individual signed CoRIMs
+
+	Unpack the signed CoRIMs found in bundle.cbor to the current directory
+
+	  cocli corim unpack --file=bundle.cbor
+
+	Unpack the signed CoRIMs found in bundle.cbor and store them to directory
+	my-dir, which is created with mode 0700 if it does not exist.
+
+	  cocli corim unpack --file=bundle.cbor --output-dir=my-dir --dir-mode=0700
+	`,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkCorimUnpackArgs(); err != nil {
+				return err
+			}
+
+			if err := prepareOutputDir(*corimUnpackOutputDir, *corimUnpackDirMode); err != nil {
+				return err
+			}
+
+			return unpack(*corimUnpackBundleFile, corimUnpackOutputDir)
+		},
+	}
+
+	corimUnpackBundleFile = cmd.Flags().StringP("file", "f", "", "a bundle of signed CoRIMs (in CBOR format)")
+	corimUnpackOutputDir = cmd.Flags().StringP("output-dir", "o", ".", "folder to which the signed CoRIMs are saved")
+	corimUnpackDirMode = cmd.Flags().String("dir-mode", defaultDirMode, "permissions of the output directory, if it needs to be created")
+
+	return cmd
+}
+
+func checkCorimUnpackArgs() error {
+	if corimUnpackBundleFile == nil || *corimUnpackBundleFile == "" {
+		return errors.New("no bundle supplied")
+	}
+
+	return nil
+}
+
+func unpack(bundleFile string, outputDir *string) error {
+	var (
+		bundleCBOR []byte
+		bundle     []cbor.RawMessage
+		err        error
+		baseDir    string
+	)
+
+	if bundleCBOR, err = afero.ReadFile(fs, bundleFile); err != nil {
+		return fmt.Errorf("error loading bundle from %s: %w", bundleFile, err)
+	}
+
+	if err = cbor.Unmarshal(bundleCBOR, &bundle); err != nil {
+		return fmt.Errorf("error decoding bundle from %s: %w", bundleFile, err)
+	}
+
+	baseDir = "."
+	if outputDir != nil {
+		baseDir = *outputDir
+	}
+
+	for i, e := range bundle {
+		var s corim.SignedCorim
+
+		if err = s.FromCOSE(e); err != nil {
+			fmt.Printf(">> skipping malformed signed CoRIM at index %d: %v\n", i, err)
+			continue
+		}
+
+		outputFile := filepath.Join(baseDir, fmt.Sprintf("%06d-signed-corim.cbor", i))
+
+		if err = afero.WriteFile(fs, outputFile, e, 0644); err != nil {
+			fmt.Printf(">> error saving signed CoRIM at index %d: %v\n", i, err)
+			continue
+		}
+
+		fmt.Printf(">> unpacked %q\n", outputFile)
+	}
+
+	return nil
+}
+
+func init() {
+	corimCmd.AddCommand(corimUnpackCmd)
+}
@@ -0,0 +1,100 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_CorimUnpackCmd_unknown_argument(t *testing.T) {
+	cmd := NewCorimUnpackCmd()
+
+	args := []string{"--unknown-argument=val"}
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "unknown flag: --unknown-argument")
+}
+
+func Test_CorimUnpackCmd_mandatory_args_missing_bundle_file(t *testing.T) {
+	cmd := NewCorimUnpackCmd()
+
+	args := []string{
+		"--output-dir=ignore.d/",
+	}
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "no bundle supplied")
+}
+
+func Test_CorimUnpackCmd_non_existent_bundle_file(t *testing.T) {
+	cmd := NewCorimUnpackCmd()
+
+	args := []string{
+		"--file=nonexistent.cbor",
+	}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "error loading bundle from nonexistent.cbor: open nonexistent.cbor: file does not exist")
+}
+
+func Test_CorimUnpackCmd_bad_bundle(t *testing.T) {
+	cmd := NewCorimUnpackCmd()
+
+	args := []string{
+		"--file=bad.txt",
+	}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "bad.txt", []byte("hello!"), 0644)
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	assert.ErrorContains(t, err, "error decoding bundle from bad.txt")
+}
+
+func Test_CorimUnpackCmd_ok(t *testing.T) {
+	cmd := NewCorimUnpackCmd()
+
+	args := []string{
+		"--file=bundle.cbor",
+		"--output-dir=my-dir/",
+	}
+	cmd.SetArgs(args)
+
+	bundle, err := cbor.Marshal([]cbor.RawMessage{
+		testSignedCorimValid,
+		testCorimValid, // not signed, skipped
+		testSignedCorimValidWithCots,
+	})
+	require.NoError(t, err)
+
+	fs = afero.NewMemMapFs()
+	err = afero.WriteFile(fs, "bundle.cbor", bundle, 0644)
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	assert.NoError(t, err)
+
+	data, err := afero.ReadFile(fs, "my-dir/000000-signed-corim.cbor")
+	assert.NoError(t, err)
+	assert.Equal(t, testSignedCorimValid, data)
+
+	_, err = fs.Stat("my-dir/000001-signed-corim.cbor")
+	assert.Error(t, err)
+
+	data, err = afero.ReadFile(fs, "my-dir/000002-signed-corim.cbor")
+	assert.NoError(t, err)
+	assert.Equal(t, testSignedCorimValidWithCots, data)
+}
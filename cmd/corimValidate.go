@@ -0,0 +1,297 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/veraison/corim/corim"
+	"github.com/veraison/corim/cots"
+	"github.com/veraison/swid"
+)
+
+var (
+	corimValidateCorimFile *string
+	corimValidateMetaFile  *string
+	corimValidateProfile   *string
+	corimValidateSchema    *string
+)
+
+var corimValidateCmd = NewCorimValidateCmd()
+
+func NewCorimValidateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "validate",
+		Short: "validate an unsigned, CBOR-encoded CoRIM without signing it",
+		Long: `validate an unsigned, CBOR-encoded CoRIM without signing it
+
+    Validate the unsigned CoRIM in unsigned-corim.cbor, i.e., run the same
+    checks that are made by "corim sign", without needing a key.  In addition,
+    the length of each measurement digest of the CoMIDs is checked against the
+    output size of its hash algorithm.  Each tag is validated independently,
+    and all the problems found are reported in one go, grouped by tag index.
+
+      cocli corim validate --file=unsigned-corim.cbor
+
+    Also validate the CorimMeta in meta.json, and check that the CoRIM
+    conforms to the profile http://arm.com/psa/iot/1.  The CoRIM must declare
+    that profile, and its CoMIDs must be valid under it (including any
+    extensions registered for the profile).
+
+      cocli corim validate --file=unsigned-corim.cbor \
+                    --meta=meta.json \
+                    --profile=http://arm.com/psa/iot/1
+
+    Also check the unsigned CoRIM, rendered as a full CoRIM JSON document (see
+    "corim full"), together with the CorimMeta in meta.json, if supplied,
+    against the JSON Schema in profile.schema.json, listing every violation
+    with its JSONPath:
+
+      cocli corim validate --file=unsigned-corim.cbor \
+                    --meta=meta.json \
+                    --schema=profile.schema.json
+    `,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkCorimValidateArgs(); err != nil {
+				return err
+			}
+
+			if err := validateCorim(*corimValidateCorimFile, *corimValidateMetaFile, *corimValidateProfile); err != nil {
+				fmt.Printf("[invalid] %q\n", *corimValidateCorimFile)
+				return err
+			}
+
+			if *corimValidateSchema != "" {
+				if err := validateCorimSchema(*corimValidateCorimFile, *corimValidateMetaFile, *corimValidateSchema); err != nil {
+					fmt.Printf("[invalid] %q\n", *corimValidateCorimFile)
+					return err
+				}
+			}
+
+			fmt.Printf("[valid] %q\n", *corimValidateCorimFile)
+			return nil
+		},
+	}
+
+	corimValidateCorimFile = cmd.Flags().StringP("file", "f", "", "an unsigned CoRIM file (in CBOR format)")
+	corimValidateMetaFile = cmd.Flags().StringP("meta", "m", "", "CoRIM Meta file (in JSON format)")
+	corimValidateProfile = cmd.Flags().String("profile", "", "check conformance of the CoRIM to this profile")
+	corimValidateSchema = cmd.Flags().String("schema", "", "also check the CoRIM, rendered to JSON, against this JSON Schema file")
+
+	return cmd
+}
+
+func checkCorimValidateArgs() error {
+	if corimValidateCorimFile == nil || *corimValidateCorimFile == "" {
+		return errors.New("no CoRIM supplied")
+	}
+
+	return nil
+}
+
+// validateCorim decodes and validates the unsigned CoRIM in corimFile and,
+// unless empty, the CoRIM Meta in metaFile.  Rather than stopping at the first
+// problem, the CoRIM and each of its tags are validated independently, and all
+// the problems found are reported together, grouped by tag index.  If profile
+// is not empty, the CoRIM must also declare it.
+func validateCorim(corimFile, metaFile, profile string) error {
+	var (
+		corimCBOR []byte
+		metaJSON  []byte
+		err       error
+		m         corim.Meta
+	)
+
+	if corimCBOR, err = afero.ReadFile(fs, corimFile); err != nil {
+		return fmt.Errorf("error loading unsigned CoRIM from %s: %w", corimFile, err)
+	}
+
+	// register the extensions of the profile declared by the CoRIM, if any
+	c := corim.GetUnsignedCorim(cborProfile(corimCBOR))
+
+	if err = c.FromCBOR(corimCBOR); err != nil {
+		return fmt.Errorf("error decoding unsigned CoRIM from %s: %w", corimFile, err)
+	}
+
+	var problems []string
+
+	if err = c.Valid(); err != nil {
+		problems = append(problems, fmt.Sprintf("CoRIM: %v", err))
+	}
+
+	problems = append(problems, corimTagProblems(*c)...)
+
+	if len(problems) != 0 {
+		return fmt.Errorf("error validating CoRIM, %d problem(s) found:\n  %s",
+			len(problems), strings.ReplaceAll(strings.Join(problems, "\n"), "\n", "\n  "))
+	}
+
+	if metaFile != "" {
+		if metaJSON, err = afero.ReadFile(fs, metaFile); err != nil {
+			return fmt.Errorf("error loading CoRIM Meta from %s: %w", metaFile, err)
+		}
+
+		if err = m.FromJSON(metaJSON); err != nil {
+			return fmt.Errorf("error decoding CoRIM Meta from %s: %w", metaFile, err)
+		}
+
+		if err = m.Valid(); err != nil {
+			return fmt.Errorf("error validating CoRIM Meta: %w", err)
+		}
+	}
+
+	if profile != "" {
+		if err = checkCorimExpectations(*c, "", profile); err != nil {
+			return fmt.Errorf("error validating CoRIM against profile %q: %w", profile, err)
+		}
+	}
+
+	return nil
+}
+
+// validateCorimSchema renders the unsigned CoRIM in corimFile, with the CoRIM
+// Meta in metaFile, unless empty, as a full CoRIM JSON document (see
+// fullCorimJSON), and checks it against the JSON Schema in schemaFile,
+// reporting all the violations found.  validateCorim must have succeeded on
+// the same files.
+func validateCorimSchema(corimFile, metaFile, schemaFile string) error {
+	schemaJSON, err := afero.ReadFile(fs, schemaFile)
+	if err != nil {
+		return fmt.Errorf("error loading JSON Schema from %s: %w", schemaFile, err)
+	}
+
+	schema, err := compileJSONSchema(schemaFile, schemaJSON)
+	if err != nil {
+		return fmt.Errorf("error loading JSON Schema from %s: %w", schemaFile, err)
+	}
+
+	corimCBOR, err := afero.ReadFile(fs, corimFile)
+	if err != nil {
+		return fmt.Errorf("error loading unsigned CoRIM from %s: %w", corimFile, err)
+	}
+
+	c := corim.GetUnsignedCorim(cborProfile(corimCBOR))
+
+	if err = c.FromCBOR(corimCBOR); err != nil {
+		return fmt.Errorf("error decoding unsigned CoRIM from %s: %w", corimFile, err)
+	}
+
+	var meta *corim.Meta
+
+	if metaFile != "" {
+		metaJSON, err := afero.ReadFile(fs, metaFile)
+		if err != nil {
+			return fmt.Errorf("error loading CoRIM Meta from %s: %w", metaFile, err)
+		}
+
+		meta = &corim.Meta{}
+		if err = meta.FromJSON(metaJSON); err != nil {
+			return fmt.Errorf("error decoding CoRIM Meta from %s: %w", metaFile, err)
+		}
+	}
+
+	data, err := fullCorimJSON(c, meta)
+	if err != nil {
+		return err
+	}
+
+	violations, err := schema.validate(data)
+	if err != nil {
+		return fmt.Errorf("error decoding CoRIM: %w", err)
+	}
+
+	if len(violations) != 0 {
+		return fmt.Errorf("error validating CoRIM against JSON Schema %s, %d violation(s) found:\n  %s",
+			schemaFile, len(violations), strings.Join(violations, "\n  "))
+	}
+
+	return nil
+}
+
+// corimTagProblems returns the problems found by validateCorimTag in each of
+// the tags of c, one per faulty tag
+func corimTagProblems(c corim.UnsignedCorim) []string {
+	var problems []string
+
+	for i, t := range c.Tags {
+		if kind, err := validateCorimTag(c, t); err != nil {
+			problems = append(problems, fmt.Sprintf("tag [%d] (%s): %v", i, kind, err))
+		}
+	}
+
+	return problems
+}
+
+// checkCorimTags is the --validate-tags check of "corim verify": it fails,
+// reporting all the faulty tags, unless every tag of c is valid
+func checkCorimTags(c corim.UnsignedCorim) error {
+	if problems := corimTagProblems(c); len(problems) != 0 {
+		return fmt.Errorf("%d/%d tag(s) not valid:\n  %s",
+			len(problems), len(c.Tags), strings.ReplaceAll(strings.Join(problems, "\n"), "\n", "\n  "))
+	}
+
+	fmt.Printf(">> %d tag(s) valid\n", len(c.Tags))
+
+	return nil
+}
+
+// validateCorimTag decodes and validates the tag t of c, returning its kind and
+// the first problem found, if any.  CoMIDs are decoded with the extensions registered for
+// the profile of c, if any.  Their digest lengths are checked first, as this
+// reports mismatches with more context than Valid does.  Tags of unknown kind
+// are skipped.
+func validateCorimTag(c corim.UnsignedCorim, t corim.Tag) (string, error) {
+	if len(t) < 4 {
+		// already reported by UnsignedCorim.Valid, if empty
+		return "", nil
+	}
+
+	cborTag, cborData := t[:3], t[3:]
+
+	switch {
+	case bytes.Equal(cborTag, corim.ComidTag):
+		cm, err := corim.UnmarshalComidFromCBOR(cborData, c.Profile)
+		if err != nil {
+			return "CoMID", fmt.Errorf("decoding failed: %w", err)
+		}
+
+		if err = checkDigestLengths(cm); err != nil {
+			return "CoMID", err
+		}
+
+		if err = checkRawValueMasks(cm); err != nil {
+			return "CoMID", err
+		}
+
+		if err = cm.Valid(); err != nil {
+			return "CoMID", err
+		}
+	case bytes.Equal(cborTag, corim.CoswidTag):
+		var sw swid.SoftwareIdentity
+		if err := sw.FromCBOR(cborData); err != nil {
+			return "CoSWID", fmt.Errorf("decoding failed: %w", err)
+		}
+	case bytes.Equal(cborTag, cots.CotsTag):
+		var cts cots.ConciseTaStore
+		if err := cts.FromCBOR(cborData); err != nil {
+			return "CoTS", fmt.Errorf("decoding failed: %w", err)
+		}
+
+		if err := cts.Valid(); err != nil {
+			return "CoTS", err
+		}
+	}
+
+	return "", nil
+}
+
+func init() {
+	corimCmd.AddCommand(corimValidateCmd)
+}
@@ -0,0 +1,220 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/corim"
+)
+
+func newTestUnsignedCorim(t *testing.T, profile string) []byte {
+	u := corim.NewUnsignedCorim().SetID("test")
+	require.NotNil(t, u.AddComid(newTestComid(t)))
+
+	if profile != "" {
+		require.NotNil(t, u.SetProfile(profile))
+	}
+
+	data, err := u.ToCBOR()
+	require.NoError(t, err)
+
+	return data
+}
+
+func Test_CorimValidateCmd_unknown_argument(t *testing.T) {
+	cmd := NewCorimValidateCmd()
+
+	args := []string{"--unknown-argument=val"}
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "unknown flag: --unknown-argument")
+}
+
+func Test_CorimValidateCmd_no_file(t *testing.T) {
+	cmd := NewCorimValidateCmd()
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "no CoRIM supplied")
+}
+
+func Test_CorimValidateCmd_file_not_found(t *testing.T) {
+	cmd := NewCorimValidateCmd()
+
+	args := []string{"--file=nonexistent.cbor"}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "error loading unsigned CoRIM from nonexistent.cbor: open nonexistent.cbor: file does not exist")
+}
+
+func Test_CorimValidateCmd_bad_corim(t *testing.T) {
+	cmd := NewCorimValidateCmd()
+
+	args := []string{"--file=bad.cbor"}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "bad.cbor", []byte{0xff, 0xff}, 0644)
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	assert.ErrorContains(t, err, "error decoding unsigned CoRIM from bad.cbor")
+}
+
+func Test_CorimValidateCmd_ok(t *testing.T) {
+	cmd := NewCorimValidateCmd()
+
+	args := []string{
+		"--file=ok.cbor",
+		"--meta=ok.json",
+	}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "ok.cbor", testCorimValid, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ok.json", testMetaValid, 0644)
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	assert.NoError(t, err)
+}
+
+func Test_CorimValidateCmd_bad_meta(t *testing.T) {
+	cmd := NewCorimValidateCmd()
+
+	args := []string{
+		"--file=ok.cbor",
+		"--meta=bad.json",
+	}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "ok.cbor", testCorimValid, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "bad.json", []byte(`{"signer": {}}`), 0644)
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	assert.EqualError(t, err, `error decoding CoRIM Meta from bad.json: missing mandatory field "Name" ("name")`)
+}
+
+func Test_CorimValidateCmd_profile_ok(t *testing.T) {
+	cmd := NewCorimValidateCmd()
+
+	args := []string{
+		"--file=ok.cbor",
+		"--profile=http://arm.com/psa/iot/1",
+	}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "ok.cbor", newTestUnsignedCorim(t, "http://arm.com/psa/iot/1"), 0644)
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	assert.NoError(t, err)
+}
+
+func Test_CorimValidateCmd_profile_mismatch(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "other.cbor", newTestUnsignedCorim(t, "http://example.com/other"), 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "none.cbor", newTestUnsignedCorim(t, ""), 0644)
+	require.NoError(t, err)
+
+	err = validateCorim("other.cbor", "", "http://arm.com/psa/iot/1")
+	assert.EqualError(t, err, `error validating CoRIM against profile "http://arm.com/psa/iot/1": CoRIM profile mismatch: expected "http://arm.com/psa/iot/1", got "http://example.com/other"`)
+
+	err = validateCorim("none.cbor", "", "http://arm.com/psa/iot/1")
+	assert.EqualError(t, err, `error validating CoRIM against profile "http://arm.com/psa/iot/1": CoRIM profile mismatch: expected "http://arm.com/psa/iot/1", got none`)
+}
+
+func Test_CorimValidateCmd_aggregate_problems(t *testing.T) {
+	u := corim.NewUnsignedCorim().SetID("test")
+	require.NotNil(t, u.AddComid(newTestComid(t)))
+
+	// faulty tags are added raw, as AddComid and AddCots validate them
+	u.Tags = append(u.Tags,
+		append(append(corim.Tag{}, corim.ComidTag...), shortDigestComidCBOR(t)...),
+		corim.Tag{0xde, 0xad, 0xbe, 0xef},
+		append(append(corim.Tag{}, corim.ComidTag...), 0xa0),
+	)
+
+	data, err := u.ToCBOR()
+	require.NoError(t, err)
+
+	fs = afero.NewMemMapFs()
+	err = afero.WriteFile(fs, "bad.cbor", data, 0644)
+	require.NoError(t, err)
+
+	err = validateCorim("bad.cbor", "", "")
+	require.Error(t, err)
+
+	lines := strings.Split(err.Error(), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, "error validating CoRIM, 2 problem(s) found:", lines[0])
+	assert.Regexp(t, `^  tag \[1\] \(CoMID\): digest length mismatch: reference-values: .* got 20$`, lines[1])
+	assert.Regexp(t, `^  tag \[3\] \(CoMID\): `, lines[2])
+}
+
+func Test_CorimVerifyCmd_validate_tags(t *testing.T) {
+	u := corim.NewUnsignedCorim().SetID("test")
+	require.NotNil(t, u.AddComid(newTestComid(t)))
+
+	// faulty tags are added raw, as AddComid validates them
+	u.Tags = append(u.Tags,
+		append(append(corim.Tag{}, corim.ComidTag...), shortDigestComidCBOR(t)...),
+		append(append(corim.Tag{}, corim.ComidTag...), 0xa0),
+	)
+
+	signer, err := corim.NewSignerFromJWK(testECKey)
+	require.NoError(t, err)
+
+	s := corim.SignedCorim{UnsignedCorim: *u}
+	s.Meta.SetSigner("ACME Ltd signing key", nil)
+
+	data, err := s.Sign(signer)
+	require.NoError(t, err)
+
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "bad.cbor", data, 0644))
+	require.NoError(t, afero.WriteFile(fs, "ok.cbor", newTestSignedCorimWithComids(t, 2), 0644))
+	require.NoError(t, afero.WriteFile(fs, "ok.jwk", testECKey, 0644))
+
+	cmd := NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=ok.cbor", "--key=ok.jwk", "--validate-tags"})
+	assert.NoError(t, cmd.Execute())
+
+	cmd = NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=bad.cbor", "--key=ok.jwk", "--validate-tags"})
+	err = cmd.Execute()
+	require.Error(t, err)
+
+	lines := strings.Split(err.Error(), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, "error verifying bad.cbor: 2/3 tag(s) not valid:", lines[0])
+	assert.Regexp(t, `^  tag \[1\] \(CoMID\): digest length mismatch: `, lines[1])
+	assert.Regexp(t, `^  tag \[2\] \(CoMID\): `, lines[2])
+
+	// without the switch, only the signature is verified
+	cmd = NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=bad.cbor", "--key=ok.jwk"})
+	assert.NoError(t, cmd.Execute())
+
+	// the signature is checked first
+	require.NoError(t, afero.WriteFile(fs, "other.jwk", testEdDSAKey, 0644))
+
+	cmd = NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=bad.cbor", "--key=other.jwk", "--validate-tags"})
+	assert.ErrorContains(t, cmd.Execute(), "error verifying bad.cbor with key other.jwk: ")
+}
@@ -5,19 +5,122 @@ package cmd
 
 import (
 	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 	"github.com/veraison/corim/corim"
 )
 
 var (
-	corimVerifyCorimFile *string
-	corimVerifyKeyFile   *string
+	corimVerifyCorimFile       *string
+	corimVerifyKeyFile         *string
+	corimVerifyStrictDecode    *bool
+	corimVerifyExpectedID      *string
+	corimVerifyExpectedProfile *string
+	corimVerifyRequireProfiles []string
+	corimVerifyTrustAnchors    []string
+	corimVerifyTSATrustAnchors []string
+	corimVerifySystemRoots     *bool
+	corimVerifyMaxSigningSkew  *time.Duration
+	corimVerifyMaxAge          *time.Duration
+	corimVerifyPayloadSHA256   *string
+	corimVerifyTimezone        *string
+	corimVerifyDirs            []string
+	corimVerifyInputGlobs      []string
+	corimVerifySince           *string
+	corimVerifyUntil           *string
+	corimVerifyPrintChain      *bool
+	corimVerifyQuorum          *int
+	corimVerifyQuorumKeys      []string
+	corimVerifyExpectedKeyID   *string
+	corimVerifyIgnoreKeyUsage  *bool
+	corimVerifyUseEmbeddedKey  *bool
+	corimVerifySequence        *bool
+	corimVerifyAllowSelfSigned *bool
+	corimVerifyJUnitFile       *string
+	corimVerifySelfConsistent  *bool
+	corimVerifyValidateTags    *bool
+	corimVerifySignatureFile   *string
+	corimVerifyPayloadFile     *string
+	corimVerifyAllowedAlgs     []string
+	corimVerifyExtract         *string
+	corimVerifyURL             *string
+	corimVerifyCacheDir        *string
+	corimVerifyUnprotectedAlg  *bool
+	corimVerifyMacKeyFile      *string
+	corimVerifyStats           *bool
+	corimVerifyStatsFormat     *string
 )
 
+// verifyOptions collects the optional checks applied by verify on top of the
+// signature verification
+type verifyOptions struct {
+	strictDecode     bool
+	expectedID       string
+	expectedProfile  string
+	profileAllowlist profileAllowlist
+	trustAnchorFiles []string
+	systemRoots      bool
+	tsaAnchorFiles   []string
+	maxSigningSkew   time.Duration
+	maxAge           time.Duration
+	payloadSHA256    string
+	timezone         *time.Location
+	printChain       bool
+	expectedKeyID    string
+	ignoreKeyUsage   bool
+	useEmbeddedKey   bool
+	allowSelfSigned  bool
+	selfConsistent   bool
+	validateTags     bool
+	algPolicy        algorithmPolicy
+	unprotectedAlg   bool
+	// if not empty, the format (text or json) in which the statistics of
+	// the verified CoRIM are printed
+	stats string
+	// if not nil, the value to print from the verified payload
+	extract jsonPath
+}
+
+// quiet tells whether the output is reserved for the extracted value or for
+// the JSON statistics, so that it can be used by scripts as is: no progress
+// line is then printed
+func (o verifyOptions) quiet() bool {
+	return o.extract != nil || o.stats == summaryFormatJSON
+}
+
+// progressf prints a progress line, unless quiet
+func progressf(quiet bool, format string, a ...interface{}) {
+	if !quiet {
+		fmt.Printf(format, a...)
+	}
+}
+
+// warnf prints a warning (or any other line that must not be lost), to stderr
+// if quiet, so that it does not corrupt the machine-readable output
+func warnf(quiet bool, format string, a ...interface{}) {
+	fmt.Fprintf(diagnosticsOutput(quiet), format, a...)
+}
+
+// diagnosticsOutput returns where warnf prints
+func diagnosticsOutput(quiet bool) io.Writer {
+	if quiet {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
 var corimVerifyCmd = NewCorimVerifyCmd()
 
 func NewCorimVerifyCmd() *cobra.Command {
@@ -30,6 +133,201 @@ func NewCorimVerifyCmd() *cobra.Command {
 	file key.jwk
 	
 	  cocli corim verify --file=signed-corim.cbor --key=key.jwk
+
+	The key file format is detected automatically: besides JWK, the key can be
+	supplied as an X.509 certificate or as a raw SubjectPublicKeyInfo, in
+	either DER or PEM format
+
+	  cocli corim verify --file=signed-corim.cbor --key=pubkey.pem
+
+	Fetch the signed CoRIM published at https://example.com/corim.cbor and
+	verify it, without saving it first.  The credentials of the auth method
+	set in the cocli configuration (as used by "corim submit"), if any, are
+	sent along with the request.  A copy of the CoRIM is kept in cache.d/
+	together with its ETag, so that it is only downloaded again if it changed
+
+	  cocli corim verify --url=https://example.com/corim.cbor --key=key.jwk \
+	    	--cache-dir=cache.d
+
+	Additionally, check that the CoRIM has the expected id and profile
+
+	  cocli corim verify --file=signed-corim.cbor --key=key.jwk \
+	    	--expected-id=5c57e8f4-46cd-421b-91c9-08cf93e13cfc \
+	    	--expected-profile=http://arm.com/psa/iot/1
+
+	Only accept the CoRIMs whose profile is the PSA or the CCA platform one.
+	The profile is checked right after decoding, before the signature, and the
+	actual profile is reported when it is not in the list
+
+	  cocli corim verify --file=signed-corim.cbor --key=key.jwk \
+	    	--require-profile-in=http://arm.com/psa/iot/1,http://arm.com/cca/ssd/1
+
+	Check that the COSE kid header is the RFC 7638 (SHA-256) JWK thumbprint of
+	the verification key (e.g., as set by "corim sign --kid=thumbprint"), or
+	matches any other value supplied verbatim
+
+	  cocli corim verify --file=signed-corim.cbor --key=key.jwk \
+	    	--expected-kid=thumbprint
+
+	Verify the signed CoRIM signed-corim.cbor using the signing certificate
+	carried in its COSE header, which must chain up to the trust anchor in
+	root.pem or to any of the system roots
+
+	  cocli corim verify --file=signed-corim.cbor \
+	    	--trust-anchor=root.pem \
+	    	--system-roots
+
+	Verify signed-corim.cbor, also requiring the RFC 3161 timestamp token added
+	by "corim sign --tsa-url" to cover its signature and to be issued by a TSA
+	whose certificate chains up to the trust anchor in tsa-root.pem
+
+	  cocli corim verify --file=signed-corim.cbor --key=key.jwk \
+	    	--tsa-trust-anchor=tsa-root.pem
+
+	Verify signed-corim.cbor in an air-gapped environment: the global --offline
+	switch forbids any network access, failing where it would be needed, and
+	reports the revocation checks (OCSP, CRL) that are not performed
+
+	  cocli corim verify --offline --file=signed-corim.cbor \
+	    	--trust-anchor=root.pem
+
+	For development only, accept a self-signed signing certificate as its own
+	trust anchor (a warning is printed when doing so).  The signature is still
+	verified with the key of the certificate
+
+	  cocli corim verify --file=signed-corim.cbor --allow-self-signed
+
+	The key usage (if restricted) of the signing certificate must include
+	digitalSignature and its extended key usage (if restricted) must include
+	codeSigning or any, unless --ignore-key-usage is given.
+
+	Verify the signed CoRIM signed-corim.cbor using the public key embedded in
+	its COSE header by "corim sign --embed-public-key".  The embedded key is
+	not authenticated: its JWK thumbprint is printed with a warning, so that it
+	can be checked against a known value (trust on first use)
+
+	  cocli corim verify --file=signed-corim.cbor --use-embedded-key
+
+	If the COSE header carries a signing time (the "iat" claim of a CWT Claims
+	header), its distance from the CoRIM Meta validity not-before is reported,
+	and a warning is printed if the signing time is outside the validity window
+	or, if --max-signing-skew is given, too far from the not-before
+
+	  cocli corim verify --file=signed-corim.cbor --key=key.jwk \
+	    	--max-signing-skew=24h
+
+	Fail unless the CoRIM was signed within the last 30 days.  The age of the
+	CoRIM is computed from its signing time or, if there is none, from the
+	CoRIM Meta validity not-before, and is reported
+
+	  cocli corim verify --file=signed-corim.cbor --key=key.jwk \
+	    	--max-age=720h
+
+	Additionally, check that the SHA-256 of the COSE payload (i.e., of the
+	unsigned CoRIM) matches the supplied value
+
+	  cocli corim verify --file=signed-corim.cbor --key=key.jwk \
+	    	--expected-payload-sha256=<hex-encoded digest>
+
+	Timestamps (e.g., the signing time) are reported in UTC, unless another
+	IANA time zone is supplied
+
+	  cocli corim verify --file=signed-corim.cbor --key=key.jwk \
+	    	--timezone=America/New_York
+
+	Print the subject, issuer, serial number, validity and SHA-256 fingerprint
+	of each certificate in the COSE x5chain header before verifying, e.g., to
+	diagnose why the chain cannot be built
+
+	  cocli corim verify --file=signed-corim.cbor \
+	    	--trust-anchor=root.pem --print-chain
+
+	Verify all the signed CoRIMs (*.cbor) in the archive/ directory whose CoRIM
+	Meta validity overlaps the year 2024.  CoRIMs outside of the window are
+	skipped
+
+	  cocli corim verify --dir=archive --key=key.jwk \
+	    	--since=2024-01-01 --until=2024-12-31T23:59:59Z
+
+	Verify all the signed CoRIMs found anywhere below the releases/ directory,
+	using the same pattern syntax on every platform
+
+	  cocli corim verify --input-glob='releases/**/*.cbor' --key=key.jwk
+
+	Verify each of the signed CoRIMs concatenated, as a CBOR sequence, in
+	signed-corims.cborseq, reporting the result for each of them
+
+	  cocli corim verify --file=signed-corims.cborseq --sequence --key=key.jwk
+
+	Verify the multi-signed (COSE Sign) CoRIM multi-signed-corim.cbor, which
+	succeeds if at least 2 of its signatures verify with distinct keys among
+	the candidate keys a.jwk, b.jwk and c.jwk
+
+	  cocli corim verify --file=multi-signed-corim.cbor --quorum=2 \
+	    	--quorum-key=a.jwk --quorum-key=b.jwk --quorum-key=c.jwk
+
+	Same as above, but with signers identified by the certificates in the
+	x5chain header of their signatures, which must chain up to root.pem
+
+	  cocli corim verify --file=multi-signed-corim.cbor --quorum=2 \
+	    	--trust-anchor=root.pem
+
+	Additionally, check that the CoRIM is self-consistent, i.e., that the
+	certificate-based keys in its CoMIDs chain to the trust anchors carried by
+	its own CoTS tags
+
+	  cocli corim verify --file=signed-corim.cbor --key=key.jwk --self-consistent
+
+	Additionally, once the signature is verified, decode and validate each of
+	the CoMID, CoSWID and CoTS tags of the CoRIM (as "corim validate" does),
+	e.g., for CoRIMs from less-trusted producers: a valid signature says
+	nothing about the structure of the content
+
+	  cocli corim verify --file=signed-corim.cbor --key=key.jwk --validate-tags
+
+	Verify the detached COSE Sign1 signature in sig.cbor (e.g., produced by an
+	external signing tool) over the unsigned CoRIM in payload.cbor.  If the
+	signature is a hash envelope, i.e., its protected header carries a
+	payload_hash_alg (258), the signature covers the hash of payload.cbor and
+	can only be verified with --key
+
+	  cocli corim verify --signature=sig.cbor --payload=payload.cbor --key=key.jwk
+
+	Refuse to verify the CoRIM, even if its signature is valid, unless it is
+	signed with ES384 or ES512, e.g., after ES256 has been deprecated
+
+	  cocli corim verify --file=signed-corim.cbor --key=key.jwk \
+	    	--allowed-algs=ES384,ES512
+
+	The signature algorithm must be in the protected header of the COSE Sign1,
+	where it is covered by the signature.  Only if the signer cannot be fixed,
+	accept (with a warning) an algorithm found in the unprotected header
+
+	  cocli corim verify --file=signed-corim.cbor --key=key.jwk \
+	    	--allow-unprotected-alg
+
+	Once verified, print the digest of the first reference value of the first
+	CoMID, and nothing else.  The expression is evaluated on the payload
+	rendered as a full CoRIM document (see "corim create --full"), i.e., with
+	its CoMIDs, CoSWIDs and CoTSs inlined in the comids, coswids and cots
+	arrays, and its CoRIM Meta in the meta object
+
+	  cocli corim verify --file=signed-corim.cbor --key=key.jwk \
+	    	--extract='$.comids[0].triples.reference-values[0].measurements[0].value.digests[0]'
+
+	Verify the MAC of the COSE Mac0-protected CoRIM mac-corim.cbor using the
+	symmetric key in JWK format (kty "oct") from file mac-key.jwk.  A valid MAC
+	only shows that the CoRIM was produced by a holder of the shared key, which
+	includes any verifier: it is not a signature
+
+	  cocli corim verify --file=mac-corim.cbor --mac-key=mac-key.jwk
+
+	Once verified, print the number of reference values, endorsed values,
+	verification keys and trust anchors found across the tags of the CoRIM, as
+	JSON, e.g., for an inventory system to pick up
+
+	  cocli corim verify --file=signed-corim.cbor --key=key.jwk \
+	    	--stats --format=json
 	`,
 
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -37,67 +335,909 @@ func NewCorimVerifyCmd() *cobra.Command {
 				return err
 			}
 
+			loc, err := loadTimezone(*corimVerifyTimezone)
+			if err != nil {
+				return err
+			}
+
+			policy, err := newAlgorithmPolicy(corimVerifyAllowedAlgs, nil)
+			if err != nil {
+				return err
+			}
+
+			allowlist, err := newProfileAllowlist(corimVerifyRequireProfiles)
+			if err != nil {
+				return err
+			}
+
+			var extract jsonPath
+
+			if *corimVerifyExtract != "" {
+				if extract, err = parseJSONPath(*corimVerifyExtract); err != nil {
+					return fmt.Errorf("invalid --extract %q: %w", *corimVerifyExtract, err)
+				}
+			}
+
 			// checkCorimVerifyArgs makes sure corimVerifyCorimFile is not nil
-			err := verify(*corimVerifyCorimFile, *corimVerifyKeyFile)
+			opts := verifyOptions{
+				strictDecode:     *corimVerifyStrictDecode,
+				expectedID:       *corimVerifyExpectedID,
+				expectedProfile:  *corimVerifyExpectedProfile,
+				profileAllowlist: allowlist,
+				trustAnchorFiles: corimVerifyTrustAnchors,
+				tsaAnchorFiles:   corimVerifyTSATrustAnchors,
+				systemRoots:      *corimVerifySystemRoots,
+				maxSigningSkew:   *corimVerifyMaxSigningSkew,
+				maxAge:           *corimVerifyMaxAge,
+				payloadSHA256:    *corimVerifyPayloadSHA256,
+				timezone:         loc,
+				printChain:       *corimVerifyPrintChain,
+				expectedKeyID:    *corimVerifyExpectedKeyID,
+				ignoreKeyUsage:   *corimVerifyIgnoreKeyUsage,
+				useEmbeddedKey:   *corimVerifyUseEmbeddedKey,
+				allowSelfSigned:  *corimVerifyAllowSelfSigned,
+				selfConsistent:   *corimVerifySelfConsistent,
+				validateTags:     *corimVerifyValidateTags,
+				algPolicy:        policy,
+				unprotectedAlg:   *corimVerifyUnprotectedAlg,
+				extract:          extract,
+			}
+
+			if *corimVerifyStats {
+				opts.stats = *corimVerifyStatsFormat
+			}
+
+			quiet := opts.quiet()
+
+			if *corimVerifyMacKeyFile != "" {
+				err = verifyMac0(*corimVerifyCorimFile, *corimVerifyMacKeyFile, opts)
+				if err != nil {
+					return err
+				}
+				if !quiet {
+					fmt.Printf(">> %q verified (MAC, not signature)\n", *corimVerifyCorimFile)
+				}
+
+				return nil
+			}
+
+			if *corimVerifyQuorum != 0 {
+				err = verifyQuorum(*corimVerifyCorimFile, corimVerifyQuorumKeys, *corimVerifyQuorum, opts)
+				if err != nil {
+					return err
+				}
+				fmt.Printf(">> %q verified (quorum of %d met)\n", *corimVerifyCorimFile, *corimVerifyQuorum)
+
+				return nil
+			}
+
+			if len(corimVerifyDirs) != 0 || len(corimVerifyInputGlobs) != 0 {
+				window, err := newValidityWindow(*corimVerifySince, *corimVerifyUntil)
+				if err != nil {
+					return err
+				}
+
+				return withJUnitReport(*corimVerifyJUnitFile, "corim verify", func(report *junitReport) error {
+					return verifyBatch(corimVerifyDirs, corimVerifyInputGlobs, *corimVerifyKeyFile, window, opts, report)
+				})
+			}
+
+			if *corimVerifySignatureFile != "" {
+				err = verifyDetached(*corimVerifySignatureFile, *corimVerifyPayloadFile, *corimVerifyKeyFile, opts)
+				if err != nil {
+					return err
+				}
+				if !quiet {
+					fmt.Printf(">> %q verified over %q\n", *corimVerifySignatureFile, *corimVerifyPayloadFile)
+				}
+
+				return nil
+			}
+
+			if *corimVerifyURL != "" {
+				return verifyURL(*corimVerifyURL, *corimVerifyCacheDir, *corimVerifyKeyFile, opts)
+			}
+
+			if *corimVerifySequence {
+				return withJUnitReport(*corimVerifyJUnitFile, "corim verify", func(report *junitReport) error {
+					return verifySequence(*corimVerifyCorimFile, *corimVerifyKeyFile, opts, report)
+				})
+			}
+
+			err = verify(*corimVerifyCorimFile, *corimVerifyKeyFile, opts)
 			if err != nil {
 				return err
 			}
-			fmt.Printf(">> %q verified\n", *corimVerifyCorimFile)
+
+			if !quiet {
+				fmt.Printf(">> %q verified\n", *corimVerifyCorimFile)
+			}
 
 			return nil
 		},
 	}
 
 	corimVerifyCorimFile = cmd.Flags().StringP("file", "f", "", "a signed CoRIM file (in CBOR format)")
-	corimVerifyKeyFile = cmd.Flags().StringP("key", "k", "", "verification key in JWK format")
+	corimVerifyKeyFile = cmd.Flags().StringP("key", "k", "", "verification key (JWK, or X.509 certificate or SubjectPublicKeyInfo in DER or PEM format)")
+	corimVerifyStrictDecode = cmd.Flags().Bool("strict-decode", false, "reject CoRIMs (and tags) carrying fields that are not understood")
+	corimVerifyExpectedID = cmd.Flags().String("expected-id", "", "fail unless the CoRIM id matches the supplied value")
+	corimVerifyExpectedProfile = cmd.Flags().String("expected-profile", "", "fail unless the CoRIM profile matches the supplied value")
+	cmd.Flags().StringSliceVar(
+		&corimVerifyRequireProfiles, "require-profile-in", []string{}, "fail, right after decoding, unless the CoRIM profile is one of these (comma-separated, or repeated)",
+	)
+	corimVerifyExpectedKeyID = cmd.Flags().String(
+		"expected-kid", "", `fail unless the COSE kid header matches the supplied value, or "thumbprint" for the JWK thumbprint of the verification key`,
+	)
+
+	cmd.Flags().StringArrayVar(
+		&corimVerifyTrustAnchors, "trust-anchor", []string{}, "a trust anchor certificate file (in DER or PEM format) used instead of --key",
+	)
+
+	cmd.Flags().StringArrayVar(
+		&corimVerifyTSATrustAnchors, "tsa-trust-anchor", []string{},
+		"a trust anchor certificate file (in DER or PEM format) for the TSA of the timestamp token, which is then required (can be repeated)",
+	)
+
+	corimVerifySystemRoots = cmd.Flags().Bool("system-roots", false, "use the system certificate pool as trust anchors, instead of --key")
+	corimVerifyUnprotectedAlg = cmd.Flags().Bool(
+		"allow-unprotected-alg", false, "accept a signature algorithm found in the unprotected header only, where it is not covered by the signature (insecure)",
+	)
+	corimVerifyAllowSelfSigned = cmd.Flags().Bool(
+		"allow-self-signed", false, "accept a self-signed signing certificate as its own trust anchor (for development only)",
+	)
+	corimVerifyIgnoreKeyUsage = cmd.Flags().Bool(
+		"ignore-key-usage", false, "do not fail if the key usage of the signing certificate does not permit signing (with --trust-anchor, --system-roots or --allow-self-signed)",
+	)
+	corimVerifyUseEmbeddedKey = cmd.Flags().Bool(
+		"use-embedded-key", false, "verify using the (unauthenticated) public key embedded in the COSE header, instead of --key",
+	)
+	corimVerifySelfConsistent = cmd.Flags().Bool(
+		"self-consistent", false, "also check that the certificate-based keys in the CoMIDs chain to the trust anchors in the CoTS tags of the same CoRIM",
+	)
+	corimVerifyValidateTags = cmd.Flags().Bool(
+		"validate-tags", false, "also decode and validate each of the tags of the verified CoRIM, reporting those that are not valid",
+	)
+	corimVerifyPayloadSHA256 = cmd.Flags().String(
+		"expected-payload-sha256", "", "fail unless the SHA-256 of the COSE payload matches the supplied (hex-encoded) value",
+	)
+	cmd.Flags().StringArrayVar(
+		&corimVerifyDirs, "dir", []string{}, "a directory containing signed CoRIM files (*.cbor) to verify, instead of --file",
+	)
+	addInputGlobFlag(cmd, &corimVerifyInputGlobs, "signed CoRIM files to verify, instead of --file")
+
+	corimVerifySequence = cmd.Flags().Bool("sequence", false, "the --file is a CBOR sequence of signed CoRIMs, each of which is verified in turn")
+
+	corimVerifyJUnitFile = cmd.Flags().String(
+		"junit", "", "with --dir, --input-glob or --sequence, also save the per-CoRIM results to this file as a JUnit XML report",
+	)
+
+	corimVerifySignatureFile = cmd.Flags().String(
+		"signature", "", "a detached COSE Sign1 signature (in CBOR format) over the --payload, instead of --file",
+	)
+	corimVerifyPayloadFile = cmd.Flags().String(
+		"payload", "", "the unsigned CoRIM (in CBOR format) covered by the detached --signature",
+	)
+	corimVerifySince = cmd.Flags().String("since", "", "with --dir or --input-glob, skip CoRIMs whose validity ends before this time (RFC 3339 or YYYY-MM-DD)")
+	corimVerifyUntil = cmd.Flags().String("until", "", "with --dir or --input-glob, skip CoRIMs whose validity starts after this time (RFC 3339 or YYYY-MM-DD)")
+	corimVerifyPrintChain = cmd.Flags().Bool("print-chain", false, "print the certificates of the COSE x5chain header before verifying")
+	corimVerifyQuorum = cmd.Flags().Int("quorum", 0, "verify a multi-signed (COSE Sign) CoRIM, requiring this many signatures to verify with distinct --quorum-key keys or x5chain certificates chaining to the --trust-anchor certificates")
+	cmd.Flags().StringArrayVar(
+		&corimVerifyQuorumKeys, "quorum-key", []string{}, "a candidate verification key for --quorum (can be repeated)",
+	)
+	cmd.Flags().StringSliceVar(
+		&corimVerifyAllowedAlgs, "allowed-algs", []string{}, "refuse to verify unless the COSE algorithm of the signature is one of these (e.g., ES384,ES512)",
+	)
+	corimVerifyURL = cmd.Flags().String("url", "", "an http(s) URL from which the signed CoRIM is fetched, instead of --file")
+	corimVerifyCacheDir = cmd.Flags().String(
+		"cache-dir", "", "with --url, keep a copy of the fetched CoRIM in this directory, and only download it again if its ETag changed",
+	)
+	corimVerifyExtract = cmd.Flags().String(
+		"extract", "", "once verified, only print the value at this JSONPath (e.g., $.comids[0].tag-identity.id) of the payload, rendered as a full CoRIM document",
+	)
+	corimVerifyTimezone = cmd.Flags().String("timezone", defaultTimezone, "IANA time zone in which timestamps are rendered")
+	corimVerifyMaxAge = cmd.Flags().Duration(
+		"max-age", 0, "fail if the CoRIM was signed longer ago than this, according to its signing time or validity not-before (0 disables the check)",
+	)
+	corimVerifyMacKeyFile = cmd.Flags().String(
+		"mac-key", "", `verify the MAC of a COSE Mac0-protected CoRIM with this symmetric key (JWK, kty "oct"), instead of a signature`,
+	)
+	corimVerifyStats = cmd.Flags().Bool(
+		"stats", false, "once verified, print the number of reference values, endorsed values, verification keys and trust anchors across the tags of the CoRIM",
+	)
+	corimVerifyStatsFormat = cmd.Flags().String("format", summaryFormatText, "format of the --stats: text or json")
+	corimVerifyMaxSigningSkew = cmd.Flags().Duration(
+		"max-signing-skew", 0, "warn if the signing time is further than this from the validity not-before (0 disables the check)",
+	)
 
 	return cmd
 }
 
 func checkCorimVerifyArgs() error {
-	if corimVerifyCorimFile == nil || *corimVerifyCorimFile == "" {
+	hasFile := corimVerifyCorimFile != nil && *corimVerifyCorimFile != ""
+	hasDirs := len(corimVerifyDirs) != 0
+	hasGlobs := len(corimVerifyInputGlobs) != 0
+	batch := hasDirs || hasGlobs
+	hasSignature := corimVerifySignatureFile != nil && *corimVerifySignatureFile != ""
+	hasPayload := corimVerifyPayloadFile != nil && *corimVerifyPayloadFile != ""
+	hasURL := corimVerifyURL != nil && *corimVerifyURL != ""
+
+	if err := checkCorimVerifyURLArgs(hasURL, hasFile, batch, hasSignature); err != nil {
+		return err
+	}
+
+	if hasPayload && !hasSignature {
+		return errors.New("--payload can only be used together with --signature")
+	}
+
+	if hasSignature {
+		if !hasPayload {
+			return errors.New("--signature requires --payload")
+		}
+
+		if hasFile || hasDirs {
+			return errors.New("--signature cannot be used together with --file or --dir")
+		}
+
+		if hasGlobs {
+			return errors.New("--signature cannot be used together with --input-glob")
+		}
+
+		if corimVerifySequence != nil && *corimVerifySequence {
+			return errors.New("--sequence cannot be used together with --signature")
+		}
+
+		if corimVerifyQuorum != nil && *corimVerifyQuorum != 0 {
+			return errors.New("--quorum cannot be used together with --signature")
+		}
+	} else if !hasFile && !batch && !hasURL {
 		return errors.New("no CoRIM supplied")
 	}
 
-	if corimVerifyKeyFile == nil || *corimVerifyKeyFile == "" {
+	if hasFile && hasDirs {
+		return errors.New("--file cannot be used together with --dir")
+	}
+
+	if hasFile && hasGlobs {
+		return errors.New("--file cannot be used together with --input-glob")
+	}
+
+	if hasDirs && corimVerifySequence != nil && *corimVerifySequence {
+		return errors.New("--sequence cannot be used together with --dir")
+	}
+
+	if hasGlobs && corimVerifySequence != nil && *corimVerifySequence {
+		return errors.New("--sequence cannot be used together with --input-glob")
+	}
+
+	if !batch && corimVerifyJUnitFile != nil && *corimVerifyJUnitFile != "" &&
+		(corimVerifySequence == nil || !*corimVerifySequence) {
+		return errors.New("--junit can only be used together with --dir, --input-glob or --sequence")
+	}
+
+	if corimVerifyExtract != nil && *corimVerifyExtract != "" &&
+		((!hasFile && !hasURL) || (corimVerifySequence != nil && *corimVerifySequence)) {
+		return errors.New("--extract can only be used together with --file (without --sequence) or --url")
+	}
+
+	if !batch && ((corimVerifySince != nil && *corimVerifySince != "") ||
+		(corimVerifyUntil != nil && *corimVerifyUntil != "")) {
+		return errors.New("--since and --until can only be used together with --dir or --input-glob")
+	}
+
+	if err := checkCorimVerifyStatsArgs(hasFile, hasURL, hasSignature); err != nil {
+		return err
+	}
+
+	if corimVerifyMaxAge != nil && *corimVerifyMaxAge < 0 {
+		return errors.New("--max-age must not be negative")
+	}
+
+	useKey := corimVerifyKeyFile != nil && *corimVerifyKeyFile != ""
+	useTrustAnchors := len(corimVerifyTrustAnchors) != 0 ||
+		(corimVerifySystemRoots != nil && *corimVerifySystemRoots)
+
+	useSelfSigned := corimVerifyAllowSelfSigned != nil && *corimVerifyAllowSelfSigned
+
+	if !useTrustAnchors && !useSelfSigned && corimVerifyIgnoreKeyUsage != nil && *corimVerifyIgnoreKeyUsage {
+		return errors.New("--ignore-key-usage can only be used together with --trust-anchor, --system-roots or --allow-self-signed")
+	}
+
+	useEmbeddedKey := corimVerifyUseEmbeddedKey != nil && *corimVerifyUseEmbeddedKey
+
+	if corimVerifyMacKeyFile != nil && *corimVerifyMacKeyFile != "" {
+		return checkCorimVerifyMacArgs(hasFile, useKey || useTrustAnchors || useEmbeddedKey || useSelfSigned)
+	}
+
+	if offline && systemRootsMayUseNetwork && corimVerifySystemRoots != nil && *corimVerifySystemRoots {
+		return fmt.Errorf("--system-roots may access the network on %s, and cannot be used together with --offline", runtime.GOOS)
+	}
+
+	if err := checkCorimVerifyQuorumArgs(hasDirs, hasGlobs, useKey, useTrustAnchors); err != nil {
+		return err
+	}
+
+	if corimVerifyQuorum != nil && *corimVerifyQuorum != 0 {
+		return nil
+	}
+
+	if !useKey && !useTrustAnchors && !useEmbeddedKey && !useSelfSigned {
 		return errors.New("no key supplied")
 	}
 
+	if useSelfSigned && (useKey || useEmbeddedKey) {
+		return errors.New("--allow-self-signed cannot be used together with --key or --use-embedded-key")
+	}
+
+	if useKey && useTrustAnchors {
+		return errors.New("--key cannot be used together with --trust-anchor or --system-roots")
+	}
+
+	if useEmbeddedKey && (useKey || useTrustAnchors) {
+		return errors.New("--use-embedded-key cannot be used together with --key, --trust-anchor or --system-roots")
+	}
+
+	if corimVerifyPayloadSHA256 != nil && *corimVerifyPayloadSHA256 != "" {
+		if d, err := hex.DecodeString(*corimVerifyPayloadSHA256); err != nil || len(d) != sha256.Size {
+			return errors.New("invalid --expected-payload-sha256: expecting 64 hex characters")
+		}
+	}
+
+	return nil
+}
+
+func checkCorimVerifyURLArgs(hasURL, hasFile, batch, hasSignature bool) error {
+	if !hasURL {
+		if corimVerifyCacheDir != nil && *corimVerifyCacheDir != "" {
+			return errors.New("--cache-dir can only be used together with --url")
+		}
+
+		return nil
+	}
+
+	if hasFile || batch || hasSignature {
+		return errors.New("--url cannot be used together with --file, --dir, --input-glob or --signature")
+	}
+
+	if (corimVerifySequence != nil && *corimVerifySequence) || (corimVerifyQuorum != nil && *corimVerifyQuorum != 0) {
+		return errors.New("--url cannot be used together with --sequence or --quorum")
+	}
+
+	if err := checkOnline("--url"); err != nil {
+		return err
+	}
+
+	_, err := newRemoteCorim(*corimVerifyURL, "", nil)
+
+	return err
+}
+
+// verifyURL fetches the signed CoRIM at rawURL (see remoteCorim) and verifies
+// it
+func verifyURL(rawURL, cacheDir, keyFile string, opts verifyOptions) error {
+	r, err := newRemoteCorim(rawURL, cacheDir, cliConfig.Auth)
+	if err != nil {
+		return err
+	}
+
+	r.quiet = opts.quiet()
+
+	data, err := r.fetch()
+	if err != nil {
+		return err
+	}
+
+	if err = verifyCorimData(rawURL, data, keyFile, opts); err != nil {
+		return err
+	}
+
+	if !opts.quiet() {
+		fmt.Printf(">> %q verified\n", rawURL)
+	}
+
 	return nil
 }
 
-func verify(signedCorimFile, keyFile string) error {
+func verify(signedCorimFile, keyFile string, opts verifyOptions) error {
+	signedCorimCBOR, err := afero.ReadFile(fs, signedCorimFile)
+	if err != nil {
+		return fmt.Errorf("error loading signed CoRIM from %s: %w", signedCorimFile, err)
+	}
+
+	return verifyCorimData(signedCorimFile, signedCorimCBOR, keyFile, opts)
+}
+
+// verifySequence verifies each of the signed CoRIMs in the CBOR sequence in
+// file, reporting the result for each of them
+func verifySequence(file, keyFile string, opts verifyOptions, report *junitReport) error {
+	var verified, errs int
+
+	n, err := readCBORSequence(file, func(i int, item []byte) {
+		name := sequenceItemName(file, i)
+
+		if err := verifyCorimData(name, item, keyFile, opts); err != nil {
+			fmt.Printf(">> verification failed for %q: %v\n", name, err)
+			report.fail(name, err)
+			errs++
+			return
+		}
+
+		fmt.Printf(">> %q verified\n", name)
+		report.pass(name)
+		verified++
+	})
+
+	fmt.Printf(">> %d verified, %d failed\n", verified, errs)
+
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return fmt.Errorf("no signed CoRIMs found in %s", file)
+	}
+
+	if errs != 0 {
+		return fmt.Errorf("%d/%d verification(s) failed", errs, n)
+	}
+
+	return nil
+}
+
+// verifyCorimData verifies the signed CoRIM signedCorimCBOR, which is
+// referred to as signedCorimFile in messages
+func verifyCorimData(signedCorimFile string, signedCorimCBOR []byte, keyFile string, opts verifyOptions) error {
 	var (
-		signedCorimCBOR []byte
-		keyJWK          []byte
-		err             error
-		pkey            crypto.PublicKey
-		s               corim.SignedCorim
+		keyData []byte
+		err     error
+		pkey    crypto.PublicKey
+		s       corim.SignedCorim
 	)
 
-	if signedCorimCBOR, err = afero.ReadFile(fs, signedCorimFile); err != nil {
-		return fmt.Errorf("error loading signed CoRIM from %s: %w", signedCorimFile, err)
+	if isMac0(signedCorimCBOR) {
+		return fmt.Errorf("error decoding signed CoRIM from %s: the CoRIM is MAC-protected (COSE Mac0), not signed: use --mac-key", signedCorimFile)
 	}
 
+	// accept both the tagged and the untagged form of COSE_Sign1
+	signedCorimCBOR = tagSign1(signedCorimCBOR)
+
 	if err = s.FromCOSE(signedCorimCBOR); err != nil {
 		return fmt.Errorf("error decoding signed CoRIM from %s: %w", signedCorimFile, err)
 	}
 
-	if keyJWK, err = afero.ReadFile(fs, keyFile); err != nil {
-		return fmt.Errorf("error loading verifying key from %s: %w", keyFile, err)
+	if opts.strictDecode {
+		if err = checkUnknownSignedCorimFields(&s, signedCorimCBOR); err != nil {
+			return fmt.Errorf("error decoding signed CoRIM from %s: %w", signedCorimFile, err)
+		}
+	}
+
+	// there is no point in verifying a CoRIM that cannot be processed
+	if err = opts.profileAllowlist.check(s.UnsignedCorim); err != nil {
+		return fmt.Errorf("error verifying %s: %w", signedCorimFile, err)
+	}
+
+	msg, err := decodeSign1(signedCorimCBOR)
+	if err != nil {
+		return fmt.Errorf("error decoding signed CoRIM from %s: %w", signedCorimFile, err)
+	}
+
+	if err = checkAlgHeader(&msg.Headers, opts.unprotectedAlg, opts.quiet()); err != nil {
+		return fmt.Errorf("error verifying %s: %w", signedCorimFile, err)
+	}
+
+	if err = checkCOSEHeaders(&msg.Headers); err != nil {
+		return fmt.Errorf("error verifying %s: %w", signedCorimFile, err)
+	}
+
+	// checkCOSEHeaders makes sure the alg header is there
+	alg, _ := msg.Headers.Protected.Algorithm()
+
+	if err = opts.algPolicy.check(alg); err != nil {
+		return fmt.Errorf("error verifying %s: %w", signedCorimFile, err)
+	}
+
+	if opts.printChain {
+		printCertChain(diagnosticsOutput(opts.quiet()), &s, opts.timezone)
+	}
+
+	if opts.useEmbeddedKey {
+		if pkey, err = verifyWithEmbeddedKey(msg, opts.quiet()); err != nil {
+			return fmt.Errorf("error verifying %s with embedded key: %w", signedCorimFile, err)
+		}
+	} else if keyFile != "" {
+		if keyData, err = afero.ReadFile(fs, keyFile); err != nil {
+			return fmt.Errorf("error loading verifying key from %s: %w", keyFile, err)
+		}
+
+		if pkey, err = parsePublicKey(keyData); err != nil {
+			return fmt.Errorf("error loading verifying key from %s: %w", keyFile, err)
+		}
+
+		if err = verifySign1(msg, pkey); err != nil {
+			return fmt.Errorf("error verifying %s with key %s: %w", signedCorimFile, keyFile, err)
+		}
+	} else {
+		roots, err := loadTrustAnchors(opts.trustAnchorFiles, opts.systemRoots, opts.quiet())
+		if err != nil {
+			return err
+		}
+
+		if opts.allowSelfSigned && s.SigningCert != nil && isSelfSigned(s.SigningCert) {
+			warnf(opts.quiet(), ">> warning: accepting self-signed signing certificate %q as its own trust anchor (--allow-self-signed is for development only)\n",
+				s.SigningCert.Subject.String())
+			roots.AddCert(s.SigningCert)
+		}
+
+		if err = verifyCertChain(&s, roots); err != nil {
+			return fmt.Errorf("error verifying %s: %w", signedCorimFile, err)
+		}
+
+		if offline {
+			for _, note := range offlineRevocationNotes(append([]*x509.Certificate{s.SigningCert}, s.IntermediateCerts...)) {
+				warnf(opts.quiet(), ">> offline: %s\n", note)
+			}
+		}
+
+		if !opts.ignoreKeyUsage {
+			if err = checkSigningKeyUsage(s.SigningCert); err != nil {
+				return fmt.Errorf("error verifying %s: %w", signedCorimFile, err)
+			}
+		}
+
+		pkey = s.SigningCert.PublicKey
+
+		if err = verifySign1(msg, pkey); err != nil {
+			return fmt.Errorf("error verifying %s with signing certificate: %w", signedCorimFile, err)
+		}
+	}
+
+	if opts.expectedKeyID != "" {
+		if err = checkKeyID(coseKeyID(msg), opts.expectedKeyID, pkey); err != nil {
+			return fmt.Errorf("error verifying %s: %w", signedCorimFile, err)
+		}
 	}
 
-	if pkey, err = corim.NewPublicKeyFromJWK(keyJWK); err != nil {
-		return fmt.Errorf("error loading verifying key from %s: %w", keyFile, err)
+	if err = checkCorimExpectations(s.UnsignedCorim, opts.expectedID, opts.expectedProfile); err != nil {
+		return fmt.Errorf("error verifying %s: %w", signedCorimFile, err)
 	}
 
-	if err = s.Verify(pkey); err != nil {
-		return fmt.Errorf("error verifying %s with key %s: %w", signedCorimFile, keyFile, err)
+	if opts.selfConsistent {
+		if err = checkSelfConsistency(s.UnsignedCorim); err != nil {
+			return fmt.Errorf("error verifying %s: %w", signedCorimFile, err)
+		}
+	}
+
+	if opts.validateTags {
+		if err = checkCorimTags(s.UnsignedCorim); err != nil {
+			return fmt.Errorf("error verifying %s: %w", signedCorimFile, err)
+		}
+	}
+
+	if opts.payloadSHA256 != "" {
+		if err = checkPayloadSHA256(signedCorimCBOR, opts.payloadSHA256); err != nil {
+			return fmt.Errorf("error verifying %s: %w", signedCorimFile, err)
+		}
+	}
+
+	if err = reportSigningTime(&s, signedCorimCBOR, opts.maxSigningSkew, opts.timezone, opts.quiet()); err != nil {
+		return fmt.Errorf("error verifying %s: %w", signedCorimFile, err)
+	}
+
+	if err = checkTimestamp(msg, opts.tsaAnchorFiles, opts.timezone, opts.quiet()); err != nil {
+		return fmt.Errorf("error verifying %s: %w", signedCorimFile, err)
+	}
+
+	if opts.maxAge != 0 {
+		now := time.Now().UTC()
+		if opts.timezone != nil {
+			now = now.In(opts.timezone)
+		}
+
+		if err = checkMaxAge(msg, s.Meta.Validity, opts.maxAge, now, opts.quiet()); err != nil {
+			return fmt.Errorf("error verifying %s: %w", signedCorimFile, err)
+		}
+	}
+
+	if opts.stats != "" {
+		if err = reportCorimStats(s.UnsignedCorim, signedCorimFile, opts.stats); err != nil {
+			return err
+		}
+	}
+
+	// only now that the payload is authenticated
+	if opts.extract != nil {
+		var meta *corim.Meta
+		if _, ok := msg.Headers.Protected[corim.HeaderLabelCorimMeta]; ok {
+			meta = &s.Meta
+		}
+
+		if err = printExtracted(&s.UnsignedCorim, meta, opts.extract); err != nil {
+			return fmt.Errorf("error extracting %s from %s: %w", opts.extract, signedCorimFile, err)
+		}
 	}
 
 	return nil
 }
 
+// checkCorimVerifyQuorumArgs checks the consistency of --quorum and
+// --quorum-key with the other switches
+func checkCorimVerifyQuorumArgs(hasDirs, hasGlobs, hasKey, hasTrustAnchors bool) error {
+	quorum := 0
+	if corimVerifyQuorum != nil {
+		quorum = *corimVerifyQuorum
+	}
+
+	if quorum < 0 {
+		return errors.New("--quorum must not be negative")
+	}
+
+	if quorum == 0 {
+		if len(corimVerifyQuorumKeys) != 0 {
+			return errors.New("--quorum-key can only be used together with --quorum")
+		}
+		return nil
+	}
+
+	if hasDirs {
+		return errors.New("--quorum cannot be used together with --dir")
+	}
+
+	if hasGlobs {
+		return errors.New("--quorum cannot be used together with --input-glob")
+	}
+
+	if hasKey {
+		return errors.New("--quorum cannot be used together with --key")
+	}
+
+	if (corimVerifyStrictDecode != nil && *corimVerifyStrictDecode) ||
+		(corimVerifyPrintChain != nil && *corimVerifyPrintChain) ||
+		(corimVerifyPayloadSHA256 != nil && *corimVerifyPayloadSHA256 != "") ||
+		(corimVerifyMaxSigningSkew != nil && *corimVerifyMaxSigningSkew != 0) ||
+		(corimVerifyMaxAge != nil && *corimVerifyMaxAge != 0) ||
+		(corimVerifyExpectedKeyID != nil && *corimVerifyExpectedKeyID != "") ||
+		(corimVerifyUseEmbeddedKey != nil && *corimVerifyUseEmbeddedKey) ||
+		(corimVerifySequence != nil && *corimVerifySequence) ||
+		(corimVerifyAllowSelfSigned != nil && *corimVerifyAllowSelfSigned) ||
+		(corimVerifySelfConsistent != nil && *corimVerifySelfConsistent) ||
+		(corimVerifyValidateTags != nil && *corimVerifyValidateTags) ||
+		(corimVerifyUnprotectedAlg != nil && *corimVerifyUnprotectedAlg) ||
+		len(corimVerifyTSATrustAnchors) != 0 ||
+		(corimVerifyStats != nil && *corimVerifyStats) ||
+		(corimVerifyExtract != nil && *corimVerifyExtract != "") {
+		return errors.New("--quorum can only be combined with --expected-id, --expected-profile, --require-profile-in, --allowed-algs, --trust-anchor, --system-roots and --ignore-key-usage")
+	}
+
+	// with trust anchors, signers are not limited to the candidate keys
+	if !hasTrustAnchors && len(corimVerifyQuorumKeys) < quorum {
+		return fmt.Errorf("--quorum of %d cannot be met with %d candidate key(s)", quorum, len(corimVerifyQuorumKeys))
+	}
+
+	return nil
+}
+
+// checkCorimVerifyStatsArgs checks the consistency of --stats and --format
+// with the other switches
+func checkCorimVerifyStatsArgs(hasFile, hasURL, hasSignature bool) error {
+	stats := corimVerifyStats != nil && *corimVerifyStats
+
+	if corimVerifyStatsFormat != nil {
+		switch *corimVerifyStatsFormat {
+		case summaryFormatText, summaryFormatJSON:
+		default:
+			return fmt.Errorf("invalid --format %q: expecting text or json", *corimVerifyStatsFormat)
+		}
+
+		if *corimVerifyStatsFormat != summaryFormatText {
+			if !stats {
+				return errors.New("--format can only be used together with --stats")
+			}
+
+			if (!hasFile && !hasURL && !hasSignature) || (corimVerifySequence != nil && *corimVerifySequence) {
+				return errors.New("--format=json can only be used to verify a single CoRIM, with --file (without --sequence), --url or --signature")
+			}
+		}
+	}
+
+	if stats && corimVerifyExtract != nil && *corimVerifyExtract != "" {
+		return errors.New("--stats cannot be used together with --extract")
+	}
+
+	return nil
+}
+
+// checkCorimVerifyMacArgs checks the arguments of the verification of a
+// Mac0-protected CoRIM, which has no signature and hence no use for the
+// signature-related switches
+func checkCorimVerifyMacArgs(hasFile, hasKeys bool) error {
+	if !hasFile || (corimVerifySequence != nil && *corimVerifySequence) {
+		return errors.New("--mac-key can only be used together with --file (without --sequence)")
+	}
+
+	if hasKeys {
+		return errors.New("--mac-key cannot be used together with --key, --trust-anchor, --system-roots, --use-embedded-key or --allow-self-signed")
+	}
+
+	if corimVerifyQuorum != nil && *corimVerifyQuorum != 0 {
+		return errors.New("--mac-key cannot be used together with --quorum")
+	}
+
+	if (corimVerifyPrintChain != nil && *corimVerifyPrintChain) ||
+		(corimVerifyPayloadSHA256 != nil && *corimVerifyPayloadSHA256 != "") ||
+		(corimVerifyMaxSigningSkew != nil && *corimVerifyMaxSigningSkew != 0) ||
+		(corimVerifyMaxAge != nil && *corimVerifyMaxAge != 0) ||
+		(corimVerifyExpectedKeyID != nil && *corimVerifyExpectedKeyID != "") ||
+		(corimVerifyIgnoreKeyUsage != nil && *corimVerifyIgnoreKeyUsage) ||
+		(corimVerifySelfConsistent != nil && *corimVerifySelfConsistent) ||
+		(corimVerifyUnprotectedAlg != nil && *corimVerifyUnprotectedAlg) ||
+		len(corimVerifyTSATrustAnchors) != 0 ||
+		len(corimVerifyAllowedAlgs) != 0 ||
+		(corimVerifyExtract != nil && *corimVerifyExtract != "") {
+		return errors.New("--mac-key can only be combined with --expected-id, --expected-profile, --require-profile-in, --validate-tags, --strict-decode and --stats")
+	}
+
+	return nil
+}
+
+// verifyBatch verifies the signed CoRIMs found in dirs or matching globs,
+// skipping those whose CoRIM Meta validity does not overlap the window
+func verifyBatch(dirs, globs []string, keyFile string, window validityWindow, opts verifyOptions, report *junitReport) error {
+	files := filesList(nil, dirs, ".cbor")
+
+	if len(globs) != 0 {
+		matched, err := globFiles(globs)
+		if err != nil {
+			return err
+		}
+		files = append(files, matched...)
+	}
+
+	if len(files) == 0 {
+		return errors.New("no files found")
+	}
+
+	var verified, skipped, errs int
+
+	for _, file := range files {
+		if !window.isOpen() {
+			validity, err := loadMetaValidity(file)
+			if err != nil {
+				fmt.Printf(">> verification failed for %q: %v\n", file, err)
+				report.fail(file, err)
+				errs++
+				continue
+			}
+
+			if !window.overlaps(validity) {
+				reason := fmt.Sprintf("validity %s is outside of the window", formatValidity(validity, opts.timezone))
+				fmt.Printf(">> skipping %q: %s\n", file, reason)
+				report.skip(file, reason)
+				skipped++
+				continue
+			}
+		}
+
+		if err := verify(file, keyFile, opts); err != nil {
+			fmt.Printf(">> verification failed for %q: %v\n", file, err)
+			report.fail(file, err)
+			errs++
+			continue
+		}
+
+		fmt.Printf(">> %q verified\n", file)
+		report.pass(file)
+		verified++
+	}
+
+	fmt.Printf(">> %d verified, %d skipped, %d failed\n", verified, skipped, errs)
+
+	if errs != 0 {
+		return fmt.Errorf("%d/%d verification(s) failed", errs, len(files))
+	}
+
+	return nil
+}
+
+// checkPayloadSHA256 compares the SHA-256 of the payload of the COSE Sign1 in
+// buf against the expected (hex-encoded) value
+func checkPayloadSHA256(buf []byte, expected string) error {
+	msg, err := decodeSign1(buf)
+	if err != nil {
+		return err
+	}
+
+	return checkSHA256(msg.Payload, expected)
+}
+
+// checkSHA256 compares the SHA-256 of payload against the expected
+// (hex-encoded) value
+func checkSHA256(payload []byte, expected string) error {
+	sum := sha256.Sum256(payload)
+	actual := hex.EncodeToString(sum[:])
+
+	if !strings.EqualFold(actual, expected) {
+		return fmt.Errorf("payload SHA-256 mismatch: expected %s, got %s", strings.ToLower(expected), actual)
+	}
+
+	return nil
+}
+
+// reportSigningTime prints the signing time of the signed CoRIM (if any), its
+// skew from the CoRIM Meta validity not-before and any related warning.
+// Timestamps are rendered in loc (UTC if nil).  If quiet, only the warnings are
+// printed, to stderr.
+func reportSigningTime(s *corim.SignedCorim, buf []byte, maxSkew time.Duration, loc *time.Location, quiet bool) error {
+	msg, err := decodeSign1(buf)
+	if err != nil {
+		return err
+	}
+
+	st, err := signingTime(msg)
+	if err != nil || st == nil {
+		return err
+	}
+
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	signed := st.In(loc)
+	validity := validityIn(s.Meta.Validity, loc)
+
+	if skew, ok := signingSkew(signed, validity); ok {
+		progressf(quiet, ">> signing time %s, skew from validity not-before: %s\n", signed.Format(time.RFC3339), skew)
+	} else {
+		progressf(quiet, ">> signing time %s, no validity not-before to compare against\n", signed.Format(time.RFC3339))
+	}
+
+	for _, w := range checkSigningTime(signed, validity, maxSkew) {
+		warnf(quiet, ">> warning: %s\n", w)
+	}
+
+	return nil
+}
+
+// checkCorimExpectations compares the id and profile of the supplied CoRIM
+// against the expected values.  Empty expectations are not checked.
+func checkCorimExpectations(c corim.UnsignedCorim, expectedID, expectedProfile string) error {
+	if expectedID != "" {
+		actual := c.GetID()
+
+		if !sameCorimID(actual, expectedID) {
+			return fmt.Errorf("CoRIM id mismatch: expected %q, got %q", expectedID, actual)
+		}
+	}
+
+	if expectedProfile != "" {
+		if c.Profile == nil {
+			return fmt.Errorf("CoRIM profile mismatch: expected %q, got none", expectedProfile)
+		}
+
+		actual, err := c.Profile.Get()
+		if err != nil {
+			return fmt.Errorf("CoRIM profile: %w", err)
+		}
+
+		if actual != expectedProfile {
+			return fmt.Errorf("CoRIM profile mismatch: expected %q, got %q", expectedProfile, actual)
+		}
+	}
+
+	return nil
+}
+
+// sameCorimID compares two CoRIM ids, ignoring case differences in the textual
+// representation of UUIDs
+func sameCorimID(a, b string) bool {
+	ua, errA := uuid.Parse(a)
+	ub, errB := uuid.Parse(b)
+
+	if errA == nil && errB == nil {
+		return ua == ub
+	}
+
+	return a == b
+}
+
 func init() {
 	corimCmd.AddCommand(corimVerifyCmd)
 }
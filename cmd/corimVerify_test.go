@@ -4,11 +4,18 @@
 package cmd
 
 import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"strings"
 	"testing"
 
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/corim"
+	cose "github.com/veraison/go-cose"
 )
 
 func Test_CorimVerifyCmd_unknown_argument(t *testing.T) {
@@ -131,3 +138,448 @@ func Test_CorimVerifyCmd_ok(t *testing.T) {
 	err = cmd.Execute()
 	assert.NoError(t, err)
 }
+
+func Test_CorimVerifyCmd_ok_strict(t *testing.T) {
+	cmd := NewCorimVerifyCmd()
+
+	args := []string{
+		"--file=ok.cbor",
+		"--key=ok.jwk",
+		"--strict-decode",
+	}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "ok.cbor", testSignedCorimValid, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ok.jwk", testECKey, 0644)
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	assert.NoError(t, err)
+}
+
+func Test_CorimVerifyCmd_expected_id_and_profile_ok(t *testing.T) {
+	cmd := NewCorimVerifyCmd()
+
+	args := []string{
+		"--file=ok.cbor",
+		"--key=ok.jwk",
+		"--expected-id=5C57E8F4-46CD-421B-91C9-08CF93E13CFC",
+		"--expected-profile=http://arm.com/iot/profile/1",
+	}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "ok.cbor", testSignedCorimValid, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ok.jwk", testECKey, 0644)
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	assert.NoError(t, err)
+}
+
+func Test_CorimVerifyCmd_expected_id_mismatch(t *testing.T) {
+	cmd := NewCorimVerifyCmd()
+
+	args := []string{
+		"--file=ok.cbor",
+		"--key=ok.jwk",
+		"--expected-id=other-corim",
+	}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "ok.cbor", testSignedCorimValid, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ok.jwk", testECKey, 0644)
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	assert.EqualError(t, err, `error verifying ok.cbor: CoRIM id mismatch: expected "other-corim", got "5c57e8f4-46cd-421b-91c9-08cf93e13cfc"`)
+}
+
+func Test_CorimVerifyCmd_expected_profile_mismatch(t *testing.T) {
+	cmd := NewCorimVerifyCmd()
+
+	args := []string{
+		"--file=ok.cbor",
+		"--key=ok.jwk",
+		"--expected-profile=http://arm.com/psa/iot/1",
+	}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "ok.cbor", testSignedCorimValid, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ok.jwk", testECKey, 0644)
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	assert.EqualError(t, err, `error verifying ok.cbor: CoRIM profile mismatch: expected "http://arm.com/psa/iot/1", got "http://arm.com/iot/profile/1"`)
+}
+
+func Test_CorimVerifyCmd_key_and_trust_anchor(t *testing.T) {
+	cmd := NewCorimVerifyCmd()
+
+	args := []string{
+		"--file=ok.cbor",
+		"--key=ok.jwk",
+		"--trust-anchor=root.der",
+	}
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "--key cannot be used together with --trust-anchor or --system-roots")
+}
+
+func Test_CorimVerifyCmd_trust_anchor_ok(t *testing.T) {
+	pki := newTestPKI(t)
+
+	cmd := NewCorimVerifyCmd()
+
+	args := []string{
+		"--file=ok.cbor",
+		"--trust-anchor=root.pem",
+	}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "ok.cbor", pki.signedCorim(t), 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "root.pem", pki.rootPEM(), 0644)
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	assert.NoError(t, err)
+}
+
+func Test_CorimVerifyCmd_system_roots_and_trust_anchor_ok(t *testing.T) {
+	pki := newTestPKI(t)
+
+	cmd := NewCorimVerifyCmd()
+
+	args := []string{
+		"--file=ok.cbor",
+		"--trust-anchor=root.der",
+		"--system-roots",
+	}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "ok.cbor", pki.signedCorim(t), 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "root.der", pki.rootDER, 0644)
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	assert.NoError(t, err)
+}
+
+func Test_CorimVerifyCmd_system_roots_unavailable_quiet(t *testing.T) {
+	pki := newTestPKI(t)
+
+	saved := systemCertPool
+	defer func() { systemCertPool = saved }()
+
+	systemCertPool = func() (*x509.CertPool, error) {
+		return nil, errors.New("no system roots")
+	}
+
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "ok.cbor", pki.signedCorim(t), 0644))
+	require.NoError(t, afero.WriteFile(fs, "root.der", pki.rootDER, 0644))
+
+	// the warning about the system pool does not end up with the extracted
+	// value
+	cmd := NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=ok.cbor", "--trust-anchor=root.der", "--system-roots", "--extract=corim-id"})
+	require.NoError(t, withDisplayOutput("out.txt", cmd.Execute))
+
+	out := string(mustReadFile(t, "out.txt"))
+	assert.NotEmpty(t, out)
+	assert.NotContains(t, out, ">> ")
+}
+
+func Test_CorimVerifyCmd_untrusted_chain(t *testing.T) {
+	pki := newTestPKI(t)
+	other := newTestPKI(t)
+
+	cmd := NewCorimVerifyCmd()
+
+	args := []string{
+		"--file=ok.cbor",
+		"--trust-anchor=root.der",
+	}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "ok.cbor", pki.signedCorim(t), 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "root.der", other.rootDER, 0644)
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	assert.ErrorContains(t, err, "error verifying ok.cbor: certificate chain validation failed: x509: certificate signed by unknown authority")
+}
+
+func Test_CorimVerifyCmd_trust_anchor_no_signing_cert(t *testing.T) {
+	pki := newTestPKI(t)
+
+	cmd := NewCorimVerifyCmd()
+
+	args := []string{
+		"--file=ok.cbor",
+		"--trust-anchor=root.der",
+	}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "ok.cbor", testSignedCorimValid, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "root.der", pki.rootDER, 0644)
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	assert.EqualError(t, err, "error verifying ok.cbor: no signing certificate found in COSE header")
+}
+
+func Test_CorimVerifyCmd_expected_payload_sha256_ok(t *testing.T) {
+	msg, err := decodeSign1(testSignedCorimValid)
+	require.NoError(t, err)
+	sum := sha256.Sum256(msg.Payload)
+
+	cmd := NewCorimVerifyCmd()
+
+	args := []string{
+		"--file=ok.cbor",
+		"--key=ok.jwk",
+		"--expected-payload-sha256=" + strings.ToUpper(hex.EncodeToString(sum[:])),
+	}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+	err = afero.WriteFile(fs, "ok.cbor", testSignedCorimValid, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ok.jwk", testECKey, 0644)
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	assert.NoError(t, err)
+}
+
+func Test_CorimVerifyCmd_expected_payload_sha256_mismatch(t *testing.T) {
+	msg, err := decodeSign1(testSignedCorimValid)
+	require.NoError(t, err)
+	sum := sha256.Sum256(msg.Payload)
+
+	cmd := NewCorimVerifyCmd()
+
+	expected := strings.Repeat("00", sha256.Size)
+	args := []string{
+		"--file=ok.cbor",
+		"--key=ok.jwk",
+		"--expected-payload-sha256=" + expected,
+	}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+	err = afero.WriteFile(fs, "ok.cbor", testSignedCorimValid, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ok.jwk", testECKey, 0644)
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	assert.EqualError(t, err, "error verifying ok.cbor: payload SHA-256 mismatch: expected "+
+		expected+", got "+hex.EncodeToString(sum[:]))
+}
+
+func Test_CorimVerifyCmd_expected_payload_sha256_invalid(t *testing.T) {
+	cmd := NewCorimVerifyCmd()
+
+	args := []string{
+		"--file=ok.cbor",
+		"--key=ok.jwk",
+		"--expected-payload-sha256=deadbeef",
+	}
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "invalid --expected-payload-sha256: expecting 64 hex characters")
+}
+
+func Test_CorimVerifyCmd_print_chain_ok(t *testing.T) {
+	pki := newTestPKI(t)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "ok.cbor", pki.signedCorim(t), 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "root.pem", pki.rootPEM(), 0644)
+	require.NoError(t, err)
+
+	cmd := NewCorimVerifyCmd()
+
+	args := []string{
+		"--file=ok.cbor",
+		"--trust-anchor=root.pem",
+		"--print-chain",
+	}
+	cmd.SetArgs(args)
+
+	err = cmd.Execute()
+	assert.NoError(t, err)
+}
+
+func Test_CorimVerifyCmd_print_chain_no_chain(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "ok.cbor", testSignedCorimValid, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ok.jwk", testECKey, 0644)
+	require.NoError(t, err)
+
+	cmd := NewCorimVerifyCmd()
+
+	args := []string{
+		"--file=ok.cbor",
+		"--key=ok.jwk",
+		"--print-chain",
+	}
+	cmd.SetArgs(args)
+
+	err = cmd.Execute()
+	assert.NoError(t, err)
+}
+
+func Test_CorimVerifyCmd_untagged_ok(t *testing.T) {
+	cmd := NewCorimVerifyCmd()
+
+	args := []string{
+		"--file=ok.cbor",
+		"--key=ok.jwk",
+	}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "ok.cbor", untagSign1(testSignedCorimValid), 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ok.jwk", testECKey, 0644)
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	assert.NoError(t, err)
+}
+
+func Test_CorimVerifyCmd_trust_anchor_bad_key_usage(t *testing.T) {
+	pki := newTestPKIWithLeafUsage(t, x509.KeyUsageKeyEncipherment)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "ok.cbor", pki.signedCorim(t), 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "root.pem", pki.rootPEM(), 0644)
+	require.NoError(t, err)
+
+	cmd := NewCorimVerifyCmd()
+	cmd.SetArgs([]string{
+		"--file=ok.cbor",
+		"--trust-anchor=root.pem",
+	})
+
+	err = cmd.Execute()
+	assert.EqualError(t, err, `error verifying ok.cbor: signing certificate "CN=Test Signer" is not suitable for signing CoRIMs: key usage (keyEncipherment) does not include digitalSignature`)
+
+	cmd = NewCorimVerifyCmd()
+	cmd.SetArgs([]string{
+		"--file=ok.cbor",
+		"--trust-anchor=root.pem",
+		"--ignore-key-usage",
+	})
+
+	err = cmd.Execute()
+	assert.NoError(t, err)
+}
+
+func Test_CorimVerifyCmd_trust_anchor_bad_ext_key_usage(t *testing.T) {
+	pki := newTestPKIWithLeafUsage(t, x509.KeyUsageDigitalSignature, x509.ExtKeyUsageServerAuth)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "ok.cbor", pki.signedCorim(t), 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "root.pem", pki.rootPEM(), 0644)
+	require.NoError(t, err)
+
+	cmd := NewCorimVerifyCmd()
+	cmd.SetArgs([]string{
+		"--file=ok.cbor",
+		"--trust-anchor=root.pem",
+	})
+
+	err = cmd.Execute()
+	assert.EqualError(t, err, `error verifying ok.cbor: signing certificate "CN=Test Signer" is not suitable for signing CoRIMs: extended key usage (serverAuth) includes neither codeSigning nor any`)
+}
+
+func Test_CorimVerifyCmd_ignore_key_usage_without_trust_anchor(t *testing.T) {
+	cmd := NewCorimVerifyCmd()
+	cmd.SetArgs([]string{
+		"--file=ok.cbor",
+		"--key=ok.jwk",
+		"--ignore-key-usage",
+	})
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "--ignore-key-usage can only be used together with --trust-anchor, --system-roots or --allow-self-signed")
+}
+
+// newTestSelfSignedCorim returns testCorimValid signed with the key of a
+// self-signed end-entity certificate, which is embedded in the COSE header
+func newTestSelfSignedCorim(t *testing.T) []byte {
+	der, _, key := newTestCert(t, "Dev Signer", false, x509.KeyUsageDigitalSignature, nil, nil)
+
+	var s corim.SignedCorim
+
+	require.NoError(t, s.UnsignedCorim.FromCBOR(testCorimValid))
+	require.NoError(t, s.Meta.FromJSON(testMetaValid))
+	require.NoError(t, s.AddSigningCert(der))
+
+	signer, err := cose.NewSigner(cose.AlgorithmES256, key)
+	require.NoError(t, err)
+
+	data, err := s.Sign(signer)
+	require.NoError(t, err)
+
+	return data
+}
+
+func Test_CorimVerifyCmd_allow_self_signed(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "signed.cbor", newTestSelfSignedCorim(t), 0644))
+	require.NoError(t, afero.WriteFile(fs, "root.pem", newTestPKI(t).rootPEM(), 0644))
+
+	cmd := NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=signed.cbor", "--trust-anchor=root.pem"})
+	assert.ErrorContains(t, cmd.Execute(), "error verifying signed.cbor: certificate chain validation failed: ")
+
+	cmd = NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=signed.cbor", "--allow-self-signed"})
+	assert.NoError(t, cmd.Execute())
+
+	cmd = NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=signed.cbor", "--trust-anchor=root.pem", "--allow-self-signed"})
+	assert.NoError(t, cmd.Execute())
+}
+
+func Test_CorimVerifyCmd_allow_self_signed_not_self_signed(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "signed.cbor", newTestPKI(t).signedCorim(t), 0644))
+
+	// a certificate issued by a CA is not accepted without its trust anchor
+	cmd := NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=signed.cbor", "--allow-self-signed"})
+	assert.ErrorContains(t, cmd.Execute(), "error verifying signed.cbor: certificate chain validation failed: ")
+}
+
+func Test_CorimVerifyCmd_allow_self_signed_with_key(t *testing.T) {
+	cmd := NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=signed.cbor", "--key=key.jwk", "--allow-self-signed"})
+	assert.EqualError(t, cmd.Execute(), "--allow-self-signed cannot be used together with --key or --use-embedded-key")
+}
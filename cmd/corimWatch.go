@@ -0,0 +1,349 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+// defaultWatchSettle is the default time a dropped file must stay unchanged
+// before it is considered completely written
+const defaultWatchSettle = 2 * time.Second
+
+var (
+	corimWatchDir          *string
+	corimWatchKeyFile      *string
+	corimWatchMetaFile     *string
+	corimWatchOutputDir    *string
+	corimWatchProcessedDir *string
+	corimWatchFailedDir    *string
+	corimWatchSettle       *time.Duration
+	corimWatchOnce         *bool
+)
+
+var corimWatchCmd = NewCorimWatchCmd()
+
+func NewCorimWatchCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "watch",
+		Short: "sign the unsigned CoRIMs dropped in a directory as they appear",
+		Long: `sign the unsigned CoRIMs dropped in a directory as they appear
+
+    Watch the drop directory for new *.cbor files and sign each (using the key
+    in JWK format from file key.jwk and the CorimMeta information from file
+    meta.json) once it has stayed unchanged for 2 seconds, so that files that
+    are still being written are not picked up.  The signed CoRIMs are saved to
+    the signed directory under the same name, and the unsigned CoRIMs are then
+    moved to drop/processed (or to drop/failed, if signing failed).  Files
+    already in the drop directory are signed too.  Stop with Ctrl-C:
+
+      cocli corim watch --dir=drop \
+                    --key=key.jwk \
+                    --meta=meta.json \
+                    --output-dir=signed
+
+    Only sign the files currently in the drop directory and exit, e.g., as a
+    CI step, waiting for 5 seconds of inactivity before signing each file:
+
+      cocli corim watch --dir=drop \
+                    --key=key.jwk \
+                    --meta=meta.json \
+                    --output-dir=signed \
+                    --settle=5s \
+                    --once
+    `,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkCorimWatchArgs(); err != nil {
+				return err
+			}
+
+			d := newDropDir(*corimWatchDir, *corimWatchKeyFile, *corimWatchMetaFile,
+				*corimWatchOutputDir, *corimWatchProcessedDir, *corimWatchFailedDir, *corimWatchSettle)
+
+			for _, dir := range []string{d.outputDir, d.processedDir, d.failedDir} {
+				if err := prepareOutputDir(dir, defaultDirMode); err != nil {
+					return err
+				}
+			}
+
+			if *corimWatchOnce {
+				return d.drain()
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			return d.watch(ctx)
+		},
+	}
+
+	corimWatchDir = cmd.Flags().StringP("dir", "d", "", "directory in which unsigned CoRIMs (*.cbor) are dropped")
+	corimWatchKeyFile = cmd.Flags().StringP("key", "k", "", "signing key in JWK format")
+	corimWatchMetaFile = cmd.Flags().StringP("meta", "m", "", "CoRIM Meta file (in JSON format)")
+	corimWatchOutputDir = cmd.Flags().StringP("output-dir", "o", "", "folder to which the signed CoRIMs are saved")
+	corimWatchProcessedDir = cmd.Flags().String(
+		"processed-dir", "", "folder to which the unsigned CoRIMs are moved once signed (default <dir>/processed)",
+	)
+	corimWatchFailedDir = cmd.Flags().String(
+		"failed-dir", "", "folder to which the unsigned CoRIMs that could not be signed are moved (default <dir>/failed)",
+	)
+	corimWatchSettle = cmd.Flags().Duration(
+		"settle", defaultWatchSettle, "how long a dropped file must stay unchanged before it is signed",
+	)
+	corimWatchOnce = cmd.Flags().Bool("once", false, "sign the files currently in the directory, then exit")
+
+	return cmd
+}
+
+func checkCorimWatchArgs() error {
+	if corimWatchDir == nil || *corimWatchDir == "" {
+		return errors.New("no directory supplied")
+	}
+
+	if corimWatchKeyFile == nil || *corimWatchKeyFile == "" {
+		return errors.New("no key supplied")
+	}
+
+	if corimWatchMetaFile == nil || *corimWatchMetaFile == "" {
+		return errors.New("no CoRIM Meta supplied")
+	}
+
+	if corimWatchOutputDir == nil || *corimWatchOutputDir == "" {
+		return errors.New("no output directory supplied")
+	}
+
+	// signed CoRIMs saved to the watched directory would be signed again
+	if filepath.Clean(*corimWatchOutputDir) == filepath.Clean(*corimWatchDir) {
+		return errors.New("the output directory must be different from the watched directory")
+	}
+
+	if *corimWatchSettle <= 0 {
+		return fmt.Errorf("invalid --settle %s: expecting a positive duration", *corimWatchSettle)
+	}
+
+	return nil
+}
+
+// fileStamp is what is used to tell whether a dropped file is still being
+// written
+type fileStamp struct {
+	size    int64
+	modTime time.Time
+}
+
+// dropDir is a directory in which unsigned CoRIMs are dropped to be signed
+type dropDir struct {
+	dir          string
+	keyFile      string
+	metaFile     string
+	outputDir    string
+	processedDir string
+	failedDir    string
+	settle       time.Duration
+
+	// the dropped files waiting to stay unchanged for settle, with their
+	// stamp when last seen
+	pending map[string]fileStamp
+}
+
+func newDropDir(dir, keyFile, metaFile, outputDir, processedDir, failedDir string, settle time.Duration) *dropDir {
+	if processedDir == "" {
+		processedDir = filepath.Join(dir, "processed")
+	}
+
+	if failedDir == "" {
+		failedDir = filepath.Join(dir, "failed")
+	}
+
+	return &dropDir{
+		dir:          dir,
+		keyFile:      keyFile,
+		metaFile:     metaFile,
+		outputDir:    outputDir,
+		processedDir: processedDir,
+		failedDir:    failedDir,
+		settle:       settle,
+		pending:      map[string]fileStamp{},
+	}
+}
+
+// watch signs the files found in the directory and those dropped in it
+// afterwards, until ctx is done.  fsnotify watches the actual file system, so
+// that watch is only useful if fs is backed by it.
+func (o *dropDir) watch(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("error watching %s: %w", o.dir, err)
+	}
+	defer watcher.Close()
+
+	if err = watcher.Add(o.dir); err != nil {
+		return fmt.Errorf("error watching %s: %w", o.dir, err)
+	}
+
+	if err = o.scan(); err != nil {
+		return err
+	}
+
+	fmt.Printf(">> watching %q for unsigned CoRIMs\n", o.dir)
+
+	ticker := time.NewTicker(o.settle)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Printf(">> stopped watching %q\n", o.dir)
+			return nil
+		case ev, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if ev.Op&(fsnotify.Create|fsnotify.Write) != 0 {
+				o.add(ev.Name)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			return fmt.Errorf("error watching %s: %w", o.dir, err)
+		case <-ticker.C:
+			o.poll()
+		}
+	}
+}
+
+// drain signs the files found in the directory, waiting for each to stay
+// unchanged for settle, and returns once there is none left.  It fails if any
+// of them could not be signed.
+func (o *dropDir) drain() error {
+	if err := o.scan(); err != nil {
+		return err
+	}
+
+	failed := 0
+
+	for len(o.pending) != 0 {
+		time.Sleep(o.settle)
+		failed += o.poll()
+	}
+
+	if failed != 0 {
+		return fmt.Errorf("%d CoRIM(s) could not be signed", failed)
+	}
+
+	return nil
+}
+
+// scan adds the files currently in the directory to the pending ones
+func (o *dropDir) scan() error {
+	entries, err := afero.ReadDir(fs, o.dir)
+	if err != nil {
+		return fmt.Errorf("error reading directory %s: %w", o.dir, err)
+	}
+
+	for _, e := range entries {
+		if !e.IsDir() {
+			o.add(filepath.Join(o.dir, e.Name()))
+		}
+	}
+
+	return nil
+}
+
+// add records file as pending, with its current stamp, if it is a CBOR file.
+// Adding a file that is already pending restarts its settle time.
+func (o *dropDir) add(file string) {
+	if !strings.EqualFold(filepath.Ext(file), ".cbor") {
+		return
+	}
+
+	fi, err := fs.Stat(file)
+	if err != nil || fi.IsDir() {
+		return
+	}
+
+	o.pending[file] = fileStamp{size: fi.Size(), modTime: fi.ModTime()}
+}
+
+// poll signs the pending files that did not change since they were last seen,
+// forgetting about those that disappeared in the meantime.  It returns the
+// number of files that could not be signed.
+func (o *dropDir) poll() int {
+	failed := 0
+
+	files := make([]string, 0, len(o.pending))
+	for file := range o.pending {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		fi, err := fs.Stat(file)
+		if err != nil || fi.IsDir() {
+			delete(o.pending, file)
+			continue
+		}
+
+		stamp := fileStamp{size: fi.Size(), modTime: fi.ModTime()}
+
+		if stamp != o.pending[file] {
+			o.pending[file] = stamp
+			continue
+		}
+
+		delete(o.pending, file)
+
+		if !o.process(file) {
+			failed++
+		}
+	}
+
+	return failed
+}
+
+// process signs file, and moves it to the processed directory or, if signing
+// failed, to the failed directory.  Errors are logged rather than returned, so
+// that one bad file does not stop the watch: process only tells whether file
+// was signed.
+func (o *dropDir) process(file string) bool {
+	outputFile := filepath.Join(o.outputDir, filepath.Base(file))
+	moveTo := o.processedDir
+
+	coseFile, _, err := sign(file, o.keyFile, o.metaFile, &outputFile, nil, nil, signOptions{})
+	if err != nil {
+		fmt.Printf(">> error signing %q: %v\n", file, err)
+		moveTo = o.failedDir
+	} else {
+		fmt.Printf(">> %q signed and saved to %q\n", file, coseFile)
+	}
+
+	dest := filepath.Join(moveTo, filepath.Base(file))
+
+	if err = fs.Rename(file, dest); err != nil {
+		fmt.Printf(">> error moving %q to %q: %v\n", file, dest, err)
+	} else {
+		fmt.Printf(">> %q moved to %q\n", file, dest)
+	}
+
+	return moveTo == o.processedDir
+}
+
+func init() {
+	corimCmd.AddCommand(corimWatchCmd)
+}
@@ -0,0 +1,153 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_CorimWatchCmd_once(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "meta.json", testMetaValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "key.jwk", testECKey, 0644))
+	require.NoError(t, afero.WriteFile(fs, "drop/good.cbor", testCorimValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "drop/bad.cbor", []byte("not a CoRIM"), 0644))
+	require.NoError(t, afero.WriteFile(fs, "drop/notes.txt", []byte("ignored"), 0644))
+
+	cmd := NewCorimWatchCmd()
+	cmd.SetArgs([]string{
+		"--dir=drop", "--key=key.jwk", "--meta=meta.json", "--output-dir=signed", "--settle=1ms", "--once",
+	})
+	assert.EqualError(t, cmd.Execute(), "1 CoRIM(s) could not be signed")
+
+	_, err := fs.Stat("signed/good.cbor")
+	assert.NoError(t, err)
+	_, err = fs.Stat("signed/bad.cbor")
+	assert.Error(t, err)
+
+	assert.Equal(t, testCorimValid, mustReadFile(t, "drop/processed/good.cbor"))
+	assert.Equal(t, []byte("not a CoRIM"), mustReadFile(t, "drop/failed/bad.cbor"))
+	assert.Equal(t, []byte("ignored"), mustReadFile(t, "drop/notes.txt"))
+
+	for _, file := range []string{"drop/good.cbor", "drop/bad.cbor"} {
+		_, err = fs.Stat(file)
+		assert.Error(t, err, file)
+	}
+}
+
+func Test_dropDir_poll_waits_for_stability(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "meta.json", testMetaValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "key.jwk", testECKey, 0644))
+	require.NoError(t, afero.WriteFile(fs, "drop/partial.cbor", testCorimValid[:10], 0644))
+
+	d := newDropDir("drop", "key.jwk", "meta.json", "signed", "", "", time.Millisecond)
+	require.NoError(t, d.scan())
+	require.Len(t, d.pending, 1)
+
+	// still being written: not picked up
+	require.NoError(t, afero.WriteFile(fs, "drop/partial.cbor", testCorimValid, 0644))
+	assert.Equal(t, 0, d.poll())
+	assert.Len(t, d.pending, 1)
+
+	_, err := fs.Stat("signed/partial.cbor")
+	assert.Error(t, err)
+
+	// unchanged since the last poll: signed
+	assert.Equal(t, 0, d.poll())
+	assert.Empty(t, d.pending)
+
+	_, err = fs.Stat("signed/partial.cbor")
+	assert.NoError(t, err)
+}
+
+func Test_dropDir_watch(t *testing.T) {
+	fs = afero.NewOsFs()
+	dir := t.TempDir()
+
+	drop := filepath.Join(dir, "drop")
+	signed := filepath.Join(dir, "signed")
+	key := filepath.Join(dir, "key.jwk")
+	meta := filepath.Join(dir, "meta.json")
+
+	require.NoError(t, afero.WriteFile(fs, key, testECKey, 0644))
+	require.NoError(t, afero.WriteFile(fs, meta, testMetaValid, 0644))
+
+	d := newDropDir(drop, key, meta, signed, "", "", 10*time.Millisecond)
+	for _, dir := range []string{drop, signed, d.processedDir, d.failedDir} {
+		require.NoError(t, prepareOutputDir(dir, defaultDirMode))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- d.watch(ctx) }()
+
+	require.NoError(t, afero.WriteFile(fs, filepath.Join(drop, "new.cbor"), testCorimValid, 0644))
+
+	assert.Eventually(t, func() bool {
+		_, err := fs.Stat(filepath.Join(d.processedDir, "new.cbor"))
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+
+	_, err := fs.Stat(filepath.Join(signed, "new.cbor"))
+	assert.NoError(t, err)
+}
+
+func Test_CorimWatchCmd_bad_args(t *testing.T) {
+	tvs := []struct {
+		desc     string
+		args     []string
+		expected string
+	}{
+		{
+			desc:     "no dir",
+			args:     []string{"--key=key.jwk", "--meta=meta.json", "--output-dir=signed"},
+			expected: "no directory supplied",
+		},
+		{
+			desc:     "no key",
+			args:     []string{"--dir=drop", "--meta=meta.json", "--output-dir=signed"},
+			expected: "no key supplied",
+		},
+		{
+			desc:     "no meta",
+			args:     []string{"--dir=drop", "--key=key.jwk", "--output-dir=signed"},
+			expected: "no CoRIM Meta supplied",
+		},
+		{
+			desc:     "no output dir",
+			args:     []string{"--dir=drop", "--key=key.jwk", "--meta=meta.json"},
+			expected: "no output directory supplied",
+		},
+		{
+			desc:     "output dir is watched dir",
+			args:     []string{"--dir=drop", "--key=key.jwk", "--meta=meta.json", "--output-dir=drop/"},
+			expected: "the output directory must be different from the watched directory",
+		},
+		{
+			desc:     "bad settle",
+			args:     []string{"--dir=drop", "--key=key.jwk", "--meta=meta.json", "--output-dir=signed", "--settle=0s"},
+			expected: "invalid --settle 0s: expecting a positive duration",
+		},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.desc, func(t *testing.T) {
+			cmd := NewCorimWatchCmd()
+			cmd.SetArgs(tv.args)
+
+			assert.EqualError(t, cmd.Execute(), tv.expected)
+		})
+	}
+}
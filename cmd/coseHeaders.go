@@ -0,0 +1,132 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/veraison/corim/corim"
+	cose "github.com/veraison/go-cose"
+)
+
+// understoodHeaders lists the COSE header labels that are processed by cocli
+// and therefore may appear in the crit header
+var understoodHeaders = map[int64]bool{
+	cose.HeaderLabelAlgorithm:   true,
+	cose.HeaderLabelContentType: true,
+	cose.HeaderLabelKeyID:       true,
+	cose.HeaderLabelX5Chain:     true,
+	corim.HeaderLabelCorimMeta:  true,
+	headerLabelCWTClaims:        true,
+}
+
+// supportedAlgorithms lists the signature algorithms accepted in the alg
+// header
+var supportedAlgorithms = map[cose.Algorithm]bool{
+	cose.AlgorithmES256: true,
+	cose.AlgorithmES384: true,
+	cose.AlgorithmES512: true,
+	cose.AlgorithmPS256: true,
+	cose.AlgorithmPS384: true,
+	cose.AlgorithmPS512: true,
+	cose.AlgorithmEdDSA: true,
+}
+
+// checkCOSEHeaders makes sure that the protected header of h (that of a
+// COSE_Sign1 message or of a COSE_Signature) carries a supported signature
+// algorithm and that each header listed as critical (see RFC 9052, Section 3.1)
+// is one that cocli understands, or one of the extra labels processed by the
+// caller.  The crit header cannot list itself.
+func checkCOSEHeaders(h *cose.Headers, extra ...int64) error {
+	alg, err := h.Protected.Algorithm()
+	if err != nil {
+		return fmt.Errorf("alg header: %w", err)
+	}
+
+	if !supportedAlgorithms[alg] {
+		return fmt.Errorf("alg header: unsupported signature algorithm %s", alg)
+	}
+
+	return checkCriticalHeaders(h.Protected, extra...)
+}
+
+// checkCriticalHeaders makes sure that each header listed as critical in the
+// protected header p is one that cocli understands, or one of the extra labels
+// processed by the caller
+func checkCriticalHeaders(p cose.ProtectedHeader, extra ...int64) error {
+	crit, err := p.Critical()
+	if err != nil {
+		return fmt.Errorf("crit header: %w", err)
+	}
+
+	for _, label := range crit {
+		if l, ok := cborInt(label); ok && (understoodHeaders[l] || slices.Contains(extra, l)) {
+			continue
+		}
+
+		return fmt.Errorf("crit header: unrecognized critical header %v", label)
+	}
+
+	return nil
+}
+
+// checkAlgHeader makes sure that the signature algorithm of h (that of a
+// COSE_Sign1 message or of a COSE_Signature) is in its protected header (RFC 9052, Section 3.1): in the unprotected header, the
+// algorithm is not covered by the signature and could be swapped by an
+// attacker.  If allowUnprotected, an algorithm found in the unprotected header
+// only is accepted with a warning, and copied to the decoded protected header
+// of h so that it can be verified.  The encoded protected header, which is
+// what the signature covers, is left untouched.  The warning goes to stderr if
+// quiet.
+func checkAlgHeader(h *cose.Headers, allowUnprotected, quiet bool) error {
+	_, inProtected := h.Protected[cose.HeaderLabelAlgorithm]
+	v, inUnprotected := h.Unprotected[cose.HeaderLabelAlgorithm]
+
+	switch {
+	case inProtected && inUnprotected:
+		return errors.New("alg header: must not be in both the protected and the unprotected header")
+	case inProtected:
+		return nil
+	case !inUnprotected:
+		return errors.New("alg header: missing from the protected header")
+	}
+
+	alg, err := cose.ProtectedHeader{cose.HeaderLabelAlgorithm: v}.Algorithm()
+	if err != nil {
+		return fmt.Errorf("alg header: %w", err)
+	}
+
+	if !allowUnprotected {
+		return fmt.Errorf("alg header: %s is in the unprotected header only, where it is not covered by the signature", alg)
+	}
+
+	warnf(quiet, ">> warning: accepting signature algorithm %s from the unprotected header, where it is not covered by the signature\n", alg)
+
+	if h.Protected == nil {
+		h.Protected = cose.ProtectedHeader{}
+	}
+
+	h.Protected.SetAlgorithm(alg)
+
+	return nil
+}
+
+// verifySign1 verifies the signature of msg with pk, using the algorithm in
+// the (decoded) protected header of msg
+func verifySign1(msg *cose.Sign1Message, pk crypto.PublicKey) error {
+	alg, err := msg.Headers.Protected.Algorithm()
+	if err != nil {
+		return fmt.Errorf("unable to get verification algorithm: %w", err)
+	}
+
+	verifier, err := cose.NewVerifier(alg, pk)
+	if err != nil {
+		return fmt.Errorf("unable to instantiate verifier: %w", err)
+	}
+
+	return msg.Verify(corim.NoExternalData, verifier)
+}
@@ -0,0 +1,239 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/corim"
+	cose "github.com/veraison/go-cose"
+)
+
+func Test_checkCOSEHeaders(t *testing.T) {
+	tvs := []struct {
+		desc string
+		hdrs map[interface{}]interface{}
+		err  string
+	}{
+		{
+			desc: "no crit",
+			hdrs: map[interface{}]interface{}{},
+		},
+		{
+			desc: "understood critical header",
+			hdrs: map[interface{}]interface{}{
+				cose.HeaderLabelCritical: []interface{}{headerLabelCWTClaims},
+				headerLabelCWTClaims:     map[int64]interface{}{cwtClaimIAT: 0},
+			},
+		},
+		{
+			desc: "unrecognized critical integer header",
+			hdrs: map[interface{}]interface{}{
+				cose.HeaderLabelCritical: []interface{}{int64(99)},
+				int64(99):                "hello!",
+			},
+			err: "crit header: unrecognized critical header 99",
+		},
+		{
+			desc: "unrecognized critical text header",
+			hdrs: map[interface{}]interface{}{
+				cose.HeaderLabelCritical: []interface{}{"x-acme"},
+				"x-acme":                 "hello!",
+			},
+			err: "crit header: unrecognized critical header x-acme",
+		},
+		{
+			desc: "crit lists itself",
+			hdrs: map[interface{}]interface{}{
+				cose.HeaderLabelCritical: []interface{}{cose.HeaderLabelCritical},
+			},
+			err: "crit header: unrecognized critical header 2",
+		},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.desc, func(t *testing.T) {
+			msg, err := decodeSign1(newTestSignedCorimWithHeaders(t, tv.hdrs))
+			require.NoError(t, err)
+
+			err = checkCOSEHeaders(&msg.Headers)
+			if tv.err != "" {
+				assert.EqualError(t, err, tv.err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func Test_checkCOSEHeaders_unsupported_alg(t *testing.T) {
+	msg := cose.NewSign1Message()
+	msg.Headers.Protected.SetAlgorithm(cose.AlgorithmRS256)
+
+	err := checkCOSEHeaders(&msg.Headers)
+	assert.EqualError(t, err, "alg header: unsupported signature algorithm RS256")
+}
+
+func Test_checkCOSEHeaders_missing_alg(t *testing.T) {
+	msg := cose.NewSign1Message()
+
+	err := checkCOSEHeaders(&msg.Headers)
+	assert.EqualError(t, err, "alg header: algorithm not found")
+}
+
+func Test_CorimVerifyCmd_unrecognized_critical_header(t *testing.T) {
+	cmd := NewCorimVerifyCmd()
+
+	args := []string{
+		"--file=crit.cbor",
+		"--key=ok.jwk",
+	}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+	data := newTestSignedCorimWithHeaders(t, map[interface{}]interface{}{
+		cose.HeaderLabelCritical: []interface{}{int64(99)},
+		int64(99):                "hello!",
+	})
+	err := afero.WriteFile(fs, "crit.cbor", data, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ok.jwk", testECKey, 0644)
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	assert.EqualError(t, err, "error verifying crit.cbor: crit header: unrecognized critical header 99")
+}
+
+// newTestSignedCorimUnprotectedAlg signs testCorimValid with testECKey,
+// putting the alg header in the unprotected header rather than in the
+// protected one
+func newTestSignedCorimUnprotectedAlg(t *testing.T) []byte {
+	var m corim.Meta
+	require.NoError(t, m.FromJSON(testMetaValid))
+
+	metaCBOR, err := m.ToCBOR()
+	require.NoError(t, err)
+
+	signer, err := corim.NewSignerFromJWK(testECKey)
+	require.NoError(t, err)
+
+	protected := cose.ProtectedHeader{
+		cose.HeaderLabelContentType: corim.ContentType,
+		corim.HeaderLabelCorimMeta:  metaCBOR,
+	}
+
+	rawProtected, err := protected.MarshalCBOR()
+	require.NoError(t, err)
+
+	msg := cose.NewSign1Message()
+	msg.Payload = testCorimValid
+	// go-cose insists on a protected alg when signing, but signs (and
+	// encodes) the raw protected header, which has none
+	msg.Headers.Protected = protected
+	msg.Headers.Protected.SetAlgorithm(signer.Algorithm())
+	msg.Headers.RawProtected = rawProtected
+	msg.Headers.Unprotected[cose.HeaderLabelAlgorithm] = signer.Algorithm()
+
+	require.NoError(t, msg.Sign(rand.Reader, corim.NoExternalData, signer))
+
+	data, err := msg.MarshalCBOR()
+	require.NoError(t, err)
+
+	return data
+}
+
+func Test_checkAlgHeader(t *testing.T) {
+	tvs := []struct {
+		desc        string
+		protected   bool
+		unprotected bool
+		err         string
+	}{
+		{
+			desc:      "protected",
+			protected: true,
+		},
+		{
+			desc:        "protected and unprotected",
+			protected:   true,
+			unprotected: true,
+			err:         "alg header: must not be in both the protected and the unprotected header",
+		},
+		{
+			desc:        "unprotected only",
+			unprotected: true,
+			err:         "alg header: ES256 is in the unprotected header only, where it is not covered by the signature",
+		},
+		{
+			desc: "absent",
+			err:  "alg header: missing from the protected header",
+		},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.desc, func(t *testing.T) {
+			msg := cose.NewSign1Message()
+			if tv.protected {
+				msg.Headers.Protected.SetAlgorithm(cose.AlgorithmES256)
+			}
+			if tv.unprotected {
+				msg.Headers.Unprotected[cose.HeaderLabelAlgorithm] = cose.AlgorithmES256
+			}
+
+			err := checkAlgHeader(&msg.Headers, false, false)
+			if tv.err != "" {
+				assert.EqualError(t, err, tv.err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func Test_checkAlgHeader_allow_unprotected(t *testing.T) {
+	msg, err := decodeSign1(newTestSignedCorimUnprotectedAlg(t))
+	require.NoError(t, err)
+
+	rawProtected := msg.Headers.RawProtected
+
+	require.NoError(t, checkAlgHeader(&msg.Headers, true, false))
+
+	alg, err := msg.Headers.Protected.Algorithm()
+	require.NoError(t, err)
+	assert.Equal(t, cose.AlgorithmES256, alg)
+	assert.Equal(t, rawProtected, msg.Headers.RawProtected)
+}
+
+func Test_CorimVerifyCmd_unprotected_alg(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "unprotected.cbor", newTestSignedCorimUnprotectedAlg(t), 0644))
+	require.NoError(t, afero.WriteFile(fs, "ok.jwk", testECKey, 0644))
+
+	cmd := NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=unprotected.cbor", "--key=ok.jwk"})
+	assert.EqualError(t, cmd.Execute(),
+		"error verifying unprotected.cbor: alg header: ES256 is in the unprotected header only, where it is not covered by the signature")
+
+	cmd = NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=unprotected.cbor", "--key=ok.jwk", "--allow-unprotected-alg"})
+	assert.NoError(t, cmd.Execute())
+}
+
+func Test_CorimVerifyCmd_allow_unprotected_alg_bad_signature(t *testing.T) {
+	data := newTestSignedCorimUnprotectedAlg(t)
+	// flip a bit of the signature, the last item of the COSE Sign1
+	data[len(data)-1] ^= 0x01
+
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "unprotected.cbor", data, 0644))
+	require.NoError(t, afero.WriteFile(fs, "ok.jwk", testECKey, 0644))
+
+	cmd := NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=unprotected.cbor", "--key=ok.jwk", "--allow-unprotected-alg"})
+	assert.EqualError(t, cmd.Execute(), "error verifying unprotected.cbor with key ok.jwk: verification error")
+}
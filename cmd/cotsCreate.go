@@ -11,6 +11,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
+	"github.com/veraison/corim/comid"
 	"github.com/veraison/corim/cots"
 )
 
@@ -29,6 +30,10 @@ var (
 	cotsCreateCtsCaDirs         []string
 	cotsCreateCtsCaFiles        []string
 	cotsCreateCtsOutputFile     *string
+	cotsCreateStrictDecode      *bool
+	cotsCreateJSONLimits        jsonLimits
+	cotsCreateCtsPEMDirs        []string
+	cotsCreateEnvClassID        *string
 )
 
 var cotsCreateCtsCmd = NewCotsCreateCtsCmd()
@@ -64,6 +69,15 @@ func NewCotsCreateCtsCmd() *cobra.Command {
 					--tafile=tas_dir \
 					--cafile=cas_dir \
 					--output=cots.cbor
+
+	Create a concise-ta-store-map holding every PEM certificate found in the roots
+	directory as a trust anchor, for the environment with class id 1.2.3.4 (a
+	UUID or a base64-encoded implementation id can also be used).  Files that
+	do not contain certificates are skipped with a warning.
+
+	  cocli cots create --from-pem-dir=roots \
+	                   --env-class-id=1.2.3.4 \
+	                   --output=cots.cbor
 	`,
 
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -78,12 +92,24 @@ func NewCotsCreateCtsCmd() *cobra.Command {
 			tasFilesList = append(tasFilesList, spkiFilesList...)
 			casFilesList := filesList(cotsCreateCtsCaFiles, cotsCreateCtsCaDirs, ".der")
 
-			if len(tasFilesList) == 0 {
+			pemTAs, err := loadPEMTrustAnchors(cotsCreateCtsPEMDirs)
+			if err != nil {
+				return err
+			}
+
+			if len(tasFilesList)+len(pemTAs) == 0 {
 				return errors.New("no TA files found")
 			}
 
-			cborFile, err := ctsTemplateToCBOR(*cotsCreateLanguage, *cotsCreateTagID, *cotsCreateTagUUID, *cotsCreateTagUUIDStr, cotsCreateTagVersion, *cotsCreateCtsEnvFile, *cotsCreateCtsPermClaimsFile, *cotsCreateCtsExclClaimsFile, cotsCreateCtsPurposes,
-				tasFilesList, casFilesList, cotsCreateCtsOutputFile)
+			var envClassID *comid.ClassID
+			if *cotsCreateEnvClassID != "" {
+				if envClassID, err = parseClassID(*cotsCreateEnvClassID); err != nil {
+					return err
+				}
+			}
+
+			cborFile, err := ctsTemplateToCBOR(*cotsCreateLanguage, *cotsCreateTagID, *cotsCreateTagUUID, *cotsCreateTagUUIDStr, cotsCreateTagVersion, *cotsCreateCtsEnvFile, envClassID, *cotsCreateCtsPermClaimsFile, *cotsCreateCtsExclClaimsFile, cotsCreateCtsPurposes,
+				tasFilesList, pemTAs, casFilesList, cotsCreateCtsOutputFile, *cotsCreateStrictDecode, cotsCreateJSONLimits)
 			if err != nil {
 				return err
 			}
@@ -99,6 +125,7 @@ func NewCotsCreateCtsCmd() *cobra.Command {
 	cotsCreateTagID = cmd.Flags().StringP("id", "", "", "string value containing a tag ID value (mutually exclusive from --uuid and --uuid-str)")
 	cotsCreateTagVersion = cmd.Flags().UintP("tag-version", "", 0, "integer value indicating version of tag identity (ignored if neither --uuid nor --id are supplied)")
 	cotsCreateCtsEnvFile = cmd.Flags().StringP("environment", "e", "", "an environment template file (in JSON format)")
+	cotsCreateEnvClassID = cmd.Flags().String("env-class-id", "", "class id (UUID, OID or base64 implementation id) of the environment, instead of --environment")
 	cotsCreateCtsPermClaimsFile = cmd.Flags().StringP("permclaims", "p", "", "a permitted claims template file (in JSON format)")
 	cotsCreateCtsExclClaimsFile = cmd.Flags().StringP("exclclaims", "x", "", "an excluded claims template file (in JSON format)")
 
@@ -113,6 +140,10 @@ func NewCotsCreateCtsCmd() *cobra.Command {
 		&cotsCreateCtsTaFiles, "tafile", "f", []string{}, "a DER-encoded trust anchor file",
 	)
 
+	cmd.Flags().StringArrayVar(
+		&cotsCreateCtsPEMDirs, "from-pem-dir", []string{}, "a directory containing PEM-encoded trust anchor certificate files",
+	)
+
 	cmd.Flags().StringArrayVarP(
 		&cotsCreateCtsCaDirs, "cas", "c", []string{}, "a directory containing binary DER-encoded X.509 CA certificate files",
 	)
@@ -121,6 +152,9 @@ func NewCotsCreateCtsCmd() *cobra.Command {
 	)
 
 	cotsCreateCtsOutputFile = cmd.Flags().StringP("output", "o", "", "name of the generated CoTS file")
+	cotsCreateStrictDecode = cmd.Flags().Bool("strict-decode", false, "reject templates carrying fields that are not understood")
+
+	addJSONLimitsFlags(cmd, &cotsCreateJSONLimits)
 
 	return cmd
 }
@@ -131,10 +165,21 @@ func IsValidUUID(u string) bool {
 }
 
 func checkctsCreateCtsArgs() error {
-	if cotsCreateCtsEnvFile == nil || *cotsCreateCtsEnvFile == "" {
+	hasEnvFile := cotsCreateCtsEnvFile != nil && *cotsCreateCtsEnvFile != ""
+	hasEnvClassID := cotsCreateEnvClassID != nil && *cotsCreateEnvClassID != ""
+
+	if !hasEnvFile && !hasEnvClassID {
 		return errors.New("no environment template supplied")
 	}
 
+	if hasEnvFile && hasEnvClassID {
+		return errors.New("--environment cannot be used together with --env-class-id")
+	}
+
+	if hasEnvClassID && (cotsCreateCtsOutputFile == nil || *cotsCreateCtsOutputFile == "") {
+		return errors.New("no output file supplied (required with --env-class-id)")
+	}
+
 	if (*cotsCreateTagUUID && *cotsCreateTagID != "") || (*cotsCreateTagUUID && *cotsCreateTagUUIDStr != "") || (*cotsCreateTagUUIDStr != "" && *cotsCreateTagID != "") {
 		return errors.New("only one of --uuid, --uuid-str and --id can be used at the same time")
 	}
@@ -143,14 +188,18 @@ func checkctsCreateCtsArgs() error {
 		return errors.New("--uuid-str does not contain a valid UUID")
 	}
 
-	if len(cotsCreateCtsTaFiles)+len(cotsCreateCtsTaDirs) == 0 {
+	if len(cotsCreateCtsTaFiles)+len(cotsCreateCtsTaDirs)+len(cotsCreateCtsPEMDirs) == 0 {
 		return errors.New("no TA files or folders supplied")
 	}
 
-	return nil
+	return cotsCreateJSONLimits.valid()
 }
 
-func ctsTemplateToCBOR(language string, tagID string, genUUID bool, uuidStr string, version *uint, envFile string, permClaimsFile string, exclClaimsFile string, purposes, taFiles, caFiles []string, outputFile *string) (string, error) {
+// ctsTemplateToCBOR builds a CoTS from the supplied templates and TAs/CAs and
+// saves it to outputFile.  The environment is taken from envFile or, if that is
+// empty, made of the environment identified by envClassID.  pemTAs are added
+// after the TAs loaded from taFiles.
+func ctsTemplateToCBOR(language string, tagID string, genUUID bool, uuidStr string, version *uint, envFile string, envClassID *comid.ClassID, permClaimsFile string, exclClaimsFile string, purposes, taFiles []string, pemTAs []cots.TrustAnchor, caFiles []string, outputFile *string, strict bool, limits jsonLimits) (string, error) {
 	var (
 		envData        []byte
 		env            cots.EnvironmentGroups
@@ -165,12 +214,16 @@ func ctsTemplateToCBOR(language string, tagID string, genUUID bool, uuidStr stri
 
 	cts := cots.ConciseTaStore{}
 
-	if envData, err = afero.ReadFile(fs, envFile); err != nil {
-		return "", fmt.Errorf("error loading template from %s: %w", envFile, err)
-	}
+	if envFile != "" {
+		if envData, err = readJSONTemplate(envFile, limits, envExpansion{}); err != nil {
+			return "", fmt.Errorf("error loading template from %s: %w", envFile, err)
+		}
 
-	if err = env.FromJSON(envData); err != nil {
-		return "", fmt.Errorf("error decoding template from %s: %w", envFile, err)
+		if err = decodeJSON(&env, envData, strict); err != nil {
+			return "", fmt.Errorf("error decoding template from %s: %w", envFile, err)
+		}
+	} else {
+		env = classIDEnvironment(envClassID)
 	}
 
 	cts.Environments = env
@@ -192,21 +245,21 @@ func ctsTemplateToCBOR(language string, tagID string, genUUID bool, uuidStr stri
 	}
 
 	if permClaimsFile != "" {
-		if permClaimsData, err = afero.ReadFile(fs, permClaimsFile); err != nil {
+		if permClaimsData, err = readJSONTemplate(permClaimsFile, limits, envExpansion{}); err != nil {
 			return "", fmt.Errorf("error loading template from %s: %w", permClaimsFile, err)
 		}
 
-		if err = permClaims.FromJSON(permClaimsData); err != nil {
+		if err = decodeJSON(&permClaims, permClaimsData, strict); err != nil {
 			return "", fmt.Errorf("error decoding template from %s: %w", permClaimsFile, err)
 		}
 		cts.AddPermClaims(&permClaims)
 	}
 	if exclClaimsFile != "" {
-		if exclClaimsData, err = afero.ReadFile(fs, exclClaimsFile); err != nil {
+		if exclClaimsData, err = readJSONTemplate(exclClaimsFile, limits, envExpansion{}); err != nil {
 			return "", fmt.Errorf("error loading template from %s: %w", exclClaimsFile, err)
 		}
 
-		if err = exclClaims.FromJSON(exclClaimsData); err != nil {
+		if err = decodeJSON(&exclClaims, exclClaimsData, strict); err != nil {
 			return "", fmt.Errorf("error decoding template from %s: %w", exclClaimsFile, err)
 		}
 		cts.AddExclClaims(&exclClaims)
@@ -240,6 +293,8 @@ func ctsTemplateToCBOR(language string, tagID string, genUUID bool, uuidStr stri
 		cts.Keys.Tas = append(cts.Keys.Tas, trustAnchor)
 	}
 
+	cts.Keys.Tas = append(cts.Keys.Tas, pemTAs...)
+
 	for _, caFile := range caFiles {
 		var (
 			cadata []byte
@@ -12,8 +12,10 @@ import (
 )
 
 var (
-	cotsDisplayFiles []string
-	cotsDisplayDirs  []string
+	cotsDisplayFiles        []string
+	cotsDisplayDirs         []string
+	cotsDisplayStrictDecode *bool
+	cotsDisplayOutputFile   *string
 )
 
 var cotsDisplayCmd = NewCotsDisplayCmd()
@@ -29,6 +31,10 @@ func NewCotsDisplayCmd() *cobra.Command {
 	
 	  cocli cots display --file=cots.cbor
 
+	Save the rendering of the CoTS in cots.cbor to cots.json instead of
+	printing it
+
+	  cocli cots display --file=cots.cbor --output=cots.json
 	`,
 
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -41,19 +47,21 @@ func NewCotsDisplayCmd() *cobra.Command {
 				return errors.New("no files found")
 			}
 
-			errs := 0
-			for _, file := range filesList {
-				if err := displayCotsFile(file); err != nil {
-					fmt.Printf(">> failed displaying %q: %v\n", file, err)
-					errs++
-					continue
+			return withDisplayOutput(*cotsDisplayOutputFile, func() error {
+				errs := 0
+				for _, file := range filesList {
+					if err := displayCotsFile(file, *cotsDisplayStrictDecode); err != nil {
+						fmt.Printf(">> failed displaying %q: %v\n", file, err)
+						errs++
+						continue
+					}
 				}
-			}
 
-			if errs != 0 {
-				return fmt.Errorf("%d/%d display(s) failed", errs, len(filesList))
-			}
-			return nil
+				if errs != 0 {
+					return fmt.Errorf("%d/%d display(s) failed", errs, len(filesList))
+				}
+				return nil
+			})
 		},
 	}
 
@@ -65,10 +73,18 @@ func NewCotsDisplayCmd() *cobra.Command {
 		&cotsDisplayDirs, "dir", "d", []string{}, "a directory containing CoTS files (in CBOR format)",
 	)
 
+	cotsDisplayStrictDecode = cmd.Flags().Bool(
+		"strict-decode", false, "reject CoTSs carrying fields that are not understood",
+	)
+
+	cotsDisplayOutputFile = cmd.Flags().StringP(
+		"output", "o", "", "save the rendered output to this file instead of printing it",
+	)
+
 	return cmd
 }
 
-func displayCotsFile(file string) error {
+func displayCotsFile(file string, strict bool) error {
 	var (
 		data []byte
 		err  error
@@ -79,7 +95,7 @@ func displayCotsFile(file string) error {
 	}
 
 	// use file name as heading
-	return printCots(data, ">> ["+file+"]")
+	return printCots(data, ">> ["+file+"]", strict)
 
 }
 
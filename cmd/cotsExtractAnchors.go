@@ -0,0 +1,242 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/veraison/corim/cots"
+)
+
+var (
+	cotsExtractAnchorsFile      *string
+	cotsExtractAnchorsOutputDir *string
+	cotsExtractAnchorsDirMode   *string
+	cotsExtractAnchorsFormat    *string
+)
+
+var cotsExtractAnchorsCmd = NewCotsExtractAnchorsCmd()
+
+func NewCotsExtractAnchorsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "extract-anchors",
+		Short: "save the trust anchors found in a CoTS to individual certificate or public key files",
+		Long: `save the trust anchors found in a CoTS to individual certificate or public key files
+
+	Save each trust anchor in cots.cbor to the anchors/ directory (created if it
+	does not exist), in PEM format.  Certificates are saved as CERTIFICATE
+	blocks, while SubjectPublicKeyInfo and TrustAnchorInfo trust anchors are
+	saved as PUBLIC KEY blocks.  Files are named after the environment of the
+	CoTS and the SHA-256 fingerprint of the anchor, e.g.,
+	zesty-hands-inc-405bbc1399c1a674.pem.
+
+	  cocli cots extract-anchors --file=cots.cbor --output-dir=anchors
+
+	Same as above, but save the trust anchors in DER format
+
+	  cocli cots extract-anchors --file=cots.cbor --output-dir=anchors --format=der
+	`,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkCotsExtractAnchorsArgs(); err != nil {
+				return err
+			}
+
+			if err := prepareOutputDir(*cotsExtractAnchorsOutputDir, *cotsExtractAnchorsDirMode); err != nil {
+				return err
+			}
+
+			return extractAnchors(*cotsExtractAnchorsFile, *cotsExtractAnchorsOutputDir, *cotsExtractAnchorsFormat)
+		},
+	}
+
+	cotsExtractAnchorsFile = cmd.Flags().StringP("file", "f", "", "a CoTS file (in CBOR format)")
+	cotsExtractAnchorsOutputDir = cmd.Flags().StringP("output-dir", "o", ".", "folder to which the trust anchors are saved")
+	cotsExtractAnchorsDirMode = cmd.Flags().String("dir-mode", defaultDirMode, "permissions of the output directory, if it needs to be created")
+	cotsExtractAnchorsFormat = cmd.Flags().String("format", "pem", "format of the saved trust anchors: pem or der")
+
+	return cmd
+}
+
+func checkCotsExtractAnchorsArgs() error {
+	if cotsExtractAnchorsFile == nil || *cotsExtractAnchorsFile == "" {
+		return errors.New("no CoTS supplied")
+	}
+
+	if cotsExtractAnchorsFormat != nil && *cotsExtractAnchorsFormat != "pem" && *cotsExtractAnchorsFormat != "der" {
+		return fmt.Errorf("unsupported format %q: expecting pem or der", *cotsExtractAnchorsFormat)
+	}
+
+	return nil
+}
+
+func extractAnchors(file, outputDir, format string) error {
+	var (
+		data []byte
+		cts  cots.ConciseTaStore
+		err  error
+	)
+
+	if data, err = afero.ReadFile(fs, file); err != nil {
+		return fmt.Errorf("error loading CoTS from %s: %w", file, err)
+	}
+
+	if err = cts.FromCBOR(data); err != nil {
+		return fmt.Errorf("error decoding CoTS from %s: %w", file, err)
+	}
+
+	if cts.Keys == nil || len(cts.Keys.Tas) == 0 {
+		return fmt.Errorf("no trust anchors found in %s", file)
+	}
+
+	env := cotsEnvironmentLabel(&cts)
+
+	for i, ta := range cts.Keys.Tas {
+		der, blockType, err := trustAnchorDER(ta)
+		if err != nil {
+			return fmt.Errorf("error processing trust anchor at index %d: %w", i, err)
+		}
+
+		fp := sha256.Sum256(der)
+		anchorFile := filepath.Join(outputDir, fmt.Sprintf("%s-%s.%s", env, hex.EncodeToString(fp[:8]), format))
+
+		out := der
+		if format == "pem" {
+			out = pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+		}
+
+		if err = afero.WriteFile(fs, anchorFile, out, 0644); err != nil {
+			return fmt.Errorf("error saving trust anchor to %s: %w", anchorFile, err)
+		}
+
+		fmt.Printf(">> [%d] %s saved to %q\n", i, strings.ToLower(blockType), anchorFile)
+	}
+
+	return nil
+}
+
+// trustAnchorDER returns the DER encoding of the certificate or public key
+// carried by ta, together with the matching PEM block type.  For
+// TrustAnchorInfo anchors, only the public key is retained.
+func trustAnchorDER(ta cots.TrustAnchor) ([]byte, string, error) {
+	switch ta.Format {
+	case cots.TaFormatCertificate:
+		if _, err := x509.ParseCertificate(ta.Data); err != nil {
+			return nil, "", fmt.Errorf("invalid certificate: %w", err)
+		}
+		return ta.Data, "CERTIFICATE", nil
+	case cots.TaFormatSubjectPublicKeyInfo:
+		if _, err := x509.ParsePKIXPublicKey(ta.Data); err != nil {
+			return nil, "", fmt.Errorf("invalid SubjectPublicKeyInfo: %w", err)
+		}
+		return ta.Data, "PUBLIC KEY", nil
+	case cots.TaFormatTrustAnchorInfo:
+		spki, err := trustAnchorInfoSPKI(ta.Data)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid TrustAnchorInfo: %w", err)
+		}
+		return spki, "PUBLIC KEY", nil
+	}
+
+	return nil, "", fmt.Errorf("unsupported trust anchor format %d", ta.Format)
+}
+
+// trustAnchorInfoSPKI extracts the SubjectPublicKeyInfo from a DER-encoded
+// TrustAnchorInfo (RFC 5914), optionally wrapped in the [2] EXPLICIT tag of
+// the taInfo alternative of TrustAnchorChoice
+func trustAnchorInfoSPKI(der []byte) ([]byte, error) {
+	var v asn1.RawValue
+
+	if _, err := asn1.Unmarshal(der, &v); err != nil {
+		return nil, err
+	}
+
+	if v.Class == asn1.ClassContextSpecific && v.Tag == 2 {
+		if _, err := asn1.Unmarshal(v.Bytes, &v); err != nil {
+			return nil, err
+		}
+	}
+
+	if v.Class != asn1.ClassUniversal || v.Tag != asn1.TagSequence {
+		return nil, errors.New("expecting a SEQUENCE")
+	}
+
+	// TrustAnchorInfo ::= SEQUENCE { version DEFAULT v1, pubKey, ... }
+	var elem asn1.RawValue
+
+	rest, err := asn1.Unmarshal(v.Bytes, &elem)
+	if err != nil {
+		return nil, err
+	}
+
+	if elem.Class == asn1.ClassUniversal && elem.Tag == asn1.TagInteger {
+		if _, err = asn1.Unmarshal(rest, &elem); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err = x509.ParsePKIXPublicKey(elem.FullBytes); err != nil {
+		return nil, err
+	}
+
+	return elem.FullBytes, nil
+}
+
+var nonFileNameChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// cotsEnvironmentLabel derives a file name friendly label from the first
+// environment group of the CoTS: the class vendor and model, the class id,
+// the name of the first tag creator entity or the name of the TA store,
+// whichever is found first
+func cotsEnvironmentLabel(cts *cots.ConciseTaStore) string {
+	var label string
+
+	if len(cts.Environments) != 0 {
+		eg := cts.Environments[0]
+
+		switch {
+		case eg.Environment != nil && eg.Environment.Class != nil:
+			c := eg.Environment.Class
+
+			var parts []string
+			if c.Vendor != nil {
+				parts = append(parts, *c.Vendor)
+			}
+			if c.Model != nil {
+				parts = append(parts, *c.Model)
+			}
+			if len(parts) == 0 && c.ClassID != nil {
+				parts = append(parts, c.ClassID.String())
+			}
+
+			label = strings.Join(parts, "-")
+		case eg.SwidTag != nil && len(eg.SwidTag.Entities) != 0:
+			label = eg.SwidTag.Entities[0].EntityName
+		case eg.NamedTaStore != nil:
+			label = *eg.NamedTaStore
+		}
+	}
+
+	label = strings.Trim(nonFileNameChars.ReplaceAllString(strings.ToLower(label), "-"), "-")
+	if label == "" {
+		return "cots"
+	}
+
+	return label
+}
+
+func init() {
+	cotsCmd.AddCommand(cotsExtractAnchorsCmd)
+}
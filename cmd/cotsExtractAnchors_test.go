@@ -0,0 +1,195 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"sort"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/corim/cots"
+)
+
+func Test_CotsExtractAnchorsCmd_unknown_argument(t *testing.T) {
+	cmd := NewCotsExtractAnchorsCmd()
+
+	args := []string{"--unknown-argument=val"}
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "unknown flag: --unknown-argument")
+}
+
+func Test_CotsExtractAnchorsCmd_no_file(t *testing.T) {
+	cmd := NewCotsExtractAnchorsCmd()
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "no CoTS supplied")
+}
+
+func Test_CotsExtractAnchorsCmd_bad_format(t *testing.T) {
+	cmd := NewCotsExtractAnchorsCmd()
+
+	args := []string{
+		"--file=cots.cbor",
+		"--format=jwk",
+	}
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, `unsupported format "jwk": expecting pem or der`)
+}
+
+func Test_CotsExtractAnchorsCmd_bad_cots(t *testing.T) {
+	var err error
+
+	fs = afero.NewMemMapFs()
+	err = afero.WriteFile(fs, "bad.cbor", []byte{0x00, 0x01}, 0644)
+	require.NoError(t, err)
+
+	cmd := NewCotsExtractAnchorsCmd()
+	cmd.SetArgs([]string{"--file=bad.cbor"})
+
+	err = cmd.Execute()
+	assert.ErrorContains(t, err, "error decoding CoTS from bad.cbor")
+}
+
+func Test_CotsExtractAnchorsCmd_pem_ok(t *testing.T) {
+	var err error
+
+	fs = afero.NewMemMapFs()
+	err = afero.WriteFile(fs, "cots.cbor", testCots, 0644)
+	require.NoError(t, err)
+
+	cmd := NewCotsExtractAnchorsCmd()
+
+	args := []string{
+		"--file=cots.cbor",
+		"--output-dir=anchors",
+	}
+	cmd.SetArgs(args)
+
+	err = cmd.Execute()
+	require.NoError(t, err)
+
+	files, err := afero.Glob(fs, "anchors/*.pem")
+	require.NoError(t, err)
+	require.Len(t, files, 3)
+
+	var blockTypes []string
+
+	for _, f := range files {
+		assert.Regexp(t, `^anchors/miscellaneous-ta-store-[0-9a-f]{16}\.pem$`, f)
+
+		data, err := afero.ReadFile(fs, f)
+		require.NoError(t, err)
+
+		block, rest := pem.Decode(data)
+		require.NotNil(t, block)
+		assert.Empty(t, rest)
+
+		switch block.Type {
+		case "CERTIFICATE":
+			_, err = x509.ParseCertificate(block.Bytes)
+		case "PUBLIC KEY":
+			_, err = x509.ParsePKIXPublicKey(block.Bytes)
+		}
+		assert.NoError(t, err)
+
+		blockTypes = append(blockTypes, block.Type)
+	}
+
+	sort.Strings(blockTypes)
+	assert.Equal(t, []string{"CERTIFICATE", "PUBLIC KEY", "PUBLIC KEY"}, blockTypes)
+}
+
+func Test_CotsExtractAnchorsCmd_der_ok(t *testing.T) {
+	var err error
+
+	fs = afero.NewMemMapFs()
+	err = afero.WriteFile(fs, "cots.cbor", testCots, 0644)
+	require.NoError(t, err)
+
+	cmd := NewCotsExtractAnchorsCmd()
+
+	args := []string{
+		"--file=cots.cbor",
+		"--format=der",
+	}
+	cmd.SetArgs(args)
+
+	err = cmd.Execute()
+	require.NoError(t, err)
+
+	files, err := afero.Glob(fs, "*.der")
+	require.NoError(t, err)
+	assert.Len(t, files, 3)
+}
+
+func Test_trustAnchorInfoSPKI(t *testing.T) {
+	pki := newTestPKI(t)
+
+	leaf, err := x509.ParseCertificate(pki.leafDER)
+	require.NoError(t, err)
+
+	spki := leaf.RawSubjectPublicKeyInfo
+
+	// TrustAnchorInfo with the default version and with an explicit one
+	keyID, err := asn1.Marshal([]byte{0x01, 0x02})
+	require.NoError(t, err)
+	version, err := asn1.Marshal(1)
+	require.NoError(t, err)
+
+	for _, content := range [][]byte{
+		append(append([]byte{}, spki...), keyID...),
+		append(append(append([]byte{}, version...), spki...), keyID...),
+	} {
+		taInfo, err := asn1.Marshal(asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSequence, IsCompound: true, Bytes: content})
+		require.NoError(t, err)
+
+		got, err := trustAnchorInfoSPKI(taInfo)
+		require.NoError(t, err)
+		assert.Equal(t, spki, got)
+
+		// taInfo alternative of TrustAnchorChoice
+		choice, err := asn1.Marshal(asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 2, IsCompound: true, Bytes: taInfo})
+		require.NoError(t, err)
+
+		got, err = trustAnchorInfoSPKI(choice)
+		require.NoError(t, err)
+		assert.Equal(t, spki, got)
+	}
+
+	_, err = trustAnchorInfoSPKI(keyID)
+	assert.EqualError(t, err, "expecting a SEQUENCE")
+}
+
+func Test_cotsEnvironmentLabel(t *testing.T) {
+	vendor := "Zesty Hands, Inc."
+	model := "Roadrunner 2"
+	store := "Miscellaneous TA Store"
+
+	tvs := []struct {
+		env      cots.EnvironmentGroups
+		expected string
+	}{
+		{nil, "cots"},
+		{cots.EnvironmentGroups{{NamedTaStore: &store}}, "miscellaneous-ta-store"},
+		{
+			cots.EnvironmentGroups{{Environment: &comid.Environment{Class: &comid.Class{Vendor: &vendor, Model: &model}}}},
+			"zesty-hands-inc-roadrunner-2",
+		},
+	}
+
+	for _, tv := range tvs {
+		cts := cots.ConciseTaStore{Environments: tv.env}
+		assert.Equal(t, tv.expected, cotsEnvironmentLabel(&cts))
+	}
+}
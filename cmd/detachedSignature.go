@@ -0,0 +1,199 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"crypto"
+	_ "crypto/sha256" // register SHA-256
+	_ "crypto/sha512" // register SHA-384 and SHA-512
+	"errors"
+	"fmt"
+
+	"github.com/spf13/afero"
+	"github.com/veraison/corim/corim"
+	cose "github.com/veraison/go-cose"
+)
+
+const (
+	// COSE header parameters of hash envelopes (draft-ietf-cose-hash-envelope),
+	// where the signed payload is the hash of the actual payload
+	headerLabelPayloadHashAlg      int64 = 258
+	headerLabelPreimageContentType int64 = 259
+	headerLabelPayloadLocation     int64 = 260
+)
+
+// payloadHashAlgs maps the COSE algorithm identifiers that can appear in the
+// payload_hash_alg header to the corresponding hash functions
+var payloadHashAlgs = map[int64]crypto.Hash{
+	-16: crypto.SHA256,
+	-43: crypto.SHA384,
+	-44: crypto.SHA512,
+}
+
+// verifyDetached verifies the detached COSE Sign1 signature in signatureFile
+// over the unsigned CoRIM in payloadFile.  If the protected header of the
+// signature carries a payload hash algorithm (i.e., it is a hash envelope), the
+// signature covers the hash of the payload, and only --key can be used to
+// verify it.  Otherwise, the payload is put back in the COSE Sign1 and the
+// result is verified as any other signed CoRIM.
+func verifyDetached(signatureFile, payloadFile, keyFile string, opts verifyOptions) error {
+	sig, err := afero.ReadFile(fs, signatureFile)
+	if err != nil {
+		return fmt.Errorf("error loading detached signature from %s: %w", signatureFile, err)
+	}
+
+	payload, err := afero.ReadFile(fs, payloadFile)
+	if err != nil {
+		return fmt.Errorf("error loading payload from %s: %w", payloadFile, err)
+	}
+
+	msg, err := decodeSign1(tagSign1(sig))
+	if err != nil {
+		return fmt.Errorf("error decoding detached signature from %s: %w", signatureFile, err)
+	}
+
+	if msg.Payload != nil {
+		return fmt.Errorf("error decoding detached signature from %s: the COSE Sign1 carries a payload", signatureFile)
+	}
+
+	hashAlg, err := payloadHashAlg(msg)
+	if err != nil {
+		return fmt.Errorf("error verifying %s: %w", signatureFile, err)
+	}
+
+	if hashAlg == 0 {
+		msg.Payload = payload
+
+		signedCorimCBOR, err := msg.MarshalCBOR()
+		if err != nil {
+			return fmt.Errorf("error attaching payload %s to %s: %w", payloadFile, signatureFile, err)
+		}
+
+		return verifyCorimData(signatureFile, signedCorimCBOR, keyFile, opts)
+	}
+
+	return verifyHashEnvelope(signatureFile, msg, payload, hashAlg, keyFile, opts)
+}
+
+// payloadHashAlg returns the hash function identified by the payload_hash_alg
+// protected header of msg, or 0 if there is no such header
+func payloadHashAlg(msg *cose.Sign1Message) (crypto.Hash, error) {
+	if _, ok := msg.Headers.Unprotected[headerLabelPayloadHashAlg]; ok {
+		return 0, errors.New("payload_hash_alg header: must be protected")
+	}
+
+	v, ok := msg.Headers.Protected[headerLabelPayloadHashAlg]
+	if !ok {
+		return 0, nil
+	}
+
+	id, ok := cborInt(v)
+	if !ok {
+		return 0, fmt.Errorf("payload_hash_alg header: expecting integer, got %T", v)
+	}
+
+	h, ok := payloadHashAlgs[id]
+	if !ok {
+		return 0, fmt.Errorf("payload_hash_alg header: unsupported hash algorithm %d", id)
+	}
+
+	return h, nil
+}
+
+// verifyHashEnvelope verifies the hash envelope msg, whose signature covers the
+// hash (computed with hashAlg) of payload, using the public key in keyFile.
+// The CoRIM checks in opts are then applied to payload.
+func verifyHashEnvelope(
+	signatureFile string, msg *cose.Sign1Message, payload []byte, hashAlg crypto.Hash, keyFile string, opts verifyOptions,
+) error {
+	if keyFile == "" {
+		return fmt.Errorf("error verifying %s: hash envelope signatures can only be verified with --key", signatureFile)
+	}
+
+	if opts.expectedKeyID != "" || opts.printChain || opts.maxSigningSkew != 0 || opts.maxAge != 0 {
+		return fmt.Errorf("error verifying %s: --expected-kid, --print-chain, --max-signing-skew and --max-age are not supported with hash envelope signatures", signatureFile)
+	}
+
+	if err := checkAlgHeader(&msg.Headers, opts.unprotectedAlg, opts.quiet()); err != nil {
+		return fmt.Errorf("error verifying %s: %w", signatureFile, err)
+	}
+
+	err := checkCOSEHeaders(&msg.Headers, headerLabelPayloadHashAlg, headerLabelPreimageContentType, headerLabelPayloadLocation)
+	if err != nil {
+		return fmt.Errorf("error verifying %s: %w", signatureFile, err)
+	}
+
+	keyData, err := afero.ReadFile(fs, keyFile)
+	if err != nil {
+		return fmt.Errorf("error loading verifying key from %s: %w", keyFile, err)
+	}
+
+	pkey, err := parsePublicKey(keyData)
+	if err != nil {
+		return fmt.Errorf("error loading verifying key from %s: %w", keyFile, err)
+	}
+
+	// checkCOSEHeaders makes sure the alg header is there
+	alg, _ := msg.Headers.Protected.Algorithm()
+
+	if err = opts.algPolicy.check(alg); err != nil {
+		return fmt.Errorf("error verifying %s: %w", signatureFile, err)
+	}
+
+	verifier, err := cose.NewVerifier(alg, pkey)
+	if err != nil {
+		return fmt.Errorf("error verifying %s with key %s: %w", signatureFile, keyFile, err)
+	}
+
+	h := hashAlg.New()
+	h.Write(payload)
+	msg.Payload = h.Sum(nil)
+
+	if err = msg.Verify(corim.NoExternalData, verifier); err != nil {
+		return fmt.Errorf("error verifying %s with key %s: %w", signatureFile, keyFile, err)
+	}
+
+	progressf(opts.quiet(), ">> hash envelope: signature covers the %s of the payload\n", hashAlg)
+
+	if err = checkTimestamp(msg, opts.tsaAnchorFiles, opts.timezone, opts.quiet()); err != nil {
+		return fmt.Errorf("error verifying %s: %w", signatureFile, err)
+	}
+
+	u := corim.GetUnsignedCorim(cborProfile(payload))
+	if err = decodeCBOR(u, payload, opts.strictDecode); err != nil {
+		return fmt.Errorf("error decoding payload of %s: %w", signatureFile, err)
+	}
+
+	if err = opts.profileAllowlist.check(*u); err != nil {
+		return fmt.Errorf("error verifying %s: %w", signatureFile, err)
+	}
+
+	if err = checkCorimExpectations(*u, opts.expectedID, opts.expectedProfile); err != nil {
+		return fmt.Errorf("error verifying %s: %w", signatureFile, err)
+	}
+
+	if opts.selfConsistent {
+		if err = checkSelfConsistency(*u); err != nil {
+			return fmt.Errorf("error verifying %s: %w", signatureFile, err)
+		}
+	}
+
+	if opts.validateTags {
+		if err = checkCorimTags(*u); err != nil {
+			return fmt.Errorf("error verifying %s: %w", signatureFile, err)
+		}
+	}
+
+	if opts.payloadSHA256 != "" {
+		if err = checkSHA256(payload, opts.payloadSHA256); err != nil {
+			return fmt.Errorf("error verifying %s: %w", signatureFile, err)
+		}
+	}
+
+	if opts.stats != "" {
+		return reportCorimStats(*u, signatureFile, opts.stats)
+	}
+
+	return nil
+}
@@ -0,0 +1,152 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/corim"
+	cose "github.com/veraison/go-cose"
+)
+
+// detachSignature returns the signed CoRIM in data with its payload removed,
+// together with the payload
+func detachSignature(t *testing.T, data []byte) ([]byte, []byte) {
+	msg, err := decodeSign1(data)
+	require.NoError(t, err)
+
+	payload := msg.Payload
+	msg.Payload = nil
+
+	sig, err := msg.MarshalCBOR()
+	require.NoError(t, err)
+
+	return sig, payload
+}
+
+// newTestHashEnvelope returns a detached hash envelope signature over payload,
+// with payload_hash_alg set to SHA-256
+func newTestHashEnvelope(t *testing.T, payload []byte) []byte {
+	signer, err := corim.NewSignerFromJWK(testECKey)
+	require.NoError(t, err)
+
+	digest := sha256.Sum256(payload)
+
+	msg := cose.NewSign1Message()
+	msg.Headers.Protected.SetAlgorithm(signer.Algorithm())
+	msg.Headers.Protected[headerLabelPayloadHashAlg] = int64(-16)
+	msg.Headers.Protected[cose.HeaderLabelCritical] = []interface{}{headerLabelPayloadHashAlg}
+	msg.Payload = digest[:]
+
+	require.NoError(t, msg.Sign(rand.Reader, corim.NoExternalData, signer))
+
+	msg.Payload = nil
+
+	sig, err := msg.MarshalCBOR()
+	require.NoError(t, err)
+
+	return sig
+}
+
+func verifyDetachedCmd(args ...string) error {
+	cmd := NewCorimVerifyCmd()
+	cmd.SetArgs(append([]string{"--signature=sig.cbor", "--payload=payload.cbor"}, args...))
+	return cmd.Execute()
+}
+
+func Test_CorimVerifyCmd_detached_ok(t *testing.T) {
+	sig, payload := detachSignature(t, testSignedCorimValid)
+
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "sig.cbor", sig, 0644))
+	require.NoError(t, afero.WriteFile(fs, "payload.cbor", payload, 0644))
+	require.NoError(t, afero.WriteFile(fs, "ok.jwk", testECKey, 0644))
+
+	assert.NoError(t, verifyDetachedCmd("--key=ok.jwk"))
+}
+
+func Test_CorimVerifyCmd_detached_wrong_payload(t *testing.T) {
+	sig, _ := detachSignature(t, testSignedCorimValid)
+
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "sig.cbor", sig, 0644))
+	require.NoError(t, afero.WriteFile(fs, "payload.cbor", testCorimValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "ok.jwk", testECKey, 0644))
+
+	assert.ErrorContains(t, verifyDetachedCmd("--key=ok.jwk"), "error verifying sig.cbor with key ok.jwk")
+}
+
+func Test_CorimVerifyCmd_detached_not_detached(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "sig.cbor", testSignedCorimValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "payload.cbor", testCorimValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "ok.jwk", testECKey, 0644))
+
+	assert.EqualError(t, verifyDetachedCmd("--key=ok.jwk"),
+		"error decoding detached signature from sig.cbor: the COSE Sign1 carries a payload")
+}
+
+func Test_CorimVerifyCmd_detached_hash_envelope_ok(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "sig.cbor", newTestHashEnvelope(t, testCorimValid), 0644))
+	require.NoError(t, afero.WriteFile(fs, "payload.cbor", testCorimValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "ok.jwk", testECKey, 0644))
+
+	var u corim.UnsignedCorim
+	require.NoError(t, u.FromCBOR(testCorimValid))
+
+	assert.NoError(t, verifyDetachedCmd("--key=ok.jwk", "--expected-id="+u.GetID()))
+}
+
+func Test_CorimVerifyCmd_detached_hash_envelope_tampered(t *testing.T) {
+	tampered := append([]byte{}, testCorimValid...)
+	tampered[len(tampered)-1] ^= 0xff
+
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "sig.cbor", newTestHashEnvelope(t, testCorimValid), 0644))
+	require.NoError(t, afero.WriteFile(fs, "payload.cbor", tampered, 0644))
+	require.NoError(t, afero.WriteFile(fs, "ok.jwk", testECKey, 0644))
+
+	assert.ErrorContains(t, verifyDetachedCmd("--key=ok.jwk"), "error verifying sig.cbor with key ok.jwk: verification error")
+}
+
+func Test_CorimVerifyCmd_detached_hash_envelope_needs_key(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "sig.cbor", newTestHashEnvelope(t, testCorimValid), 0644))
+	require.NoError(t, afero.WriteFile(fs, "payload.cbor", testCorimValid, 0644))
+
+	assert.EqualError(t, verifyDetachedCmd("--use-embedded-key"),
+		"error verifying sig.cbor: hash envelope signatures can only be verified with --key")
+}
+
+func Test_payloadHashAlg_unsupported(t *testing.T) {
+	msg := cose.NewSign1Message()
+	msg.Headers.Protected[headerLabelPayloadHashAlg] = int64(-999)
+
+	_, err := payloadHashAlg(msg)
+	assert.EqualError(t, err, "payload_hash_alg header: unsupported hash algorithm -999")
+}
+
+func Test_CorimVerifyCmd_detached_bad_args(t *testing.T) {
+	tvs := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"--payload=payload.cbor", "--key=ok.jwk"}, "--payload can only be used together with --signature"},
+		{[]string{"--signature=sig.cbor", "--key=ok.jwk"}, "--signature requires --payload"},
+		{[]string{"--signature=sig.cbor", "--payload=payload.cbor", "--file=ok.cbor", "--key=ok.jwk"}, "--signature cannot be used together with --file or --dir"},
+		{[]string{"--signature=sig.cbor", "--payload=payload.cbor", "--sequence", "--key=ok.jwk"}, "--sequence cannot be used together with --signature"},
+	}
+
+	for _, tv := range tvs {
+		cmd := NewCorimVerifyCmd()
+		cmd.SetArgs(tv.args)
+		assert.EqualError(t, cmd.Execute(), tv.expected, tv.args)
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// deterministicCBOR rewrites the single CBOR data item in data so that the
+// entries of each map (at any nesting level) are sorted in the bytewise
+// lexicographic order of their encoded keys, as mandated by the core
+// deterministic encoding requirements of RFC 8949 §4.2.1.  Everything else,
+// including tags, is preserved as-is.  Indefinite-length items are rejected.
+func deterministicCBOR(data []byte) ([]byte, error) {
+	out, n, err := deterministicItem(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if n != len(data) {
+		return nil, fmt.Errorf("%d trailing byte(s) after CBOR data item", len(data)-n)
+	}
+
+	return out, nil
+}
+
+// deterministicItem processes the data item at the start of data and returns
+// its deterministic encoding together with the number of bytes consumed
+func deterministicItem(data []byte) ([]byte, int, error) {
+	major, arg, hlen, err := cborHead(data)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	switch major {
+	case 0, 1, 7: // unsigned, negative, simple values and floats
+		return data[:hlen], hlen, nil
+	case 2, 3: // byte and text strings
+		if uint64(len(data)-hlen) < arg {
+			return nil, 0, errors.New("truncated CBOR string")
+		}
+		n := hlen + int(arg)
+		return data[:n], n, nil
+	case 4: // array
+		out := append([]byte{}, data[:hlen]...)
+		off := hlen
+		for i := uint64(0); i < arg; i++ {
+			item, n, err := deterministicItem(data[off:])
+			if err != nil {
+				return nil, 0, err
+			}
+			out = append(out, item...)
+			off += n
+		}
+		return out, off, nil
+	case 5: // map
+		type entry struct{ key, kv []byte }
+		var entries []entry
+		off := hlen
+		for i := uint64(0); i < arg; i++ {
+			key, n, err := deterministicItem(data[off:])
+			if err != nil {
+				return nil, 0, err
+			}
+			off += n
+			val, n, err := deterministicItem(data[off:])
+			if err != nil {
+				return nil, 0, err
+			}
+			off += n
+			entries = append(entries, entry{key, append(append([]byte{}, key...), val...)})
+		}
+		sort.SliceStable(entries, func(i, j int) bool {
+			return bytes.Compare(entries[i].key, entries[j].key) < 0
+		})
+		out := append([]byte{}, data[:hlen]...)
+		for _, e := range entries {
+			out = append(out, e.kv...)
+		}
+		return out, off, nil
+	default: // tag
+		content, n, err := deterministicItem(data[hlen:])
+		if err != nil {
+			return nil, 0, err
+		}
+		return append(append([]byte{}, data[:hlen]...), content...), hlen + n, nil
+	}
+}
+
+// cborHead decodes the initial byte and argument of the data item at the
+// start of data, returning its major type, argument and head length
+func cborHead(data []byte) (byte, uint64, int, error) {
+	if len(data) == 0 {
+		return 0, 0, 0, errors.New("unexpected end of CBOR data")
+	}
+
+	major, ai := data[0]>>5, data[0]&0x1f
+
+	var hlen int
+
+	switch {
+	case ai < 24:
+		return major, uint64(ai), 1, nil
+	case ai == 24:
+		hlen = 2
+	case ai == 25:
+		hlen = 3
+	case ai == 26:
+		hlen = 5
+	case ai == 27:
+		hlen = 9
+	case ai == 31:
+		return 0, 0, 0, errors.New("indefinite-length CBOR items are not supported")
+	default:
+		return 0, 0, 0, fmt.Errorf("malformed CBOR head 0x%02x", data[0])
+	}
+
+	if len(data) < hlen {
+		return 0, 0, 0, errors.New("unexpected end of CBOR data")
+	}
+
+	buf := make([]byte, 8)
+	copy(buf[8-(hlen-1):], data[1:hlen])
+
+	return major, binary.BigEndian.Uint64(buf), hlen, nil
+}
@@ -0,0 +1,64 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/veraison/corim/comid"
+)
+
+func Test_deterministicCBOR(t *testing.T) {
+	tvs := []struct {
+		desc     string
+		input    string
+		expected string
+		err      string
+	}{
+		{
+			desc:     "already sorted",
+			input:    "a201020304",
+			expected: "a201020304",
+		},
+		{
+			// {3: 4, 1: [{"b": 1, "a": 2}], 0: 1(1363896240)}
+			desc:     "nested maps, arrays and tags",
+			input:    "a3030401a2616201616102" + "00c11a514b67b0",
+			expected: "a300c11a514b67b001a2616102616201" + "0304",
+		},
+		{
+			desc:  "indefinite-length map",
+			input: "bf0102ff",
+			err:   "indefinite-length CBOR items are not supported",
+		},
+		{
+			desc:  "truncated string",
+			input: "43aabb",
+			err:   "truncated CBOR string",
+		},
+		{
+			desc:  "trailing bytes",
+			input: "0102",
+			err:   "1 trailing byte(s) after CBOR data item",
+		},
+		{
+			desc:  "empty",
+			input: "",
+			err:   "unexpected end of CBOR data",
+		},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.desc, func(t *testing.T) {
+			actual, err := deterministicCBOR(comid.MustHexDecode(t, tv.input))
+			if tv.err != "" {
+				assert.EqualError(t, err, tv.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, comid.MustHexDecode(t, tv.expected), actual)
+		})
+	}
+}
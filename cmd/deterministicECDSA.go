@@ -0,0 +1,98 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	cose "github.com/veraison/go-cose"
+)
+
+// deterministicECDSASigner is a cose.Signer producing ECDSA signatures whose
+// nonce is derived from the private key and the message as described in RFC
+// 6979, rather than drawn from a random source: signing the same content with
+// the same key always yields the same signature.
+//
+// RFC 6979 signatures are as secure as randomized ones, as long as the key is
+// only ever used with the same hash function (which COSE ensures), and are not
+// exposed to a poor random number generator.  They can be verified by any
+// ECDSA implementation.  The signatures are computed by crypto/ecdsa, which
+// produces RFC 6979 signatures when no random source is supplied.
+type deterministicECDSASigner struct {
+	key  *ecdsa.PrivateKey
+	alg  cose.Algorithm
+	hash crypto.Hash
+}
+
+// newDeterministicECDSASigner returns a deterministic ECDSA signer using the
+// private key in JWK format j, which must be a P-256, P-384 or P-521 key
+func newDeterministicECDSASigner(j []byte) (*deterministicECDSASigner, error) {
+	k, err := jwk.ParseKey(j)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw interface{}
+
+	if err = k.Raw(&raw); err != nil {
+		return nil, err
+	}
+
+	key, ok := raw.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("deterministic signatures require an ECDSA private key")
+	}
+
+	o := &deterministicECDSASigner{key: key}
+
+	switch key.Curve {
+	case elliptic.P256():
+		o.alg, o.hash = cose.AlgorithmES256, crypto.SHA256
+	case elliptic.P384():
+		o.alg, o.hash = cose.AlgorithmES384, crypto.SHA384
+	case elliptic.P521():
+		o.alg, o.hash = cose.AlgorithmES512, crypto.SHA512
+	default:
+		return nil, fmt.Errorf("unsupported elliptic curve %s", key.Curve.Params().Name)
+	}
+
+	return o, nil
+}
+
+func (o *deterministicECDSASigner) Algorithm() cose.Algorithm {
+	return o.alg
+}
+
+// Sign returns the RFC 6979 signature of content, in the fixed-size r || s form
+// used by COSE.  The random source is ignored.
+func (o *deterministicECDSASigner) Sign(_ io.Reader, content []byte) ([]byte, error) {
+	h := o.hash.New()
+	h.Write(content)
+
+	// a nil random source selects RFC 6979
+	der, err := o.key.Sign(nil, h.Sum(nil), o.hash)
+	if err != nil {
+		return nil, fmt.Errorf("deterministic ECDSA signature: %w", err)
+	}
+
+	var sig struct {
+		R, S *big.Int
+	}
+
+	if _, err = asn1.Unmarshal(der, &sig); err != nil {
+		return nil, fmt.Errorf("deterministic ECDSA signature: %w", err)
+	}
+
+	size := (o.key.Curve.Params().N.BitLen() + 7) / 8
+
+	return append(sig.R.FillBytes(make([]byte, size)), sig.S.FillBytes(make([]byte, size))...), nil
+}
@@ -0,0 +1,185 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/corim"
+	cose "github.com/veraison/go-cose"
+)
+
+func mustHexToB64URL(t *testing.T, s string) string {
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// RFC 6979, appendix A.2.5 to A.2.7, message "sample", using the hash function
+// that COSE associates with each curve
+func Test_deterministicECDSASigner_RFC6979_vectors(t *testing.T) {
+	tvs := []struct {
+		crv, d, x, y string
+		alg          cose.Algorithm
+		sig          string
+	}{
+		{
+			crv: "P-256",
+			d:   "C9AFA9D845BA75166B5C215767B1D6934E50C3DB36E89B127B8A622B120F6721",
+			x:   "60FED4BA255A9D31C961EB74C6356D68C049B8923B61FA6CE669622E60F29FB6",
+			y:   "7903FE1008B8BC99A41AE9E95628BC64F2F1B20C2D7E9F5177A3C294D4462299",
+			alg: cose.AlgorithmES256,
+			sig: "EFD48B2AACB6A8FD1140DD9CD45E81D69D2C877B56AAF991C34D0EA84EAF3716" +
+				"F7CB1C942D657C41D436C7A1B6E29F65F3E900DBB9AFF4064DC4AB2F843ACDA8",
+		},
+		{
+			crv: "P-384",
+			d:   "6B9D3DAD2E1B8C1C05B19875B6659F4DE23C3B667BF297BA9AA47740787137D896D5724E4C70A825F872C9EA60D2EDF5",
+			x:   "EC3A4E415B4E19A4568618029F427FA5DA9A8BC4AE92E02E06AAE5286B300C64DEF8F0EA9055866064A254515480BC13",
+			y:   "8015D9B72D7D57244EA8EF9AC0C621896708A59367F9DFB9F54CA84B3F1C9DB1288B231C3AE0D4FE7344FD2533264720",
+			alg: cose.AlgorithmES384,
+			sig: "94EDBB92A5ECB8AAD4736E56C691916B3F88140666CE9FA73D64C4EA95AD133C81A648152E44ACF96E36DD1E80FABE46" +
+				"99EF4AEB15F178CEA1FE40DB2603138F130E740A19624526203B6351D0A3A94FA329C145786E679E7B82C71A38628AC8",
+		},
+		{
+			crv: "P-521",
+			d:   "00FAD06DAA62BA3B25D2FB40133DA757205DE67F5BB0018FEE8C86E1B68C7E75CAA896EB32F1F47C70855836A6D16FCC1466F6D8FBEC67DB89EC0C08B0E996B83538",
+			x:   "01894550D0785932E00EAA23B694F213F8C3121F86DC97A04E5A7167DB4E5BCD371123D46E45DB6B5D5370A7F20FB633155D38FFA16D2BD761DCAC474B9A2F5023A4",
+			y:   "00493101C962CD4D2FDDF782285E64584139C2F91B47F87FF82354D6630F746A28A0DB25741B5B34A828008B22ACC23F924FAAFBD4D33F81EA66956DFEAA2BFDFCF5",
+			alg: cose.AlgorithmES512,
+			sig: "00C328FAFCBD79DD77850370C46325D987CB525569FB63C5D3BC53950E6D4C5F174E25A1EE9017B5D450606ADD152B534931D7D4E8455CC91F9B15BF05EC36E377FA" +
+				"00617CCE7CF5064806C467F678D3B4080D6F1CC50AF26CA209417308281B68AF282623EAA63E5B5C0723D8B8C37FF0777B1A20F8CCB1DCCC43997F1EE0E44DA4A67A",
+		},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.crv, func(t *testing.T) {
+			key := fmt.Sprintf(`{"kty":"EC","crv":%q,"d":%q,"x":%q,"y":%q}`,
+				tv.crv, mustHexToB64URL(t, tv.d), mustHexToB64URL(t, tv.x), mustHexToB64URL(t, tv.y))
+
+			signer, err := newDeterministicECDSASigner([]byte(key))
+			require.NoError(t, err)
+			assert.Equal(t, tv.alg, signer.Algorithm())
+
+			sig, err := signer.Sign(nil, []byte("sample"))
+			require.NoError(t, err)
+
+			assert.Equal(t, tv.sig, fmt.Sprintf("%X", sig))
+		})
+	}
+}
+
+func Test_deterministicECDSASigner_curves(t *testing.T) {
+	tvs := []struct {
+		curve elliptic.Curve
+		alg   cose.Algorithm
+	}{
+		{elliptic.P256(), cose.AlgorithmES256},
+		{elliptic.P384(), cose.AlgorithmES384},
+		{elliptic.P521(), cose.AlgorithmES512},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.curve.Params().Name, func(t *testing.T) {
+			priv, err := ecdsa.GenerateKey(tv.curve, rand.Reader)
+			require.NoError(t, err)
+
+			k, err := jwk.FromRaw(priv)
+			require.NoError(t, err)
+
+			keyJWK, err := json.Marshal(k)
+			require.NoError(t, err)
+
+			signer, err := newDeterministicECDSASigner(keyJWK)
+			require.NoError(t, err)
+			assert.Equal(t, tv.alg, signer.Algorithm())
+
+			sig1, err := signer.Sign(rand.Reader, []byte("content"))
+			require.NoError(t, err)
+
+			sig2, err := signer.Sign(rand.Reader, []byte("content"))
+			require.NoError(t, err)
+
+			other, err := signer.Sign(rand.Reader, []byte("other content"))
+			require.NoError(t, err)
+
+			assert.Equal(t, sig1, sig2)
+			assert.NotEqual(t, sig1, other)
+
+			verifier, err := cose.NewVerifier(tv.alg, &priv.PublicKey)
+			require.NoError(t, err)
+			assert.NoError(t, verifier.Verify([]byte("content"), sig1))
+		})
+	}
+}
+
+func Test_newDeterministicECDSASigner_not_ecdsa(t *testing.T) {
+	_, err := newDeterministicECDSASigner(testEdDSAKey)
+	assert.EqualError(t, err, "deterministic signatures require an ECDSA private key")
+}
+
+func Test_CorimSignCmd_deterministic_ecdsa(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "ok.cbor", testCorimValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "ok.json", testMetaValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "ok.jwk", testECKey, 0644))
+
+	var outputs [][]byte
+
+	for _, out := range []string{"one.cbor", "two.cbor"} {
+		cmd := NewCorimSignCmd()
+		cmd.SetArgs([]string{
+			"--file=ok.cbor",
+			"--key=ok.jwk",
+			"--meta=ok.json",
+			"--reproducible",
+			"--deterministic-ecdsa",
+			"--output=" + out,
+		})
+		require.NoError(t, cmd.Execute())
+
+		outputs = append(outputs, mustReadFile(t, out))
+	}
+
+	assert.Equal(t, outputs[0], outputs[1])
+
+	var s corim.SignedCorim
+	require.NoError(t, s.FromCOSE(outputs[0]))
+
+	pk, err := corim.NewPublicKeyFromJWK(testECKey)
+	require.NoError(t, err)
+	assert.NoError(t, s.Verify(pk))
+}
+
+func Test_CorimSignCmd_deterministic_ecdsa_bad_key(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "ok.cbor", testCorimValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "ok.json", testMetaValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "ok.jwk", testEdDSAKey, 0644))
+
+	cmd := NewCorimSignCmd()
+	cmd.SetArgs([]string{
+		"--file=ok.cbor", "--key=ok.jwk", "--meta=ok.json", "--deterministic-ecdsa", "--output=out.cbor",
+	})
+	assert.EqualError(t, cmd.Execute(),
+		"error loading signing key from ok.jwk: deterministic signatures require an ECDSA private key")
+}
+
+func Test_CorimSignCmd_deterministic_ecdsa_ssh_agent(t *testing.T) {
+	cmd := NewCorimSignCmd()
+	cmd.SetArgs([]string{"--file=ok.cbor", "--ssh-agent", "--meta=ok.json", "--deterministic-ecdsa"})
+	assert.EqualError(t, cmd.Execute(), "--deterministic-ecdsa cannot be used together with --ssh-agent")
+}
@@ -0,0 +1,63 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/swid"
+)
+
+// digestSizes maps the hash algorithms of the Named Information Hash Algorithm
+// Registry to the length (in bytes) of their output
+var digestSizes = map[uint64]int{
+	swid.Sha256:     32,
+	swid.Sha256_128: 16,
+	swid.Sha256_120: 15,
+	swid.Sha256_96:  12,
+	swid.Sha256_64:  8,
+	swid.Sha256_32:  4,
+	swid.Sha384:     48,
+	swid.Sha512:     64,
+	swid.Sha3_224:   28,
+	swid.Sha3_256:   32,
+	swid.Sha3_384:   48,
+	swid.Sha3_512:   64,
+}
+
+// checkDigestLengths checks that the length of each digest in the reference
+// and endorsed value measurements of c matches the output size of its hash
+// algorithm, e.g., that a sha-256 digest is not 20 bytes long.  All the
+// mismatches are reported, together with the environment and key of the
+// measurement.  Digests using unknown algorithms are not checked.
+func checkDigestLengths(c *comid.Comid) error {
+	ims, err := indexMeasurements(c)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+
+	for _, im := range ims {
+		for i, h := range im.hashes {
+			want, ok := digestSizes[h.HashAlgID]
+			if !ok || want == len(h.HashValue) {
+				continue
+			}
+
+			errs = append(errs, fmt.Errorf(
+				"%s: environment %s, key %s: digest at index %d: %s digest must be %d bytes long, got %d",
+				im.triple, im.environment, orDash(string(im.key)), i, h.AlgIDToString(), want, len(h.HashValue),
+			))
+		}
+	}
+
+	if len(errs) != 0 {
+		return fmt.Errorf("digest length mismatch: %w", errors.Join(errs...))
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/corim/corim"
+)
+
+// newTestComidWithShortDigest returns the test CoMID, with the sha-256 digest
+// of its first measurement truncated to the length of a SHA-1 digest
+func newTestComidWithShortDigest(t *testing.T) *comid.Comid {
+	c := newTestComid(t)
+
+	m := &c.Triples.ReferenceValues.Values[0].Measurements.Values[0]
+	require.NotNil(t, m.Val.Digests)
+
+	d := *m.Val.Digests
+	d[0].HashValue = d[0].HashValue[:20]
+
+	return c
+}
+
+// shortDigestComidCBOR returns the CBOR encoding of the test CoMID with the
+// first sha-256 digest truncated to 20 bytes.  ToCBOR refuses to encode such a
+// CoMID, so the encoding of the intact one is patched instead.
+func shortDigestComidCBOR(t *testing.T) []byte {
+	c := newTestComid(t)
+
+	data, err := c.ToCBOR()
+	require.NoError(t, err)
+
+	digest := (*c.Triples.ReferenceValues.Values[0].Measurements.Values[0].Val.Digests)[0].HashValue
+	require.Len(t, digest, 32)
+
+	// bstr(32) -> bstr(20)
+	long := append([]byte{0x58, 0x20}, digest...)
+	short := append([]byte{0x54}, digest[:20]...)
+	require.Equal(t, 1, bytes.Count(data, long))
+
+	return bytes.Replace(data, long, short, 1)
+}
+
+func Test_checkDigestLengths_ok(t *testing.T) {
+	assert.NoError(t, checkDigestLengths(newTestComid(t)))
+}
+
+func Test_checkDigestLengths_mismatch(t *testing.T) {
+	err := checkDigestLengths(newTestComidWithShortDigest(t))
+	require.Error(t, err)
+	assert.Regexp(t, `^digest length mismatch: reference-values: environment \{.*"model":"RoadRunner".*\}, key \{.*\}: digest at index 0: sha-256 digest must be 32 bytes long, got 20$`, err.Error())
+}
+
+func Test_ComidValidateCmd_digest_length_mismatch(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "short.cbor", shortDigestComidCBOR(t), 0644)
+	require.NoError(t, err)
+
+	err = validateComid("short.cbor")
+	assert.ErrorContains(t, err, "error validating CoMID short.cbor: digest length mismatch: ")
+	assert.ErrorContains(t, err, "sha-256 digest must be 32 bytes long, got 20")
+}
+
+func Test_CorimValidateCmd_digest_length_mismatch(t *testing.T) {
+	u := corim.NewUnsignedCorim().SetID("test")
+	require.NotNil(t, u.AddComid(newTestComid(t)))
+
+	// AddComid validates the CoMID, so the faulty one is added as a raw tag
+	u.Tags = append(u.Tags, append(append(corim.Tag{}, corim.ComidTag...), shortDigestComidCBOR(t)...))
+
+	corimCBOR, err := u.ToCBOR()
+	require.NoError(t, err)
+
+	fs = afero.NewMemMapFs()
+	err = afero.WriteFile(fs, "short.cbor", corimCBOR, 0644)
+	require.NoError(t, err)
+
+	err = validateCorim("short.cbor", "", "")
+	assert.ErrorContains(t, err, "error validating CoRIM, 1 problem(s) found:\n  tag [1] (CoMID): digest length mismatch: ")
+	assert.ErrorContains(t, err, "sha-256 digest must be 32 bytes long, got 20")
+}
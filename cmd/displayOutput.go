@@ -0,0 +1,61 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/afero"
+)
+
+// withDisplayOutput runs the display function fn and, if outputFile is not
+// empty, saves what fn prints to stdout to outputFile rather than printing it.
+// Messages printed to stderr are not affected.  What has been rendered is
+// saved even if fn fails (e.g., if only some of a number of files could be
+// displayed), unless nothing at all was rendered.
+func withDisplayOutput(outputFile string, fn func() error) error {
+	if outputFile == "" {
+		return fn()
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("error redirecting output to %s: %w", outputFile, err)
+	}
+	defer r.Close()
+
+	rendered := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		rendered <- data
+	}()
+
+	stdout := os.Stdout
+	os.Stdout = w
+
+	fnErr := fn()
+
+	os.Stdout = stdout
+	w.Close()
+
+	data := <-rendered
+
+	if fnErr != nil && len(data) == 0 {
+		return fnErr
+	}
+
+	if err = afero.WriteFile(fs, outputFile, data, 0644); err != nil {
+		return fmt.Errorf("error saving output to %s: %w", outputFile, err)
+	}
+
+	if fnErr != nil {
+		return fnErr
+	}
+
+	fmt.Printf(">> output saved to %q\n", outputFile)
+
+	return nil
+}
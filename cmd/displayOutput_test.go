@@ -0,0 +1,117 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_withDisplayOutput(t *testing.T) {
+	fs = afero.NewMemMapFs()
+
+	err := withDisplayOutput("out.txt", func() error {
+		fmt.Println("hello")
+		return nil
+	})
+	require.NoError(t, err)
+
+	data, err := afero.ReadFile(fs, "out.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "hello\n", string(data))
+
+	// partial output is saved, and the error is still reported
+	err = withDisplayOutput("partial.txt", func() error {
+		fmt.Println("partial")
+		return errors.New("boom")
+	})
+	assert.EqualError(t, err, "boom")
+
+	data, err = afero.ReadFile(fs, "partial.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "partial\n", string(data))
+
+	// nothing is saved if nothing was rendered
+	err = withDisplayOutput("empty.txt", func() error {
+		return errors.New("boom")
+	})
+	assert.EqualError(t, err, "boom")
+
+	_, err = fs.Stat("empty.txt")
+	assert.Error(t, err)
+}
+
+func Test_CorimDisplayCmd_output(t *testing.T) {
+	cmd := NewCorimDisplayCmd()
+
+	args := []string{
+		"--file=ok.cbor",
+		"--measurements-flat",
+		"--json",
+		"--output=digests.json",
+	}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "ok.cbor", newTestSignedCorimWithComids(t, 1), 0644)
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	require.NoError(t, err)
+
+	data, err := afero.ReadFile(fs, "digests.json")
+	require.NoError(t, err)
+
+	var rows []map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &rows))
+	assert.Len(t, rows, 3)
+}
+
+func Test_ComidDisplayCmd_output(t *testing.T) {
+	cmd := NewComidDisplayCmd()
+
+	args := []string{
+		"--file=ok.cbor",
+		"--output=comid.txt",
+	}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "ok.cbor", testComid, 0644)
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	require.NoError(t, err)
+
+	data, err := afero.ReadFile(fs, "comid.txt")
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `>> [ok.cbor]`)
+}
+
+func Test_CotsDisplayCmd_output(t *testing.T) {
+	cmd := NewCotsDisplayCmd()
+
+	args := []string{
+		"--file=ok.cbor",
+		"--output=cots.txt",
+	}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "ok.cbor", testCots, 0644)
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	require.NoError(t, err)
+
+	data, err := afero.ReadFile(fs, "cots.txt")
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `>> [ok.cbor]`)
+}
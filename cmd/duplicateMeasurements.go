@@ -0,0 +1,65 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/veraison/corim/corim"
+)
+
+// duplicateMeasurements returns one error for each measurement that appears
+// more than once, with identical key and value, in the reference or endorsed
+// values triples of the same environment of a CoMID tag of c.  Duplicates are
+// reported in the order of their first occurrence.
+func duplicateMeasurements(c corim.UnsignedCorim) ([]error, error) {
+	var dups []error
+
+	for i, t := range c.Tags {
+		if len(t) < 4 || !bytes.Equal(t[:3], corim.ComidTag) {
+			continue
+		}
+
+		cm, err := corim.UnmarshalComidFromCBOR(t[3:], c.Profile)
+		if err != nil {
+			return nil, fmt.Errorf("tag [%d] (CoMID): decoding failed: %w", i, err)
+		}
+
+		ims, err := indexMeasurements(cm)
+		if err != nil {
+			return nil, fmt.Errorf("tag [%d] (CoMID): %w", i, err)
+		}
+
+		var (
+			order []string
+			count = make(map[string]int)
+			first = make(map[string]indexedMeasurement)
+		)
+
+		for _, im := range ims {
+			id := fmt.Sprintf("%s|%s|%s|%s", im.triple, im.environment, im.key, im.value)
+
+			if count[id] == 0 {
+				order = append(order, id)
+				first[id] = im
+			}
+			count[id]++
+		}
+
+		for _, id := range order {
+			if count[id] < 2 {
+				continue
+			}
+
+			im := first[id]
+			dups = append(dups, fmt.Errorf(
+				"tag [%d] (CoMID): %s: environment %s, key %s: measurement repeated %d times",
+				i, im.triple, im.environment, orDash(string(im.key)), count[id],
+			))
+		}
+	}
+
+	return dups, nil
+}
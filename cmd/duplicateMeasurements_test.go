@@ -0,0 +1,95 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/corim/corim"
+)
+
+// newTestCorimWithDuplicates returns an unsigned CoRIM whose only CoMID
+// repeats the first measurement of its first reference values triple
+func newTestCorimWithDuplicates(t *testing.T) *corim.UnsignedCorim {
+	var c comid.Comid
+	require.NoError(t, c.FromJSON([]byte(comid.PSARefValJSONTemplate)))
+
+	ms := &c.Triples.ReferenceValues.Values[0].Measurements
+	ms.Values = append(ms.Values, ms.Values[0], ms.Values[0])
+
+	u := corim.NewUnsignedCorim().SetID("dups").AddComid(&c)
+	require.NotNil(t, u)
+
+	return u
+}
+
+func Test_duplicateMeasurements(t *testing.T) {
+	dups, err := duplicateMeasurements(*newTestCorimWithDuplicates(t))
+	require.NoError(t, err)
+	require.Len(t, dups, 1)
+
+	assert.Contains(t, dups[0].Error(), "tag [0] (CoMID): reference-values: environment ")
+	assert.Contains(t, dups[0].Error(), `"label":"BL"`)
+	assert.Contains(t, dups[0].Error(), "measurement repeated 3 times")
+}
+
+func Test_duplicateMeasurements_none(t *testing.T) {
+	var u corim.UnsignedCorim
+	require.NoError(t, u.FromCBOR(testCorimValid))
+
+	dups, err := duplicateMeasurements(u)
+	require.NoError(t, err)
+	assert.Empty(t, dups)
+}
+
+func setupDuplicatesSign(t *testing.T) {
+	data, err := newTestCorimWithDuplicates(t).ToCBOR()
+	require.NoError(t, err)
+
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "dups.cbor", data, 0644))
+	require.NoError(t, afero.WriteFile(fs, "meta.json", testMetaValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "key.jwk", testECKey, 0644))
+}
+
+func Test_CorimSignCmd_check_duplicate_measurements_warns(t *testing.T) {
+	setupDuplicatesSign(t)
+
+	cmd := NewCorimSignCmd()
+	cmd.SetArgs([]string{
+		"--file=dups.cbor",
+		"--meta=meta.json",
+		"--key=key.jwk",
+		"--output=signed.cbor",
+		"--check-duplicate-measurements",
+	})
+
+	require.NoError(t, cmd.Execute())
+
+	_, err := fs.Stat("signed.cbor")
+	assert.NoError(t, err)
+}
+
+func Test_CorimSignCmd_fail_on_duplicate_measurements(t *testing.T) {
+	setupDuplicatesSign(t)
+
+	cmd := NewCorimSignCmd()
+	cmd.SetArgs([]string{
+		"--file=dups.cbor",
+		"--meta=meta.json",
+		"--key=key.jwk",
+		"--output=signed.cbor",
+		"--fail-on-duplicate-measurements",
+	})
+
+	err := cmd.Execute()
+	assert.ErrorContains(t, err, "duplicate measurements in dups.cbor: tag [0] (CoMID): reference-values")
+
+	_, err = fs.Stat("signed.cbor")
+	assert.Error(t, err)
+}
@@ -0,0 +1,120 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+
+	"github.com/fxamacker/cbor/v2"
+	cose "github.com/veraison/go-cose"
+)
+
+// headerLabelCOSEKey is the (private use) COSE header label under which
+// "corim sign --embed-public-key" places the COSE_Key of the signing key
+const headerLabelCOSEKey int64 = -65537
+
+// embeddedPublicKey returns pk, the public part of the signing key, as a
+// COSE_Key
+func embeddedPublicKey(pk crypto.PublicKey) (*cose.Key, error) {
+	switch k := pk.(type) {
+	case *ecdsa.PublicKey:
+		var alg cose.Algorithm
+
+		switch k.Curve.Params().Name {
+		case "P-256":
+			alg = cose.AlgorithmES256
+		case "P-384":
+			alg = cose.AlgorithmES384
+		case "P-521":
+			alg = cose.AlgorithmES512
+		default:
+			return nil, fmt.Errorf("unsupported curve %s", k.Curve.Params().Name)
+		}
+
+		// the coordinates must be padded to the size of the curve
+		size := (k.Curve.Params().BitSize + 7) / 8
+
+		return cose.NewKeyEC2(alg, k.X.FillBytes(make([]byte, size)), k.Y.FillBytes(make([]byte, size)), nil)
+	case ed25519.PublicKey:
+		return cose.NewKeyOKP(cose.AlgorithmEdDSA, k, nil)
+	default:
+		return nil, fmt.Errorf("unsupported key type %T (only EC and Ed25519 keys can be embedded)", pk)
+	}
+}
+
+// coseEmbeddedKey returns the public key carried in the unprotected header of
+// msg under headerLabelCOSEKey, or nil if there is none
+func coseEmbeddedKey(msg *cose.Sign1Message) (crypto.PublicKey, error) {
+	v, ok := msg.Headers.Unprotected[headerLabelCOSEKey]
+	if !ok {
+		return nil, nil
+	}
+
+	// header values are decoded generically, so the COSE_Key is re-encoded
+	// before being decoded as such
+	data, err := cbor.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("embedded public key: %w", err)
+	}
+
+	var k cose.Key
+	if err = k.UnmarshalCBOR(data); err != nil {
+		return nil, fmt.Errorf("embedded public key: %w", err)
+	}
+
+	pk, err := k.PublicKey()
+	if err != nil {
+		return nil, fmt.Errorf("embedded public key: %w", err)
+	}
+
+	return pk, nil
+}
+
+// embeddedKeyThumbprint returns the JWK thumbprint of the public key embedded
+// in msg, or an empty string if there is none
+func embeddedKeyThumbprint(msg *cose.Sign1Message) (string, error) {
+	pk, err := coseEmbeddedKey(msg)
+	if err != nil || pk == nil {
+		return "", err
+	}
+
+	tp, err := jwkThumbprint(pk)
+	if err != nil {
+		return "", fmt.Errorf("embedded public key: %w", err)
+	}
+
+	return tp, nil
+}
+
+// verifyWithEmbeddedKey verifies msg using the public key it embeds.  The
+// embedded key is not authenticated, hence a warning is printed which carries
+// its thumbprint, so that it can be compared against a known value (trust on
+// first use).  The warning goes to stderr if quiet.
+func verifyWithEmbeddedKey(msg *cose.Sign1Message, quiet bool) (crypto.PublicKey, error) {
+	pk, err := coseEmbeddedKey(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	if pk == nil {
+		return nil, errors.New("no embedded public key found")
+	}
+
+	tp, err := jwkThumbprint(pk)
+	if err != nil {
+		return nil, fmt.Errorf("embedded public key: %w", err)
+	}
+
+	warnf(quiet, ">> warning: using the unauthenticated public key embedded in the COSE header (JWK thumbprint %s)\n", tp)
+
+	if err = verifySign1(msg, pk); err != nil {
+		return nil, err
+	}
+
+	return pk, nil
+}
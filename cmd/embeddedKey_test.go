@@ -0,0 +1,112 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/corim"
+)
+
+// signTestCorimWithEmbeddedKey signs testCorimValid with keyJWK into
+// signed.cbor, embedding its public key
+func signTestCorimWithEmbeddedKey(t *testing.T, keyJWK []byte) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "unsigned.cbor", testCorimValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "meta.json", testMetaValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "key.jwk", keyJWK, 0644))
+
+	cmd := NewCorimSignCmd()
+	cmd.SetArgs([]string{
+		"--file=unsigned.cbor",
+		"--meta=meta.json",
+		"--key=key.jwk",
+		"--output=signed.cbor",
+		"--embed-public-key",
+	})
+	require.NoError(t, cmd.Execute())
+}
+
+func Test_CorimSignCmd_embed_public_key(t *testing.T) {
+	tvs := []struct {
+		desc       string
+		key        []byte
+		thumbprint string
+	}{
+		{"EC", testECKey, testECKeyThumbprint},
+		{"Ed25519", testEdDSAKey, testEdDSAKeyThumbprint},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.desc, func(t *testing.T) {
+			signTestCorimWithEmbeddedKey(t, tv.key)
+
+			data, err := afero.ReadFile(fs, "signed.cbor")
+			require.NoError(t, err)
+
+			msg, err := decodeSign1(data)
+			require.NoError(t, err)
+
+			tp, err := embeddedKeyThumbprint(msg)
+			require.NoError(t, err)
+			assert.Equal(t, tv.thumbprint, tp)
+
+			cmd := NewCorimVerifyCmd()
+			cmd.SetArgs([]string{"--file=signed.cbor", "--use-embedded-key"})
+			assert.NoError(t, cmd.Execute())
+
+			cmd = NewCorimDisplayCmd()
+			cmd.SetArgs([]string{"--file=signed.cbor", "--output=signed.txt"})
+			require.NoError(t, cmd.Execute())
+
+			out, err := afero.ReadFile(fs, "signed.txt")
+			require.NoError(t, err)
+			assert.Contains(t, string(out), "Embedded public key (JWK thumbprint): "+tv.thumbprint)
+		})
+	}
+}
+
+func Test_CorimVerifyCmd_use_embedded_key_wrong_key(t *testing.T) {
+	signTestCorimWithEmbeddedKey(t, testECKey)
+
+	data, err := afero.ReadFile(fs, "signed.cbor")
+	require.NoError(t, err)
+
+	// the unprotected header is not covered by the signature, so the embedded
+	// key can be swapped without invalidating the COSE Sign1 structure
+	msg, err := decodeSign1(data)
+	require.NoError(t, err)
+
+	pk, err := corim.NewPublicKeyFromJWK(testEdDSAKey)
+	require.NoError(t, err)
+
+	msg.Headers.Unprotected[headerLabelCOSEKey], err = embeddedPublicKey(pk)
+	require.NoError(t, err)
+	msg.Headers.RawUnprotected = nil
+
+	data, err = msg.MarshalCBOR()
+	require.NoError(t, err)
+	require.NoError(t, afero.WriteFile(fs, "signed.cbor", data, 0644))
+
+	cmd := NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=signed.cbor", "--use-embedded-key"})
+	assert.ErrorContains(t, cmd.Execute(), "error verifying signed.cbor with embedded key: ")
+}
+
+func Test_CorimVerifyCmd_use_embedded_key_none(t *testing.T) {
+	signTestCorimWithKeyID(t, "acme-key-1")
+
+	cmd := NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=signed.cbor", "--use-embedded-key"})
+	assert.EqualError(t, cmd.Execute(), "error verifying signed.cbor with embedded key: no embedded public key found")
+}
+
+func Test_CorimVerifyCmd_use_embedded_key_with_key(t *testing.T) {
+	cmd := NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=signed.cbor", "--key=key.jwk", "--use-embedded-key"})
+	assert.EqualError(t, cmd.Execute(), "--use-embedded-key cannot be used together with --key, --trust-anchor or --system-roots")
+}
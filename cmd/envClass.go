@@ -0,0 +1,144 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/veraison/corim/comid"
+)
+
+// canonicalUUIDLength is the length of a UUID in its canonical (8-4-4-4-12
+// hex digits) textual form
+const canonicalUUIDLength = 36
+
+// envClass collects the flags describing the class of the environment of the
+// CoMIDs built from the command line (rather than from templates): its class
+// id, either guessed from its format (--env-class-id) or of an explicit type
+// (--class-id-oid, --class-id-uuid, --class-id-int), and, optionally, its
+// vendor and model
+type envClass struct {
+	classID string
+	oid     string
+	uuid    string
+	intID   string
+	vendor  string
+	model   string
+}
+
+// addEnvClassFlags registers the environment class switches of cmd, storing
+// their values in c.  The modes of cmd which use them are listed in modes.
+func addEnvClassFlags(cmd *cobra.Command, c *envClass, modes string) {
+	cmd.Flags().StringVar(
+		&c.classID, "env-class-id", "", "class id (UUID, OID or base64 implementation id) of the measured environment ("+modes+")",
+	)
+
+	cmd.Flags().StringVar(
+		&c.oid, "class-id-oid", "", "class id of the measured environment, as a dotted-decimal OID ("+modes+")",
+	)
+
+	cmd.Flags().StringVar(
+		&c.uuid, "class-id-uuid", "", "class id of the measured environment, as a UUID ("+modes+")",
+	)
+
+	cmd.Flags().StringVar(
+		&c.intID, "class-id-int", "", "class id of the measured environment, as an integer ("+modes+")",
+	)
+
+	cmd.Flags().StringVar(
+		&c.vendor, "vendor", "", "vendor of the measured environment ("+modes+")",
+	)
+
+	cmd.Flags().StringVar(
+		&c.model, "model", "", "model of the measured environment ("+modes+")",
+	)
+}
+
+// hasTyped tells whether any switch other than --env-class-id was supplied
+func (o envClass) hasTyped() bool {
+	return o.oid != "" || o.uuid != "" || o.intID != "" || o.vendor != "" || o.model != ""
+}
+
+// valid checks that at most one class id was supplied, and that the typed
+// ones are well-formed.  An --env-class-id is only interpreted by class().
+func (o envClass) valid() error {
+	n := 0
+	for _, s := range []string{o.classID, o.oid, o.uuid, o.intID} {
+		if s != "" {
+			n++
+		}
+	}
+
+	if n > 1 {
+		return errors.New("only one of --env-class-id, --class-id-oid, --class-id-uuid and --class-id-int can be supplied")
+	}
+
+	if n == 0 {
+		return errors.New("no environment class id supplied")
+	}
+
+	_, err := o.typedClassID()
+
+	return err
+}
+
+// typedClassID returns the class id supplied with --class-id-oid,
+// --class-id-uuid or --class-id-int, or nil if none was
+func (o envClass) typedClassID() (*comid.ClassID, error) {
+	switch {
+	case o.oid != "":
+		classID, err := comid.NewOIDClassID(o.oid)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --class-id-oid %q: expecting a dotted-decimal OID (e.g., 1.2.3.4)", o.oid)
+		}
+
+		return classID, nil
+	case o.uuid != "":
+		// the UUID parser also accepts the URN and the braced forms: only
+		// the canonical one is wanted here
+		classID, err := comid.NewUUIDClassID(o.uuid)
+		if err != nil || len(o.uuid) != canonicalUUIDLength {
+			return nil, fmt.Errorf("invalid --class-id-uuid %q: expecting a UUID (e.g., 31fb5abf-023e-4992-aa4e-95f9c1503bfa)", o.uuid)
+		}
+
+		return classID, nil
+	case o.intID != "":
+		classID, err := comid.NewIntClassID(o.intID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --class-id-int %q: expecting an integer", o.intID)
+		}
+
+		return classID, nil
+	}
+
+	return nil, nil
+}
+
+// class returns the environment class described by the switches
+func (o envClass) class() (*comid.Class, error) {
+	classID, err := o.typedClassID()
+	if err != nil {
+		return nil, err
+	}
+
+	if classID == nil {
+		if classID, err = parseClassID(o.classID); err != nil {
+			return nil, err
+		}
+	}
+
+	c := &comid.Class{ClassID: classID}
+
+	if o.vendor != "" {
+		c.SetVendor(o.vendor)
+	}
+
+	if o.model != "" {
+		c.SetModel(o.model)
+	}
+
+	return c, nil
+}
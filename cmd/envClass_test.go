@@ -0,0 +1,182 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/comid"
+)
+
+func Test_envClass_class(t *testing.T) {
+	tvs := []struct {
+		ec           envClass
+		expectedType string
+		expectedID   string
+	}{
+		{envClass{classID: "1.2.3.4"}, comid.OIDType, "1.2.3.4"},
+		{envClass{oid: "2.16.840.1.113741"}, comid.OIDType, "2.16.840.1.113741"},
+		{envClass{uuid: "31fb5abf-023e-4992-aa4e-95f9c1503bfa"}, comid.UUIDType, "31fb5abf-023e-4992-aa4e-95f9c1503bfa"},
+		{envClass{intID: "42"}, comid.IntType, "42"},
+	}
+
+	for _, tv := range tvs {
+		require.NoError(t, tv.ec.valid())
+
+		class, err := tv.ec.class()
+		require.NoError(t, err)
+		assert.Equal(t, tv.expectedType, class.ClassID.Type())
+		assert.Equal(t, tv.expectedID, class.ClassID.String())
+		assert.Nil(t, class.Vendor)
+		assert.Nil(t, class.Model)
+	}
+
+	class, err := envClass{intID: "7", vendor: "ACME Inc.", model: "RoadRunner"}.class()
+	require.NoError(t, err)
+	assert.Equal(t, "ACME Inc.", *class.Vendor)
+	assert.Equal(t, "RoadRunner", *class.Model)
+}
+
+func Test_envClass_valid(t *testing.T) {
+	tvs := []struct {
+		ec       envClass
+		expected string
+	}{
+		{
+			envClass{vendor: "ACME Inc."},
+			"no environment class id supplied",
+		},
+		{
+			envClass{classID: "1.2.3.4", intID: "1"},
+			"only one of --env-class-id, --class-id-oid, --class-id-uuid and --class-id-int can be supplied",
+		},
+		{
+			envClass{oid: "1.2"},
+			`invalid --class-id-oid "1.2": expecting a dotted-decimal OID (e.g., 1.2.3.4)`,
+		},
+		{
+			envClass{oid: "31fb5abf-023e-4992-aa4e-95f9c1503bfa"},
+			`invalid --class-id-oid "31fb5abf-023e-4992-aa4e-95f9c1503bfa": expecting a dotted-decimal OID (e.g., 1.2.3.4)`,
+		},
+		{
+			envClass{uuid: "1.2.3.4"},
+			`invalid --class-id-uuid "1.2.3.4": expecting a UUID (e.g., 31fb5abf-023e-4992-aa4e-95f9c1503bfa)`,
+		},
+		{
+			envClass{uuid: "urn:uuid:31fb5abf-023e-4992-aa4e-95f9c1503bfa"},
+			`invalid --class-id-uuid "urn:uuid:31fb5abf-023e-4992-aa4e-95f9c1503bfa": expecting a UUID (e.g., 31fb5abf-023e-4992-aa4e-95f9c1503bfa)`,
+		},
+		{
+			envClass{intID: "forty-two"},
+			`invalid --class-id-int "forty-two": expecting an integer`,
+		},
+	}
+
+	for _, tv := range tvs {
+		assert.EqualError(t, tv.ec.valid(), tv.expected)
+	}
+}
+
+func Test_ComidCreateCmd_class_id_uuid(t *testing.T) {
+	fs = afero.NewMemMapFs()
+
+	cmd := NewComidCreateCmd()
+	cmd.SetArgs([]string{
+		"--svn=3",
+		"--class-id-uuid=31fb5abf-023e-4992-aa4e-95f9c1503bfa",
+		"--vendor=ACME Inc.",
+		"--model=RoadRunner",
+		"--output=comid.cbor",
+	})
+	require.NoError(t, cmd.Execute())
+
+	data, err := afero.ReadFile(fs, "comid.cbor")
+	require.NoError(t, err)
+
+	var c comid.Comid
+	require.NoError(t, c.FromCBOR(data))
+	require.NotNil(t, c.Triples.ReferenceValues)
+
+	class := c.Triples.ReferenceValues.Values[0].Environment.Class
+	assert.Equal(t, comid.UUIDType, class.ClassID.Type())
+	assert.Equal(t, "31fb5abf-023e-4992-aa4e-95f9c1503bfa", class.ClassID.String())
+	assert.Equal(t, "ACME Inc.", *class.Vendor)
+	assert.Equal(t, "RoadRunner", *class.Model)
+}
+
+func Test_ComidCreateCmd_bulk_vendor(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "measurements.csv", []byte(testBulkCSV), 0644))
+
+	cmd := NewComidCreateCmd()
+	cmd.SetArgs([]string{"--bulk", "--csv=measurements.csv", "--class-id-int=42", "--vendor=ACME Inc.", "--output=comid.cbor"})
+	require.NoError(t, cmd.Execute())
+
+	data, err := afero.ReadFile(fs, "comid.cbor")
+	require.NoError(t, err)
+
+	var c comid.Comid
+	require.NoError(t, c.FromCBOR(data))
+	require.NotNil(t, c.Triples.ReferenceValues)
+
+	rvs := c.Triples.ReferenceValues.Values
+	require.Len(t, rvs, 2)
+	for i, model := range []string{"bl1", "fw"} {
+		class := rvs[i].Environment.Class
+		assert.Equal(t, "42", class.ClassID.String())
+		assert.Equal(t, "ACME Inc.", *class.Vendor)
+		assert.Equal(t, model, *class.Model)
+	}
+}
+
+func Test_ComidCreateCmd_env_class_bad_args(t *testing.T) {
+	tvs := []struct {
+		desc     string
+		args     []string
+		expected string
+	}{
+		{
+			desc:     "typed class id with templates",
+			args:     []string{"--template=t.json", "--class-id-oid=1.2.3.4"},
+			expected: "--class-id-oid, --class-id-uuid, --class-id-int, --vendor and --model can only be used together with --bulk, --merge-measurements, --svn or --min-svn",
+		},
+		{
+			desc:     "vendor with templates",
+			args:     []string{"--template=t.json", "--vendor=ACME Inc."},
+			expected: "--class-id-oid, --class-id-uuid, --class-id-int, --vendor and --model can only be used together with --bulk, --merge-measurements, --svn or --min-svn",
+		},
+		{
+			desc:     "model with bulk",
+			args:     []string{"--bulk", "--csv=m.csv", "--env-class-id=1.2.3.4", "--model=RoadRunner"},
+			expected: "--model cannot be used together with --bulk, where the component names are used as models",
+		},
+		{
+			desc:     "two class ids",
+			args:     []string{"--svn=1", "--env-class-id=1.2.3.4", "--class-id-uuid=31fb5abf-023e-4992-aa4e-95f9c1503bfa", "--output=comid.cbor"},
+			expected: "only one of --env-class-id, --class-id-oid, --class-id-uuid and --class-id-int can be supplied",
+		},
+		{
+			desc:     "bad uuid",
+			args:     []string{"--merge-measurements", "--class-id-uuid=acme", "--output=comid.cbor", "m.json"},
+			expected: `invalid --class-id-uuid "acme": expecting a UUID (e.g., 31fb5abf-023e-4992-aa4e-95f9c1503bfa)`,
+		},
+		{
+			desc:     "vendor without class id",
+			args:     []string{"--svn=1", "--vendor=ACME Inc.", "--output=comid.cbor"},
+			expected: "no environment class id supplied",
+		},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.desc, func(t *testing.T) {
+			cmd := NewComidCreateCmd()
+			cmd.SetArgs(tv.args)
+
+			assert.EqualError(t, cmd.Execute(), tv.expected)
+		})
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// envReference matches ${NAME} references to environment variables.  The bare
+// $NAME form is deliberately not supported, as it clashes with JSON members
+// such as "$schema".
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// envExpansion controls the substitution of environment variables in JSON
+// templates
+type envExpansion struct {
+	enabled      bool
+	allowMissing bool
+}
+
+// addEnvExpansionFlags registers the --expand-env and --allow-missing-env
+// switches of cmd, storing their values in e
+func addEnvExpansionFlags(cmd *cobra.Command, e *envExpansion) {
+	cmd.Flags().BoolVar(
+		&e.enabled, "expand-env", false, "substitute ${VAR} references in templates with the value of environment variable VAR",
+	)
+
+	cmd.Flags().BoolVar(
+		&e.allowMissing, "allow-missing-env", false, "substitute unset environment variables with the empty string (with --expand-env)",
+	)
+}
+
+func (e envExpansion) valid() error {
+	if e.allowMissing && !e.enabled {
+		return errors.New("--allow-missing-env can only be used together with --expand-env")
+	}
+
+	return nil
+}
+
+// expand substitutes the ${NAME} references in data with the value of the
+// corresponding environment variables.  Values are inserted verbatim, i.e.,
+// they are not JSON-escaped.  Unless allowMissing is set, it is an error if any
+// of the referenced variables is unset.
+func (e envExpansion) expand(data []byte) ([]byte, error) {
+	if !e.enabled {
+		return data, nil
+	}
+
+	var (
+		missing []string
+		seen    = map[string]bool{}
+	)
+
+	expanded := envReference.ReplaceAllFunc(data, func(ref []byte) []byte {
+		name := string(envReference.FindSubmatch(ref)[1])
+
+		value, ok := os.LookupEnv(name)
+		if !ok && !e.allowMissing && !seen[name] {
+			missing = append(missing, name)
+			seen[name] = true
+		}
+
+		return []byte(value)
+	})
+
+	if len(missing) != 0 {
+		return nil, fmt.Errorf("unset environment variable(s) referenced in template: %s", strings.Join(missing, ", "))
+	}
+
+	return expanded, nil
+}
@@ -0,0 +1,132 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/comid"
+)
+
+func Test_envExpansion_expand(t *testing.T) {
+	t.Setenv("COCLI_TEST_BUILD_ID", "build-42")
+	t.Setenv("COCLI_TEST_EMPTY", "")
+
+	e := envExpansion{enabled: true}
+
+	out, err := e.expand([]byte(`{"$schema": "x", "id": "${COCLI_TEST_BUILD_ID}", "e": "${COCLI_TEST_EMPTY}", "v": "$COCLI_TEST_BUILD_ID"}`))
+	require.NoError(t, err)
+	assert.Equal(t, `{"$schema": "x", "id": "build-42", "e": "", "v": "$COCLI_TEST_BUILD_ID"}`, string(out))
+
+	_, err = e.expand([]byte(`["${COCLI_TEST_UNSET_B}", "${COCLI_TEST_UNSET_A}", "${COCLI_TEST_UNSET_B}"]`))
+	assert.EqualError(t, err, "unset environment variable(s) referenced in template: COCLI_TEST_UNSET_B, COCLI_TEST_UNSET_A")
+
+	e.allowMissing = true
+
+	out, err = e.expand([]byte(`["${COCLI_TEST_UNSET_A}"]`))
+	require.NoError(t, err)
+	assert.Equal(t, `[""]`, string(out))
+
+	// disabled expansion leaves the template untouched
+	out, err = envExpansion{}.expand([]byte(`["${COCLI_TEST_BUILD_ID}"]`))
+	require.NoError(t, err)
+	assert.Equal(t, `["${COCLI_TEST_BUILD_ID}"]`, string(out))
+}
+
+func Test_readJSONTemplate_expansion_exceeds_size(t *testing.T) {
+	t.Setenv("COCLI_TEST_BIG", strings.Repeat("x", 100))
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "t.json", []byte(`["${COCLI_TEST_BIG}"]`), 0644)
+	require.NoError(t, err)
+
+	_, err = readJSONTemplate("t.json", jsonLimits{maxSize: 64}, envExpansion{enabled: true})
+	assert.EqualError(t, err, "template exceeds the maximum size of 64 bytes (see --max-json-size)")
+}
+
+func Test_ComidCreateCmd_expand_env(t *testing.T) {
+	var err error
+
+	t.Setenv("COCLI_TEST_MODEL", "WileE")
+
+	tmpl := strings.Replace(comid.PSARefValJSONTemplate, `"RoadRunner"`, `"${COCLI_TEST_MODEL}"`, 1)
+
+	fs = afero.NewMemMapFs()
+	err = afero.WriteFile(fs, "env.json", []byte(tmpl), 0644)
+	require.NoError(t, err)
+
+	// without --expand-env the reference is kept as is
+	cmd := NewComidCreateCmd()
+	cmd.SetArgs([]string{"--template=env.json"})
+	require.NoError(t, cmd.Execute())
+
+	data, err := afero.ReadFile(fs, "env.cbor")
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "${COCLI_TEST_MODEL}")
+
+	cmd = NewComidCreateCmd()
+	cmd.SetArgs([]string{"--template=env.json", "--expand-env"})
+	require.NoError(t, cmd.Execute())
+
+	data, err = afero.ReadFile(fs, "env.cbor")
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "WileE")
+	assert.NotContains(t, string(data), "${COCLI_TEST_MODEL}")
+}
+
+func Test_ComidCreateCmd_expand_env_unset(t *testing.T) {
+	var err error
+
+	tmpl := strings.Replace(comid.PSARefValJSONTemplate, `"RoadRunner"`, `"${COCLI_TEST_UNSET}"`, 1)
+
+	fs = afero.NewMemMapFs()
+	err = afero.WriteFile(fs, "env.json", []byte(tmpl), 0644)
+	require.NoError(t, err)
+
+	_, err = templateToCBOR("env.json", ".", false, jsonLimits{}, envExpansion{enabled: true}, nil, psaIDs{})
+	assert.EqualError(t, err, "error loading template from env.json: unset environment variable(s) referenced in template: COCLI_TEST_UNSET")
+
+	cmd := NewComidCreateCmd()
+	cmd.SetArgs([]string{"--template=env.json", "--expand-env", "--allow-missing-env"})
+	assert.NoError(t, cmd.Execute())
+}
+
+func Test_ComidCreateCmd_allow_missing_env_without_expand_env(t *testing.T) {
+	cmd := NewComidCreateCmd()
+
+	args := []string{
+		"--template=ok.json",
+		"--allow-missing-env",
+	}
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "--allow-missing-env can only be used together with --expand-env")
+}
+
+func Test_CorimCreateCmd_expand_env_unset(t *testing.T) {
+	var err error
+
+	fs = afero.NewMemMapFs()
+	err = afero.WriteFile(fs, "corim.json", []byte(`{"corim-id": "${COCLI_TEST_UNSET}"}`), 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "comid.cbor", testComid, 0644)
+	require.NoError(t, err)
+
+	cmd := NewCorimCreateCmd()
+
+	args := []string{
+		"--template=corim.json",
+		"--comid=comid.cbor",
+		"--expand-env",
+	}
+	cmd.SetArgs(args)
+
+	err = cmd.Execute()
+	assert.EqualError(t, err, "error loading template from corim.json: unset environment variable(s) referenced in template: COCLI_TEST_UNSET")
+}
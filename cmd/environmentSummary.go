@@ -0,0 +1,107 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/veraison/corim/comid"
+)
+
+// environmentSummaryView is the number of measurements and keys that the
+// triples of a CoMID carry for one of its environments, used by "comid display
+// --environment-summary"
+type environmentSummaryView struct {
+	Environment      json.RawMessage `json:"environment"`
+	ReferenceValues  int             `json:"reference-values"`
+	EndorsedValues   int             `json:"endorsed-values"`
+	VerificationKeys int             `json:"attester-verification-keys"`
+	IdentityKeys     int             `json:"dev-identity-keys"`
+}
+
+// environmentSummary groups the content of the triples of c by environment.
+// Environments are listed in the order in which they first appear (reference
+// values first, then endorsed values, attester verification keys and device
+// identity keys), and are told apart by their JSON rendering.
+func environmentSummary(c *comid.Comid) ([]environmentSummaryView, error) {
+	var (
+		views []environmentSummaryView
+		index = map[string]int{}
+	)
+
+	view := func(env comid.Environment, what string, i int) (*environmentSummaryView, error) {
+		j, err := json.Marshal(env)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding environment of %s triple %d: %w", what, i, err)
+		}
+
+		n, ok := index[string(j)]
+		if !ok {
+			n = len(views)
+			index[string(j)] = n
+			views = append(views, environmentSummaryView{Environment: j})
+		}
+
+		return &views[n], nil
+	}
+
+	if vts := c.Triples.ReferenceValues; vts != nil {
+		for i, vt := range vts.Values {
+			v, err := view(vt.Environment, "reference-values", i)
+			if err != nil {
+				return nil, err
+			}
+			v.ReferenceValues += len(vt.Measurements.Values)
+		}
+	}
+
+	if vts := c.Triples.EndorsedValues; vts != nil {
+		for i, vt := range vts.Values {
+			v, err := view(vt.Environment, "endorsed-values", i)
+			if err != nil {
+				return nil, err
+			}
+			v.EndorsedValues += len(vt.Measurements.Values)
+		}
+	}
+
+	if kts := c.Triples.AttestVerifKeys; kts != nil {
+		for i, kt := range *kts {
+			v, err := view(kt.Environment, "attester-verification-keys", i)
+			if err != nil {
+				return nil, err
+			}
+			v.VerificationKeys += len(kt.VerifKeys)
+		}
+	}
+
+	if kts := c.Triples.DevIdentityKeys; kts != nil {
+		for i, kt := range *kts {
+			v, err := view(kt.Environment, "dev-identity-keys", i)
+			if err != nil {
+				return nil, err
+			}
+			v.IdentityKeys += len(kt.VerifKeys)
+		}
+	}
+
+	return views, nil
+}
+
+// printEnvironmentSummary prints views as a table, one environment per row
+func printEnvironmentSummary(w io.Writer, views []environmentSummaryView) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "REF-VALUES\tENDORSED-VALUES\tVERIF-KEYS\tIDENTITY-KEYS\tENVIRONMENT")
+
+	for _, v := range views {
+		fmt.Fprintf(tw, "%d\t%d\t%d\t%d\t%s\n",
+			v.ReferenceValues, v.EndorsedValues, v.VerificationKeys, v.IdentityKeys, v.Environment)
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,79 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/comid"
+)
+
+// newTestMultiEnvComid returns a CoMID with the reference values of the PSA
+// template, split over two triples for the same environment, and the attester
+// verification keys of two other environments
+func newTestMultiEnvComid(t *testing.T) *comid.Comid {
+	var c comid.Comid
+	require.NoError(t, c.FromJSON([]byte(comid.PSAKeysJSONTemplate)))
+
+	rv := newTestComid(t).Triples.ReferenceValues
+	vt := rv.Values[0]
+
+	first, second := vt, vt
+	first.Measurements.Values = vt.Measurements.Values[:2]
+	second.Measurements.Values = vt.Measurements.Values[2:]
+	rv.Values = []comid.ValueTriple{first, second}
+
+	c.Triples.ReferenceValues = rv
+
+	return &c
+}
+
+func Test_environmentSummary(t *testing.T) {
+	views, err := environmentSummary(newTestMultiEnvComid(t))
+	require.NoError(t, err)
+	require.Len(t, views, 3)
+
+	assert.Equal(t, 3, views[0].ReferenceValues)
+	assert.Zero(t, views[0].VerificationKeys)
+	assert.NotContains(t, string(views[0].Environment), "instance")
+
+	for _, v := range views[1:] {
+		assert.Zero(t, v.ReferenceValues)
+		assert.Equal(t, 1, v.VerificationKeys)
+		assert.Contains(t, string(v.Environment), "instance")
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, printEnvironmentSummary(&buf, views))
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 4)
+	assert.Regexp(t, `^REF-VALUES +ENDORSED-VALUES +VERIF-KEYS +IDENTITY-KEYS +ENVIRONMENT$`, string(lines[0]))
+	assert.Regexp(t, `^3 +0 +0 +0 +\{"class":`, string(lines[1]))
+}
+
+func Test_ComidDisplayCmd_environment_summary(t *testing.T) {
+	data, err := newTestMultiEnvComid(t).ToCBOR()
+	require.NoError(t, err)
+
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "c.cbor", data, 0644))
+
+	for _, args := range [][]string{
+		{"--file=c.cbor", "--environment-summary"},
+		{"--file=c.cbor", "--environment-summary", "--json"},
+	} {
+		cmd := NewComidDisplayCmd()
+		cmd.SetArgs(args)
+		assert.NoError(t, cmd.Execute())
+	}
+
+	cmd := NewComidDisplayCmd()
+	cmd.SetArgs([]string{"--file=c.cbor", "--environment-summary", "--links"})
+	assert.EqualError(t, cmd.Execute(), "--environment-summary cannot be used together with --verification-keys, --raw-values or --links")
+}
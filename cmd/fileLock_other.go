@@ -0,0 +1,18 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package cmd
+
+import "os"
+
+// lockFile is a no-op on platforms without flock(2): appends are then only
+// as safe as the O_APPEND semantics of the platform
+func lockFile(f *os.File) error {
+	return nil
+}
+
+func unlockFile(f *os.File) error {
+	return nil
+}
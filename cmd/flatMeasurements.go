@@ -0,0 +1,155 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/afero"
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/corim/corim"
+)
+
+// flatMeasurement is one row of the flat measurements view of a CoRIM: a
+// digest of a measurement found in one of its CoMID tags
+type flatMeasurement struct {
+	TagIndex    int             `json:"tag-index"`
+	Triple      string          `json:"triple"`
+	Environment json.RawMessage `json:"environment"`
+	Key         json.RawMessage `json:"key,omitempty"`
+	Alg         string          `json:"alg,omitempty"`
+	Digest      string          `json:"digest,omitempty"`
+}
+
+// loadCorimTags returns the tags of the (signed or unsigned) CoRIM in file
+func loadCorimTags(file string, strict bool) ([]corim.Tag, error) {
+	data, err := afero.ReadFile(fs, file)
+	if err != nil {
+		return nil, fmt.Errorf("error loading CoRIM from %s: %w", file, err)
+	}
+
+	var s corim.SignedCorim
+	if err = s.FromCOSE(data); err == nil {
+		if strict {
+			if err = checkUnknownSignedCorimFields(&s, data); err != nil {
+				return nil, fmt.Errorf("error decoding signed CoRIM from %s: %w", file, err)
+			}
+		}
+		return s.UnsignedCorim.Tags, nil
+	}
+
+	var u corim.UnsignedCorim
+	if err = decodeCBOR(&u, data, strict); err != nil {
+		return nil, fmt.Errorf("error decoding CoRIM (signed or unsigned) from %s: %w", file, err)
+	}
+
+	return u.Tags, nil
+}
+
+// flatMeasurements lists the digests of the reference and endorsed value
+// measurements of every CoMID tag.  Measurements without digests are listed
+// once, with empty algorithm and digest.  Tags other than CoMIDs are ignored,
+// while malformed CoMIDs are skipped and reported in the returned warnings.
+func flatMeasurements(tags []corim.Tag, strict bool) ([]flatMeasurement, []string) {
+	var (
+		rows     []flatMeasurement
+		warnings []string
+	)
+
+	for i, t := range tags {
+		if len(t) < 4 || !bytes.Equal(t[:3], corim.ComidTag) {
+			continue
+		}
+
+		var c comid.Comid
+		if err := decodeCBOR(&c, t[3:], strict); err != nil {
+			warnings = append(warnings, fmt.Sprintf("skipping malformed CoMID tag at index %d: %v", i, err))
+			continue
+		}
+
+		ims, err := indexMeasurements(&c)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("skipping malformed CoMID tag at index %d: %v", i, err))
+			continue
+		}
+
+		for _, im := range ims {
+			row := flatMeasurement{
+				TagIndex:    i,
+				Triple:      im.triple,
+				Environment: im.environment,
+				Key:         im.key,
+			}
+
+			if len(im.hashes) == 0 {
+				rows = append(rows, row)
+				continue
+			}
+
+			for _, h := range im.hashes {
+				row.Alg = h.AlgIDToString()
+				row.Digest = hex.EncodeToString(h.HashValue)
+				rows = append(rows, row)
+			}
+		}
+	}
+
+	return rows, warnings
+}
+
+// displayFlatMeasurements prints the measurement digests of the CoRIM in file,
+// one per line, or in JSON format if jf is not nil
+func displayFlatMeasurements(file string, strict bool, jf *jsonFormat) error {
+	tags, err := loadCorimTags(file, strict)
+	if err != nil {
+		return err
+	}
+
+	rows, warnings := flatMeasurements(tags, strict)
+
+	// warnings go to stderr, so as not to interfere with the rows
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, ">> %s\n", w)
+	}
+
+	if jf != nil {
+		if rows == nil {
+			rows = []flatMeasurement{}
+		}
+
+		j, err := jf.marshal(rows)
+		if err != nil {
+			return fmt.Errorf("error encoding measurements of %s: %w", file, err)
+		}
+
+		fmt.Println(string(j))
+
+		return nil
+	}
+
+	// one tab-separated row per digest: tag-index env measurement-key alg digest
+	for _, r := range rows {
+		fmt.Println(strings.Join([]string{
+			fmt.Sprint(r.TagIndex),
+			string(r.Environment),
+			orDash(string(r.Key)),
+			orDash(r.Alg),
+			orDash(r.Digest),
+		}, "\t"))
+	}
+
+	return nil
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
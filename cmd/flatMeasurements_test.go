@@ -0,0 +1,118 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/corim"
+)
+
+func Test_CorimDisplayCmd_flat_with_show_tags(t *testing.T) {
+	cmd := NewCorimDisplayCmd()
+
+	args := []string{
+		"--file=ok.cbor",
+		"--measurements-flat",
+		"--show-tags",
+	}
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "--measurements-flat cannot be used together with --show-tags")
+}
+
+func Test_CorimDisplayCmd_json_without_flat(t *testing.T) {
+	cmd := NewCorimDisplayCmd()
+
+	args := []string{
+		"--file=ok.cbor",
+		"--json",
+	}
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "--json can only be used together with --measurements-flat")
+}
+
+func Test_CorimDisplayCmd_flat_ok(t *testing.T) {
+	var err error
+
+	fs = afero.NewMemMapFs()
+	err = afero.WriteFile(fs, "ok.cbor", newTestSignedCorimWithComids(t, 2), 0644)
+	require.NoError(t, err)
+
+	for _, extra := range [][]string{{}, {"--json"}} {
+		cmd := NewCorimDisplayCmd()
+
+		args := append([]string{"--file=ok.cbor", "--measurements-flat"}, extra...)
+		cmd.SetArgs(args)
+
+		err = cmd.Execute()
+		assert.NoError(t, err)
+	}
+}
+
+func Test_flatMeasurements_ok(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "ok.cbor", newTestSignedCorimWithComids(t, 2), 0644)
+	require.NoError(t, err)
+
+	tags, err := loadCorimTags("ok.cbor", false)
+	require.NoError(t, err)
+	require.Len(t, tags, 2)
+
+	rows, warnings := flatMeasurements(tags, false)
+	assert.Empty(t, warnings)
+
+	// the PSA template has 3 measurements with one digest each
+	require.Len(t, rows, 6)
+
+	assert.Equal(t, 0, rows[0].TagIndex)
+	assert.Equal(t, 1, rows[3].TagIndex)
+	assert.Equal(t, "reference-values", rows[0].Triple)
+	assert.Equal(t, "sha-256", rows[0].Alg)
+	assert.Equal(t, "87428fc522803d31065e7bce3cf03fe475096631e5e07bbd7a0fde60c4cf25c7", rows[0].Digest)
+	assert.Contains(t, string(rows[0].Key), `"BL"`)
+	assert.Contains(t, string(rows[0].Environment), `"RoadRunner"`)
+
+	j, err := json.Marshal(rows[0])
+	require.NoError(t, err)
+	assert.Contains(t, string(j), `"tag-index":0`)
+}
+
+func Test_flatMeasurements_unsigned_corim(t *testing.T) {
+	u := corim.NewUnsignedCorim().SetID("test")
+	require.NotNil(t, u.AddComid(newTestComid(t)))
+
+	data, err := u.ToCBOR()
+	require.NoError(t, err)
+
+	fs = afero.NewMemMapFs()
+	err = afero.WriteFile(fs, "unsigned.cbor", data, 0644)
+	require.NoError(t, err)
+
+	tags, err := loadCorimTags("unsigned.cbor", false)
+	require.NoError(t, err)
+
+	rows, warnings := flatMeasurements(tags, false)
+	assert.Empty(t, warnings)
+	assert.Len(t, rows, 3)
+}
+
+func Test_flatMeasurements_skip_malformed_comid(t *testing.T) {
+	tags := []corim.Tag{
+		append(append([]byte{}, corim.ComidTag...), 0xa0),
+		append(append([]byte{}, corim.CoswidTag...), 0xa0),
+	}
+
+	rows, warnings := flatMeasurements(tags, false)
+	assert.Empty(t, rows)
+	require.Len(t, warnings, 1)
+	assert.Contains(t, warnings[0], "skipping malformed CoMID tag at index 0")
+}
@@ -0,0 +1,343 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/spf13/afero"
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/corim/corim"
+	"github.com/veraison/corim/cots"
+)
+
+// the values accepted by corim extract --format
+const (
+	flattenFormatCSV   = "csv"
+	flattenFormatJSONL = "jsonl"
+)
+
+// measurementRowDigest is the type of the rows that carry a digest
+const measurementRowDigest = "digest"
+
+// flattenCSVHeader is the header line of the CSV saved by corim extract
+// --flatten-measurements, in the order of the fields of measurementRow
+var flattenCSVHeader = []string{
+	"tag-index", "tag-id", "triple",
+	"class-id-type", "class-id", "vendor", "model", "layer", "index",
+	"instance-type", "instance", "group-type", "group",
+	"key-type", "key", "type", "algorithm", "value",
+}
+
+// measurementRow is one row of the dataset saved by corim extract
+// --flatten-measurements: a single digest, or a single other measured value,
+// of a measurement, together with its environment and the tag it comes from
+type measurementRow struct {
+	TagIndex     int     `json:"tag-index"`
+	TagID        string  `json:"tag-id"`
+	Triple       string  `json:"triple"`
+	ClassIDType  string  `json:"class-id-type"`
+	ClassID      string  `json:"class-id"`
+	Vendor       string  `json:"vendor"`
+	Model        string  `json:"model"`
+	Layer        *uint64 `json:"layer"`
+	Index        *uint64 `json:"index"`
+	InstanceType string  `json:"instance-type"`
+	Instance     string  `json:"instance"`
+	GroupType    string  `json:"group-type"`
+	Group        string  `json:"group"`
+	KeyType      string  `json:"key-type"`
+	Key          string  `json:"key"`
+	// "digest", or else the JSON name of the measured value (e.g., "svn")
+	Type string `json:"type"`
+	// only set for digests, using the IANA Named Information Hash Algorithm
+	// name (e.g., sha-256)
+	Algorithm string `json:"algorithm"`
+	// hex-encoded for digests, or else the JSON encoding of the measured value
+	// (unquoted if it is a string)
+	Value string `json:"value"`
+}
+
+func (r measurementRow) csvRecord() []string {
+	optUint := func(v *uint64) string {
+		if v == nil {
+			return ""
+		}
+		return strconv.FormatUint(*v, 10)
+	}
+
+	return []string{
+		strconv.Itoa(r.TagIndex), r.TagID, r.Triple,
+		r.ClassIDType, r.ClassID, r.Vendor, r.Model, optUint(r.Layer), optUint(r.Index),
+		r.InstanceType, r.Instance, r.GroupType, r.Group,
+		r.KeyType, r.Key, r.Type, r.Algorithm, r.Value,
+	}
+}
+
+// flattenComid returns the rows of the reference and endorsed values of c,
+// the CoMID at index tagIndex of its CoRIM
+func flattenComid(c *comid.Comid, tagIndex int) ([]measurementRow, error) {
+	var rows []measurementRow
+
+	for _, t := range []struct {
+		name string
+		vts  *comid.ValueTriples
+	}{
+		{"reference-values", c.Triples.ReferenceValues},
+		{"endorsed-values", c.Triples.EndorsedValues},
+	} {
+		if t.vts == nil {
+			continue
+		}
+
+		for _, vt := range t.vts.Values {
+			env := newMeasurementRow(tagIndex, c.TagIdentity.TagID.String(), t.name, vt.Environment)
+
+			for i := range vt.Measurements.Values {
+				mrows, err := flattenMeasurement(env, &vt.Measurements.Values[i])
+				if err != nil {
+					return nil, fmt.Errorf("%s measurement %d: %w", t.name, i, err)
+				}
+
+				rows = append(rows, mrows...)
+			}
+		}
+	}
+
+	return rows, nil
+}
+
+// newMeasurementRow returns a row with the tag and environment columns set
+func newMeasurementRow(tagIndex int, tagID, triple string, env comid.Environment) measurementRow {
+	r := measurementRow{TagIndex: tagIndex, TagID: tagID, Triple: triple}
+
+	if class := env.Class; class != nil {
+		if class.ClassID != nil {
+			r.ClassIDType, r.ClassID = class.ClassID.Type(), class.ClassID.String()
+		}
+		if class.Vendor != nil {
+			r.Vendor = *class.Vendor
+		}
+		if class.Model != nil {
+			r.Model = *class.Model
+		}
+		r.Layer, r.Index = class.Layer, class.Index
+	}
+
+	if env.Instance != nil {
+		r.InstanceType, r.Instance = env.Instance.Type(), env.Instance.String()
+	}
+
+	if env.Group != nil {
+		r.GroupType, r.Group = env.Group.Type(), env.Group.String()
+	}
+
+	return r
+}
+
+// flattenMeasurement returns one row, based on env, for each digest and for
+// each other measured value of m.  The non-digest values are listed in
+// alphabetical order.
+func flattenMeasurement(env measurementRow, m *comid.Measurement) ([]measurementRow, error) {
+	var err error
+
+	if env.KeyType, env.Key, err = measurementKey(m); err != nil {
+		return nil, err
+	}
+
+	var rows []measurementRow
+
+	if m.Val.Digests != nil {
+		for _, d := range *m.Val.Digests {
+			r := env
+			r.Type = measurementRowDigest
+			r.Algorithm = d.AlgIDToString()
+			r.Value = hex.EncodeToString(d.HashValue)
+			rows = append(rows, r)
+		}
+	}
+
+	j, err := json.Marshal(m.Val)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding measurement value: %w", err)
+	}
+
+	var vals map[string]json.RawMessage
+	if err = json.Unmarshal(j, &vals); err != nil {
+		return nil, fmt.Errorf("error decoding measurement value: %w", err)
+	}
+
+	delete(vals, "digests")
+
+	names := make([]string, 0, len(vals))
+	for name := range vals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		r := env
+		r.Type = name
+		r.Value = jsonScalar(vals[name])
+		rows = append(rows, r)
+	}
+
+	return rows, nil
+}
+
+// measurementKey returns the type and the value (as rendered by jsonScalar)
+// of the key of m, or empty strings if m has no key
+func measurementKey(m *comid.Measurement) (string, string, error) {
+	if m.Key == nil || !m.Key.IsSet() {
+		return "", "", nil
+	}
+
+	j, err := json.Marshal(m.Key)
+	if err != nil {
+		return "", "", fmt.Errorf("error encoding measurement key: %w", err)
+	}
+
+	var key struct {
+		Type  string          `json:"type"`
+		Value json.RawMessage `json:"value"`
+	}
+	if err = json.Unmarshal(j, &key); err != nil {
+		return "", "", fmt.Errorf("error decoding measurement key: %w", err)
+	}
+
+	return key.Type, jsonScalar(key.Value), nil
+}
+
+// jsonScalar returns the compact form of the JSON value raw, without the
+// quotes if it is a string
+func jsonScalar(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+
+	var buf bytes.Buffer
+	if json.Compact(&buf, raw) != nil {
+		return string(raw)
+	}
+
+	return buf.String()
+}
+
+// encodeMeasurementRows encodes rows as CSV (with a header line) or as JSON
+// Lines, according to format
+func encodeMeasurementRows(rows []measurementRow, format string) ([]byte, error) {
+	var buf bytes.Buffer
+
+	if format == flattenFormatJSONL {
+		enc := json.NewEncoder(&buf)
+		for _, r := range rows {
+			if err := enc.Encode(r); err != nil {
+				return nil, err
+			}
+		}
+
+		return buf.Bytes(), nil
+	}
+
+	w := csv.NewWriter(&buf)
+
+	if err := w.Write(flattenCSVHeader); err != nil {
+		return nil, err
+	}
+
+	for _, r := range rows {
+		if err := w.Write(r.csvRecord()); err != nil {
+			return nil, err
+		}
+	}
+
+	w.Flush()
+
+	return buf.Bytes(), w.Error()
+}
+
+// extractFlattenedMeasurements saves every measurement of the CoMIDs of
+// signedCorimFile (within page) to outputFile, one row per measured value, in
+// the given format
+func extractFlattenedMeasurements(signedCorimFile, outputFile, format string, page tagPage) (tagCounts, error) {
+	var (
+		signedCorimCBOR []byte
+		err             error
+		s               corim.SignedCorim
+		rows            []measurementRow
+		counts          tagCounts
+	)
+
+	if signedCorimCBOR, err = afero.ReadFile(fs, signedCorimFile); err != nil {
+		return counts, fmt.Errorf("error loading signed CoRIM from %s: %w", signedCorimFile, err)
+	}
+
+	if err = s.FromCOSE(signedCorimCBOR); err != nil {
+		return counts, fmt.Errorf("error decoding signed CoRIM from %s: %w", signedCorimFile, err)
+	}
+
+	tags := s.UnsignedCorim.Tags
+	page.report(len(tags))
+	page.tagType.report(tags)
+
+	for i, e := range tags {
+		if !page.contains(i, len(tags)) {
+			continue
+		}
+
+		// need at least 3 bytes for the tag and 1 for the smallest bstr
+		if len(e) < 3+1 {
+			fmt.Printf(">> skipping malformed tag at index %d\n", i)
+			continue
+		}
+
+		// split tag from data
+		cborTag, cborData := e[:3], e[3:]
+
+		if bytes.Equal(cborTag, cots.CotsTag) {
+			counts.cots++
+		}
+
+		if !bytes.Equal(cborTag, corim.ComidTag) {
+			fmt.Printf(">> skipping non-CoMID tag at index %d\n", i)
+			continue
+		}
+
+		var c comid.Comid
+
+		if err = c.FromCBOR(cborData); err != nil {
+			fmt.Printf(">> skipping malformed CoMID tag at index %d: %v\n", i, err)
+			continue
+		}
+
+		counts.comids++
+
+		crows, err := flattenComid(&c, i)
+		if err != nil {
+			return counts, fmt.Errorf("error flattening CoMID tag at index %d: %w", i, err)
+		}
+
+		rows = append(rows, crows...)
+	}
+
+	data, err := encodeMeasurementRows(rows, format)
+	if err != nil {
+		return counts, fmt.Errorf("error encoding measurements to %s: %w", format, err)
+	}
+
+	if err = afero.WriteFile(fs, outputFile, data, 0644); err != nil {
+		return counts, fmt.Errorf("error saving measurements to file %s: %w", outputFile, err)
+	}
+
+	fmt.Printf(">> %d measurement row(s) from %d CoMID(s) saved to %q\n", len(rows), counts.comids, outputFile)
+
+	return counts, nil
+}
@@ -0,0 +1,152 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/comid"
+)
+
+func Test_flattenMeasurement_digests_and_other_values(t *testing.T) {
+	var m comid.Measurement
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"key": { "type": "uint", "value": 3 },
+		"value": {
+			"digests": [
+				"sha-256:h0KPxSKAPTEGXnvOPPA/5HUJZjHl4Hu9eg/eYMTPJcc=",
+				"sha-256:AmOCmYm2/ZVPcrqvL8ZLwuLwHWktTecphuqAj26ZgT8="
+			],
+			"svn": { "type": "exact-value", "value": 2 },
+			"serial-number": "SN-0001"
+		}
+	}`), &m))
+
+	env := newMeasurementRow(0, "acme-fw", "reference-values", comid.Environment{})
+
+	rows, err := flattenMeasurement(env, &m)
+	require.NoError(t, err)
+	require.Len(t, rows, 4)
+
+	for _, r := range rows {
+		assert.Equal(t, "uint", r.KeyType)
+		assert.Equal(t, "3", r.Key)
+		assert.Equal(t, "acme-fw", r.TagID)
+	}
+
+	assert.Equal(t, measurementRowDigest, rows[0].Type)
+	assert.Equal(t, "sha-256", rows[0].Algorithm)
+	assert.Equal(t, "87428fc522803d31065e7bce3cf03fe475096631e5e07bbd7a0fde60c4cf25c7", rows[0].Value)
+	assert.Equal(t, measurementRowDigest, rows[1].Type)
+
+	// the other values follow, sorted by name
+	assert.Equal(t, "serial-number", rows[2].Type)
+	assert.Equal(t, "SN-0001", rows[2].Value)
+	assert.Empty(t, rows[2].Algorithm)
+
+	assert.Equal(t, "svn", rows[3].Type)
+	assert.JSONEq(t, `{"type":"exact-value","value":2}`, rows[3].Value)
+}
+
+func Test_CorimExtractCmd_flatten_measurements_csv(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "signed.cbor", newTestSignedCorimWithComids(t, 2), 0644))
+
+	cmd := NewCorimExtractCmd()
+	cmd.SetArgs([]string{"--file=signed.cbor", "--flatten-measurements", "--output=measurements.csv"})
+	require.NoError(t, cmd.Execute())
+
+	data, err := afero.ReadFile(fs, "measurements.csv")
+	require.NoError(t, err)
+
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	require.NoError(t, err)
+
+	// the header, then one digest for each of the 3 measurements of each CoMID
+	require.Len(t, records, 1+2*3)
+	assert.Equal(t, flattenCSVHeader, records[0])
+
+	first := records[1]
+	assert.Equal(t, []string{
+		"0", "43bbe37f-2e61-4b33-aed3-53cff1428b16", "reference-values",
+		"psa.impl-id", "YWNtZS1pbXBsZW1lbnRhdGlvbi1pZC0wMDAwMDAwMDE=", "ACME", "RoadRunner", "", "",
+		"", "", "", "",
+		"psa.refval-id",
+	}, first[:14])
+	assert.JSONEq(t, `{"label":"BL","version":"2.1.0","signer-id":"rLsRx+TaIXIFUjzkzhokWuGiOa48a/2eeHH35di66Gs="}`, first[14])
+	assert.Equal(t, []string{"digest", "sha-256", "87428fc522803d31065e7bce3cf03fe475096631e5e07bbd7a0fde60c4cf25c7"}, first[15:])
+
+	assert.Equal(t, "1", records[len(records)-1][0])
+}
+
+func Test_CorimExtractCmd_flatten_measurements_jsonl(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "signed.cbor", newTestSignedCorimWithComids(t, 1), 0644))
+
+	cmd := NewCorimExtractCmd()
+	cmd.SetArgs([]string{"--file=signed.cbor", "--flatten-measurements", "--format=jsonl", "--output=measurements.jsonl"})
+	require.NoError(t, cmd.Execute())
+
+	data, err := afero.ReadFile(fs, "measurements.jsonl")
+	require.NoError(t, err)
+
+	lines := bytes.Split(bytes.TrimSpace(data), []byte("\n"))
+	require.Len(t, lines, 3)
+
+	var r measurementRow
+	require.NoError(t, json.Unmarshal(lines[2], &r))
+	assert.Equal(t, "reference-values", r.Triple)
+	assert.Equal(t, "ACME", r.Vendor)
+	assert.Nil(t, r.Layer)
+	assert.Equal(t, measurementRowDigest, r.Type)
+}
+
+func Test_CorimExtractCmd_flatten_measurements_bad_args(t *testing.T) {
+	tvs := []struct {
+		desc     string
+		args     []string
+		expected string
+	}{
+		{
+			desc:     "no output",
+			args:     []string{"--file=ok.cbor", "--flatten-measurements"},
+			expected: "no output file supplied",
+		},
+		{
+			desc:     "json array",
+			args:     []string{"--file=ok.cbor", "--flatten-measurements", "--json-array", "--output=out"},
+			expected: "--json-array cannot be used together with --flatten-measurements",
+		},
+		{
+			desc:     "unknown format",
+			args:     []string{"--file=ok.cbor", "--flatten-measurements", "--format=parquet", "--output=out"},
+			expected: `invalid --format "parquet": expecting csv or jsonl`,
+		},
+		{
+			desc:     "format without flatten",
+			args:     []string{"--file=ok.cbor", "--format=jsonl"},
+			expected: "--format can only be used together with --flatten-measurements",
+		},
+		{
+			desc:     "cots only",
+			args:     []string{"--file=ok.cbor", "--flatten-measurements", "--output=out", "--tag-type=cots"},
+			expected: "--flatten-measurements only reads CoMIDs, and cannot be used together with --tag-type=cots",
+		},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.desc, func(t *testing.T) {
+			cmd := NewCorimExtractCmd()
+			cmd.SetArgs(tv.args)
+
+			assert.EqualError(t, cmd.Execute(), tv.expected)
+		})
+	}
+}
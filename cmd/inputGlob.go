@@ -0,0 +1,75 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+// addInputGlobFlag adds the --input-glob switch, used by batch commands to
+// select their input files, to cmd
+func addInputGlobFlag(cmd *cobra.Command, globs *[]string, what string) {
+	cmd.Flags().StringArrayVar(
+		globs, "input-glob", []string{},
+		"a pattern matching "+what+`; "**" matches any number of directories (can be repeated)`,
+	)
+}
+
+// globFiles returns the regular files matching any of patterns, in the order
+// of the first pattern they match and then lexically, without duplicates.
+// Patterns use "/" as separator on every platform, and "**" matches zero or
+// more directories (e.g., "releases/**/*.cbor").  The number of files matched
+// by each pattern is reported.
+func globFiles(patterns []string) ([]string, error) {
+	var (
+		l    []string
+		seen = make(map[string]bool)
+	)
+
+	for _, p := range patterns {
+		pattern := filepath.ToSlash(filepath.Clean(p))
+
+		if !doublestar.ValidatePattern(pattern) {
+			return nil, fmt.Errorf("invalid --input-glob pattern %q: %w", p, doublestar.ErrBadPattern)
+		}
+
+		// only walk the part of the tree that can match
+		base, _ := doublestar.SplitPattern(pattern)
+
+		var n int
+
+		err := afero.Walk(fs, filepath.FromSlash(base), func(path string, info os.FileInfo, err error) error {
+			// unreadable (or missing) directories match nothing
+			if err != nil || info.IsDir() {
+				return nil
+			}
+
+			if ok, _ := doublestar.Match(pattern, filepath.ToSlash(path)); !ok {
+				return nil
+			}
+
+			n++
+
+			if !seen[path] {
+				seen[path] = true
+				l = append(l, path)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error walking %s for --input-glob pattern %q: %w", base, p, err)
+		}
+
+		fmt.Printf(">> %d file(s) matched %q\n", n, p)
+	}
+
+	return l, nil
+}
@@ -0,0 +1,182 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeGlobTree(t *testing.T, data []byte, files ...string) {
+	fs = afero.NewMemMapFs()
+
+	for _, file := range files {
+		require.NoError(t, afero.WriteFile(fs, file, data, 0644))
+	}
+}
+
+func Test_globFiles(t *testing.T) {
+	writeGlobTree(t, testCorimValid,
+		"releases/a.cbor",
+		"releases/v1/b.cbor",
+		"releases/v1/deep/c.cbor",
+		"releases/v1/notes.txt",
+		"other/d.cbor",
+	)
+
+	tvs := []struct {
+		patterns []string
+		expected []string
+	}{
+		{
+			[]string{"releases/**/*.cbor"},
+			[]string{"releases/a.cbor", "releases/v1/b.cbor", "releases/v1/deep/c.cbor"},
+		},
+		{
+			[]string{"releases/*/*.cbor"},
+			[]string{"releases/v1/b.cbor"},
+		},
+		{
+			[]string{"./other/*.cbor", "**/a.cbor", "releases/*.cbor"},
+			[]string{"other/d.cbor", "releases/a.cbor"},
+		},
+		{
+			[]string{"**/*.txt"},
+			[]string{"releases/v1/notes.txt"},
+		},
+		{
+			[]string{"missing/**/*.cbor"},
+			nil,
+		},
+	}
+
+	for _, tv := range tvs {
+		files, err := globFiles(tv.patterns)
+		require.NoError(t, err)
+		assert.Equal(t, tv.expected, files, tv.patterns)
+	}
+}
+
+func Test_globFiles_bad_pattern(t *testing.T) {
+	writeGlobTree(t, testCorimValid)
+
+	_, err := globFiles([]string{"releases/[*.cbor"})
+	assert.ErrorContains(t, err, `invalid --input-glob pattern "releases/[*.cbor"`)
+}
+
+func Test_CorimSignBatchCmd_input_glob(t *testing.T) {
+	writeGlobTree(t, testCorimValid, "releases/v1/a.cbor", "releases/v2/b.cbor", "c.cbor")
+	require.NoError(t, afero.WriteFile(fs, "meta.json", testMetaValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "key.jwk", testECKey, 0644))
+
+	cmd := NewCorimSignBatchCmd()
+	cmd.SetArgs([]string{
+		"--key=key.jwk",
+		"--meta=meta.json",
+		"--output=bundle.cbor",
+		"--input-glob=releases/**/*.cbor",
+		"c.cbor",
+	})
+	require.NoError(t, cmd.Execute())
+
+	var bundle []cbor.RawMessage
+	require.NoError(t, cbor.Unmarshal(mustReadFile(t, "bundle.cbor"), &bundle))
+	assert.Len(t, bundle, 3)
+}
+
+func Test_CorimSignBatchCmd_input_glob_no_match(t *testing.T) {
+	writeGlobTree(t, testCorimValid, "c.cbor")
+
+	cmd := NewCorimSignBatchCmd()
+	cmd.SetArgs([]string{
+		"--key=key.jwk",
+		"--meta=meta.json",
+		"--output=bundle.cbor",
+		"--input-glob=releases/**/*.cbor",
+	})
+	assert.EqualError(t, cmd.Execute(), "no files found")
+}
+
+func Test_CorimVerifyCmd_input_glob(t *testing.T) {
+	writeGlobTree(t, testSignedCorimValid, "releases/v1/a.cbor", "releases/v1/deep/b.cbor")
+	require.NoError(t, afero.WriteFile(fs, "releases/v2/bad.cbor", []byte("not a CoRIM"), 0644))
+	require.NoError(t, afero.WriteFile(fs, "key.jwk", testECKey, 0644))
+
+	cmd := NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--input-glob=releases/v1/**/*.cbor", "--key=key.jwk"})
+	require.NoError(t, cmd.Execute())
+
+	cmd = NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--input-glob=releases/**/*.cbor", "--key=key.jwk"})
+	assert.EqualError(t, cmd.Execute(), "1/3 verification(s) failed")
+}
+
+func Test_CorimVerifyCmd_input_glob_bad_args(t *testing.T) {
+	tvs := []struct {
+		args     []string
+		expected string
+	}{
+		{
+			[]string{"--input-glob=**/*.cbor", "--file=a.cbor", "--key=key.jwk"},
+			"--file cannot be used together with --input-glob",
+		},
+		{
+			[]string{"--input-glob=**/*.cbor", "--sequence", "--key=key.jwk"},
+			"--sequence cannot be used together with --input-glob",
+		},
+		{
+			[]string{"--input-glob=**/*.cbor", "--signature=s.cbor", "--payload=p.cbor", "--key=key.jwk"},
+			"--signature cannot be used together with --input-glob",
+		},
+		{
+			[]string{"--input-glob=**/*.cbor", "--quorum=1", "--quorum-key=a.jwk"},
+			"--quorum cannot be used together with --input-glob",
+		},
+	}
+
+	for _, tv := range tvs {
+		cmd := NewCorimVerifyCmd()
+		cmd.SetArgs(tv.args)
+		assert.EqualError(t, cmd.Execute(), tv.expected)
+	}
+}
+
+func Test_CorimDisplayCmd_input_glob(t *testing.T) {
+	writeGlobTree(t, testSignedCorimValid, "releases/v1/a.cbor", "releases/v2/b.cbor")
+
+	cmd := NewCorimDisplayCmd()
+	cmd.SetArgs([]string{"--input-glob=releases/**/*.cbor", "--output=out.txt"})
+	require.NoError(t, cmd.Execute())
+
+	out := string(mustReadFile(t, "out.txt"))
+	assert.Contains(t, out, ">> 2 file(s) matched \"releases/**/*.cbor\"")
+	assert.Contains(t, out, ">> releases/v1/a.cbor:")
+	assert.Contains(t, out, ">> releases/v2/b.cbor:")
+}
+
+func Test_CorimDisplayCmd_input_glob_bad_args(t *testing.T) {
+	tvs := []struct {
+		args     []string
+		expected string
+	}{
+		{
+			[]string{"--input-glob=**/*.cbor", "--file=a.cbor"},
+			"--file cannot be used together with --input-glob",
+		},
+		{
+			[]string{"--input-glob=**/*.cbor", "--hash"},
+			"--input-glob cannot be used together with --measurements-flat, --sequence or --hash",
+		},
+	}
+
+	for _, tv := range tvs {
+		cmd := NewCorimDisplayCmd()
+		cmd.SetArgs(tv.args)
+		assert.EqualError(t, cmd.Execute(), tv.expected)
+	}
+}
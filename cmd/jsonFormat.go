@@ -0,0 +1,68 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	// defaultJSONIndent is the number of spaces JSON output is indented with,
+	// unless --json-indent or --json-compact say otherwise
+	defaultJSONIndent = 2
+	// maxJSONIndent is the largest --json-indent accepted
+	maxJSONIndent = 8
+)
+
+// jsonFormat controls the rendering of the --json output of a command: either
+// indented with the given number of spaces per level, or compact (a single
+// line, without insignificant whitespace)
+type jsonFormat struct {
+	indent  int
+	compact bool
+}
+
+// addJSONFormatFlags registers the --json-indent and --json-compact switches
+// of cmd, storing their values in f
+func addJSONFormatFlags(cmd *cobra.Command, f *jsonFormat) {
+	cmd.Flags().IntVar(
+		&f.indent, "json-indent", defaultJSONIndent, "number of spaces to indent the --json output with (0 puts each value on its own line, unindented)",
+	)
+
+	cmd.Flags().BoolVar(
+		&f.compact, "json-compact", false, "print the --json output on a single line, without whitespace",
+	)
+}
+
+// isDefault tells whether neither --json-indent nor --json-compact changed the
+// default rendering
+func (o jsonFormat) isDefault() bool {
+	return o.indent == defaultJSONIndent && !o.compact
+}
+
+func (o jsonFormat) valid() error {
+	if o.indent < 0 || o.indent > maxJSONIndent {
+		return fmt.Errorf("--json-indent must be between 0 and %d", maxJSONIndent)
+	}
+
+	if o.compact && o.indent != defaultJSONIndent {
+		return errors.New("--json-compact cannot be used together with --json-indent")
+	}
+
+	return nil
+}
+
+// marshal returns the JSON encoding of v, rendered according to o
+func (o jsonFormat) marshal(v interface{}) ([]byte, error) {
+	if o.compact {
+		return json.Marshal(v)
+	}
+
+	return json.MarshalIndent(v, "", strings.Repeat(" ", o.indent))
+}
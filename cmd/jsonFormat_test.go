@@ -0,0 +1,106 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_jsonFormat_marshal(t *testing.T) {
+	v := map[string][]int{"a": {1}}
+
+	tvs := []struct {
+		format   jsonFormat
+		expected string
+	}{
+		{jsonFormat{indent: defaultJSONIndent}, "{\n  \"a\": [\n    1\n  ]\n}"},
+		{jsonFormat{indent: 4}, "{\n    \"a\": [\n        1\n    ]\n}"},
+		{jsonFormat{indent: 0}, "{\n\"a\": [\n1\n]\n}"},
+		{jsonFormat{indent: defaultJSONIndent, compact: true}, `{"a":[1]}`},
+	}
+
+	for _, tv := range tvs {
+		require.NoError(t, tv.format.valid())
+
+		data, err := tv.format.marshal(v)
+		require.NoError(t, err)
+		assert.Equal(t, tv.expected, string(data))
+	}
+}
+
+func Test_jsonFormat_valid(t *testing.T) {
+	assert.EqualError(t, jsonFormat{indent: -1}.valid(), "--json-indent must be between 0 and 8")
+	assert.EqualError(t, jsonFormat{indent: 9}.valid(), "--json-indent must be between 0 and 8")
+	assert.EqualError(t,
+		jsonFormat{indent: 4, compact: true}.valid(),
+		"--json-compact cannot be used together with --json-indent",
+	)
+}
+
+func Test_CorimDisplayCmd_json_compact(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "ok.cbor", newTestSignedCorimWithComids(t, 1), 0644))
+
+	cmd := NewCorimDisplayCmd()
+	cmd.SetArgs([]string{"--file=ok.cbor", "--measurements-flat", "--json", "--json-compact", "--output=digests.json"})
+	require.NoError(t, cmd.Execute())
+
+	data, err := afero.ReadFile(fs, "digests.json")
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(data), "\n"), "a single line is expected")
+
+	var rows []map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &rows))
+	assert.Len(t, rows, 3)
+}
+
+func Test_JSONFormat_bad_args(t *testing.T) {
+	tvs := []struct {
+		desc     string
+		newCmd   func() *cobra.Command
+		args     []string
+		expected string
+	}{
+		{
+			desc:     "comid diff indent without json",
+			newCmd:   NewComidDiffCmd,
+			args:     []string{"--old=old.cbor", "--new=new.cbor", "--json-indent=4"},
+			expected: "--json-indent and --json-compact can only be used together with --json",
+		},
+		{
+			desc:     "comid display indent too large",
+			newCmd:   NewComidDisplayCmd,
+			args:     []string{"--file=comid.cbor", "--raw-values", "--json", "--json-indent=10"},
+			expected: "--json-indent must be between 0 and 8",
+		},
+		{
+			desc:     "corim display compact without json",
+			newCmd:   NewCorimDisplayCmd,
+			args:     []string{"--file=ok.cbor", "--measurements-flat", "--json-compact"},
+			expected: "--json-indent and --json-compact can only be used together with --json",
+		},
+		{
+			desc:     "corim display compact and indent",
+			newCmd:   NewCorimDisplayCmd,
+			args:     []string{"--file=ok.cbor", "--measurements-flat", "--json", "--json-compact", "--json-indent=4"},
+			expected: "--json-compact cannot be used together with --json-indent",
+		},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.desc, func(t *testing.T) {
+			cmd := tv.newCmd()
+			cmd.SetArgs(tv.args)
+
+			assert.EqualError(t, cmd.Execute(), tv.expected)
+		})
+	}
+}
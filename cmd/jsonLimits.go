@@ -0,0 +1,135 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	// defaultMaxJSONSize is the default maximum size (in bytes) of a JSON template
+	defaultMaxJSONSize = 10 << 20
+	// defaultMaxJSONDepth is the default maximum nesting of arrays and objects
+	// in a JSON template
+	defaultMaxJSONDepth = 64
+)
+
+// jsonLimits bounds the JSON templates that are accepted for decoding.  A zero
+// maxSize or maxDepth disables the corresponding check.
+type jsonLimits struct {
+	maxSize  int64
+	maxDepth int
+}
+
+// addJSONLimitsFlags registers the --max-json-size and --max-json-depth
+// switches of cmd, storing their values in l
+func addJSONLimitsFlags(cmd *cobra.Command, l *jsonLimits) {
+	cmd.Flags().Int64Var(
+		&l.maxSize, "max-json-size", defaultMaxJSONSize, "reject JSON templates larger than this many bytes (0 for no limit)",
+	)
+
+	cmd.Flags().IntVar(
+		&l.maxDepth, "max-json-depth", defaultMaxJSONDepth, "reject JSON templates nested deeper than this (0 for no limit)",
+	)
+}
+
+func (l jsonLimits) valid() error {
+	if l.maxSize < 0 {
+		return errors.New("--max-json-size must not be negative")
+	}
+
+	if l.maxDepth < 0 {
+		return errors.New("--max-json-depth must not be negative")
+	}
+
+	return nil
+}
+
+// readJSONTemplate loads the JSON template in file, expanding environment
+// variable references if so requested, and makes sure that the result is within
+// the size and nesting limits before it is handed over to a decoder.  At most
+// maxSize+1 bytes are read, whatever the size of the file.
+func readJSONTemplate(file string, l jsonLimits, env envExpansion) ([]byte, error) {
+	f, err := fs.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if l.maxSize > 0 {
+		r = io.LimitReader(f, l.maxSize+1)
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = l.checkSize(data); err != nil {
+		return nil, err
+	}
+
+	if env.enabled {
+		if data, err = env.expand(data); err != nil {
+			return nil, err
+		}
+
+		// expansion may have grown the template past the limit
+		if err = l.checkSize(data); err != nil {
+			return nil, err
+		}
+	}
+
+	if l.maxDepth > 0 {
+		if err = checkJSONDepth(data, l.maxDepth); err != nil {
+			return nil, err
+		}
+	}
+
+	return data, nil
+}
+
+func (l jsonLimits) checkSize(data []byte) error {
+	if l.maxSize > 0 && int64(len(data)) > l.maxSize {
+		return fmt.Errorf("template exceeds the maximum size of %d bytes (see --max-json-size)", l.maxSize)
+	}
+
+	return nil
+}
+
+// checkJSONDepth scans the tokens of data, without building any value, and
+// fails as soon as arrays and objects are nested deeper than maxDepth.
+// Syntax errors are left to the decoder.
+func checkJSONDepth(data []byte, maxDepth int) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+
+	for depth := 0; ; {
+		t, err := dec.Token()
+		if err != nil {
+			return nil
+		}
+
+		d, ok := t.(json.Delim)
+		if !ok {
+			continue
+		}
+
+		switch d {
+		case '[', '{':
+			depth++
+			if depth > maxDepth {
+				return fmt.Errorf("template exceeds the maximum nesting depth of %d (see --max-json-depth)", maxDepth)
+			}
+		default:
+			depth--
+		}
+	}
+}
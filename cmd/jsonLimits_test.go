@@ -0,0 +1,149 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/comid"
+)
+
+func Test_checkJSONDepth(t *testing.T) {
+	tvs := []struct {
+		data     string
+		maxDepth int
+		fails    bool
+	}{
+		{`{"a": [1, 2, {"b": 3}]}`, 3, false},
+		{`{"a": [1, 2, {"b": 3}]}`, 2, true},
+		{`[[], [], []]`, 2, false},
+		{`"scalar"`, 1, false},
+		// syntax errors are left to the decoder
+		{`{"a": ]`, 1, false},
+		{strings.Repeat("[", 100000), 64, true},
+	}
+
+	for _, tv := range tvs {
+		err := checkJSONDepth([]byte(tv.data), tv.maxDepth)
+		if tv.fails {
+			assert.EqualError(t, err, fmt.Sprintf(
+				"template exceeds the maximum nesting depth of %d (see --max-json-depth)", tv.maxDepth))
+		} else {
+			assert.NoError(t, err, tv.data)
+		}
+	}
+}
+
+func Test_readJSONTemplate(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "t.json", []byte(`{"a": {"b": {"c": 1}}}`), 0644)
+	require.NoError(t, err)
+
+	data, err := readJSONTemplate("t.json", jsonLimits{}, envExpansion{})
+	assert.NoError(t, err)
+	assert.Len(t, data, 22)
+
+	_, err = readJSONTemplate("t.json", jsonLimits{maxSize: 22, maxDepth: 3}, envExpansion{})
+	assert.NoError(t, err)
+
+	_, err = readJSONTemplate("t.json", jsonLimits{maxSize: 21}, envExpansion{})
+	assert.EqualError(t, err, "template exceeds the maximum size of 21 bytes (see --max-json-size)")
+
+	_, err = readJSONTemplate("t.json", jsonLimits{maxDepth: 2}, envExpansion{})
+	assert.EqualError(t, err, "template exceeds the maximum nesting depth of 2 (see --max-json-depth)")
+
+	_, err = readJSONTemplate("missing.json", jsonLimits{}, envExpansion{})
+	assert.EqualError(t, err, "open missing.json: file does not exist")
+}
+
+func Test_ComidCreateCmd_template_too_large(t *testing.T) {
+	var err error
+
+	fs = afero.NewMemMapFs()
+	err = afero.WriteFile(fs, "ok.json", []byte(comid.PSARefValJSONTemplate), 0644)
+	require.NoError(t, err)
+
+	cmd := NewComidCreateCmd()
+
+	args := []string{
+		"--template=ok.json",
+		"--max-json-size=100",
+	}
+	cmd.SetArgs(args)
+
+	err = cmd.Execute()
+	assert.EqualError(t, err, "1/1 creations(s) failed")
+
+	_, err = fs.Stat("ok.cbor")
+	assert.Error(t, err)
+
+	// the default limits accept the template
+	cmd = NewComidCreateCmd()
+	cmd.SetArgs([]string{"--template=ok.json"})
+
+	err = cmd.Execute()
+	assert.NoError(t, err)
+}
+
+func Test_ComidCreateCmd_negative_limit(t *testing.T) {
+	cmd := NewComidCreateCmd()
+
+	args := []string{
+		"--template=ok.json",
+		"--max-json-depth=-1",
+	}
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "--max-json-depth must not be negative")
+}
+
+func Test_CorimCreateCmd_template_too_deep(t *testing.T) {
+	var err error
+
+	fs = afero.NewMemMapFs()
+	err = afero.WriteFile(fs, "corim.json", []byte(`{"corim-id": [[[[1]]]]}`), 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "comid.cbor", testComid, 0644)
+	require.NoError(t, err)
+
+	cmd := NewCorimCreateCmd()
+
+	args := []string{
+		"--template=corim.json",
+		"--comid=comid.cbor",
+		"--max-json-depth=3",
+	}
+	cmd.SetArgs(args)
+
+	err = cmd.Execute()
+	assert.EqualError(t, err, "error loading template from corim.json: template exceeds the maximum nesting depth of 3 (see --max-json-depth)")
+}
+
+func Test_CotsCreateCmd_template_too_large(t *testing.T) {
+	var err error
+
+	fs = afero.NewMemMapFs()
+	err = afero.WriteFile(fs, "env.json", []byte(`{"environments": []}`), 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ta.der", []byte{}, 0644)
+	require.NoError(t, err)
+
+	cmd := NewCotsCreateCtsCmd()
+
+	args := []string{
+		"--environment=env.json",
+		"--tafile=ta.der",
+		"--max-json-size=8",
+	}
+	cmd.SetArgs(args)
+
+	err = cmd.Execute()
+	assert.EqualError(t, err, "error loading template from env.json: template exceeds the maximum size of 8 bytes (see --max-json-size)")
+}
@@ -0,0 +1,50 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// mergeJSON applies the JSON object patch to the JSON object base, following
+// the JSON Merge Patch (RFC 7396) rules: members of patch that are objects are
+// merged recursively with those of base, null members remove the corresponding
+// member of base, and any other member (including arrays) replaces it.
+func mergeJSON(base, patch []byte) ([]byte, error) {
+	var b, p map[string]interface{}
+
+	if err := json.Unmarshal(base, &b); err != nil {
+		return nil, errors.New("base is not a JSON object")
+	}
+
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return nil, errors.New("patch is not a JSON object")
+	}
+
+	return json.Marshal(mergeJSONObjects(b, p))
+}
+
+func mergeJSONObjects(base, patch map[string]interface{}) map[string]interface{} {
+	if base == nil {
+		base = map[string]interface{}{}
+	}
+
+	for k, v := range patch {
+		if v == nil {
+			delete(base, k)
+			continue
+		}
+
+		if po, ok := v.(map[string]interface{}); ok {
+			bo, _ := base[k].(map[string]interface{})
+			base[k] = mergeJSONObjects(bo, po)
+			continue
+		}
+
+		base[k] = v
+	}
+
+	return base
+}
@@ -0,0 +1,43 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_mergeJSON(t *testing.T) {
+	tvs := []struct {
+		desc     string
+		base     string
+		patch    string
+		expected string
+	}{
+		{"add", `{"a": 1}`, `{"b": 2}`, `{"a": 1, "b": 2}`},
+		{"replace", `{"a": 1, "b": 2}`, `{"a": "x"}`, `{"a": "x", "b": 2}`},
+		{"nested", `{"a": {"b": 1, "c": 2}}`, `{"a": {"c": 3, "d": 4}}`, `{"a": {"b": 1, "c": 3, "d": 4}}`},
+		{"remove", `{"a": {"b": 1, "c": 2}}`, `{"a": {"c": null}}`, `{"a": {"b": 1}}`},
+		{"arrays are replaced", `{"a": [1, 2]}`, `{"a": [3]}`, `{"a": [3]}`},
+		{"object over scalar", `{"a": 1}`, `{"a": {"b": 1}}`, `{"a": {"b": 1}}`},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.desc, func(t *testing.T) {
+			actual, err := mergeJSON([]byte(tv.base), []byte(tv.patch))
+			require.NoError(t, err)
+			assert.JSONEq(t, tv.expected, string(actual))
+		})
+	}
+}
+
+func Test_mergeJSON_not_objects(t *testing.T) {
+	_, err := mergeJSON([]byte(`[1]`), []byte(`{}`))
+	assert.EqualError(t, err, "base is not a JSON object")
+
+	_, err = mergeJSON([]byte(`{}`), []byte(`"x"`))
+	assert.EqualError(t, err, "patch is not a JSON object")
+}
@@ -0,0 +1,197 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/veraison/corim/corim"
+)
+
+// jsonPathStep is either an object member or an array index
+type jsonPathStep struct {
+	member  string
+	index   int
+	isIndex bool
+}
+
+func (o jsonPathStep) String() string {
+	if o.isIndex {
+		return fmt.Sprintf("[%d]", o.index)
+	}
+
+	if strings.ContainsAny(o.member, ".[]'\"") {
+		return fmt.Sprintf("[%q]", o.member)
+	}
+
+	return "." + o.member
+}
+
+// jsonPath is a simple JSONPath expression, made of a sequence of object
+// members, in dot (.name) or bracket (['name'] or ["name"]) notation, and array
+// indexes ([0], or [-1] for the last element), e.g.,
+// $.comids[0].triples.reference-values[0].measurements[0].value.digests[0].
+// The leading $ is optional.
+type jsonPath []jsonPathStep
+
+func (o jsonPath) String() string {
+	var sb strings.Builder
+
+	sb.WriteString("$")
+	for _, step := range o {
+		sb.WriteString(step.String())
+	}
+
+	return sb.String()
+}
+
+// parseJSONPath parses the JSONPath expression s (see jsonPath).  The path to
+// the root ($) is empty, but not nil.
+func parseJSONPath(s string) (jsonPath, error) {
+	path := jsonPath{}
+
+	s = strings.TrimSpace(s)
+	rest := strings.TrimPrefix(s, "$")
+
+	for rest != "" {
+		offset := len(s) - len(rest)
+
+		switch {
+		// a bare member name is accepted as the first step, e.g., "profile"
+		case rest[0] == '.' || (offset == 0 && rest[0] != '['):
+			rest = strings.TrimPrefix(rest, ".")
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+
+			if end == 0 {
+				return nil, fmt.Errorf("empty member name at offset %d", offset)
+			}
+
+			path = append(path, jsonPathStep{member: rest[:end]})
+			rest = rest[end:]
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated [ at offset %d", offset)
+			}
+
+			step, err := parseJSONPathBracket(rest[1:end])
+			if err != nil {
+				return nil, fmt.Errorf("%w at offset %d", err, offset)
+			}
+
+			path = append(path, step)
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("unexpected %q at offset %d", rest[0], offset)
+		}
+	}
+
+	return path, nil
+}
+
+// parseJSONPathBracket parses the content of [...]: a quoted member name or
+// an array index
+func parseJSONPathBracket(s string) (jsonPathStep, error) {
+	if len(s) >= 2 && (s[0] == '\'' || s[0] == '"') {
+		if s[len(s)-1] != s[0] {
+			return jsonPathStep{}, errors.New("unterminated member name")
+		}
+
+		return jsonPathStep{member: s[1 : len(s)-1]}, nil
+	}
+
+	i, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return jsonPathStep{}, fmt.Errorf("invalid array index %q", s)
+	}
+
+	return jsonPathStep{index: i, isIndex: true}, nil
+}
+
+// eval returns the value found at o in doc, as decoded by encoding/json
+func (o jsonPath) eval(doc interface{}) (interface{}, error) {
+	v := doc
+
+	for i, step := range o {
+		at := o[:i+1]
+
+		if !step.isIndex {
+			obj, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s: not an object", o[:i])
+			}
+
+			if v, ok = obj[step.member]; !ok {
+				return nil, fmt.Errorf("%s: no such member", at)
+			}
+
+			continue
+		}
+
+		arr, ok := v.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s: not an array", o[:i])
+		}
+
+		index := step.index
+		if index < 0 {
+			index += len(arr)
+		}
+
+		if index < 0 || index >= len(arr) {
+			return nil, fmt.Errorf("%s: index out of range (%d element(s))", at, len(arr))
+		}
+
+		v = arr[index]
+	}
+
+	return v, nil
+}
+
+// printExtracted prints the value at path in the full CoRIM document rendered
+// from u and meta (see fullCorimJSON).  Strings are printed as is, other
+// values in JSON format.
+func printExtracted(u *corim.UnsignedCorim, meta *corim.Meta, path jsonPath) error {
+	data, err := fullCorimJSON(u, meta)
+	if err != nil {
+		return err
+	}
+
+	var doc interface{}
+
+	// keep numbers as they are, e.g., large integers
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	if err = dec.Decode(&doc); err != nil {
+		return fmt.Errorf("error decoding CoRIM: %w", err)
+	}
+
+	v, err := path.eval(doc)
+	if err != nil {
+		return err
+	}
+
+	if str, ok := v.(string); ok {
+		fmt.Println(str)
+		return nil
+	}
+
+	j, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding extracted value: %w", err)
+	}
+
+	fmt.Println(string(j))
+
+	return nil
+}
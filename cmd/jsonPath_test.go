@@ -0,0 +1,168 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/corim/corim"
+)
+
+func Test_parseJSONPath(t *testing.T) {
+	tvs := []struct {
+		expr     string
+		expected string
+	}{
+		{expr: "$", expected: "$"},
+		{expr: "$.comids[0].tag-identity.id", expected: "$.comids[0].tag-identity.id"},
+		{expr: "comids[-1]", expected: "$.comids[-1]"},
+		{expr: ".meta['signer'][\"name\"]", expected: "$.meta.signer.name"},
+		{expr: "$['a.b'][ 2 ]", expected: `$["a.b"][2]`},
+	}
+
+	for _, tv := range tvs {
+		path, err := parseJSONPath(tv.expr)
+		require.NoError(t, err, tv.expr)
+		assert.NotNil(t, path, tv.expr)
+		assert.Equal(t, tv.expected, path.String(), tv.expr)
+	}
+}
+
+func Test_parseJSONPath_bad(t *testing.T) {
+	tvs := []struct {
+		expr     string
+		expected string
+	}{
+		{expr: "$.comids..id", expected: "empty member name at offset 8"},
+		{expr: "$.comids[0", expected: "unterminated [ at offset 8"},
+		{expr: "$.comids[x]", expected: `invalid array index "x" at offset 8`},
+		{expr: "$['id]", expected: "unterminated member name at offset 1"},
+		{expr: "$comids", expected: `unexpected 'c' at offset 1`},
+	}
+
+	for _, tv := range tvs {
+		_, err := parseJSONPath(tv.expr)
+		assert.EqualError(t, err, tv.expected, tv.expr)
+	}
+}
+
+func Test_jsonPath_eval(t *testing.T) {
+	var doc interface{}
+	require.NoError(t, json.Unmarshal([]byte(`{"a": [ {"b": "x"}, {"b": "y"} ], "c": 1}`), &doc))
+
+	tvs := []struct {
+		expr     string
+		expected interface{}
+		err      string
+	}{
+		{expr: "$.a[0].b", expected: "x"},
+		{expr: "$.a[-1].b", expected: "y"},
+		{expr: "$.c", expected: float64(1)},
+		{expr: "$.d", err: "$.d: no such member"},
+		{expr: "$.a[2]", err: "$.a[2]: index out of range (2 element(s))"},
+		{expr: "$.c.d", err: "$.c: not an object"},
+		{expr: "$.a.b", err: "$.a: not an object"},
+		{expr: "$[0]", err: "$: not an array"},
+	}
+
+	for _, tv := range tvs {
+		path, err := parseJSONPath(tv.expr)
+		require.NoError(t, err, tv.expr)
+
+		v, err := path.eval(doc)
+		if tv.err != "" {
+			assert.EqualError(t, err, tv.err, tv.expr)
+		} else {
+			require.NoError(t, err, tv.expr)
+			assert.Equal(t, tv.expected, v, tv.expr)
+		}
+	}
+}
+
+func setupExtractTest(t *testing.T) {
+	var c comid.Comid
+	require.NoError(t, c.FromJSON([]byte(comid.PSARefValJSONTemplate)))
+
+	u := corim.NewUnsignedCorim().SetID("extract").AddComid(&c)
+	require.NotNil(t, u)
+
+	data, err := u.ToCBOR()
+	require.NoError(t, err)
+
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "unsigned.cbor", data, 0644))
+	require.NoError(t, afero.WriteFile(fs, "meta.json", testMetaValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "key.jwk", testECKey, 0644))
+
+	cmd := NewCorimSignCmd()
+	cmd.SetArgs([]string{"--file=unsigned.cbor", "--meta=meta.json", "--key=key.jwk", "--output=signed.cbor"})
+	require.NoError(t, cmd.Execute())
+}
+
+func Test_CorimVerifyCmd_extract(t *testing.T) {
+	setupExtractTest(t)
+
+	extract := func(expr string) (string, error) {
+		cmd := NewCorimVerifyCmd()
+		cmd.SetArgs([]string{"--file=signed.cbor", "--key=key.jwk", "--extract=" + expr})
+		err := withDisplayOutput("out.txt", cmd.Execute)
+
+		return string(mustReadFile(t, "out.txt")), err
+	}
+
+	out, err := extract("$.comids[0].triples.reference-values[0].measurements[0].value.digests[0]")
+	require.NoError(t, err)
+	assert.Regexp(t, `^sha-256;[A-Za-z0-9+/=]+\n$`, out)
+
+	out, err = extract("corim-id")
+	require.NoError(t, err)
+	assert.Equal(t, "extract\n", out)
+
+	out, err = extract("$.meta.signer")
+	require.NoError(t, err)
+	assert.Contains(t, out, `"name": `)
+
+	// the root path prints the whole payload, and nothing else
+	out, err = extract("$")
+	require.NoError(t, err)
+	assert.NotContains(t, out, ">> ")
+
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(out), &doc))
+	assert.Equal(t, "extract", doc["corim-id"])
+	assert.Contains(t, doc, "comids")
+
+	_, err = extract("$.coswids[0]")
+	assert.EqualError(t, err, "error extracting $.coswids[0] from signed.cbor: $.coswids: no such member")
+}
+
+func Test_CorimVerifyCmd_extract_unverified(t *testing.T) {
+	setupExtractTest(t)
+	require.NoError(t, afero.WriteFile(fs, "other.jwk", testEdDSAKey, 0644))
+
+	cmd := NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=signed.cbor", "--key=other.jwk", "--extract=corim-id"})
+	err := withDisplayOutput("out.txt", cmd.Execute)
+
+	assert.ErrorContains(t, err, "error verifying signed.cbor with key other.jwk")
+
+	// nothing is saved on error
+	_, err = fs.Stat("out.txt")
+	assert.Error(t, err)
+}
+
+func Test_CorimVerifyCmd_extract_bad_args(t *testing.T) {
+	cmd := NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=signed.cbor", "--key=key.jwk", "--extract=$comids"})
+	assert.EqualError(t, cmd.Execute(), `invalid --extract "$comids": unexpected 'c' at offset 1`)
+
+	cmd = NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--dir=signed", "--key=key.jwk", "--extract=corim-id"})
+	assert.EqualError(t, cmd.Execute(), "--extract can only be used together with --file (without --sequence) or --url")
+}
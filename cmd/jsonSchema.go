@@ -0,0 +1,139 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// jsonSchemaPrinter renders the validation errors
+var jsonSchemaPrinter = message.NewPrinter(language.English)
+
+// jsonSchema is a compiled JSON Schema.  Schemas that do not declare their
+// draft with $schema are taken to be draft 2020-12.  Annotations, including
+// format, are not asserted.
+type jsonSchema struct {
+	schema *jsonschema.Schema
+}
+
+// jsonSchemaLoader loads the schema documents referred to by $ref (e.g.,
+// "common.schema.json#/$defs/entity") from files, through fs.  Remote schemas
+// are never fetched.
+type jsonSchemaLoader struct{}
+
+func (jsonSchemaLoader) Load(url string) (any, error) {
+	file, err := jsonschema.FileLoader{}.ToFile(url)
+	if err != nil {
+		return nil, errors.New("only references to files are supported")
+	}
+
+	f, err := fs.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return jsonschema.UnmarshalJSON(f)
+}
+
+// compileJSONSchema compiles the JSON Schema in data, read from file, relative
+// to which the $ref to other schema files are resolved
+func compileJSONSchema(file string, data []byte) (*jsonSchema, error) {
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("error decoding JSON Schema: %w", err)
+	}
+
+	c := jsonschema.NewCompiler()
+	c.DefaultDraft(jsonschema.Draft2020)
+	c.UseLoader(jsonSchemaLoader{})
+
+	if err = c.AddResource(file, doc); err != nil {
+		return nil, err
+	}
+
+	s, err := c.Compile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return &jsonSchema{schema: s}, nil
+}
+
+// validate returns the violations of the JSON document in data, each
+// prefixed with the JSONPath of the offending value, sorted
+func (o *jsonSchema) validate(data []byte) ([]string, error) {
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	err = o.schema.Validate(doc)
+	if err == nil {
+		return nil, nil
+	}
+
+	var ve *jsonschema.ValidationError
+	if !errors.As(err, &ve) {
+		return nil, err
+	}
+
+	var violations []string
+
+	collectJSONSchemaViolations(ve, doc, &violations)
+
+	// the causes of a failed keyword are not in any particular order
+	sort.Strings(violations)
+
+	return violations, nil
+}
+
+// collectJSONSchemaViolations appends to out the leaves of the tree of
+// validation errors rooted at ve, i.e., the keywords that failed, rather than
+// the (sub)schemas holding them
+func collectJSONSchemaViolations(ve *jsonschema.ValidationError, doc any, out *[]string) {
+	if len(ve.Causes) == 0 {
+		*out = append(*out, instanceJSONPath(doc, ve.InstanceLocation).String()+": "+ve.ErrorKind.LocalizedString(jsonSchemaPrinter))
+		return
+	}
+
+	for _, cause := range ve.Causes {
+		collectJSONSchemaViolations(cause, doc, out)
+	}
+}
+
+// instanceJSONPath translates location, the JSON pointer tokens of a value in
+// doc, into the JSONPath of that value, telling array indexes from object
+// members by walking doc
+func instanceJSONPath(doc any, location []string) jsonPath {
+	path := jsonPath{}
+
+	for _, token := range location {
+		switch v := doc.(type) {
+		case []any:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(v) {
+				return append(path, jsonPathStep{member: token})
+			}
+
+			path = append(path, jsonPathStep{index: i, isIndex: true})
+			doc = v[i]
+		case map[string]any:
+			path = append(path, jsonPathStep{member: token})
+			doc = v[token]
+		default:
+			return append(path, jsonPathStep{member: token})
+		}
+	}
+
+	return path
+}
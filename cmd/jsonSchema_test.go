@@ -0,0 +1,190 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustValidateJSON(t *testing.T, schema, doc string) []string {
+	s, err := compileJSONSchema("schema.json", []byte(schema))
+	require.NoError(t, err)
+
+	violations, err := s.validate([]byte(doc))
+	require.NoError(t, err)
+
+	return violations
+}
+
+func Test_jsonSchema_violations(t *testing.T) {
+	tvs := []struct {
+		desc     string
+		schema   string
+		doc      string
+		expected []string
+	}{
+		{
+			desc:     "type",
+			schema:   `{"type": "object"}`,
+			doc:      `[]`,
+			expected: []string{"$: got array, want object"},
+		},
+		{
+			desc:   "integer is a number",
+			schema: `{"type": ["number", "null"]}`,
+			doc:    `3`,
+		},
+		{
+			desc:     "required and additional properties",
+			schema:   `{"required": ["a", "b"], "properties": {"a": {}}, "additionalProperties": false}`,
+			doc:      `{"a": 1, "c": 2}`,
+			expected: []string{"$: additional properties 'c' not allowed", "$: missing property 'b'"},
+		},
+		{
+			desc:     "array items",
+			schema:   `{"items": {"minLength": 2, "maxLength": 3, "pattern": "^[a-z]+$"}}`,
+			doc:      `["ab", "a", "abcd", "AB"]`,
+			expected: []string{"$[1]: minLength: got 1, want 2", "$[2]: maxLength: got 4, want 3", "$[3]: 'AB' does not match pattern '^[a-z]+$'"},
+		},
+		{
+			desc:     "member names needing brackets",
+			schema:   `{"properties": {"a.b": {"type": "string"}}}`,
+			doc:      `{"a.b": 1}`,
+			expected: []string{`$["a.b"]: got number, want string`},
+		},
+		{
+			desc:     "recursive ref",
+			schema:   `{"$defs": {"node": {"type": "object", "properties": {"next": {"$ref": "#/$defs/node"}}}}, "$ref": "#/$defs/node"}`,
+			doc:      `{"next": {"next": 1}}`,
+			expected: []string{"$.next.next: got number, want object"},
+		},
+		{
+			desc:     "unevaluated properties",
+			schema:   `{"properties": {"a": {}}, "unevaluatedProperties": false}`,
+			doc:      `{"a": 1, "b": 2}`,
+			expected: []string{"$.b: false schema"},
+		},
+		{
+			desc:   "format is an annotation",
+			schema: `{"$schema": "https://json-schema.org/draft/2020-12/schema", "title": "t", "format": "uuid"}`,
+			doc:    `"not-a-uuid"`,
+		},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.desc, func(t *testing.T) {
+			assert.Equal(t, tv.expected, mustValidateJSON(t, tv.schema, tv.doc))
+		})
+	}
+}
+
+func Test_compileJSONSchema_errors(t *testing.T) {
+	fs = afero.NewMemMapFs()
+
+	tvs := []struct {
+		schema   string
+		expected string
+	}{
+		{`{"type": "text"}`, "is not valid against metaschema"},
+		{`{"pattern": "("}`, "'(' is not valid regex"},
+		{`{"$ref": "https://example.com/schema.json"}`, `failing loading "https://example.com/schema.json": only references to files are supported`},
+		{`{"$ref": "#/$defs/missing"}`, "#/$defs/missing"},
+		{`{`, "error decoding JSON Schema"},
+	}
+
+	for _, tv := range tvs {
+		_, err := compileJSONSchema("schema.json", []byte(tv.schema))
+		assert.ErrorContains(t, err, tv.expected, tv.schema)
+	}
+}
+
+func Test_compileJSONSchema_file_ref(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/schemas/common.json", []byte(`{
+		"$defs": { "entity": { "required": ["regid"] } }
+	}`), 0644))
+
+	s, err := compileJSONSchema("/schemas/profile.json", []byte(`{"items": {"$ref": "common.json#/$defs/entity"}}`))
+	require.NoError(t, err)
+
+	violations, err := s.validate([]byte(`[{"regid": "x"}, {}]`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"$[1]: missing property 'regid'"}, violations)
+}
+
+// testCorimSchema requires a CoRIM profile and, for each CoMID, a tag id
+// and entities with a regid
+var testCorimSchema = []byte(`{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"type": "object",
+	"required": ["corim-id", "comids", "profile"],
+	"properties": {
+		"comids": {
+			"type": "array",
+			"minItems": 1,
+			"items": { "$ref": "#/$defs/comid" }
+		}
+	},
+	"$defs": {
+		"comid": {
+			"required": ["tag-identity", "entities"],
+			"properties": {
+				"tag-identity": { "required": ["id"] },
+				"entities": {
+					"type": "array",
+					"items": { "required": ["regid"] }
+				}
+			}
+		}
+	}
+}`)
+
+func Test_CorimValidateCmd_schema(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "corim.cbor", newTestUnsignedCorim(t, ""), 0644))
+	require.NoError(t, afero.WriteFile(fs, "profile-corim.cbor", newTestUnsignedCorim(t, "http://arm.com/psa/iot/1"), 0644))
+	require.NoError(t, afero.WriteFile(fs, "profile.schema.json", testCorimSchema, 0644))
+
+	cmd := NewCorimValidateCmd()
+	cmd.SetArgs([]string{"--file=profile-corim.cbor", "--schema=profile.schema.json"})
+	assert.NoError(t, cmd.Execute())
+
+	cmd = NewCorimValidateCmd()
+	cmd.SetArgs([]string{"--file=corim.cbor", "--schema=profile.schema.json"})
+	assert.EqualError(t, cmd.Execute(), "error validating CoRIM against JSON Schema profile.schema.json, 1 violation(s) found:\n"+
+		"  $: missing property 'profile'")
+}
+
+func Test_CorimValidateCmd_schema_meta(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "corim.cbor", newTestUnsignedCorim(t, ""), 0644))
+	require.NoError(t, afero.WriteFile(fs, "meta.json", testMetaValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "meta.schema.json", []byte(`{
+		"required": ["meta"],
+		"properties": { "meta": { "required": ["validity"] } }
+	}`), 0644))
+
+	cmd := NewCorimValidateCmd()
+	cmd.SetArgs([]string{"--file=corim.cbor", "--schema=meta.schema.json"})
+	assert.EqualError(t, cmd.Execute(), "error validating CoRIM against JSON Schema meta.schema.json, 1 violation(s) found:\n"+
+		"  $: missing property 'meta'")
+
+	cmd = NewCorimValidateCmd()
+	cmd.SetArgs([]string{"--file=corim.cbor", "--meta=meta.json", "--schema=meta.schema.json"})
+	assert.NoError(t, cmd.Execute())
+}
+
+func Test_CorimValidateCmd_schema_invalid(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "corim.cbor", newTestUnsignedCorim(t, ""), 0644))
+	require.NoError(t, afero.WriteFile(fs, "bad.schema.json", []byte(`{"minItems": -1}`), 0644))
+
+	cmd := NewCorimValidateCmd()
+	cmd.SetArgs([]string{"--file=corim.cbor", "--schema=bad.schema.json"})
+	assert.ErrorContains(t, cmd.Execute(), "error loading JSON Schema from bad.schema.json: ")
+}
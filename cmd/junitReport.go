@@ -0,0 +1,140 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/xml"
+	"fmt"
+
+	"github.com/spf13/afero"
+)
+
+// junitTestSuites is the root element of a JUnit XML report
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+// junitReport collects the per-file results of a batch command (e.g., "corim
+// verify --dir") as the test cases of a JUnit test suite.  A nil report
+// discards the results, so that commands can record them unconditionally.
+type junitReport struct {
+	suite junitTestSuite
+}
+
+// newJUnitReport returns a report for the suite named after the command (e.g.,
+// "corim verify") if file is set, nil otherwise
+func newJUnitReport(file, suite string) *junitReport {
+	if file == "" {
+		return nil
+	}
+
+	return &junitReport{suite: junitTestSuite{Name: suite}}
+}
+
+func (o *junitReport) add(tc junitTestCase) {
+	tc.Classname = o.suite.Name
+	o.suite.Cases = append(o.suite.Cases, tc)
+	o.suite.Tests++
+}
+
+// pass records name as a passed test case
+func (o *junitReport) pass(name string) {
+	if o == nil {
+		return
+	}
+
+	o.add(junitTestCase{Name: name})
+}
+
+// fail records name as a failed test case, with err as the failure message
+func (o *junitReport) fail(name string, err error) {
+	if o == nil {
+		return
+	}
+
+	o.add(junitTestCase{Name: name, Failure: &junitFailure{Message: err.Error(), Text: err.Error()}})
+	o.suite.Failures++
+}
+
+// skip records name as a skipped test case, for the given reason
+func (o *junitReport) skip(name, reason string) {
+	if o == nil {
+		return
+	}
+
+	o.add(junitTestCase{Name: name, Skipped: &junitSkipped{Message: reason}})
+	o.suite.Skipped++
+}
+
+// save writes the report to file in JUnit XML format
+func (o *junitReport) save(file string) error {
+	if o == nil {
+		return nil
+	}
+
+	report := junitTestSuites{
+		Tests:    o.suite.Tests,
+		Failures: o.suite.Failures,
+		Skipped:  o.suite.Skipped,
+		Suites:   []junitTestSuite{o.suite},
+	}
+
+	data, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding JUnit report: %w", err)
+	}
+
+	data = append([]byte(xml.Header), data...)
+	data = append(data, '\n')
+
+	if err = afero.WriteFile(fs, file, data, 0644); err != nil {
+		return fmt.Errorf("error saving JUnit report to %s: %w", file, err)
+	}
+
+	fmt.Printf(">> JUnit report saved to %q\n", file)
+
+	return nil
+}
+
+// withJUnitReport runs fn with a report for suite and, if file is set, saves
+// the report to file once fn is done, whether it succeeded or not
+func withJUnitReport(file, suite string, fn func(*junitReport) error) error {
+	report := newJUnitReport(file, suite)
+
+	fnErr := fn(report)
+
+	if err := report.save(file); err != nil && fnErr == nil {
+		return err
+	}
+
+	return fnErr
+}
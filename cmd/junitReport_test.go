@@ -0,0 +1,145 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/xml"
+	"errors"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func loadJUnitReport(t *testing.T, file string) junitTestSuites {
+	data, err := afero.ReadFile(fs, file)
+	require.NoError(t, err)
+
+	var report junitTestSuites
+	require.NoError(t, xml.Unmarshal(data, &report))
+	require.Len(t, report.Suites, 1)
+
+	return report
+}
+
+func Test_junitReport_nil(t *testing.T) {
+	fs = afero.NewMemMapFs()
+
+	report := newJUnitReport("", "corim verify")
+	assert.Nil(t, report)
+
+	report.pass("a.cbor")
+	report.fail("b.cbor", errors.New("bad signature"))
+	report.skip("c.cbor", "out of window")
+	assert.NoError(t, report.save(""))
+}
+
+func Test_junitReport_save(t *testing.T) {
+	fs = afero.NewMemMapFs()
+
+	report := newJUnitReport("report.xml", "comid validate")
+	report.pass("a.cbor")
+	report.fail("b.cbor", errors.New(`unexpected "<" & ">"`))
+	require.NoError(t, report.save("report.xml"))
+
+	data, err := afero.ReadFile(fs, "report.xml")
+	require.NoError(t, err)
+
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites tests="2" failures="1" skipped="0">
+  <testsuite name="comid validate" tests="2" failures="1" skipped="0">
+    <testcase name="a.cbor" classname="comid validate"></testcase>
+    <testcase name="b.cbor" classname="comid validate">
+      <failure message="unexpected &#34;&lt;&#34; &amp; &#34;&gt;&#34;">unexpected &#34;&lt;&#34; &amp; &#34;&gt;&#34;</failure>
+    </testcase>
+  </testsuite>
+</testsuites>
+`
+	assert.Equal(t, expected, string(data))
+}
+
+func Test_CorimVerifyCmd_dir_junit(t *testing.T) {
+	cmd := NewCorimVerifyCmd()
+	cmd.SetArgs([]string{
+		"--dir=archive",
+		"--key=ok.jwk",
+		"--junit=report.xml",
+	})
+
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "archive/a.cbor", testSignedCorimValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "archive/b.cbor", testSignedCorimInvalid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "ok.jwk", testECKey, 0644))
+
+	// the report is saved even though verification fails
+	assert.EqualError(t, cmd.Execute(), "1/2 verification(s) failed")
+
+	report := loadJUnitReport(t, "report.xml")
+	assert.Equal(t, 2, report.Tests)
+	assert.Equal(t, 1, report.Failures)
+
+	cases := report.Suites[0].Cases
+	require.Len(t, cases, 2)
+	assert.Equal(t, "archive/a.cbor", cases[0].Name)
+	assert.Equal(t, "corim verify", cases[0].Classname)
+	assert.Nil(t, cases[0].Failure)
+	assert.Equal(t, "archive/b.cbor", cases[1].Name)
+	require.NotNil(t, cases[1].Failure)
+	assert.Contains(t, cases[1].Failure.Message, "error decoding signed CoRIM from archive/b.cbor")
+}
+
+func Test_CorimVerifyCmd_dir_junit_skipped(t *testing.T) {
+	cmd := NewCorimVerifyCmd()
+
+	// testSignedCorimValid is valid from 2021-12-31 to 2025-12-31
+	cmd.SetArgs([]string{
+		"--dir=archive",
+		"--key=ok.jwk",
+		"--since=2026-01-01",
+		"--junit=report.xml",
+	})
+
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "archive/a.cbor", testSignedCorimValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "ok.jwk", testECKey, 0644))
+
+	require.NoError(t, cmd.Execute())
+
+	report := loadJUnitReport(t, "report.xml")
+	assert.Equal(t, 1, report.Skipped)
+	require.Len(t, report.Suites[0].Cases, 1)
+	require.NotNil(t, report.Suites[0].Cases[0].Skipped)
+	assert.Contains(t, report.Suites[0].Cases[0].Skipped.Message, "is outside of the window")
+}
+
+func Test_CorimVerifyCmd_junit_without_batch(t *testing.T) {
+	cmd := NewCorimVerifyCmd()
+	cmd.SetArgs([]string{
+		"--file=a.cbor",
+		"--key=ok.jwk",
+		"--junit=report.xml",
+	})
+
+	assert.EqualError(t, cmd.Execute(), "--junit can only be used together with --dir, --input-glob or --sequence")
+}
+
+func Test_ComidValidateCmd_junit(t *testing.T) {
+	cmd := NewComidValidateCmd()
+	cmd.SetArgs([]string{
+		"--dir=comids",
+		"--junit=report.xml",
+	})
+
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "comids/bad.cbor", []byte{0xff}, 0644))
+	require.NoError(t, afero.WriteFile(fs, "comids/ok.cbor", PSARefValCBOR, 0644))
+
+	assert.EqualError(t, cmd.Execute(), "1/2 validation(s) failed")
+
+	report := loadJUnitReport(t, "report.xml")
+	assert.Equal(t, 2, report.Tests)
+	assert.Equal(t, 1, report.Failures)
+	assert.Equal(t, "comid validate", report.Suites[0].Name)
+}
@@ -0,0 +1,112 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"unicode/utf8"
+
+	cose "github.com/veraison/go-cose"
+)
+
+// kidThumbprint is the special --kid value that stands for the JWK thumbprint
+// of the signing (or verification) key
+const kidThumbprint = "thumbprint"
+
+// jwkThumbprint returns the base64url-encoded SHA-256 JWK thumbprint of pk, as
+// defined in RFC 7638
+func jwkThumbprint(pk crypto.PublicKey) (string, error) {
+	b64 := base64.RawURLEncoding.EncodeToString
+
+	var members string
+
+	// the required members, in lexicographic order and without whitespace
+	switch k := pk.(type) {
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		members = fmt.Sprintf(`{"crv":%q,"kty":"EC","x":%q,"y":%q}`,
+			k.Curve.Params().Name, b64(k.X.FillBytes(make([]byte, size))), b64(k.Y.FillBytes(make([]byte, size))))
+	case ed25519.PublicKey:
+		members = fmt.Sprintf(`{"crv":"Ed25519","kty":"OKP","x":%q}`, b64(k))
+	case *rsa.PublicKey:
+		members = fmt.Sprintf(`{"e":%q,"kty":"RSA","n":%q}`,
+			b64(big.NewInt(int64(k.E)).Bytes()), b64(k.N.Bytes()))
+	default:
+		return "", fmt.Errorf("unsupported key type %T", pk)
+	}
+
+	sum := sha256.Sum256([]byte(members))
+
+	return b64(sum[:]), nil
+}
+
+// signingKeyID returns the COSE kid to use for kid, which is either taken
+// verbatim or, if it is kidThumbprint, derived from pk, the public part of the
+// signing key
+func signingKeyID(kid string, pk crypto.PublicKey) ([]byte, error) {
+	if kid != kidThumbprint {
+		return []byte(kid), nil
+	}
+
+	tp, err := jwkThumbprint(pk)
+	if err != nil {
+		return nil, fmt.Errorf("error computing JWK thumbprint: %w", err)
+	}
+
+	return []byte(tp), nil
+}
+
+// coseKeyID returns the kid header of msg, looking in the protected header
+// first
+func coseKeyID(msg *cose.Sign1Message) []byte {
+	for _, h := range []cose.ProtectedHeader{msg.Headers.Protected, cose.ProtectedHeader(msg.Headers.Unprotected)} {
+		if kid, ok := h[cose.HeaderLabelKeyID].([]byte); ok {
+			return kid
+		}
+	}
+
+	return nil
+}
+
+// formatKeyID renders kid as a quoted string if it is printable, else in hex
+func formatKeyID(kid []byte) string {
+	if utf8.Valid(kid) && !bytes.ContainsFunc(kid, func(r rune) bool { return r < 0x20 || r == 0x7f }) {
+		return fmt.Sprintf("%q", kid)
+	}
+
+	return fmt.Sprintf("%x", kid)
+}
+
+// checkKeyID checks that kid matches expected, which is either taken verbatim
+// or, if it is kidThumbprint, is the JWK thumbprint of the verification key pk
+func checkKeyID(kid []byte, expected string, pk crypto.PublicKey) error {
+	if kid == nil {
+		return errors.New("kid mismatch: no kid header found")
+	}
+
+	want := []byte(expected)
+
+	if expected == kidThumbprint {
+		tp, err := jwkThumbprint(pk)
+		if err != nil {
+			return fmt.Errorf("error computing JWK thumbprint of the verification key: %w", err)
+		}
+		want = []byte(tp)
+	}
+
+	if !bytes.Equal(kid, want) {
+		return fmt.Errorf("kid mismatch: expected %s, got %s", formatKeyID(want), formatKeyID(kid))
+	}
+
+	return nil
+}
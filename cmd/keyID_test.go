@@ -0,0 +1,202 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/corim"
+)
+
+// RFC 7638 thumbprints of testECKey and testEdDSAKey
+const (
+	testECKeyThumbprint    = "cn-I_WNMClehiVp51i_0VpOENW1upEerA8sEam5hn-s"
+	testEdDSAKeyThumbprint = "RBx2781Ag7Sd1vmuVbxpe0LzWT94pmB3GPtNx6m_gsQ"
+)
+
+// signTestCorimWithKeyID signs testCorimValid with testECKey into signed.cbor,
+// passing kid to --kid
+func signTestCorimWithKeyID(t *testing.T, kid string) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "unsigned.cbor", testCorimValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "meta.json", testMetaValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "key.jwk", testECKey, 0644))
+
+	cmd := NewCorimSignCmd()
+	cmd.SetArgs([]string{
+		"--file=unsigned.cbor",
+		"--meta=meta.json",
+		"--key=key.jwk",
+		"--output=signed.cbor",
+		"--kid=" + kid,
+	})
+	require.NoError(t, cmd.Execute())
+}
+
+func Test_jwkThumbprint(t *testing.T) {
+	pk, err := corim.NewPublicKeyFromJWK(testECKey)
+	require.NoError(t, err)
+
+	tp, err := jwkThumbprint(pk)
+	require.NoError(t, err)
+	assert.Equal(t, testECKeyThumbprint, tp)
+
+	pk, err = corim.NewPublicKeyFromJWK(testEdDSAKey)
+	require.NoError(t, err)
+
+	tp, err = jwkThumbprint(pk)
+	require.NoError(t, err)
+	assert.Equal(t, testEdDSAKeyThumbprint, tp)
+}
+
+func Test_jwkThumbprint_unsupported_key(t *testing.T) {
+	_, err := jwkThumbprint("not a key")
+	assert.EqualError(t, err, "unsupported key type string")
+}
+
+func Test_formatKeyID(t *testing.T) {
+	assert.Equal(t, `"acme-key-1"`, formatKeyID([]byte("acme-key-1")))
+	assert.Equal(t, "00ff10", formatKeyID([]byte{0x00, 0xff, 0x10}))
+}
+
+func Test_CorimSignCmd_kid_thumbprint(t *testing.T) {
+	signTestCorimWithKeyID(t, "thumbprint")
+
+	data, err := afero.ReadFile(fs, "signed.cbor")
+	require.NoError(t, err)
+
+	msg, err := decodeSign1(data)
+	require.NoError(t, err)
+	assert.Equal(t, []byte(testECKeyThumbprint), coseKeyID(msg))
+
+	// the CoRIM Meta is still there
+	var s corim.SignedCorim
+	require.NoError(t, s.FromCOSE(data))
+	assert.NotEmpty(t, s.Meta.Signer.Name)
+}
+
+func Test_CorimSignCmd_kid_verbatim(t *testing.T) {
+	signTestCorimWithKeyID(t, "acme-key-1")
+
+	data, err := afero.ReadFile(fs, "signed.cbor")
+	require.NoError(t, err)
+
+	msg, err := decodeSign1(data)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("acme-key-1"), coseKeyID(msg))
+}
+
+func Test_CorimVerifyCmd_expected_kid(t *testing.T) {
+	tvs := []struct {
+		desc     string
+		kid      string
+		expected string
+		err      string
+	}{
+		{
+			desc:     "thumbprint",
+			kid:      "thumbprint",
+			expected: "thumbprint",
+		},
+		{
+			desc:     "thumbprint supplied verbatim",
+			kid:      "thumbprint",
+			expected: testECKeyThumbprint,
+		},
+		{
+			desc:     "verbatim",
+			kid:      "acme-key-1",
+			expected: "acme-key-1",
+		},
+		{
+			desc:     "not the thumbprint",
+			kid:      "acme-key-1",
+			expected: "thumbprint",
+			err:      `error verifying signed.cbor: kid mismatch: expected "` + testECKeyThumbprint + `", got "acme-key-1"`,
+		},
+		{
+			desc:     "mismatch",
+			kid:      "thumbprint",
+			expected: "acme-key-1",
+			err:      `error verifying signed.cbor: kid mismatch: expected "acme-key-1", got "` + testECKeyThumbprint + `"`,
+		},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.desc, func(t *testing.T) {
+			signTestCorimWithKeyID(t, tv.kid)
+
+			cmd := NewCorimVerifyCmd()
+			cmd.SetArgs([]string{
+				"--file=signed.cbor",
+				"--key=key.jwk",
+				"--expected-kid=" + tv.expected,
+			})
+
+			err := cmd.Execute()
+			if tv.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tv.err)
+			}
+		})
+	}
+}
+
+func Test_CorimVerifyCmd_expected_kid_missing(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "unsigned.cbor", testCorimValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "meta.json", testMetaValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "key.jwk", testECKey, 0644))
+
+	cmd := NewCorimSignCmd()
+	cmd.SetArgs([]string{
+		"--file=unsigned.cbor",
+		"--meta=meta.json",
+		"--key=key.jwk",
+		"--output=signed.cbor",
+	})
+	require.NoError(t, cmd.Execute())
+
+	cmd = NewCorimVerifyCmd()
+	cmd.SetArgs([]string{
+		"--file=signed.cbor",
+		"--key=key.jwk",
+		"--expected-kid=thumbprint",
+	})
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "error verifying signed.cbor: kid mismatch: no kid header found")
+}
+
+func Test_CorimVerifyCmd_expected_kid_with_quorum(t *testing.T) {
+	cmd := NewCorimVerifyCmd()
+	cmd.SetArgs([]string{
+		"--file=multi.cbor",
+		"--quorum=1",
+		"--quorum-key=a.jwk",
+		"--expected-kid=thumbprint",
+	})
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "--quorum can only be combined with --expected-id, --expected-profile, --require-profile-in, --allowed-algs, --trust-anchor, --system-roots and --ignore-key-usage")
+}
+
+func Test_CorimDisplayCmd_kid(t *testing.T) {
+	signTestCorimWithKeyID(t, "thumbprint")
+
+	cmd := NewCorimDisplayCmd()
+	cmd.SetArgs([]string{
+		"--file=signed.cbor",
+		"--output=signed.txt",
+	})
+	require.NoError(t, cmd.Execute())
+
+	out, err := afero.ReadFile(fs, "signed.txt")
+	require.NoError(t, err)
+	assert.Contains(t, string(out), `Key ID: "`+testECKeyThumbprint+`"`)
+}
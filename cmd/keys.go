@@ -0,0 +1,60 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+
+	"github.com/veraison/corim/corim"
+)
+
+// parsePublicKey decodes a verification key, sniffing its format from the
+// content of data.  The supported formats are: JWK; X.509 certificate, in
+// which case the certified public key is returned; and (raw) SubjectPublicKeyInfo.
+// Both certificates and SubjectPublicKeyInfo can be DER or PEM encoded.
+func parsePublicKey(data []byte) (crypto.PublicKey, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return corim.NewPublicKeyFromJWK(data)
+	}
+
+	if !bytes.Contains(data, []byte("-----BEGIN")) {
+		if cert, err := x509.ParseCertificate(data); err == nil {
+			return cert.PublicKey, nil
+		}
+
+		if pk, err := x509.ParsePKIXPublicKey(data); err == nil {
+			return pk, nil
+		}
+
+		return nil, errors.New(
+			"unrecognized key format: expecting a JWK, an X.509 certificate or a SubjectPublicKeyInfo",
+		)
+	}
+
+	for {
+		var block *pem.Block
+
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		switch block.Type {
+		case "PUBLIC KEY":
+			return x509.ParsePKIXPublicKey(block.Bytes)
+		case "CERTIFICATE":
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			return cert.PublicKey, nil
+		}
+	}
+
+	return nil, errors.New("no PEM-encoded public key or certificate found")
+}
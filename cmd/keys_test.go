@@ -0,0 +1,107 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/corim"
+)
+
+// testECKeySPKI returns the DER-encoded SubjectPublicKeyInfo of testECKey
+func testECKeySPKI(t *testing.T) []byte {
+	pk, err := corim.NewPublicKeyFromJWK(testECKey)
+	require.NoError(t, err)
+
+	der, err := x509.MarshalPKIXPublicKey(pk)
+	require.NoError(t, err)
+
+	return der
+}
+
+func Test_parsePublicKey(t *testing.T) {
+	expected, err := corim.NewPublicKeyFromJWK(testECKey)
+	require.NoError(t, err)
+
+	spki := testECKeySPKI(t)
+
+	cert, err := x509.ParseCertificate(testSigningCertificate)
+	require.NoError(t, err)
+
+	tvs := []struct {
+		desc     string
+		data     []byte
+		expected interface{}
+	}{
+		{"JWK", testECKey, expected},
+		{"DER SubjectPublicKeyInfo", spki, expected},
+		{"PEM SubjectPublicKeyInfo", pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: spki}), expected},
+		{"DER certificate", testSigningCertificate, cert.PublicKey},
+		{
+			"PEM certificate",
+			pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: testSigningCertificate}),
+			cert.PublicKey,
+		},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.desc, func(t *testing.T) {
+			actual, err := parsePublicKey(tv.data)
+			require.NoError(t, err)
+			assert.Equal(t, tv.expected, actual)
+		})
+	}
+}
+
+func Test_parsePublicKey_bad(t *testing.T) {
+	_, err := parsePublicKey([]byte{0x01, 0x02, 0x03})
+	assert.EqualError(t, err, "unrecognized key format: expecting a JWK, an X.509 certificate or a SubjectPublicKeyInfo")
+
+	_, err = parsePublicKey(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte{0x01}}))
+	assert.EqualError(t, err, "no PEM-encoded public key or certificate found")
+}
+
+func Test_CorimVerifyCmd_ok_with_pem_public_key(t *testing.T) {
+	cmd := NewCorimVerifyCmd()
+
+	args := []string{
+		"--file=ok.cbor",
+		"--key=ok.pem",
+	}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "ok.cbor", testSignedCorimValid, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ok.pem",
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: testECKeySPKI(t)}), 0644)
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	assert.NoError(t, err)
+}
+
+func Test_CorimVerifyCmd_ok_with_der_public_key(t *testing.T) {
+	cmd := NewCorimVerifyCmd()
+
+	args := []string{
+		"--file=ok.cbor",
+		"--key=ok.der",
+	}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "ok.cbor", testSignedCorimValid, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ok.der", testECKeySPKI(t), 0644)
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	assert.NoError(t, err)
+}
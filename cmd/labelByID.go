@@ -0,0 +1,113 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/afero"
+	"github.com/veraison/corim/corim"
+)
+
+const (
+	// maxIDLabelLength is the longest CoRIM id used verbatim as a file name
+	maxIDLabelLength = 64
+	// idLabelHashLength is the number of hex digits of the SHA-256 of the
+	// CoRIM id appended to the labels of ids that are not file-name-safe
+	idLabelHashLength = 8
+)
+
+// isIDLabelChar tells whether r can appear in a file name derived from a CoRIM
+// id, whatever the platform
+func isIDLabelChar(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') ||
+		r == '-' || r == '_' || r == '.'
+}
+
+// corimIDLabel returns the base name (without extension) of the file a CoRIM
+// with the supplied id is saved to by "corim sign --label-output-by-id".
+// UUIDs, and string ids made of letters, digits, '-', '_' and '.' only, are
+// used verbatim.  Other ids are sanitized (unsafe characters are replaced by
+// '_', hidden-file dots are dropped and the result is truncated) and suffixed
+// with part of their SHA-256, so that distinct ids keep distinct labels.
+func corimIDLabel(id string) string {
+	safe := id != "" && len(id) <= maxIDLabelLength && !strings.HasPrefix(id, ".")
+	for _, r := range id {
+		if !isIDLabelChar(r) {
+			safe = false
+			break
+		}
+	}
+
+	if safe {
+		return id
+	}
+
+	sanitized := strings.Map(func(r rune) rune {
+		if isIDLabelChar(r) {
+			return r
+		}
+		return '_'
+	}, id)
+
+	sanitized = strings.TrimLeft(sanitized, ".")
+	if len(sanitized) > maxIDLabelLength {
+		sanitized = sanitized[:maxIDLabelLength]
+	}
+
+	sum := sha256.Sum256([]byte(id))
+	hash := hex.EncodeToString(sum[:])[:idLabelHashLength]
+
+	if sanitized == "" {
+		return hash
+	}
+
+	return sanitized + "-" + hash
+}
+
+// idOutputFileName returns the id of the unsigned CoRIM in unsignedCorimFile,
+// together with the name of the file in outputDir its signed version is saved
+// to with --label-output-by-id
+func idOutputFileName(unsignedCorimFile, outputDir string) (string, string, error) {
+	data, err := afero.ReadFile(fs, unsignedCorimFile)
+	if err != nil {
+		return "", "", fmt.Errorf("error loading unsigned CoRIM from %s: %w", unsignedCorimFile, err)
+	}
+
+	u := corim.GetUnsignedCorim(cborProfile(data))
+	if err = u.FromCBOR(data); err != nil {
+		return "", "", fmt.Errorf("error decoding unsigned CoRIM from %s: %w", unsignedCorimFile, err)
+	}
+
+	id := u.ID.String()
+
+	return id, filepath.Join(outputDir, corimIDLabel(id)+".cbor"), nil
+}
+
+// idLabelCollision describes what is wrong with overwriting file with the
+// signed CoRIM with the supplied id, if file already holds something else,
+// e.g., a CoRIM with a different id that has the same label.  It returns an
+// empty string if file does not exist, or holds a CoRIM with the same id.
+func idLabelCollision(file, id string) string {
+	data, err := afero.ReadFile(fs, file)
+	if err != nil {
+		// nothing to overwrite
+		return ""
+	}
+
+	s, err := corim.UnmarshalSignedCorimFromCBOR(tagSign1(data))
+	if err != nil {
+		return fmt.Sprintf("overwriting %q, which does not hold a signed CoRIM", file)
+	}
+
+	if other := s.UnsignedCorim.ID.String(); other != id {
+		return fmt.Sprintf("overwriting %q, which holds the CoRIM with id %q (label collision with id %q)", file, other, id)
+	}
+
+	return ""
+}
@@ -0,0 +1,105 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/corim"
+)
+
+func Test_corimIDLabel(t *testing.T) {
+	// used verbatim
+	for _, id := range []string{
+		"5c57e8f4-46cd-421b-91c9-08cf93e13cfc",
+		"acme-fw_1.2",
+		strings.Repeat("a", maxIDLabelLength),
+	} {
+		assert.Equal(t, id, corimIDLabel(id))
+	}
+
+	// sanitized and hashed
+	assert.Regexp(t, `^acme_fw_1.2-[0-9a-f]{8}$`, corimIDLabel("acme/fw 1.2"))
+	assert.Regexp(t, `^_passwd-[0-9a-f]{8}$`, corimIDLabel("../passwd"))
+	assert.Regexp(t, `^hidden-[0-9a-f]{8}$`, corimIDLabel(".hidden"))
+	assert.Regexp(t, `^[0-9a-f]{8}$`, corimIDLabel(".."))
+	assert.Regexp(t, `^a{64}-[0-9a-f]{8}$`, corimIDLabel(strings.Repeat("a", maxIDLabelLength+1)))
+
+	// distinct ids keep distinct labels
+	assert.NotEqual(t, corimIDLabel("a/b"), corimIDLabel("a_b"))
+	assert.NotEqual(t, corimIDLabel("a/b"), corimIDLabel("a:b"))
+}
+
+func Test_CorimSignCmd_label_output_by_id(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "ok.cbor", testCorimValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "ok.json", testMetaValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "ok.jwk", testECKey, 0644))
+
+	id, name, err := idOutputFileName("ok.cbor", "store")
+	require.NoError(t, err)
+	assert.Equal(t, "store/"+corimIDLabel(id)+".cbor", name)
+
+	cmd := NewCorimSignCmd()
+	cmd.SetArgs([]string{"--file=ok.cbor", "--key=ok.jwk", "--meta=ok.json", "--label-output-by-id", "--output-dir=store"})
+	require.NoError(t, cmd.Execute())
+
+	data, err := afero.ReadFile(fs, name)
+	require.NoError(t, err)
+
+	s, err := corim.UnmarshalSignedCorimFromCBOR(data)
+	require.NoError(t, err)
+	assert.Equal(t, id, s.UnsignedCorim.ID.String())
+
+	_, err = fs.Stat("signed-ok.cbor")
+	assert.Error(t, err)
+
+	// signing the same CoRIM again is not a collision
+	assert.Empty(t, idLabelCollision(name, id))
+}
+
+func Test_idLabelCollision(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "other.cbor", testSignedCorimValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "junk.cbor", []byte("junk"), 0644))
+
+	assert.Empty(t, idLabelCollision("missing.cbor", "a/b"))
+	assert.Empty(t, idLabelCollision("other.cbor", "5c57e8f4-46cd-421b-91c9-08cf93e13cfc"))
+	assert.Equal(t,
+		`overwriting "other.cbor", which holds the CoRIM with id "5c57e8f4-46cd-421b-91c9-08cf93e13cfc" (label collision with id "a/b")`,
+		idLabelCollision("other.cbor", "a/b"))
+	assert.Equal(t, `overwriting "junk.cbor", which does not hold a signed CoRIM`, idLabelCollision("junk.cbor", "a/b"))
+}
+
+func Test_CorimSignCmd_label_output_by_id_bad_args(t *testing.T) {
+	tvs := []struct {
+		desc     string
+		args     []string
+		expected string
+	}{
+		{
+			desc:     "label and output",
+			args:     []string{"--file=ok.cbor", "--key=ok.jwk", "--meta=ok.json", "--label-output-by-id", "--output=signed.cbor"},
+			expected: "--label-output-by-id cannot be used together with --output",
+		},
+		{
+			desc:     "output dir without label",
+			args:     []string{"--file=ok.cbor", "--key=ok.jwk", "--meta=ok.json", "--output-dir=store"},
+			expected: "--output-dir can only be used together with --label-output-by-id",
+		},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.desc, func(t *testing.T) {
+			cmd := NewCorimSignCmd()
+			cmd.SetArgs(tv.args)
+
+			assert.EqualError(t, cmd.Execute(), tv.expected)
+		})
+	}
+}
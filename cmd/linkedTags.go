@@ -0,0 +1,44 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/veraison/corim/comid"
+)
+
+// linkedTagView is the rendering of one linked tag of a CoMID, used by "comid
+// display --links".  It reads as "source rel target", e.g., "acme-fw-2
+// replaces acme-fw-1".
+type linkedTagView struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Rel    string `json:"rel"`
+}
+
+// linkedTags returns the linked tags of c, in the order in which they appear
+func linkedTags(c *comid.Comid) []linkedTagView {
+	views := []linkedTagView{}
+
+	if c.LinkedTags == nil {
+		return views
+	}
+
+	source := c.TagIdentity.TagID.String()
+
+	for _, lt := range *c.LinkedTags {
+		views = append(views, linkedTagView{
+			Source: source,
+			Target: lt.LinkedTagID.String(),
+			Rel:    lt.Rel.String(),
+		})
+	}
+
+	return views
+}
+
+func (o linkedTagView) String() string {
+	return fmt.Sprintf("%s %s %s", o.Source, o.Rel, o.Target)
+}
@@ -0,0 +1,95 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/comid"
+)
+
+func newTestComidLinkedTags(t *testing.T) *comid.Comid {
+	c := newTestComidRawValues(t)
+
+	require.NotNil(t, c.AddLinkedTag("acme-fw-1", comid.RelReplaces))
+	require.NotNil(t, c.AddLinkedTag("acme-base", comid.RelSupplements))
+
+	return c
+}
+
+func Test_linkedTags(t *testing.T) {
+	views := linkedTags(newTestComidLinkedTags(t))
+
+	require.Len(t, views, 2)
+	assert.Equal(t, "43bbe37f-2e61-4b33-aed3-53cff1428b16 replaces acme-fw-1", views[0].String())
+	assert.Equal(t, "43bbe37f-2e61-4b33-aed3-53cff1428b16 supplements acme-base", views[1].String())
+}
+
+func Test_linkedTags_none(t *testing.T) {
+	assert.Empty(t, linkedTags(newTestComidRawValues(t)))
+}
+
+func Test_ComidDisplayCmd_links_ok(t *testing.T) {
+	data, err := newTestComidLinkedTags(t).ToCBOR()
+	require.NoError(t, err)
+
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "linked.cbor", data, 0644))
+
+	for _, format := range []string{"--json=false", "--json"} {
+		cmd := NewComidDisplayCmd()
+		cmd.SetArgs([]string{
+			"--file=linked.cbor",
+			"--links",
+			"--output=out.txt",
+			format,
+		})
+
+		require.NoError(t, cmd.Execute())
+
+		out := mustReadFile(t, "out.txt")
+
+		if format == "--json" {
+			var views []linkedTagView
+			// skip the ">> [linked.cbor]" heading
+			j := out[len(">> [linked.cbor]\n"):]
+			require.NoError(t, json.Unmarshal(j, &views))
+			require.Len(t, views, 2)
+			assert.Equal(t, "acme-fw-1", views[0].Target)
+			assert.Equal(t, "replaces", views[0].Rel)
+		} else {
+			assert.Contains(t, string(out), " replaces acme-fw-1\n")
+			assert.Contains(t, string(out), " supplements acme-base\n")
+		}
+	}
+}
+
+func Test_ComidDisplayCmd_links_none(t *testing.T) {
+	data, err := newTestComidRawValues(t).ToCBOR()
+	require.NoError(t, err)
+
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "plain.cbor", data, 0644))
+
+	cmd := NewComidDisplayCmd()
+	cmd.SetArgs([]string{"--file=plain.cbor", "--links", "--output=out.txt"})
+	require.NoError(t, cmd.Execute())
+	assert.Equal(t, ">> [plain.cbor]\nno linked tags\n", string(mustReadFile(t, "out.txt")))
+
+	cmd = NewComidDisplayCmd()
+	cmd.SetArgs([]string{"--file=plain.cbor", "--links", "--json", "--output=out.txt"})
+	require.NoError(t, cmd.Execute())
+	assert.Equal(t, ">> [plain.cbor]\n[]\n", string(mustReadFile(t, "out.txt")))
+}
+
+func Test_ComidDisplayCmd_links_with_raw_values(t *testing.T) {
+	cmd := NewComidDisplayCmd()
+	cmd.SetArgs([]string{"--file=ok.cbor", "--links", "--raw-values"})
+
+	assert.EqualError(t, cmd.Execute(), "--links cannot be used together with --verification-keys or --raw-values")
+}
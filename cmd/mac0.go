@@ -0,0 +1,302 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"hash"
+	"sort"
+	"strings"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/spf13/afero"
+	"github.com/veraison/corim/corim"
+	cose "github.com/veraison/go-cose"
+)
+
+const (
+	// coseMac0Tag is the COSE_Mac0 CBOR tag
+	coseMac0Tag = 17
+	// cborTagMac0 is the encoding of the COSE_Mac0 CBOR tag (17)
+	cborTagMac0 = 0xd1
+)
+
+// macAlgorithm is a COSE MAC algorithm
+type macAlgorithm struct {
+	name string
+	hash func() hash.Hash
+	// length of the (possibly truncated) MAC tag, in bytes
+	size int
+}
+
+// macAlgorithms are the COSE HMAC algorithms (RFC 9053, Section 3.1), keyed by
+// their COSE algorithm identifier.  The AES-MAC ones are not supported.
+var macAlgorithms = map[int64]macAlgorithm{
+	4: {name: "HMAC 256/64", hash: sha256.New, size: 8},
+	5: {name: "HMAC 256/256", hash: sha256.New, size: sha256.Size},
+	6: {name: "HMAC 384/384", hash: sha512.New384, size: sha512.Size384},
+	7: {name: "HMAC 512/512", hash: sha512.New, size: sha512.Size},
+}
+
+// macAlgorithmNames returns the names of the supported MAC algorithms, sorted
+func macAlgorithmNames() string {
+	var names []string
+	for _, alg := range macAlgorithms {
+		names = append(names, alg.name)
+	}
+
+	sort.Strings(names)
+
+	return strings.Join(names, ", ")
+}
+
+// mac0Message is a COSE_Mac0 message (RFC 9052, Section 6.2)
+type mac0Message struct {
+	_           struct{} `cbor:",toarray"`
+	Protected   []byte
+	Unprotected cbor.RawMessage
+	Payload     []byte
+	Tag         []byte
+}
+
+// mac0Headers are the COSE header parameters of a Mac0-protected CoRIM that
+// cocli makes use of
+type mac0Headers struct {
+	Alg       interface{}     `cbor:"1,keyasint,omitempty"`
+	KeyID     []byte          `cbor:"4,keyasint,omitempty"`
+	CorimMeta cbor.RawMessage `cbor:"8,keyasint,omitempty"`
+}
+
+// mac0Corim is a decoded Mac0-protected CoRIM
+type mac0Corim struct {
+	msg      mac0Message
+	alg      macAlgorithm
+	kid      []byte
+	meta     *corim.Meta
+	unsigned *corim.UnsignedCorim
+}
+
+// isMac0 tells whether buf looks like a COSE_Mac0 message.  Unlike COSE_Sign1,
+// an untagged COSE_Mac0 cannot be told apart from other COSE messages, hence
+// only the tagged form is recognized.
+func isMac0(buf []byte) bool {
+	return len(buf) != 0 && buf[0] == cborTagMac0
+}
+
+// decodeMac0Corim decodes the (tagged) COSE_Mac0 in buf and the unsigned CoRIM
+// it carries, without verifying the MAC.  If strict, fields of the unsigned
+// CoRIM that are not understood are rejected.
+func decodeMac0Corim(buf []byte, strict bool) (*mac0Corim, error) {
+	var tag cbor.RawTag
+	if err := cbor.Unmarshal(buf, &tag); err != nil {
+		return nil, fmt.Errorf("decoding COSE_Mac0: %w", err)
+	}
+
+	if tag.Number != coseMac0Tag {
+		return nil, fmt.Errorf("decoding COSE_Mac0: unexpected CBOR tag %d", tag.Number)
+	}
+
+	var c mac0Corim
+
+	if err := cbor.Unmarshal(tag.Content, &c.msg); err != nil {
+		return nil, fmt.Errorf("decoding COSE_Mac0: %w", err)
+	}
+
+	if c.msg.Payload == nil {
+		return nil, errors.New("decoding COSE_Mac0: detached payloads are not supported")
+	}
+
+	if err := checkMac0CriticalHeaders(c.msg.Protected); err != nil {
+		return nil, err
+	}
+
+	var protected, unprotected mac0Headers
+
+	if len(c.msg.Protected) != 0 {
+		if err := cbor.Unmarshal(c.msg.Protected, &protected); err != nil {
+			return nil, fmt.Errorf("decoding COSE_Mac0 protected header: %w", err)
+		}
+	}
+
+	if err := cbor.Unmarshal(c.msg.Unprotected, &unprotected); err != nil {
+		return nil, fmt.Errorf("decoding COSE_Mac0 unprotected header: %w", err)
+	}
+
+	if protected.Alg == nil {
+		return nil, errors.New("alg header: missing from the protected header")
+	}
+
+	alg, err := macAlgorithmOf(protected.Alg)
+	if err != nil {
+		return nil, err
+	}
+
+	c.alg = alg
+
+	c.kid = protected.KeyID
+	if c.kid == nil {
+		c.kid = unprotected.KeyID
+	}
+
+	if protected.CorimMeta != nil {
+		c.meta = &corim.Meta{}
+		if err = c.meta.FromCBOR(protected.CorimMeta); err != nil {
+			return nil, fmt.Errorf("decoding CoRIM Meta: %w", err)
+		}
+	}
+
+	c.unsigned = corim.GetUnsignedCorim(cborProfile(c.msg.Payload))
+	if err = decodeCBOR(c.unsigned, c.msg.Payload, strict); err != nil {
+		return nil, fmt.Errorf("decoding unsigned CoRIM: %w", err)
+	}
+
+	return &c, nil
+}
+
+// checkMac0CriticalHeaders makes sure that each header listed as critical in
+// the encoded protected header of a COSE_Mac0 is one that cocli understands, as
+// checkCOSEHeaders does for COSE_Sign1
+func checkMac0CriticalHeaders(encoded []byte) error {
+	// ProtectedHeader decodes the bstr wrapping the header map
+	wrapped, err := cbor.Marshal(encoded)
+	if err != nil {
+		return fmt.Errorf("decoding COSE_Mac0 protected header: %w", err)
+	}
+
+	var p cose.ProtectedHeader
+	if err = p.UnmarshalCBOR(wrapped); err != nil {
+		return fmt.Errorf("decoding COSE_Mac0 protected header: %w", err)
+	}
+
+	return checkCriticalHeaders(p)
+}
+
+// macAlgorithmOf returns the MAC algorithm identified by the alg header v
+func macAlgorithmOf(v interface{}) (macAlgorithm, error) {
+	var id int64
+
+	switch t := v.(type) {
+	case uint64:
+		id = int64(t)
+	case int64:
+		id = t
+	default:
+		return macAlgorithm{}, fmt.Errorf("alg header: unsupported MAC algorithm %v", v)
+	}
+
+	alg, ok := macAlgorithms[id]
+	if !ok {
+		return macAlgorithm{}, fmt.Errorf("alg header: unsupported MAC algorithm %d, expecting one of: %s", id, macAlgorithmNames())
+	}
+
+	return alg, nil
+}
+
+// macStructure returns the MAC_structure (RFC 9052, Section 6.3) the tag of msg
+// is computed over, with no external data
+func (o mac0Message) macStructure() ([]byte, error) {
+	return cbor.Marshal([]interface{}{"MAC0", o.Protected, []byte{}, o.Payload})
+}
+
+// computeMac0Tag returns the tag of msg under key, using alg
+func computeMac0Tag(msg mac0Message, alg macAlgorithm, key []byte) ([]byte, error) {
+	toBeMACed, err := msg.macStructure()
+	if err != nil {
+		return nil, fmt.Errorf("encoding MAC_structure: %w", err)
+	}
+
+	h := hmac.New(alg.hash, key)
+	h.Write(toBeMACed)
+
+	return h.Sum(nil)[:alg.size], nil
+}
+
+// verify checks the MAC of the Mac0-protected CoRIM with key
+func (o *mac0Corim) verify(key []byte) error {
+	expected, err := computeMac0Tag(o.msg, o.alg, key)
+	if err != nil {
+		return err
+	}
+
+	if !hmac.Equal(expected, o.msg.Tag) {
+		return errors.New("MAC verification failed")
+	}
+
+	return nil
+}
+
+// loadMacKey loads the symmetric key in file, in JWK format (kty "oct")
+func loadMacKey(file string) ([]byte, error) {
+	data, err := afero.ReadFile(fs, file)
+	if err != nil {
+		return nil, fmt.Errorf("error loading MAC key from %s: %w", file, err)
+	}
+
+	k, err := jwk.ParseKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("error loading MAC key from %s: %w", file, err)
+	}
+
+	sk, ok := k.(jwk.SymmetricKey)
+	if !ok {
+		return nil, fmt.Errorf("error loading MAC key from %s: expecting a symmetric (kty \"oct\") JWK, got kty %q", file, k.KeyType())
+	}
+
+	if len(sk.Octets()) == 0 {
+		return nil, fmt.Errorf("error loading MAC key from %s: empty key", file)
+	}
+
+	return sk.Octets(), nil
+}
+
+// verifyMac0 verifies the MAC of the Mac0-protected CoRIM in mac0CorimFile
+// with the symmetric key in macKeyFile, then checks the CoRIM against opts
+func verifyMac0(mac0CorimFile, macKeyFile string, opts verifyOptions) error {
+	data, err := afero.ReadFile(fs, mac0CorimFile)
+	if err != nil {
+		return fmt.Errorf("error loading MAC-protected CoRIM from %s: %w", mac0CorimFile, err)
+	}
+
+	c, err := decodeMac0Corim(data, opts.strictDecode)
+	if err != nil {
+		return fmt.Errorf("error decoding MAC-protected CoRIM from %s: %w", mac0CorimFile, err)
+	}
+
+	if err = opts.profileAllowlist.check(*c.unsigned); err != nil {
+		return fmt.Errorf("error verifying %s: %w", mac0CorimFile, err)
+	}
+
+	key, err := loadMacKey(macKeyFile)
+	if err != nil {
+		return err
+	}
+
+	if err = c.verify(key); err != nil {
+		return fmt.Errorf("error verifying %s with MAC key %s: %w", mac0CorimFile, macKeyFile, err)
+	}
+
+	progressf(opts.quiet(), ">> MAC (%s) verified with %s: the CoRIM was produced by a holder of the shared key, it is not signed\n",
+		c.alg.name, macKeyFile)
+
+	if err = checkCorimExpectations(*c.unsigned, opts.expectedID, opts.expectedProfile); err != nil {
+		return fmt.Errorf("error verifying %s: %w", mac0CorimFile, err)
+	}
+
+	if opts.validateTags {
+		if err = checkCorimTags(*c.unsigned); err != nil {
+			return fmt.Errorf("error verifying %s: %w", mac0CorimFile, err)
+		}
+	}
+
+	if opts.stats != "" {
+		return reportCorimStats(*c.unsigned, mac0CorimFile, opts.stats)
+	}
+
+	return nil
+}
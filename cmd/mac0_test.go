@@ -0,0 +1,188 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testMacKey      = []byte(`{"kty":"oct","k":"hJtXIZ2uSN5kbQfbtTNWbpdmhkV8FJG-Onbc6mxCcYg"}`)
+	testOtherMacKey = []byte(`{"kty":"oct","k":"c2VjcmV0LWtleS10aGF0LWlzLW5vdC10aGUtcmlnaHQtb25l"}`)
+)
+
+// newTestMac0Corim returns testCorimValid protected by a COSE Mac0 with the
+// supplied COSE algorithm and testMacKey
+func newTestMac0Corim(t *testing.T, algID int64) []byte {
+	return newTestMac0CorimWithProtected(t, map[int]interface{}{1: algID})
+}
+
+// newTestMac0CorimWithProtected returns a Mac0-protected testCorimValid with
+// the protected header h, MACed with testMacKey if the alg header of h is
+// supported
+func newTestMac0CorimWithProtected(t *testing.T, h map[int]interface{}) []byte {
+	protected, err := cbor.Marshal(h)
+	require.NoError(t, err)
+
+	algID, _ := h[1].(int64)
+
+	unprotected, err := cbor.Marshal(map[int]interface{}{4: []byte("mac-key-1")})
+	require.NoError(t, err)
+
+	msg := mac0Message{
+		Protected:   protected,
+		Unprotected: unprotected,
+		Payload:     testCorimValid,
+	}
+
+	if alg, ok := macAlgorithms[algID]; ok {
+		key, err := loadMacKeyData(t, testMacKey)
+		require.NoError(t, err)
+
+		msg.Tag, err = computeMac0Tag(msg, alg, key)
+		require.NoError(t, err)
+	} else {
+		msg.Tag = []byte{0x00}
+	}
+
+	data, err := cbor.Marshal(cbor.Tag{Number: coseMac0Tag, Content: msg})
+	require.NoError(t, err)
+
+	return data
+}
+
+func loadMacKeyData(t *testing.T, jwk []byte) ([]byte, error) {
+	saved := fs
+	defer func() { fs = saved }()
+
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "mac.jwk", jwk, 0644))
+
+	return loadMacKey("mac.jwk")
+}
+
+func Test_decodeMac0Corim(t *testing.T) {
+	data := newTestMac0Corim(t, 5)
+	require.True(t, isMac0(data))
+	assert.False(t, isMac0(testSignedCorimValid))
+
+	c, err := decodeMac0Corim(data, true)
+	require.NoError(t, err)
+	assert.Equal(t, "HMAC 256/256", c.alg.name)
+	assert.Equal(t, []byte("mac-key-1"), c.kid)
+	assert.Len(t, c.msg.Tag, 32)
+
+	_, err = decodeMac0Corim(newTestMac0Corim(t, 1), false)
+	assert.EqualError(t, err, "alg header: unsupported MAC algorithm 1, expecting one of: HMAC 256/256, HMAC 256/64, HMAC 384/384, HMAC 512/512")
+}
+
+func Test_CorimVerifyCmd_mac_key(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "mac-corim.cbor", newTestMac0Corim(t, 4), 0644))
+	require.NoError(t, afero.WriteFile(fs, "mac-key.jwk", testMacKey, 0644))
+	require.NoError(t, afero.WriteFile(fs, "other-mac-key.jwk", testOtherMacKey, 0644))
+	require.NoError(t, afero.WriteFile(fs, "ec.jwk", testECKey, 0644))
+
+	cmd := NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=mac-corim.cbor", "--mac-key=mac-key.jwk", "--validate-tags", "--strict-decode"})
+	require.NoError(t, cmd.Execute())
+
+	cmd = NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=mac-corim.cbor", "--mac-key=other-mac-key.jwk"})
+	assert.EqualError(t, cmd.Execute(), "error verifying mac-corim.cbor with MAC key other-mac-key.jwk: MAC verification failed")
+
+	cmd = NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=mac-corim.cbor", "--mac-key=ec.jwk"})
+	assert.EqualError(t, cmd.Execute(), `error loading MAC key from ec.jwk: expecting a symmetric (kty "oct") JWK, got kty "EC"`)
+
+	cmd = NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=mac-corim.cbor", "--mac-key=mac-key.jwk", "--expected-id=other"})
+	assert.ErrorContains(t, cmd.Execute(), "error verifying mac-corim.cbor: ")
+
+	// a Mac0 cannot be verified as a signature
+	cmd = NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=mac-corim.cbor", "--key=ec.jwk"})
+	assert.EqualError(t, cmd.Execute(),
+		"error decoding signed CoRIM from mac-corim.cbor: the CoRIM is MAC-protected (COSE Mac0), not signed: use --mac-key")
+}
+
+func Test_CorimVerifyCmd_mac_key_critical_header(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "mac-key.jwk", testMacKey, 0644))
+
+	// an understood critical header
+	require.NoError(t, afero.WriteFile(fs, "mac-corim.cbor",
+		newTestMac0CorimWithProtected(t, map[int]interface{}{1: int64(5), 2: []interface{}{int64(1)}}), 0644))
+
+	cmd := NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=mac-corim.cbor", "--mac-key=mac-key.jwk"})
+	require.NoError(t, cmd.Execute())
+
+	// an unknown header marked as critical is rejected, even though the MAC
+	// verifies
+	require.NoError(t, afero.WriteFile(fs, "mac-corim.cbor",
+		newTestMac0CorimWithProtected(t, map[int]interface{}{1: int64(5), 2: []interface{}{int64(99)}, 99: "x"}), 0644))
+
+	cmd = NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=mac-corim.cbor", "--mac-key=mac-key.jwk"})
+	assert.EqualError(t, cmd.Execute(),
+		"error decoding MAC-protected CoRIM from mac-corim.cbor: crit header: unrecognized critical header 99")
+}
+
+func Test_CorimVerifyCmd_mac_key_bad_args(t *testing.T) {
+	tvs := []struct {
+		desc     string
+		args     []string
+		expected string
+	}{
+		{
+			desc:     "no file",
+			args:     []string{"--dir=corims", "--mac-key=mac-key.jwk"},
+			expected: "--mac-key can only be used together with --file (without --sequence)",
+		},
+		{
+			desc:     "with key",
+			args:     []string{"--file=mac-corim.cbor", "--mac-key=mac-key.jwk", "--key=ec.jwk"},
+			expected: "--mac-key cannot be used together with --key, --trust-anchor, --system-roots, --use-embedded-key or --allow-self-signed",
+		},
+		{
+			desc:     "with quorum",
+			args:     []string{"--file=mac-corim.cbor", "--mac-key=mac-key.jwk", "--quorum=1", "--quorum-key=a.jwk"},
+			expected: "--mac-key cannot be used together with --quorum",
+		},
+		{
+			desc:     "with signature switches",
+			args:     []string{"--file=mac-corim.cbor", "--mac-key=mac-key.jwk", "--allowed-algs=ES256"},
+			expected: "--mac-key can only be combined with --expected-id, --expected-profile, --require-profile-in, --validate-tags, --strict-decode and --stats",
+		},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.desc, func(t *testing.T) {
+			cmd := NewCorimVerifyCmd()
+			cmd.SetArgs(tv.args)
+
+			assert.EqualError(t, cmd.Execute(), tv.expected)
+		})
+	}
+}
+
+func Test_CorimDisplayCmd_mac0(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "mac-corim.cbor", newTestMac0Corim(t, 7), 0644))
+	require.NoError(t, afero.WriteFile(fs, "bad-mac-corim.cbor", newTestMac0Corim(t, 1), 0644))
+
+	cmd := NewCorimDisplayCmd()
+	cmd.SetArgs([]string{"--file=mac-corim.cbor", "--show-tags"})
+	require.NoError(t, cmd.Execute())
+
+	cmd = NewCorimDisplayCmd()
+	cmd.SetArgs([]string{"--file=bad-mac-corim.cbor"})
+	assert.ErrorContains(t, cmd.Execute(), "error decoding MAC-protected CoRIM from bad-mac-corim.cbor: alg header: unsupported MAC algorithm 1")
+}
@@ -0,0 +1,53 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"crypto/x509"
+	"fmt"
+	"time"
+
+	"github.com/veraison/corim/corim"
+)
+
+// clampMetaValidity sets the validity of the CoRIM Meta m to the intersection
+// of its current validity, if any, and that of the signing certificate cert,
+// so that the CoRIM is never valid when its signing certificate is not.  A
+// warning is printed for each bound of the requested validity that exceeds the
+// certificate's.  A missing not-before is silently taken from cert.
+func clampMetaValidity(m *corim.Meta, cert *x509.Certificate) error {
+	certValidity := corim.Validity{NotBefore: &cert.NotBefore, NotAfter: cert.NotAfter}
+
+	if m.Validity == nil {
+		m.Validity = &certValidity
+		return nil
+	}
+
+	requested := formatValidity(m.Validity, nil)
+
+	v := *m.Validity
+
+	if v.NotBefore == nil {
+		v.NotBefore = &cert.NotBefore
+	} else if v.NotBefore.Before(cert.NotBefore) {
+		fmt.Printf(">> warning: requested validity not-before %s precedes that of the signing certificate, using %s\n",
+			v.NotBefore.UTC().Format(time.RFC3339), cert.NotBefore.UTC().Format(time.RFC3339))
+		v.NotBefore = &cert.NotBefore
+	}
+
+	if v.NotAfter.After(cert.NotAfter) {
+		fmt.Printf(">> warning: requested validity not-after %s exceeds that of the signing certificate, using %s\n",
+			v.NotAfter.UTC().Format(time.RFC3339), cert.NotAfter.UTC().Format(time.RFC3339))
+		v.NotAfter = cert.NotAfter
+	}
+
+	if !v.NotBefore.Before(v.NotAfter) {
+		return fmt.Errorf("requested validity %s does not overlap that of the signing certificate %s",
+			requested, formatValidity(&certValidity, nil))
+	}
+
+	m.Validity = &v
+
+	return nil
+}
@@ -0,0 +1,116 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"crypto/x509"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/corim"
+)
+
+// the validity of testSigningCertificate
+var (
+	testCertNotBefore = time.Date(2025, 2, 11, 15, 11, 48, 0, time.UTC)
+	testCertNotAfter  = time.Date(2030, 2, 10, 15, 11, 48, 0, time.UTC)
+)
+
+func newTestSigningCert(t *testing.T) *x509.Certificate {
+	cert, err := x509.ParseCertificate(testSigningCertificate)
+	require.NoError(t, err)
+	return cert
+}
+
+func Test_clampMetaValidity(t *testing.T) {
+	cert := newTestSigningCert(t)
+
+	at := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+	ptr := func(t time.Time) *time.Time { return &t }
+
+	tvs := []struct {
+		desc      string
+		requested *corim.Validity
+		expected  corim.Validity
+	}{
+		{
+			desc:     "no validity",
+			expected: corim.Validity{NotBefore: &testCertNotBefore, NotAfter: testCertNotAfter},
+		},
+		{
+			desc:      "no not-before",
+			requested: &corim.Validity{NotAfter: at(2026, 1, 1)},
+			expected:  corim.Validity{NotBefore: &testCertNotBefore, NotAfter: at(2026, 1, 1)},
+		},
+		{
+			desc:      "within",
+			requested: &corim.Validity{NotBefore: ptr(at(2026, 1, 1)), NotAfter: at(2027, 1, 1)},
+			expected:  corim.Validity{NotBefore: ptr(at(2026, 1, 1)), NotAfter: at(2027, 1, 1)},
+		},
+		{
+			desc:      "exceeding",
+			requested: &corim.Validity{NotBefore: ptr(at(2020, 1, 1)), NotAfter: at(2035, 1, 1)},
+			expected:  corim.Validity{NotBefore: &testCertNotBefore, NotAfter: testCertNotAfter},
+		},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.desc, func(t *testing.T) {
+			m := corim.Meta{Validity: tv.requested}
+
+			require.NoError(t, clampMetaValidity(&m, cert))
+			require.NotNil(t, m.Validity)
+			assert.True(t, tv.expected.NotBefore.Equal(*m.Validity.NotBefore))
+			assert.True(t, tv.expected.NotAfter.Equal(m.Validity.NotAfter))
+		})
+	}
+}
+
+func Test_clampMetaValidity_no_overlap(t *testing.T) {
+	nb := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	m := corim.Meta{Validity: &corim.Validity{NotBefore: &nb, NotAfter: nb.AddDate(1, 0, 0)}}
+
+	assert.EqualError(t, clampMetaValidity(&m, newTestSigningCert(t)),
+		"requested validity [2020-01-01T00:00:00Z, 2021-01-01T00:00:00Z] does not overlap that of the signing certificate [2025-02-11T15:11:48Z, 2030-02-10T15:11:48Z]")
+}
+
+func Test_CorimSignCmd_meta_validity_from_cert(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "ok.cbor", testCorimValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "ok.json", testMetaValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "ok.jwk", testECKey, 0644))
+	require.NoError(t, afero.WriteFile(fs, "cert.der", testSigningCertificate, 0644))
+
+	cmd := NewCorimSignCmd()
+	cmd.SetArgs([]string{
+		"--file=ok.cbor", "--key=ok.jwk", "--meta=ok.json", "--cert=cert.der",
+		"--output=signed.cbor", "--meta-validity-from-cert",
+	})
+	require.NoError(t, withDisplayOutput("out.txt", cmd.Execute))
+
+	// testMetaValid is valid from 2021-12-31 to 2025-12-31
+	assert.Contains(t, string(mustReadFile(t, "out.txt")),
+		">> warning: requested validity not-before 2021-12-31T00:00:00Z precedes that of the signing certificate, using 2025-02-11T15:11:48Z\n")
+
+	var s corim.SignedCorim
+	require.NoError(t, s.FromCOSE(mustReadFile(t, "signed.cbor")))
+	require.NotNil(t, s.Meta.Validity)
+	assert.True(t, testCertNotBefore.Equal(*s.Meta.Validity.NotBefore))
+	assert.True(t, time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC).Equal(s.Meta.Validity.NotAfter))
+}
+
+func Test_CorimSignCmd_meta_validity_from_cert_bad_args(t *testing.T) {
+	cmd := NewCorimSignCmd()
+	cmd.SetArgs([]string{"--file=ok.cbor", "--key=ok.jwk", "--meta=ok.json", "--meta-validity-from-cert"})
+	assert.EqualError(t, cmd.Execute(), "--meta-validity-from-cert requires --cert, --cert-chain or --cert-url")
+
+	cmd = NewCorimSignCmd()
+	cmd.SetArgs([]string{"--file=ok.cbor", "--key=ok.jwk", "--no-meta", "--cert=cert.der", "--meta-validity-from-cert"})
+	assert.EqualError(t, cmd.Execute(), "--meta-validity-from-cert cannot be used together with --no-meta")
+}
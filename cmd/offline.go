@@ -0,0 +1,58 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"crypto/x509"
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// offline is set by the global --offline switch, which forbids any network
+// access: commands and checks that cannot do without it fail instead
+var offline bool
+
+// systemRootsMayUseNetwork tells whether verifying against the system
+// certificate pool is delegated to the platform verifier, which may fetch
+// missing intermediates or revocation information over the network
+var systemRootsMayUseNetwork = runtime.GOOS == "darwin" || runtime.GOOS == "windows"
+
+// checkOnline fails in offline mode, on behalf of what, which needs the network
+func checkOnline(what string) error {
+	if offline {
+		return fmt.Errorf("%s requires network access, which is disabled by --offline", what)
+	}
+
+	return nil
+}
+
+// offlineRevocationNotes returns, for each of certs that points to revocation
+// information (OCSP responders or CRL distribution points), a note that its
+// revocation status is not checked.  cocli never fetches such information, but
+// in offline mode the skipped checks are reported explicitly.
+func offlineRevocationNotes(certs []*x509.Certificate) []string {
+	var notes []string
+
+	for _, c := range certs {
+		var sources []string
+
+		for _, u := range c.OCSPServer {
+			sources = append(sources, "OCSP "+u)
+		}
+
+		for _, u := range c.CRLDistributionPoints {
+			sources = append(sources, "CRL "+u)
+		}
+
+		if len(sources) == 0 {
+			continue
+		}
+
+		notes = append(notes, fmt.Sprintf("revocation check of %q skipped (%s)",
+			c.Subject.String(), strings.Join(sources, ", ")))
+	}
+
+	return notes
+}
@@ -0,0 +1,93 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	mock_deps "github.com/veraison/cocli/cmd/mocks"
+)
+
+// setOffline sets the --offline mode for the duration of the test
+func setOffline(t *testing.T) {
+	offline = true
+	t.Cleanup(func() { offline = false })
+}
+
+func Test_offline_flag(t *testing.T) {
+	assert.NotNil(t, rootCmd.PersistentFlags().Lookup("offline"))
+}
+
+func Test_offlineRevocationNotes(t *testing.T) {
+	certs := []*x509.Certificate{
+		{
+			Subject:               pkix.Name{CommonName: "Signer"},
+			OCSPServer:            []string{"http://ocsp.example"},
+			CRLDistributionPoints: []string{"http://crl.example/inter.crl"},
+		},
+		{
+			Subject: pkix.Name{CommonName: "No Revocation Info"},
+		},
+		{
+			Subject:               pkix.Name{CommonName: "Intermediate"},
+			CRLDistributionPoints: []string{"http://crl.example/root.crl"},
+		},
+	}
+
+	assert.Equal(t, []string{
+		`revocation check of "CN=Signer" skipped (OCSP http://ocsp.example, CRL http://crl.example/inter.crl)`,
+		`revocation check of "CN=Intermediate" skipped (CRL http://crl.example/root.crl)`,
+	}, offlineRevocationNotes(certs))
+}
+
+func Test_CorimSubmitCmd_offline(t *testing.T) {
+	setOffline(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// no call to the submitter is expected
+	cmd := NewCorimSubmitCmd(mock_deps.NewMockISubmitter(ctrl))
+	cmd.SetArgs([]string{
+		"--corim-file=corim.cbor",
+		"--api-server=https://veraison.example/endorsement-provisioning/v1/submit",
+		"--media-type=application/corim-unsigned+cbor",
+	})
+
+	assert.EqualError(t, cmd.Execute(), "corim submit requires network access, which is disabled by --offline")
+}
+
+func Test_CorimVerifyCmd_offline_system_roots(t *testing.T) {
+	setOffline(t)
+
+	orig := systemRootsMayUseNetwork
+	systemRootsMayUseNetwork = true
+	t.Cleanup(func() { systemRootsMayUseNetwork = orig })
+
+	cmd := NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=signed.cbor", "--system-roots"})
+
+	assert.ErrorContains(t, cmd.Execute(), "cannot be used together with --offline")
+}
+
+func Test_CorimVerifyCmd_offline_trust_anchor(t *testing.T) {
+	setOffline(t)
+
+	pki := newTestPKI(t)
+
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "signed.cbor", pki.signedCorim(t), 0644))
+	require.NoError(t, afero.WriteFile(fs, "root.pem", pki.rootPEM(), 0644))
+
+	cmd := NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=signed.cbor", "--trust-anchor=root.pem"})
+
+	assert.NoError(t, cmd.Execute())
+}
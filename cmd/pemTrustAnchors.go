@@ -0,0 +1,63 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/afero"
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/corim/cots"
+)
+
+// loadPEMTrustAnchors returns a certificate trust anchor for each PEM
+// certificate found in the files of dirs (bundles with more than one
+// certificate are supported).  Files that do not contain any parsable
+// certificate are skipped with a warning.
+func loadPEMTrustAnchors(dirs []string) ([]cots.TrustAnchor, error) {
+	var tas []cots.TrustAnchor
+
+	for _, dir := range dirs {
+		filesInfo, err := afero.ReadDir(fs, dir)
+		if err != nil {
+			return nil, fmt.Errorf("error reading PEM directory %s: %w", dir, err)
+		}
+
+		for _, fileInfo := range filesInfo {
+			if fileInfo.IsDir() {
+				continue
+			}
+
+			file := filepath.Join(dir, fileInfo.Name())
+
+			data, err := afero.ReadFile(fs, file)
+			if err != nil {
+				return nil, fmt.Errorf("error loading TA from %s: %w", file, err)
+			}
+
+			certs, err := parseCertificates(data)
+			if err != nil {
+				fmt.Printf(">> warning: skipping %s: %v\n", file, err)
+				continue
+			}
+
+			for _, cert := range certs {
+				tas = append(tas, cots.TrustAnchor{Format: cots.TaFormatCertificate, Data: cert.Raw})
+			}
+
+			fmt.Printf(">> %d certificate(s) loaded from %s\n", len(certs), file)
+		}
+	}
+
+	return tas, nil
+}
+
+// classIDEnvironment returns the environment groups made of the single
+// environment identified by classID
+func classIDEnvironment(classID *comid.ClassID) cots.EnvironmentGroups {
+	return cots.EnvironmentGroups{
+		{Environment: &comid.Environment{Class: &comid.Class{ClassID: classID}}},
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/pem"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/cots"
+)
+
+// writeTestPEMDir saves a directory of root CA files, with a bundle of two
+// certificates and a file that is not a certificate
+func writeTestPEMDir(t *testing.T) testPKI {
+	pki := newTestPKI(t)
+	intermediatePEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: pki.intermediateDER})
+
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "roots/a.pem", pki.rootPEM(), 0644))
+	require.NoError(t, afero.WriteFile(fs, "roots/bundle.pem", append(pki.rootPEM(), intermediatePEM...), 0644))
+	require.NoError(t, afero.WriteFile(fs, "roots/README", []byte("root CAs\n"), 0644))
+
+	return pki
+}
+
+func Test_loadPEMTrustAnchors(t *testing.T) {
+	pki := writeTestPEMDir(t)
+
+	tas, err := loadPEMTrustAnchors([]string{"roots"})
+	require.NoError(t, err)
+	require.Len(t, tas, 3)
+
+	// files are read in lexical order: README (skipped), a.pem, bundle.pem
+	assert.Equal(t, pki.rootDER, tas[0].Data)
+	assert.Equal(t, pki.rootDER, tas[1].Data)
+	assert.Equal(t, pki.intermediateDER, tas[2].Data)
+
+	for _, ta := range tas {
+		assert.Equal(t, cots.TaFormatCertificate, ta.Format)
+	}
+}
+
+func Test_loadPEMTrustAnchors_no_dir(t *testing.T) {
+	fs = afero.NewMemMapFs()
+
+	_, err := loadPEMTrustAnchors([]string{"roots"})
+	assert.EqualError(t, err, "error reading PEM directory roots: open roots: file does not exist")
+}
+
+func Test_CotsCreateCtsCmd_from_pem_dir(t *testing.T) {
+	pki := writeTestPEMDir(t)
+
+	cmd := NewCotsCreateCtsCmd()
+	cmd.SetArgs([]string{
+		"--from-pem-dir=roots",
+		"--env-class-id=1.2.3.4",
+		"--output=cots.cbor",
+	})
+	require.NoError(t, cmd.Execute())
+
+	data, err := afero.ReadFile(fs, "cots.cbor")
+	require.NoError(t, err)
+
+	var cts cots.ConciseTaStore
+	require.NoError(t, cts.FromCBOR(data))
+	require.NoError(t, cts.Valid())
+
+	require.Len(t, cts.Environments, 1)
+	assert.Equal(t, "1.2.3.4", cts.Environments[0].Environment.Class.ClassID.String())
+
+	require.Len(t, cts.Keys.Tas, 3)
+	assert.Equal(t, pki.intermediateDER, cts.Keys.Tas[2].Data)
+}
+
+func Test_CotsCreateCtsCmd_from_pem_dir_no_certs(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "roots/README", []byte("root CAs\n"), 0644))
+
+	cmd := NewCotsCreateCtsCmd()
+	cmd.SetArgs([]string{
+		"--from-pem-dir=roots",
+		"--env-class-id=1.2.3.4",
+		"--output=cots.cbor",
+	})
+	assert.EqualError(t, cmd.Execute(), "no TA files found")
+}
+
+func Test_CotsCreateCtsCmd_env_class_id_bad_args(t *testing.T) {
+	tvs := []struct {
+		desc     string
+		args     []string
+		expected string
+	}{
+		{
+			desc:     "with environment",
+			args:     []string{"--environment=env.json", "--env-class-id=1.2.3.4", "--output=cots.cbor", "--from-pem-dir=roots"},
+			expected: "--environment cannot be used together with --env-class-id",
+		},
+		{
+			desc:     "no output",
+			args:     []string{"--env-class-id=1.2.3.4", "--from-pem-dir=roots"},
+			expected: "no output file supplied (required with --env-class-id)",
+		},
+		{
+			desc:     "bad class id",
+			args:     []string{"--env-class-id=not a class id", "--from-pem-dir=roots", "--output=cots.cbor"},
+			expected: `invalid environment class id "not a class id": expecting a UUID, an OID or a base64-encoded implementation id`,
+		},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.desc, func(t *testing.T) {
+			writeTestPEMDir(t)
+
+			cmd := NewCotsCreateCtsCmd()
+			cmd.SetArgs(tv.args)
+			assert.EqualError(t, cmd.Execute(), tv.expected)
+		})
+	}
+}
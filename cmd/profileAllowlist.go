@@ -0,0 +1,62 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/veraison/corim/corim"
+)
+
+// profileAllowlist lists the profiles a verified CoRIM may declare.  An empty
+// list allows any profile, or none.
+type profileAllowlist []string
+
+// newProfileAllowlist builds a profileAllowlist from the profile identifiers
+// supplied with --require-profile-in
+func newProfileAllowlist(profiles []string) (profileAllowlist, error) {
+	for _, p := range profiles {
+		if strings.TrimSpace(p) == "" {
+			return nil, errors.New("invalid --require-profile-in: empty profile identifier")
+		}
+	}
+
+	return profileAllowlist(profiles), nil
+}
+
+// check returns an error, reporting the actual profile, unless c declares one
+// of the allowed profiles
+func (l profileAllowlist) check(c corim.UnsignedCorim) error {
+	if len(l) == 0 {
+		return nil
+	}
+
+	if c.Profile == nil {
+		return fmt.Errorf("CoRIM profile not allowed: got none, expecting one of %s", l)
+	}
+
+	actual, err := c.Profile.Get()
+	if err != nil {
+		return fmt.Errorf("CoRIM profile: %w", err)
+	}
+
+	for _, p := range l {
+		if p == actual {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("CoRIM profile not allowed: got %q, expecting one of %s", actual, l)
+}
+
+func (l profileAllowlist) String() string {
+	quoted := make([]string, len(l))
+	for i, p := range l {
+		quoted[i] = fmt.Sprintf("%q", p)
+	}
+
+	return strings.Join(quoted, ", ")
+}
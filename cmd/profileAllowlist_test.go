@@ -0,0 +1,59 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_CorimVerifyCmd_require_profile_in_ok(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "ok.cbor", testSignedCorimValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "ok.jwk", testECKey, 0644))
+
+	cmd := NewCorimVerifyCmd()
+	cmd.SetArgs([]string{
+		"--file=ok.cbor",
+		"--key=ok.jwk",
+		"--require-profile-in=http://arm.com/psa/iot/1,http://arm.com/iot/profile/1",
+	})
+	assert.NoError(t, cmd.Execute())
+}
+
+func Test_CorimVerifyCmd_require_profile_in_mismatch(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "ok.cbor", testSignedCorimValid, 0644))
+
+	// the profile is checked before the key is even loaded
+	cmd := NewCorimVerifyCmd()
+	cmd.SetArgs([]string{
+		"--file=ok.cbor",
+		"--key=missing.jwk",
+		"--require-profile-in=http://arm.com/psa/iot/1",
+		"--require-profile-in=http://arm.com/cca/ssd/1",
+	})
+	assert.EqualError(t, cmd.Execute(), `error verifying ok.cbor: CoRIM profile not allowed: `+
+		`got "http://arm.com/iot/profile/1", expecting one of "http://arm.com/psa/iot/1", "http://arm.com/cca/ssd/1"`)
+}
+
+func Test_CorimVerifyCmd_require_profile_in_none(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "ok.cbor", newTestSignedCorimWithComids(t, 1), 0644))
+	require.NoError(t, afero.WriteFile(fs, "ok.jwk", testECKey, 0644))
+
+	cmd := NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=ok.cbor", "--key=ok.jwk", "--require-profile-in=http://arm.com/psa/iot/1"})
+	assert.EqualError(t, cmd.Execute(),
+		`error verifying ok.cbor: CoRIM profile not allowed: got none, expecting one of "http://arm.com/psa/iot/1"`)
+}
+
+func Test_CorimVerifyCmd_require_profile_in_empty(t *testing.T) {
+	cmd := NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=ok.cbor", "--key=ok.jwk", "--require-profile-in=http://arm.com/psa/iot/1,"})
+	assert.EqualError(t, cmd.Execute(), "invalid --require-profile-in: empty profile identifier")
+}
@@ -0,0 +1,241 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/corim/corim"
+	"github.com/veraison/corim/extensions"
+	"github.com/veraison/eat"
+)
+
+var profileDefFiles []string
+
+// profileDef is a profile descriptor, i.e., a profile identifier together with
+// the extension fields it adds at each extension point, e.g.:
+//
+//	{
+//	  "profile": "http://example.com/acme/1",
+//	  "extensions": {
+//	    "ReferenceValue": [
+//	      { "name": "build-id", "key": -1001, "type": "string" }
+//	    ]
+//	  }
+//	}
+type profileDef struct {
+	Profile    string                       `json:"profile"`
+	Extensions map[string][]profileDefField `json:"extensions"`
+}
+
+type profileDefField struct {
+	Name string `json:"name"`
+	Key  *int   `json:"key"`
+	Type string `json:"type"`
+}
+
+// profileDefTypes maps the types that can be used in a profile descriptor to
+// the Go type of the corresponding extension field
+var profileDefTypes = map[string]reflect.Type{
+	"string": reflect.TypeOf((*string)(nil)),
+	"int":    reflect.TypeOf((*int64)(nil)),
+	"uint":   reflect.TypeOf((*uint64)(nil)),
+	"bool":   reflect.TypeOf((*bool)(nil)),
+	"bytes":  reflect.TypeOf([]byte(nil)),
+}
+
+// initProfileDefs registers the profiles described in the files supplied via
+// --profile-def
+func initProfileDefs() {
+	for _, file := range profileDefFiles {
+		cobra.CheckErr(loadProfileDef(file))
+	}
+}
+
+// loadProfileDef parses the profile descriptor in file and registers the
+// described profile with the CoRIM library, so that the extensions it declares
+// are decoded, validated and encoded along with the rest of the CoRIM and of
+// its CoMIDs
+func loadProfileDef(file string) error {
+	data, err := afero.ReadFile(fs, file)
+	if err != nil {
+		return fmt.Errorf("error loading profile definition from %s: %w", file, err)
+	}
+
+	var def profileDef
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	if err = dec.Decode(&def); err != nil {
+		return fmt.Errorf("error decoding profile definition from %s: %w", file, err)
+	}
+
+	id, exts, err := def.toExtensions()
+	if err != nil {
+		return fmt.Errorf("error in profile definition from %s: %w", file, err)
+	}
+
+	if err = corim.RegisterProfile(id, exts); err != nil {
+		return fmt.Errorf("error registering profile definition from %s: %w", file, err)
+	}
+
+	return nil
+}
+
+// toExtensions returns the profile identifier of o and, for each of its
+// extension points, a pointer to a newly built struct type that carries the
+// declared extension fields
+func (o profileDef) toExtensions() (*eat.Profile, extensions.Map, error) {
+	if o.Profile == "" {
+		return nil, nil, errors.New("missing profile identifier")
+	}
+
+	id, err := eat.NewProfile(o.Profile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid profile identifier %q: %w", o.Profile, err)
+	}
+
+	if len(o.Extensions) == 0 {
+		return nil, nil, errors.New("no extensions defined")
+	}
+
+	// sort the extension points so that problems are reported consistently
+	points := make([]string, 0, len(o.Extensions))
+	for p := range o.Extensions {
+		points = append(points, p)
+	}
+	sort.Strings(points)
+
+	exts := extensions.NewMap()
+
+	for _, p := range points {
+		point := extensions.Point(p)
+		if !corim.AllExtensionPoints[point] {
+			return nil, nil, fmt.Errorf("unknown extension point %q", p)
+		}
+
+		t, err := profileDefStruct(o.Extensions[p])
+		if err != nil {
+			return nil, nil, fmt.Errorf("extension point %q: %w", p, err)
+		}
+
+		exts.Add(point, reflect.New(t).Interface())
+	}
+
+	return id, exts, nil
+}
+
+// profileDefStruct builds the struct type used to decode and encode the
+// extension fields in fields
+func profileDefStruct(fields []profileDefField) (reflect.Type, error) {
+	if len(fields) == 0 {
+		return nil, errors.New("no extension fields defined")
+	}
+
+	var (
+		sfs   []reflect.StructField
+		names = map[string]bool{}
+		keys  = map[int]bool{}
+	)
+
+	for i, f := range fields {
+		if f.Name == "" {
+			return nil, fmt.Errorf("field at index %d: missing name", i)
+		}
+
+		if names[f.Name] {
+			return nil, fmt.Errorf("field %q: duplicate name", f.Name)
+		}
+		names[f.Name] = true
+
+		if f.Key == nil {
+			return nil, fmt.Errorf("field %q: missing key", f.Name)
+		}
+
+		if keys[*f.Key] {
+			return nil, fmt.Errorf("field %q: duplicate key %d", f.Name, *f.Key)
+		}
+		keys[*f.Key] = true
+
+		t, ok := profileDefTypes[f.Type]
+		if !ok {
+			return nil, fmt.Errorf("field %q: unsupported type %q", f.Name, f.Type)
+		}
+
+		sfs = append(sfs, reflect.StructField{
+			Name: fmt.Sprintf("Field%d", i),
+			Type: t,
+			Tag: reflect.StructTag(fmt.Sprintf(
+				`cbor:"%d,keyasint,omitempty" json:"%s,omitempty"`, *f.Key, f.Name,
+			)),
+		})
+	}
+
+	return reflect.StructOf(sfs), nil
+}
+
+// newComid returns a CoMID with the extensions registered for profile, if any
+func newComid(profile *eat.Profile) *comid.Comid {
+	if pm, ok := corim.GetProfileManifest(profile); ok {
+		return pm.GetComid()
+	}
+
+	return comid.NewComid()
+}
+
+// parseProfile parses the profile identifier s, which may be empty or the
+// short name of one of the Arm profiles (e.g., "cca")
+func parseProfile(s string) (*eat.Profile, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	if p, ok := armProfiles[s]; ok {
+		s = p.id
+	}
+
+	p, err := eat.NewProfile(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid profile identifier %q: %w", s, err)
+	}
+
+	return p, nil
+}
+
+// cborProfile returns the profile declared by the unsigned CoRIM in data, or
+// nil if it declares none or cannot be decoded
+func cborProfile(data []byte) *eat.Profile {
+	profiled := struct {
+		Profile *eat.Profile `cbor:"3,keyasint,omitempty"`
+	}{}
+
+	if err := cbor.Unmarshal(data, &profiled); err != nil {
+		return nil
+	}
+
+	return profiled.Profile
+}
+
+// jsonProfile is the JSON counterpart of cborProfile
+func jsonProfile(data []byte) *eat.Profile {
+	profiled := struct {
+		Profile *eat.Profile `json:"profile,omitempty"`
+	}{}
+
+	if err := json.Unmarshal(data, &profiled); err != nil {
+		return nil
+	}
+
+	return profiled.Profile
+}
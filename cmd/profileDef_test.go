@@ -0,0 +1,244 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/corim/corim"
+	"github.com/veraison/eat"
+)
+
+var testProfileDef = `{
+  "profile": "http://example.com/acme/1",
+  "extensions": {
+    "UnsignedCorim": [
+      { "name": "build-site", "key": -1000, "type": "string" }
+    ],
+    "ReferenceValue": [
+      { "name": "build-id", "key": -1001, "type": "string" },
+      { "name": "hardened", "key": -1002, "type": "bool" }
+    ]
+  }
+}`
+
+// loadTestProfileDef registers testProfileDef for the duration of the test
+func loadTestProfileDef(t *testing.T) *eat.Profile {
+	require.NoError(t, afero.WriteFile(fs, "profile.json", []byte(testProfileDef), 0644))
+	require.NoError(t, loadProfileDef("profile.json"))
+
+	profile, err := eat.NewProfile("http://example.com/acme/1")
+	require.NoError(t, err)
+
+	t.Cleanup(func() { corim.UnregisterProfile(profile) })
+
+	return profile
+}
+
+// newTestExtendedComidTemplate returns the PSA reference value template, with
+// the ReferenceValue extensions of testProfileDef added to its first
+// measurement
+func newTestExtendedComidTemplate(t *testing.T) []byte {
+	var tmpl map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(comid.PSARefValJSONTemplate), &tmpl))
+
+	rv := tmpl["triples"].(map[string]interface{})["reference-values"].([]interface{})[0]
+	m := rv.(map[string]interface{})["measurements"].([]interface{})[0]
+	v := m.(map[string]interface{})["value"].(map[string]interface{})
+	v["build-id"] = "acme-1234"
+	v["hardened"] = true
+
+	data, err := json.Marshal(tmpl)
+	require.NoError(t, err)
+
+	return data
+}
+
+func Test_loadProfileDef_ok(t *testing.T) {
+	fs = afero.NewMemMapFs()
+
+	profile := loadTestProfileDef(t)
+
+	pm, ok := corim.GetProfileManifest(profile)
+	require.True(t, ok)
+	assert.Len(t, pm.MapExtensions, 2)
+}
+
+func Test_loadProfileDef_not_found(t *testing.T) {
+	fs = afero.NewMemMapFs()
+
+	err := loadProfileDef("profile.json")
+	assert.EqualError(t, err, "error loading profile definition from profile.json: open profile.json: file does not exist")
+}
+
+func Test_loadProfileDef_already_registered(t *testing.T) {
+	fs = afero.NewMemMapFs()
+
+	loadTestProfileDef(t)
+
+	err := loadProfileDef("profile.json")
+	assert.EqualError(t, err, `error registering profile definition from profile.json: profile with id "http://example.com/acme/1" already registered`)
+}
+
+func Test_loadProfileDef_bad(t *testing.T) {
+	tvs := []struct {
+		desc     string
+		def      string
+		expected string
+	}{
+		{
+			desc:     "unknown member",
+			def:      `{"profile": "http://example.com/acme/1", "label": "x"}`,
+			expected: `error decoding profile definition from profile.json: json: unknown field "label"`,
+		},
+		{
+			desc:     "missing profile",
+			def:      `{"extensions": {"Comid": [{"name": "x", "key": -1, "type": "string"}]}}`,
+			expected: "error in profile definition from profile.json: missing profile identifier",
+		},
+		{
+			desc:     "no extensions",
+			def:      `{"profile": "http://example.com/acme/1"}`,
+			expected: "error in profile definition from profile.json: no extensions defined",
+		},
+		{
+			desc:     "unknown extension point",
+			def:      `{"profile": "http://example.com/acme/1", "extensions": {"Widget": [{"name": "x", "key": -1, "type": "string"}]}}`,
+			expected: `error in profile definition from profile.json: unknown extension point "Widget"`,
+		},
+		{
+			desc:     "no fields",
+			def:      `{"profile": "http://example.com/acme/1", "extensions": {"Comid": []}}`,
+			expected: `error in profile definition from profile.json: extension point "Comid": no extension fields defined`,
+		},
+		{
+			desc:     "missing name",
+			def:      `{"profile": "http://example.com/acme/1", "extensions": {"Comid": [{"key": -1, "type": "string"}]}}`,
+			expected: `error in profile definition from profile.json: extension point "Comid": field at index 0: missing name`,
+		},
+		{
+			desc:     "missing key",
+			def:      `{"profile": "http://example.com/acme/1", "extensions": {"Comid": [{"name": "x", "type": "string"}]}}`,
+			expected: `error in profile definition from profile.json: extension point "Comid": field "x": missing key`,
+		},
+		{
+			desc:     "duplicate name",
+			def:      `{"profile": "http://example.com/acme/1", "extensions": {"Comid": [{"name": "x", "key": -1, "type": "string"}, {"name": "x", "key": -2, "type": "string"}]}}`,
+			expected: `error in profile definition from profile.json: extension point "Comid": field "x": duplicate name`,
+		},
+		{
+			desc:     "duplicate key",
+			def:      `{"profile": "http://example.com/acme/1", "extensions": {"Comid": [{"name": "x", "key": -1, "type": "string"}, {"name": "y", "key": -1, "type": "string"}]}}`,
+			expected: `error in profile definition from profile.json: extension point "Comid": field "y": duplicate key -1`,
+		},
+		{
+			desc:     "unsupported type",
+			def:      `{"profile": "http://example.com/acme/1", "extensions": {"Comid": [{"name": "x", "key": -1, "type": "float"}]}}`,
+			expected: `error in profile definition from profile.json: extension point "Comid": field "x": unsupported type "float"`,
+		},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.desc, func(t *testing.T) {
+			fs = afero.NewMemMapFs()
+			require.NoError(t, afero.WriteFile(fs, "profile.json", []byte(tv.def), 0644))
+
+			err := loadProfileDef("profile.json")
+			assert.EqualError(t, err, tv.expected)
+		})
+	}
+}
+
+func Test_ProfileDef_create_validate_display(t *testing.T) {
+	fs = afero.NewMemMapFs()
+
+	loadTestProfileDef(t)
+
+	require.NoError(t, afero.WriteFile(fs, "comid.json", newTestExtendedComidTemplate(t), 0644))
+	require.NoError(t, afero.WriteFile(fs, "corim.json", []byte(`{
+  "corim-id": "5c57e8f4-46cd-421b-91c9-08cf93e13cfc",
+  "profile": "http://example.com/acme/1",
+  "build-site": "cambridge"
+}`), 0644))
+
+	cmd := NewComidCreateCmd()
+	cmd.SetArgs([]string{
+		"--template=comid.json",
+		"--profile=http://example.com/acme/1",
+		"--strict-decode",
+	})
+	require.NoError(t, cmd.Execute())
+
+	cmd = NewCorimCreateCmd()
+	cmd.SetArgs([]string{
+		"--template=corim.json",
+		"--comid=comid.cbor",
+		"--output=corim.cbor",
+		"--strict-decode",
+	})
+	require.NoError(t, cmd.Execute())
+
+	cmd = NewCorimValidateCmd()
+	cmd.SetArgs([]string{"--file=corim.cbor"})
+	require.NoError(t, cmd.Execute())
+
+	cmd = NewCorimDisplayCmd()
+	cmd.SetArgs([]string{
+		"--file=corim.cbor",
+		"--show-tags",
+		"--strict-decode",
+		"--output=corim.txt",
+	})
+	require.NoError(t, cmd.Execute())
+
+	out, err := afero.ReadFile(fs, "corim.txt")
+	require.NoError(t, err)
+	assert.Contains(t, string(out), `"build-site": "cambridge"`)
+	assert.Contains(t, string(out), `"build-id": "acme-1234"`)
+	assert.Contains(t, string(out), `"hardened": true`)
+
+	cmd = NewComidDisplayCmd()
+	cmd.SetArgs([]string{
+		"--file=comid.cbor",
+		"--profile=http://example.com/acme/1",
+		"--strict-decode",
+		"--output=comid.txt",
+	})
+	require.NoError(t, cmd.Execute())
+
+	out, err = afero.ReadFile(fs, "comid.txt")
+	require.NoError(t, err)
+	assert.Contains(t, string(out), `"build-id": "acme-1234"`)
+}
+
+func Test_ProfileDef_comid_create_without_profile(t *testing.T) {
+	fs = afero.NewMemMapFs()
+
+	loadTestProfileDef(t)
+
+	require.NoError(t, afero.WriteFile(fs, "comid.json", newTestExtendedComidTemplate(t), 0644))
+
+	cmd := NewComidCreateCmd()
+	cmd.SetArgs([]string{
+		"--template=comid.json",
+		"--strict-decode",
+	})
+	assert.EqualError(t, cmd.Execute(), "1/1 creations(s) failed")
+}
+
+func Test_ComidCreateCmd_profile_with_bulk(t *testing.T) {
+	cmd := NewComidCreateCmd()
+	cmd.SetArgs([]string{
+		"--bulk",
+		"--csv=measurements.csv",
+		"--env-class-id=1.2.3.4",
+		"--profile=http://example.com/acme/1",
+	})
+	assert.EqualError(t, cmd.Execute(), "--profile cannot be used together with --bulk")
+}
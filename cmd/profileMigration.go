@@ -0,0 +1,169 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/corim/corim"
+	"github.com/veraison/eat"
+)
+
+// migrationReport collects the changes applied by a profile migration, and the
+// fields that could not be migrated automatically
+type migrationReport struct {
+	changes    []string
+	unmigrated []string
+}
+
+func (o *migrationReport) change(format string, args ...interface{}) {
+	o.changes = append(o.changes, fmt.Sprintf(format, args...))
+}
+
+func (o *migrationReport) unmigrate(format string, args ...interface{}) {
+	o.unmigrated = append(o.unmigrated, fmt.Sprintf(format, args...))
+}
+
+// profileMigration upgrades CoRIMs from one profile (version) to another.  The
+// profiles can be referred to by their identifier or by their short name.
+type profileMigration struct {
+	fromName, from string
+	toName, to     string
+	// migrateComid migrates the CoMID c in place, recording what it does in
+	// r.  where locates c in messages.
+	migrateComid func(where string, c *comid.Comid, r *migrationReport)
+}
+
+// profileMigrations lists the known profile migrations
+var profileMigrations = []profileMigration{
+	{
+		fromName:     "psa-v1",
+		from:         "http://arm.com/psa/iot/1",
+		toName:       "psa-v2",
+		to:           "https://arm.com/psa/iot/2.0.0",
+		migrateComid: migratePSAComidV1ToV2,
+	},
+}
+
+// lookupProfileMigration returns the migration from profile from to profile
+// to, each of which is either an identifier or a short name
+func lookupProfileMigration(from, to string) (profileMigration, error) {
+	var known []string
+
+	for _, m := range profileMigrations {
+		if (from == m.from || from == m.fromName) && (to == m.to || to == m.toName) {
+			return m, nil
+		}
+
+		known = append(known, fmt.Sprintf("%s -> %s", m.fromName, m.toName))
+	}
+
+	return profileMigration{}, fmt.Errorf("no migration known from %q to %q (known migrations: %s)",
+		from, to, strings.Join(known, ", "))
+}
+
+// migrateCorim migrates c, which must declare the source profile of m, and its
+// CoMIDs to the target profile of m.  Other tags are left untouched.
+func migrateCorim(c *corim.UnsignedCorim, m profileMigration) (*migrationReport, error) {
+	var actual string
+	if c.Profile != nil {
+		actual, _ = c.Profile.Get()
+	}
+
+	if actual != m.from {
+		return nil, fmt.Errorf("CoRIM profile mismatch: expected %q, got %q", m.from, actual)
+	}
+
+	r := &migrationReport{}
+
+	for i, t := range c.Tags {
+		if len(t) < 4 || !bytes.Equal(t[:3], corim.ComidTag) {
+			continue
+		}
+
+		cm, err := corim.UnmarshalComidFromCBOR(t[3:], c.Profile)
+		if err != nil {
+			return nil, fmt.Errorf("tag [%d] (CoMID): decoding failed: %w", i, err)
+		}
+
+		m.migrateComid(fmt.Sprintf("tag [%d] (CoMID)", i), cm, r)
+
+		data, err := cm.ToCBOR()
+		if err != nil {
+			return nil, fmt.Errorf("tag [%d] (CoMID): encoding failed: %w", i, err)
+		}
+
+		c.Tags[i] = append(append(corim.Tag{}, corim.ComidTag...), data...)
+	}
+
+	p, err := eat.NewProfile(m.to)
+	if err != nil {
+		return nil, fmt.Errorf("target profile: %w", err)
+	}
+
+	c.Profile = p
+	r.change("CoRIM: profile %q replaced with %q", m.from, m.to)
+
+	return r, nil
+}
+
+// migratePSAComidV1ToV2 migrates the reference values of a PSA CoMID from
+// version 1 of the profile, in which the version of a software component can
+// be carried in the version measurement value, to version 2, in which it is
+// carried in the psa.refval-id measurement key (the version scheme is dropped).
+// Measurements without a psa.refval-id key cannot be migrated.
+func migratePSAComidV1ToV2(where string, c *comid.Comid, r *migrationReport) {
+	if c.Triples.ReferenceValues == nil {
+		return
+	}
+
+	for i := range c.Triples.ReferenceValues.Values {
+		ms := c.Triples.ReferenceValues.Values[i].Measurements.Values
+
+		for j := range ms {
+			m := &ms[j]
+			w := fmt.Sprintf("%s: reference-values[%d]: measurement [%d]", where, i, j)
+
+			if m.Key == nil || !m.Key.IsSet() {
+				r.unmigrate("%s: missing measurement key, expecting a %s", w, comid.PSARefValIDType)
+				continue
+			}
+
+			id, err := m.Key.GetPSARefValID()
+			if err != nil {
+				r.unmigrate("%s: measurement key of type %s cannot be mapped to a %s", w, m.Key.Type(), comid.PSARefValIDType)
+				continue
+			}
+
+			if m.Val.Ver == nil {
+				continue
+			}
+
+			v := m.Val.Ver.Version
+
+			switch {
+			case id.Version == nil:
+				id.Version = &v
+
+				key, err := comid.NewMkeyPSARefvalID(id)
+				if err != nil {
+					r.unmigrate("%s: version %q: %v", w, v, err)
+					continue
+				}
+
+				m.Key = key
+				m.Val.Ver = nil
+				r.change("%s: version %q moved into the %s key", w, v, comid.PSARefValIDType)
+			case *id.Version == v:
+				m.Val.Ver = nil
+				r.change("%s: version %q dropped, as already in the %s key", w, v, comid.PSARefValIDType)
+			default:
+				r.unmigrate("%s: version %q conflicts with version %q of the %s key", w, v, *id.Version, comid.PSARefValIDType)
+			}
+		}
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/eat"
+)
+
+const (
+	// psaImplIDLength is the length of a PSA implementation id
+	psaImplIDLength = 32
+	// psaInstIDLength is the length of a PSA instance id: a RAND UEID, made of
+	// the UEID type byte followed by the 32 bytes of the hash of the IAK
+	psaInstIDLength = 33
+)
+
+// psaIDs collects the --psa-impl-id and --psa-inst-id switches, which set the
+// implementation id and instance id of the CoMIDs created from templates the
+// way the PSA and CCA profiles expect them
+type psaIDs struct {
+	implID string
+	instID string
+}
+
+// addPSAIDFlags registers the PSA id switches of cmd, storing their values in o
+func addPSAIDFlags(cmd *cobra.Command, o *psaIDs) {
+	cmd.Flags().StringVar(
+		&o.implID, "psa-impl-id", "",
+		"PSA implementation id (32 bytes, base64 or hex) set as the class id of the reference-values and attester-verification-keys environments (with --profile=psa or cca)",
+	)
+
+	cmd.Flags().StringVar(
+		&o.instID, "psa-inst-id", "",
+		"PSA instance id (a 33 bytes RAND ueid, base64 or hex) set as the instance of the attester-verification-keys environment (with --profile=psa or cca)",
+	)
+}
+
+// isSet tells whether any of the PSA id switches was supplied
+func (o psaIDs) isSet() bool {
+	return o.implID != "" || o.instID != ""
+}
+
+// valid checks that the PSA ids are only supplied together with one of the Arm
+// profiles, and that they are well-formed
+func (o psaIDs) valid(profile string) error {
+	if !o.isSet() {
+		return nil
+	}
+
+	p, err := parseProfile(profile)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := lookupArmProfile(p); !ok {
+		return errors.New("--psa-impl-id and --psa-inst-id can only be used together with --profile=psa or --profile=cca")
+	}
+
+	if o.implID != "" {
+		if _, err := o.classID(); err != nil {
+			return err
+		}
+	}
+
+	if o.instID != "" {
+		if _, err := o.instance(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// classID returns the class id carrying the --psa-impl-id
+func (o psaIDs) classID() (*comid.ClassID, error) {
+	b, err := decodePSAID(o.implID)
+	if err != nil || len(b) != psaImplIDLength {
+		return nil, fmt.Errorf("invalid --psa-impl-id %q: expecting %d bytes, base64 or hex encoded", o.implID, psaImplIDLength)
+	}
+
+	return comid.NewImplIDClassID(b)
+}
+
+// instance returns the instance carrying the --psa-inst-id
+func (o psaIDs) instance() (*comid.Instance, error) {
+	b, err := decodePSAID(o.instID)
+	if err != nil || len(b) != psaInstIDLength {
+		return nil, fmt.Errorf("invalid --psa-inst-id %q: expecting %d bytes, base64 or hex encoded", o.instID, psaInstIDLength)
+	}
+
+	if b[0] != eat.UEIDTypeRAND {
+		return nil, fmt.Errorf("invalid --psa-inst-id %q: expecting a RAND ueid (type 0x%02x), got type 0x%02x", o.instID, eat.UEIDTypeRAND, b[0])
+	}
+
+	return comid.NewUEIDInstance(b)
+}
+
+// decodePSAID decodes s, which is either hex or (standard) base64 encoded.
+// The two cannot be confused for the lengths of the PSA ids: for instance, the
+// 64 hex digits of an implementation id would decode to 48 bytes as base64.
+func decodePSAID(s string) ([]byte, error) {
+	if b, err := hex.DecodeString(s); err == nil {
+		return b, nil
+	}
+
+	return base64.StdEncoding.DecodeString(s)
+}
+
+// apply sets the PSA ids in c: the implementation id as the class id of the
+// environments of its reference-values and attester-verification-keys
+// triples, and the instance id as the instance of the environment of its
+// (single) attester-verification-keys triple.  The ids already present in the
+// template are kept if they match, and are otherwise reported as conflicts.
+func (o psaIDs) apply(c *comid.Comid) error {
+	var errs []error
+
+	if o.implID != "" {
+		classID, err := o.classID()
+		if err != nil {
+			return err
+		}
+
+		if rvs := c.Triples.ReferenceValues; rvs != nil {
+			for i := range rvs.Values {
+				where := fmt.Sprintf("reference-values[%d]", i)
+				if err := setPSAImplID(&rvs.Values[i].Environment, classID); err != nil {
+					errs = append(errs, fmt.Errorf("%s: %w", where, err))
+				}
+			}
+		}
+
+		if avks := c.Triples.AttestVerifKeys; avks != nil {
+			for i := range *avks {
+				where := fmt.Sprintf("attester-verification-keys[%d]", i)
+				if err := setPSAImplID(&(*avks)[i].Environment, classID); err != nil {
+					errs = append(errs, fmt.Errorf("%s: %w", where, err))
+				}
+			}
+		}
+	}
+
+	if o.instID != "" {
+		instance, err := o.instance()
+		if err != nil {
+			return err
+		}
+
+		// each instance has its own attestation key
+		avks := c.Triples.AttestVerifKeys
+		if avks == nil || len(*avks) != 1 {
+			n := 0
+			if avks != nil {
+				n = len(*avks)
+			}
+
+			return fmt.Errorf("--psa-inst-id requires exactly one attester-verification-keys triple, found %d", n)
+		}
+
+		env := &(*avks)[0].Environment
+
+		switch {
+		case env.Instance == nil:
+			env.Instance = instance
+		case env.Instance.Type() != instance.Type() || !bytes.Equal(env.Instance.Bytes(), instance.Bytes()):
+			errs = append(errs, fmt.Errorf(
+				"attester-verification-keys[0]: environment instance %s %s conflicts with --psa-inst-id",
+				env.Instance.Type(), env.Instance.String(),
+			))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// setPSAImplID sets the class id of env to classID, unless it already has a
+// class id, which must then be the same
+func setPSAImplID(env *comid.Environment, classID *comid.ClassID) error {
+	if env.Class == nil {
+		env.Class = &comid.Class{}
+	}
+
+	switch cur := env.Class.ClassID; {
+	case cur == nil:
+		env.Class.ClassID = classID
+	case cur.Type() != classID.Type() || !bytes.Equal(cur.Bytes(), classID.Bytes()):
+		return fmt.Errorf("environment class id %s %s conflicts with --psa-impl-id", cur.Type(), cur.String())
+	}
+
+	return nil
+}
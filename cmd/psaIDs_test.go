@@ -0,0 +1,138 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/comid"
+)
+
+const (
+	testPSAImplID = "YWNtZS1pbXBsZW1lbnRhdGlvbi1pZC0wMDAwMDAwMDE="
+	testPSAInstID = "Ac7rrnuJJ6MiflMDz14PH3s0u1Qq1yUKwD+83jbsLxUI"
+)
+
+// testPSAIakPubTemplate is a PSA attestation verification key template that
+// leaves the implementation and instance ids out
+var testPSAIakPubTemplate = `{
+  "tag-identity": { "id": "366D0A0A-5988-45ED-8488-2F2A544F6242" },
+  "triples": {
+    "attester-verification-keys": [
+      {
+        "environment": {
+          "class": { "vendor": "ACME", "model": "RoadRunner" }
+        },
+        "verification-keys": [
+          {
+            "type": "pkix-base64-key",
+            "value": "-----BEGIN PUBLIC KEY-----\nMFkwEwYHKoZIzj0CAQYIKoZIzj0DAQcDQgAEFn0taoAwR3PmrKkYLtAsD9o05KSM6mbgfNCgpuL0g6VpTHkZl73wk5BDxoV7n+Oeee0iIqkW3HMZT3ETiniJdg==\n-----END PUBLIC KEY-----"
+          }
+        ]
+      }
+    ]
+  }
+}`
+
+func Test_ComidCreateCmd_psa_ids(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "iakpub.json", []byte(testPSAIakPubTemplate), 0644))
+
+	implIDBytes, err := base64.StdEncoding.DecodeString(testPSAImplID)
+	require.NoError(t, err)
+
+	cmd := NewComidCreateCmd()
+	cmd.SetArgs([]string{
+		"--template=iakpub.json",
+		"--profile=psa",
+		// hex and base64 are both accepted
+		"--psa-impl-id=" + hex.EncodeToString(implIDBytes),
+		"--psa-inst-id=" + testPSAInstID,
+	})
+	require.NoError(t, cmd.Execute())
+
+	var c comid.Comid
+	require.NoError(t, c.FromCBOR(mustReadFile(t, "iakpub.cbor")))
+
+	env := (*c.Triples.AttestVerifKeys)[0].Environment
+	assert.Equal(t, comid.ImplIDType, env.Class.ClassID.Type())
+	assert.Equal(t, testPSAImplID, env.Class.ClassID.String())
+	assert.Equal(t, "RoadRunner", *env.Class.Model)
+	assert.Equal(t, comid.UEIDType, env.Instance.Type())
+	assert.Equal(t, testPSAInstID, env.Instance.String())
+}
+
+func Test_ComidCreateCmd_psa_ids_matching_template(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "cca.json", []byte(testCCARefValTemplate), 0644))
+
+	cmd := NewComidCreateCmd()
+	cmd.SetArgs([]string{"--template=cca.json", "--profile=cca", "--psa-impl-id=" + testPSAImplID})
+	assert.NoError(t, cmd.Execute())
+}
+
+func Test_psaIDs_apply_conflicts(t *testing.T) {
+	c := testComidFromTemplate(t, testCCARefValTemplate)
+
+	ids := psaIDs{implID: base64.StdEncoding.EncodeToString(make([]byte, psaImplIDLength))}
+	assert.EqualError(t, ids.apply(c),
+		"reference-values[0]: environment class id psa.impl-id "+testPSAImplID+" conflicts with --psa-impl-id")
+
+	// the CCA reference values have no attestation key
+	ids = psaIDs{instID: testPSAInstID}
+	assert.EqualError(t, ids.apply(c), "--psa-inst-id requires exactly one attester-verification-keys triple, found 0")
+}
+
+func Test_ComidCreateCmd_psa_ids_bad_args(t *testing.T) {
+	tvs := []struct {
+		desc     string
+		args     []string
+		expected string
+	}{
+		{
+			desc:     "no profile",
+			args:     []string{"--template=t.json", "--psa-impl-id=" + testPSAImplID},
+			expected: "--psa-impl-id and --psa-inst-id can only be used together with --profile=psa or --profile=cca",
+		},
+		{
+			desc:     "other profile",
+			args:     []string{"--template=t.json", "--profile=http://example.com/profile", "--psa-inst-id=" + testPSAInstID},
+			expected: "--psa-impl-id and --psa-inst-id can only be used together with --profile=psa or --profile=cca",
+		},
+		{
+			desc:     "short impl id",
+			args:     []string{"--template=t.json", "--profile=psa", "--psa-impl-id=AAAA"},
+			expected: `invalid --psa-impl-id "AAAA": expecting 32 bytes, base64 or hex encoded`,
+		},
+		{
+			desc:     "impl id as inst id",
+			args:     []string{"--template=t.json", "--profile=psa", "--psa-inst-id=" + testPSAImplID},
+			expected: `invalid --psa-inst-id "` + testPSAImplID + `": expecting 33 bytes, base64 or hex encoded`,
+		},
+		{
+			desc:     "not a RAND ueid",
+			args:     []string{"--template=t.json", "--profile=psa", "--psa-inst-id=02" + hex.EncodeToString(make([]byte, 32))},
+			expected: `invalid --psa-inst-id "02` + hex.EncodeToString(make([]byte, 32)) + `": expecting a RAND ueid (type 0x01), got type 0x02`,
+		},
+		{
+			desc:     "no templates",
+			args:     []string{"--svn=1", "--env-class-id=1.2.3.4", "--output=c.cbor", "--psa-impl-id=" + testPSAImplID},
+			expected: "--psa-impl-id and --psa-inst-id can only be used together with --template or --template-dir",
+		},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.desc, func(t *testing.T) {
+			cmd := NewComidCreateCmd()
+			cmd.SetArgs(tv.args)
+
+			assert.EqualError(t, cmd.Execute(), tv.expected)
+		})
+	}
+}
@@ -0,0 +1,218 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/afero"
+	"github.com/veraison/corim/corim"
+	cose "github.com/veraison/go-cose"
+)
+
+// verifyQuorum verifies the signatures of the multi-signed (COSE Sign) CoRIM in
+// file using the candidate keys in keyFiles and, if trust anchors are set in
+// opts, the signing certificate carried in the x5chain header of each
+// signature, which must chain up to one of them.  Verification succeeds if the
+// signatures of at least quorum distinct signers, i.e., public keys, verify.
+func verifyQuorum(file string, keyFiles []string, quorum int, opts verifyOptions) error {
+	var (
+		data []byte
+		err  error
+		c    corim.UnsignedCorim
+	)
+
+	if data, err = afero.ReadFile(fs, file); err != nil {
+		return fmt.Errorf("error loading signed CoRIM from %s: %w", file, err)
+	}
+
+	msg := cose.NewSignMessage()
+	if err = msg.UnmarshalCBOR(data); err != nil {
+		return fmt.Errorf("error decoding multi-signed CoRIM from %s: %w", file, err)
+	}
+
+	if err = c.FromCBOR(msg.Payload); err != nil {
+		return fmt.Errorf("error decoding unsigned CoRIM from %s: %w", file, err)
+	}
+
+	if err = c.Valid(); err != nil {
+		return fmt.Errorf("error validating CoRIM from %s: %w", file, err)
+	}
+
+	if err = opts.profileAllowlist.check(c); err != nil {
+		return fmt.Errorf("error verifying %s: %w", file, err)
+	}
+
+	keys := make([]crypto.PublicKey, len(keyFiles))
+	for i, keyFile := range keyFiles {
+		keyData, err := afero.ReadFile(fs, keyFile)
+		if err != nil {
+			return fmt.Errorf("error loading verifying key from %s: %w", keyFile, err)
+		}
+
+		if keys[i], err = parsePublicKey(keyData); err != nil {
+			return fmt.Errorf("error loading verifying key from %s: %w", keyFile, err)
+		}
+	}
+
+	var roots *x509.CertPool
+
+	if len(opts.trustAnchorFiles) != 0 || opts.systemRoots {
+		if roots, err = loadTrustAnchors(opts.trustAnchorFiles, opts.systemRoots, false); err != nil {
+			return err
+		}
+	}
+
+	if err = checkCriticalHeaders(msg.Headers.Protected); err != nil {
+		return fmt.Errorf("error verifying %s: %w", file, err)
+	}
+
+	protected, err := msg.Headers.MarshalProtected()
+	if err != nil {
+		return fmt.Errorf("error decoding multi-signed CoRIM from %s: %w", file, err)
+	}
+
+	var signers []string
+	// the thumbprints of the public keys of the signers already counted
+	seen := map[string]bool{}
+
+	for i, sig := range msg.Signatures {
+		// each signature is subject to the same header checks as a COSE_Sign1
+		// message, an algorithm in the unprotected header being rejected
+		if err := checkAlgHeader(&sig.Headers, false, false); err != nil {
+			fmt.Printf(">> signature [%d]: not counted: %v\n", i, err)
+			continue
+		}
+
+		if err := checkCOSEHeaders(&sig.Headers); err != nil {
+			fmt.Printf(">> signature [%d]: not counted: %v\n", i, err)
+			continue
+		}
+
+		// checkCOSEHeaders makes sure the alg header is there
+		alg, _ := sig.Headers.Protected.Algorithm()
+
+		if err := opts.algPolicy.check(alg); err != nil {
+			fmt.Printf(">> signature [%d] (%s): not counted: %v\n", i, alg, err)
+			continue
+		}
+
+		var (
+			signer string
+			pub    crypto.PublicKey
+		)
+
+		for j, key := range keys {
+			verifier, err := cose.NewVerifier(alg, key)
+			if err != nil {
+				// wrong type of key for the algorithm
+				continue
+			}
+
+			if sig.Verify(verifier, protected, msg.Payload, nil) == nil {
+				signer, pub = keyFiles[j], key
+				break
+			}
+		}
+
+		if pub == nil && roots != nil {
+			chain, err := verifyX5ChainSignature(sig, alg, protected, msg.Payload, roots, opts.ignoreKeyUsage)
+			if err != nil {
+				fmt.Printf(">> signature [%d] (%s): not verified by any candidate key, nor by its x5chain: %v\n", i, alg, err)
+				continue
+			}
+
+			if offline {
+				for _, note := range offlineRevocationNotes(chain) {
+					fmt.Printf(">> offline: %s\n", note)
+				}
+			}
+
+			signer, pub = fmt.Sprintf("certificate %q", chain[0].Subject.String()), chain[0].PublicKey
+		}
+
+		if pub == nil {
+			fmt.Printf(">> signature [%d] (%s): not verified by any candidate key\n", i, alg)
+			continue
+		}
+
+		thumbprint, err := publicKeyThumbprint(pub)
+		if err != nil {
+			fmt.Printf(">> signature [%d] (%s): not counted: %v\n", i, alg, err)
+			continue
+		}
+
+		if seen[thumbprint] {
+			fmt.Printf(">> signature [%d] (%s): verified with %s (already counted)\n", i, alg, signer)
+			continue
+		}
+
+		fmt.Printf(">> signature [%d] (%s): verified with %s\n", i, alg, signer)
+		seen[thumbprint] = true
+		signers = append(signers, signer)
+	}
+
+	fmt.Printf(">> %d of %d signature(s) verified by distinct signers", len(signers), len(msg.Signatures))
+	if len(signers) != 0 {
+		fmt.Printf(": %s", strings.Join(signers, ", "))
+	}
+	fmt.Println()
+
+	if len(signers) < quorum {
+		return fmt.Errorf("error verifying %s: quorum not met: %d signer(s) verified, %d required",
+			file, len(signers), quorum)
+	}
+
+	if err = checkCorimExpectations(c, opts.expectedID, opts.expectedProfile); err != nil {
+		return fmt.Errorf("error verifying %s: %w", file, err)
+	}
+
+	return nil
+}
+
+// verifyX5ChainSignature verifies sig, using alg, with the signing certificate
+// carried in its (protected) x5chain header, which must chain up to one of
+// roots, possibly via the intermediate certificates that follow it.  Unless
+// ignoreKeyUsage, the signing certificate must also permit signing CoRIMs (see
+// checkSigningKeyUsage).  The certificates in the x5chain header, starting with
+// the signing certificate, are returned.
+func verifyX5ChainSignature(
+	sig *cose.Signature, alg cose.Algorithm, protected, payload []byte,
+	roots *x509.CertPool, ignoreKeyUsage bool,
+) ([]*x509.Certificate, error) {
+	v, ok := sig.Headers.Protected[cose.HeaderLabelX5Chain]
+	if !ok {
+		return nil, errors.New("no x5chain header")
+	}
+
+	certs, err := parseX5Chain(v)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = verifyCertificates(certs[0], certs[1:], roots); err != nil {
+		return nil, err
+	}
+
+	if !ignoreKeyUsage {
+		if err = checkSigningKeyUsage(certs[0]); err != nil {
+			return nil, err
+		}
+	}
+
+	verifier, err := cose.NewVerifier(alg, certs[0].PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = sig.Verify(verifier, protected, payload, nil); err != nil {
+		return nil, fmt.Errorf("signature verification failed: %w", err)
+	}
+
+	return certs, nil
+}
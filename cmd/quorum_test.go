@@ -0,0 +1,337 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/corim"
+	cose "github.com/veraison/go-cose"
+)
+
+// newTestMultiSignedCorim returns testCorimValid signed (COSE Sign) with the
+// supplied signers
+func newTestMultiSignedCorim(t *testing.T, signers ...cose.Signer) []byte {
+	msg := cose.NewSignMessage()
+	msg.Payload = testCorimValid
+	msg.Headers.Protected[cose.HeaderLabelContentType] = corim.ContentType
+
+	for _, signer := range signers {
+		sig := cose.NewSignature()
+		sig.Headers.Protected.SetAlgorithm(signer.Algorithm())
+		msg.Signatures = append(msg.Signatures, sig)
+	}
+
+	require.NoError(t, msg.Sign(rand.Reader, nil, signers...))
+
+	data, err := msg.MarshalCBOR()
+	require.NoError(t, err)
+
+	return data
+}
+
+// setupQuorumTest writes a CoRIM signed by the EC and EdDSA test keys to
+// multi.cbor, together with three candidate keys: ec.jwk and ed.jwk (the
+// signers) and other.pem (not a signer)
+func setupQuorumTest(t *testing.T) {
+	ecSigner, err := corim.NewSignerFromJWK(testECKey)
+	require.NoError(t, err)
+	edSigner, err := corim.NewSignerFromJWK(testEdDSAKey)
+	require.NoError(t, err)
+
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	spki, err := x509.MarshalPKIXPublicKey(other.Public())
+	require.NoError(t, err)
+
+	fs = afero.NewMemMapFs()
+	err = afero.WriteFile(fs, "multi.cbor", newTestMultiSignedCorim(t, ecSigner, edSigner), 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ec.jwk", testECKey, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ed.jwk", testEdDSAKey, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "other.pem", pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: spki}), 0644)
+	require.NoError(t, err)
+}
+
+// newTestX5ChainMultiSignedCorim is like newTestMultiSignedCorim, with an
+// additional signature by the leaf key of each of pkis, carrying the leaf and
+// intermediate certificates in its x5chain header
+func newTestX5ChainMultiSignedCorim(t *testing.T, pkis []testPKI, signers ...cose.Signer) []byte {
+	msg := cose.NewSignMessage()
+	msg.Payload = testCorimValid
+	msg.Headers.Protected[cose.HeaderLabelContentType] = corim.ContentType
+
+	for _, signer := range signers {
+		sig := cose.NewSignature()
+		sig.Headers.Protected.SetAlgorithm(signer.Algorithm())
+		msg.Signatures = append(msg.Signatures, sig)
+	}
+
+	for _, pki := range pkis {
+		signer, err := cose.NewSigner(cose.AlgorithmES256, pki.leafKey)
+		require.NoError(t, err)
+
+		sig := cose.NewSignature()
+		sig.Headers.Protected.SetAlgorithm(cose.AlgorithmES256)
+		sig.Headers.Protected[cose.HeaderLabelX5Chain] = []interface{}{pki.leafDER, pki.intermediateDER}
+		msg.Signatures = append(msg.Signatures, sig)
+		signers = append(signers, signer)
+	}
+
+	require.NoError(t, msg.Sign(rand.Reader, nil, signers...))
+
+	data, err := msg.MarshalCBOR()
+	require.NoError(t, err)
+
+	return data
+}
+
+func Test_CorimVerifyCmd_quorum_ok(t *testing.T) {
+	setupQuorumTest(t)
+
+	cmd := NewCorimVerifyCmd()
+
+	args := []string{
+		"--file=multi.cbor",
+		"--quorum=2",
+		"--quorum-key=other.pem",
+		"--quorum-key=ed.jwk",
+		"--quorum-key=ec.jwk",
+	}
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+	assert.NoError(t, err)
+}
+
+func Test_CorimVerifyCmd_quorum_not_met(t *testing.T) {
+	setupQuorumTest(t)
+
+	cmd := NewCorimVerifyCmd()
+
+	args := []string{
+		"--file=multi.cbor",
+		"--quorum=2",
+		"--quorum-key=other.pem",
+		"--quorum-key=ec.jwk",
+	}
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "error verifying multi.cbor: quorum not met: 1 signer(s) verified, 2 required")
+}
+
+func Test_verifyQuorum_same_signer_counted_once(t *testing.T) {
+	ecSigner, err := corim.NewSignerFromJWK(testECKey)
+	require.NoError(t, err)
+
+	fs = afero.NewMemMapFs()
+	err = afero.WriteFile(fs, "multi.cbor", newTestMultiSignedCorim(t, ecSigner, ecSigner), 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ec.jwk", testECKey, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ec-again.jwk", testECKey, 0644)
+	require.NoError(t, err)
+
+	// the first matching candidate key is credited with both signatures
+	err = verifyQuorum("multi.cbor", []string{"ec.jwk", "ec-again.jwk"}, 2, verifyOptions{})
+	assert.EqualError(t, err, "error verifying multi.cbor: quorum not met: 1 signer(s) verified, 2 required")
+
+	assert.NoError(t, verifyQuorum("multi.cbor", []string{"ec.jwk"}, 1, verifyOptions{}))
+}
+
+func Test_verifyQuorum_expectations(t *testing.T) {
+	setupQuorumTest(t)
+
+	err := verifyQuorum("multi.cbor", []string{"ec.jwk"}, 1, verifyOptions{expectedProfile: "http://example.com/other"})
+	assert.ErrorContains(t, err, "error verifying multi.cbor: CoRIM profile mismatch")
+}
+
+func Test_verifyQuorum_not_multi_signed(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "signed.cbor", testSignedCorimValid, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ec.jwk", testECKey, 0644)
+	require.NoError(t, err)
+
+	err = verifyQuorum("signed.cbor", []string{"ec.jwk"}, 1, verifyOptions{})
+	assert.ErrorContains(t, err, "error decoding multi-signed CoRIM from signed.cbor")
+}
+
+func Test_verifyQuorum_signature_headers(t *testing.T) {
+	ecSigner, err := corim.NewSignerFromJWK(testECKey)
+	require.NoError(t, err)
+	edSigner, err := corim.NewSignerFromJWK(testEdDSAKey)
+	require.NoError(t, err)
+
+	msg := cose.NewSignMessage()
+	msg.Payload = testCorimValid
+	msg.Headers.Protected[cose.HeaderLabelContentType] = corim.ContentType
+
+	// the EC signature carries an unknown critical header
+	ecSig := cose.NewSignature()
+	ecSig.Headers.Protected.SetAlgorithm(ecSigner.Algorithm())
+	ecSig.Headers.Protected[cose.HeaderLabelCritical] = []interface{}{int64(99)}
+	ecSig.Headers.Protected[int64(99)] = "hello!"
+
+	edSig := cose.NewSignature()
+	edSig.Headers.Protected.SetAlgorithm(edSigner.Algorithm())
+
+	msg.Signatures = append(msg.Signatures, ecSig, edSig)
+	require.NoError(t, msg.Sign(rand.Reader, nil, ecSigner, edSigner))
+
+	data, err := msg.MarshalCBOR()
+	require.NoError(t, err)
+
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "multi.cbor", data, 0644))
+	require.NoError(t, afero.WriteFile(fs, "ec.jwk", testECKey, 0644))
+	require.NoError(t, afero.WriteFile(fs, "ed.jwk", testEdDSAKey, 0644))
+
+	err = withDisplayOutput("out.txt", func() error {
+		return verifyQuorum("multi.cbor", []string{"ec.jwk", "ed.jwk"}, 2, verifyOptions{})
+	})
+	assert.EqualError(t, err, "error verifying multi.cbor: quorum not met: 1 signer(s) verified, 2 required")
+	assert.Contains(t, string(mustReadFile(t, "out.txt")),
+		">> signature [0]: not counted: crit header: unrecognized critical header 99")
+
+	assert.NoError(t, verifyQuorum("multi.cbor", []string{"ed.jwk"}, 1, verifyOptions{}))
+}
+
+func Test_CorimVerifyCmd_quorum_trust_anchors(t *testing.T) {
+	pki1, pki2 := newTestPKI(t), newTestPKI(t)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "multi.cbor", newTestX5ChainMultiSignedCorim(t, []testPKI{pki1, pki2}), 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "root1.pem", pki1.rootPEM(), 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "root2.pem", pki2.rootPEM(), 0644)
+	require.NoError(t, err)
+
+	cmd := NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=multi.cbor", "--quorum=2", "--trust-anchor=root1.pem", "--trust-anchor=root2.pem"})
+	assert.NoError(t, cmd.Execute())
+
+	// the signer whose certificate does not chain to the trust anchors is not
+	// counted
+	cmd = NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=multi.cbor", "--quorum=2", "--trust-anchor=root1.pem"})
+	err = withDisplayOutput("out.txt", cmd.Execute)
+	assert.EqualError(t, err, "error verifying multi.cbor: quorum not met: 1 signer(s) verified, 2 required")
+
+	out := string(mustReadFile(t, "out.txt"))
+	assert.Contains(t, out, `>> signature [0] (ES256): verified with certificate "CN=Test Signer"`)
+	assert.Contains(t, out, ">> signature [1] (ES256): not verified by any candidate key, nor by its x5chain: certificate chain validation failed")
+}
+
+func Test_CorimVerifyCmd_quorum_trust_anchors_same_signer_counted_once(t *testing.T) {
+	pki := newTestPKI(t)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "multi.cbor", newTestX5ChainMultiSignedCorim(t, []testPKI{pki, pki}), 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "root.pem", pki.rootPEM(), 0644)
+	require.NoError(t, err)
+
+	cmd := NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=multi.cbor", "--quorum=2", "--trust-anchor=root.pem"})
+	err = withDisplayOutput("out.txt", cmd.Execute)
+	assert.EqualError(t, err, "error verifying multi.cbor: quorum not met: 1 signer(s) verified, 2 required")
+	assert.Contains(t, string(mustReadFile(t, "out.txt")),
+		`>> signature [1] (ES256): verified with certificate "CN=Test Signer" (already counted)`)
+}
+
+func Test_CorimVerifyCmd_quorum_keys_and_trust_anchors(t *testing.T) {
+	ecSigner, err := corim.NewSignerFromJWK(testECKey)
+	require.NoError(t, err)
+
+	pki := newTestPKI(t)
+
+	fs = afero.NewMemMapFs()
+	err = afero.WriteFile(fs, "multi.cbor", newTestX5ChainMultiSignedCorim(t, []testPKI{pki}, ecSigner), 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ec.jwk", testECKey, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "root.pem", pki.rootPEM(), 0644)
+	require.NoError(t, err)
+
+	cmd := NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=multi.cbor", "--quorum=2", "--quorum-key=ec.jwk", "--trust-anchor=root.pem"})
+	assert.NoError(t, cmd.Execute())
+
+	// without trust anchors, x5chain headers are not looked at
+	cmd = NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=multi.cbor", "--quorum=1", "--quorum-key=ec.jwk"})
+	assert.NoError(t, cmd.Execute())
+
+	err = verifyQuorum("multi.cbor", []string{"ec.jwk"}, 2, verifyOptions{})
+	assert.EqualError(t, err, "error verifying multi.cbor: quorum not met: 1 signer(s) verified, 2 required")
+}
+
+func Test_CorimVerifyCmd_quorum_trust_anchors_key_usage(t *testing.T) {
+	pki := newTestPKIWithLeafUsage(t, x509.KeyUsageKeyEncipherment)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "multi.cbor", newTestX5ChainMultiSignedCorim(t, []testPKI{pki}), 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "root.pem", pki.rootPEM(), 0644)
+	require.NoError(t, err)
+
+	err = verifyQuorum("multi.cbor", nil, 1, verifyOptions{trustAnchorFiles: []string{"root.pem"}})
+	assert.EqualError(t, err, "error verifying multi.cbor: quorum not met: 0 signer(s) verified, 1 required")
+
+	err = verifyQuorum("multi.cbor", nil, 1, verifyOptions{trustAnchorFiles: []string{"root.pem"}, ignoreKeyUsage: true})
+	assert.NoError(t, err)
+}
+
+func Test_CorimVerifyCmd_quorum_bad_args(t *testing.T) {
+	tvs := []struct {
+		args     []string
+		expected string
+	}{
+		{
+			[]string{"--file=multi.cbor", "--quorum=-1"},
+			"--quorum must not be negative",
+		},
+		{
+			[]string{"--file=multi.cbor", "--key=ec.jwk", "--quorum-key=ed.jwk"},
+			"--quorum-key can only be used together with --quorum",
+		},
+		{
+			[]string{"--dir=archive", "--quorum=1", "--quorum-key=ed.jwk"},
+			"--quorum cannot be used together with --dir",
+		},
+		{
+			[]string{"--file=multi.cbor", "--quorum=1", "--quorum-key=ed.jwk", "--key=ec.jwk"},
+			"--quorum cannot be used together with --key",
+		},
+		{
+			[]string{"--file=multi.cbor", "--quorum=1", "--quorum-key=ed.jwk", "--print-chain"},
+			"--quorum can only be combined with --expected-id, --expected-profile, --require-profile-in, --allowed-algs, --trust-anchor, --system-roots and --ignore-key-usage",
+		},
+		{
+			[]string{"--file=multi.cbor", "--quorum=3", "--quorum-key=ec.jwk", "--quorum-key=ed.jwk"},
+			"--quorum of 3 cannot be met with 2 candidate key(s)",
+		},
+	}
+
+	for _, tv := range tvs {
+		cmd := NewCorimVerifyCmd()
+		cmd.SetArgs(tv.args)
+
+		err := cmd.Execute()
+		assert.EqualError(t, err, tv.expected, tv.args)
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/veraison/corim/comid"
+)
+
+// checkRawValueMasks checks that each raw-value-mask in the reference and
+// endorsed value measurements of c comes with a raw-value of the same length,
+// since the mask selects the bits of the raw-value that are compared.  All the
+// mismatches are reported, together with the environment and key of the
+// measurement.
+func checkRawValueMasks(c *comid.Comid) error {
+	var errs []error
+
+	triples := []struct {
+		name string
+		vts  *comid.ValueTriples
+	}{
+		{"reference-values", c.Triples.ReferenceValues},
+		{"endorsed-values", c.Triples.EndorsedValues},
+	}
+
+	for _, t := range triples {
+		if t.vts == nil {
+			continue
+		}
+
+		for _, vt := range t.vts.Values {
+			for _, m := range vt.Measurements.Values {
+				if m.Val.RawValueMask == nil {
+					continue
+				}
+
+				err := checkRawValueMask(&m.Val)
+				if err == nil {
+					continue
+				}
+
+				env, jerr := json.Marshal(vt.Environment)
+				if jerr != nil {
+					return fmt.Errorf("error encoding environment: %w", jerr)
+				}
+
+				var key []byte
+				if m.Key != nil && m.Key.IsSet() {
+					if key, jerr = json.Marshal(m.Key); jerr != nil {
+						return fmt.Errorf("error encoding measurement key: %w", jerr)
+					}
+				}
+
+				errs = append(errs, fmt.Errorf("%s: environment %s, key %s: %w", t.name, env, orDash(string(key)), err))
+			}
+		}
+	}
+
+	if len(errs) != 0 {
+		return fmt.Errorf("raw-value mask mismatch: %w", errors.Join(errs...))
+	}
+
+	return nil
+}
+
+func checkRawValueMask(v *comid.Mval) error {
+	if v.RawValue == nil {
+		return errors.New("raw-value-mask without raw-value")
+	}
+
+	value, err := v.RawValue.GetBytes()
+	if err != nil {
+		return fmt.Errorf("raw-value: %w", err)
+	}
+
+	if len(value) != len(*v.RawValueMask) {
+		return fmt.Errorf("raw-value-mask must be as long as raw-value (%d bytes), got %d",
+			len(value), len(*v.RawValueMask))
+	}
+
+	return nil
+}
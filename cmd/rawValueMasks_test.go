@@ -0,0 +1,116 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/comid"
+)
+
+// rawValueTemplate returns a CoMID template with a single measurement, made of
+// the supplied (JSON-encoded) measurement values
+func rawValueTemplate(values string) []byte {
+	return []byte(fmt.Sprintf(`{
+  "tag-identity": { "id": "raw-value-test" },
+  "entities": [
+    { "name": "ACME Ltd.", "regid": "https://acme.example", "roles": [ "tagCreator", "creator", "maintainer" ] }
+  ],
+  "triples": {
+    "reference-values": [
+      {
+        "environment": { "class": { "id": { "type": "oid", "value": "1.2.3.4" } } },
+        "measurements": [ { "key": { "type": "uint", "value": 1 }, "value": { %s } } ]
+      }
+    ]
+  }
+}`, values))
+}
+
+func newTestComidWithRawValue(t *testing.T, value, mask []byte) *comid.Comid {
+	c := newTestComid(t)
+
+	m := &c.Triples.ReferenceValues.Values[0].Measurements.Values[0]
+	m.Val.RawValue = comid.NewRawValue().SetBytes(value)
+	m.Val.RawValueMask = &mask
+
+	return c
+}
+
+func Test_checkRawValueMasks_ok(t *testing.T) {
+	assert.NoError(t, checkRawValueMasks(newTestComid(t)))
+	assert.NoError(t, checkRawValueMasks(newTestComidWithRawValue(t, []byte{0, 1, 2, 3}, []byte{0xff, 0, 0xff, 0})))
+}
+
+func Test_checkRawValueMasks_length_mismatch(t *testing.T) {
+	err := checkRawValueMasks(newTestComidWithRawValue(t, []byte{0, 1, 2, 3}, []byte{0xff, 0xff}))
+	require.Error(t, err)
+	assert.Regexp(t, `^raw-value mask mismatch: reference-values: environment \{.*"model":"RoadRunner".*\}, key \{.*\}: raw-value-mask must be as long as raw-value \(4 bytes\), got 2$`, err.Error())
+}
+
+func Test_checkRawValueMasks_no_raw_value(t *testing.T) {
+	c := newTestComid(t)
+
+	mask := []byte{0xff}
+	c.Triples.ReferenceValues.Values[0].Measurements.Values[0].Val.RawValueMask = &mask
+
+	assert.ErrorContains(t, checkRawValueMasks(c), "raw-value-mask without raw-value")
+}
+
+func Test_ComidCreateCmd_raw_value_mask(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	// raw-value 00010203, raw-value-mask ffff0000
+	err := afero.WriteFile(fs, "rv.json", rawValueTemplate(
+		`"raw-value": { "type": "bytes", "value": "AAECAw==" }, "raw-value-mask": "//8AAA=="`,
+	), 0644)
+	require.NoError(t, err)
+
+	cmd := NewComidCreateCmd()
+	cmd.SetArgs([]string{"--template=rv.json"})
+	require.NoError(t, cmd.Execute())
+
+	var c comid.Comid
+	require.NoError(t, c.FromCBOR(mustReadFile(t, "rv.cbor")))
+
+	v := c.Triples.ReferenceValues.Values[0].Measurements.Values[0].Val
+	require.NotNil(t, v.RawValue)
+	require.NotNil(t, v.RawValueMask)
+
+	value, err := v.RawValue.GetBytes()
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0, 1, 2, 3}, value)
+	assert.Equal(t, []byte{0xff, 0xff, 0, 0}, *v.RawValueMask)
+}
+
+func Test_ComidCreateCmd_raw_value_mask_length_mismatch(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	// 4 bytes raw-value, 2 bytes raw-value-mask
+	err := afero.WriteFile(fs, "rv.json", rawValueTemplate(
+		`"raw-value": { "type": "bytes", "value": "AAECAw==" }, "raw-value-mask": "//8="`,
+	), 0644)
+	require.NoError(t, err)
+
+	cmd := NewComidCreateCmd()
+	cmd.SetArgs([]string{"--template=rv.json"})
+	assert.EqualError(t, cmd.Execute(), "1/1 creations(s) failed")
+
+	_, err = fs.Stat("rv.cbor")
+	assert.Error(t, err)
+
+	_, err = templateToCBOR("rv.json", ".", false, jsonLimits{}, envExpansion{}, nil, psaIDs{})
+	assert.EqualError(t, err, `error validating template rv.json: raw-value mask mismatch: reference-values: environment {"class":{"id":{"type":"oid","value":"1.2.3.4"}}}, key {"type":"uint","value":1}: raw-value-mask must be as long as raw-value (4 bytes), got 2`)
+}
+
+func Test_ComidCreateCmd_raw_value_mask_without_raw_value(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "rv.json", rawValueTemplate(`"raw-value-mask": "//8="`), 0644)
+	require.NoError(t, err)
+
+	_, err = templateToCBOR("rv.json", ".", false, jsonLimits{}, envExpansion{}, nil, psaIDs{})
+	assert.ErrorContains(t, err, "raw-value-mask without raw-value")
+}
@@ -0,0 +1,249 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/swid"
+)
+
+// measurementValuesView is the rendering of the measurement values of one
+// measurement of a reference or endorsed values triple, used by "comid display
+// --raw-values"
+type measurementValuesView struct {
+	Triple      string          `json:"triple"`
+	Environment json.RawMessage `json:"environment"`
+	Key         json.RawMessage `json:"key,omitempty"`
+	Values      []rawValueView  `json:"values"`
+}
+
+// rawValueView is a single measurement value, together with its CBOR type and
+// a type-specific rendering
+type rawValueView struct {
+	Name     string `json:"name"`
+	CBORType string `json:"cbor-type"`
+	Value    string `json:"value"`
+}
+
+// rawValues returns the measurement values of the reference and endorsed values
+// triples of c
+func rawValues(c *comid.Comid) ([]measurementValuesView, error) {
+	var views []measurementValuesView
+
+	triples := []struct {
+		name string
+		vts  *comid.ValueTriples
+	}{
+		{"reference-values", c.Triples.ReferenceValues},
+		{"endorsed-values", c.Triples.EndorsedValues},
+	}
+
+	for _, t := range triples {
+		if t.vts == nil {
+			continue
+		}
+
+		for i, vt := range t.vts.Values {
+			env, err := json.Marshal(vt.Environment)
+			if err != nil {
+				return nil, fmt.Errorf("error encoding environment of %s triple %d: %w", t.name, i, err)
+			}
+
+			for j, m := range vt.Measurements.Values {
+				v := measurementValuesView{Triple: t.name, Environment: env}
+
+				if m.Key != nil && m.Key.IsSet() {
+					if v.Key, err = json.Marshal(m.Key); err != nil {
+						return nil, fmt.Errorf("error encoding key of measurement %d in %s triple %d: %w", j, t.name, i, err)
+					}
+				}
+
+				if v.Values, err = typedValues(&m.Val); err != nil {
+					return nil, fmt.Errorf("error rendering measurement %d in %s triple %d: %w", j, t.name, i, err)
+				}
+
+				views = append(views, v)
+			}
+		}
+	}
+
+	return views, nil
+}
+
+// typedValues renders each of the values set in v, in the order of their
+// measurement-values-map code points.  Digests and flags are expanded into one
+// value per digest and flag.
+func typedValues(v *comid.Mval) ([]rawValueView, error) {
+	var values []rawValueView
+
+	add := func(name, cborType, value string) {
+		values = append(values, rawValueView{Name: name, CBORType: cborType, Value: value})
+	}
+
+	if v.Ver != nil {
+		add("version", "map (version-map)", fmt.Sprintf("%s (scheme %s)", v.Ver.Version, v.Ver.Scheme.String()))
+	}
+
+	if v.SVN != nil {
+		add("svn", svnCBORType(v.SVN), fmt.Sprintf("%s (%s)", v.SVN.Value.String(), v.SVN.Value.Type()))
+	}
+
+	if v.Digests != nil {
+		for i, d := range *v.Digests {
+			add(fmt.Sprintf("digests[%d]", i), digestCBORType(d), digestString(d))
+		}
+	}
+
+	if v.Flags != nil {
+		for _, f := range flagValues(v.Flags) {
+			add("flags."+f.name, "bool", fmt.Sprint(*f.value))
+		}
+	}
+
+	if v.RawValue != nil {
+		b, err := v.RawValue.GetBytes()
+		if err != nil {
+			return nil, fmt.Errorf("raw-value: %w", err)
+		}
+		add("raw-value", fmt.Sprintf("tag 560 (bstr, %d bytes)", len(b)), hex.EncodeToString(b))
+	}
+
+	if v.RawValueMask != nil {
+		add("raw-value-mask", bytesCBORType("bstr", *v.RawValueMask), hex.EncodeToString(*v.RawValueMask))
+	}
+
+	if v.MACAddr != nil {
+		// formatted as EUI-48 or EUI-64, with colons
+		add("mac-addr", bytesCBORType("bstr", *v.MACAddr), net.HardwareAddr(*v.MACAddr).String())
+	}
+
+	if v.IPAddr != nil {
+		add("ip-addr", bytesCBORType("bstr", *v.IPAddr), v.IPAddr.String())
+	}
+
+	if v.SerialNumber != nil {
+		add("serial-number", "tstr", fmt.Sprintf("%q", *v.SerialNumber))
+	}
+
+	if v.UEID != nil {
+		add("ueid", bytesCBORType("bstr", *v.UEID), hex.EncodeToString(*v.UEID))
+	}
+
+	if v.UUID != nil {
+		add("uuid", "bstr (16 bytes)", v.UUID.String())
+	}
+
+	if v.IntegrityRegisters != nil {
+		for _, r := range integrityRegisterValues(v.IntegrityRegisters) {
+			add("integrity-registers["+r.index+"]", "array (digests)", r.digests)
+		}
+	}
+
+	if !v.Extensions.IsEmpty() {
+		j, err := json.Marshal(v.Extensions.IMapValue)
+		if err != nil {
+			return nil, fmt.Errorf("extensions: %w", err)
+		}
+		add("extensions", "map", string(j))
+	}
+
+	return values, nil
+}
+
+func svnCBORType(svn *comid.SVN) string {
+	switch svn.Value.(type) {
+	case *comid.TaggedSVN, comid.TaggedSVN:
+		return "tag 552 (uint)"
+	case *comid.TaggedMinSVN, comid.TaggedMinSVN:
+		return "tag 553 (uint)"
+	default:
+		return fmt.Sprintf("%T", svn.Value)
+	}
+}
+
+func digestCBORType(h swid.HashEntry) string {
+	return fmt.Sprintf("array [int, bstr (%d bytes)]", len(h.HashValue))
+}
+
+func digestString(h swid.HashEntry) string {
+	return h.AlgIDToString() + ":" + hex.EncodeToString(h.HashValue)
+}
+
+func bytesCBORType(base string, b []byte) string {
+	return fmt.Sprintf("%s (%d bytes)", base, len(b))
+}
+
+type flagValue struct {
+	name  string
+	value *bool
+}
+
+// flagValues returns the flags that are set in f, in code point order
+func flagValues(f *comid.FlagsMap) []flagValue {
+	all := []flagValue{
+		{"is-configured", f.IsConfigured},
+		{"is-secure", f.IsSecure},
+		{"is-recovery", f.IsRecovery},
+		{"is-debug", f.IsDebug},
+		{"is-replay-protected", f.IsReplayProtected},
+		{"is-integrity-protected", f.IsIntegrityProtected},
+		{"is-runtime-meas", f.IsRuntimeMeasured},
+		{"is-immutable", f.IsImmutable},
+		{"is-tcb", f.IsTcb},
+	}
+
+	var set []flagValue
+	for _, fv := range all {
+		if fv.value != nil {
+			set = append(set, fv)
+		}
+	}
+
+	return set
+}
+
+type integrityRegisterValue struct {
+	index   string
+	digests string
+}
+
+// integrityRegisterValues returns the digests of each register in r, sorted by
+// register index (uint indices first)
+func integrityRegisterValues(r *comid.IntegrityRegisters) []integrityRegisterValue {
+	var values []integrityRegisterValue
+
+	for idx, ds := range r.IndexMap {
+		var digests []string
+		for _, d := range ds {
+			digests = append(digests, digestString(d))
+		}
+
+		index := fmt.Sprintf("%q", idx)
+		if _, ok := idx.(string); !ok {
+			index = fmt.Sprint(idx)
+		}
+
+		values = append(values, integrityRegisterValue{index: index, digests: strings.Join(digests, ", ")})
+	}
+
+	sort.Slice(values, func(i, j int) bool {
+		ui, uj := !strings.HasPrefix(values[i].index, `"`), !strings.HasPrefix(values[j].index, `"`)
+		if ui != uj {
+			return ui
+		}
+		if ui && len(values[i].index) != len(values[j].index) {
+			return len(values[i].index) < len(values[j].index)
+		}
+		return values[i].index < values[j].index
+	})
+
+	return values
+}
@@ -0,0 +1,165 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/swid"
+)
+
+var testComidRawValuesJSON = `{
+  "tag-identity": {
+    "id": "43BBE37F-2E61-4B33-AED3-53CFF1428B16"
+  },
+  "entities": [
+    {
+      "name": "ACME Ltd.",
+      "regid": "https://acme.example",
+      "roles": [ "tagCreator", "creator", "maintainer" ]
+    }
+  ],
+  "triples": {
+    "reference-values": [
+      {
+        "environment": {
+          "class": {
+            "vendor": "ACME",
+            "model": "RoadRunner"
+          }
+        },
+        "measurements": [
+          {
+            "key": {
+              "type": "uint",
+              "value": 1
+            },
+            "value": {
+              "svn": {
+                "type": "exact-value",
+                "value": 3
+              },
+              "digests": [
+                "sha-256;5Fty9cDAtXLbTY06t+l/No/3TmI0eoJN7LZ6hOUiTXU="
+              ],
+              "flags": {
+                "is-secure": true,
+                "is-debug": false
+              },
+              "raw-value": {
+                "type": "bytes",
+                "value": "3q2+7w=="
+              },
+              "mac-addr": "02:00:5e:10:00:01",
+              "ip-addr": "192.0.2.1",
+              "serial-number": "C02X70VHJHD5"
+            }
+          }
+        ]
+      }
+    ]
+  }
+}`
+
+func newTestComidRawValues(t *testing.T) *comid.Comid {
+	var c comid.Comid
+	require.NoError(t, c.FromJSON([]byte(testComidRawValuesJSON)))
+	return &c
+}
+
+func Test_rawValues(t *testing.T) {
+	views, err := rawValues(newTestComidRawValues(t))
+	require.NoError(t, err)
+	require.Len(t, views, 1)
+
+	assert.Equal(t, "reference-values", views[0].Triple)
+	assert.JSONEq(t, `{"type":"uint","value":1}`, string(views[0].Key))
+
+	expected := []rawValueView{
+		{"svn", "tag 552 (uint)", "3 (exact-value)"},
+		{"digests[0]", "array [int, bstr (32 bytes)]", "sha-256:e45b72f5c0c0b572db4d8d3ab7e97f368ff74e62347a824decb67a84e5224d75"},
+		{"flags.is-secure", "bool", "true"},
+		{"flags.is-debug", "bool", "false"},
+		{"raw-value", "tag 560 (bstr, 4 bytes)", "deadbeef"},
+		{"mac-addr", "bstr (6 bytes)", "02:00:5e:10:00:01"},
+		{"ip-addr", "bstr (16 bytes)", "192.0.2.1"}, // IPv4-mapped, as parsed from JSON
+		{"serial-number", "tstr", `"C02X70VHJHD5"`},
+	}
+
+	assert.Equal(t, expected, views[0].Values)
+}
+
+func Test_rawValues_no_value_triples(t *testing.T) {
+	var c comid.Comid
+	require.NoError(t, c.FromJSON([]byte(comid.PSAKeysJSONTemplate)))
+
+	views, err := rawValues(&c)
+	require.NoError(t, err)
+	assert.Empty(t, views)
+}
+
+func Test_integrityRegisterValues_order(t *testing.T) {
+	r := comid.NewIntegrityRegisters()
+	d := swid.HashEntry{HashAlgID: swid.Sha256, HashValue: make([]byte, 32)}
+	require.NoError(t, r.AddDigest("pcr-b", d))
+	require.NoError(t, r.AddDigest(uint64(10), d))
+	require.NoError(t, r.AddDigest(uint64(2), d))
+
+	values := integrityRegisterValues(r)
+	require.Len(t, values, 3)
+	assert.Equal(t, "2", values[0].index)
+	assert.Equal(t, "10", values[1].index)
+	assert.Equal(t, `"pcr-b"`, values[2].index)
+}
+
+func Test_ComidDisplayCmd_raw_values_ok(t *testing.T) {
+	data, err := newTestComidRawValues(t).ToCBOR()
+	require.NoError(t, err)
+
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "values.cbor", data, 0644))
+
+	for _, format := range []string{"--json=false", "--json"} {
+		cmd := NewComidDisplayCmd()
+		cmd.SetArgs([]string{
+			"--file=values.cbor",
+			"--raw-values",
+			"--output=out.txt",
+			format,
+		})
+
+		require.NoError(t, cmd.Execute())
+
+		out, err := afero.ReadFile(fs, "out.txt")
+		require.NoError(t, err)
+		assert.Contains(t, string(out), "02:00:5e:10:00:01")
+
+		if format == "--json" {
+			var views []measurementValuesView
+			// skip the ">> [values.cbor]" heading
+			j := out[len(">> [values.cbor]\n"):]
+			require.NoError(t, json.Unmarshal(j, &views))
+			assert.Len(t, views, 1)
+		} else {
+			assert.Contains(t, string(out), "  mac-addr (bstr (6 bytes)): 02:00:5e:10:00:01\n")
+		}
+	}
+}
+
+func Test_ComidDisplayCmd_raw_values_with_verification_keys(t *testing.T) {
+	cmd := NewComidDisplayCmd()
+	cmd.SetArgs([]string{
+		"--file=ok.cbor",
+		"--raw-values",
+		"--verification-keys",
+	})
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "--verification-keys and --raw-values cannot be used together")
+}
@@ -0,0 +1,173 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/veraison/apiclient/auth"
+)
+
+// maxRemoteCorimSize is the largest signed CoRIM fetched by "corim verify
+// --url".  As the signature covers the whole payload, the CoRIM is downloaded
+// in full (no range requests are made) and buffered before being verified.
+// The limit also applies to a streamed (chunked) response, whose size is not
+// known in advance.
+const maxRemoteCorimSize = 64 << 20
+
+// remoteCorimClient is the HTTP client used to fetch remote CoRIMs
+var remoteCorimClient = &http.Client{Timeout: 60 * time.Second}
+
+// remoteCorim fetches signed CoRIMs over HTTP, optionally keeping a copy of
+// each, together with its ETag, in cacheDir so that unchanged CoRIMs are not
+// downloaded again.  It is also used for the other artifacts that cocli fetches
+// the same way, such as the signing certificate of "corim sign --cert-url".
+type remoteCorim struct {
+	url      string
+	cacheDir string
+	auth     auth.IAuthenticator
+	// what is fetched, for error messages
+	what string
+	// the Accept header of the request
+	accept string
+	// the extension of the cached copy
+	cacheExt string
+	// if set, the progress lines are not printed
+	quiet bool
+}
+
+func newRemoteCorim(rawURL, cacheDir string, a auth.IAuthenticator) (*remoteCorim, error) {
+	if err := checkHTTPURL("--url", rawURL); err != nil {
+		return nil, err
+	}
+
+	return &remoteCorim{
+		url:      rawURL,
+		cacheDir: cacheDir,
+		auth:     a,
+		what:     "signed CoRIM",
+		accept:   "application/rim+cose, application/cose, */*;q=0.5",
+		cacheExt: ".cbor",
+	}, nil
+}
+
+// checkHTTPURL checks that rawURL, the value of flag, is an http(s) URL
+func checkHTTPURL(flag, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid %s %q: expecting an http or https URL", flag, rawURL)
+	}
+
+	return nil
+}
+
+// cacheFiles returns the names of the files in which the CoRIM and its ETag
+// are cached, derived from the URL and from authorization, the Authorization
+// header of the request (if any), so that a copy fetched with some credentials
+// is never served to a request made with other (or no) credentials.  Only the
+// hash of the header ends up in the file names.
+func (o *remoteCorim) cacheFiles(authorization string) (string, string) {
+	key := o.url
+	if authorization != "" {
+		key += "\x00" + authorization
+	}
+
+	sum := sha256.Sum256([]byte(key))
+	base := filepath.Join(o.cacheDir, hex.EncodeToString(sum[:]))
+
+	return base + o.cacheExt, base + ".etag"
+}
+
+// fetch returns the signed CoRIM at the URL.  If a cached copy exists, it is
+// only downloaded again if its ETag changed.
+func (o *remoteCorim) fetch() ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, o.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching %s: %w", o.url, err)
+	}
+
+	req.Header.Set("Accept", o.accept)
+
+	if o.auth != nil {
+		header, err := o.auth.EncodeHeader()
+		if err != nil {
+			return nil, fmt.Errorf("error fetching %s: %w", o.url, err)
+		}
+
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+	}
+
+	var cached []byte
+
+	corimFile, etagFile := o.cacheFiles(req.Header.Get("Authorization"))
+
+	if o.cacheDir != "" {
+		etag, err := afero.ReadFile(fs, etagFile)
+		if err == nil {
+			if cached, err = afero.ReadFile(fs, corimFile); err == nil {
+				req.Header.Set("If-None-Match", strings.TrimSpace(string(etag)))
+			}
+		}
+	}
+
+	resp, err := remoteCorimClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching %s: %w", o.url, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached != nil:
+		progressf(o.quiet, ">> %q not modified, using the cached copy\n", o.url)
+		return cached, nil
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("error fetching %s: unexpected HTTP status %q", o.url, resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRemoteCorimSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("error fetching %s: %w", o.url, err)
+	}
+
+	if len(data) > maxRemoteCorimSize {
+		return nil, fmt.Errorf("error fetching %s: %s larger than %d bytes", o.url, o.what, maxRemoteCorimSize)
+	}
+
+	if etag := resp.Header.Get("ETag"); o.cacheDir != "" && etag != "" {
+		if err = o.store(corimFile, etagFile, data, etag); err != nil {
+			return nil, err
+		}
+	}
+
+	return data, nil
+}
+
+// store saves data and its etag to corimFile and etagFile (see cacheFiles) in
+// the cache directory
+func (o *remoteCorim) store(corimFile, etagFile string, data []byte, etag string) error {
+	if err := prepareOutputDir(o.cacheDir, defaultDirMode); err != nil {
+		return err
+	}
+
+	if err := afero.WriteFile(fs, corimFile, data, 0644); err != nil {
+		return fmt.Errorf("error caching %s: %w", o.url, err)
+	}
+
+	if err := afero.WriteFile(fs, etagFile, []byte(etag), 0644); err != nil {
+		return fmt.Errorf("error caching %s: %w", o.url, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,230 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/apiclient/auth"
+)
+
+// corimServer serves data with ETag "v1", honouring If-None-Match, and
+// records the headers of the requests it receives
+type corimServer struct {
+	data     []byte
+	requests []http.Header
+	statuses []int
+}
+
+func (o *corimServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	o.requests = append(o.requests, r.Header.Clone())
+
+	if r.Header.Get("If-None-Match") == `"v1"` {
+		o.statuses = append(o.statuses, http.StatusNotModified)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	o.statuses = append(o.statuses, http.StatusOK)
+	w.Header().Set("ETag", `"v1"`)
+	w.Header().Set("Content-Type", "application/rim+cose")
+	_, _ = w.Write(o.data)
+}
+
+func Test_CorimVerifyCmd_url(t *testing.T) {
+	srv := &corimServer{data: testSignedCorimValid}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "ok.jwk", testECKey, 0644))
+
+	cmd := NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--url=" + ts.URL + "/corim.cbor", "--key=ok.jwk"})
+	require.NoError(t, cmd.Execute())
+
+	require.Len(t, srv.requests, 1)
+	assert.Empty(t, srv.requests[0].Get("If-None-Match"))
+	assert.Contains(t, srv.requests[0].Get("Accept"), "application/rim+cose")
+}
+
+func Test_CorimVerifyCmd_url_etag_cache(t *testing.T) {
+	srv := &corimServer{data: testSignedCorimValid}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "ok.jwk", testECKey, 0644))
+
+	for i := 0; i < 2; i++ {
+		cmd := NewCorimVerifyCmd()
+		cmd.SetArgs([]string{"--url=" + ts.URL + "/corim.cbor", "--key=ok.jwk", "--cache-dir=cache.d"})
+		require.NoError(t, cmd.Execute())
+	}
+
+	require.Len(t, srv.requests, 2)
+	assert.Equal(t, `"v1"`, srv.requests[1].Get("If-None-Match"))
+	assert.Equal(t, []int{http.StatusOK, http.StatusNotModified}, srv.statuses)
+
+	r, err := newRemoteCorim(ts.URL+"/corim.cbor", "cache.d", nil)
+	require.NoError(t, err)
+
+	corimFile, etagFile := r.cacheFiles("")
+	assert.Equal(t, testSignedCorimValid, mustReadFile(t, corimFile))
+	assert.Equal(t, `"v1"`, string(mustReadFile(t, etagFile)))
+}
+
+func Test_CorimVerifyCmd_url_etag_cache_quiet(t *testing.T) {
+	srv := &corimServer{data: newTestSignedCorimWithHeaders(t, nil)}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "ok.jwk", testECKey, 0644))
+
+	for i := 0; i < 2; i++ {
+		cmd := NewCorimVerifyCmd()
+		cmd.SetArgs([]string{"--url=" + ts.URL + "/corim.cbor", "--key=ok.jwk", "--cache-dir=cache.d", "--extract=corim-id"})
+		require.NoError(t, withDisplayOutput("out.txt", cmd.Execute))
+
+		// using the cached copy is not reported along with the extracted value
+		assert.Equal(t, "5c57e8f4-46cd-421b-91c9-08cf93e13cfc\n", string(mustReadFile(t, "out.txt")))
+	}
+
+	assert.Equal(t, []int{http.StatusOK, http.StatusNotModified}, srv.statuses)
+}
+
+func Test_CorimVerifyCmd_url_tampered_cache(t *testing.T) {
+	srv := &corimServer{data: testSignedCorimValid}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "ok.jwk", testECKey, 0644))
+
+	r, err := newRemoteCorim(ts.URL+"/corim.cbor", "cache.d", nil)
+	require.NoError(t, err)
+	corimFile, etagFile := r.cacheFiles("")
+	require.NoError(t, r.store(corimFile, etagFile, []byte("not a CoRIM"), `"v1"`))
+
+	// the cached copy is verified like a downloaded one
+	cmd := NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--url=" + ts.URL + "/corim.cbor", "--key=ok.jwk", "--cache-dir=cache.d"})
+	assert.ErrorContains(t, cmd.Execute(), "error decoding signed CoRIM from "+ts.URL+"/corim.cbor")
+}
+
+func Test_remoteCorim_fetch_auth(t *testing.T) {
+	srv := &corimServer{data: testSignedCorimValid}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	a := &auth.BasicAuthenticator{}
+	require.NoError(t, a.Configure(map[string]interface{}{"username": "user", "password": "secret"}))
+
+	r, err := newRemoteCorim(ts.URL, "", a)
+	require.NoError(t, err)
+
+	data, err := r.fetch()
+	require.NoError(t, err)
+	assert.Equal(t, testSignedCorimValid, data)
+
+	require.Len(t, srv.requests, 1)
+	// base64("user:secret")
+	assert.Equal(t, "Basic dXNlcjpzZWNyZXQ=", srv.requests[0].Get("Authorization"))
+}
+
+func Test_remoteCorim_fetch_cache_per_credentials(t *testing.T) {
+	srv := &corimServer{data: testSignedCorimValid}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	fs = afero.NewMemMapFs()
+
+	fetch := func(user string) {
+		var a auth.IAuthenticator
+
+		if user != "" {
+			basic := &auth.BasicAuthenticator{}
+			require.NoError(t, basic.Configure(map[string]interface{}{"username": user, "password": "secret"}))
+			a = basic
+		}
+
+		r, err := newRemoteCorim(ts.URL+"/corim.cbor", "cache.d", a)
+		require.NoError(t, err)
+
+		data, err := r.fetch()
+		require.NoError(t, err)
+		assert.Equal(t, testSignedCorimValid, data)
+	}
+
+	// the copy cached for alice is neither used for bob nor for anonymous
+	// requests, which get their own
+	fetch("alice")
+	fetch("bob")
+	fetch("")
+	fetch("alice")
+	fetch("")
+
+	assert.Equal(t, []int{http.StatusOK, http.StatusOK, http.StatusOK, http.StatusNotModified, http.StatusNotModified}, srv.statuses)
+
+	names, err := afero.ReadDir(fs, "cache.d")
+	require.NoError(t, err)
+	// a CoRIM and an ETag file for each of alice, bob and anonymous
+	assert.Len(t, names, 6)
+}
+
+func Test_remoteCorim_fetch_not_found(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	defer ts.Close()
+
+	r, err := newRemoteCorim(ts.URL+"/missing.cbor", "", nil)
+	require.NoError(t, err)
+
+	_, err = r.fetch()
+	assert.EqualError(t, err, "error fetching "+ts.URL+`/missing.cbor: unexpected HTTP status "404 Not Found"`)
+}
+
+func Test_CorimVerifyCmd_url_bad_args(t *testing.T) {
+	tvs := []struct {
+		args []string
+		err  string
+	}{
+		{
+			args: []string{"--url=https://example.com/c.cbor", "--file=ok.cbor", "--key=ok.jwk"},
+			err:  "--url cannot be used together with --file, --dir, --input-glob or --signature",
+		},
+		{
+			args: []string{"--url=https://example.com/c.cbor", "--sequence", "--key=ok.jwk"},
+			err:  "--url cannot be used together with --sequence or --quorum",
+		},
+		{
+			args: []string{"--url=ftp://example.com/c.cbor", "--key=ok.jwk"},
+			err:  `invalid --url "ftp://example.com/c.cbor": expecting an http or https URL`,
+		},
+		{
+			args: []string{"--file=ok.cbor", "--cache-dir=cache.d", "--key=ok.jwk"},
+			err:  "--cache-dir can only be used together with --url",
+		},
+	}
+
+	for _, tv := range tvs {
+		cmd := NewCorimVerifyCmd()
+		cmd.SetArgs(tv.args)
+		assert.EqualError(t, cmd.Execute(), tv.err, tv.args)
+	}
+}
+
+func Test_CorimVerifyCmd_url_offline(t *testing.T) {
+	offline = true
+	defer func() { offline = false }()
+
+	cmd := NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--url=https://example.com/c.cbor", "--key=ok.jwk"})
+	assert.EqualError(t, cmd.Execute(), "--url requires network access, which is disabled by --offline")
+}
@@ -42,9 +42,15 @@ func Execute() {
 }
 
 func init() {
-	cobra.OnInitialize(initConfig)
+	cobra.OnInitialize(initConfig, initProfileDefs)
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $XDG_CONFIG_HOME/cocli/config.yaml)")
+	rootCmd.PersistentFlags().StringArrayVar(
+		&profileDefFiles, "profile-def", []string{}, "a profile definition file (in JSON format) declaring the extensions of a custom profile, can be repeated",
+	)
+	rootCmd.PersistentFlags().BoolVar(
+		&offline, "offline", false, "never access the network: commands and checks that need it fail instead",
+	)
 }
 
 // initConfig reads in config file and ENV variables if set
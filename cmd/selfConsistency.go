@@ -0,0 +1,171 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"crypto/x509"
+	"errors"
+	"fmt"
+
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/corim/corim"
+	"github.com/veraison/corim/cots"
+)
+
+// certKey is a certificate-based key found in a CoMID key triple, together
+// with its location, used in messages
+type certKey struct {
+	where string
+	key   *comid.CryptoKey
+}
+
+// checkSelfConsistency checks that each certificate-based key (pkix-base64-cert
+// or pkix-base64-cert-path) in the key triples of the CoMIDs of c chains up to
+// a certificate trust anchor in the CoTS tags of c.  The CA certificates of the
+// CoTS tags can be used as intermediates.  All the keys that do not chain are
+// reported.
+func checkSelfConsistency(c corim.UnsignedCorim) error {
+	var (
+		keys    []certKey
+		roots   = x509.NewCertPool()
+		cas     = x509.NewCertPool()
+		anchors int
+	)
+
+	for i, t := range c.Tags {
+		if len(t) < 4 {
+			continue
+		}
+
+		cborTag, cborData := t[:3], t[3:]
+
+		switch {
+		case bytes.Equal(cborTag, corim.ComidTag):
+			cm, err := corim.UnmarshalComidFromCBOR(cborData, c.Profile)
+			if err != nil {
+				return fmt.Errorf("tag [%d] (CoMID): decoding failed: %w", i, err)
+			}
+
+			keys = append(keys, comidCertKeys(i, cm)...)
+		case bytes.Equal(cborTag, cots.CotsTag):
+			var cts cots.ConciseTaStore
+			if err := cts.FromCBOR(cborData); err != nil {
+				return fmt.Errorf("tag [%d] (CoTS): decoding failed: %w", i, err)
+			}
+
+			if cts.Keys == nil {
+				continue
+			}
+
+			for j, ta := range cts.Keys.Tas {
+				if ta.Format != cots.TaFormatCertificate {
+					continue
+				}
+
+				cert, err := x509.ParseCertificate(ta.Data)
+				if err != nil {
+					return fmt.Errorf("tag [%d] (CoTS): trust anchor at index %d: %w", i, j, err)
+				}
+
+				roots.AddCert(cert)
+				anchors++
+			}
+
+			for j, ca := range cts.Keys.Cas {
+				cert, err := x509.ParseCertificate(ca)
+				if err != nil {
+					return fmt.Errorf("tag [%d] (CoTS): CA certificate at index %d: %w", i, j, err)
+				}
+
+				cas.AddCert(cert)
+			}
+		}
+	}
+
+	if len(keys) == 0 {
+		fmt.Println(">> no certificate-based keys found in the CoMIDs")
+		return nil
+	}
+
+	if anchors == 0 {
+		return fmt.Errorf("not self-consistent: no CoTS certificate trust anchors found for %d certificate-based key(s)", len(keys))
+	}
+
+	var errs []error
+
+	for _, k := range keys {
+		if err := chainToAnchors(k.key, roots, cas); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", k.where, err))
+		}
+	}
+
+	if len(errs) != 0 {
+		return fmt.Errorf("not self-consistent, %d/%d key(s) do not chain to a CoTS trust anchor: %w",
+			len(errs), len(keys), errors.Join(errs...))
+	}
+
+	fmt.Printf(">> %d certificate-based key(s) chain to the CoTS trust anchors\n", len(keys))
+
+	return nil
+}
+
+// comidCertKeys returns the certificate-based keys of the dev-identity-keys and
+// attester-verification-keys triples of the CoMID at index tag
+func comidCertKeys(tag int, c *comid.Comid) []certKey {
+	var keys []certKey
+
+	triples := []struct {
+		name string
+		kts  *comid.KeyTriples
+	}{
+		{"dev-identity-keys", c.Triples.DevIdentityKeys},
+		{"attester-verification-keys", c.Triples.AttestVerifKeys},
+	}
+
+	for _, t := range triples {
+		if t.kts == nil {
+			continue
+		}
+
+		for i, kt := range *t.kts {
+			for j, k := range kt.VerifKeys {
+				switch k.Type() {
+				case comid.PKIXBase64CertType, comid.PKIXBase64CertPathType:
+					keys = append(keys, certKey{
+						where: fmt.Sprintf("tag [%d] (CoMID): %s[%d]: key [%d]", tag, t.name, i, j),
+						key:   k,
+					})
+				}
+			}
+		}
+	}
+
+	return keys
+}
+
+// chainToAnchors builds a chain from the (leaf) certificate of k to one of
+// roots, using the other certificates of k, if any, and cas as intermediates
+func chainToAnchors(k *comid.CryptoKey, roots, cas *x509.CertPool) error {
+	certs, err := parseCertificates([]byte(k.String()))
+	if err != nil {
+		return err
+	}
+
+	intermediates := cas.Clone()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err = certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return fmt.Errorf("certificate %q: %w", certs[0].Subject.String(), err)
+	}
+
+	return nil
+}
@@ -0,0 +1,161 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/pem"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/corim/corim"
+	"github.com/veraison/corim/cots"
+)
+
+func certPEM(ders ...[]byte) string {
+	var out []byte
+	for _, der := range ders {
+		out = append(out, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	}
+	return string(out)
+}
+
+// newTestSelfContainedCorim returns an unsigned CoRIM with a CoMID carrying
+// the supplied attester verification keys and, unless anchors is empty, a CoTS
+// with the supplied trust anchors and CA certificates
+func newTestSelfContainedCorim(t *testing.T, keys []*comid.CryptoKey, anchors, cas [][]byte) corim.UnsignedCorim {
+	classID, err := parseClassID("1.2.3.4")
+	require.NoError(t, err)
+
+	env := comid.Environment{Class: &comid.Class{ClassID: classID}}
+
+	c := comid.NewComid().SetTagIdentity("self-contained", 0)
+	require.NotNil(t, c.AddAttestVerifKey(comid.KeyTriple{Environment: env, VerifKeys: keys}))
+
+	u := corim.NewUnsignedCorim().SetID("self-contained")
+	require.NotNil(t, u.AddComid(c))
+
+	if len(anchors) != 0 {
+		tas := cots.NewTasAndCas()
+		for _, ta := range anchors {
+			tas.AddTaCert(ta)
+		}
+		for _, ca := range cas {
+			tas.AddCaCert(ca)
+		}
+
+		cts := cots.NewConciseTaStore().SetKeys(*tas)
+		cts.Environments = classIDEnvironment(classID)
+		require.NotNil(t, u.AddCots(cts))
+	}
+
+	return *u
+}
+
+func newTestCertKey(t *testing.T, ders ...[]byte) *comid.CryptoKey {
+	var (
+		k   *comid.CryptoKey
+		err error
+	)
+
+	if len(ders) == 1 {
+		k, err = comid.NewPKIXBase64Cert(certPEM(ders...))
+	} else {
+		k, err = comid.NewPKIXBase64CertPath(certPEM(ders...))
+	}
+	require.NoError(t, err)
+
+	return k
+}
+
+func Test_checkSelfConsistency_ok(t *testing.T) {
+	pki := newTestPKI(t)
+
+	// a certificate path, and a certificate whose issuer is a CoTS CA
+	keys := []*comid.CryptoKey{
+		newTestCertKey(t, pki.leafDER, pki.intermediateDER),
+		newTestCertKey(t, pki.leafDER),
+	}
+
+	u := newTestSelfContainedCorim(t, keys, [][]byte{pki.rootDER}, [][]byte{pki.intermediateDER})
+	assert.NoError(t, checkSelfConsistency(u))
+}
+
+func Test_checkSelfConsistency_not_chaining(t *testing.T) {
+	pki, other := newTestPKI(t), newTestPKI(t)
+
+	keys := []*comid.CryptoKey{
+		newTestCertKey(t, pki.leafDER, pki.intermediateDER),
+		newTestCertKey(t, other.leafDER, other.intermediateDER),
+		// the intermediate is neither in the path nor among the CoTS CAs
+		newTestCertKey(t, pki.leafDER),
+	}
+
+	u := newTestSelfContainedCorim(t, keys, [][]byte{pki.rootDER}, nil)
+
+	err := checkSelfConsistency(u)
+	assert.ErrorContains(t, err, "not self-consistent, 2/3 key(s) do not chain to a CoTS trust anchor")
+	assert.ErrorContains(t, err, `tag [0] (CoMID): attester-verification-keys[0]: key [1]: certificate "CN=Test Signer": x509: certificate signed by unknown authority`)
+	assert.ErrorContains(t, err, "attester-verification-keys[0]: key [2]")
+	assert.NotContains(t, err.Error(), "key [0]")
+}
+
+func Test_checkSelfConsistency_no_anchors(t *testing.T) {
+	pki := newTestPKI(t)
+
+	u := newTestSelfContainedCorim(t, []*comid.CryptoKey{newTestCertKey(t, pki.leafDER)}, nil, nil)
+	assert.EqualError(t, checkSelfConsistency(u),
+		"not self-consistent: no CoTS certificate trust anchors found for 1 certificate-based key(s)")
+}
+
+func Test_checkSelfConsistency_no_cert_keys(t *testing.T) {
+	k, err := comid.NewPKIXBase64Key(comid.TestECPubKey)
+	require.NoError(t, err)
+
+	u := newTestSelfContainedCorim(t, []*comid.CryptoKey{k}, nil, nil)
+	assert.NoError(t, checkSelfConsistency(u))
+}
+
+func Test_CorimVerifyCmd_self_consistent(t *testing.T) {
+	pki, other := newTestPKI(t), newTestPKI(t)
+
+	sign := func(keys []*comid.CryptoKey) []byte {
+		u := newTestSelfContainedCorim(t, keys, [][]byte{pki.rootDER}, nil)
+
+		signer, err := corim.NewSignerFromJWK(testECKey)
+		require.NoError(t, err)
+
+		s := corim.SignedCorim{UnsignedCorim: u}
+		s.Meta.SetSigner("ACME Ltd signing key", nil)
+
+		data, err := s.Sign(signer)
+		require.NoError(t, err)
+
+		return data
+	}
+
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "ok.cbor", sign([]*comid.CryptoKey{
+		newTestCertKey(t, pki.leafDER, pki.intermediateDER),
+	}), 0644))
+	require.NoError(t, afero.WriteFile(fs, "bad.cbor", sign([]*comid.CryptoKey{
+		newTestCertKey(t, other.leafDER, other.intermediateDER),
+	}), 0644))
+	require.NoError(t, afero.WriteFile(fs, "ok.jwk", testECKey, 0644))
+
+	cmd := NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=ok.cbor", "--key=ok.jwk", "--self-consistent"})
+	assert.NoError(t, cmd.Execute())
+
+	cmd = NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=bad.cbor", "--key=ok.jwk", "--self-consistent"})
+	assert.ErrorContains(t, cmd.Execute(), "error verifying bad.cbor: not self-consistent, 1/1 key(s) do not chain to a CoTS trust anchor")
+
+	// without the switch, only the signature is verified
+	cmd = NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=bad.cbor", "--key=ok.jwk"})
+	assert.NoError(t, cmd.Execute())
+}
@@ -0,0 +1,181 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/afero"
+	"github.com/veraison/corim/corim"
+)
+
+// signState records what a signed CoRIM was produced from, so that corim sign
+// --sign-only-if-changed can skip signing when nothing changed.  It is saved
+// to a sidecar file next to the signed CoRIM (see signStateFileName).
+type signState struct {
+	// SHA-256 of the unsigned CoRIM
+	PayloadSHA256 string `json:"payload-sha256"`
+	// SHA-256 thumbprint of the SubjectPublicKeyInfo of the signing key
+	KeyThumbprint string `json:"key-thumbprint"`
+	// SHA-256 over the CoRIM Meta and certificate files, and the signing
+	// options
+	InputsSHA256 string `json:"inputs-sha256"`
+	// SHA-256 of the signed CoRIM, so that changes made to it by other means
+	// are detected
+	OutputSHA256 string `json:"output-sha256"`
+}
+
+// signStateFileName returns the name of the sidecar file recording the sign
+// state of the signed CoRIM saved to signedCorimFile
+func signStateFileName(signedCorimFile string) string {
+	return signedCorimFile + ".sign-state"
+}
+
+// newSignState computes the sign state (except for the output hash) for
+// signing unsignedCorimFile with the supplied key, CoRIM Meta, certificates and
+// options
+func newSignState(unsignedCorimFile, keyFile, metaFile string, certFile *string, intermediatesFiles []string, opts signOptions) (signState, error) {
+	var state signState
+
+	payload, err := afero.ReadFile(fs, unsignedCorimFile)
+	if err != nil {
+		return state, fmt.Errorf("error loading unsigned CoRIM from %s: %w", unsignedCorimFile, err)
+	}
+
+	state.PayloadSHA256 = sha256Hex(payload)
+
+	if state.KeyThumbprint, err = signingKeyThumbprint(keyFile, opts); err != nil {
+		return state, err
+	}
+
+	h := sha256.New()
+
+	// length-prefix each field, so that moving bytes across fields changes
+	// the hash
+	write := func(data []byte) {
+		var n [8]byte
+		binary.BigEndian.PutUint64(n[:], uint64(len(data)))
+		h.Write(n[:])
+		h.Write(data)
+	}
+
+	files := []string{metaFile, opts.additionalMeta, opts.metaFromCorim, opts.certChain}
+	if certFile != nil {
+		files = append(files, *certFile)
+	}
+	files = append(files, intermediatesFiles...)
+
+	for _, file := range files {
+		write([]byte(file))
+
+		if file == "" {
+			continue
+		}
+
+		data, err := afero.ReadFile(fs, file)
+		if err != nil {
+			return state, fmt.Errorf("error loading %s: %w", file, err)
+		}
+
+		write(data)
+	}
+
+	// all the options count, including the checks applied before signing
+	// (e.g., the algorithm policy), except for the index the signed CoRIM is
+	// recorded in, which does not affect it
+	opts.index = ""
+	write([]byte(fmt.Sprintf("%+v", opts)))
+
+	state.InputsSHA256 = hex.EncodeToString(h.Sum(nil))
+
+	return state, nil
+}
+
+// signingKeyThumbprint returns the thumbprint of the public part of the key
+// in keyFile or, with opts.sshAgent, of the selected key of the SSH agent
+func signingKeyThumbprint(keyFile string, opts signOptions) (string, error) {
+	if opts.sshAgent {
+		ag, conn, err := dialSSHAgent()
+		if err != nil {
+			return "", fmt.Errorf("error connecting to the SSH agent: %w", err)
+		}
+		defer conn.Close()
+
+		agentSigner, err := newSSHAgentSigner(ag, opts.sshKey)
+		if err != nil {
+			return "", fmt.Errorf("error loading signing key from the SSH agent: %w", err)
+		}
+
+		return publicKeyThumbprint(agentSigner.pub)
+	}
+
+	keyJWK, err := afero.ReadFile(fs, keyFile)
+	if err != nil {
+		return "", fmt.Errorf("error loading signing key from %s: %w", keyFile, err)
+	}
+
+	pk, err := corim.NewPublicKeyFromJWK(keyJWK)
+	if err != nil {
+		return "", fmt.Errorf("error loading signing key from %s: %w", keyFile, err)
+	}
+
+	return publicKeyThumbprint(pk)
+}
+
+// signStateUnchanged tells whether the signed CoRIM saved to signedCorimFile
+// exists, is unmodified, and was produced from state, as recorded in its
+// sidecar file.  A missing or unreadable sidecar counts as a change.
+func signStateUnchanged(signedCorimFile string, state signState) bool {
+	output, err := afero.ReadFile(fs, signedCorimFile)
+	if err != nil {
+		return false
+	}
+
+	data, err := afero.ReadFile(fs, signStateFileName(signedCorimFile))
+	if err != nil {
+		return false
+	}
+
+	var recorded signState
+	if err = json.Unmarshal(data, &recorded); err != nil {
+		return false
+	}
+
+	state.OutputSHA256 = sha256Hex(output)
+
+	return recorded == state
+}
+
+// saveSignState records state, together with the hash of the signed CoRIM
+// saved to signedCorimFile, in the sidecar file of signedCorimFile
+func saveSignState(signedCorimFile string, state signState) error {
+	output, err := afero.ReadFile(fs, signedCorimFile)
+	if err != nil {
+		return fmt.Errorf("error loading signed CoRIM from %s: %w", signedCorimFile, err)
+	}
+
+	state.OutputSHA256 = sha256Hex(output)
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding sign state: %w", err)
+	}
+
+	file := signStateFileName(signedCorimFile)
+
+	if err = afero.WriteFile(fs, file, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("error saving sign state to %s: %w", file, err)
+	}
+
+	return nil
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,93 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupSignStateTest(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "unsigned.cbor", testCorimValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "meta.json", testMetaValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "ec.jwk", testECKey, 0644))
+	require.NoError(t, afero.WriteFile(fs, "ed.jwk", testEdDSAKey, 0644))
+}
+
+// signIfChanged runs corim sign --sign-only-if-changed and returns the signed
+// CoRIM.  ES256 signatures are randomized, so that re-signing always changes
+// the output.
+func signIfChanged(t *testing.T, args ...string) []byte {
+	cmd := NewCorimSignCmd()
+	cmd.SetArgs(append([]string{
+		"--file=unsigned.cbor", "--meta=meta.json", "--output=signed.cbor", "--sign-only-if-changed",
+	}, args...))
+	require.NoError(t, cmd.Execute())
+
+	return mustReadFile(t, "signed.cbor")
+}
+
+func Test_CorimSignCmd_sign_only_if_changed(t *testing.T) {
+	setupSignStateTest(t)
+
+	first := signIfChanged(t, "--key=ec.jwk")
+
+	var state signState
+	require.NoError(t, json.Unmarshal(mustReadFile(t, "signed.cbor.sign-state"), &state))
+	assert.Equal(t, sha256Hex(testCorimValid), state.PayloadSHA256)
+	assert.Equal(t, sha256Hex(first), state.OutputSHA256)
+
+	// nothing changed: skipped
+	assert.Equal(t, first, signIfChanged(t, "--key=ec.jwk"))
+
+	// different option: re-signed
+	second := signIfChanged(t, "--key=ec.jwk", "--kid=thumbprint")
+	assert.NotEqual(t, first, second)
+	assert.Equal(t, second, signIfChanged(t, "--key=ec.jwk", "--kid=thumbprint"))
+}
+
+func Test_CorimSignCmd_sign_only_if_changed_key(t *testing.T) {
+	setupSignStateTest(t)
+
+	first := signIfChanged(t, "--key=ec.jwk")
+
+	// same file name, different key
+	require.NoError(t, afero.WriteFile(fs, "ec.jwk", testEdDSAKey, 0644))
+
+	assert.NotEqual(t, first, signIfChanged(t, "--key=ec.jwk"))
+}
+
+func Test_CorimSignCmd_sign_only_if_changed_inputs(t *testing.T) {
+	setupSignStateTest(t)
+
+	first := signIfChanged(t, "--key=ec.jwk")
+
+	// CoRIM Meta changed
+	meta := append([]byte(nil), testMetaValid...)
+	require.NoError(t, afero.WriteFile(fs, "meta.json", append(meta, '\n'), 0644))
+
+	second := signIfChanged(t, "--key=ec.jwk")
+	assert.NotEqual(t, first, second)
+
+	// output modified by other means
+	require.NoError(t, afero.WriteFile(fs, "signed.cbor", first, 0644))
+
+	assert.NotEqual(t, first, signIfChanged(t, "--key=ec.jwk"))
+}
+
+func Test_signStateUnchanged_no_sidecar(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "signed.cbor", testSignedCorimValid, 0644))
+
+	assert.False(t, signStateUnchanged("signed.cbor", signState{}))
+
+	require.NoError(t, afero.WriteFile(fs, "signed.cbor.sign-state", []byte("not JSON"), 0644))
+
+	assert.False(t, signStateUnchanged("signed.cbor", signState{}))
+}
@@ -0,0 +1,177 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/veraison/corim/corim"
+	"github.com/veraison/corim/cots"
+	cose "github.com/veraison/go-cose"
+)
+
+// the values accepted by corim sign --format and corim verify --format
+const (
+	summaryFormatText = "text"
+	summaryFormatJSON = "json"
+)
+
+// signSummaryTags counts the tags of a CoRIM by type
+type signSummaryTags struct {
+	CoMID  int `json:"comid"`
+	CoSWID int `json:"coswid"`
+	CoTS   int `json:"cots"`
+	Other  int `json:"other,omitempty"`
+}
+
+// signSummaryValidity is the validity window of a CoRIM, as RFC 3339 timestamps
+type signSummaryValidity struct {
+	NotBefore string `json:"not-before,omitempty"`
+	NotAfter  string `json:"not-after"`
+}
+
+// signSummary describes the outcome of corim sign --summary
+type signSummary struct {
+	Input           string               `json:"input"`
+	Output          string               `json:"output"`
+	ID              string               `json:"id"`
+	Profile         string               `json:"profile,omitempty"`
+	Tags            signSummaryTags      `json:"tags"`
+	Algorithm       string               `json:"algorithm"`
+	CertFingerprint string               `json:"cert-sha256,omitempty"`
+	Validity        *signSummaryValidity `json:"validity,omitempty"`
+}
+
+// newSignSummary describes the signed CoRIM signedCorimCBOR, obtained by
+// signing input and saved to output
+func newSignSummary(input, output string, signedCorimCBOR []byte) (signSummary, error) {
+	msg, err := decodeSign1(tagSign1(signedCorimCBOR))
+	if err != nil {
+		return signSummary{}, err
+	}
+
+	var u corim.UnsignedCorim
+	if err = u.FromCBOR(msg.Payload); err != nil {
+		return signSummary{}, fmt.Errorf("error decoding unsigned CoRIM: %w", err)
+	}
+
+	alg, err := msg.Headers.Protected.Algorithm()
+	if err != nil {
+		return signSummary{}, fmt.Errorf("error reading COSE algorithm: %w", err)
+	}
+
+	sum := signSummary{
+		Input:     input,
+		Output:    output,
+		ID:        u.ID.String(),
+		Algorithm: alg.String(),
+	}
+
+	if u.Profile != nil {
+		if sum.Profile, err = u.Profile.Get(); err != nil {
+			return signSummary{}, fmt.Errorf("error reading CoRIM profile: %w", err)
+		}
+	}
+
+	for _, t := range u.Tags {
+		switch {
+		case bytes.HasPrefix(t, corim.ComidTag):
+			sum.Tags.CoMID++
+		case bytes.HasPrefix(t, corim.CoswidTag):
+			sum.Tags.CoSWID++
+		case bytes.HasPrefix(t, cots.CotsTag):
+			sum.Tags.CoTS++
+		default:
+			sum.Tags.Other++
+		}
+	}
+
+	if sum.CertFingerprint, err = signingCertFingerprint(msg); err != nil {
+		return signSummary{}, err
+	}
+
+	if metaCBOR, ok := msg.Headers.Protected[corim.HeaderLabelCorimMeta].([]byte); ok {
+		var m corim.Meta
+		if err = m.FromCBOR(metaCBOR); err != nil {
+			return signSummary{}, fmt.Errorf("error decoding CoRIM Meta: %w", err)
+		}
+
+		if v := m.Validity; v != nil {
+			sum.Validity = &signSummaryValidity{NotAfter: v.NotAfter.UTC().Format(time.RFC3339)}
+			if v.NotBefore != nil {
+				sum.Validity.NotBefore = v.NotBefore.UTC().Format(time.RFC3339)
+			}
+		}
+	}
+
+	return sum, nil
+}
+
+// signingCertFingerprint returns the SHA-256 fingerprint of the signing (i.e.,
+// first) certificate in the x5chain header of msg, or "" if there is none
+func signingCertFingerprint(msg *cose.Sign1Message) (string, error) {
+	var der []byte
+
+	switch t := msg.Headers.Protected[cose.HeaderLabelX5Chain].(type) {
+	case nil:
+		return "", nil
+	case []byte:
+		der = t
+	case []interface{}:
+		if len(t) == 0 {
+			return "", nil
+		}
+		der, _ = t[0].([]byte)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return "", fmt.Errorf("error decoding signing certificate: %w", err)
+	}
+
+	fp := sha256.Sum256(cert.Raw)
+
+	return colonHex(fp[:]), nil
+}
+
+// print prints the summary in format (text or json)
+func (o signSummary) print(format string) error {
+	if format == summaryFormatJSON {
+		j, err := json.MarshalIndent(o, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error encoding summary: %w", err)
+		}
+
+		fmt.Println(string(j))
+
+		return nil
+	}
+
+	tags := fmt.Sprintf("%d CoMID, %d CoSWID, %d CoTS", o.Tags.CoMID, o.Tags.CoSWID, o.Tags.CoTS)
+	if o.Tags.Other != 0 {
+		tags += fmt.Sprintf(", %d other", o.Tags.Other)
+	}
+
+	validity := "[-, -]"
+	if o.Validity != nil {
+		validity = fmt.Sprintf("[%s, %s]", orDash(o.Validity.NotBefore), o.Validity.NotAfter)
+	}
+
+	fmt.Println(">> summary:")
+	fmt.Printf("    input:        %s\n", o.Input)
+	fmt.Printf("    output:       %s\n", o.Output)
+	fmt.Printf("    id:           %s\n", o.ID)
+	fmt.Printf("    profile:      %s\n", orDash(o.Profile))
+	fmt.Printf("    tags:         %s\n", tags)
+	fmt.Printf("    algorithm:    %s\n", o.Algorithm)
+	fmt.Printf("    cert sha-256: %s\n", orDash(o.CertFingerprint))
+	fmt.Printf("    validity:     %s\n", validity)
+
+	return nil
+}
@@ -0,0 +1,146 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeSignSummaryInputs(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "unsigned.cbor", testCorimValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "meta.json", testMetaValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "key.jwk", testECKey, 0644))
+	require.NoError(t, afero.WriteFile(fs, "cert.der", testSigningCertificate, 0644))
+}
+
+func Test_newSignSummary(t *testing.T) {
+	writeSignSummaryInputs(t)
+
+	cmd := NewCorimSignCmd()
+	cmd.SetArgs([]string{
+		"--file=unsigned.cbor", "--meta=meta.json", "--key=key.jwk", "--cert=cert.der", "--output=signed.cbor",
+	})
+	require.NoError(t, cmd.Execute())
+
+	sum, err := newSignSummary("unsigned.cbor", "signed.cbor", mustReadFile(t, "signed.cbor"))
+	require.NoError(t, err)
+
+	fp := sha256.Sum256(testSigningCertificate)
+
+	assert.Equal(t, "unsigned.cbor", sum.Input)
+	assert.Equal(t, "signed.cbor", sum.Output)
+	assert.NotEmpty(t, sum.ID)
+	assert.Equal(t, "ES256", sum.Algorithm)
+	assert.Equal(t, colonHex(fp[:]), sum.CertFingerprint)
+	// the test CoRIM carries a single, unrecognized tag
+	assert.Equal(t, signSummaryTags{Other: 1}, sum.Tags)
+	require.NotNil(t, sum.Validity)
+	assert.NotEmpty(t, sum.Validity.NotAfter)
+}
+
+func Test_newSignSummary_untagged_no_meta(t *testing.T) {
+	writeSignSummaryInputs(t)
+
+	cmd := NewCorimSignCmd()
+	cmd.SetArgs([]string{
+		"--file=unsigned.cbor", "--no-meta", "--key=key.jwk", "--no-wrap-tagged", "--output=signed.cbor",
+	})
+	require.NoError(t, cmd.Execute())
+
+	sum, err := newSignSummary("unsigned.cbor", "signed.cbor", mustReadFile(t, "signed.cbor"))
+	require.NoError(t, err)
+
+	assert.Empty(t, sum.CertFingerprint)
+	assert.Nil(t, sum.Validity)
+}
+
+func Test_signSummary_print_json(t *testing.T) {
+	fs = afero.NewMemMapFs()
+
+	sum := signSummary{
+		Input:     "unsigned.cbor",
+		Output:    "signed.cbor",
+		ID:        "corim-1",
+		Tags:      signSummaryTags{CoMID: 2, CoTS: 1},
+		Algorithm: "ES256",
+		Validity:  &signSummaryValidity{NotAfter: "2030-01-01T00:00:00Z"},
+	}
+
+	require.NoError(t, withDisplayOutput("summary.json", func() error { return sum.print(summaryFormatJSON) }))
+
+	var actual map[string]interface{}
+	require.NoError(t, json.Unmarshal(mustReadFile(t, "summary.json"), &actual))
+
+	assert.Equal(t, map[string]interface{}{
+		"input":     "unsigned.cbor",
+		"output":    "signed.cbor",
+		"id":        "corim-1",
+		"tags":      map[string]interface{}{"comid": 2.0, "coswid": 0.0, "cots": 1.0},
+		"algorithm": "ES256",
+		"validity":  map[string]interface{}{"not-after": "2030-01-01T00:00:00Z"},
+	}, actual)
+}
+
+func Test_signSummary_print_text(t *testing.T) {
+	fs = afero.NewMemMapFs()
+
+	sum := signSummary{
+		Input:           "unsigned.cbor",
+		Output:          "signed.cbor",
+		ID:              "corim-1",
+		Profile:         "tag:example.com,2024:profile",
+		Tags:            signSummaryTags{CoMID: 1, Other: 2},
+		Algorithm:       "EdDSA",
+		CertFingerprint: "AB:CD",
+	}
+
+	require.NoError(t, withDisplayOutput("summary.txt", func() error { return sum.print(summaryFormatText) }))
+
+	out := string(mustReadFile(t, "summary.txt"))
+	assert.Contains(t, out, "    profile:      tag:example.com,2024:profile\n")
+	assert.Contains(t, out, "    tags:         1 CoMID, 0 CoSWID, 0 CoTS, 2 other\n")
+	assert.Contains(t, out, "    cert sha-256: AB:CD\n")
+	assert.Contains(t, out, "    validity:     [-, -]\n")
+}
+
+func Test_CorimSignCmd_summary(t *testing.T) {
+	for _, format := range []string{summaryFormatText, summaryFormatJSON} {
+		writeSignSummaryInputs(t)
+
+		cmd := NewCorimSignCmd()
+		cmd.SetArgs([]string{
+			"--file=unsigned.cbor", "--meta=meta.json", "--key=key.jwk", "--summary", "--format=" + format,
+		})
+		assert.NoError(t, cmd.Execute(), format)
+	}
+}
+
+func Test_CorimSignCmd_summary_bad_args(t *testing.T) {
+	tvs := []struct {
+		args     []string
+		expected string
+	}{
+		{
+			[]string{"--file=unsigned.cbor", "--meta=meta.json", "--key=key.jwk", "--format=json"},
+			"--format can only be used together with --summary",
+		},
+		{
+			[]string{"--file=unsigned.cbor", "--meta=meta.json", "--key=key.jwk", "--summary", "--format=yaml"},
+			`invalid --format "yaml": expecting text or json`,
+		},
+	}
+
+	for _, tv := range tvs {
+		cmd := NewCorimSignCmd()
+		cmd.SetArgs(tv.args)
+		assert.EqualError(t, cmd.Execute(), tv.expected)
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/veraison/corim/corim"
+	cose "github.com/veraison/go-cose"
+)
+
+const (
+	// COSE header parameter carrying CWT claims (RFC 9597)
+	headerLabelCWTClaims int64 = 15
+	// CWT "iat" (issued at) claim (RFC 8392)
+	cwtClaimIAT int64 = 6
+)
+
+// signingTime returns the signing time found in the "iat" claim of the CWT
+// claims protected header of msg, or nil if there is none
+func signingTime(msg *cose.Sign1Message) (*time.Time, error) {
+	v, ok := msg.Headers.Protected[headerLabelCWTClaims]
+	if !ok {
+		return nil, nil
+	}
+
+	claims, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("CWT claims header: expecting map, got %T", v)
+	}
+
+	for k, iat := range claims {
+		if label, ok := cborInt(k); !ok || label != cwtClaimIAT {
+			continue
+		}
+
+		switch t := iat.(type) {
+		case float64:
+			sec, frac := math.Modf(t)
+			st := time.Unix(int64(sec), int64(frac*1e9)).UTC()
+			return &st, nil
+		default:
+			sec, ok := cborInt(t)
+			if !ok {
+				return nil, fmt.Errorf("CWT iat claim: expecting numeric date, got %T", iat)
+			}
+			st := time.Unix(sec, 0).UTC()
+			return &st, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// cborInt converts a decoded CBOR integer to int64
+func cborInt(v interface{}) (int64, bool) {
+	switch t := v.(type) {
+	case int64:
+		return t, true
+	case uint64:
+		if t > math.MaxInt64 {
+			return 0, false
+		}
+		return int64(t), true
+	}
+	return 0, false
+}
+
+// signingSkew returns the distance of the signing time st from the validity
+// not-before of the CoRIM Meta, if there is one
+func signingSkew(st time.Time, validity *corim.Validity) (time.Duration, bool) {
+	if validity == nil || validity.NotBefore == nil {
+		return 0, false
+	}
+
+	return st.Sub(*validity.NotBefore), true
+}
+
+// checkSigningTime returns a warning for each of the following conditions:
+// the signing time st falls outside of the CoRIM Meta validity window; the
+// signing time is further than maxSkew (if non-zero) from the validity
+// not-before
+func checkSigningTime(st time.Time, validity *corim.Validity, maxSkew time.Duration) []string {
+	var warnings []string
+
+	if validity == nil {
+		return nil
+	}
+
+	if validity.NotBefore != nil && st.Before(*validity.NotBefore) {
+		warnings = append(warnings, fmt.Sprintf(
+			"signing time %s precedes validity not-before %s",
+			st.Format(time.RFC3339), validity.NotBefore.Format(time.RFC3339)))
+	}
+
+	if st.After(validity.NotAfter) {
+		warnings = append(warnings, fmt.Sprintf(
+			"signing time %s follows validity not-after %s",
+			st.Format(time.RFC3339), validity.NotAfter.Format(time.RFC3339)))
+	}
+
+	if skew, ok := signingSkew(st, validity); ok && maxSkew > 0 {
+		if skew.Abs() > maxSkew {
+			warnings = append(warnings, fmt.Sprintf(
+				"signing time skew %s exceeds the maximum of %s", skew, maxSkew))
+		}
+	}
+
+	return warnings
+}
+
+// checkMaxAge fails if the CoRIM signed in msg, whose CoRIM Meta validity is
+// validity, is older than maxAge at time now.  The age is computed from the
+// signing time or, if there is none, from the validity not-before, and is
+// reported in the location of now, unless quiet.
+func checkMaxAge(msg *cose.Sign1Message, validity *corim.Validity, maxAge time.Duration, now time.Time, quiet bool) error {
+	signed, err := signingTime(msg)
+	if err != nil {
+		return err
+	}
+
+	source := "signing time"
+
+	if signed == nil {
+		if validity == nil || validity.NotBefore == nil {
+			return errors.New("CoRIM age unknown: no signing time (CWT iat claim) and no validity not-before in the CoRIM Meta")
+		}
+
+		signed, source = validity.NotBefore, "validity not-before"
+	}
+
+	age := now.Sub(*signed).Round(time.Second)
+
+	progressf(quiet, ">> CoRIM age %s (from %s %s)\n", age, source, signed.In(now.Location()).Format(time.RFC3339))
+
+	if age > maxAge {
+		return fmt.Errorf("CoRIM too old: age %s exceeds --max-age %s", age, maxAge)
+	}
+
+	return nil
+}
@@ -0,0 +1,246 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/corim"
+	cose "github.com/veraison/go-cose"
+)
+
+// newTestSignedCorimWithClaims signs testCorimValid with testECKey, adding
+// the supplied CWT claims to the protected header
+func newTestSignedCorimWithClaims(t *testing.T, claims map[int64]interface{}) []byte {
+	return newTestSignedCorimWithHeaders(t, map[interface{}]interface{}{
+		headerLabelCWTClaims: claims,
+	})
+}
+
+// newTestSignedCorimWithHeaders signs testCorimValid with testECKey, adding
+// the supplied headers to the protected header
+func newTestSignedCorimWithHeaders(t *testing.T, hdrs map[interface{}]interface{}) []byte {
+	var m corim.Meta
+	require.NoError(t, m.FromJSON(testMetaValid))
+
+	metaCBOR, err := m.ToCBOR()
+	require.NoError(t, err)
+
+	signer, err := corim.NewSignerFromJWK(testECKey)
+	require.NoError(t, err)
+
+	msg := cose.NewSign1Message()
+	msg.Payload = testCorimValid
+	msg.Headers.Protected.SetAlgorithm(signer.Algorithm())
+	msg.Headers.Protected[cose.HeaderLabelContentType] = corim.ContentType
+	msg.Headers.Protected[corim.HeaderLabelCorimMeta] = metaCBOR
+	for k, v := range hdrs {
+		msg.Headers.Protected[k] = v
+	}
+
+	require.NoError(t, msg.Sign(rand.Reader, corim.NoExternalData, signer))
+
+	data, err := msg.MarshalCBOR()
+	require.NoError(t, err)
+
+	return data
+}
+
+func Test_signingTime(t *testing.T) {
+	iat := time.Date(2022, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	data := newTestSignedCorimWithClaims(t, map[int64]interface{}{
+		1:           "ACME Ltd.",
+		cwtClaimIAT: iat.Unix(),
+	})
+
+	msg, err := decodeSign1(data)
+	require.NoError(t, err)
+
+	st, err := signingTime(msg)
+	require.NoError(t, err)
+	require.NotNil(t, st)
+	assert.Equal(t, iat, *st)
+}
+
+func Test_signingTime_absent(t *testing.T) {
+	data := newTestSignedCorimWithClaims(t, map[int64]interface{}{1: "ACME Ltd."})
+
+	msg, err := decodeSign1(data)
+	require.NoError(t, err)
+
+	st, err := signingTime(msg)
+	assert.NoError(t, err)
+	assert.Nil(t, st)
+}
+
+func Test_signingTime_bad_iat(t *testing.T) {
+	data := newTestSignedCorimWithClaims(t, map[int64]interface{}{cwtClaimIAT: "yesterday"})
+
+	msg, err := decodeSign1(data)
+	require.NoError(t, err)
+
+	_, err = signingTime(msg)
+	assert.EqualError(t, err, "CWT iat claim: expecting numeric date, got string")
+}
+
+func Test_checkSigningTime(t *testing.T) {
+	notBefore := time.Date(2021, 12, 31, 0, 0, 0, 0, time.UTC)
+	validity := corim.Validity{
+		NotBefore: &notBefore,
+		NotAfter:  time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC),
+	}
+
+	tvs := []struct {
+		desc     string
+		st       time.Time
+		maxSkew  time.Duration
+		expected []string
+	}{
+		{
+			desc:    "within window and skew",
+			st:      notBefore.Add(time.Hour),
+			maxSkew: 24 * time.Hour,
+		},
+		{
+			desc: "within window, skew not checked",
+			st:   notBefore.Add(1000 * time.Hour),
+		},
+		{
+			desc:    "excessive skew",
+			st:      notBefore.Add(48 * time.Hour),
+			maxSkew: 24 * time.Hour,
+			expected: []string{
+				"signing time skew 48h0m0s exceeds the maximum of 24h0m0s",
+			},
+		},
+		{
+			desc:    "before not-before",
+			st:      notBefore.Add(-time.Hour),
+			maxSkew: 24 * time.Hour,
+			expected: []string{
+				"signing time 2021-12-30T23:00:00Z precedes validity not-before 2021-12-31T00:00:00Z",
+			},
+		},
+		{
+			desc: "after not-after",
+			st:   validity.NotAfter.Add(time.Second),
+			expected: []string{
+				"signing time 2025-12-31T00:00:01Z follows validity not-after 2025-12-31T00:00:00Z",
+			},
+		},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.desc, func(t *testing.T) {
+			assert.Equal(t, tv.expected, checkSigningTime(tv.st, &validity, tv.maxSkew))
+		})
+	}
+
+	assert.Nil(t, checkSigningTime(notBefore, nil, time.Second))
+}
+
+func Test_CorimVerifyCmd_with_signing_time(t *testing.T) {
+	cmd := NewCorimVerifyCmd()
+
+	args := []string{
+		"--file=ok.cbor",
+		"--key=ok.jwk",
+		"--max-signing-skew=1h",
+		"--timezone=Australia/Sydney",
+	}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+	data := newTestSignedCorimWithClaims(t, map[int64]interface{}{
+		cwtClaimIAT: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC).Unix(),
+	})
+	err := afero.WriteFile(fs, "ok.cbor", data, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ok.jwk", testECKey, 0644)
+	require.NoError(t, err)
+
+	// out of window signing times are only warned about
+	err = cmd.Execute()
+	assert.NoError(t, err)
+}
+
+func Test_CorimVerifyCmd_bad_timezone(t *testing.T) {
+	cmd := NewCorimVerifyCmd()
+
+	args := []string{
+		"--file=ok.cbor",
+		"--key=ok.jwk",
+		"--timezone=Europe/Atlantis",
+	}
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, `invalid time zone "Europe/Atlantis": expecting an IANA time zone name, e.g., Europe/London`)
+}
+
+func Test_checkMaxAge(t *testing.T) {
+	iat := time.Date(2022, 1, 1, 12, 0, 0, 0, time.UTC)
+	now := iat.Add(2 * time.Hour)
+
+	msg, err := decodeSign1(newTestSignedCorimWithClaims(t, map[int64]interface{}{cwtClaimIAT: iat.Unix()}))
+	require.NoError(t, err)
+
+	notBefore := time.Date(2021, 12, 31, 0, 0, 0, 0, time.UTC)
+	validity := &corim.Validity{NotBefore: &notBefore, NotAfter: notBefore.AddDate(1, 0, 0)}
+
+	assert.NoError(t, checkMaxAge(msg, validity, 3*time.Hour, now, false))
+	assert.EqualError(t, checkMaxAge(msg, validity, time.Hour, now, false), "CoRIM too old: age 2h0m0s exceeds --max-age 1h0m0s")
+
+	// without a signing time, the age is that of the validity not-before
+	msg, err = decodeSign1(newTestSignedCorimWithHeaders(t, nil))
+	require.NoError(t, err)
+
+	assert.NoError(t, checkMaxAge(msg, validity, 39*time.Hour, now, false))
+	assert.EqualError(t, checkMaxAge(msg, validity, 37*time.Hour, now, false), "CoRIM too old: age 38h0m0s exceeds --max-age 37h0m0s")
+
+	assert.EqualError(t, checkMaxAge(msg, &corim.Validity{NotAfter: validity.NotAfter}, time.Hour, now, false),
+		"CoRIM age unknown: no signing time (CWT iat claim) and no validity not-before in the CoRIM Meta")
+}
+
+func Test_CorimVerifyCmd_max_age(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	data := newTestSignedCorimWithClaims(t, map[int64]interface{}{
+		cwtClaimIAT: time.Now().Add(-2 * time.Hour).Unix(),
+	})
+	require.NoError(t, afero.WriteFile(fs, "ok.cbor", data, 0644))
+	require.NoError(t, afero.WriteFile(fs, "ok.jwk", testECKey, 0644))
+
+	cmd := NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=ok.cbor", "--key=ok.jwk", "--max-age=24h"})
+	assert.NoError(t, cmd.Execute())
+
+	cmd = NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=ok.cbor", "--key=ok.jwk", "--max-age=1h"})
+	assert.ErrorContains(t, cmd.Execute(), "error verifying ok.cbor: CoRIM too old: age 2h0m")
+}
+
+func Test_CorimVerifyCmd_max_age_quiet(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "ok.cbor", newTestSignedCorimWithHeaders(t, nil), 0644))
+	require.NoError(t, afero.WriteFile(fs, "ok.jwk", testECKey, 0644))
+
+	// the age is not reported along with the extracted value
+	cmd := NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=ok.cbor", "--key=ok.jwk", "--max-age=876000h", "--extract=corim-id"})
+	require.NoError(t, withDisplayOutput("out.txt", cmd.Execute))
+
+	assert.Equal(t, "5c57e8f4-46cd-421b-91c9-08cf93e13cfc\n", string(mustReadFile(t, "out.txt")))
+}
+
+func Test_CorimVerifyCmd_negative_max_age(t *testing.T) {
+	cmd := NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=ok.cbor", "--key=ok.jwk", "--max-age=-1h"})
+	assert.EqualError(t, cmd.Execute(), "--max-age must not be negative")
+}
@@ -0,0 +1,171 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"strings"
+
+	cose "github.com/veraison/go-cose"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// dialSSHAgent connects to the SSH agent listening on SSH_AUTH_SOCK.  The
+// returned io.Closer terminates the connection.
+var dialSSHAgent = func() (agent.Agent, io.Closer, error) {
+	sock := os.Getenv("SSH_AUTH_SOCK")
+	if sock == "" {
+		return nil, nil, errors.New("SSH_AUTH_SOCK is not set")
+	}
+
+	conn, err := net.Dial("unix", sock)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return agent.NewClient(conn), conn, nil
+}
+
+// sshAgentAlgs maps the SSH key types that can be used for signing CoRIMs to
+// the corresponding COSE algorithms.  The agent hashes the data to be signed
+// with the hash function that RFC 5656 associates with the curve of ECDSA keys,
+// which is the one required by the COSE algorithm.
+var sshAgentAlgs = map[string]cose.Algorithm{
+	ssh.KeyAlgoED25519:  cose.AlgorithmEdDSA,
+	ssh.KeyAlgoECDSA256: cose.AlgorithmES256,
+	ssh.KeyAlgoECDSA384: cose.AlgorithmES384,
+	ssh.KeyAlgoECDSA521: cose.AlgorithmES512,
+}
+
+// sshAgentSigner is a cose.Signer that delegates signing to an SSH agent, so
+// that the private key never leaves the agent
+type sshAgentSigner struct {
+	agent agent.Agent
+	key   *agent.Key
+	alg   cose.Algorithm
+	pub   crypto.PublicKey
+}
+
+// newSSHAgentSigner returns a signer using the key of ag selected by selector
+// (see selectSSHAgentKey)
+func newSSHAgentSigner(ag agent.Agent, selector string) (*sshAgentSigner, error) {
+	keys, err := ag.List()
+	if err != nil {
+		return nil, fmt.Errorf("error listing the keys of the SSH agent: %w", err)
+	}
+
+	key, err := selectSSHAgentKey(keys, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	alg, ok := sshAgentAlgs[key.Type()]
+	if !ok {
+		return nil, fmt.Errorf("%s: unsupported key type %s (only Ed25519 and ECDSA keys can be used)",
+			describeSSHAgentKey(key), key.Type())
+	}
+
+	pk, err := ssh.ParsePublicKey(key.Marshal())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", describeSSHAgentKey(key), err)
+	}
+
+	cpk, ok := pk.(ssh.CryptoPublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: cannot extract the public key", describeSSHAgentKey(key))
+	}
+
+	return &sshAgentSigner{agent: ag, key: key, alg: alg, pub: cpk.CryptoPublicKey()}, nil
+}
+
+// selectSSHAgentKey returns the key in keys whose comment or SHA-256
+// fingerprint (e.g., "SHA256:uNiVztksCsDhcc0u9e8BujQXVUpKZIDTMczCvj3tD2s") is
+// selector.  If selector is empty, the agent must hold exactly one key.
+func selectSSHAgentKey(keys []*agent.Key, selector string) (*agent.Key, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("the SSH agent holds no keys")
+	}
+
+	var (
+		matches []*agent.Key
+		all     []string
+	)
+
+	for _, k := range keys {
+		all = append(all, describeSSHAgentKey(k))
+
+		if selector == "" || k.Comment == selector || ssh.FingerprintSHA256(k) == selector {
+			matches = append(matches, k)
+		}
+	}
+
+	switch {
+	case len(matches) == 1:
+		return matches[0], nil
+	case len(matches) == 0:
+		return nil, fmt.Errorf("no key matching %q in the SSH agent (available: %s)", selector, strings.Join(all, ", "))
+	case selector == "":
+		return nil, fmt.Errorf("the SSH agent holds %d keys, select one with --ssh-key (available: %s)",
+			len(keys), strings.Join(all, ", "))
+	default:
+		return nil, fmt.Errorf("%d keys matching %q in the SSH agent, select one by fingerprint", len(matches), selector)
+	}
+}
+
+// describeSSHAgentKey returns the fingerprint of k, followed by its comment if
+// there is one
+func describeSSHAgentKey(k *agent.Key) string {
+	if k.Comment == "" {
+		return ssh.FingerprintSHA256(k)
+	}
+
+	return fmt.Sprintf("%s (%s)", ssh.FingerprintSHA256(k), k.Comment)
+}
+
+func (o *sshAgentSigner) String() string {
+	return "ssh-agent key " + describeSSHAgentKey(o.key)
+}
+
+func (o *sshAgentSigner) Algorithm() cose.Algorithm {
+	return o.alg
+}
+
+// Sign has the agent sign content.  ECDSA signatures, which SSH encodes as a
+// pair of mpints, are converted to the fixed-size r || s form used by COSE.
+func (o *sshAgentSigner) Sign(_ io.Reader, content []byte) ([]byte, error) {
+	sig, err := o.agent.Sign(o.key, content)
+	if err != nil {
+		return nil, fmt.Errorf("SSH agent signature failed: %w", err)
+	}
+
+	if sig.Format != o.key.Type() {
+		return nil, fmt.Errorf("SSH agent signature: expecting format %s, got %s", o.key.Type(), sig.Format)
+	}
+
+	pub, ok := o.pub.(*ecdsa.PublicKey)
+	if !ok {
+		// ed25519 signatures are the same in SSH and COSE
+		return sig.Blob, nil
+	}
+
+	var rs struct {
+		R, S *big.Int
+	}
+
+	if err = ssh.Unmarshal(sig.Blob, &rs); err != nil {
+		return nil, fmt.Errorf("SSH agent signature: %w", err)
+	}
+
+	size := (pub.Curve.Params().BitSize + 7) / 8
+
+	return append(rs.R.FillBytes(make([]byte, size)), rs.S.FillBytes(make([]byte, size))...), nil
+}
@@ -0,0 +1,167 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	cose "github.com/veraison/go-cose"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
+
+// useTestSSHAgent makes dialSSHAgent return an in-memory agent holding keys,
+// keyed by comment, for the duration of the test
+func useTestSSHAgent(t *testing.T, keys map[string]crypto.Signer) agent.Agent {
+	ag := agent.NewKeyring()
+	for comment, k := range keys {
+		require.NoError(t, ag.Add(agent.AddedKey{PrivateKey: k, Comment: comment}))
+	}
+
+	orig := dialSSHAgent
+	dialSSHAgent = func() (agent.Agent, io.Closer, error) { return ag, nopCloser{}, nil }
+	t.Cleanup(func() { dialSSHAgent = orig })
+
+	return ag
+}
+
+func sshFingerprint(t *testing.T, k crypto.Signer) string {
+	pk, err := ssh.NewPublicKey(k.Public())
+	require.NoError(t, err)
+	return ssh.FingerprintSHA256(pk)
+}
+
+func signWithSSHAgent(t *testing.T, args ...string) error {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "unsigned.cbor", testCorimValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "meta.json", testMetaValid, 0644))
+
+	cmd := NewCorimSignCmd()
+	cmd.SetArgs(append([]string{"--file=unsigned.cbor", "--meta=meta.json", "--output=signed.cbor", "--ssh-agent"}, args...))
+
+	return cmd.Execute()
+}
+
+func Test_CorimSignCmd_ssh_agent(t *testing.T) {
+	_, ed, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	p256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+	p521, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	require.NoError(t, err)
+
+	useTestSSHAgent(t, map[string]crypto.Signer{"ed": ed, "p256": p256, "p384": p384, "p521": p521})
+
+	tvs := []struct {
+		selector string
+		key      crypto.Signer
+		alg      cose.Algorithm
+	}{
+		{"ed", ed, cose.AlgorithmEdDSA},
+		{"p256", p256, cose.AlgorithmES256},
+		{"p384", p384, cose.AlgorithmES384},
+		{sshFingerprint(t, p521), p521, cose.AlgorithmES512},
+	}
+
+	for _, tv := range tvs {
+		require.NoError(t, signWithSSHAgent(t, "--ssh-key="+tv.selector, "--verify-after-sign"), tv.selector)
+
+		data := mustReadFile(t, "signed.cbor")
+
+		msg, err := decodeSign1(data)
+		require.NoError(t, err)
+
+		alg, err := msg.Headers.Protected.Algorithm()
+		require.NoError(t, err)
+		assert.Equal(t, tv.alg, alg, tv.selector)
+
+		assert.NoError(t, verifyAfterSign(data, tv.key.Public(), tv.alg), tv.selector)
+	}
+}
+
+func Test_CorimSignCmd_ssh_agent_single_key(t *testing.T) {
+	p256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	useTestSSHAgent(t, map[string]crypto.Signer{"": p256})
+
+	require.NoError(t, signWithSSHAgent(t, "--kid=thumbprint"))
+
+	msg, err := decodeSign1(mustReadFile(t, "signed.cbor"))
+	require.NoError(t, err)
+
+	expected, err := jwkThumbprint(p256.Public())
+	require.NoError(t, err)
+	assert.Equal(t, []byte(expected), coseKeyID(msg))
+}
+
+func Test_CorimSignCmd_ssh_agent_bad_selection(t *testing.T) {
+	ed1, ed2 := ed25519.NewKeyFromSeed(make([]byte, 32)), ed25519.NewKeyFromSeed(append(make([]byte, 31), 1))
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	useTestSSHAgent(t, map[string]crypto.Signer{"ed1": ed1, "ed2": ed2, "rsa": rsaKey})
+
+	err = signWithSSHAgent(t)
+	assert.ErrorContains(t, err, "error loading signing key from the SSH agent: the SSH agent holds 3 keys, select one with --ssh-key")
+
+	err = signWithSSHAgent(t, "--ssh-key=nope")
+	assert.ErrorContains(t, err, `error loading signing key from the SSH agent: no key matching "nope" in the SSH agent`)
+
+	err = signWithSSHAgent(t, "--ssh-key=rsa")
+	assert.ErrorContains(t, err, "unsupported key type ssh-rsa (only Ed25519 and ECDSA keys can be used)")
+}
+
+func Test_CorimSignCmd_ssh_agent_unavailable(t *testing.T) {
+	orig := dialSSHAgent
+	dialSSHAgent = func() (agent.Agent, io.Closer, error) { return nil, nil, errors.New("SSH_AUTH_SOCK is not set") }
+	t.Cleanup(func() { dialSSHAgent = orig })
+
+	err := signWithSSHAgent(t)
+	assert.EqualError(t, err, "error connecting to the SSH agent: SSH_AUTH_SOCK is not set")
+}
+
+func Test_CorimSignCmd_ssh_agent_bad_args(t *testing.T) {
+	tvs := []struct {
+		args     []string
+		expected string
+	}{
+		{
+			[]string{"--file=unsigned.cbor", "--meta=meta.json", "--key=key.jwk", "--ssh-agent"},
+			"--key cannot be used together with --ssh-agent",
+		},
+		{
+			[]string{"--file=unsigned.cbor", "--meta=meta.json", "--key=key.jwk", "--ssh-key=ops"},
+			"--ssh-key can only be used together with --ssh-agent",
+		},
+	}
+
+	for _, tv := range tvs {
+		cmd := NewCorimSignCmd()
+		cmd.SetArgs(tv.args)
+		assert.EqualError(t, cmd.Execute(), tv.expected)
+	}
+}
+
+func Test_selectSSHAgentKey_none(t *testing.T) {
+	_, err := selectSSHAgentKey(nil, "")
+	assert.EqualError(t, err, "the SSH agent holds no keys")
+}
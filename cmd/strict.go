@@ -0,0 +1,496 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/veraison/corim/corim"
+	"github.com/veraison/corim/cots"
+	"github.com/veraison/corim/extensions"
+	"github.com/veraison/swid"
+)
+
+// CBORCodec is implemented by the CoRIM, CoMID, CoSWID and CoTS types
+type CBORCodec interface {
+	FromCBORLoader
+	ToCBOR() ([]byte, error)
+}
+
+// JSONCodec is implemented by the CoRIM, CoMID, CoSWID and CoTS types
+type JSONCodec interface {
+	FromJSON([]byte) error
+	ToJSON() ([]byte, error)
+}
+
+// decodeCBOR decodes data into v.  If strict is set, any map key in data that
+// is not understood by v is reported as an error, and so is any in the tags
+// carried by v if it is an unsigned CoRIM (see checkUnknownTagFields).
+func decodeCBOR(v CBORCodec, data []byte, strict bool) error {
+	if err := v.FromCBOR(data); err != nil {
+		return err
+	}
+
+	if !strict {
+		return nil
+	}
+
+	if err := checkUnknownCBORFields(v, data); err != nil {
+		return err
+	}
+
+	if u, ok := v.(*corim.UnsignedCorim); ok {
+		return checkUnknownTagFields(u)
+	}
+
+	return nil
+}
+
+// decodeJSON decodes data into v.  If strict is set, any object member in data
+// that is not understood by v is reported as an error.
+func decodeJSON(v JSONCodec, data []byte, strict bool) error {
+	if err := v.FromJSON(data); err != nil {
+		return err
+	}
+
+	if strict {
+		return checkUnknownJSONFields(v, data)
+	}
+
+	return nil
+}
+
+// checkUnknownSignedCorimFields applies checkUnknownCBORFields to the unsigned
+// CoRIM payload, to the tags it carries and to the CoRIM Meta of the already
+// decoded s
+func checkUnknownSignedCorimFields(s *corim.SignedCorim, buf []byte) error {
+	msg, err := decodeSign1(buf)
+	if err != nil {
+		return err
+	}
+
+	if err = checkUnknownCBORFields(&s.UnsignedCorim, msg.Payload); err != nil {
+		return fmt.Errorf("unsigned CoRIM: %w", err)
+	}
+
+	if err = checkUnknownTagFields(&s.UnsignedCorim); err != nil {
+		return fmt.Errorf("unsigned CoRIM: %w", err)
+	}
+
+	if metaCBOR, ok := msg.Headers.Protected[corim.HeaderLabelCorimMeta].([]byte); ok {
+		if err = checkUnknownCBORFields(&s.Meta, metaCBOR); err != nil {
+			return fmt.Errorf("CoRIM Meta: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// checkUnknownTagFields decodes each of the CoMID, CoSWID and CoTS tags of u,
+// which the CoRIM decoder leaves as opaque bytes, and rejects those carrying
+// map keys that are not understood.  CoMIDs are decoded with the extensions
+// registered for the profile of u, if any.  Tags that cannot be decoded at all
+// are left to --validate-tags.
+func checkUnknownTagFields(u *corim.UnsignedCorim) error {
+	for i, t := range u.Tags {
+		if len(t) < 4 {
+			continue
+		}
+
+		var v CBORCodec
+
+		switch cborTag := t[:3]; {
+		case bytes.Equal(cborTag, corim.ComidTag):
+			v = newComid(u.Profile)
+		case bytes.Equal(cborTag, corim.CoswidTag):
+			v = &swid.SoftwareIdentity{}
+		case bytes.Equal(cborTag, cots.CotsTag):
+			v = &cots.ConciseTaStore{}
+		default:
+			continue
+		}
+
+		if err := v.FromCBOR(t[3:]); err != nil {
+			continue
+		}
+
+		if err := checkUnknownCBORFields(v, t[3:]); err != nil {
+			return fmt.Errorf("tag at index %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// checkUnknownCBORFields re-encodes the already decoded v and looks for map
+// keys in the original data that did not survive the round-trip, i.e., keys
+// that were silently dropped by the tolerant decoder
+func checkUnknownCBORFields(v CBORCodec, data []byte) error {
+	paths, err := unknownCBORFields(v, data)
+	if err != nil {
+		return fmt.Errorf("strict decoding: %w", err)
+	}
+
+	if len(paths) != 0 {
+		return fmt.Errorf("unknown field %q", paths[0])
+	}
+
+	return nil
+}
+
+// unknownCBORFields returns the sorted paths of all the map keys in data that
+// did not survive the round-trip through the already decoded v
+func unknownCBORFields(v CBORCodec, data []byte) ([]string, error) {
+	var orig, rt interface{}
+
+	rtData, err := v.ToCBOR()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cbor.Unmarshal(data, &orig); err != nil {
+		return nil, err
+	}
+
+	if err := cbor.Unmarshal(rtData, &rt); err != nil {
+		return nil, err
+	}
+
+	known := newKeyProber(orig, extensionKeys(v), cbor.Marshal, cbor.Unmarshal, func(data []byte) ([]byte, error) {
+		fresh := reflect.New(reflect.TypeOf(v).Elem()).Interface().(CBORCodec)
+		if err := fresh.FromCBOR(data); err != nil {
+			return nil, err
+		}
+		return fresh.ToCBOR()
+	})
+
+	return findUnknownFields("", orig, rt, known), nil
+}
+
+// checkUnknownJSONFields is the JSON counterpart of checkUnknownCBORFields
+func checkUnknownJSONFields(v JSONCodec, data []byte) error {
+	var orig, rt interface{}
+
+	rtData, err := v.ToJSON()
+	if err != nil {
+		return fmt.Errorf("strict decoding: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &orig); err != nil {
+		return fmt.Errorf("strict decoding: %w", err)
+	}
+
+	if err := json.Unmarshal(rtData, &rt); err != nil {
+		return fmt.Errorf("strict decoding: %w", err)
+	}
+
+	known := newKeyProber(orig, extensionKeys(v), json.Marshal, json.Unmarshal, func(data []byte) ([]byte, error) {
+		fresh := reflect.New(reflect.TypeOf(v).Elem()).Interface().(JSONCodec)
+		if err := fresh.FromJSON(data); err != nil {
+			return nil, err
+		}
+		return fresh.ToJSON()
+	})
+
+	if path, found := findUnknownField("", orig, rt, known); found {
+		return fmt.Errorf("unknown field %q", path)
+	}
+
+	return nil
+}
+
+// keyProber tells whether the map key at keys (a path of map keys and array
+// indices) is understood, despite its zero value not surviving the round-trip
+type keyProber func(keys []interface{}) bool
+
+// newKeyProber returns a keyProber that temporarily replaces, in orig, the
+// zero value at the probed key with a value of another type (see probeValue),
+// and runs the result through roundTrip, which decodes and re-encodes it with a
+// fresh codec.  The key is understood if the probe value is rejected by the
+// codec, or if it survives the round-trip.  An unknown key is silently ignored
+// whatever its value.  As the fresh codec has no registered extensions, the
+// keys of the profile extensions registered with the decoded value, extKeys
+// (see extensionKeys), are taken as understood instead, wherever they are
+// found.
+func newKeyProber(
+	orig interface{},
+	extKeys map[interface{}]bool,
+	marshal func(interface{}) ([]byte, error),
+	unmarshal func([]byte, interface{}) error,
+	roundTrip func([]byte) ([]byte, error),
+) keyProber {
+	return func(keys []interface{}) bool {
+		if len(keys) != 0 && extKeys[keys[len(keys)-1]] {
+			return true
+		}
+
+		data, err := marshal(orig)
+		if err != nil {
+			return false
+		}
+
+		rtData, err := roundTrip(data)
+		if err != nil {
+			return true
+		}
+
+		var rt interface{}
+		if err := unmarshal(rtData, &rt); err != nil {
+			return false
+		}
+
+		return hasKeyPath(rt, keys)
+	}
+}
+
+// extensionsType is the type holding the extensions registered with the
+// CoRIM, CoMID, CoSWID and CoTS types, and with their members
+var extensionsType = reflect.TypeOf(extensions.Extensions{})
+
+// extensionKeys returns the map keys, as decoded from CBOR (uint64 or int64
+// labels) or from JSON (member names), of the fields of all the extensions
+// registered with v and with its members
+func extensionKeys(v interface{}) map[interface{}]bool {
+	keys := map[interface{}]bool{}
+
+	collectExtensionKeys(reflect.ValueOf(v), keys)
+
+	return keys
+}
+
+// collectExtensionKeys walks v, adding to keys those of the extensions
+// registered with any of the structs found along the way
+func collectExtensionKeys(v reflect.Value, keys map[interface{}]bool) {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if !v.IsNil() {
+			collectExtensionKeys(v.Elem(), keys)
+		}
+	case reflect.Struct:
+		if v.Type() == extensionsType {
+			// the IMapValue, a pointer to the extension struct
+			if ext := v.Field(0); !ext.IsNil() {
+				addExtensionFieldKeys(ext.Elem().Type(), keys)
+			}
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			collectExtensionKeys(v.Field(i), keys)
+		}
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			collectExtensionKeys(v.Index(i), keys)
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			collectExtensionKeys(iter.Value(), keys)
+		}
+	}
+}
+
+// addExtensionFieldKeys adds to keys the CBOR labels and the JSON member names
+// of the fields of the extension struct type t (or pointer to it)
+func addExtensionFieldKeys(t reflect.Type, keys map[interface{}]bool) {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		if name, _, _ := strings.Cut(f.Tag.Get("cbor"), ","); name != "" && name != "-" {
+			if n, err := strconv.ParseInt(name, 10, 64); err != nil {
+				keys[name] = true
+			} else if n < 0 {
+				keys[n] = true
+			} else {
+				keys[uint64(n)] = true
+			}
+		}
+
+		if name, _, _ := strings.Cut(f.Tag.Get("json"), ","); name != "" && name != "-" {
+			keys[name] = true
+		}
+	}
+}
+
+// probeValue returns a non-zero value whose type differs from that of v
+func probeValue(v interface{}) interface{} {
+	if _, ok := v.(string); ok {
+		return true
+	}
+	return "x"
+}
+
+// hasKeyPath tells whether the map key or array index at the end of keys is
+// found in v
+func hasKeyPath(v interface{}, keys []interface{}) bool {
+	if len(keys) == 0 {
+		return true
+	}
+
+	switch t := v.(type) {
+	case cbor.Tag:
+		return hasKeyPath(t.Content, keys)
+	case map[interface{}]interface{}:
+		if e, ok := t[keys[0]]; ok {
+			return hasKeyPath(e, keys[1:])
+		}
+	case map[string]interface{}:
+		if k, ok := keys[0].(string); ok {
+			if e, ok := t[k]; ok {
+				return hasKeyPath(e, keys[1:])
+			}
+		}
+	case []interface{}:
+		if i, ok := keys[0].(int); ok && i < len(t) {
+			return hasKeyPath(t[i], keys[1:])
+		}
+	}
+
+	return false
+}
+
+// findUnknownField walks orig and rt in parallel and returns the path (in
+// JSON pointer style) of the first, in lexical order, map key found in orig but
+// not in rt.  If known is not nil, it is used to tell the map keys with a zero
+// value that a known field would have dropped on re-encoding because of
+// omitempty from the unknown ones; otherwise, all of them are reported.
+func findUnknownField(path string, orig, rt interface{}, known keyProber) (string, bool) {
+	paths := findUnknownFields(path, orig, rt, known)
+	if len(paths) == 0 {
+		return "", false
+	}
+
+	return paths[0], true
+}
+
+// findUnknownFields is like findUnknownField, but returns the sorted paths of
+// all the map keys found in orig but not in rt
+func findUnknownFields(path string, orig, rt interface{}, known keyProber) []string {
+	var paths []string
+
+	collectUnknownFields(path, nil, orig, rt, known, &paths)
+	sort.Strings(paths)
+
+	return paths
+}
+
+// collectUnknownFields appends to paths the path of every map key of orig
+// that is missing from rt, whatever its value, unless known tells that the key
+// is understood.  keys holds the map keys and array indices leading to orig.
+// Arrays are walked element by element, up to the length of the shorter one.
+func collectUnknownFields(path string, keys []interface{}, orig, rt interface{}, known keyProber, paths *[]string) {
+	// sub returns keys followed by k, without sharing the backing array
+	sub := func(k interface{}) []interface{} {
+		return append(append([]interface{}{}, keys...), k)
+	}
+
+	switch o := orig.(type) {
+	case cbor.Tag:
+		if r, ok := rt.(cbor.Tag); ok {
+			collectUnknownFields(path, keys, o.Content, r.Content, known, paths)
+		}
+	case map[interface{}]interface{}:
+		r, ok := rt.(map[interface{}]interface{})
+		if !ok {
+			return
+		}
+		for k, ov := range o {
+			p := fmt.Sprintf("%s/%v", path, k)
+			rv, ok := r[k]
+			if !ok {
+				if !isKnownZeroValue(ov, func(v interface{}) { o[k] = v }, sub(k), known) {
+					*paths = append(*paths, p)
+				}
+				continue
+			}
+			collectUnknownFields(p, sub(k), ov, rv, known, paths)
+		}
+	case map[string]interface{}:
+		r, ok := rt.(map[string]interface{})
+		if !ok {
+			return
+		}
+		for k, ov := range o {
+			p := fmt.Sprintf("%s/%s", path, k)
+			rv, ok := r[k]
+			if !ok {
+				if !isKnownZeroValue(ov, func(v interface{}) { o[k] = v }, sub(k), known) {
+					*paths = append(*paths, p)
+				}
+				continue
+			}
+			collectUnknownFields(p, sub(k), ov, rv, known, paths)
+		}
+	case []interface{}:
+		r, ok := rt.([]interface{})
+		if !ok {
+			return
+		}
+		for i := range o {
+			if i >= len(r) {
+				break
+			}
+			collectUnknownFields(fmt.Sprintf("%s/%d", path, i), sub(i), o[i], r[i], known, paths)
+		}
+	}
+}
+
+// isKnownZeroValue tells whether v, found at keys, is a zero value whose key is
+// understood according to known.  set replaces v in orig, so that known can be
+// given a probe value in its place.
+func isKnownZeroValue(v interface{}, set func(interface{}), keys []interface{}, known keyProber) bool {
+	if known == nil || !isEmptyValue(v) {
+		return false
+	}
+
+	set(probeValue(v))
+	defer set(v)
+
+	return known(keys)
+}
+
+// isEmptyValue reports whether v is a value that a known field would have
+// dropped on re-encoding because of omitempty
+func isEmptyValue(v interface{}) bool {
+	switch t := v.(type) {
+	case nil:
+		return true
+	case bool:
+		return !t
+	case uint64:
+		return t == 0
+	case int64:
+		return t == 0
+	case float64:
+		return t == 0
+	case string:
+		return t == ""
+	case []byte:
+		return len(t) == 0
+	case []interface{}:
+		return len(t) == 0
+	case map[string]interface{}:
+		return len(t) == 0
+	case map[interface{}]interface{}:
+		return len(t) == 0
+	}
+	return false
+}
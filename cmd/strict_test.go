@@ -0,0 +1,203 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/corim/corim"
+)
+
+func Test_findUnknownField(t *testing.T) {
+	rt := map[string]interface{}{
+		"a": []interface{}{
+			map[string]interface{}{"b": "x"},
+		},
+	}
+
+	tvs := []struct {
+		desc     string
+		orig     interface{}
+		expected string
+		found    bool
+	}{
+		{
+			desc:  "same",
+			orig:  rt,
+			found: false,
+		},
+		{
+			desc: "nested unknown",
+			orig: map[string]interface{}{
+				"a": []interface{}{
+					map[string]interface{}{"b": "x", "c": "y"},
+				},
+			},
+			expected: "/a/0/c",
+			found:    true,
+		},
+		{
+			desc: "zero unknown value",
+			orig: map[string]interface{}{
+				"a": []interface{}{
+					map[string]interface{}{"b": "x"},
+				},
+				"d": []interface{}{},
+				"e": float64(0),
+			},
+			expected: "/d",
+			found:    true,
+		},
+		{
+			desc: "unknown in array of different length",
+			orig: map[string]interface{}{
+				"a": []interface{}{
+					map[string]interface{}{"b": "x", "c": false},
+					map[string]interface{}{"b": "y"},
+				},
+			},
+			expected: "/a/0/c",
+			found:    true,
+		},
+	}
+
+	for _, tv := range tvs {
+		path, found := findUnknownField("", tv.orig, rt, nil)
+		assert.Equal(t, tv.found, found, tv.desc)
+		assert.Equal(t, tv.expected, path, tv.desc)
+	}
+}
+
+func Test_ComidCreateCmd_template_with_zero_unknown_field_strict(t *testing.T) {
+	for _, v := range []string{`0`, `false`, `""`, `null`, `[]`, `{}`} {
+		tmpl := strings.Replace(comid.PSARefValJSONTemplate, "{", `{ "bogus": `+v+`,`, 1)
+
+		fs = afero.NewMemMapFs()
+		require.NoError(t, afero.WriteFile(fs, "unknown.json", []byte(tmpl), 0644))
+
+		_, err := templateToCBOR("unknown.json", ".", true, jsonLimits{}, envExpansion{}, nil, psaIDs{})
+		assert.EqualError(t, err, `error decoding template from unknown.json: unknown field "/bogus"`, v)
+	}
+}
+
+func Test_ComidCreateCmd_template_with_zero_known_field_strict(t *testing.T) {
+	// the zero tag version of the template is dropped on re-encoding, but is
+	// understood
+	require.Contains(t, comid.PSARefValJSONTemplate, `"version": 0`)
+
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "zero.json", []byte(comid.PSARefValJSONTemplate), 0644))
+
+	_, err := templateToCBOR("zero.json", ".", true, jsonLimits{}, envExpansion{}, nil, psaIDs{})
+	assert.NoError(t, err)
+}
+
+func Test_CorimDisplayCmd_zero_unknown_field_strict(t *testing.T) {
+	var c map[interface{}]interface{}
+	require.NoError(t, cbor.Unmarshal(testCorimValid, &c))
+	c[uint64(99)] = uint64(0)
+
+	data, err := cbor.Marshal(c)
+	require.NoError(t, err)
+
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "unknown.cbor", data, 0644))
+
+	cmd := NewCorimDisplayCmd()
+	cmd.SetArgs([]string{"--file=unknown.cbor", "--strict-decode"})
+	assert.EqualError(t, cmd.Execute(), `error decoding CoRIM (signed or unsigned) from unknown.cbor: unknown field "/99"`)
+}
+
+// newTestCorimWithUnknownComidField returns an unsigned CoRIM carrying the PSA
+// reference value CoMID, with an unknown (zero-valued) field added to it
+func newTestCorimWithUnknownComidField(t *testing.T) []byte {
+	c := comid.NewComid()
+	require.NoError(t, c.FromJSON([]byte(comid.PSARefValJSONTemplate)))
+
+	data, err := c.ToCBOR()
+	require.NoError(t, err)
+
+	var m map[interface{}]interface{}
+	require.NoError(t, cbor.Unmarshal(data, &m))
+	m[uint64(99)] = uint64(0)
+
+	data, err = cbor.Marshal(m)
+	require.NoError(t, err)
+
+	u := corim.NewUnsignedCorim().SetID("unknown-in-tag")
+	require.NotNil(t, u)
+	u.Tags = append(u.Tags, corim.Tag(append(append([]byte{}, corim.ComidTag...), data...)))
+
+	data, err = u.ToCBOR()
+	require.NoError(t, err)
+
+	return data
+}
+
+func Test_strict_unknown_field_in_tag(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "unsigned.cbor", newTestCorimWithUnknownComidField(t), 0644))
+	require.NoError(t, afero.WriteFile(fs, "meta.json", testMetaValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "key.jwk", testECKey, 0644))
+
+	cmd := NewCorimSignCmd()
+	cmd.SetArgs([]string{"--file=unsigned.cbor", "--meta=meta.json", "--key=key.jwk", "--output=signed.cbor"})
+	require.NoError(t, cmd.Execute())
+
+	// without --strict-decode, the unknown field is ignored
+	cmd = NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=signed.cbor", "--key=key.jwk"})
+	require.NoError(t, cmd.Execute())
+
+	cmd = NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=signed.cbor", "--key=key.jwk", "--strict-decode"})
+	assert.EqualError(t, cmd.Execute(),
+		`error decoding signed CoRIM from signed.cbor: unsigned CoRIM: tag at index 0: unknown field "/99"`)
+
+	cmd = NewCorimDisplayCmd()
+	cmd.SetArgs([]string{"--file=signed.cbor", "--show-tags", "--strict-decode"})
+	assert.EqualError(t, cmd.Execute(),
+		`error decoding signed CoRIM from signed.cbor: unsigned CoRIM: tag at index 0: unknown field "/99"`)
+
+	cmd = NewCorimDisplayCmd()
+	cmd.SetArgs([]string{"--file=unsigned.cbor", "--show-tags", "--strict-decode"})
+	assert.EqualError(t, cmd.Execute(),
+		`error decoding CoRIM (signed or unsigned) from unsigned.cbor: tag at index 0: unknown field "/99"`)
+}
+
+func Test_strict_zero_extension_field(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	profile := loadTestProfileDef(t)
+
+	// a null extension field is dropped on re-encoding, but is understood as
+	// the profile registers it
+	var tmpl map[string]interface{}
+	require.NoError(t, json.Unmarshal(newTestExtendedComidTemplate(t), &tmpl))
+
+	rv := tmpl["triples"].(map[string]interface{})["reference-values"].([]interface{})[0]
+	m := rv.(map[string]interface{})["measurements"].([]interface{})[0]
+	v := m.(map[string]interface{})["value"].(map[string]interface{})
+	v["build-id"] = nil
+
+	data, err := json.Marshal(tmpl)
+	require.NoError(t, err)
+
+	assert.NoError(t, decodeJSON(newComid(profile), data, true))
+
+	// a field unknown to the profile is still reported
+	v["bogus"] = nil
+
+	data, err = json.Marshal(tmpl)
+	require.NoError(t, err)
+
+	assert.EqualError(t, decodeJSON(newComid(profile), data, true),
+		`unknown field "/triples/reference-values/0/measurements/0/value/bogus"`)
+}
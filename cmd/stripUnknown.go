@@ -0,0 +1,72 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/veraison/corim/corim"
+	"github.com/veraison/corim/cots"
+	"github.com/veraison/swid"
+)
+
+// stripUnknownFields re-encodes the CoMID, CoSWID and CoTS tags of the
+// unsigned CoRIM c, decoded from data, so that the fields not understood by
+// their codecs (the CoMID one being that of the CoRIM profile, if any) are
+// dropped.  It returns the paths, relative to the CoRIM, of the dropped fields:
+// first those of the CoRIM itself, which are dropped anyway when c is
+// re-encoded, then those of each tag, in order.  Tags of unknown type cannot
+// be re-encoded and are left as is.
+func stripUnknownFields(c *corim.UnsignedCorim, data []byte) ([]string, error) {
+	dropped, err := unknownCBORFields(c, data)
+	if err != nil {
+		return nil, fmt.Errorf("error re-encoding CoRIM: %w", err)
+	}
+
+	for i, t := range c.Tags {
+		var v CBORCodec
+
+		switch {
+		case bytes.HasPrefix(t, corim.ComidTag):
+			v = newComid(c.Profile)
+		case bytes.HasPrefix(t, corim.CoswidTag):
+			v = &swid.SoftwareIdentity{}
+		case bytes.HasPrefix(t, cots.CotsTag):
+			v = &cots.ConciseTaStore{}
+		default:
+			continue
+		}
+
+		// all three CBOR tag prefixes are 3 bytes long
+		cborTag, cborData := t[:3], t[3:]
+
+		if err = v.FromCBOR(cborData); err != nil {
+			return nil, fmt.Errorf("error decoding tag at index %d: %w", i, err)
+		}
+
+		paths, err := unknownCBORFields(v, cborData)
+		if err != nil {
+			return nil, fmt.Errorf("error re-encoding tag at index %d: %w", i, err)
+		}
+
+		if len(paths) == 0 {
+			continue
+		}
+
+		// tags are at key 1 of the CoRIM
+		for _, p := range paths {
+			dropped = append(dropped, fmt.Sprintf("/1/%d%s", i, p))
+		}
+
+		stripped, err := v.ToCBOR()
+		if err != nil {
+			return nil, fmt.Errorf("error re-encoding tag at index %d: %w", i, err)
+		}
+
+		c.Tags[i] = append(append(corim.Tag{}, cborTag...), stripped...)
+	}
+
+	return dropped, nil
+}
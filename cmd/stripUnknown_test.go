@@ -0,0 +1,108 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/corim/corim"
+)
+
+// addUnknownCBORField returns data, a CBOR map, with the extra key 99
+func addUnknownCBORField(t *testing.T, data []byte) []byte {
+	var m map[interface{}]interface{}
+	require.NoError(t, cbor.Unmarshal(data, &m))
+
+	m[uint64(99)] = "unknown"
+
+	data, err := cbor.Marshal(m)
+	require.NoError(t, err)
+
+	return data
+}
+
+// newTestCorimWithUnknownFields returns an unsigned CoRIM with an unknown
+// field both at its top level and in its only (CoMID) tag
+func newTestCorimWithUnknownFields(t *testing.T) []byte {
+	var c comid.Comid
+	require.NoError(t, c.FromJSON([]byte(comid.PSARefValJSONTemplate)))
+
+	comidCBOR, err := c.ToCBOR()
+	require.NoError(t, err)
+
+	u := corim.NewUnsignedCorim().SetID("unknown")
+	require.NotNil(t, u)
+
+	u.Tags = append(u.Tags, append(append(corim.Tag{}, corim.ComidTag...), addUnknownCBORField(t, comidCBOR)...))
+
+	data, err := u.ToCBOR()
+	require.NoError(t, err)
+
+	return addUnknownCBORField(t, data)
+}
+
+func Test_stripUnknownFields(t *testing.T) {
+	data := newTestCorimWithUnknownFields(t)
+
+	var u corim.UnsignedCorim
+	require.NoError(t, u.FromCBOR(data))
+
+	dropped, err := stripUnknownFields(&u, data)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/99", "/1/0/99"}, dropped)
+
+	var c comid.Comid
+	require.NoError(t, decodeCBOR(&c, u.Tags[0][3:], true))
+
+	// nothing left to strip
+	dropped, err = stripUnknownFields(&u, data)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/99"}, dropped)
+}
+
+func Test_stripUnknownFields_none(t *testing.T) {
+	var u corim.UnsignedCorim
+	require.NoError(t, u.FromCBOR(testCorimValid))
+
+	dropped, err := stripUnknownFields(&u, testCorimValid)
+	require.NoError(t, err)
+	assert.Empty(t, dropped)
+}
+
+func Test_CorimSignCmd_strip_unknown(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "unknown.cbor", newTestCorimWithUnknownFields(t), 0644))
+	require.NoError(t, afero.WriteFile(fs, "meta.json", testMetaValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "key.jwk", testECKey, 0644))
+
+	signedTag := func(args ...string) corim.Tag {
+		cmd := NewCorimSignCmd()
+		cmd.SetArgs(append([]string{
+			"--file=unknown.cbor", "--meta=meta.json", "--key=key.jwk", "--output=signed.cbor",
+		}, args...))
+		require.NoError(t, withDisplayOutput("out.txt", cmd.Execute))
+
+		var s corim.SignedCorim
+		require.NoError(t, s.FromCOSE(mustReadFile(t, "signed.cbor")))
+		require.Len(t, s.UnsignedCorim.Tags, 1)
+
+		return s.UnsignedCorim.Tags[0]
+	}
+
+	// by default, the CoMID is signed as is
+	tag := signedTag()
+	assert.Error(t, decodeCBOR(&comid.Comid{}, tag[3:], true))
+
+	tag = signedTag("--strip-unknown")
+	assert.NoError(t, decodeCBOR(&comid.Comid{}, tag[3:], true))
+
+	out := string(mustReadFile(t, "out.txt"))
+	assert.Contains(t, out, `>> warning: dropped unknown field "/99" from unknown.cbor`)
+	assert.Contains(t, out, `>> warning: dropped unknown field "/1/0/99" from unknown.cbor`)
+}
@@ -0,0 +1,85 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// tagPage selects the range of the tags of a CoRIM that are processed by
+// "corim display --show-tags" and "corim extract", so that huge CoRIMs can be
+// inspected a page at a time.  A zero limit means no limit.  Within the range,
+// only the tags of tagType, if set, are processed.
+type tagPage struct {
+	offset  int
+	limit   int
+	tagType tagType
+}
+
+// addTagPageFlags adds the --offset and --limit switches to cmd
+func addTagPageFlags(cmd *cobra.Command) (offset, limit *int) {
+	offset = cmd.Flags().Int("offset", 0, "skip this many tags before the first one processed")
+	limit = cmd.Flags().Int("limit", 0, "process at most this many tags (0 means no limit)")
+
+	return offset, limit
+}
+
+func newTagPage(offset, limit *int) (tagPage, error) {
+	var p tagPage
+
+	if offset != nil {
+		p.offset = *offset
+	}
+
+	if limit != nil {
+		p.limit = *limit
+	}
+
+	if p.offset < 0 || p.limit < 0 {
+		return tagPage{}, errors.New("--offset and --limit cannot be negative")
+	}
+
+	return p, nil
+}
+
+func (o tagPage) isSet() bool {
+	return o.offset != 0 || o.limit != 0
+}
+
+// bounds returns the (half-open) range of indices selected among n tags
+func (o tagPage) bounds(n int) (int, int) {
+	lo := min(o.offset, n)
+
+	hi := n
+	if o.limit != 0 {
+		hi = min(lo+o.limit, n)
+	}
+
+	return lo, hi
+}
+
+// contains says whether the tag at index i (among n tags) is selected
+func (o tagPage) contains(i, n int) bool {
+	lo, hi := o.bounds(n)
+
+	return i >= lo && i < hi
+}
+
+// report prints the range of tags selected among n tags, if paging is in use
+func (o tagPage) report(n int) {
+	if !o.isSet() {
+		return
+	}
+
+	lo, hi := o.bounds(n)
+	if lo == hi {
+		fmt.Printf(">> showing no tags (offset %d) of %d\n", o.offset, n)
+		return
+	}
+
+	fmt.Printf(">> showing tags %d-%d of %d\n", lo, hi-1, n)
+}
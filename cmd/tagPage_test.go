@@ -0,0 +1,121 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_tagPage_bounds(t *testing.T) {
+	tvs := []struct {
+		page   tagPage
+		n      int
+		lo, hi int
+	}{
+		{tagPage{}, 5, 0, 5},
+		{tagPage{offset: 2}, 5, 2, 5},
+		{tagPage{limit: 2}, 5, 0, 2},
+		{tagPage{offset: 1, limit: 3}, 5, 1, 4},
+		{tagPage{offset: 4, limit: 3}, 5, 4, 5},
+		{tagPage{offset: 7, limit: 3}, 5, 5, 5},
+	}
+
+	for _, tv := range tvs {
+		lo, hi := tv.page.bounds(tv.n)
+		assert.Equal(t, tv.lo, lo, "%+v", tv.page)
+		assert.Equal(t, tv.hi, hi, "%+v", tv.page)
+	}
+}
+
+func Test_newTagPage_negative(t *testing.T) {
+	offset, limit := -1, 0
+
+	_, err := newTagPage(&offset, &limit)
+	assert.EqualError(t, err, "--offset and --limit cannot be negative")
+}
+
+func Test_CorimDisplayCmd_page(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "signed.cbor", newTestSignedCorimWithComids(t, 5), 0644))
+
+	cmd := NewCorimDisplayCmd()
+	cmd.SetArgs([]string{
+		"--file=signed.cbor",
+		"--show-tags",
+		"--offset=1",
+		"--limit=2",
+		"--output=out.txt",
+	})
+	require.NoError(t, cmd.Execute())
+
+	out, err := afero.ReadFile(fs, "out.txt")
+	require.NoError(t, err)
+
+	assert.Contains(t, string(out), ">> showing tags 1-2 of 5\n")
+	assert.Contains(t, string(out), ">> [ 1 ]")
+	assert.Contains(t, string(out), ">> [ 2 ]")
+	assert.NotContains(t, string(out), ">> [ 0 ]")
+	assert.NotContains(t, string(out), ">> [ 3 ]")
+}
+
+func Test_CorimDisplayCmd_page_without_show_tags(t *testing.T) {
+	cmd := NewCorimDisplayCmd()
+	cmd.SetArgs([]string{"--file=signed.cbor", "--limit=2"})
+	assert.EqualError(t, cmd.Execute(), "--offset and --limit can only be used together with --show-tags")
+}
+
+func Test_CorimExtractCmd_page(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "signed.cbor", newTestSignedCorimWithComids(t, 5), 0644))
+
+	cmd := NewCorimExtractCmd()
+	cmd.SetArgs([]string{
+		"--file=signed.cbor",
+		"--output-dir=out",
+		"--offset=3",
+	})
+	require.NoError(t, cmd.Execute())
+
+	files, err := afero.ReadDir(fs, "out")
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	assert.Equal(t, "000003-comid.cbor", files[0].Name())
+	assert.Equal(t, "000004-comid.cbor", files[1].Name())
+}
+
+func Test_CorimExtractCmd_page_json_array(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "signed.cbor", newTestSignedCorimWithComids(t, 5), 0644))
+
+	cmd := NewCorimExtractCmd()
+	cmd.SetArgs([]string{
+		"--file=signed.cbor",
+		"--json-array",
+		"--output=comids.json",
+		"--limit=2",
+	})
+	require.NoError(t, cmd.Execute())
+
+	var comids []interface{}
+	data, err := afero.ReadFile(fs, "comids.json")
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &comids))
+	assert.Len(t, comids, 2)
+}
+
+func Test_CorimExtractCmd_page_with_expected_counts(t *testing.T) {
+	cmd := NewCorimExtractCmd()
+	cmd.SetArgs([]string{
+		"--file=signed.cbor",
+		"--limit=2",
+		"--expect-comid-count=5",
+	})
+	assert.EqualError(t, cmd.Execute(),
+		"--expect-comid-count and --expect-cots-count cannot be used together with --offset or --limit")
+}
@@ -0,0 +1,97 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/veraison/corim/corim"
+	"github.com/veraison/corim/cots"
+)
+
+// the tag types accepted by --tag-type
+const (
+	tagTypeComid  = "comid"
+	tagTypeCoswid = "coswid"
+	tagTypeCots   = "cots"
+)
+
+// tagType restricts the tags of a CoRIM that are processed by "corim display
+// --show-tags" and "corim extract" to those of one type.  The empty tagType
+// selects all the tags.
+type tagType string
+
+// addTagTypeFlag adds the --tag-type switch to cmd
+func addTagTypeFlag(cmd *cobra.Command) *string {
+	return cmd.Flags().String("tag-type", "", "only process the tags of this type: comid, coswid or cots")
+}
+
+func newTagType(s *string) (tagType, error) {
+	if s == nil {
+		return "", nil
+	}
+
+	switch *s {
+	case "", tagTypeComid, tagTypeCoswid, tagTypeCots:
+		return tagType(*s), nil
+	}
+
+	return "", fmt.Errorf("invalid --tag-type %q: expecting comid, coswid or cots", *s)
+}
+
+// contains says whether a tag with CBOR tag prefix cborTag is selected
+func (o tagType) contains(cborTag []byte) bool {
+	switch o {
+	case tagTypeComid:
+		return bytes.Equal(cborTag, corim.ComidTag)
+	case tagTypeCoswid:
+		return bytes.Equal(cborTag, corim.CoswidTag)
+	case tagTypeCots:
+		return bytes.Equal(cborTag, cots.CotsTag)
+	}
+
+	return true
+}
+
+// tagTypeCounts is the number of tags of each type in a CoRIM
+type tagTypeCounts struct {
+	Comids  int `json:"comids"`
+	Coswids int `json:"coswids"`
+	Cots    int `json:"cots"`
+	Other   int `json:"other"`
+}
+
+func countTags(tags []corim.Tag) tagTypeCounts {
+	var n tagTypeCounts
+
+	for _, t := range tags {
+		switch {
+		case bytes.HasPrefix(t, corim.ComidTag):
+			n.Comids++
+		case bytes.HasPrefix(t, corim.CoswidTag):
+			n.Coswids++
+		case bytes.HasPrefix(t, cots.CotsTag):
+			n.Cots++
+		default:
+			n.Other++
+		}
+	}
+
+	return n
+}
+
+// report prints the number of tags of each type, whatever their type, if a
+// type is selected
+func (o tagType) report(tags []corim.Tag) {
+	if o == "" {
+		return
+	}
+
+	n := countTags(tags)
+
+	fmt.Printf(">> tags: %d CoMID(s), %d CoSWID(s), %d CoTS(s), %d other (only processing %s)\n",
+		n.Comids, n.Coswids, n.Cots, n.Other, o)
+}
@@ -0,0 +1,110 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/corim/corim"
+)
+
+// newTestSignedMixedCorim returns a signed CoRIM with two CoMIDs (at index 0
+// and 2) and one CoTS (at index 1)
+func newTestSignedMixedCorim(t *testing.T) []byte {
+	var withCots corim.SignedCorim
+	require.NoError(t, withCots.FromCOSE(testSignedCorimValidWithCots))
+	require.Len(t, withCots.UnsignedCorim.Tags, 1)
+
+	var c comid.Comid
+	require.NoError(t, c.FromJSON([]byte(comid.PSARefValJSONTemplate)))
+
+	u := corim.NewUnsignedCorim().SetID("mixed")
+	require.NotNil(t, u.AddComid(&c))
+	u.Tags = append(u.Tags, withCots.UnsignedCorim.Tags[0])
+	require.NotNil(t, u.AddComid(&c))
+
+	signer, err := corim.NewSignerFromJWK(testECKey)
+	require.NoError(t, err)
+
+	s := corim.SignedCorim{UnsignedCorim: *u}
+	s.Meta.SetSigner("ACME Ltd signing key", nil)
+
+	data, err := s.Sign(signer)
+	require.NoError(t, err)
+
+	return data
+}
+
+func Test_newTagType(t *testing.T) {
+	for _, s := range []string{"", "comid", "coswid", "cots"} {
+		tt, err := newTagType(&s)
+		require.NoError(t, err)
+		assert.Equal(t, tagType(s), tt)
+	}
+
+	bad := "CoMID"
+	_, err := newTagType(&bad)
+	assert.EqualError(t, err, `invalid --tag-type "CoMID": expecting comid, coswid or cots`)
+}
+
+func Test_tagType_contains(t *testing.T) {
+	assert.True(t, tagType("").contains(corim.ComidTag))
+	assert.True(t, tagType("comid").contains(corim.ComidTag))
+	assert.False(t, tagType("comid").contains(corim.CoswidTag))
+	assert.False(t, tagType("cots").contains(corim.ComidTag))
+}
+
+func Test_CorimDisplayCmd_tag_type(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "signed.cbor", newTestSignedMixedCorim(t), 0644))
+
+	cmd := NewCorimDisplayCmd()
+	cmd.SetArgs([]string{"--file=signed.cbor", "--show-tags", "--tag-type=cots", "--output=out.txt"})
+	require.NoError(t, cmd.Execute())
+
+	out := string(mustReadFile(t, "out.txt"))
+	assert.Contains(t, out, ">> tags: 2 CoMID(s), 0 CoSWID(s), 1 CoTS(s), 0 other (only processing cots)\n")
+	assert.Contains(t, out, ">> [ 1 ]")
+	assert.NotContains(t, out, ">> [ 0 ]")
+	assert.NotContains(t, out, ">> [ 2 ]")
+}
+
+func Test_CorimDisplayCmd_tag_type_bad_args(t *testing.T) {
+	cmd := NewCorimDisplayCmd()
+	cmd.SetArgs([]string{"--file=signed.cbor", "--tag-type=comid"})
+	assert.EqualError(t, cmd.Execute(), "--tag-type can only be used together with --show-tags")
+
+	cmd = NewCorimDisplayCmd()
+	cmd.SetArgs([]string{"--file=signed.cbor", "--show-tags", "--tag-type=swid"})
+	assert.EqualError(t, cmd.Execute(), `invalid --tag-type "swid": expecting comid, coswid or cots`)
+}
+
+func Test_CorimExtractCmd_tag_type(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "signed.cbor", newTestSignedMixedCorim(t), 0644))
+
+	// the expected counts are about all the tags
+	cmd := NewCorimExtractCmd()
+	cmd.SetArgs([]string{
+		"--file=signed.cbor", "--output-dir=out", "--tag-type=comid",
+		"--expect-comid-count=2", "--expect-cots-count=1",
+	})
+	require.NoError(t, cmd.Execute())
+
+	files, err := afero.ReadDir(fs, "out")
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	assert.Equal(t, "000000-comid.cbor", files[0].Name())
+	assert.Equal(t, "000002-comid.cbor", files[1].Name())
+}
+
+func Test_CorimExtractCmd_tag_type_json_array(t *testing.T) {
+	cmd := NewCorimExtractCmd()
+	cmd.SetArgs([]string{"--file=signed.cbor", "--json-array", "--output=c.json", "--tag-type=cots"})
+	assert.EqualError(t, cmd.Execute(), "--json-array only saves CoMIDs, and cannot be used together with --tag-type=cots")
+}
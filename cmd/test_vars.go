@@ -24,6 +24,10 @@ var (
 	testCorimValid = comid.MustHexDecode(nil,
 		"a200505c57e8f446cd421b91c908cf93e13cfc0181d901f944deadbeef",
 	)
+	// as above, with an extra (unknown) key: {..., 99: 1}
+	testCorimUnknownField = comid.MustHexDecode(nil,
+		"a300505c57e8f446cd421b91c908cf93e13cfc0181d901f944deadbeef186301",
+	)
 	// {0: h'5C57E8F446CD421B91C908CF93E13CFC'}
 	testCorimInvalid = comid.MustHexDecode(nil,
 		"a100505c57e8f446cd421b91c908cf93e13cfc",
@@ -43,6 +47,9 @@ var (
 	//go:embed testcases/ec-p256.jwk
 	testECKey []byte
 
+	//go:embed testcases/ed25519.jwk
+	testEdDSAKey []byte
+
 	//go:embed testcases/test-certs/endEntity.der
 	testSigningCertificate []byte
 
@@ -0,0 +1,548 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/veraison/apiclient/auth"
+	cose "github.com/veraison/go-cose"
+)
+
+// headerLabelTimestampToken is the (private use) COSE header label under which
+// "corim sign --tsa-url" places the RFC 3161 timestamp token obtained over the
+// signature, in the unprotected header
+const headerLabelTimestampToken int64 = -65538
+
+// maxTimestampResponseSize is the largest TimeStampResp accepted from a TSA
+const maxTimestampResponseSize = 1 << 20
+
+// timestampHash is the hash function of the message imprint sent to the TSA
+const timestampHash = crypto.SHA256
+
+// RFC 3161 object identifiers
+var (
+	oidTSTInfo       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 4}
+	oidRSAEncryption = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidSHA384WithRSA = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 12}
+	oidSHA512WithRSA = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 13}
+	oidECPublicKey   = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+)
+
+const (
+	// the TimeStampReq and TSTInfo versions
+	timestampVersion = 1
+	// the granted and grantedWithMods PKIStatus values
+	timestampGranted         = 0
+	timestampGrantedWithMods = 1
+	// the tag of the directoryName choice of a GeneralName
+	generalNameDirectoryNameTag = 4
+	// the tag of the (IMPLICIT [0]) subjectKeyIdentifier signer identifier
+	cmsSubjectKeyIDTag = 0
+)
+
+// timestampMessageImprint is a MessageImprint (RFC 3161, Section 2.4.1)
+type timestampMessageImprint struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	HashedMessage []byte
+}
+
+// timestampRequest is a TimeStampReq (RFC 3161, Section 2.4.1), without
+// extensions
+type timestampRequest struct {
+	Version        int
+	MessageImprint timestampMessageImprint
+	ReqPolicy      asn1.ObjectIdentifier `asn1:"optional"`
+	Nonce          *big.Int              `asn1:"optional"`
+	CertReq        bool                  `asn1:"optional"`
+}
+
+// timestampResponse is a TimeStampResp (RFC 3161, Section 2.4.2)
+type timestampResponse struct {
+	Status         timestampStatusInfo
+	TimeStampToken asn1.RawValue `asn1:"optional"`
+}
+
+// timestampStatusInfo is a PKIStatusInfo (RFC 3161, Section 2.4.2)
+type timestampStatusInfo struct {
+	Status       int
+	StatusString []string       `asn1:"optional"`
+	FailInfo     asn1.BitString `asn1:"optional"`
+}
+
+// tstInfo is a TSTInfo (RFC 3161, Section 2.4.2), the content signed by the TSA
+type tstInfo struct {
+	Version        int
+	Policy         asn1.ObjectIdentifier
+	MessageImprint timestampMessageImprint
+	SerialNumber   *big.Int
+	GenTime        time.Time         `asn1:"generalized"`
+	Accuracy       timestampAccuracy `asn1:"optional"`
+	Ordering       bool              `asn1:"optional"`
+	Nonce          *big.Int          `asn1:"optional"`
+	TSA            asn1.RawValue     `asn1:"optional,explicit,tag:0"`
+	Extensions     asn1.RawValue     `asn1:"optional,tag:1"`
+}
+
+type timestampAccuracy struct {
+	Seconds int `asn1:"optional"`
+	Millis  int `asn1:"optional,tag:0"`
+	Micros  int `asn1:"optional,tag:1"`
+}
+
+// timestampToken is a verified RFC 3161 timestamp token
+type timestampToken struct {
+	info tstInfo
+	// the certificate of the TSA, and the other certificates carried in the
+	// token
+	cert  *x509.Certificate
+	certs []*x509.Certificate
+}
+
+// tsaName returns the name of the TSA: the one in the TSTInfo, if it is a
+// directory name, or otherwise the subject of the TSA certificate
+func (o timestampToken) tsaName() string {
+	if o.info.TSA.Class == asn1.ClassContextSpecific && o.info.TSA.Tag == generalNameDirectoryNameTag {
+		var name pkix.RDNSequence
+		if _, err := asn1.Unmarshal(o.info.TSA.Bytes, &name); err == nil {
+			return name.String()
+		}
+	}
+
+	return o.cert.Subject.String()
+}
+
+// timestampImprint returns the message imprint of the signature of msg, which
+// is what the timestamp token covers
+func timestampImprint(msg *cose.Sign1Message) timestampMessageImprint {
+	h := timestampHash.New()
+	h.Write(msg.Signature)
+
+	return timestampMessageImprint{
+		HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidSHA256},
+		HashedMessage: h.Sum(nil),
+	}
+}
+
+// addTimestamp requests, from the TSA at tsaURL, a timestamp token over the
+// signature of the signed CoRIM signedCorimCBOR, checks it, and returns the
+// signed CoRIM with the token added to its unprotected header.  The signature
+// itself is left untouched.
+func addTimestamp(signedCorimCBOR []byte, tsaURL string, a auth.IAuthenticator) ([]byte, *timestampToken, error) {
+	msg, err := decodeSign1(signedCorimCBOR)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	imprint := timestampImprint(msg)
+
+	nonce, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error generating timestamp nonce: %w", err)
+	}
+
+	der, err := requestTimestamp(tsaURL, timestampRequest{
+		Version:        timestampVersion,
+		MessageImprint: imprint,
+		Nonce:          nonce,
+		CertReq:        true,
+	}, a)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tst, err := parseTimestampToken(der)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error checking timestamp token from %s: %w", tsaURL, err)
+	}
+
+	if err = tst.checkImprint(imprint); err != nil {
+		return nil, nil, fmt.Errorf("error checking timestamp token from %s: %w", tsaURL, err)
+	}
+
+	if tst.info.Nonce == nil || tst.info.Nonce.Cmp(nonce) != 0 {
+		return nil, nil, fmt.Errorf("error checking timestamp token from %s: nonce mismatch", tsaURL)
+	}
+
+	msg.Headers.Unprotected[headerLabelTimestampToken] = der
+	// the unprotected header is re-encoded, while the protected one is kept
+	// as signed
+	msg.Headers.RawUnprotected = nil
+
+	data, err := msg.MarshalCBOR()
+	if err != nil {
+		return nil, nil, fmt.Errorf("signed-corim marshaling failed: %w", err)
+	}
+
+	return data, tst, nil
+}
+
+// requestTimestamp sends req to the TSA at tsaURL (RFC 3161, Section 3.4) and
+// returns the timestamp token it grants
+func requestTimestamp(tsaURL string, req timestampRequest, a auth.IAuthenticator) ([]byte, error) {
+	body, err := asn1.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding timestamp request: %w", err)
+	}
+
+	httpReq, err := http.NewRequest(http.MethodPost, tsaURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("error requesting timestamp from %s: %w", tsaURL, err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/timestamp-query")
+	httpReq.Header.Set("Accept", "application/timestamp-reply")
+
+	if a != nil {
+		header, err := a.EncodeHeader()
+		if err != nil {
+			return nil, fmt.Errorf("error requesting timestamp from %s: %w", tsaURL, err)
+		}
+
+		if header != "" {
+			httpReq.Header.Set("Authorization", header)
+		}
+	}
+
+	resp, err := remoteCorimClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("error requesting timestamp from %s: %w", tsaURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error requesting timestamp from %s: unexpected HTTP status %q", tsaURL, resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxTimestampResponseSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("error requesting timestamp from %s: %w", tsaURL, err)
+	}
+
+	if len(data) > maxTimestampResponseSize {
+		return nil, fmt.Errorf("error requesting timestamp from %s: response larger than %d bytes", tsaURL, maxTimestampResponseSize)
+	}
+
+	var tsr timestampResponse
+	if rest, err := asn1.Unmarshal(data, &tsr); err != nil || len(rest) != 0 {
+		return nil, fmt.Errorf("error decoding timestamp response from %s: malformed TimeStampResp", tsaURL)
+	}
+
+	if s := tsr.Status.Status; s != timestampGranted && s != timestampGrantedWithMods {
+		return nil, fmt.Errorf("timestamp request rejected by %s: status %d %q", tsaURL, s, tsr.Status.StatusString)
+	}
+
+	if len(tsr.TimeStampToken.FullBytes) == 0 {
+		return nil, fmt.Errorf("error decoding timestamp response from %s: no timestamp token", tsaURL)
+	}
+
+	return tsr.TimeStampToken.FullBytes, nil
+}
+
+// parseTimestampToken decodes the timestamp token der, a CMS SignedData
+// carrying a TSTInfo, and checks its signature with the TSA certificate it
+// carries.  Whether the TSA certificate can be trusted is not checked.
+func parseTimestampToken(der []byte) (*timestampToken, error) {
+	var ci cmsContentInfo
+	if rest, err := asn1.Unmarshal(der, &ci); err != nil || len(rest) != 0 || !ci.ContentType.Equal(oidSignedData) {
+		return nil, errors.New("not a CMS SignedData")
+	}
+
+	var sd cmsSignedData
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &sd); err != nil {
+		return nil, fmt.Errorf("malformed SignedData: %w", err)
+	}
+
+	if !sd.EncapContentInfo.EContentType.Equal(oidTSTInfo) {
+		return nil, fmt.Errorf("unexpected content type %s, expecting a TSTInfo", sd.EncapContentInfo.EContentType)
+	}
+
+	if len(sd.SignerInfos) != 1 {
+		return nil, fmt.Errorf("expecting one signer, found %d", len(sd.SignerInfos))
+	}
+
+	tst := timestampToken{}
+
+	if _, err := asn1.Unmarshal(sd.EncapContentInfo.EContent, &tst.info); err != nil {
+		return nil, fmt.Errorf("malformed TSTInfo: %w", err)
+	}
+
+	certs, err := x509.ParseCertificates(sd.Certificates.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("malformed certificates: %w", err)
+	}
+
+	si := sd.SignerInfos[0]
+
+	if tst.cert = findTimestampSigner(si.SID, certs); tst.cert == nil {
+		return nil, errors.New("the TSA certificate is not in the timestamp token")
+	}
+
+	for _, c := range certs {
+		if c != tst.cert {
+			tst.certs = append(tst.certs, c)
+		}
+	}
+
+	if err = checkTimestampSignature(si, sd.EncapContentInfo.EContent, tst.cert); err != nil {
+		return nil, err
+	}
+
+	return &tst, nil
+}
+
+// findTimestampSigner returns the certificate, among certs, identified by sid,
+// either an issuerAndSerialNumber or a subjectKeyIdentifier
+func findTimestampSigner(sid asn1.RawValue, certs []*x509.Certificate) *x509.Certificate {
+	var ias cmsIssuerAndSerialNumber
+
+	isSKI := sid.Class == asn1.ClassContextSpecific && sid.Tag == cmsSubjectKeyIDTag
+
+	if !isSKI {
+		if _, err := asn1.Unmarshal(sid.FullBytes, &ias); err != nil {
+			return nil
+		}
+	}
+
+	for _, c := range certs {
+		switch {
+		case isSKI && len(c.SubjectKeyId) != 0 && bytes.Equal(c.SubjectKeyId, sid.Bytes):
+			return c
+		case !isSKI && bytes.Equal(c.RawIssuer, ias.Issuer.FullBytes) && c.SerialNumber.Cmp(ias.SerialNumber) == 0:
+			return c
+		}
+	}
+
+	return nil
+}
+
+// checkTimestampSignature checks that the content-type signed attribute of si
+// is id-ct-TSTInfo, that its message-digest signed attribute is the digest of
+// content, and that the signature of si over its signed attributes verifies
+// with cert
+func checkTimestampSignature(si cmsSignerInfo, content []byte, cert *x509.Certificate) error {
+	if len(si.SignedAttrs.FullBytes) == 0 {
+		return errors.New("no signed attributes")
+	}
+
+	hash, ok := cmsDigestHash(si.DigestAlgorithm.Algorithm)
+	if !ok {
+		return fmt.Errorf("unsupported digest algorithm %s", si.DigestAlgorithm.Algorithm)
+	}
+
+	// the signature is computed over the SET OF encoding of the signed
+	// attributes, which are carried with an IMPLICIT [0] tag instead
+	attrsDER := append([]byte{0x31}, si.SignedAttrs.FullBytes[1:]...)
+
+	var attrs []cmsAttribute
+	if _, err := asn1.UnmarshalWithParams(attrsDER, &attrs, "set"); err != nil {
+		return fmt.Errorf("malformed signed attributes: %w", err)
+	}
+
+	var (
+		contentType asn1.ObjectIdentifier
+		digest      []byte
+	)
+
+	for _, a := range attrs {
+		if a.Type.Equal(oidAttrContentType) && len(a.Values) == 1 {
+			if _, err := asn1.Unmarshal(a.Values[0].FullBytes, &contentType); err != nil {
+				return fmt.Errorf("malformed content-type attribute: %w", err)
+			}
+		}
+
+		if a.Type.Equal(oidAttrMessageDigest) && len(a.Values) == 1 {
+			if _, err := asn1.Unmarshal(a.Values[0].FullBytes, &digest); err != nil {
+				return fmt.Errorf("malformed message-digest attribute: %w", err)
+			}
+		}
+	}
+
+	// RFC 5652, section 5.3, the content-type attribute must match the
+	// encapsulated content, id-ct-TSTInfo for a timestamp token (RFC 3161)
+	if !contentType.Equal(oidTSTInfo) {
+		return errors.New("the content-type attribute is not id-ct-TSTInfo")
+	}
+
+	h := hash.New()
+	h.Write(content)
+
+	if !bytes.Equal(digest, h.Sum(nil)) {
+		return errors.New("the message-digest attribute does not match the TSTInfo")
+	}
+
+	alg, ok := cmsSignatureAlgorithm(si.SignatureAlgorithm.Algorithm, hash)
+	if !ok {
+		return fmt.Errorf("unsupported signature algorithm %s", si.SignatureAlgorithm.Algorithm)
+	}
+
+	if err := cert.CheckSignature(alg, attrsDER, si.Signature); err != nil {
+		return fmt.Errorf("signature verification failed: %w", err)
+	}
+
+	return nil
+}
+
+// cmsDigestHash returns the hash function identified by oid
+func cmsDigestHash(oid asn1.ObjectIdentifier) (crypto.Hash, bool) {
+	switch {
+	case oid.Equal(oidSHA256):
+		return crypto.SHA256, true
+	case oid.Equal(oidSHA384):
+		return crypto.SHA384, true
+	case oid.Equal(oidSHA512):
+		return crypto.SHA512, true
+	}
+
+	return 0, false
+}
+
+// cmsSignatureAlgorithm returns the X.509 signature algorithm identified by
+// oid, using hash if oid only identifies the key type, as many TSAs do
+func cmsSignatureAlgorithm(oid asn1.ObjectIdentifier, hash crypto.Hash) (x509.SignatureAlgorithm, bool) {
+	byHash := func(algs map[crypto.Hash]x509.SignatureAlgorithm) (x509.SignatureAlgorithm, bool) {
+		alg, ok := algs[hash]
+		return alg, ok
+	}
+
+	switch {
+	case oid.Equal(oidECDSAWithSHA256):
+		return x509.ECDSAWithSHA256, true
+	case oid.Equal(oidECDSAWithSHA384):
+		return x509.ECDSAWithSHA384, true
+	case oid.Equal(oidECDSAWithSHA512):
+		return x509.ECDSAWithSHA512, true
+	case oid.Equal(oidSHA256WithRSA):
+		return x509.SHA256WithRSA, true
+	case oid.Equal(oidSHA384WithRSA):
+		return x509.SHA384WithRSA, true
+	case oid.Equal(oidSHA512WithRSA):
+		return x509.SHA512WithRSA, true
+	case oid.Equal(oidEd25519):
+		return x509.PureEd25519, true
+	case oid.Equal(oidECPublicKey):
+		return byHash(map[crypto.Hash]x509.SignatureAlgorithm{
+			crypto.SHA256: x509.ECDSAWithSHA256, crypto.SHA384: x509.ECDSAWithSHA384, crypto.SHA512: x509.ECDSAWithSHA512,
+		})
+	case oid.Equal(oidRSAEncryption):
+		return byHash(map[crypto.Hash]x509.SignatureAlgorithm{
+			crypto.SHA256: x509.SHA256WithRSA, crypto.SHA384: x509.SHA384WithRSA, crypto.SHA512: x509.SHA512WithRSA,
+		})
+	}
+
+	return 0, false
+}
+
+// checkImprint checks that the token covers imprint
+func (o timestampToken) checkImprint(imprint timestampMessageImprint) error {
+	if !o.info.MessageImprint.HashAlgorithm.Algorithm.Equal(imprint.HashAlgorithm.Algorithm) ||
+		!bytes.Equal(o.info.MessageImprint.HashedMessage, imprint.HashedMessage) {
+		return errors.New("the timestamp token does not cover the signature")
+	}
+
+	return nil
+}
+
+// checkTrust checks that the TSA certificate chains up to one of roots,
+// possibly via the other certificates in the token, at the time of the
+// timestamp, and that it is meant for timestamping (RFC 3161, Section 2.3)
+func (o timestampToken) checkTrust(roots *x509.CertPool) error {
+	intermediates := x509.NewCertPool()
+	for _, cert := range o.certs {
+		intermediates.AddCert(cert)
+	}
+
+	opts := x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   o.info.GenTime,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+	}
+
+	if _, err := o.cert.Verify(opts); err != nil {
+		return fmt.Errorf("TSA certificate chain validation failed: %w", err)
+	}
+
+	return nil
+}
+
+// coseTimestampToken returns the timestamp token carried in the unprotected
+// header of msg under headerLabelTimestampToken, checked against the
+// signature of msg, or nil if there is none
+func coseTimestampToken(msg *cose.Sign1Message) (*timestampToken, error) {
+	v, ok := msg.Headers.Unprotected[headerLabelTimestampToken]
+	if !ok {
+		return nil, nil
+	}
+
+	der, ok := v.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("timestamp token: expecting a byte string, got %T", v)
+	}
+
+	tst, err := parseTimestampToken(der)
+	if err != nil {
+		return nil, fmt.Errorf("timestamp token: %w", err)
+	}
+
+	if err = tst.checkImprint(timestampImprint(msg)); err != nil {
+		return nil, fmt.Errorf("timestamp token: %w", err)
+	}
+
+	return tst, nil
+}
+
+// checkTimestamp checks the timestamp token of the signed CoRIM msg, if any,
+// and prints its time (rendered in loc, UTC if nil) and TSA, unless quiet.  If
+// tsaTrustAnchorFiles are supplied, the token is required, and the TSA
+// certificate must chain up to one of them.
+func checkTimestamp(msg *cose.Sign1Message, tsaTrustAnchorFiles []string, loc *time.Location, quiet bool) error {
+	tst, err := coseTimestampToken(msg)
+	if err != nil {
+		return err
+	}
+
+	if tst == nil {
+		if len(tsaTrustAnchorFiles) != 0 {
+			return errors.New("no timestamp token found in the COSE unprotected header")
+		}
+
+		return nil
+	}
+
+	if len(tsaTrustAnchorFiles) != 0 {
+		roots, err := loadTrustAnchors(tsaTrustAnchorFiles, false, quiet)
+		if err != nil {
+			return err
+		}
+
+		if err = tst.checkTrust(roots); err != nil {
+			return fmt.Errorf("timestamp token: %w", err)
+		}
+	}
+
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	progressf(quiet, ">> timestamp %s by %q (serial number %s)\n",
+		tst.info.GenTime.In(loc).Format(time.RFC3339), tst.tsaName(), tst.info.SerialNumber)
+
+	if len(tsaTrustAnchorFiles) == 0 {
+		warnf(quiet, ">> warning: the TSA certificate is not checked, use --tsa-trust-anchor to check it\n")
+	}
+
+	return nil
+}
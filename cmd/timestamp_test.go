@@ -0,0 +1,456 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testTSA is a time-stamping authority whose certificate is issued by its own
+// CA
+type testTSA struct {
+	signer crypto.Signer
+	cert   *x509.Certificate
+	caPEM  []byte
+	// status is the PKIStatus of the responses
+	status int
+	// mutate, if not nil, alters the TSTInfo before it is signed
+	mutate func(*tstInfo)
+}
+
+func newTestTSA(t *testing.T) *testTSA {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ACME TSA Root"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, caKey.Public(), caKey)
+	require.NoError(t, err)
+
+	ca, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "ACME TSA"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, key.Public(), caKey)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testTSA{
+		signer: key,
+		cert:   cert,
+		caPEM:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}),
+	}
+}
+
+// signTestTimestampToken returns the timestamp token, a CMS SignedData with
+// encapsulated content of type contentType, over content, signed by the TSA
+func (o *testTSA) signTestTimestampToken(contentType asn1.ObjectIdentifier, content []byte) ([]byte, error) {
+	algs, err := cmsAlgorithmsFor(o.signer.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	h := algs.hash.New()
+	h.Write(content)
+
+	contentTypeDER, err := asn1.Marshal(contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	digestDER, err := asn1.Marshal(h.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+
+	attrs, err := asn1.MarshalWithParams([]cmsAttribute{
+		{Type: oidAttrContentType, Values: []asn1.RawValue{{FullBytes: contentTypeDER}}},
+		{Type: oidAttrMessageDigest, Values: []asn1.RawValue{{FullBytes: digestDER}}},
+	}, "set")
+	if err != nil {
+		return nil, err
+	}
+
+	ah := algs.hash.New()
+	ah.Write(attrs)
+
+	sig, err := o.signer.Sign(rand.Reader, ah.Sum(nil), algs.hash)
+	if err != nil {
+		return nil, err
+	}
+
+	sid, err := asn1.Marshal(cmsIssuerAndSerialNumber{
+		Issuer:       asn1.RawValue{FullBytes: o.cert.RawIssuer},
+		SerialNumber: o.cert.SerialNumber,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	digestAlg := pkix.AlgorithmIdentifier{Algorithm: algs.digestOID}
+
+	sd, err := asn1.Marshal(cmsSignedData{
+		// the version for content other than id-data (RFC 5652, Section 5.1)
+		Version:          3,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{digestAlg},
+		EncapContentInfo: cmsEncapContentInfo{EContentType: contentType, EContent: content},
+		Certificates:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: cmsCertificatesTag, IsCompound: true, Bytes: o.cert.Raw},
+		SignerInfos: []cmsSignerInfo{
+			{
+				Version:            cmsSignerInfoVersion,
+				SID:                asn1.RawValue{FullBytes: sid},
+				DigestAlgorithm:    digestAlg,
+				SignedAttrs:        asn1.RawValue{FullBytes: append([]byte{cmsSignedAttrsTag}, attrs[1:]...)},
+				SignatureAlgorithm: pkix.AlgorithmIdentifier{Algorithm: algs.sigOID},
+				Signature:          sig,
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(cmsContentInfo{
+		ContentType: oidSignedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: sd},
+	})
+}
+
+func (o *testTSA) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var resp timestampResponse
+
+	body, _ := io.ReadAll(r.Body)
+
+	var req timestampRequest
+	if _, err := asn1.Unmarshal(body, &req); err != nil || r.Header.Get("Content-Type") != "application/timestamp-query" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	resp.Status.Status = o.status
+
+	if o.status != timestampGranted {
+		resp.Status.StatusString = []string{"request rejected"}
+	} else {
+		info := tstInfo{
+			Version:        timestampVersion,
+			Policy:         asn1.ObjectIdentifier{1, 2, 3, 4, 1},
+			MessageImprint: req.MessageImprint,
+			SerialNumber:   big.NewInt(1234),
+			GenTime:        time.Now().UTC().Truncate(time.Second),
+			Nonce:          req.Nonce,
+		}
+
+		if o.mutate != nil {
+			o.mutate(&info)
+		}
+
+		content, _ := asn1.Marshal(info)
+
+		token, err := o.signTestTimestampToken(oidTSTInfo, content)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		resp.TimeStampToken = asn1.RawValue{FullBytes: token}
+	}
+
+	data, _ := asn1.Marshal(resp)
+
+	w.Header().Set("Content-Type", "application/timestamp-reply")
+	_, _ = w.Write(data)
+}
+
+// signWithTSA signs testCorimValid with testECKey, with a timestamp from the
+// TSA at url, into signed.cbor
+func signWithTSA(t *testing.T, url string, extraArgs ...string) error {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "unsigned.cbor", testCorimValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "key.jwk", testECKey, 0644))
+	require.NoError(t, afero.WriteFile(fs, "meta.json", testMetaValid, 0644))
+
+	cmd := NewCorimSignCmd()
+	cmd.SetArgs(append([]string{
+		"--file=unsigned.cbor",
+		"--key=key.jwk",
+		"--meta=meta.json",
+		"--output=signed.cbor",
+		"--tsa-url=" + url,
+	}, extraArgs...))
+
+	return cmd.Execute()
+}
+
+func Test_CorimSignCmd_tsa_url(t *testing.T) {
+	tsa := newTestTSA(t)
+	ts := httptest.NewServer(tsa)
+	defer ts.Close()
+
+	require.NoError(t, signWithTSA(t, ts.URL+"/tsa"))
+
+	msg, err := decodeSign1(mustReadFile(t, "signed.cbor"))
+	require.NoError(t, err)
+
+	tst, err := coseTimestampToken(msg)
+	require.NoError(t, err)
+	require.NotNil(t, tst)
+	assert.Equal(t, "CN=ACME TSA", tst.tsaName())
+	assert.Equal(t, tsa.cert.Raw, tst.cert.Raw)
+
+	// the signature is left untouched by the timestamp
+	cmd := NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=signed.cbor", "--key=key.jwk"})
+	assert.NoError(t, cmd.Execute())
+
+	require.NoError(t, afero.WriteFile(fs, "tsa-ca.pem", tsa.caPEM, 0644))
+
+	cmd = NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=signed.cbor", "--key=key.jwk", "--tsa-trust-anchor=tsa-ca.pem"})
+	assert.NoError(t, cmd.Execute())
+
+	cmd = NewCorimDisplayCmd()
+	cmd.SetArgs([]string{"--file=signed.cbor"})
+	assert.NoError(t, cmd.Execute())
+}
+
+func Test_CorimVerifyCmd_tsa_trust_anchor_mismatch(t *testing.T) {
+	ts := httptest.NewServer(newTestTSA(t))
+	defer ts.Close()
+
+	require.NoError(t, signWithTSA(t, ts.URL+"/tsa"))
+
+	// the CA of another TSA
+	require.NoError(t, afero.WriteFile(fs, "other-ca.pem", newTestTSA(t).caPEM, 0644))
+
+	cmd := NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=signed.cbor", "--key=key.jwk", "--tsa-trust-anchor=other-ca.pem"})
+	assert.ErrorContains(t, cmd.Execute(),
+		"error verifying signed.cbor: timestamp token: TSA certificate chain validation failed: ")
+}
+
+func Test_CorimVerifyCmd_tsa_trust_anchor_no_timestamp(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "signed.cbor", testSignedCorimValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "key.jwk", testECKey, 0644))
+	require.NoError(t, afero.WriteFile(fs, "tsa-ca.pem", newTestTSA(t).caPEM, 0644))
+
+	cmd := NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=signed.cbor", "--key=key.jwk", "--tsa-trust-anchor=tsa-ca.pem"})
+	assert.EqualError(t, cmd.Execute(),
+		"error verifying signed.cbor: no timestamp token found in the COSE unprotected header")
+}
+
+func Test_CorimVerifyCmd_timestamp_replaced(t *testing.T) {
+	ts := httptest.NewServer(newTestTSA(t))
+	defer ts.Close()
+
+	require.NoError(t, signWithTSA(t, ts.URL+"/tsa"))
+	first := mustReadFile(t, "signed.cbor")
+
+	// a timestamp token taken from another signed CoRIM does not cover the
+	// signature
+	require.NoError(t, signWithTSA(t, ts.URL+"/tsa"))
+
+	other, err := decodeSign1(mustReadFile(t, "signed.cbor"))
+	require.NoError(t, err)
+
+	msg, err := decodeSign1(first)
+	require.NoError(t, err)
+
+	msg.Headers.Unprotected[headerLabelTimestampToken] = other.Headers.Unprotected[headerLabelTimestampToken]
+	msg.Headers.RawUnprotected = nil
+
+	data, err := msg.MarshalCBOR()
+	require.NoError(t, err)
+	require.NoError(t, afero.WriteFile(fs, "signed.cbor", data, 0644))
+
+	cmd := NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=signed.cbor", "--key=key.jwk"})
+	assert.EqualError(t, cmd.Execute(),
+		"error verifying signed.cbor: timestamp token: the timestamp token does not cover the signature")
+}
+
+func Test_CorimVerifyCmd_timestamp_quiet(t *testing.T) {
+	ts := httptest.NewServer(newTestTSA(t))
+	defer ts.Close()
+
+	require.NoError(t, signWithTSA(t, ts.URL+"/tsa"))
+
+	// neither the timestamp nor the unchecked TSA warning are printed along
+	// with the extracted value
+	cmd := NewCorimVerifyCmd()
+	cmd.SetArgs([]string{"--file=signed.cbor", "--key=key.jwk", "--extract=corim-id"})
+	require.NoError(t, withDisplayOutput("out.txt", cmd.Execute))
+
+	assert.Equal(t, "5c57e8f4-46cd-421b-91c9-08cf93e13cfc\n", string(mustReadFile(t, "out.txt")))
+}
+
+func Test_checkTimestampSignature_content_type(t *testing.T) {
+	tsa := newTestTSA(t)
+
+	content, err := asn1.Marshal(tstInfo{
+		Version: timestampVersion,
+		Policy:  asn1.ObjectIdentifier{1, 2, 3, 4, 1},
+		MessageImprint: timestampMessageImprint{
+			HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidSHA256},
+			HashedMessage: make([]byte, 32),
+		},
+		SerialNumber: big.NewInt(1234),
+		GenTime:      time.Now().UTC().Truncate(time.Second),
+	})
+	require.NoError(t, err)
+
+	for _, tv := range []struct {
+		name        string
+		contentType asn1.ObjectIdentifier
+		expectedErr string
+	}{
+		{"id-ct-TSTInfo", oidTSTInfo, ""},
+		{"id-data", oidData, "the content-type attribute is not id-ct-TSTInfo"},
+	} {
+		t.Run(tv.name, func(t *testing.T) {
+			token, err := tsa.signTestTimestampToken(tv.contentType, content)
+			require.NoError(t, err)
+
+			var ci cmsContentInfo
+			_, err = asn1.Unmarshal(token, &ci)
+			require.NoError(t, err)
+
+			var sd cmsSignedData
+			_, err = asn1.Unmarshal(ci.Content.Bytes, &sd)
+			require.NoError(t, err)
+			require.Len(t, sd.SignerInfos, 1)
+
+			err = checkTimestampSignature(sd.SignerInfos[0], content, tsa.cert)
+			if tv.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tv.expectedErr)
+			}
+		})
+	}
+}
+
+func Test_CorimSignCmd_tsa_url_bad_responses(t *testing.T) {
+	tvs := []struct {
+		desc     string
+		status   int
+		mutate   func(*tstInfo)
+		expected string
+	}{
+		{
+			desc:     "rejected",
+			status:   2,
+			expected: `timestamp request rejected by %s: status 2 ["request rejected"]`,
+		},
+		{
+			desc:     "wrong imprint",
+			mutate:   func(i *tstInfo) { i.MessageImprint.HashedMessage = make([]byte, 32) },
+			expected: "error checking timestamp token from %s: the timestamp token does not cover the signature",
+		},
+		{
+			desc:     "wrong nonce",
+			mutate:   func(i *tstInfo) { i.Nonce = big.NewInt(1) },
+			expected: "error checking timestamp token from %s: nonce mismatch",
+		},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.desc, func(t *testing.T) {
+			tsa := newTestTSA(t)
+			tsa.status = tv.status
+			tsa.mutate = tv.mutate
+
+			ts := httptest.NewServer(tsa)
+			defer ts.Close()
+
+			url := ts.URL + "/tsa"
+			assert.EqualError(t, signWithTSA(t, url), fmt.Sprintf(tv.expected, url))
+
+			_, err := fs.Stat("signed.cbor")
+			assert.Error(t, err)
+		})
+	}
+}
+
+func Test_CorimSignCmd_tsa_url_bad_args(t *testing.T) {
+	tvs := []struct {
+		desc     string
+		args     []string
+		expected string
+	}{
+		{
+			desc:     "not http",
+			args:     []string{"--tsa-url=ftp://tsa.example.com/"},
+			expected: `invalid --tsa-url "ftp://tsa.example.com/": expecting an http or https URL`,
+		},
+		{
+			desc:     "reproducible",
+			args:     []string{"--tsa-url=https://tsa.example.com/", "--reproducible"},
+			expected: "--tsa-url cannot be used together with --reproducible",
+		},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.desc, func(t *testing.T) {
+			cmd := NewCorimSignCmd()
+			cmd.SetArgs(append([]string{"--file=ok.cbor", "--key=ok.jwk", "--meta=ok.json"}, tv.args...))
+
+			assert.EqualError(t, cmd.Execute(), tv.expected)
+		})
+	}
+}
+
+func Test_cmsSignatureAlgorithm(t *testing.T) {
+	alg, ok := cmsSignatureAlgorithm(oidRSAEncryption, crypto.SHA384)
+	assert.True(t, ok)
+	assert.Equal(t, x509.SHA384WithRSA, alg)
+
+	alg, ok = cmsSignatureAlgorithm(oidECDSAWithSHA256, crypto.SHA512)
+	assert.True(t, ok)
+	assert.Equal(t, x509.ECDSAWithSHA256, alg)
+
+	_, ok = cmsSignatureAlgorithm(oidECPublicKey, crypto.SHA1)
+	assert.False(t, ok)
+}
@@ -0,0 +1,44 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"time"
+	// embed the IANA time zone database, for systems that lack one
+	_ "time/tzdata"
+
+	"github.com/veraison/corim/corim"
+)
+
+// defaultTimezone is the time zone used when rendering timestamps
+const defaultTimezone = "UTC"
+
+// loadTimezone resolves the supplied IANA time zone name, e.g., Europe/London.
+// As with time.LoadLocation, "UTC" and the empty string map to UTC, and
+// "Local" to the system time zone.
+func loadTimezone(name string) (*time.Location, error) {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone %q: expecting an IANA time zone name, e.g., Europe/London", name)
+	}
+
+	return loc, nil
+}
+
+// validityIn returns a copy of v with its timestamps rendered in loc
+func validityIn(v *corim.Validity, loc *time.Location) *corim.Validity {
+	if v == nil {
+		return nil
+	}
+
+	l := corim.Validity{NotAfter: v.NotAfter.In(loc)}
+
+	if v.NotBefore != nil {
+		nb := v.NotBefore.In(loc)
+		l.NotBefore = &nb
+	}
+
+	return &l
+}
@@ -0,0 +1,48 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/corim"
+)
+
+func Test_loadTimezone(t *testing.T) {
+	loc, err := loadTimezone(defaultTimezone)
+	require.NoError(t, err)
+	assert.Equal(t, time.UTC, loc)
+
+	loc, err = loadTimezone("Europe/London")
+	require.NoError(t, err)
+	assert.Equal(t, "Europe/London", loc.String())
+
+	_, err = loadTimezone("Nowhere/Special")
+	assert.EqualError(t, err, `invalid time zone "Nowhere/Special": expecting an IANA time zone name, e.g., Europe/London`)
+}
+
+func Test_validityIn(t *testing.T) {
+	loc, err := loadTimezone("Asia/Tokyo")
+	require.NoError(t, err)
+
+	notBefore := time.Date(2021, 12, 31, 0, 0, 0, 0, time.UTC)
+	v := corim.Validity{
+		NotBefore: &notBefore,
+		NotAfter:  time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC),
+	}
+
+	l := validityIn(&v, loc)
+	require.NotNil(t, l)
+	assert.Equal(t, "2021-12-31T09:00:00+09:00", l.NotBefore.Format(time.RFC3339))
+	assert.Equal(t, "2025-12-31T09:00:00+09:00", l.NotAfter.Format(time.RFC3339))
+
+	// same instants, and the original is left untouched
+	assert.True(t, l.NotBefore.Equal(notBefore))
+	assert.Equal(t, time.UTC, v.NotBefore.Location())
+
+	assert.Nil(t, validityIn(nil, loc))
+}
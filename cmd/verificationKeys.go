@@ -0,0 +1,181 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/swid"
+)
+
+// usage declared by the attester-verification-keys triple
+const attesterVerificationUsage = "attester-verification"
+
+// verificationKeyView is a focused view of a key found in an
+// attester-verification-keys triple
+type verificationKeyView struct {
+	Environment  json.RawMessage `json:"environment"`
+	Type         string          `json:"type"`
+	Thumbprint   string          `json:"thumbprint"`
+	Usage        string          `json:"usage"`
+	CertKeyUsage []string        `json:"cert-key-usage,omitempty"`
+}
+
+// verificationKeys projects the attester-verification-keys triples of c
+func verificationKeys(c *comid.Comid) ([]verificationKeyView, error) {
+	var views []verificationKeyView
+
+	if c.Triples.AttestVerifKeys == nil {
+		return views, nil
+	}
+
+	for i, kt := range *c.Triples.AttestVerifKeys {
+		env, err := json.Marshal(kt.Environment)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding environment of triple %d: %w", i, err)
+		}
+
+		for j, k := range kt.VerifKeys {
+			v := verificationKeyView{
+				Environment: env,
+				Type:        k.Type(),
+				Usage:       attesterVerificationUsage,
+			}
+
+			if v.Thumbprint, err = keyThumbprint(k); err != nil {
+				return nil, fmt.Errorf("error computing thumbprint of key %d in triple %d: %w", j, i, err)
+			}
+
+			v.CertKeyUsage = certKeyUsage(k)
+
+			views = append(views, v)
+		}
+	}
+
+	return views, nil
+}
+
+// keyThumbprint returns the SHA-256 of the DER-encoded SubjectPublicKeyInfo of
+// k.  Keys that are already thumbprints are returned as-is, and keys for which
+// no public key can be extracted are hashed in their CBOR encoding.
+func keyThumbprint(k *comid.CryptoKey) (string, error) {
+	switch k.Type() {
+	case comid.ThumbprintType, comid.CertThumbprintType, comid.CertPathThumbprintType:
+		return k.String(), nil
+	}
+
+	pk, err := k.PublicKey()
+	if err == nil {
+		if tp, err := publicKeyThumbprint(pk); err == nil {
+			return tp, nil
+		}
+	}
+
+	data, err := k.MarshalCBOR()
+	if err != nil {
+		return "", err
+	}
+
+	return sha256Thumbprint(data), nil
+}
+
+// publicKeyThumbprint returns the SHA-256 of the DER-encoded
+// SubjectPublicKeyInfo of pk
+func publicKeyThumbprint(pk crypto.PublicKey) (string, error) {
+	data, err := x509.MarshalPKIXPublicKey(pk)
+	if err != nil {
+		return "", err
+	}
+
+	return sha256Thumbprint(data), nil
+}
+
+// sha256Thumbprint formats the SHA-256 of data as a named-information hash
+// entry, e.g., "sha-256;<base64 digest>"
+func sha256Thumbprint(data []byte) string {
+	sum := sha256.Sum256(data)
+
+	return swid.HashEntry{HashAlgID: swid.Sha256, HashValue: sum[:]}.String()
+}
+
+// certKeyUsage returns the key usages declared by the (leaf) certificate of
+// certificate-based keys, or nil for any other key type
+func certKeyUsage(k *comid.CryptoKey) []string {
+	switch k.Type() {
+	case comid.PKIXBase64CertType, comid.PKIXBase64CertPathType:
+	default:
+		return nil
+	}
+
+	certs, err := parseCertificates([]byte(k.String()))
+	if err != nil || len(certs) == 0 {
+		return nil
+	}
+
+	return keyUsageNames(certs[0])
+}
+
+var keyUsageBits = []struct {
+	bit  x509.KeyUsage
+	name string
+}{
+	{x509.KeyUsageDigitalSignature, "digitalSignature"},
+	{x509.KeyUsageContentCommitment, "contentCommitment"},
+	{x509.KeyUsageKeyEncipherment, "keyEncipherment"},
+	{x509.KeyUsageDataEncipherment, "dataEncipherment"},
+	{x509.KeyUsageKeyAgreement, "keyAgreement"},
+	{x509.KeyUsageCertSign, "keyCertSign"},
+	{x509.KeyUsageCRLSign, "cRLSign"},
+	{x509.KeyUsageEncipherOnly, "encipherOnly"},
+	{x509.KeyUsageDecipherOnly, "decipherOnly"},
+}
+
+var extKeyUsageNames = map[x509.ExtKeyUsage]string{
+	x509.ExtKeyUsageAny:             "any",
+	x509.ExtKeyUsageServerAuth:      "serverAuth",
+	x509.ExtKeyUsageClientAuth:      "clientAuth",
+	x509.ExtKeyUsageCodeSigning:     "codeSigning",
+	x509.ExtKeyUsageEmailProtection: "emailProtection",
+	x509.ExtKeyUsageTimeStamping:    "timeStamping",
+	x509.ExtKeyUsageOCSPSigning:     "OCSPSigning",
+}
+
+// keyUsageNames lists the key usage and extended key usage of cert, using the
+// names from RFC 5280
+func keyUsageNames(cert *x509.Certificate) []string {
+	return append(keyUsageBitNames(cert.KeyUsage), extKeyUsageList(cert.ExtKeyUsage)...)
+}
+
+// keyUsageBitNames lists the names of the bits set in ku
+func keyUsageBitNames(ku x509.KeyUsage) []string {
+	var names []string
+
+	for _, b := range keyUsageBits {
+		if ku&b.bit != 0 {
+			names = append(names, b.name)
+		}
+	}
+
+	return names
+}
+
+// extKeyUsageList lists the names of the extended key usages in ekus
+func extKeyUsageList(ekus []x509.ExtKeyUsage) []string {
+	var names []string
+
+	for _, eku := range ekus {
+		if name, ok := extKeyUsageNames[eku]; ok {
+			names = append(names, name)
+		} else {
+			names = append(names, fmt.Sprintf("extKeyUsage(%d)", eku))
+		}
+	}
+
+	return names
+}
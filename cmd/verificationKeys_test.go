@@ -0,0 +1,73 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/swid"
+)
+
+func Test_verificationKeys(t *testing.T) {
+	var c comid.Comid
+	require.NoError(t, c.FromJSON([]byte(comid.PSAKeysJSONTemplate)))
+
+	views, err := verificationKeys(&c)
+	require.NoError(t, err)
+	require.Len(t, views, 2)
+
+	for i, v := range views {
+		assert.Equal(t, comid.PKIXBase64KeyType, v.Type)
+		assert.Equal(t, attesterVerificationUsage, v.Usage)
+		assert.Nil(t, v.CertKeyUsage)
+		assert.Contains(t, string(v.Environment), `"vendor":"ACME"`)
+
+		k := (*c.Triples.AttestVerifKeys)[i].VerifKeys[0]
+		pk, err := k.PublicKey()
+		require.NoError(t, err)
+		der, err := x509.MarshalPKIXPublicKey(pk)
+		require.NoError(t, err)
+		sum := sha256.Sum256(der)
+
+		assert.Equal(t, swid.HashEntry{HashAlgID: swid.Sha256, HashValue: sum[:]}.String(), v.Thumbprint)
+	}
+}
+
+func Test_verificationKeys_none(t *testing.T) {
+	var c comid.Comid
+	require.NoError(t, c.FromJSON([]byte(comid.PSARefValJSONTemplate)))
+
+	views, err := verificationKeys(&c)
+	require.NoError(t, err)
+	assert.Empty(t, views)
+}
+
+func Test_keyThumbprint_thumbprint_type(t *testing.T) {
+	k := comid.MustNewThumbprint(swid.HashEntry{HashAlgID: swid.Sha256, HashValue: make([]byte, 32)})
+
+	tp, err := keyThumbprint(k)
+	require.NoError(t, err)
+	assert.Equal(t, "sha-256;AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=", tp)
+}
+
+func Test_certKeyUsage(t *testing.T) {
+	pki := newTestPKI(t)
+
+	pemCert := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: pki.leafDER}))
+
+	k, err := comid.NewPKIXBase64Cert(pemCert)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"digitalSignature"}, certKeyUsage(k))
+
+	tp, err := keyThumbprint(k)
+	require.NoError(t, err)
+	assert.Contains(t, tp, "sha-256;")
+}
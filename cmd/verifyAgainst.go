@@ -0,0 +1,65 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/afero"
+)
+
+// verifyAgainstResult is the outcome of checking a freshly signed CoRIM
+// against one of the keys supplied with "corim sign --verify-against"
+type verifyAgainstResult struct {
+	keyFile string
+	err     error
+}
+
+// verifyAgainstKeys verifies the signed CoRIM signedCorimCBOR with each of the
+// public keys in keyFiles.  Failing to load a key counts as a failed
+// verification against it, rather than as an error, so that all the keys are
+// always reported on.
+func verifyAgainstKeys(signedCorimCBOR []byte, keyFiles []string) []verifyAgainstResult {
+	results := make([]verifyAgainstResult, 0, len(keyFiles))
+
+	for _, keyFile := range keyFiles {
+		results = append(results, verifyAgainstResult{
+			keyFile: keyFile,
+			err:     verifyAgainstKey(signedCorimCBOR, keyFile),
+		})
+	}
+
+	return results
+}
+
+func verifyAgainstKey(signedCorimCBOR []byte, keyFile string) error {
+	keyData, err := afero.ReadFile(fs, keyFile)
+	if err != nil {
+		return fmt.Errorf("error loading verifying key: %w", err)
+	}
+
+	pkey, err := parsePublicKey(keyData)
+	if err != nil {
+		return fmt.Errorf("error loading verifying key: %w", err)
+	}
+
+	msg, err := decodeSign1(signedCorimCBOR)
+	if err != nil {
+		return err
+	}
+
+	return verifySign1(msg, pkey)
+}
+
+// printVerifyAgainst prints one pass or fail line per key
+func printVerifyAgainst(results []verifyAgainstResult) {
+	for _, r := range results {
+		if r.err != nil {
+			fmt.Printf(">> verify-against %q: fail (%v)\n", r.keyFile, r.err)
+			continue
+		}
+
+		fmt.Printf(">> verify-against %q: pass\n", r.keyFile)
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_verifyAgainstKeys(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "new.jwk", testECKey, 0644))
+	require.NoError(t, afero.WriteFile(fs, "old.jwk", testEdDSAKey, 0644))
+
+	results := verifyAgainstKeys(testSignedCorimValid, []string{"new.jwk", "old.jwk", "missing.jwk"})
+	require.Len(t, results, 3)
+
+	assert.Equal(t, "new.jwk", results[0].keyFile)
+	assert.NoError(t, results[0].err)
+
+	assert.Equal(t, "old.jwk", results[1].keyFile)
+	assert.Error(t, results[1].err)
+
+	assert.Equal(t, "missing.jwk", results[2].keyFile)
+	assert.ErrorContains(t, results[2].err, "error loading verifying key")
+}
+
+func Test_CorimSignCmd_verify_against(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "unsigned.cbor", testCorimValid, 0644))
+	require.NoError(t, afero.WriteFile(fs, "key.jwk", testECKey, 0644))
+	require.NoError(t, afero.WriteFile(fs, "old.jwk", testEdDSAKey, 0644))
+
+	cmd := NewCorimSignCmd()
+	cmd.SetArgs([]string{
+		"--file=unsigned.cbor",
+		"--key=key.jwk",
+		"--no-meta",
+		"--output=signed.cbor",
+		"--verify-against=key.jwk",
+		"--verify-against=old.jwk",
+	})
+
+	// a failed check against the old key does not fail the signing
+	require.NoError(t, cmd.Execute())
+
+	signed, err := afero.ReadFile(fs, "signed.cbor")
+	require.NoError(t, err)
+
+	results := verifyAgainstKeys(signed, []string{"key.jwk", "old.jwk"})
+	assert.NoError(t, results[0].err)
+	assert.Error(t, results[1].err)
+}
+
+func Test_CorimSignCmd_verify_against_json_summary(t *testing.T) {
+	cmd := NewCorimSignCmd()
+	cmd.SetArgs([]string{
+		"--file=unsigned.cbor",
+		"--key=key.jwk",
+		"--no-meta",
+		"--summary",
+		"--format=json",
+		"--verify-against=key.jwk",
+	})
+
+	assert.EqualError(t, cmd.Execute(), "--verify-against cannot be used together with --format=json")
+}
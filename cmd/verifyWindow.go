@@ -0,0 +1,120 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/veraison/corim/corim"
+)
+
+// validityWindow is the time window used to select which CoRIMs to verify.  A
+// zero since or until leaves the corresponding side of the window open.
+type validityWindow struct {
+	since time.Time
+	until time.Time
+}
+
+// newValidityWindow parses the supplied bounds, each given either as an RFC
+// 3339 timestamp or as a date (YYYY-MM-DD, meaning midnight UTC)
+func newValidityWindow(since, until string) (validityWindow, error) {
+	var (
+		w   validityWindow
+		err error
+	)
+
+	if since != "" {
+		if w.since, err = parseWindowTime(since); err != nil {
+			return w, fmt.Errorf("invalid --since: %w", err)
+		}
+	}
+
+	if until != "" {
+		if w.until, err = parseWindowTime(until); err != nil {
+			return w, fmt.Errorf("invalid --until: %w", err)
+		}
+	}
+
+	if !w.since.IsZero() && !w.until.IsZero() && w.until.Before(w.since) {
+		return w, errors.New("--until precedes --since")
+	}
+
+	return w, nil
+}
+
+func parseWindowTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+
+	return time.Time{}, fmt.Errorf("%q is neither an RFC 3339 timestamp nor a YYYY-MM-DD date", s)
+}
+
+// isOpen reports whether the window is unbounded on both sides
+func (w validityWindow) isOpen() bool {
+	return w.since.IsZero() && w.until.IsZero()
+}
+
+// overlaps reports whether the validity period v (which lasts forever in the
+// past when it has no not-before) overlaps the window.  CoRIMs with no
+// validity are always considered to overlap.
+func (w validityWindow) overlaps(v *corim.Validity) bool {
+	if v == nil {
+		return true
+	}
+
+	if !w.since.IsZero() && v.NotAfter.Before(w.since) {
+		return false
+	}
+
+	if !w.until.IsZero() && v.NotBefore != nil && v.NotBefore.After(w.until) {
+		return false
+	}
+
+	return true
+}
+
+// loadMetaValidity decodes the CoRIM Meta of the signed CoRIM in file and
+// returns its validity, if any
+func loadMetaValidity(file string) (*corim.Validity, error) {
+	data, err := afero.ReadFile(fs, file)
+	if err != nil {
+		return nil, fmt.Errorf("error loading signed CoRIM from %s: %w", file, err)
+	}
+
+	var s corim.SignedCorim
+	if err = s.FromCOSE(data); err != nil {
+		return nil, fmt.Errorf("error decoding signed CoRIM from %s: %w", file, err)
+	}
+
+	return s.Meta.Validity, nil
+}
+
+// formatValidity renders v for reporting, using loc (UTC if nil) for the
+// timestamps
+func formatValidity(v *corim.Validity, loc *time.Location) string {
+	if v == nil {
+		return "[-, -]"
+	}
+
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	l := validityIn(v, loc)
+
+	nb := "-"
+	if l.NotBefore != nil {
+		nb = l.NotBefore.Format(time.RFC3339)
+	}
+
+	return fmt.Sprintf("[%s, %s]", nb, l.NotAfter.Format(time.RFC3339))
+}
@@ -0,0 +1,162 @@
+// Copyright 2021-2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/corim"
+)
+
+func Test_newValidityWindow(t *testing.T) {
+	w, err := newValidityWindow("2024-01-01", "2024-12-31T23:59:59Z")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), w.since)
+	assert.Equal(t, time.Date(2024, 12, 31, 23, 59, 59, 0, time.UTC), w.until)
+	assert.False(t, w.isOpen())
+
+	w, err = newValidityWindow("", "")
+	require.NoError(t, err)
+	assert.True(t, w.isOpen())
+
+	_, err = newValidityWindow("yesterday", "")
+	assert.EqualError(t, err, `invalid --since: "yesterday" is neither an RFC 3339 timestamp nor a YYYY-MM-DD date`)
+
+	_, err = newValidityWindow("", "2024-13-01")
+	assert.EqualError(t, err, `invalid --until: "2024-13-01" is neither an RFC 3339 timestamp nor a YYYY-MM-DD date`)
+
+	_, err = newValidityWindow("2024-06-01", "2024-01-01")
+	assert.EqualError(t, err, "--until precedes --since")
+}
+
+func Test_validityWindow_overlaps(t *testing.T) {
+	notBefore := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	v := corim.Validity{
+		NotBefore: &notBefore,
+		NotAfter:  time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	tvs := []struct {
+		desc     string
+		since    string
+		until    string
+		validity *corim.Validity
+		expected bool
+	}{
+		{"inside", "2022-06-01", "2022-07-01", &v, true},
+		{"straddling not-before", "2021-06-01", "2022-01-01", &v, true},
+		{"straddling not-after", "2023-01-01", "2024-01-01", &v, true},
+		{"before", "2020-01-01", "2021-12-31", &v, false},
+		{"after", "2023-01-02", "", &v, false},
+		{"open since", "", "2022-06-01", &v, true},
+		{"no not-before", "", "2000-01-01", &corim.Validity{NotAfter: v.NotAfter}, true},
+		{"no validity", "2030-01-01", "", nil, true},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.desc, func(t *testing.T) {
+			w, err := newValidityWindow(tv.since, tv.until)
+			require.NoError(t, err)
+			assert.Equal(t, tv.expected, w.overlaps(tv.validity))
+		})
+	}
+}
+
+func Test_CorimVerifyCmd_dir_window(t *testing.T) {
+	cmd := NewCorimVerifyCmd()
+
+	// testSignedCorimValid is valid from 2021-12-31 to 2025-12-31
+	args := []string{
+		"--dir=archive",
+		"--key=ok.jwk",
+		"--since=2026-01-01",
+	}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "archive/a.cbor", testSignedCorimValid, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "archive/b.cbor", testSignedCorimInvalid, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ok.jwk", testECKey, 0644)
+	require.NoError(t, err)
+
+	// a.cbor is skipped, b.cbor cannot be decoded
+	err = cmd.Execute()
+	assert.EqualError(t, err, "1/2 verification(s) failed")
+
+	err = fs.Remove("archive/b.cbor")
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	assert.NoError(t, err)
+}
+
+func Test_CorimVerifyCmd_dir_ok(t *testing.T) {
+	cmd := NewCorimVerifyCmd()
+
+	args := []string{
+		"--dir=archive",
+		"--key=ok.jwk",
+		"--since=2024-01-01",
+		"--until=2024-12-31",
+	}
+	cmd.SetArgs(args)
+
+	fs = afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "archive/a.cbor", testSignedCorimValid, 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "archive/README.md", []byte("not a CoRIM"), 0644)
+	require.NoError(t, err)
+	err = afero.WriteFile(fs, "ok.jwk", testECKey, 0644)
+	require.NoError(t, err)
+
+	err = cmd.Execute()
+	assert.NoError(t, err)
+}
+
+func Test_CorimVerifyCmd_dir_args(t *testing.T) {
+	tvs := []struct {
+		desc     string
+		args     []string
+		expected string
+	}{
+		{
+			desc:     "file and dir",
+			args:     []string{"--file=a.cbor", "--dir=archive", "--key=ok.jwk"},
+			expected: "--file cannot be used together with --dir",
+		},
+		{
+			desc:     "window without dir",
+			args:     []string{"--file=a.cbor", "--key=ok.jwk", "--since=2024-01-01"},
+			expected: "--since and --until can only be used together with --dir or --input-glob",
+		},
+		{
+			desc:     "bad window",
+			args:     []string{"--dir=archive", "--key=ok.jwk", "--until=soon"},
+			expected: `invalid --until: "soon" is neither an RFC 3339 timestamp nor a YYYY-MM-DD date`,
+		},
+		{
+			desc:     "empty dir",
+			args:     []string{"--dir=archive", "--key=ok.jwk"},
+			expected: "no files found",
+		},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.desc, func(t *testing.T) {
+			cmd := NewCorimVerifyCmd()
+			cmd.SetArgs(tv.args)
+
+			fs = afero.NewMemMapFs()
+
+			err := cmd.Execute()
+			assert.EqualError(t, err, tv.expected)
+		})
+	}
+}